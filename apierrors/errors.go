@@ -0,0 +1,81 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the outcomes handlers answer differently; repositories wrap their errors with them
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrBadRequest = errors.New("bad request")
+)
+
+// AppError is an error with the HTTP status and machine-readable code it is answered with
+type AppError struct {
+	Code       string `json:"code"`    // e.g. product_not_found, the locale key of the message
+	Message    string `json:"message"` // ข้อความสำหรับผู้ใช้ ตามภาษาของ request
+	StatusCode int    `json:"-"`
+	Cause      error  `json:"-"` // underlying error, logged but never sent
+}
+
+// New creates an AppError
+func New(statusCode int, code, message string) *AppError {
+	return &AppError{
+		Code:       code,
+		Message:    message,
+		StatusCode: statusCode,
+	}
+}
+
+// NewStatus creates an AppError whose code is derived from the status, e.g. 404 -> not_found
+func NewStatus(statusCode int, message string) *AppError {
+	return New(statusCode, CodeForStatus(statusCode), message)
+}
+
+// CodeForStatus returns the snake_case status text, e.g. 400 -> bad_request
+func CodeForStatus(statusCode int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+}
+
+func (e *AppError) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches the sentinel error of the AppError's status, so errors.Is(err, ErrNotFound) holds for any 404
+func (e *AppError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	}
+	return false
+}
+
+// WithCause returns a copy of the error carrying cause
+func (e *AppError) WithCause(cause error) *AppError {
+	withCause := *e
+	withCause.Cause = cause
+	return &withCause
+}
+
+// Write sends the error as {"code": "...", "message": "..."} with its status
+func Write(w http.ResponseWriter, appErr *AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(appErr.StatusCode)
+	json.NewEncoder(w).Encode(appErr)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,62 @@ type Config struct {
 	MongoURI    string
 	Database    string
 	Environment string
+
+	MongoConnectTimeout time.Duration // per attempt to reach MongoDB at startup
+	MongoMaxRetries     int           // times the startup ping is retried while MongoDB is not reachable
+	MongoMaxPoolSize    int
+	AdminToken          string
+
+	TLSCertFile         string // with TLSKeyFile, serves HTTPS (HTTP/2) instead of plain HTTP
+	TLSKeyFile          string
+	LetsEncrypt         bool     // serves HTTPS with certificates from Let's Encrypt instead of the files
+	LetsEncryptDomains  []string // the host names certificates may be requested for
+	LetsEncryptCacheDir string
+	HTTPRedirectPort    string // plain HTTP port redirecting to HTTPS in TLS mode ("0" = none)
+
+	AuditLogTTLDays int // 0 = keep audit logs forever
+
+	QuotationExpiryInterval  time.Duration
+	RecurringOrderInterval   time.Duration
+	VelocityClassifyInterval time.Duration
+	PopularityScoreInterval  time.Duration
+
+	VATRate float64 // stored on new sales, purchases and quotations, e.g. 0.07 for 7%
+
+	QRDataFormat string // product QR code content with {skuId} for the SKU ID; the bare SKU ID when empty
+
+	InvoiceMatchTolerancePercent float64 // how far matched purchases may be from a supplier invoice total
+
+	SMTPHost              string // low-stock alerts are disabled when empty
+	SMTPPort              int
+	SMTPUser              string // also the sender address
+	SMTPPassword          string
+	AlertEmail            string // recipient of low-stock alerts
+	LowStockCheckInterval time.Duration
+
+	RateLimitRPS                float64 // requests per second per IP (0 = no limit)
+	RateLimitBurst              int
+	MigrationRateLimitPerMinute int      // CSV imports per minute per IP (0 = no limit)
+	TrustedProxies              []string // reverse proxies (IPs or CIDRs) whose X-Forwarded-For is believed
+
+	MaxBodyBytes int64 // largest JSON request body; file uploads and JSON imports get middleware.MaxUploadBytes
+
+	RequestTimeout     time.Duration // 0 = no limit
+	SlowRequestTimeout time.Duration // reports, exports and imports; 0 = no limit
+
+	SKUCacheTTL time.Duration // how long the highest SKU number of a category is cached when creating products
+
+	CORSAllowedOrigins []string // "*" (any origin) when CORS_ALLOWED_ORIGINS is empty
+	CORSAllowedHeaders []string
+
+	ResponseEnvelope bool // wrap JSON responses in {data, meta} / {error, meta}
+
+	StorageBackend     string // local or s3
+	AWSBucket          string
+	AWSRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSEndpoint        string // optional, for S3-compatible storage such as MinIO
 }
 
 func Load() *Config {
@@ -25,12 +84,137 @@ func Load() *Config {
 		MongoURI:    getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		Database:    getEnv("DATABASE_NAME", "goodpack"),
 		Environment: getEnv("ENVIRONMENT", "development"),
+
+		MongoConnectTimeout: time.Duration(getEnvInt("MONGO_CONNECT_TIMEOUT_SECONDS", 10)) * time.Second,
+		MongoMaxRetries:     getEnvInt("MONGO_MAX_RETRIES", 10),
+		MongoMaxPoolSize:    getEnvInt("MONGO_MAX_POOL_SIZE", 100),
+		AdminToken:          getEnv("ADMIN_TOKEN", ""),
+
+		TLSCertFile:         getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:          getEnv("TLS_KEY_FILE", ""),
+		LetsEncrypt:         getEnvBool("LETS_ENCRYPT", false),
+		LetsEncryptDomains:  getEnvList("LETS_ENCRYPT_DOMAINS", nil),
+		LetsEncryptCacheDir: getEnv("LETS_ENCRYPT_CACHE_DIR", "certs"),
+		HTTPRedirectPort:    getEnv("HTTP_REDIRECT_PORT", "80"),
+
+		AuditLogTTLDays: getEnvInt("AUDIT_LOG_TTL_DAYS", 0),
+
+		QuotationExpiryInterval:  getEnvDuration("QUOTATION_EXPIRY_INTERVAL", time.Hour),
+		RecurringOrderInterval:   getEnvDuration("RECURRING_ORDER_INTERVAL", 24*time.Hour),
+		VelocityClassifyInterval: getEnvDuration("VELOCITY_CLASSIFY_INTERVAL", 24*time.Hour),
+		PopularityScoreInterval:  getEnvDuration("POPULARITY_SCORE_INTERVAL", 24*time.Hour),
+
+		VATRate: getEnvRate("VAT_RATE", 0.07),
+
+		QRDataFormat: getEnv("QR_DATA_FORMAT", ""),
+
+		InvoiceMatchTolerancePercent: getEnvFloat("INVOICE_MATCH_TOLERANCE_PERCENT", 1),
+
+		SMTPHost:              getEnv("SMTP_HOST", ""),
+		SMTPPort:              getEnvInt("SMTP_PORT", 587),
+		SMTPUser:              getEnv("SMTP_USER", ""),
+		SMTPPassword:          getEnv("SMTP_PASSWORD", ""),
+		AlertEmail:            getEnv("ALERT_EMAIL", ""),
+		LowStockCheckInterval: getEnvDuration("LOW_STOCK_CHECK_INTERVAL", time.Hour),
+
+		RateLimitRPS:                getEnvFloat("RATE_LIMIT_RPS", 10),
+		RateLimitBurst:              getEnvInt("RATE_LIMIT_BURST", 20),
+		MigrationRateLimitPerMinute: getEnvInt("MIGRATION_RATE_LIMIT_PER_MINUTE", 5),
+		TrustedProxies:              getEnvList("TRUSTED_PROXIES", nil),
+
+		MaxBodyBytes: int64(getEnvInt("MAX_BODY_BYTES", 1<<20)),
+
+		RequestTimeout:     time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 30)) * time.Second,
+		SlowRequestTimeout: time.Duration(getEnvInt("SLOW_REQUEST_TIMEOUT_SECONDS", 300)) * time.Second,
+
+		SKUCacheTTL: getEnvDuration("SKU_CACHE_TTL", time.Minute),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS", []string{"*"}),
+
+		ResponseEnvelope: getEnvBool("RESPONSE_ENVELOPE", false),
+
+		StorageBackend:     getEnv("STORAGE_BACKEND", "local"),
+		AWSBucket:          getEnv("AWS_BUCKET", ""),
+		AWSRegion:          getEnv("AWS_REGION", ""),
+		AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSEndpoint:        getEnv("AWS_ENDPOINT", ""),
 	}
 }
 
+// TLSEnabled reports whether the server is configured to serve HTTPS
+func (c *Config) TLSEnabled() bool {
+	return c.LetsEncrypt || (c.TLSCertFile != "" && c.TLSKeyFile != "")
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %d", key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %g", key, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvRate reads a rate as a fraction between 0 and 1 (exclusive), e.g. 0.07 for 7%
+func getEnvRate(key string, defaultValue float64) float64 {
+	rate := getEnvFloat(key, defaultValue)
+	if rate <= 0 || rate >= 1 {
+		log.Printf("Invalid value for %s, using default %g", key, defaultValue)
+		return defaultValue
+	}
+	return rate
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %t", key, defaultValue)
+	}
+	return defaultValue
+}
+
+// getEnvList parses a comma-separated value, ignoring blank entries
+func getEnvList(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+		log.Printf("Invalid value for %s, using default %s", key, defaultValue)
+	}
+	return defaultValue
+}
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 // CategoryItem represents a category configuration item
@@ -14,8 +20,17 @@ type CategoryItem struct {
 	Name         string `json:"name"`
 	Abbreviation string `json:"abbreviation"`
 	English      string `json:"english"`
+	IsActive     bool   `json:"isActive"` // true unless set to false in categories.json
+}
+
+// CategorySource provides the categories from somewhere other than categories.json, e.g. the database
+type CategorySource interface {
+	ConfigCategories(ctx context.Context) ([]CategoryItem, error)
 }
 
+// categorySourceTimeout bounds each read of the category source
+const categorySourceTimeout = 5 * time.Second
+
 // ColorItem represents a color configuration item
 type ColorItem struct {
 	Name         string `json:"name"`
@@ -40,16 +55,57 @@ type ConfigData struct {
 	Accounts   []AccountItem  `json:"accounts"`
 }
 
+// configFiles are the files LoadConfig reads from the config directory
+var configFiles = map[string]bool{
+	"categories.json": true,
+	"colors.json":     true,
+	"accounts.json":   true,
+}
+
 // ConfigLoader handles loading configuration from JSON files
 type ConfigLoader struct {
-	config ConfigData
+	mu             sync.RWMutex
+	config         ConfigData
+	configDir      string
+	watcher        *fsnotify.Watcher
+	categorySource CategorySource
 }
 
+var (
+	defaultLoader     *ConfigLoader
+	defaultLoaderErr  error
+	defaultLoaderOnce sync.Once
+)
+
 // NewConfigLoader creates a new config loader
 func NewConfigLoader() *ConfigLoader {
 	return &ConfigLoader{}
 }
 
+// DefaultLoader returns the config loader shared by the server. The first call loads the config files
+// and starts watching them, so edits to categories, colors or accounts apply without a restart.
+func DefaultLoader() *ConfigLoader {
+	defaultLoaderOnce.Do(func() {
+		defaultLoader = NewConfigLoader()
+		if err := defaultLoader.LoadConfig(); err != nil {
+			// If config loading fails, continue with empty config
+			defaultLoaderErr = err
+			fmt.Printf("Warning: Failed to load config: %v\n", err)
+		}
+		if err := defaultLoader.Watch(); err != nil {
+			fmt.Printf("Warning: Failed to watch config files: %v\n", err)
+		}
+	})
+	return defaultLoader
+}
+
+// LoadDefault returns the shared config loader like DefaultLoader, along with the error that kept the
+// config files from loading, for callers that cannot work with an empty config
+func LoadDefault() (*ConfigLoader, error) {
+	loader := DefaultLoader()
+	return loader, defaultLoaderErr
+}
+
 // LoadConfig loads configuration from JSON files
 func (cl *ConfigLoader) LoadConfig() error {
 	// Get the directory where the executable is located
@@ -67,48 +123,148 @@ func (cl *ConfigLoader) LoadConfig() error {
 		configDir = "config"
 	}
 
+	return cl.LoadConfigFrom(configDir)
+}
+
+// LoadConfigFrom loads configuration from the JSON files in configDir
+func (cl *ConfigLoader) LoadConfigFrom(configDir string) error {
+	cl.mu.Lock()
+	cl.configDir = configDir
+	cl.mu.Unlock()
+
+	return cl.ReloadConfig()
+}
+
+// ReloadConfig re-reads the config files. If any file cannot be read or parsed the current
+// configuration is kept.
+func (cl *ConfigLoader) ReloadConfig() error {
+	cl.mu.RLock()
+	configDir := cl.configDir
+	cl.mu.RUnlock()
+
 	// Load categories
-	if err := cl.loadCategories(filepath.Join(configDir, "categories.json")); err != nil {
+	categories, err := loadCategories(filepath.Join(configDir, "categories.json"))
+	if err != nil {
 		return fmt.Errorf("failed to load categories: %v", err)
 	}
 
 	// Load colors
-	if err := cl.loadColors(filepath.Join(configDir, "colors.json")); err != nil {
+	colors, err := loadColors(filepath.Join(configDir, "colors.json"))
+	if err != nil {
 		return fmt.Errorf("failed to load colors: %v", err)
 	}
 
 	// Load accounts
-	if err := cl.loadAccounts(filepath.Join(configDir, "accounts.json")); err != nil {
+	accounts, err := loadAccounts(filepath.Join(configDir, "accounts.json"))
+	if err != nil {
 		return fmt.Errorf("failed to load accounts: %v", err)
 	}
 
+	cl.mu.Lock()
+	cl.config = ConfigData{
+		Categories: categories,
+		Colors:     colors,
+		Accounts:   accounts,
+	}
+	cl.mu.Unlock()
 	return nil
 }
 
-// loadCategories loads categories from JSON file
-func (cl *ConfigLoader) loadCategories(filename string) error {
-	data, err := ioutil.ReadFile(filename)
+// Watch reloads the configuration whenever one of the config files changes on disk.
+// The directory is watched rather than the files, so editors that save by replacing the file are seen too.
+func (cl *ConfigLoader) Watch() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.watcher != nil {
+		return nil
+	}
+	if cl.configDir == "" {
+		return errors.New("config has not been loaded")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
+	if err := watcher.Add(cl.configDir); err != nil {
+		watcher.Close()
+		return err
+	}
+	cl.watcher = watcher
+
+	go cl.watch(watcher)
+	return nil
+}
+
+// watch handles file events until the watcher is closed
+func (cl *ConfigLoader) watch(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !configFiles[filepath.Base(event.Name)] || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			if err := cl.ReloadConfig(); err != nil {
+				fmt.Printf("Warning: Failed to reload config after %s changed: %v\n", filepath.Base(event.Name), err)
+				continue
+			}
+			log.Printf("Reloaded config after %s changed", filepath.Base(event.Name))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Warning: Config watcher error: %v\n", err)
+		}
+	}
+}
+
+// Close stops watching the config files
+func (cl *ConfigLoader) Close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.watcher == nil {
+		return nil
+	}
+	err := cl.watcher.Close()
+	cl.watcher = nil
+	return err
+}
+
+// loadCategories loads categories from JSON file
+func loadCategories(filename string) ([]CategoryItem, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
 
 	var categories struct {
-		Categories []CategoryItem `json:"categories"`
+		Categories []json.RawMessage `json:"categories"`
 	}
 
 	if err := json.Unmarshal(data, &categories); err != nil {
-		return err
+		return nil, err
 	}
 
-	cl.config.Categories = categories.Categories
-	return nil
+	items := make([]CategoryItem, len(categories.Categories))
+	for i, raw := range categories.Categories {
+		items[i].IsActive = true
+		if err := json.Unmarshal(raw, &items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
 }
 
 // loadColors loads colors from JSON file
-func (cl *ConfigLoader) loadColors(filename string) error {
-	data, err := ioutil.ReadFile(filename)
+func loadColors(filename string) ([]ColorItem, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var colors struct {
@@ -116,46 +272,86 @@ func (cl *ConfigLoader) loadColors(filename string) error {
 	}
 
 	if err := json.Unmarshal(data, &colors); err != nil {
-		return err
+		return nil, err
 	}
 
-	cl.config.Colors = colors.Colors
-	return nil
+	return colors.Colors, nil
 }
 
 // loadAccounts loads accounts from JSON file
-func (cl *ConfigLoader) loadAccounts(filename string) error {
-	data, err := ioutil.ReadFile(filename)
+func loadAccounts(filename string) ([]AccountItem, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var accounts []AccountItem
 	if err := json.Unmarshal(data, &accounts); err != nil {
-		return err
+		return nil, err
 	}
 
-	cl.config.Accounts = accounts
-	return nil
+	return accounts, nil
+}
+
+// SetCategorySource makes GetCategories read the categories from source instead of categories.json,
+// which is still used whenever source fails
+func (cl *ConfigLoader) SetCategorySource(source CategorySource) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.categorySource = source
 }
 
-// GetCategories returns all categories
+// GetCategories returns all categories, active or not
 func (cl *ConfigLoader) GetCategories() []CategoryItem {
-	return cl.config.Categories
+	cl.mu.RLock()
+	source := cl.categorySource
+	fileCategories := cl.config.Categories
+	cl.mu.RUnlock()
+
+	if source == nil {
+		return fileCategories
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), categorySourceTimeout)
+	defer cancel()
+	categories, err := source.ConfigCategories(ctx)
+	if err != nil {
+		fmt.Printf("Warning: Failed to read categories, using categories.json: %v\n", err)
+		return fileCategories
+	}
+	return categories
+}
+
+// GetActiveCategories returns only active categories
+func (cl *ConfigLoader) GetActiveCategories() []CategoryItem {
+	var activeCategories []CategoryItem
+	for _, category := range cl.GetCategories() {
+		if category.IsActive {
+			activeCategories = append(activeCategories, category)
+		}
+	}
+	return activeCategories
 }
 
 // GetColors returns all colors
 func (cl *ConfigLoader) GetColors() []ColorItem {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
 	return cl.config.Colors
 }
 
 // GetAccounts returns all accounts
 func (cl *ConfigLoader) GetAccounts() []AccountItem {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
 	return cl.config.Accounts
 }
 
 // GetActiveAccounts returns only active accounts
 func (cl *ConfigLoader) GetActiveAccounts() []AccountItem {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
 	var activeAccounts []AccountItem
 	for _, account := range cl.config.Accounts {
 		if account.IsActive {
@@ -169,7 +365,7 @@ func (cl *ConfigLoader) GetActiveAccounts() []AccountItem {
 func (cl *ConfigLoader) GetCategoryAbbreviation(categoryName string) string {
 	categoryLower := strings.ToLower(categoryName)
 
-	for _, category := range cl.config.Categories {
+	for _, category := range cl.GetCategories() {
 		if strings.ToLower(category.Name) == categoryLower ||
 			strings.ToLower(category.English) == categoryLower {
 			return category.Abbreviation
@@ -201,7 +397,7 @@ func (cl *ConfigLoader) GetCategoryAbbreviation(categoryName string) string {
 func (cl *ConfigLoader) GetColorAbbreviation(colorName string) string {
 	colorLower := strings.ToLower(colorName)
 
-	for _, color := range cl.config.Colors {
+	for _, color := range cl.GetColors() {
 		if strings.ToLower(color.Name) == colorLower ||
 			strings.ToLower(color.English) == colorLower {
 			return color.Abbreviation
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to name in dir
+func writeConfigFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testConfigDir returns a directory holding config files with the given categories
+func testConfigDir(t *testing.T, categories string) string {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "categories.json", categories)
+	writeConfigFile(t, dir, "colors.json", `{"colors": [{"name": "ขาว", "abbreviation": "WH", "english": "white"}]}`)
+	writeConfigFile(t, dir, "accounts.json", `[{"id": "acc1", "name": "Main", "isActive": true}]`)
+	return dir
+}
+
+func categoryNames(cl *ConfigLoader) []string {
+	var names []string
+	for _, category := range cl.GetCategories() {
+		names = append(names, category.Name)
+	}
+	return names
+}
+
+func TestWatchReloadsChangedCategories(t *testing.T) {
+	dir := testConfigDir(t, `{"categories": [{"name": "ขวด", "abbreviation": "BT", "english": "bottle"}]}`)
+	cl := NewConfigLoader()
+	if err := cl.LoadConfigFrom(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	writeConfigFile(t, dir, "categories.json", `{"categories": [
+		{"name": "ขวด", "abbreviation": "BT", "english": "bottle"},
+		{"name": "ฝา", "abbreviation": "CP", "english": "cap"}
+	]}`)
+
+	want := []string{"ขวด", "ฝา"}
+	deadline := time.Now().Add(5 * time.Second)
+	for !reflect.DeepEqual(categoryNames(cl), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("categories = %q, want %q after the file changed", categoryNames(cl), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir := testConfigDir(t, `{"categories": [{"name": "ขวด", "abbreviation": "BT", "english": "bottle"}]}`)
+	cl := NewConfigLoader()
+	if err := cl.LoadConfigFrom(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfigFile(t, dir, "categories.json", `{"categories": [{"name": "ฝา", "abbreviation": "CP", "english": "cap", "isActive": false}]}`)
+	if err := cl.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := categoryNames(cl); !reflect.DeepEqual(got, []string{"ฝา"}) {
+		t.Errorf("categories = %q, want the reloaded file", got)
+	}
+	if active := cl.GetActiveCategories(); len(active) != 0 {
+		t.Errorf("active categories = %v, want none", active)
+	}
+
+	// A file that no longer parses keeps the configuration already loaded
+	writeConfigFile(t, dir, "categories.json", `{"categories": [`)
+	if err := cl.ReloadConfig(); err == nil {
+		t.Error("ReloadConfig accepted invalid JSON")
+	}
+	if got := categoryNames(cl); !reflect.DeepEqual(got, []string{"ฝา"}) {
+		t.Errorf("categories = %q after a failed reload, want them unchanged", got)
+	}
+	if colors := cl.GetColors(); len(colors) != 1 || colors[0].Abbreviation != "WH" {
+		t.Errorf("colors = %v after a failed reload, want them unchanged", colors)
+	}
+}
+
+func TestWatchRequiresLoadedConfig(t *testing.T) {
+	if err := NewConfigLoader().Watch(); err == nil {
+		t.Error("Watch succeeded before the config was loaded")
+	}
+}
+
+// stubCategorySource serves categories, or fails with err when it is set
+type stubCategorySource struct {
+	categories []CategoryItem
+	err        error
+}
+
+func (s *stubCategorySource) ConfigCategories(ctx context.Context) ([]CategoryItem, error) {
+	return s.categories, s.err
+}
+
+func TestGetCategoriesReadsTheCategorySource(t *testing.T) {
+	dir := testConfigDir(t, `{"categories": [{"name": "ขวด", "abbreviation": "BT", "english": "bottle"}]}`)
+	cl := NewConfigLoader()
+	if err := cl.LoadConfigFrom(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &stubCategorySource{categories: []CategoryItem{{Name: "ถาด", Abbreviation: "TR", English: "tray", IsActive: true}}}
+	cl.SetCategorySource(source)
+	if got, want := categoryNames(cl), []string{"ถาด"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("categories = %q, want %q from the source", got, want)
+	}
+
+	// Categories added to the source apply without a reload
+	source.categories = append(source.categories, CategoryItem{Name: "ถุง", Abbreviation: "BG", English: "bag", IsActive: true})
+	if got := cl.GetCategoryAbbreviation("bag"); got != "BG" {
+		t.Errorf("GetCategoryAbbreviation(bag) = %s, want BG", got)
+	}
+
+	source.err = errors.New("database unavailable")
+	if got, want := categoryNames(cl), []string{"ขวด"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("categories = %q while the source fails, want %q from categories.json", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"", 0.07},
+		{"0.1", 0.1},
+		{"0", 0.07},
+		{"7", 0.07}, // a percentage, not a rate
+		{"abc", 0.07},
+	}
+	for _, tt := range tests {
+		t.Setenv("VAT_RATE", tt.value)
+		if got := getEnvRate("VAT_RATE", 0.07); got != tt.want {
+			t.Errorf("VAT_RATE=%q: rate = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{"*"}},
+		{" , ", []string{"*"}},
+		{"https://app.goodpack.co.th", []string{"https://app.goodpack.co.th"}},
+		{"https://app.goodpack.co.th, http://localhost:3000,", []string{"https://app.goodpack.co.th", "http://localhost:3000"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("CORS_ALLOWED_ORIGINS", tt.value)
+		if got := getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CORS_ALLOWED_ORIGINS=%q: origins = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
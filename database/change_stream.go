@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+// productStock is the part of a product document the inventory change stream reads
+type productStock struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	SKUID string             `bson:"skuId"`
+	Stock struct {
+		ActualStock int `bson:"actualStock"`
+	} `bson:"stock"`
+}
+
+type productChange struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	FullDocument  *productStock       `bson:"fullDocument"`
+}
+
+// WatchInventory opens a change stream on the products collection and sends an InventoryEvent to eventCh
+// whenever a product's stock.actualStock changes. It blocks until ctx is cancelled (returning nil) or the
+// stream fails. Change streams need MongoDB to run as a replica set.
+//
+// Products are updated by replacing the whole document, so replace operations are watched along with updates.
+// The stock before each change is kept in memory, starting from the stock of every product when the stream
+// opens, so no pre-images are needed on the collection.
+func WatchInventory(ctx context.Context, db *mongo.Database, eventCh chan<- models.InventoryEvent) error {
+	collection := db.Collection("products")
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}}}}},
+		{{Key: "$project", Value: bson.M{
+			"operationType":                  1,
+			"clusterTime":                    1,
+			"fullDocument._id":               1,
+			"fullDocument.skuId":             1,
+			"fullDocument.stock.actualStock": 1,
+		}}},
+	}
+	stream, err := collection.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return fmt.Errorf("failed to open products change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	// Opened before reading the current stock, so no change between the two is missed
+	stock, err := currentStock(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	for stream.Next(ctx) {
+		var change productChange
+		if err := stream.Decode(&change); err != nil {
+			return fmt.Errorf("failed to decode product change: %w", err)
+		}
+		if change.FullDocument == nil {
+			continue // Removed before the lookup
+		}
+
+		productID := change.FullDocument.ID.Hex()
+		newStock := change.FullDocument.Stock.ActualStock
+		previousStock, known := stock[productID]
+		stock[productID] = newStock
+		if change.OperationType == "insert" || !known || previousStock == newStock {
+			continue
+		}
+
+		event := models.InventoryEvent{
+			ProductID:     productID,
+			SKUID:         change.FullDocument.SKUID,
+			PreviousStock: previousStock,
+			NewStock:      newStock,
+			Timestamp:     time.Unix(int64(change.ClusterTime.T), 0),
+		}
+		select {
+		case eventCh <- event:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if err := stream.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("products change stream failed: %w", err)
+	}
+	return nil
+}
+
+// currentStock reads the actual stock of every product, keyed by product ID
+func currentStock(ctx context.Context, collection *mongo.Collection) (map[string]int, error) {
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"skuId": 1, "stock.actualStock": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product stock: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var products []productStock
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("failed to read product stock: %w", err)
+	}
+
+	stock := make(map[string]int, len(products))
+	for _, product := range products {
+		stock[product.ID.Hex()] = product.Stock.ActualStock
+	}
+	return stock, nil
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditLogTTL sets how long audit logs are kept before MongoDB removes them.
+// Zero keeps audit logs forever. It must be set before EnsureIndexes is called.
+var AuditLogTTL time.Duration
+
+// EnsureIndexes creates the indexes used by the repositories.
+// It is safe to call on every startup: existing indexes with the same keys and options are left untouched.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	auditLogCreatedAt := options.Index()
+	if AuditLogTTL > 0 {
+		auditLogCreatedAt.SetExpireAfterSeconds(int32(AuditLogTTL.Seconds()))
+	}
+
+	indexes := map[string][]mongo.IndexModel{
+		"products": {
+			{Keys: bson.D{{Key: "skuId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "code", Value: 1}}},
+			{Keys: bson.D{{Key: "category", Value: 1}, {Key: "name", Value: 1}}},
+			{Keys: bson.D{{Key: "isDeleted", Value: 1}}},
+			{Keys: bson.D{{Key: "velocityClass", Value: 1}}},
+			{Keys: bson.D{{Key: "popularityScore", Value: -1}}},
+			// Language "none" skips English stemming and stop words; Thai is not split into words, so product
+			// search falls back to regex when the text search finds nothing
+			{
+				Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+				Options: options.Index().SetName("products_text").SetDefaultLanguage("none"),
+			},
+		},
+		"customers": {
+			{Keys: bson.D{{Key: "customerCode", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+			{Keys: bson.D{{Key: "isDeleted", Value: 1}}},
+		},
+		"suppliers": {
+			{Keys: bson.D{{Key: "supplierCode", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"purchases": {
+			{Keys: bson.D{{Key: "purchaseCode", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "customerId", Value: 1}}},
+			{Keys: bson.D{{Key: "supplierId", Value: 1}}},
+			{Keys: bson.D{{Key: "items.productId", Value: 1}, {Key: "purchaseDate", Value: -1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isDeleted", Value: 1}}},
+			{Keys: bson.D{{Key: "approvalStatus", Value: 1}}},
+		},
+		"sales": {
+			{Keys: bson.D{{Key: "saleCode", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "customerId", Value: 1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "isDeleted", Value: 1}}},
+			{Keys: bson.D{{Key: "deliveryStatus", Value: 1}}},
+		},
+		"sale_returns": {
+			{Keys: bson.D{{Key: "returnCode", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "originalSaleId", Value: 1}}},
+			{Keys: bson.D{{Key: "returnDate", Value: -1}}},
+		},
+		"migrations": {
+			{Keys: bson.D{{Key: "transactionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"categories": {
+			{Keys: bson.D{{Key: "abbreviation", Value: 1}}},
+		},
+		"quotations": {
+			{Keys: bson.D{{Key: "quotationCode", Value: 1}}},
+			{Keys: bson.D{{Key: "customerId", Value: 1}}},
+			{Keys: bson.D{{Key: "quotationDate", Value: -1}}},
+		},
+		"stock_adjustments": {
+			{Keys: bson.D{{Key: "productId", Value: 1}, {Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "sourceType", Value: 1}, {Key: "sourceId", Value: 1}}},
+		},
+		"stock_counts": {
+			{Keys: bson.D{{Key: "countId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "startedAt", Value: -1}}},
+		},
+		"budgets": {
+			{Keys: bson.D{{Key: "period", Value: 1}}},
+		},
+		"bundles": {
+			{Keys: bson.D{{Key: "name", Value: 1}}},
+		},
+		"serial_numbers": {
+			{Keys: bson.D{{Key: "productId", Value: 1}, {Key: "serialNumber", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "saleId", Value: 1}}},
+		},
+		"lots": {
+			{Keys: bson.D{{Key: "productId", Value: 1}, {Key: "lotNumber", Value: 1}, {Key: "purchaseId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expiryDate", Value: 1}}},
+		},
+		"customer_notes": {
+			{Keys: bson.D{{Key: "customerId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		},
+		"exchange_rates": {
+			{Keys: bson.D{{Key: "currency", Value: 1}, {Key: "date", Value: -1}}, Options: options.Index().SetUnique(true)},
+		},
+		"supplier_invoices": {
+			{Keys: bson.D{{Key: "supplierId", Value: 1}, {Key: "invoiceNumber", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "invoiceDate", Value: -1}}},
+			{Keys: bson.D{{Key: "matchedPurchaseIds", Value: 1}}},
+		},
+		"recurring_orders": {
+			{Keys: bson.D{{Key: "isActive", Value: 1}, {Key: "nextRunAt", Value: 1}}},
+			{Keys: bson.D{{Key: "customerId", Value: 1}}},
+		},
+		"webhooks": {
+			{Keys: bson.D{{Key: "events", Value: 1}}},
+		},
+		"webhook_deliveries": {
+			{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		},
+		"audit_logs": {
+			{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: auditLogCreatedAt},
+			{Keys: bson.D{{Key: "entityType", Value: 1}, {Key: "entityId", Value: 1}}},
+		},
+	}
+
+	// Keep going past a collection that fails, so one bad index does not leave the others missing
+	collections := make([]string, 0, len(indexes))
+	for collection := range indexes {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	var errs []error
+	for _, collection := range collections {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes[collection]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create indexes on %s: %w", collection, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
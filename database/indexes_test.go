@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase returns a database of its own on the MongoDB at MONGODB_TEST_URI, dropped when the test ends.
+// Tests that need MongoDB are skipped when the variable is not set.
+func testDatabase(t *testing.T) *mongo.Database {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database(fmt.Sprintf("goodpack_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+// indexNames lists the names of the indexes on each collection of db
+func indexNames(t *testing.T, db *mongo.Database) map[string][]string {
+	ctx := context.Background()
+	collections, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make(map[string][]string)
+	for _, collection := range collections {
+		specs, err := db.Collection(collection).Indexes().ListSpecifications(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, spec := range specs {
+			names[collection] = append(names[collection], spec.Name)
+		}
+	}
+	return names
+}
+
+func TestEnsureIndexesIsIdempotent(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	if err := EnsureIndexes(ctx, db); err != nil {
+		t.Fatalf("first EnsureIndexes = %v", err)
+	}
+	first := indexNames(t, db)
+	if err := EnsureIndexes(ctx, db); err != nil {
+		t.Fatalf("second EnsureIndexes = %v", err)
+	}
+	if second := indexNames(t, db); !reflect.DeepEqual(first, second) {
+		t.Errorf("indexes after the second run = %v, want them unchanged from %v", second, first)
+	}
+
+	for collection, name := range map[string]string{
+		"products":          "skuId_1",
+		"customers":         "customerCode_1",
+		"purchases":         "purchaseCode_1",
+		"sales":             "saleCode_1",
+		"stock_adjustments": "productId_1_createdAt_-1",
+	} {
+		found := false
+		for _, n := range first[collection] {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("%s has indexes %v, want %s among them", collection, first[collection], name)
+		}
+	}
+}
+
+func TestEnsureIndexesCreatesTheRestWhenOneCollectionFails(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	// An index with the same name but other keys makes the customers indexes fail
+	_, err := db.Collection("customers").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "companyName", Value: 1}},
+		Options: options.Index().SetName("customerCode_1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureIndexes(ctx, db); err == nil {
+		t.Fatal("EnsureIndexes = nil, want the customers error")
+	}
+	names := indexNames(t, db)
+	for _, collection := range []string{"audit_logs", "products", "webhooks"} {
+		if len(names[collection]) < 2 {
+			t.Errorf("%s has indexes %v, want them created despite the customers error", collection, names[collection])
+		}
+	}
+}
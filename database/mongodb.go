@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -14,18 +15,84 @@ type MongoDB struct {
 	Database *mongo.Database
 }
 
-func NewMongoDB(uri, dbName string) (*MongoDB, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// Defaults for connecting to MongoDB
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultMaxRetries     = 10
+	DefaultMaxPoolSize    = 100
+	initialRetryDelay     = 2 * time.Second
+	maxRetryDelay         = 30 * time.Second
+)
+
+type connectConfig struct {
+	connectTimeout time.Duration
+	maxRetries     int
+	maxPoolSize    uint64
+}
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+// MongoDBOption configures how NewMongoDB connects
+type MongoDBOption func(*connectConfig)
+
+// WithConnectTimeout sets how long each attempt to reach the server may take
+func WithConnectTimeout(timeout time.Duration) MongoDBOption {
+	return func(c *connectConfig) {
+		if timeout > 0 {
+			c.connectTimeout = timeout
+		}
+	}
+}
+
+// WithMaxRetries sets how many times the first ping is retried before giving up; 0 fails on the first error
+func WithMaxRetries(retries int) MongoDBOption {
+	return func(c *connectConfig) {
+		if retries >= 0 {
+			c.maxRetries = retries
+		}
+	}
+}
+
+// WithMaxPoolSize sets the largest number of connections the client keeps open to the server
+func WithMaxPoolSize(size int) MongoDBOption {
+	return func(c *connectConfig) {
+		if size > 0 {
+			c.maxPoolSize = uint64(size)
+		}
+	}
+}
+
+// NewMongoDB connects to the server and pings it. A server that is still starting (e.g. started alongside this
+// one by Docker Compose) is waited for: the ping is retried with exponential backoff from 2 seconds.
+func NewMongoDB(uri, dbName string, opts ...MongoDBOption) (*MongoDB, error) {
+	cfg := connectConfig{
+		connectTimeout: DefaultConnectTimeout,
+		maxRetries:     DefaultMaxRetries,
+		maxPoolSize:    DefaultMaxPoolSize,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetConnectTimeout(cfg.connectTimeout).
+		SetServerSelectionTimeout(cfg.connectTimeout).
+		SetMaxPoolSize(cfg.maxPoolSize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.connectTimeout)
+	client, err := mongo.Connect(ctx, clientOptions)
+	cancel()
 	if err != nil {
 		return nil, err
 	}
 
 	// Test the connection
-	err = client.Ping(ctx, nil)
+	err = retryWithBackoff(cfg.maxRetries, initialRetryDelay, time.Sleep, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.connectTimeout)
+		defer cancel()
+		return client.Ping(ctx, nil)
+	})
 	if err != nil {
+		client.Disconnect(context.Background())
 		return nil, err
 	}
 
@@ -39,6 +106,20 @@ func NewMongoDB(uri, dbName string) (*MongoDB, error) {
 	}, nil
 }
 
+// retryWithBackoff calls attempt until it succeeds or has been retried maxRetries times, sleeping delay before
+// the first retry and doubling it (up to maxRetryDelay) before each one after that
+func retryWithBackoff(maxRetries int, delay time.Duration, sleep func(time.Duration), attempt func() error) error {
+	for retry := 1; ; retry++ {
+		err := attempt()
+		if err == nil || retry > maxRetries {
+			return err
+		}
+		log.Printf("MongoDB not reachable (attempt %d of %d): %v; retrying in %s", retry, maxRetries+1, err, delay)
+		sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
 func (m *MongoDB) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -48,3 +129,13 @@ func (m *MongoDB) Close() error {
 func (m *MongoDB) GetCollection(name string) *mongo.Collection {
 	return m.Database.Collection(name)
 }
+
+// Ping checks that the MongoDB server is reachable
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, nil)
+}
+
+// CountDocuments returns the number of documents in a collection
+func (m *MongoDB) CountDocuments(ctx context.Context, collection string) (int64, error) {
+	return m.Database.Collection(collection).CountDocuments(ctx, bson.M{})
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoffDoublesDelayUpToMax(t *testing.T) {
+	var slept []time.Duration
+	calls := 0
+	err := retryWithBackoff(6, 8*time.Second, func(d time.Duration) { slept = append(slept, d) }, func() error {
+		calls++
+		if calls < 6 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("retryWithBackoff = %v, want nil once the server answers", err)
+	}
+
+	want := []time.Duration{8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second, 30 * time.Second}
+	if !reflect.DeepEqual(slept, want) {
+		t.Errorf("slept %v, want %v", slept, want)
+	}
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxRetries(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	calls := 0
+	err := retryWithBackoff(3, time.Second, func(time.Duration) {}, func() error {
+		calls++
+		return errRefused
+	})
+	if !errors.Is(err, errRefused) {
+		t.Errorf("retryWithBackoff = %v, want the last error", err)
+	}
+	if calls != 4 {
+		t.Errorf("attempts = %d, want 4 (the first and 3 retries)", calls)
+	}
+}
+
+func TestRetryWithBackoffWithoutRetries(t *testing.T) {
+	calls := 0
+	retryWithBackoff(0, time.Second, func(time.Duration) { t.Error("slept with no retries allowed") }, func() error {
+		calls++
+		return errors.New("connection refused")
+	})
+	if calls != 1 {
+		t.Errorf("attempts = %d, want 1", calls)
+	}
+}
+
+func TestMongoDBOptionsIgnoreInvalidValues(t *testing.T) {
+	cfg := connectConfig{connectTimeout: DefaultConnectTimeout, maxRetries: DefaultMaxRetries, maxPoolSize: DefaultMaxPoolSize}
+	for _, opt := range []MongoDBOption{WithConnectTimeout(0), WithMaxRetries(-1), WithMaxPoolSize(0)} {
+		opt(&cfg)
+	}
+	want := connectConfig{connectTimeout: DefaultConnectTimeout, maxRetries: DefaultMaxRetries, maxPoolSize: DefaultMaxPoolSize}
+	if cfg != want {
+		t.Errorf("config = %+v, want the defaults kept", cfg)
+	}
+
+	WithMaxRetries(0)(&cfg)
+	WithMaxPoolSize(20)(&cfg)
+	if cfg.maxRetries != 0 || cfg.maxPoolSize != 20 {
+		t.Errorf("config = %+v, want 0 retries and a pool of 20", cfg)
+	}
+}
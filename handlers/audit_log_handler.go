@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type AuditLogHandler struct {
+	auditRepo *repository.AuditLogRepository
+}
+
+func NewAuditLogHandler(auditRepo *repository.AuditLogRepository) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// GetAuditLogs lists audit logs filtered by entity type, entity ID and date range
+func (h *AuditLogHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	filter := models.AuditLogFilter{
+		EntityType: query.Get("entityType"),
+		EntityID:   query.Get("entityId"),
+	}
+
+	if startDateStr := query.Get("startDate"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_start_date"))
+			return
+		}
+		filter.StartDate = parsed
+	}
+	if endDateStr := query.Get("endDate"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_end_date"))
+			return
+		}
+		// Set to end of day
+		filter.EndDate = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	// Get limit and skip from query parameters
+	limit := 50
+	skip := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+	if skipStr := query.Get("skip"); skipStr != "" {
+		if parsedSkip, err := strconv.Atoi(skipStr); err == nil && parsedSkip >= 0 {
+			skip = parsedSkip
+		}
+	}
+
+	logs, err := h.auditRepo.GetAll(r.Context(), filter, limit, skip)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "audit_logs_fetch_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(logs)
+}
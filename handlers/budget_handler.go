@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type BudgetHandler struct {
+	budgetRepo   *repository.BudgetRepository
+	reportRepo   *repository.ReportRepository
+	supplierRepo *repository.SupplierRepository
+}
+
+func NewBudgetHandler(budgetRepo *repository.BudgetRepository, reportRepo *repository.ReportRepository, supplierRepo *repository.SupplierRepository) *BudgetHandler {
+	return &BudgetHandler{
+		budgetRepo:   budgetRepo,
+		reportRepo:   reportRepo,
+		supplierRepo: supplierRepo,
+	}
+}
+
+// validBudgetPeriod reports whether period is a YYYY-MM month
+func validBudgetPeriod(period string) bool {
+	_, err := time.Parse(models.BudgetPeriodFormat, period)
+	return err == nil
+}
+
+// GetBudgets lists the budgets, optionally only those of ?period=YYYY-MM
+func (h *BudgetHandler) GetBudgets(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period != "" && !validBudgetPeriod(period) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_budget_period"))
+		return
+	}
+
+	budgets, err := h.budgetRepo.GetAll(r.Context(), period)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budgets_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+func (h *BudgetHandler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	budget, err := h.budgetRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "budget_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var budgetRequest models.BudgetRequest
+	if !h.decodeBudgetRequest(w, r, &budgetRequest) {
+		return
+	}
+
+	budget := budgetRequest.ToBudget()
+	if err := h.budgetRepo.Create(r.Context(), budget); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budget_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var budgetRequest models.BudgetRequest
+	if !h.decodeBudgetRequest(w, r, &budgetRequest) {
+		return
+	}
+
+	budget, err := h.budgetRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "budget_not_found"))
+		return
+	}
+
+	budget.UpdateFromRequest(&budgetRequest)
+	if err := h.budgetRepo.Update(r.Context(), id, budget); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budget_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+func (h *BudgetHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.budgetRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budget_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeBudgetRequest decodes and validates a budget body, writing the error response when it is invalid
+func (h *BudgetHandler) decodeBudgetRequest(w http.ResponseWriter, r *http.Request, budgetRequest *models.BudgetRequest) bool {
+	if err := json.NewDecoder(r.Body).Decode(budgetRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return false
+	}
+	if !validateRequest(w, r, budgetRequest) {
+		return false
+	}
+	if !validBudgetPeriod(budgetRequest.Period) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_budget_period"))
+		return false
+	}
+	return true
+}
+
+// GetBudgetVariance compares a month's purchase budgets with the actual purchase cost (line totals after
+// discounts, excluding VAT and shipping), per product category or, with groupBy=supplier, per supplier
+func (h *BudgetHandler) GetBudgetVariance(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	period := query.Get("period")
+	from, err := time.Parse(models.BudgetPeriodFormat, period)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_budget_period"))
+		return
+	}
+	to := from.AddDate(0, 1, 0).Add(-time.Nanosecond)
+
+	groupBy := strings.ToLower(query.Get("groupBy"))
+	if groupBy == "" {
+		groupBy = models.BudgetGroupByCategory
+	}
+	if groupBy != models.BudgetGroupByCategory && groupBy != models.BudgetGroupBySupplier {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_budget_group_by"))
+		return
+	}
+
+	budgets, err := h.budgetRepo.GetAll(r.Context(), period)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budgets_fetch_failed"))
+		return
+	}
+
+	var spending []models.PurchaseSpend
+	supplierNames := map[string]string{}
+	if groupBy == models.BudgetGroupBySupplier {
+		spending, err = h.reportRepo.GetPurchaseSpendBySupplier(r.Context(), from, to)
+		if err == nil {
+			supplierNames, err = h.supplierNames(r)
+		}
+	} else {
+		spending, err = h.reportRepo.GetPurchaseSpendByCategory(r.Context(), from, to)
+	}
+	if err != nil {
+		fmt.Printf("Error computing budget variance: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "budget_variance_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewBudgetVarianceReport(period, groupBy, budgets, spending, supplierNames))
+}
+
+// supplierNames maps supplier IDs to company names, for suppliers with a budget but no purchases
+func (h *BudgetHandler) supplierNames(r *http.Request) (map[string]string, error) {
+	suppliers, err := h.supplierRepo.GetAll(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(suppliers))
+	for _, supplier := range suppliers {
+		names[supplier.ID.Hex()] = supplier.CompanyName
+	}
+	return names, nil
+}
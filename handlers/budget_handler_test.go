@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+func TestGetBudgetVariance(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := NewBudgetHandler(
+		repository.NewBudgetRepository(db.Collection("budgets")),
+		reportRepoHandler(db).reportRepo,
+		repository.NewSupplierRepository(db.Collection("suppliers")),
+	)
+
+	box := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	wrap := &models.Product{ID: primitive.NewObjectID(), SKUID: "WRP-0001", Name: "Bubble Wrap", Category: "Wrap"}
+	for _, product := range []*models.Product{box, wrap} {
+		if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, body := range []string{
+		`{"period": "2024-01", "budgetAmount": 10000}`,
+		`{"period": "2024-01", "category": "Box", "budgetAmount": 5000}`,
+		`{"period": "2024-01", "category": "Wrap", "budgetAmount": 2000}`,
+		`{"period": "2024-02", "category": "Box", "budgetAmount": 99999}`,
+	} {
+		rec := httptest.NewRecorder()
+		h.CreateBudget(rec, httptest.NewRequest(http.MethodPost, "/api/budgets", strings.NewReader(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, want %d", body, rec.Code, http.StatusCreated)
+		}
+	}
+
+	line := func(product *models.Product, totalPrice float64) bson.M {
+		return bson.M{"productId": product.ID.Hex(), "quantity": 1, "totalPrice": totalPrice}
+	}
+	january := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	if _, err := db.Collection("purchases").InsertMany(ctx, []interface{}{
+		bson.M{"purchaseDate": january, "items": bson.A{line(box, 4000), line(wrap, 1500)}},
+		bson.M{"purchaseDate": january.AddDate(0, 0, 10), "items": bson.A{line(box, 2000)}},
+		bson.M{"purchaseDate": january.AddDate(0, 1, 0), "items": bson.A{line(box, 7777)}}, // February
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetBudgetVariance(rec, httptest.NewRequest(http.MethodGet, "/api/reports/budget-variance?period=2024-01", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var report models.BudgetVarianceReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		key                      string
+		budget, actual, variance float64
+		variancePercent          float64
+	}{
+		{"Box", 5000, 6000, -1000, -20},
+		{"Wrap", 2000, 1500, 500, 25},
+	}
+	if len(report.Lines) != len(want) {
+		t.Fatalf("lines = %+v, want %d", report.Lines, len(want))
+	}
+	for i, w := range want {
+		got := report.Lines[i]
+		if got.Key != w.key || got.BudgetAmount != w.budget || got.ActualAmount != w.actual || got.Variance != w.variance ||
+			got.VariancePercent == nil || *got.VariancePercent != w.variancePercent {
+			t.Errorf("line %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if total := report.Total; total.BudgetAmount != 10000 || total.ActualAmount != 7500 || total.Variance != 2500 {
+		t.Errorf("total = %+v, want 10000 budgeted, 7500 spent", total)
+	}
+}
+
+func TestBudgetCRUD(t *testing.T) {
+	db := testDatabase(t)
+	h := NewBudgetHandler(repository.NewBudgetRepository(db.Collection("budgets")), nil, nil)
+
+	send := func(handler http.HandlerFunc, method, body, id string, result interface{}) int {
+		req := mux.SetURLVars(httptest.NewRequest(method, "/api/budgets/"+id, strings.NewReader(body)), map[string]string{"id": id})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if result != nil && rec.Code < 300 {
+			if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code
+	}
+
+	var budget models.Budget
+	if status := send(h.CreateBudget, http.MethodPost, `{"period": "2024-01", "category": "Box", "budgetAmount": 5000}`, "", &budget); status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	id := budget.ID.Hex()
+
+	if status := send(h.UpdateBudget, http.MethodPut, `{"period": "2024-01", "category": "Box", "budgetAmount": 6500}`, id, &budget); status != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d", status, http.StatusOK)
+	}
+	if status := send(h.GetBudget, http.MethodGet, "", id, &budget); status != http.StatusOK || budget.BudgetAmount != 6500 {
+		t.Errorf("get: status %d with amount %v, want 200 with 6500", status, budget.BudgetAmount)
+	}
+	if status := send(h.DeleteBudget, http.MethodDelete, "", id, nil); status != http.StatusNoContent {
+		t.Errorf("delete: status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status := send(h.GetBudget, http.MethodGet, "", id, nil); status != http.StatusNotFound {
+		t.Errorf("get after delete: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestBudgetRequestsRejectInvalidPeriods(t *testing.T) {
+	h := &BudgetHandler{}
+	for _, body := range []string{
+		`{"period": "2024-13", "budgetAmount": 100}`,
+		`{"period": "January", "budgetAmount": 100}`,
+		`{"period": "2024-01", "budgetAmount": -1}`,
+		`{"period": "2024-01", "category": "Box", "supplierId": "s1", "budgetAmount": 100}`,
+	} {
+		rec := httptest.NewRecorder()
+		h.CreateBudget(rec, httptest.NewRequest(http.MethodPost, "/api/budgets", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("create %s: status = %d, want %d", body, rec.Code, http.StatusBadRequest)
+		}
+	}
+	for _, query := range []string{"", "period=2024-1", "period=2024-01&groupBy=product"} {
+		rec := httptest.NewRecorder()
+		h.GetBudgetVariance(rec, httptest.NewRequest(http.MethodGet, "/api/reports/budget-variance?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("variance %q: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type BundleHandler struct {
+	bundleRepo  *repository.BundleRepository
+	productRepo *repository.ProductRepository
+}
+
+func NewBundleHandler(bundleRepo *repository.BundleRepository, productRepo *repository.ProductRepository) *BundleHandler {
+	return &BundleHandler{
+		bundleRepo:  bundleRepo,
+		productRepo: productRepo,
+	}
+}
+
+func (h *BundleHandler) GetBundles(w http.ResponseWriter, r *http.Request) {
+	bundles, err := h.bundleRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "bundles_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundles)
+}
+
+func (h *BundleHandler) GetBundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	bundle, err := h.bundleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "bundle_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundle)
+}
+
+func (h *BundleHandler) CreateBundle(w http.ResponseWriter, r *http.Request) {
+	var bundleRequest models.ProductBundleRequest
+	if !h.decodeBundleRequest(w, r, &bundleRequest) {
+		return
+	}
+
+	bundle := bundleRequest.ToProductBundle()
+	if err := h.bundleRepo.Create(r.Context(), bundle); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "bundle_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bundle)
+}
+
+func (h *BundleHandler) UpdateBundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var bundleRequest models.ProductBundleRequest
+	if !h.decodeBundleRequest(w, r, &bundleRequest) {
+		return
+	}
+
+	bundle, err := h.bundleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "bundle_not_found"))
+		return
+	}
+
+	bundle.UpdateFromRequest(&bundleRequest)
+	if err := h.bundleRepo.Update(r.Context(), id, bundle); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "bundle_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundle)
+}
+
+func (h *BundleHandler) DeleteBundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.bundleRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "bundle_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBundleAvailability reports whether every component has the stock for ?quantity= bundles (default 1)
+// and how many bundles the current stock allows
+func (h *BundleHandler) GetBundleAvailability(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	quantity := 1
+	if quantityStr := r.URL.Query().Get("quantity"); quantityStr != "" {
+		parsed, err := strconv.Atoi(quantityStr)
+		if err != nil || parsed < 1 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_bundle_quantity"))
+			return
+		}
+		quantity = parsed
+	}
+
+	bundle, err := h.bundleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "bundle_not_found"))
+		return
+	}
+
+	// A component whose product was deleted counts as out of stock
+	stock := make(map[string]int, len(bundle.Components))
+	names := make(map[string]string, len(bundle.Components))
+	for _, component := range bundle.Components {
+		product, err := h.productRepo.GetByID(r.Context(), component.ProductID)
+		if err != nil {
+			continue
+		}
+		stock[component.ProductID] = product.GetTotalStock()
+		names[component.ProductID] = product.Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bundle.CheckAvailability(quantity, stock, names))
+}
+
+// decodeBundleRequest decodes and validates a bundle body, writing the error response when it is invalid.
+// Every component must be an existing product.
+func (h *BundleHandler) decodeBundleRequest(w http.ResponseWriter, r *http.Request, bundleRequest *models.ProductBundleRequest) bool {
+	if err := json.NewDecoder(r.Body).Decode(bundleRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return false
+	}
+	if !validateRequest(w, r, bundleRequest) {
+		return false
+	}
+
+	for _, component := range bundleRequest.Components {
+		if _, err := h.productRepo.GetByID(r.Context(), component.ProductID); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", component.ProductID))
+			return false
+		}
+	}
+	return true
+}
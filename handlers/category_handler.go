@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/utils"
+)
+
+type CategoryHandler struct {
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewCategoryHandler(categoryRepo *repository.CategoryRepository) *CategoryHandler {
+	return &CategoryHandler{
+		categoryRepo: categoryRepo,
+	}
+}
+
+// GetCategories lists every category, active or not
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "categories_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	category, err := h.categoryRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "category_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var categoryRequest models.CategoryRequest
+	if !h.decodeCategoryRequest(w, r, "", &categoryRequest) {
+		return
+	}
+
+	category := categoryRequest.ToCategory()
+	if err := h.categoryRepo.Create(r.Context(), category); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "category_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var categoryRequest models.CategoryRequest
+	if !h.decodeCategoryRequest(w, r, id, &categoryRequest) {
+		return
+	}
+
+	category, err := h.categoryRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "category_not_found"))
+		return
+	}
+
+	category.UpdateFromRequest(&categoryRequest)
+	if err := h.categoryRepo.Update(r.Context(), id, category); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "category_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.categoryRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "category_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeCategoryRequest decodes and validates a category body, writing the error response when it is
+// invalid. The name and abbreviation must not be used by another category than the one with id.
+func (h *CategoryHandler) decodeCategoryRequest(w http.ResponseWriter, r *http.Request, id string, categoryRequest *models.CategoryRequest) bool {
+	if err := json.NewDecoder(r.Body).Decode(categoryRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return false
+	}
+	categoryRequest.Normalize()
+	if !validateRequest(w, r, categoryRequest) {
+		return false
+	}
+	if err := utils.ValidateCategoryAbbreviation(categoryRequest.Abbreviation); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_category_abbreviation"))
+		return false
+	}
+
+	categories, err := h.categoryRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "categories_fetch_failed"))
+		return false
+	}
+	for _, category := range categories {
+		if category.ID.Hex() == id {
+			continue
+		}
+		if strings.EqualFold(category.Name, categoryRequest.Name) || category.Abbreviation == categoryRequest.Abbreviation {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "category_exists"))
+			return false
+		}
+	}
+	return true
+}
@@ -1,31 +1,116 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"go.mongodb.org/mongo-driver/bson"
+
+	"goodpack-server/apierrors"
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/utils"
 )
 
 type CustomerHandler struct {
-	repo *repository.CustomerRepository
+	repo          *repository.CustomerRepository
+	purchaseRepo  *repository.PurchaseRepository
+	saleRepo      *repository.SaleRepository
+	quotationRepo *repository.QuotationRepository
 }
 
-func NewCustomerHandler(repo *repository.CustomerRepository) *CustomerHandler {
+func NewCustomerHandler(repo *repository.CustomerRepository, purchaseRepo *repository.PurchaseRepository, saleRepo *repository.SaleRepository, quotationRepo *repository.QuotationRepository) *CustomerHandler {
 	return &CustomerHandler{
-		repo: repo,
+		repo:          repo,
+		purchaseRepo:  purchaseRepo,
+		saleRepo:      saleRepo,
+		quotationRepo: quotationRepo,
 	}
 }
 
+// GetCustomers lists the customers; ?email= returns only the customer with that email (ignoring case) and
+// ?emailDomain= those with an email at that domain
 func (h *CustomerHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
-	customers, err := h.repo.GetAll()
+	ctx := r.Context()
+
+	if email := strings.TrimSpace(r.URL.Query().Get("email")); email != "" {
+		customers := []*models.Customer{}
+		customer, err := h.repo.GetByEmail(ctx, email)
+		switch {
+		case err == nil:
+			customers = append(customers, customer)
+		case !errors.Is(err, apierrors.ErrNotFound):
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed").WithCause(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(customers)
+		return
+	}
+	if domain := strings.TrimSpace(r.URL.Query().Get("emailDomain")); domain != "" {
+		customers, err := h.repo.SearchByEmailDomain(ctx, domain)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed").WithCause(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(customers)
+		return
+	}
+
+	sort, ok := parseSort(w, r, customerSortFields)
+	if !ok {
+		return
+	}
+
+	customers, err := h.repo.GetAll(ctx, sort)
 	if err != nil {
 		log.Printf("Error fetching customers: %v", err)
-		http.Error(w, fmt.Sprintf("NEW!! Failed to fetch customers: %v", err), http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed").WithCause(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customers)
+}
+
+// minCustomerSearchLength is the shortest query SearchCustomers accepts, in characters
+const minCustomerSearchLength = 2
+
+// SearchCustomers finds customers by company name, contact name, phone or tax ID, or by exact customer code.
+// field limits the search to one or more comma-separated fields.
+func (h *CustomerHandler) SearchCustomers(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) < minCustomerSearchLength {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "search_query_too_short"))
+		return
+	}
+
+	var fields []string
+	if value := r.URL.Query().Get("field"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(repository.CustomerSearchFields, field) {
+				RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_search_field"))
+				return
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	customers, err := h.repo.Search(r.Context(), query, fields)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_search_failed"))
 		return
 	}
 
@@ -34,17 +119,19 @@ func (h *CustomerHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_customer_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
-	customer, err := h.repo.GetByID(id)
+	customer, err := h.repo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Customer not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
 		return
 	}
 
@@ -53,15 +140,35 @@ func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	var customerRequest models.CustomerRequest
 	if err := json.NewDecoder(r.Body).Decode(&customerRequest); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &customerRequest) {
+		return
+	}
+
+	if customerRequest.TaxID != "" {
+		if err := utils.ValidateThaiTaxID(customerRequest.TaxID); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_tax_id", err.Error()))
+			return
+		}
+	}
+
+	if !h.checkEmail(w, r, customerRequest.Email, "") {
 		return
 	}
 
 	customer := customerRequest.ToCustomer()
-	if err := h.repo.Create(customer); err != nil {
-		http.Error(w, "Failed to create customer", http.StatusInternalServerError)
+	if err := h.repo.Create(ctx, customer); err != nil {
+		if errors.Is(err, repository.ErrEmailInUse) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "customer_email_exists"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_create_failed"))
 		return
 	}
 
@@ -71,31 +178,61 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_customer_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	// Get existing customer
-	existingCustomer, err := h.repo.GetByID(id)
+	existingCustomer, err := h.repo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Customer not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
 		return
 	}
 
 	var customerRequest models.CustomerRequest
 	if err := json.NewDecoder(r.Body).Decode(&customerRequest); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &customerRequest) {
+		return
+	}
+
+	if customerRequest.TaxID != "" {
+		if err := utils.ValidateThaiTaxID(customerRequest.TaxID); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_tax_id", err.Error()))
+			return
+		}
+	}
+
+	if !h.checkEmail(w, r, customerRequest.Email, id) {
+		return
+	}
+
+	if !checkVersion(w, r, customerRequest.Version, existingCustomer.Version) {
 		return
 	}
 
 	// Update customer
 	existingCustomer.UpdateFromRequest(&customerRequest)
-	if err := h.repo.Update(id, existingCustomer); err != nil {
-		http.Error(w, "Failed to update customer", http.StatusInternalServerError)
+	if err := h.repo.Update(ctx, id, existingCustomer); err != nil {
+		if errors.Is(err, repository.ErrEmailInUse) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "customer_email_exists"))
+			return
+		}
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.repo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_update_failed"))
 		return
 	}
 
@@ -103,19 +240,302 @@ func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(existingCustomer)
 }
 
+// PatchCustomer updates only the fields present in the request body
+func (h *CustomerHandler) PatchCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_customer_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-1]
+
+	var patchRequest models.CustomerPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+
+	if patchRequest.TaxID != nil && *patchRequest.TaxID != "" {
+		if err := utils.ValidateThaiTaxID(*patchRequest.TaxID); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_tax_id", err.Error()))
+			return
+		}
+	}
+
+	if patchRequest.Email != nil && !h.checkEmail(w, r, *patchRequest.Email, id) {
+		return
+	}
+
+	fields := patchRequest.ToUpdateFields()
+	if len(fields) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "no_fields_to_update"))
+		return
+	}
+
+	if err := h.repo.Patch(ctx, id, fields); err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+			return
+		}
+		if errors.Is(err, repository.ErrEmailInUse) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "customer_email_exists"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_update_failed"))
+		return
+	}
+
+	customer, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
+
+// checkEmail rejects an email that is not valid or that a customer other than id already has; id is empty when
+// creating. An empty email is allowed.
+func (h *CustomerHandler) checkEmail(w http.ResponseWriter, r *http.Request, email, id string) bool {
+	ctx := r.Context()
+
+	email = models.NormalizeEmail(email)
+	if email == "" {
+		return true
+	}
+
+	if err := utils.ValidateEmail(email); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_email", err.Error()))
+		return false
+	}
+	if existing, err := h.repo.GetByEmail(ctx, email); err == nil && existing.ID.Hex() != id {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "customer_email_exists"))
+		return false
+	}
+	return true
+}
+
 func (h *CustomerHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_customer_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
-	if err := h.repo.Delete(id); err != nil {
-		http.Error(w, "Failed to delete customer", http.StatusInternalServerError)
+	if err := h.repo.Delete(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_delete_failed"))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// GetCustomerPurchases lists a customer's purchases, newest first
+func (h *CustomerHandler) GetCustomerPurchases(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.customerIDFromSubPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit, skip := parseLimitSkip(r.URL.Query())
+	purchases, err := h.purchaseRepo.GetByCustomerID(r.Context(), id, limit, skip)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_purchases_fetch_failed"))
+		return
+	}
+	if purchases == nil {
+		purchases = []*models.Purchase{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchases)
+}
+
+// GetCustomerSales lists a customer's sales, newest first
+func (h *CustomerHandler) GetCustomerSales(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.customerIDFromSubPath(w, r)
+	if !ok {
+		return
+	}
+
+	limit, skip := parseLimitSkip(r.URL.Query())
+	sales, err := h.saleRepo.GetByCustomerID(r.Context(), id, limit, skip)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_sales_fetch_failed"))
+		return
+	}
+	if sales == nil {
+		sales = []*models.Sale{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sales)
+}
+
+// GetCustomerSummary returns transaction counts, totals and the last transaction date of a customer
+func (h *CustomerHandler) GetCustomerSummary(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.customerIDFromSubPath(w, r)
+	if !ok {
+		return
+	}
+
+	purchaseTotals, err := h.purchaseRepo.GetCustomerTotals(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_purchases_summary_failed"))
+		return
+	}
+	saleTotals, err := h.saleRepo.GetCustomerTotals(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_sales_summary_failed"))
+		return
+	}
+
+	summary := models.CustomerSummary{
+		CustomerID:          id,
+		TotalPurchases:      purchaseTotals.Count,
+		TotalSales:          saleTotals.Count,
+		TotalPurchaseAmount: purchaseTotals.TotalAmount,
+		TotalSaleAmount:     saleTotals.TotalAmount,
+		LastTransactionDate: purchaseTotals.LastDate,
+	}
+	if saleTotals.LastDate != nil && (summary.LastTransactionDate == nil || saleTotals.LastDate.After(*summary.LastTransactionDate)) {
+		summary.LastTransactionDate = saleTotals.LastDate
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// SetCreditLimit sets a customer's credit limit (0 = no limit) and refreshes their outstanding balance
+func (h *CustomerHandler) SetCreditLimit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.customerIDFromSubPath(w, r)
+	if !ok {
+		return
+	}
+
+	var creditReq models.CreditLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&creditReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if creditReq.CreditLimit < 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "credit_limit_negative"))
+		return
+	}
+
+	outstanding, err := h.saleRepo.GetTotalUnpaidByCustomer(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "outstanding_balance_failed"))
+		return
+	}
+
+	fields := bson.M{
+		"creditLimit":        creditReq.CreditLimit,
+		"outstandingBalance": outstanding,
+		"updatedAt":          time.Now(),
+	}
+	if err := h.repo.Patch(ctx, id, fields); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "credit_limit_update_failed"))
+		return
+	}
+
+	customer, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
+}
+
+// RenumberCustomers gives customers gapless codes from C-0001 in the order they were created (deleted customers
+// last, see models.RenumberCustomers) and updates the customer code stored on their sales, purchases and quotations,
+// all in one transaction. It returns the old and new code of every customer whose code changed.
+func (h *CustomerHandler) RenumberCustomers(w http.ResponseWriter, r *http.Request) {
+	var result *models.CustomerRenumberResult
+	err := h.repo.WithTransaction(r.Context(), func(txCtx context.Context) error {
+		customers, err := h.repo.GetAllForRenumbering(txCtx)
+		if err != nil {
+			return err
+		}
+		changes := models.RenumberCustomers(customers)
+
+		// Codes are unique, so the customers being renumbered first move out of the way to a temporary code
+		for _, change := range changes {
+			if err := h.repo.SetCustomerCode(txCtx, change.CustomerID, "renumber-"+change.CustomerID); err != nil {
+				return fmt.Errorf("customer %s: %w", change.OldCode, err)
+			}
+		}
+		for _, change := range changes {
+			if err := h.saleRepo.SetCustomerCode(txCtx, change.CustomerID, change.NewCode); err != nil {
+				return fmt.Errorf("sales of customer %s: %w", change.OldCode, err)
+			}
+			if err := h.purchaseRepo.SetCustomerCode(txCtx, change.CustomerID, change.NewCode); err != nil {
+				return fmt.Errorf("purchases of customer %s: %w", change.OldCode, err)
+			}
+			if err := h.quotationRepo.SetCustomerCode(txCtx, change.CustomerID, change.NewCode); err != nil {
+				return fmt.Errorf("quotations of customer %s: %w", change.OldCode, err)
+			}
+			if err := h.repo.SetCustomerCode(txCtx, change.CustomerID, change.NewCode); err != nil {
+				return fmt.Errorf("customer %s: %w", change.OldCode, err)
+			}
+		}
+
+		result = &models.CustomerRenumberResult{Total: len(customers), Changed: len(changes), Changes: changes}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error renumbering customers: %v", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_renumber_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// customerIDFromSubPath extracts the customer ID from /api/customers/{id}/<sub> and checks the customer exists
+func (h *CustomerHandler) customerIDFromSubPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ctx := r.Context()
+
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_customer_id"))
+		return "", false
+	}
+	id := pathParts[len(pathParts)-2]
+
+	if _, err := h.repo.GetByID(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+		return "", false
+	}
+	return id, true
+}
+
+// parseLimitSkip reads the limit (default 50) and skip query parameters
+func parseLimitSkip(query url.Values) (int, int) {
+	limit := 50
+	skip := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+	if skipStr := query.Get("skip"); skipStr != "" {
+		if parsedSkip, err := strconv.Atoi(skipStr); err == nil && parsedSkip >= 0 {
+			skip = parsedSkip
+		}
+	}
+	return limit, skip
+}
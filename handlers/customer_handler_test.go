@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// historyTest is a customer handler on a test database with two customers; the first has three sales, the
+// second two sales and a later purchase
+type historyTest struct {
+	t      *testing.T
+	h      *CustomerHandler
+	first  string
+	second string
+}
+
+func newHistoryTest(t *testing.T) *historyTest {
+	db := testDatabase(t)
+	ht := &historyTest{
+		t: t,
+		h: NewCustomerHandler(
+			repository.NewCustomerRepository(db.Collection("customers")),
+			repository.NewPurchaseRepository(db.Collection("purchases")),
+			repository.NewSaleRepository(db.Collection("sales")),
+			repository.NewQuotationRepository(db.Collection("quotations")),
+		),
+		first:  primitive.NewObjectID().Hex(),
+		second: primitive.NewObjectID().Hex(),
+	}
+	ctx := context.Background()
+
+	for i, id := range []string{ht.first, ht.second} {
+		objectID, _ := primitive.ObjectIDFromHex(id)
+		customer := &models.Customer{ID: objectID, CustomerCode: fmt.Sprintf("C%04d", i+1), CompanyName: "Company", ContactName: "Contact"}
+		if _, err := db.Collection("customers").InsertOne(ctx, customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sales := []struct {
+		customerID string
+		day        int
+		total      float64
+	}{
+		{ht.first, 1, 100},
+		{ht.first, 3, 300},
+		{ht.first, 2, 200},
+		{ht.second, 4, 50},
+		{ht.second, 5, 70},
+	}
+	for _, s := range sales {
+		sale := &models.Sale{
+			ID:         primitive.NewObjectID(),
+			CustomerID: s.customerID,
+			SaleDate:   time.Date(2024, time.January, s.day, 0, 0, 0, 0, time.UTC),
+			Items:      []models.SaleItem{{ProductID: primitive.NewObjectID().Hex(), Quantity: 1, UnitPrice: s.total, TotalPrice: s.total}},
+		}
+		if _, err := db.Collection("sales").InsertOne(ctx, sale); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purchase := &models.Purchase{
+		ID:           primitive.NewObjectID(),
+		CustomerID:   ht.second,
+		PurchaseDate: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		GrandTotal:   80,
+	}
+	if _, err := db.Collection("purchases").InsertOne(ctx, purchase); err != nil {
+		t.Fatal(err)
+	}
+	return ht
+}
+
+// get calls handler on target and decodes the JSON response into v
+func (ht *historyTest) get(handler http.HandlerFunc, target string, v interface{}) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		ht.t.Fatalf("GET %s status = %d, want %d: %s", target, rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		ht.t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+}
+
+func TestGetCustomerSalesListsOnlyTheirSalesNewestFirst(t *testing.T) {
+	ht := newHistoryTest(t)
+
+	tests := []struct {
+		customerID string
+		query      string
+		wantDays   []int
+	}{
+		{ht.first, "", []int{3, 2, 1}},
+		{ht.first, "?limit=2&skip=1", []int{2, 1}},
+		{ht.second, "", []int{5, 4}},
+	}
+	for _, tt := range tests {
+		var sales []models.Sale
+		ht.get(ht.h.GetCustomerSales, "/api/customers/"+tt.customerID+"/sales"+tt.query, &sales)
+
+		var days []int
+		for _, sale := range sales {
+			if sale.CustomerID != tt.customerID {
+				t.Errorf("customer %s%s: got sale of customer %s", tt.customerID, tt.query, sale.CustomerID)
+			}
+			days = append(days, sale.SaleDate.UTC().Day())
+		}
+		if len(days) != len(tt.wantDays) {
+			t.Errorf("customer %s%s: sale days = %v, want %v", tt.customerID, tt.query, days, tt.wantDays)
+			continue
+		}
+		for i := range days {
+			if days[i] != tt.wantDays[i] {
+				t.Errorf("customer %s%s: sale days = %v, want %v", tt.customerID, tt.query, days, tt.wantDays)
+				break
+			}
+		}
+	}
+}
+
+func TestGetCustomerPurchasesIsEmptyWithoutPurchases(t *testing.T) {
+	ht := newHistoryTest(t)
+
+	var purchases []models.Purchase
+	ht.get(ht.h.GetCustomerPurchases, "/api/customers/"+ht.first+"/purchases", &purchases)
+	if purchases == nil || len(purchases) != 0 {
+		t.Errorf("first customer purchases = %v, want an empty list", purchases)
+	}
+
+	ht.get(ht.h.GetCustomerPurchases, "/api/customers/"+ht.second+"/purchases", &purchases)
+	if len(purchases) != 1 || purchases[0].GrandTotal != 80 {
+		t.Errorf("second customer purchases = %+v, want the one 80 purchase", purchases)
+	}
+}
+
+func TestGetCustomerSummary(t *testing.T) {
+	ht := newHistoryTest(t)
+
+	tests := []struct {
+		customerID string
+		want       models.CustomerSummary
+		wantLast   time.Time
+	}{
+		{
+			customerID: ht.first,
+			want:       models.CustomerSummary{TotalSales: 3, TotalSaleAmount: 600},
+			wantLast:   time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			customerID: ht.second,
+			want:       models.CustomerSummary{TotalPurchases: 1, TotalSales: 2, TotalPurchaseAmount: 80, TotalSaleAmount: 120},
+			wantLast:   time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		var summary models.CustomerSummary
+		ht.get(ht.h.GetCustomerSummary, "/api/customers/"+tt.customerID+"/summary", &summary)
+
+		if summary.LastTransactionDate == nil || !summary.LastTransactionDate.Equal(tt.wantLast) {
+			t.Errorf("customer %s: LastTransactionDate = %v, want %v", tt.customerID, summary.LastTransactionDate, tt.wantLast)
+		}
+		summary.LastTransactionDate = nil
+		tt.want.CustomerID = tt.customerID
+		if summary != tt.want {
+			t.Errorf("customer %s: summary = %+v, want %+v", tt.customerID, summary, tt.want)
+		}
+	}
+}
+
+func TestGetCustomerSummaryOfUnknownCustomer(t *testing.T) {
+	ht := newHistoryTest(t)
+
+	rec := httptest.NewRecorder()
+	ht.h.GetCustomerSummary(rec, httptest.NewRequest(http.MethodGet, "/api/customers/"+primitive.NewObjectID().Hex()+"/summary", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSearchCustomers(t *testing.T) {
+	db := testDatabase(t)
+	h := &CustomerHandler{repo: repository.NewCustomerRepository(db.Collection("customers"))}
+
+	for _, customer := range []*models.Customer{
+		{CustomerCode: "C0001", CompanyName: "Somchai Packaging", ContactName: "Somchai Jaidee", Phone: "081-234-5678", TaxID: "0105551234567"},
+		{CustomerCode: "C0002", CompanyName: "Bangkok Bottles", ContactName: "Suda Rakdee", Phone: "02-555-0100"},
+		{CustomerCode: "C0003", CompanyName: "Chiang Mai Caps", ContactName: "Anan SOMCHAI", Phone: "053-111-222"},
+		{CustomerCode: "C0004", CompanyName: "Somchai Deleted", IsDeleted: true},
+	} {
+		customer.ID = primitive.NewObjectID()
+		if _, err := db.Collection("customers").InsertOne(context.Background(), customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"q=somchai", []string{"C0003", "C0001"}},          // company or contact, any case
+		{"q=somchai&field=companyName", []string{"C0001"}}, // company only
+		{"q=555", []string{"C0002", "C0001"}},              // phone or tax ID
+		{"q=234-5678&field=phone", []string{"C0001"}},      // partial phone
+		{"q=C0002&field=companyName", []string{"C0002"}},   // exact customer code
+		{"q=" + url.QueryEscape("(.*"), []string{}},        // regex characters are literal
+		{"q=nobody", []string{}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.SearchCustomers(rec, httptest.NewRequest(http.MethodGet, "/api/customers/search?"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+			continue
+		}
+
+		var customers []models.Customer
+		if err := json.NewDecoder(rec.Body).Decode(&customers); err != nil {
+			t.Fatal(err)
+		}
+		codes := []string{}
+		for _, customer := range customers {
+			codes = append(codes, customer.CustomerCode)
+		}
+		if !reflect.DeepEqual(codes, tt.want) {
+			t.Errorf("%q: customers = %q, want %q", tt.query, codes, tt.want)
+		}
+	}
+}
+
+func TestSearchCustomersRejectsInvalidQueries(t *testing.T) {
+	h := &CustomerHandler{}
+	for _, query := range []string{"", "q=s", "q=%20s%20", "q=somchai&field=email"} {
+		rec := httptest.NewRecorder()
+		h.SearchCustomers(rec, httptest.NewRequest(http.MethodGet, "/api/customers/search?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
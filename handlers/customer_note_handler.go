@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type CustomerNoteHandler struct {
+	noteRepo     *repository.CustomerNoteRepository
+	customerRepo *repository.CustomerRepository
+}
+
+func NewCustomerNoteHandler(noteRepo *repository.CustomerNoteRepository, customerRepo *repository.CustomerRepository) *CustomerNoteHandler {
+	return &CustomerNoteHandler{
+		noteRepo:     noteRepo,
+		customerRepo: customerRepo,
+	}
+}
+
+// GetCustomerNotes lists a customer's notes, newest first (limit, skip)
+func (h *CustomerNoteHandler) GetCustomerNotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+	if _, err := h.customerRepo.GetByID(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+		return
+	}
+
+	limit, skip := parseLimitSkip(r.URL.Query())
+	notes, err := h.noteRepo.GetByCustomerID(r.Context(), id, limit, skip)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_notes_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// CreateCustomerNote adds a note to a customer's activity log and sets the customer's last contact to now.
+// The note is recorded as created by the X-User-ID header.
+func (h *CustomerNoteHandler) CreateCustomerNote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	var noteRequest models.CustomerNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&noteRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &noteRequest) {
+		return
+	}
+
+	if _, err := h.customerRepo.GetByID(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_not_found"))
+		return
+	}
+
+	note := noteRequest.ToCustomerNote(id, changedBy(r))
+	if err := h.noteRepo.Create(r.Context(), note); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_note_create_failed"))
+		return
+	}
+
+	if err := h.customerRepo.SetDerived(ctx, id, bson.M{"lastContactAt": note.CreatedAt, "updatedAt": note.CreatedAt}); err != nil {
+		// The note is saved; only the customer's last contact is out of date
+		log.Printf("Warning: Failed to update last contact of customer %s: %v", id, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// DeleteCustomerNote deletes one of a customer's notes; the customer's last contact is left as it was
+func (h *CustomerNoteHandler) DeleteCustomerNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.noteRepo.Delete(r.Context(), vars["id"], vars["noteId"]); err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "customer_note_not_found"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_note_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
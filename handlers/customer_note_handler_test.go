@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// noteRequest is a request for the customer's notes; with a note it is a POST, without one a GET with query
+func noteRequest(customerID string, note *models.CustomerNoteRequest, query string) *http.Request {
+	var req *http.Request
+	if note != nil {
+		body, _ := json.Marshal(note)
+		req = httptest.NewRequest(http.MethodPost, "/api/customers/"+customerID+"/notes", bytes.NewReader(body))
+		req.Header.Set("X-User-ID", "user-1")
+	} else {
+		req = httptest.NewRequest(http.MethodGet, "/api/customers/"+customerID+"/notes?"+query, nil)
+	}
+	return mux.SetURLVars(req, map[string]string{"id": customerID})
+}
+
+func TestCustomerNotes(t *testing.T) {
+	ctx := context.Background()
+
+	db := testDatabase(t)
+	customerRepo := repository.NewCustomerRepository(db.Collection("customers"))
+	h := NewCustomerNoteHandler(repository.NewCustomerNoteRepository(db.Collection("customer_notes")), customerRepo)
+
+	customer := &models.Customer{ID: primitive.NewObjectID(), CompanyName: "Siam Foods", ContactName: "Somchai"}
+	other := &models.Customer{ID: primitive.NewObjectID(), CompanyName: "Bangkok Bakery", ContactName: "Malee"}
+	for _, c := range []*models.Customer{customer, other} {
+		if err := customerRepo.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id := customer.ID.Hex()
+
+	// Creating a note sets the customer's last contact
+	before := time.Now().Add(-time.Second)
+	rec := httptest.NewRecorder()
+	h.CreateCustomerNote(rec, noteRequest(id, &models.CustomerNoteRequest{NoteText: "Note 1", NoteType: "call"}, ""))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var created models.CustomerNote
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.NoteID.IsZero() || created.CustomerID != id || created.NoteType != "call" || created.CreatedBy == nil || *created.CreatedBy != "user-1" {
+		t.Errorf("created note = %+v, want a call by user-1 for the customer", created)
+	}
+	stored, err := customerRepo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastContactAt == nil || stored.LastContactAt.Before(before) {
+		t.Fatalf("LastContactAt = %v, want the time of the note", stored.LastContactAt)
+	}
+	firstContact := *stored.LastContactAt
+
+	for i := 2; i <= 5; i++ {
+		rec := httptest.NewRecorder()
+		h.CreateCustomerNote(rec, noteRequest(id, &models.CustomerNoteRequest{NoteText: fmt.Sprintf("Note %d", i)}, ""))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+	}
+	rec = httptest.NewRecorder()
+	h.CreateCustomerNote(rec, noteRequest(other.ID.Hex(), &models.CustomerNoteRequest{NoteText: "Other customer"}, ""))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if stored, err = customerRepo.GetByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastContactAt.Before(firstContact) {
+		t.Errorf("LastContactAt = %v after more notes, want no earlier than %v", stored.LastContactAt, firstContact)
+	}
+
+	// Notes are listed newest first, a page at a time
+	pages := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Note 5", "Note 4", "Note 3", "Note 2", "Note 1"}},
+		{"limit=2", []string{"Note 5", "Note 4"}},
+		{"limit=2&skip=2", []string{"Note 3", "Note 2"}},
+		{"limit=2&skip=4", []string{"Note 1"}},
+		{"skip=5", []string{}},
+	}
+	for _, page := range pages {
+		rec := httptest.NewRecorder()
+		h.GetCustomerNotes(rec, noteRequest(id, nil, page.query))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want %d: %s", page.query, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var notes []models.CustomerNote
+		if err := json.NewDecoder(rec.Body).Decode(&notes); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, note := range notes {
+			got = append(got, note.NoteText)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(page.want) {
+			t.Errorf("%q: notes = %v, want %v", page.query, got, page.want)
+		}
+	}
+
+	// A note can only be deleted through its own customer
+	deleteNote := func(customerID string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/customers/"+customerID+"/notes/"+created.NoteID.Hex(), nil)
+		req = mux.SetURLVars(req, map[string]string{"id": customerID, "noteId": created.NoteID.Hex()})
+		rec := httptest.NewRecorder()
+		h.DeleteCustomerNote(rec, req)
+		return rec.Code
+	}
+	if code := deleteNote(other.ID.Hex()); code != http.StatusNotFound {
+		t.Errorf("delete through another customer status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := deleteNote(id); code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := deleteNote(id); code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestCreateCustomerNoteRejectsInvalidNotes(t *testing.T) {
+	h := &CustomerNoteHandler{}
+	id := primitive.NewObjectID().Hex()
+	for _, note := range []*models.CustomerNoteRequest{
+		{NoteText: ""},
+		{NoteText: "Met at the trade fair", NoteType: "fax"},
+	} {
+		rec := httptest.NewRecorder()
+		h.CreateCustomerNote(rec, noteRequest(id, note, ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%+v: status = %d, want %d", note, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
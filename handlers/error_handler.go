@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"goodpack-server/apierrors"
+)
+
+// RespondWithError writes err as a JSON error. An AppError is sent as it is; the sentinel errors become
+// 404, 409 or 400, and any other error a 500 whose details are only logged.
+func RespondWithError(w http.ResponseWriter, err error) {
+	var appErr *apierrors.AppError
+	switch {
+	case errors.As(err, &appErr):
+	case errors.Is(err, apierrors.ErrNotFound):
+		appErr = apierrors.NewStatus(http.StatusNotFound, http.StatusText(http.StatusNotFound)).WithCause(err)
+	case errors.Is(err, apierrors.ErrConflict):
+		appErr = apierrors.NewStatus(http.StatusConflict, http.StatusText(http.StatusConflict)).WithCause(err)
+	case errors.Is(err, apierrors.ErrBadRequest):
+		appErr = apierrors.NewStatus(http.StatusBadRequest, http.StatusText(http.StatusBadRequest)).WithCause(err)
+	default:
+		appErr = apierrors.New(http.StatusInternalServerError, "internal_error", http.StatusText(http.StatusInternalServerError)).WithCause(err)
+	}
+
+	if appErr.StatusCode >= http.StatusInternalServerError && appErr.Cause != nil {
+		fmt.Printf("Error: %s: %v\n", appErr.Code, appErr.Cause)
+	}
+	apierrors.Write(w, appErr)
+}
+
+// localisedError builds an AppError whose code is an error code from locales/*.json and whose message is
+// that code's text in the request's language, with args filled in by fmt.Sprintf when the text has parameters
+func localisedError(ctx context.Context, statusCode int, errorCode string, args ...interface{}) *apierrors.AppError {
+	message := localise(ctx, errorCode)
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	return apierrors.New(statusCode, errorCode, message)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/middleware"
+)
+
+func TestRespondWithError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"app error", apierrors.New(http.StatusUnprocessableEntity, "credit_limit_exceeded", "Over the limit"), http.StatusUnprocessableEntity, "credit_limit_exceeded"},
+		{"wrapped app error", fmt.Errorf("create sale: %w", apierrors.New(http.StatusConflict, "version_conflict", "Stale")), http.StatusConflict, "version_conflict"},
+		{"not found", fmt.Errorf("product abc: %w", apierrors.ErrNotFound), http.StatusNotFound, "not_found"},
+		{"conflict", fmt.Errorf("sku: %w", apierrors.ErrConflict), http.StatusConflict, "conflict"},
+		{"bad request", fmt.Errorf("id: %w", apierrors.ErrBadRequest), http.StatusBadRequest, "bad_request"},
+		{"other", errors.New("connection reset"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		RespondWithError(w, tt.err)
+
+		var body apierrors.AppError
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: invalid body %q: %v", tt.name, w.Body.String(), err)
+			continue
+		}
+		if w.Code != tt.wantStatus || body.Code != tt.wantCode {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, w.Code, body.Code, tt.wantStatus, tt.wantCode)
+		}
+		if tt.wantStatus == http.StatusInternalServerError && body.Message != http.StatusText(http.StatusInternalServerError) {
+			t.Errorf("%s: message %q leaks the cause", tt.name, body.Message)
+		}
+	}
+}
+
+func TestLocalisedErrorFollowsAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"th-TH,th;q=0.9", "ไม่พบสินค้า"},
+		{"en-US", "Product not found"},
+		{"", "Product not found"},
+	}
+	for _, tt := range tests {
+		var appErr *apierrors.AppError
+		handler := middleware.I18n(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			appErr = localisedError(r.Context(), http.StatusNotFound, "product_not_found")
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/api/products/abc", nil)
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if appErr.Code != "product_not_found" || appErr.Message != tt.want || !errors.Is(appErr, apierrors.ErrNotFound) {
+			t.Errorf("%q: %s %q, want product_not_found %q matching ErrNotFound", tt.acceptLanguage, appErr.Code, appErr.Message, tt.want)
+		}
+	}
+}
+
+func TestLocalisedErrorFillsInParameters(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"th", "ไม่พบสินค้า: 64b7f0c2a1"},
+		{"en", "Product not found: 64b7f0c2a1"},
+	}
+	for _, tt := range tests {
+		var appErr *apierrors.AppError
+		handler := middleware.I18n(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			appErr = localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", "64b7f0c2a1")
+		}))
+		r := httptest.NewRequest(http.MethodPost, "/api/sales", nil)
+		r.Header.Set("Accept-Language", tt.acceptLanguage)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if appErr.Code != "product_not_found_id" || appErr.Message != tt.want {
+			t.Errorf("%q: %s %q, want product_not_found_id %q", tt.acceptLanguage, appErr.Code, appErr.Message, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// currencyCodePattern matches an ISO 4217 currency code such as USD
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type ExchangeRateHandler struct {
+	repo *repository.ExchangeRateRepository
+}
+
+func NewExchangeRateHandler(repo *repository.ExchangeRateRepository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		repo: repo,
+	}
+}
+
+// GetExchangeRate returns the baht rate of ?currency= on ?date= (default today), or the latest rate entered before
+// that day. Baht itself is always 1.
+func (h *ExchangeRateHandler) GetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	currency := strings.ToUpper(strings.TrimSpace(query.Get("currency")))
+	if !currencyCodePattern.MatchString(currency) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_currency"))
+		return
+	}
+
+	date := time.Now()
+	if dateStr := query.Get("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_date"))
+			return
+		}
+		date = parsed
+	}
+
+	rate := &models.ExchangeRate{Currency: models.BaseCurrency, Date: models.RateDate(date), Rate: 1}
+	if currency != models.BaseCurrency {
+		var err error
+		rate, err = h.repo.GetRate(r.Context(), currency, date)
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "exchange_rate_not_found"))
+			return
+		}
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "exchange_rate_fetch_failed"))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rate)
+}
+
+// CreateExchangeRate enters the baht rate of a currency on a day (default today) by hand, replacing the rate
+// already entered for that day
+func (h *ExchangeRateHandler) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var rateRequest models.ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&rateRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &rateRequest) {
+		return
+	}
+	if rateRequest.Currency == models.BaseCurrency {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "exchange_rate_base_currency"))
+		return
+	}
+	if rateRequest.Date.IsZero() {
+		rateRequest.Date.Time = time.Now()
+	}
+
+	rate := rateRequest.ToExchangeRate(changedBy(r))
+	if err := h.repo.Save(r.Context(), rate); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "exchange_rate_save_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goodpack-server/repository"
+)
+
+// Column layouts mirror the migration CSV templates so exports can be re-imported as-is
+var (
+	customerCSVHeaders = []string{"customerCode", "companyName", "contactName", "taxId", "phone", "address", "contactMethod", "email"}
+	productCSVHeaders  = []string{"skuId", "name", "description", "color", "size", "category", "purchasePriceVAT", "purchasePriceNonVAT", "salePriceVAT", "salePriceNonVAT", "stockVAT", "stockNonVAT", "actualStock", "tags"}
+	purchaseCSVHeaders = []string{"purchaseCode", "purchaseDate", "customerCode", "productCode", "quantity", "unitPrice", "isVAT", "shippingCost", "notes", "discountPercent", "discountAmount"}
+	saleCSVHeaders     = []string{"saleCode", "saleDate", "customerCode", "productCode", "quantity", "unitPrice", "isVAT", "shippingCost", "notes", "discountPercent", "discountAmount"}
+)
+
+type ExportHandler struct {
+	customerRepo *repository.CustomerRepository
+	productRepo  *repository.ProductRepository
+	purchaseRepo *repository.PurchaseRepository
+	saleRepo     *repository.SaleRepository
+}
+
+func NewExportHandler(customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, purchaseRepo *repository.PurchaseRepository, saleRepo *repository.SaleRepository) *ExportHandler {
+	return &ExportHandler{
+		customerRepo: customerRepo,
+		productRepo:  productRepo,
+		purchaseRepo: purchaseRepo,
+		saleRepo:     saleRepo,
+	}
+}
+
+// ExportCustomersCSV exports customers created within the optional date range
+func (h *ExportHandler) ExportCustomersCSV(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	customers, err := h.customerRepo.GetAll(ctx, nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed"))
+		return
+	}
+
+	writer := startCSVExport(w, "customers")
+	writer.Write(customerCSVHeaders)
+	for _, customer := range customers {
+		if !inDateRange(customer.CreatedAt, startDate, endDate) {
+			continue
+		}
+		writer.Write([]string{
+			customer.CustomerCode,
+			customer.CompanyName,
+			customer.ContactName,
+			customer.TaxID,
+			customer.Phone,
+			customer.Address,
+			customer.ContactMethod,
+			customer.Email,
+		})
+	}
+	writer.Flush()
+}
+
+// ExportProductsCSV exports products created within the optional date range
+func (h *ExportHandler) ExportProductsCSV(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	products, err := h.productRepo.GetAll(r.Context(), nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_fetch_failed"))
+		return
+	}
+
+	writer := startCSVExport(w, "products")
+	writer.Write(productCSVHeaders)
+	for _, product := range products {
+		if !inDateRange(product.CreatedAt, startDate, endDate) {
+			continue
+		}
+		writer.Write([]string{
+			product.SKUID,
+			product.Name,
+			product.Description,
+			product.Color,
+			product.Size,
+			product.Category,
+			formatCSVMoney(product.Price.PurchaseVAT.Latest),
+			formatCSVMoney(product.Price.PurchaseNonVAT.Latest),
+			formatCSVMoney(product.Price.SaleVAT.Latest),
+			formatCSVMoney(product.Price.SaleNonVAT.Latest),
+			strconv.Itoa(product.Stock.VAT.Remaining),
+			strconv.Itoa(product.Stock.NonVAT.Remaining),
+			strconv.Itoa(product.Stock.ActualStock),
+			strings.Join(product.Tags, ","),
+		})
+	}
+	writer.Flush()
+}
+
+// ExportPurchasesCSV exports purchases dated within the optional date range, one row per item
+func (h *ExportHandler) ExportPurchasesCSV(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	purchases, err := h.purchaseRepo.GetAll(r.Context(), "", nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchases_fetch_failed"))
+		return
+	}
+
+	customerCodes, err := h.customerCodesByID(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed"))
+		return
+	}
+
+	writer := startCSVExport(w, "purchases")
+	writer.Write(purchaseCSVHeaders)
+	for _, purchase := range purchases {
+		if !inDateRange(purchase.PurchaseDate, startDate, endDate) {
+			continue
+		}
+		for i, item := range purchase.Items {
+			// Shipping cost and notes are read from the first row of each purchase on import
+			shippingCost, notes := "", ""
+			if i == 0 {
+				shippingCost = formatCSVMoney(purchase.ShippingCost)
+				if purchase.Notes != nil {
+					notes = *purchase.Notes
+				}
+			}
+			writer.Write([]string{
+				purchase.PurchaseCode,
+				purchase.PurchaseDate.Format("2006-01-02"),
+				customerCodes[purchase.CustomerID],
+				item.ProductCode,
+				strconv.Itoa(item.Quantity),
+				formatCSVMoney(item.UnitPrice),
+				strconv.FormatBool(purchase.IsVAT),
+				shippingCost,
+				notes,
+				formatCSVOptional(item.DiscountPercent),
+				formatCSVOptional(item.DiscountAmount),
+			})
+		}
+	}
+	writer.Flush()
+}
+
+// ExportSalesCSV exports sales dated within the optional date range, one row per item
+func (h *ExportHandler) ExportSalesCSV(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	sales, err := h.saleRepo.GetAll(r.Context(), "", nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sales_fetch_failed"))
+		return
+	}
+
+	customerCodes, err := h.customerCodesByID(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customers_fetch_failed"))
+		return
+	}
+
+	writer := startCSVExport(w, "sales")
+	writer.Write(saleCSVHeaders)
+	for _, sale := range sales {
+		if !inDateRange(sale.SaleDate, startDate, endDate) {
+			continue
+		}
+		for i, item := range sale.Items {
+			// Shipping cost and notes are read from the first row of each sale on import
+			shippingCost, notes := "", ""
+			if i == 0 {
+				shippingCost = formatCSVMoney(sale.ShippingCost)
+				if sale.Notes != nil {
+					notes = *sale.Notes
+				}
+			}
+			writer.Write([]string{
+				sale.SaleCode,
+				sale.SaleDate.Format("2006-01-02"),
+				customerCodes[sale.CustomerID],
+				item.ProductCode,
+				strconv.FormatFloat(item.Quantity, 'f', -1, 64),
+				formatCSVMoney(item.UnitPrice),
+				strconv.FormatBool(sale.IsVAT),
+				shippingCost,
+				notes,
+				formatCSVOptional(item.DiscountPercent),
+				formatCSVOptional(item.DiscountAmount),
+			})
+		}
+	}
+	writer.Flush()
+}
+
+// customerCodesByID maps customer IDs to customer codes for the sale/purchase exports
+func (h *ExportHandler) customerCodesByID(ctx context.Context) (map[string]string, error) {
+	customers, err := h.customerRepo.GetAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string]string, len(customers))
+	for _, customer := range customers {
+		codes[customer.ID.Hex()] = customer.CustomerCode
+	}
+	return codes, nil
+}
+
+// parseExportDateRange reads the optional startDate/endDate query parameters, writing a 400 on invalid input
+func parseExportDateRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	var startDate, endDate time.Time
+
+	if startDateStr := r.URL.Query().Get("startDate"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_start_date"))
+			return startDate, endDate, false
+		}
+		startDate = parsed
+	}
+	if endDateStr := r.URL.Query().Get("endDate"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_end_date"))
+			return startDate, endDate, false
+		}
+		// Set to end of day
+		endDate = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	return startDate, endDate, true
+}
+
+// inDateRange reports whether t falls within the range; zero bounds are open
+func inDateRange(t, startDate, endDate time.Time) bool {
+	if !startDate.IsZero() && t.Before(startDate) {
+		return false
+	}
+	if !endDate.IsZero() && t.After(endDate) {
+		return false
+	}
+	return true
+}
+
+// startCSVExport sets the download headers and returns a CSV writer on the response
+func startCSVExport(w http.ResponseWriter, name string) *csv.Writer {
+	filename := fmt.Sprintf("%s-%s.csv", name, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	return csv.NewWriter(w)
+}
+
+func formatCSVMoney(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// formatCSVOptional leaves zero values empty, matching optional import columns
+func formatCSVOptional(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+const roundTripRecords = 10
+
+// csvStore is the set of repositories on one test database
+type csvStore struct {
+	db        *mongo.Database
+	customers *repository.CustomerRepository
+	products  *repository.ProductRepository
+	purchases *repository.PurchaseRepository
+	sales     *repository.SaleRepository
+}
+
+func newCSVStore(t *testing.T) *csvStore {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &csvStore{
+		db:        db,
+		customers: repository.NewCustomerRepository(db.Collection("customers")),
+		products:  productRepo,
+		purchases: repository.NewPurchaseRepository(db.Collection("purchases")),
+		sales:     repository.NewSaleRepository(db.Collection("sales")),
+	}
+}
+
+func (s *csvStore) insert(t *testing.T, collection string, documents ...interface{}) {
+	if _, err := s.db.Collection(collection).InsertMany(context.Background(), documents); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seed stores roundTripRecords customers, products, purchases and sales. Each sale has two items so the
+// export writes several rows for it.
+func (s *csvStore) seed(t *testing.T) {
+	var customers, products, purchases, sales []interface{}
+	for i := 0; i < roundTripRecords; i++ {
+		customers = append(customers, &models.Customer{
+			ID:            primitive.NewObjectID(),
+			CustomerCode:  fmt.Sprintf("C%04d", i+1),
+			CompanyName:   fmt.Sprintf("Company %d", i+1),
+			ContactName:   fmt.Sprintf("Contact %d", i+1),
+			Phone:         fmt.Sprintf("08100000%02d", i),
+			Address:       fmt.Sprintf("%d Sukhumvit Rd, Bangkok", i+1),
+			ContactMethod: "line",
+			Email:         fmt.Sprintf("buyer%d@example.com", i+1),
+		})
+
+		product := &models.Product{
+			ID:          primitive.NewObjectID(),
+			SKUID:       fmt.Sprintf("BOX-%04d", i+1),
+			Name:        fmt.Sprintf("Kraft Box %d", i+1),
+			Description: "Corrugated, 3 ply",
+			Color:       "Brown",
+			Size:        fmt.Sprintf("S%d", i),
+			Category:    "Box",
+			Tags:        []string{"kraft", "shipping"},
+		}
+		product.Code = (&MigrationHandler{}).generateProductCode(product.Category, product.Size, product.Color)
+		product.Price.PurchaseVAT.Latest = 10 + float64(i)
+		product.Price.PurchaseNonVAT.Latest = 9.5 + float64(i)
+		product.Price.SaleVAT.Latest = 15.25 + float64(i)
+		product.Price.SaleNonVAT.Latest = 14 + float64(i)
+		product.Stock.VAT.Remaining = 100 + i
+		product.Stock.NonVAT.Remaining = 50 + i
+		product.Stock.ActualStock = 150 + 2*i
+		products = append(products, product)
+
+		date := time.Date(2024, time.March, i+1, 12, 0, 0, 0, time.UTC)
+		notes := fmt.Sprintf("Order %d, deliver to dock \"B\"", i+1)
+		purchases = append(purchases, &models.Purchase{
+			ID:           primitive.NewObjectID(),
+			PurchaseCode: fmt.Sprintf("PO-%04d", i+1),
+			PurchaseDate: date,
+			CustomerID:   customers[i].(*models.Customer).ID.Hex(),
+			IsVAT:        i%2 == 0,
+			ShippingCost: 40,
+			Notes:        &notes,
+			Items: []models.PurchaseItem{
+				{ProductID: product.ID.Hex(), ProductCode: product.Code, Quantity: 20 + i, UnitPrice: 10 + float64(i), DiscountPercent: 5},
+			},
+		})
+		sales = append(sales, &models.Sale{
+			ID:           primitive.NewObjectID(),
+			SaleCode:     fmt.Sprintf("SO-%04d", i+1),
+			SaleDate:     date,
+			CustomerID:   customers[i].(*models.Customer).ID.Hex(),
+			IsVAT:        i%2 == 1,
+			ShippingCost: 25.5,
+			Notes:        &notes,
+		})
+	}
+	// The second item of each sale is the next product along
+	for i, sale := range sales {
+		for _, product := range []*models.Product{products[i].(*models.Product), products[(i+1)%roundTripRecords].(*models.Product)} {
+			sale.(*models.Sale).Items = append(sale.(*models.Sale).Items, models.SaleItem{
+				ProductID: product.ID.Hex(), ProductCode: product.Code, Quantity: 3, UnitPrice: product.Price.SaleVAT.Latest, DiscountAmount: 2,
+			})
+		}
+	}
+
+	s.insert(t, "customers", customers...)
+	s.insert(t, "products", products...)
+	s.insert(t, "purchases", purchases...)
+	s.insert(t, "sales", sales...)
+}
+
+// export runs an export handler and returns the CSV it wrote
+func export(t *testing.T, handler http.HandlerFunc, target string) string {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d: %s", target, rec.Code, http.StatusOK, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+// checkImport fails the test unless every row of an import succeeded
+func checkImport(t *testing.T, name string, result *MigrationResult, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("import %s: %v", name, err)
+	}
+	if result.FailedRows != 0 || len(result.Errors) != 0 || result.SuccessRows != roundTripRecords {
+		t.Fatalf("import %s: %d succeeded, %d failed: %v", name, result.SuccessRows, result.FailedRows, result.Errors)
+	}
+}
+
+func TestCSVExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newCSVStore(t), newCSVStore(t)
+	src.seed(t)
+
+	exporter := NewExportHandler(src.customers, src.products, src.purchases, src.sales)
+	importer := &MigrationHandler{customerRepo: dst.customers, productRepo: dst.products, purchaseRepo: dst.purchases, saleRepo: dst.sales}
+
+	customersCSV := export(t, exporter.ExportCustomersCSV, "/api/export/customers/csv")
+	productsCSV := export(t, exporter.ExportProductsCSV, "/api/export/products/csv")
+	purchasesCSV := export(t, exporter.ExportPurchasesCSV, "/api/export/purchases/csv")
+	salesCSV := export(t, exporter.ExportSalesCSV, "/api/export/sales/csv")
+
+	result, err := importer.parseAndMigrateCustomerCSV(context.Background(), strings.NewReader(customersCSV), nil, false)
+	checkImport(t, "customers", result, err)
+	result, err = importer.parseAndMigrateProductCSV(ctx, strings.NewReader(productsCSV), nil, false)
+	checkImport(t, "products", result, err)
+	assertSameDocuments(t, "customers", customerDocuments(t, src), customerDocuments(t, dst))
+	// Importing the purchases and sales below moves the stock, so the products are compared first
+	assertSameDocuments(t, "products", productDocuments(t, src), productDocuments(t, dst))
+
+	result, err = importer.parseAndMigratePurchaseCSV(ctx, strings.NewReader(purchasesCSV), nil, false)
+	checkImport(t, "purchases", result, err)
+	result, err = importer.parseAndMigrateSaleCSV(ctx, strings.NewReader(salesCSV), nil, false)
+	checkImport(t, "sales", result, err)
+	assertSameDocuments(t, "purchases", purchaseDocuments(t, src), purchaseDocuments(t, dst))
+	assertSameDocuments(t, "sales", saleDocuments(t, src), saleDocuments(t, dst))
+}
+
+func TestCSVExportFiltersByDate(t *testing.T) {
+	src := newCSVStore(t)
+	src.seed(t)
+	exporter := NewExportHandler(src.customers, src.products, src.purchases, src.sales)
+
+	body := export(t, exporter.ExportPurchasesCSV, "/api/export/purchases/csv?startDate=2024-03-03&endDate=2024-03-04")
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 purchases:\n%s", len(lines), body)
+	}
+	for i, code := range []string{"PO-0003", "PO-0004"} {
+		if !strings.HasPrefix(lines[i+1], code+",") {
+			t.Errorf("line %d = %q, want purchase %s", i+1, lines[i+1], code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	exporter.ExportSalesCSV(rec, httptest.NewRequest(http.MethodGet, "/api/export/sales/csv?startDate=03/01/2024", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid startDate status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// assertSameDocuments compares the documents of one collection in both databases, keyed by their code
+func assertSameDocuments(t *testing.T, name string, want, got map[string]string) {
+	t.Helper()
+	if len(want) != roundTripRecords {
+		t.Fatalf("%s: seeded %d documents, want %d", name, len(want), roundTripRecords)
+	}
+	if !reflect.DeepEqual(got, want) {
+		for code, doc := range want {
+			if got[code] != doc {
+				t.Errorf("%s %s after round trip:\n got %s\nwant %s", name, code, got[code], doc)
+			}
+		}
+		t.Errorf("%s: imported %d documents, want %d", name, len(got), len(want))
+	}
+}
+
+func customerDocuments(t *testing.T, s *csvStore) map[string]string {
+	ctx := context.Background()
+
+	customers, err := s.customers.GetAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]string)
+	for _, c := range customers {
+		docs[c.CustomerCode] = fmt.Sprintf("%q", []string{c.CompanyName, c.ContactName, c.TaxID, c.Phone, c.Address, c.ContactMethod, c.Email})
+	}
+	return docs
+}
+
+func productDocuments(t *testing.T, s *csvStore) map[string]string {
+	products, err := s.products.GetAll(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]string)
+	for _, p := range products {
+		docs[p.SKUID] = fmt.Sprintf("%q %v %v %d/%d/%d %q", []string{p.Code, p.Name, p.Description, p.Color, p.Size, p.Category},
+			[]float64{p.Price.PurchaseVAT.Latest, p.Price.PurchaseNonVAT.Latest}, []float64{p.Price.SaleVAT.Latest, p.Price.SaleNonVAT.Latest},
+			p.Stock.VAT.Remaining, p.Stock.NonVAT.Remaining, p.Stock.ActualStock, p.Tags)
+	}
+	return docs
+}
+
+// customerCode returns the code of the customer with the given ID
+func customerCode(t *testing.T, s *csvStore, id string) string {
+	ctx := context.Background()
+
+	customer, err := s.customers.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return customer.CustomerCode
+}
+
+func purchaseDocuments(t *testing.T, s *csvStore) map[string]string {
+	purchases, err := s.purchases.GetAll(context.Background(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]string)
+	for _, p := range purchases {
+		doc := fmt.Sprintf("%s %s vat=%t shipping=%v notes=%q", p.PurchaseDate.Format("2006-01-02"), customerCode(t, s, p.CustomerID), p.IsVAT, p.ShippingCost, *p.Notes)
+		for _, item := range p.Items {
+			doc += fmt.Sprintf(" [%s %d@%v -%v%% -%v]", item.ProductCode, item.Quantity, item.UnitPrice, item.DiscountPercent, item.DiscountAmount)
+		}
+		docs[p.PurchaseCode] = doc
+	}
+	return docs
+}
+
+func saleDocuments(t *testing.T, s *csvStore) map[string]string {
+	sales, err := s.sales.GetAll(context.Background(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]string)
+	for _, sale := range sales {
+		doc := fmt.Sprintf("%s %s vat=%t shipping=%v notes=%q", sale.SaleDate.Format("2006-01-02"), customerCode(t, s, sale.CustomerID), sale.IsVAT, sale.ShippingCost, *sale.Notes)
+		for _, item := range sale.Items {
+			doc += fmt.Sprintf(" [%s %v@%v -%v%% -%v]", item.ProductCode, item.Quantity, item.UnitPrice, item.DiscountPercent, item.DiscountAmount)
+		}
+		docs[sale.SaleCode] = doc
+	}
+	return docs
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout bounds the database ping and counts of a health check
+const healthCheckTimeout = 2 * time.Second
+
+// healthCollections are the collections whose document counts the health check reports
+var healthCollections = []string{"products", "customers", "sales", "purchases", "quotations"}
+
+// HealthDatabase is the part of the database the health checks use; *database.MongoDB implements it
+type HealthDatabase interface {
+	Ping(ctx context.Context) error
+	CountDocuments(ctx context.Context, collection string) (int64, error)
+}
+
+type HealthHandler struct {
+	db HealthDatabase
+}
+
+func NewHealthHandler(db HealthDatabase) *HealthHandler {
+	return &HealthHandler{db: db}
+}
+
+// HealthResponse is the body of GET /api/health
+type HealthResponse struct {
+	Status      string           `json:"status"`      // healthy or degraded
+	MongoStatus string           `json:"mongoStatus"` // connected or disconnected
+	LatencyMs   int64            `json:"latencyMs"`   // เวลาที่ใช้ ping MongoDB
+	Collections map[string]int64 `json:"collections,omitempty"`
+	Timestamp   string           `json:"timestamp"`
+	Version     string           `json:"version"`
+	Database    string           `json:"database"`
+}
+
+// HealthCheck pings MongoDB and counts the documents of the main collections; it returns 503 when the
+// database is unreachable
+func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	response := HealthResponse{
+		Status:      "healthy",
+		MongoStatus: "connected",
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Version:     "1.0.0",
+		Database:    "mongodb",
+	}
+
+	start := time.Now()
+	err := h.db.Ping(ctx)
+	response.LatencyMs = time.Since(start).Milliseconds()
+
+	status := http.StatusOK
+	if err != nil {
+		fmt.Printf("Warning: Health check ping failed: %v\n", err)
+		response.Status = "degraded"
+		response.MongoStatus = "disconnected"
+		status = http.StatusServiceUnavailable
+	} else {
+		response.Collections = make(map[string]int64, len(healthCollections))
+		for _, collection := range healthCollections {
+			count, err := h.db.CountDocuments(ctx, collection)
+			if err != nil {
+				fmt.Printf("Warning: Health check failed to count %s: %v\n", collection, err)
+				response.Status = "degraded"
+				continue
+			}
+			response.Collections[collection] = count
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Readiness is the Kubernetes readiness probe: 200 when MongoDB answers a ping, 503 otherwise
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.db.Ping(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "not ready", "mongoStatus": "disconnected"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready", "mongoStatus": "connected"})
+}
+
+// Liveness is the Kubernetes liveness probe: 200 whenever the server can handle requests
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// mockHealthDB is a HealthDatabase whose ping fails with pingErr and whose collections hold counts
+type mockHealthDB struct {
+	pingErr  error
+	counts   map[string]int64
+	countErr error
+}
+
+func (m *mockHealthDB) Ping(ctx context.Context) error {
+	return m.pingErr
+}
+
+func (m *mockHealthDB) CountDocuments(ctx context.Context, collection string) (int64, error) {
+	if m.countErr != nil {
+		return 0, m.countErr
+	}
+	return m.counts[collection], nil
+}
+
+func checkHealth(t *testing.T, db HealthDatabase) (int, HealthResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	NewHealthHandler(db).HealthCheck(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+
+	var response HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, response
+}
+
+func TestHealthCheckReportsCollectionCounts(t *testing.T) {
+	counts := map[string]int64{"products": 12, "customers": 5, "sales": 40, "purchases": 8, "quotations": 3}
+	code, response := checkHealth(t, &mockHealthDB{counts: counts})
+
+	if code != http.StatusOK {
+		t.Errorf("status = %d, want %d", code, http.StatusOK)
+	}
+	if response.Status != "healthy" || response.MongoStatus != "connected" {
+		t.Errorf("status %q, mongoStatus %q, want healthy and connected", response.Status, response.MongoStatus)
+	}
+	if !reflect.DeepEqual(response.Collections, counts) {
+		t.Errorf("collections = %v, want %v", response.Collections, counts)
+	}
+}
+
+func TestHealthCheckUnreachableDatabase(t *testing.T) {
+	code, response := checkHealth(t, &mockHealthDB{pingErr: errors.New("server selection timeout")})
+
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if response.Status != "degraded" || response.MongoStatus != "disconnected" {
+		t.Errorf("status %q, mongoStatus %q, want degraded and disconnected", response.Status, response.MongoStatus)
+	}
+	if response.Collections != nil {
+		t.Errorf("collections = %v, want none", response.Collections)
+	}
+}
+
+func TestHealthCheckFailedCountIsDegraded(t *testing.T) {
+	code, response := checkHealth(t, &mockHealthDB{countErr: errors.New("not authorized")})
+
+	if code != http.StatusOK {
+		t.Errorf("status = %d, want %d", code, http.StatusOK)
+	}
+	if response.Status != "degraded" || response.MongoStatus != "connected" {
+		t.Errorf("status %q, mongoStatus %q, want degraded and connected", response.Status, response.MongoStatus)
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	down := &mockHealthDB{pingErr: errors.New("connection refused")}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"readiness up", NewHealthHandler(&mockHealthDB{}).Readiness, http.StatusOK},
+		{"readiness down", NewHealthHandler(down).Readiness, http.StatusServiceUnavailable},
+		{"liveness down", NewHealthHandler(down).Liveness, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(http.MethodGet, "/api/health/ready", nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
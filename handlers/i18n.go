@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"context"
+
+	"goodpack-server/locales"
+	"goodpack-server/middleware"
+)
+
+// localise returns the message for an error code in the request's language (see locales/*.json)
+func localise(ctx context.Context, errorCode string) string {
+	return locales.Message(middleware.LocaleFromContext(ctx), errorCode)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// defaultExpiringLotsDays is how far ahead the expiring lots report looks without ?daysAhead=
+const defaultExpiringLotsDays = 30
+
+type LotHandler struct {
+	lotRepo     *repository.LotRepository
+	productRepo *repository.ProductRepository
+}
+
+func NewLotHandler(lotRepo *repository.LotRepository, productRepo *repository.ProductRepository) *LotHandler {
+	return &LotHandler{
+		lotRepo:     lotRepo,
+		productRepo: productRepo,
+	}
+}
+
+// GetProductLots lists the lots of a product with stock left in FEFO order; ?includeEmpty=true adds used up lots
+func (h *LotHandler) GetProductLots(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	includeEmpty := r.URL.Query().Get("includeEmpty") == "true"
+
+	if _, err := h.productRepo.GetByID(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	lots, err := h.lotRepo.GetByProduct(r.Context(), id, includeEmpty)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "lots_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lots)
+}
+
+// GetExpiringLots lists the lots with stock left that expire within ?daysAhead= days (default 30), including
+// lots that have already expired, earliest expiry first
+func (h *LotHandler) GetExpiringLots(w http.ResponseWriter, r *http.Request) {
+	daysAhead := defaultExpiringLotsDays
+	if value := r.URL.Query().Get("daysAhead"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_days_ahead"))
+			return
+		}
+		daysAhead = days
+	}
+
+	now := time.Now()
+	lots, err := h.lotRepo.GetExpiring(r.Context(), now.AddDate(0, 0, daysAhead))
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "lots_fetch_failed"))
+		return
+	}
+
+	products := make(map[string]*models.Product)
+	expiring := make([]models.ExpiringLot, 0, len(lots))
+	for _, lot := range lots {
+		product, ok := products[lot.ProductID]
+		if !ok {
+			product, _ = h.productRepo.GetByID(r.Context(), lot.ProductID)
+			products[lot.ProductID] = product
+		}
+		expiring = append(expiring, models.NewExpiringLot(lot, product, now))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expiring)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dryRunCodes stands in for the database during a dry run, remembering the codes that earlier rows
+// would have saved so duplicates within the file are still reported
+type dryRunCodes map[string]bool
+
+// claim marks code as taken; it reports false if an earlier row already took it
+func (c dryRunCodes) claim(code string) bool {
+	if c[code] {
+		return false
+	}
+	c[code] = true
+	return true
+}
+
+// next returns code, or for a generated code such as C-0003 already taken by an earlier row,
+// the first following number that is free (C-0004, C-0005, ...)
+func (c dryRunCodes) next(code string) string {
+	dash := strings.LastIndex(code, "-")
+	if dash < 0 {
+		return code
+	}
+	prefix, digits := code[:dash+1], code[dash+1:]
+	number, err := strconv.Atoi(digits)
+	if err != nil {
+		return code
+	}
+	for c[code] {
+		number++
+		code = fmt.Sprintf("%s%0*d", prefix, len(digits), number)
+	}
+	return code
+}
+
+// claimSKU claims a product's SKU ID; an empty SKU ID is generated on save, so it never clashes
+func (c dryRunCodes) claimSKU(skuID string) error {
+	if skuID != "" && !c.claim(skuID) {
+		return skuExistsError(skuID)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,23 +12,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/errgroup"
+
+	"goodpack-server/apierrors"
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/services"
+	"goodpack-server/utils"
 )
 
 type MigrationHandler struct {
-	customerRepo *repository.CustomerRepository
-	productRepo  *repository.ProductRepository
-	purchaseRepo *repository.PurchaseRepository
-	saleRepo     *repository.SaleRepository
+	customerRepo  *repository.CustomerRepository
+	productRepo   *repository.ProductRepository
+	purchaseRepo  *repository.PurchaseRepository
+	saleRepo      *repository.SaleRepository
+	migrationRepo *repository.MigrationRepository
 }
 
-func NewMigrationHandler(customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, purchaseRepo *repository.PurchaseRepository, saleRepo *repository.SaleRepository) *MigrationHandler {
+func NewMigrationHandler(customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, purchaseRepo *repository.PurchaseRepository, saleRepo *repository.SaleRepository, migrationRepo *repository.MigrationRepository) *MigrationHandler {
 	return &MigrationHandler{
-		customerRepo: customerRepo,
-		productRepo:  productRepo,
-		purchaseRepo: purchaseRepo,
-		saleRepo:     saleRepo,
+		customerRepo:  customerRepo,
+		productRepo:   productRepo,
+		purchaseRepo:  purchaseRepo,
+		saleRepo:      saleRepo,
+		migrationRepo: migrationRepo,
 	}
 }
 
@@ -42,93 +51,121 @@ type CustomerCSVRow struct {
 	ContactMethod string `csv:"contactMethod"`
 }
 
-// ProductCSVRow represents a row in the product CSV file
+// ProductCSVRow represents a row in the product CSV file, or an object in a JSON product import
 type ProductCSVRow struct {
-	SKUID               string `csv:"skuId"`
-	Name                string `csv:"name"`
-	Description         string `csv:"description"`
-	Color               string `csv:"color"`
-	Size                string `csv:"size"`
-	Category            string `csv:"category"`
-	PurchasePriceVAT    string `csv:"purchasePriceVAT"`
-	PurchasePriceNonVAT string `csv:"purchasePriceNonVAT"`
-	SalePriceVAT        string `csv:"salePriceVAT"`
-	SalePriceNonVAT     string `csv:"salePriceNonVAT"`
-	StockVAT            string `csv:"stockVAT"`
-	StockNonVAT         string `csv:"stockNonVAT"`
-	ActualStock         string `csv:"actualStock"`
+	SKUID               string `csv:"skuId" json:"skuId"`
+	Name                string `csv:"name" json:"name"`
+	Description         string `csv:"description" json:"description"`
+	Color               string `csv:"color" json:"color"`
+	Size                string `csv:"size" json:"size"`
+	Category            string `csv:"category" json:"category"`
+	PurchasePriceVAT    string `csv:"purchasePriceVAT" json:"purchasePriceVAT"`
+	PurchasePriceNonVAT string `csv:"purchasePriceNonVAT" json:"purchasePriceNonVAT"`
+	SalePriceVAT        string `csv:"salePriceVAT" json:"salePriceVAT"`
+	SalePriceNonVAT     string `csv:"salePriceNonVAT" json:"salePriceNonVAT"`
+	StockVAT            string `csv:"stockVAT" json:"stockVAT"`
+	StockNonVAT         string `csv:"stockNonVAT" json:"stockNonVAT"`
+	ActualStock         string `csv:"actualStock" json:"actualStock"`
+	Tags                string `csv:"tags" json:"tags"` // optional, comma-separated
+}
+
+// record returns the row's values in productCSVHeaders order so it can be parsed like a CSV record
+func (row ProductCSVRow) record() []string {
+	return []string{row.SKUID, row.Name, row.Description, row.Color, row.Size, row.Category, row.PurchasePriceVAT, row.PurchasePriceNonVAT, row.SalePriceVAT, row.SalePriceNonVAT, row.StockVAT, row.StockNonVAT, row.ActualStock, row.Tags}
 }
 
+// defaultProductBatchSize is how many products a JSON import inserts in parallel when batchSize is not given
+const defaultProductBatchSize = 100
+
 // PurchaseCSVRow represents a row in the purchase CSV file
 type PurchaseCSVRow struct {
-	PurchaseCode string `csv:"purchaseCode"`
-	PurchaseDate string `csv:"purchaseDate"`
-	CustomerCode string `csv:"customerCode"`
-	ProductCode  string `csv:"productCode"`
-	Quantity     string `csv:"quantity"`
-	UnitPrice    string `csv:"unitPrice"`
-	IsVAT        string `csv:"isVAT"`
-	ShippingCost string `csv:"shippingCost"`
-	Notes        string `csv:"notes"`
+	PurchaseCode    string `csv:"purchaseCode"`
+	PurchaseDate    string `csv:"purchaseDate"`
+	CustomerCode    string `csv:"customerCode"`
+	ProductCode     string `csv:"productCode"`
+	Quantity        string `csv:"quantity"`
+	UnitPrice       string `csv:"unitPrice"`
+	IsVAT           string `csv:"isVAT"`
+	ShippingCost    string `csv:"shippingCost"`
+	Notes           string `csv:"notes"`
+	DiscountPercent string `csv:"discountPercent"` // optional
+	DiscountAmount  string `csv:"discountAmount"`  // optional
 }
 
 // SaleCSVRow represents a row in the sale CSV file
 type SaleCSVRow struct {
-	SaleCode     string `csv:"saleCode"`
-	SaleDate     string `csv:"saleDate"`
-	CustomerCode string `csv:"customerCode"`
-	ProductCode  string `csv:"productCode"`
-	Quantity     string `csv:"quantity"`
-	UnitPrice    string `csv:"unitPrice"`
-	IsVAT        string `csv:"isVAT"`
-	ShippingCost string `csv:"shippingCost"`
-	Notes        string `csv:"notes"`
+	SaleCode        string `csv:"saleCode"`
+	SaleDate        string `csv:"saleDate"`
+	CustomerCode    string `csv:"customerCode"`
+	ProductCode     string `csv:"productCode"`
+	Quantity        string `csv:"quantity"`
+	UnitPrice       string `csv:"unitPrice"`
+	IsVAT           string `csv:"isVAT"`
+	ShippingCost    string `csv:"shippingCost"`
+	Notes           string `csv:"notes"`
+	DiscountPercent string `csv:"discountPercent"` // optional
+	DiscountAmount  string `csv:"discountAmount"`  // optional
 }
 
 // MigrationResult represents the result of migration
 type MigrationResult struct {
-	TotalRows   int       `json:"totalRows"`
-	SuccessRows int       `json:"successRows"`
-	FailedRows  int       `json:"failedRows"`
-	Errors      []string  `json:"errors"`
-	ProcessedAt time.Time `json:"processedAt"`
+	TransactionID string    `json:"transactionId,omitempty"`
+	TotalRows     int       `json:"totalRows"`
+	SuccessRows   int       `json:"successRows"`
+	SkippedRows   int       `json:"skippedRows"` // rows already imported by an earlier run with the same transactionId
+	FailedRows    int       `json:"failedRows"`
+	Errors        []string  `json:"errors"`
+	ProcessedAt   time.Time `json:"processedAt"`
+	DryRun        bool      `json:"dryRun,omitempty"` // rows were validated but nothing was saved
 }
 
 // MigrateCustomersFromCSV handles CSV file upload and migration
 func (h *MigrationHandler) MigrateCustomersFromCSV(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max file size
 	if err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "form_parse_failed"))
 		return
 	}
 
 	// Get the uploaded file
 	file, _, err := r.FormFile("csvFile")
 	if err != nil {
-		http.Error(w, "No CSV file uploaded", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "csv_file_required"))
 		return
 	}
 	defer file.Close()
 
+	dryRun := r.FormValue("dryRun") == "true"
+	tracker, err := newMigrationTracker(r.Context(), h.migrationRepo, strings.TrimSpace(r.FormValue("transactionId")), "customers", dryRun)
+	if errors.Is(err, errMigrationEntityMismatch) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "migration_transaction_mismatch"))
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_progress_failed"))
+		return
+	}
+
 	// Parse CSV
-	result, err := h.parseAndMigrateCustomerCSV(file)
+	result, err := h.parseAndMigrateCustomerCSV(r.Context(), file, tracker, dryRun)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process CSV: %v", err), http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "csv_process_failed", err.Error()))
 		return
 	}
+	tracker.complete(result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 // parseAndMigrateCustomerCSV parses CSV file and migrates data to database
-func (h *MigrationHandler) parseAndMigrateCustomerCSV(file io.Reader) (*MigrationResult, error) {
+func (h *MigrationHandler) parseAndMigrateCustomerCSV(ctx context.Context, file io.Reader, tracker *migrationTracker, dryRun bool) (*MigrationResult, error) {
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 
@@ -151,7 +188,7 @@ func (h *MigrationHandler) parseAndMigrateCustomerCSV(file io.Reader) (*Migratio
 
 	// Validate required headers
 	requiredHeaders := []string{"companyname", "contactname"}
-	optionalHeaders := []string{"customercode", "taxid", "phone", "address", "contactmethod"}
+	optionalHeaders := []string{"customercode", "taxid", "phone", "address", "contactmethod", "email"}
 
 	for _, required := range requiredHeaders {
 		if _, exists := headerMap[required]; !exists {
@@ -172,11 +209,20 @@ func (h *MigrationHandler) parseAndMigrateCustomerCSV(file io.Reader) (*Migratio
 		FailedRows:  0,
 		Errors:      []string{},
 		ProcessedAt: time.Now(),
+		DryRun:      dryRun,
 	}
 
+	claimed := dryRunCodes{}
+	emails := map[string]bool{} // emails of earlier rows in the file
+
 	// Process data rows
 	for i, record := range records[1:] {
 		rowNum := i + 2 // +2 because we start from row 2 (after header)
+		rowKey := fmt.Sprintf("row:%d", rowNum)
+		if tracker.isProcessed(rowKey) {
+			result.SkippedRows++
+			continue
+		}
 
 		// Create customer from CSV row
 		customer := &models.Customer{
@@ -187,6 +233,7 @@ func (h *MigrationHandler) parseAndMigrateCustomerCSV(file io.Reader) (*Migratio
 			Phone:         h.getFieldValue(record, headerMap, "phone"),
 			Address:       h.getFieldValue(record, headerMap, "address"),
 			ContactMethod: h.getFieldValue(record, headerMap, "contactmethod"),
+			Email:         models.NormalizeEmail(h.getFieldValue(record, headerMap, "email")),
 			CreatedAt:     time.Now(),
 			UpdatedAt:     time.Now(),
 		}
@@ -204,34 +251,67 @@ func (h *MigrationHandler) parseAndMigrateCustomerCSV(file io.Reader) (*Migratio
 			continue
 		}
 
+		if customer.TaxID != "" {
+			if err := utils.ValidateThaiTaxID(customer.TaxID); err != nil {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid tax ID %s: %v", rowNum, customer.TaxID, err))
+				continue
+			}
+		}
+
+		if customer.Email != "" {
+			if err := utils.ValidateEmail(customer.Email); err != nil {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Invalid email %s: %v", rowNum, customer.Email, err))
+				continue
+			}
+			if _, err := h.customerRepo.GetByEmail(ctx, customer.Email); err == nil || emails[customer.Email] {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Email '%s' already exists", rowNum, customer.Email))
+				continue
+			}
+		}
+
 		// Handle customer code
 		if customer.CustomerCode == "" {
 			// Generate customer code if not provided
-			customerCode, err := h.customerRepo.GenerateCustomerCode()
+			customerCode, err := h.customerRepo.GenerateCustomerCode(ctx)
 			if err != nil {
 				result.FailedRows++
 				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to generate customer code - %v", rowNum, err))
 				continue
 			}
+			if dryRun {
+				// Nothing is saved, so skip past the codes earlier rows would have taken
+				customerCode = claimed.next(customerCode)
+			}
 			customer.CustomerCode = customerCode
 		} else {
 			// Check if customer code already exists
-			existingCustomer, err := h.customerRepo.GetByCustomerCode(customer.CustomerCode)
-			if err == nil && existingCustomer != nil {
+			existingCustomer, err := h.customerRepo.GetByCustomerCode(ctx, customer.CustomerCode)
+			if (err == nil && existingCustomer != nil) || claimed[customer.CustomerCode] {
 				result.FailedRows++
 				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Customer code '%s' already exists", rowNum, customer.CustomerCode))
 				continue
 			}
 		}
 
-		// Save to database
-		err := h.customerRepo.Create(customer)
-		if err != nil {
-			result.FailedRows++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save customer - %v", rowNum, err))
-			continue
+		if dryRun {
+			claimed.claim(customer.CustomerCode)
+		} else {
+			// Save to database
+			err := h.customerRepo.Create(ctx, customer)
+			if err != nil {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save customer - %v", rowNum, err))
+				continue
+			}
+		}
+		if customer.Email != "" {
+			emails[customer.Email] = true
 		}
 
+		tracker.recordSuccess(rowKey)
 		result.SuccessRows++
 	}
 
@@ -249,15 +329,15 @@ func (h *MigrationHandler) getFieldValue(record []string, headerMap map[string]i
 // GetCustomerCSVTemplate returns a CSV template for customer data
 func (h *MigrationHandler) GetCustomerCSVTemplate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Create CSV template
-	template := "customerCode,companyName,contactName,taxId,phone,address,contactMethod\n"
-	template += "C-0001,บริษัทตัวอย่าง จำกัด,นายสมชาย ใจดี,1234567890123,02-123-4567,123 ถนนสุขุมวิท กรุงเทพฯ 10110,email\n"
-	template += ",บริษัททดสอบ จำกัด,นางสมหญิง รักดี,9876543210987,02-987-6543,456 ถนนรัชดาภิเษก กรุงเทพฯ 10400,phone\n"
-	template += "C-0003,บริษัทสินค้าดี จำกัด,นายวิชัย เก่งมาก,1111111111111,02-111-2222,789 ถนนพหลโยธิน กรุงเทพฯ 10900,line\n"
+	template := "customerCode,companyName,contactName,taxId,phone,address,contactMethod,email\n"
+	template += "C-0001,บริษัทตัวอย่าง จำกัด,นายสมชาย ใจดี,1234567890123,02-123-4567,123 ถนนสุขุมวิท กรุงเทพฯ 10110,email,somchai@example.co.th\n"
+	template += ",บริษัททดสอบ จำกัด,นางสมหญิง รักดี,9876543210987,02-987-6543,456 ถนนรัชดาภิเษก กรุงเทพฯ 10400,phone,\n"
+	template += "C-0003,บริษัทสินค้าดี จำกัด,นายวิชัย เก่งมาก,1111111111111,02-111-2222,789 ถนนพหลโยธิน กรุงเทพฯ 10900,line,\n"
 
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=customer_template.csv")
@@ -266,15 +346,17 @@ func (h *MigrationHandler) GetCustomerCSVTemplate(w http.ResponseWriter, r *http
 
 // GetMigrationStatus returns the status of recent migrations
 func (h *MigrationHandler) GetMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Get total customer count
-	customers, err := h.customerRepo.GetAll()
+	customers, err := h.customerRepo.GetAll(ctx, nil)
 	if err != nil {
-		http.Error(w, "Failed to get customer count", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_count_failed"))
 		return
 	}
 
@@ -287,41 +369,71 @@ func (h *MigrationHandler) GetMigrationStatus(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(status)
 }
 
+// GetMigrationProgress returns the progress of the migration started with a transaction ID
+func (h *MigrationHandler) GetMigrationProgress(w http.ResponseWriter, r *http.Request) {
+	transactionID := mux.Vars(r)["transactionId"]
+
+	migration, err := h.migrationRepo.GetByTransactionID(r.Context(), transactionID)
+	if err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "migration_not_found"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(migration)
+}
+
 // MigrateProductsFromCSV handles CSV file upload and migration for products
 func (h *MigrationHandler) MigrateProductsFromCSV(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max file size
 	if err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "form_parse_failed"))
 		return
 	}
 
 	// Get the uploaded file
 	file, _, err := r.FormFile("csvFile")
 	if err != nil {
-		http.Error(w, "No CSV file uploaded", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "csv_file_required"))
 		return
 	}
 	defer file.Close()
 
+	dryRun := r.FormValue("dryRun") == "true"
+	tracker, err := newMigrationTracker(r.Context(), h.migrationRepo, strings.TrimSpace(r.FormValue("transactionId")), "products", dryRun)
+	if errors.Is(err, errMigrationEntityMismatch) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "migration_transaction_mismatch"))
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_progress_failed"))
+		return
+	}
+
 	// Parse CSV
-	result, err := h.parseAndMigrateProductCSV(file)
+	result, err := h.parseAndMigrateProductCSV(r.Context(), file, tracker, dryRun)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process CSV: %v", err), http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "csv_process_failed", err.Error()))
 		return
 	}
+	tracker.complete(result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 // parseAndMigrateProductCSV parses CSV file and migrates product data to database
-func (h *MigrationHandler) parseAndMigrateProductCSV(file io.Reader) (*MigrationResult, error) {
+func (h *MigrationHandler) parseAndMigrateProductCSV(ctx context.Context, file io.Reader, tracker *migrationTracker, dryRun bool) (*MigrationResult, error) {
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 
@@ -344,7 +456,7 @@ func (h *MigrationHandler) parseAndMigrateProductCSV(file io.Reader) (*Migration
 
 	// Validate required headers
 	requiredHeaders := []string{"name", "category"}
-	optionalHeaders := []string{"skuid", "description", "color", "size", "purchasepricevat", "purchasepricenonvat", "salepricevat", "salepricenonvat", "stockvat", "stocknonvat", "actualstock"}
+	optionalHeaders := []string{"skuid", "description", "color", "size", "purchasepricevat", "purchasepricenonvat", "salepricevat", "salepricenonvat", "stockvat", "stocknonvat", "actualstock", "tags"}
 
 	for _, required := range requiredHeaders {
 		if _, exists := headerMap[required]; !exists {
@@ -373,78 +485,212 @@ func (h *MigrationHandler) parseAndMigrateProductCSV(file io.Reader) (*Migration
 		FailedRows:  0,
 		Errors:      []string{},
 		ProcessedAt: time.Now(),
+		DryRun:      dryRun,
 	}
 
+	claimed := dryRunCodes{}
+
 	// Process data rows
 	for i, record := range records[1:] {
 		rowNum := i + 2 // +2 because we start from row 2 (after header)
+		rowKey := fmt.Sprintf("row:%d", rowNum)
+		if tracker.isProcessed(rowKey) {
+			result.SkippedRows++
+			continue
+		}
 
 		// Create product from CSV row
-		colorValue := h.getFieldValue(record, headerMap, "color")
-		descriptionValue := h.getFieldValue(record, headerMap, "description")
-		product := &models.Product{
-			SKUID:       h.getFieldValue(record, headerMap, "skuid"),
-			Name:        h.getFieldValue(record, headerMap, "name"),
-			Description: descriptionValue,
-			Color:       colorValue,
-			Size:        h.getFieldValue(record, headerMap, "size"),
-			Category:    h.getFieldValue(record, headerMap, "category"),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
+		product := h.productFromRecord(record, headerMap)
 
 		// Debug: Log parsed values for first row
 		if i == 0 {
-			fmt.Printf("Row %d: Color value parsed: '%s' (length: %d)\n", rowNum, colorValue, len(colorValue))
-			fmt.Printf("Row %d: Description value parsed: '%s' (length: %d)\n", rowNum, descriptionValue, len(descriptionValue))
+			fmt.Printf("Row %d: Color value parsed: '%s' (length: %d)\n", rowNum, product.Color, len(product.Color))
+			fmt.Printf("Row %d: Description value parsed: '%s' (length: %d)\n", rowNum, product.Description, len(product.Description))
 			fmt.Printf("Row %d: Product before save - Color: '%s', Description: '%s'\n", rowNum, product.Color, product.Description)
 		}
 
-		// Validate required fields
-		if product.Name == "" {
+		err := h.validateAndCreateProduct(ctx, product, dryRun)
+		if err == nil && dryRun {
+			err = claimed.claimSKU(product.SKUID)
+		}
+		if err != nil {
 			result.FailedRows++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Product name is required", rowNum))
+			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %v", rowNum, err))
 			continue
 		}
 
-		if product.Category == "" {
-			result.FailedRows++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Category is required", rowNum))
-			continue
+		tracker.recordSuccess(rowKey)
+		result.SuccessRows++
+	}
+
+	return result, nil
+}
+
+// MigrateProductsFromJSON imports products from a JSON array of objects with the product CSV columns.
+// Products are inserted batchSize at a time, each batch in parallel.
+func (h *MigrationHandler) MigrateProductsFromJSON(w http.ResponseWriter, r *http.Request) {
+	batchSize := defaultProductBatchSize
+	if value := r.URL.Query().Get("batchSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_batch_size"))
+			return
 		}
+		batchSize = size
+	}
 
-		// Parse prices
-		product.Price = h.parseProductPrices(record, headerMap)
+	var rows []ProductCSVRow
+	if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if len(rows) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "products_json_required"))
+		return
+	}
 
-		// Parse stock
-		product.Stock = h.parseProductStock(record, headerMap)
-		// Handle SKU ID
-		if product.SKUID != "" {
-			// Check if SKU ID already exists
-			existingProduct, err := h.productRepo.GetBySKUID(context.Background(), product.SKUID)
-			if err == nil && existingProduct != nil {
-				result.FailedRows++
-				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: SKU ID '%s' already exists", rowNum, product.SKUID))
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	tracker, err := newMigrationTracker(r.Context(), h.migrationRepo, strings.TrimSpace(r.URL.Query().Get("transactionId")), "products", dryRun)
+	if errors.Is(err, errMigrationEntityMismatch) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "migration_transaction_mismatch"))
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_progress_failed"))
+		return
+	}
+
+	result := h.migrateProductRows(r.Context(), rows, batchSize, tracker, dryRun)
+	tracker.complete(result)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// migrateProductRows creates the products in batches of batchSize, inserting each batch in parallel
+func (h *MigrationHandler) migrateProductRows(ctx context.Context, rows []ProductCSVRow, batchSize int, tracker *migrationTracker, dryRun bool) *MigrationResult {
+	headerMap := make(map[string]int, len(productCSVHeaders))
+	for i, header := range productCSVHeaders {
+		headerMap[strings.ToLower(header)] = i
+	}
+
+	result := &MigrationResult{
+		TotalRows:   len(rows),
+		Errors:      []string{},
+		ProcessedAt: time.Now(),
+		DryRun:      dryRun,
+	}
+	claimed := dryRunCodes{}
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+		skipped := make([]bool, end-start)
+		skuIDs := make([]string, end-start)
+		errs := make([]error, end-start)
+
+		// Rows of a batch are inserted in parallel, so a SKU ID repeated within the batch is caught here rather
+		// than racing between the existence check and the insert
+		batchSKUs := make(map[string]bool)
+		var g errgroup.Group
+		for i := start; i < end; i++ {
+			i := i
+			if tracker.isProcessed(fmt.Sprintf("item:%d", i+1)) {
+				skipped[i-start] = true
 				continue
 			}
+			product := h.productFromRecord(rows[i].record(), headerMap)
+			skuIDs[i-start] = product.SKUID
+			if product.SKUID != "" {
+				if batchSKUs[product.SKUID] {
+					errs[i-start] = skuExistsError(product.SKUID)
+					continue
+				}
+				batchSKUs[product.SKUID] = true
+			}
+			g.Go(func() error {
+				errs[i-start] = h.validateAndCreateProduct(ctx, product, dryRun)
+				return nil
+			})
 		}
-		// If SKUID is empty, it will be generated by the repository
+		g.Wait()
 
-		// Generate Product Code
-		product.Code = h.generateProductCode(product.Category, product.Size, product.Color)
+		// Tally in order so errors and progress match the request
+		for i := start; i < end; i++ {
+			if dryRun && !skipped[i-start] && errs[i-start] == nil {
+				errs[i-start] = claimed.claimSKU(skuIDs[i-start])
+			}
+			switch {
+			case skipped[i-start]:
+				result.SkippedRows++
+			case errs[i-start] != nil:
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Item %d: %v", i+1, errs[i-start]))
+			default:
+				tracker.recordSuccess(fmt.Sprintf("item:%d", i+1))
+				result.SuccessRows++
+			}
+		}
+	}
 
-		// Save to database
-		err := h.productRepo.Create(context.Background(), product)
-		if err != nil {
-			result.FailedRows++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save product - %v", rowNum, err))
-			continue
+	return result
+}
+
+// productFromRecord builds a product from a CSV record, reading the columns through headerMap
+func (h *MigrationHandler) productFromRecord(record []string, headerMap map[string]int) *models.Product {
+	return &models.Product{
+		SKUID:       h.getFieldValue(record, headerMap, "skuid"),
+		Name:        h.getFieldValue(record, headerMap, "name"),
+		Description: h.getFieldValue(record, headerMap, "description"),
+		Color:       h.getFieldValue(record, headerMap, "color"),
+		Size:        h.getFieldValue(record, headerMap, "size"),
+		Category:    h.getFieldValue(record, headerMap, "category"),
+		Tags:        models.NormalizeTags(strings.Split(h.getFieldValue(record, headerMap, "tags"), ",")),
+		Price:       h.parseProductPrices(record, headerMap),
+		Stock:       h.parseProductStock(record, headerMap),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
+
+// validateAndCreateProduct checks an imported product's required fields and SKU ID, then saves it unless dryRun
+func (h *MigrationHandler) validateAndCreateProduct(ctx context.Context, product *models.Product, dryRun bool) error {
+	// Validate required fields
+	if product.Name == "" {
+		return errors.New("Product name is required")
+	}
+	if product.Category == "" {
+		return errors.New("Category is required")
+	}
+
+	// Handle SKU ID
+	if product.SKUID != "" {
+		// Check if SKU ID already exists
+		existingProduct, err := h.productRepo.GetBySKUID(ctx, product.SKUID)
+		if err == nil && existingProduct != nil {
+			return skuExistsError(product.SKUID)
 		}
+	}
+	// If SKUID is empty, it will be generated by the repository
 
-		result.SuccessRows++
+	// Generate Product Code
+	product.Code = h.generateProductCode(product.Category, product.Size, product.Color)
+	if dryRun {
+		return nil
 	}
 
-	return result, nil
+	// Save to database
+	if err := h.productRepo.Create(ctx, product); err != nil {
+		if errors.Is(err, repository.ErrSKUInUse) {
+			return skuExistsError(product.SKUID)
+		}
+		return fmt.Errorf("Failed to save product - %v", err)
+	}
+	return nil
+}
+
+// skuExistsError is the row error for a product whose SKU ID another product already has
+func skuExistsError(skuID string) error {
+	return fmt.Errorf("SKU ID '%s' already exists", skuID)
 }
 
 // parseProductPrices parses price information from CSV row
@@ -540,15 +786,15 @@ func (h *MigrationHandler) parseInt(s string) (int, error) {
 // GetProductCSVTemplate returns a CSV template for product data
 func (h *MigrationHandler) GetProductCSVTemplate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Create CSV template
-	template := "skuId,name,description,color,size,category,purchasePriceVAT,purchasePriceNonVAT,salePriceVAT,salePriceNonVAT,stockVAT,stockNonVAT,actualStock\n"
-	template += "SH-0001,เสื้อเชิ้ต,เสื้อเชิ้ตผ้าฝ้าย,ขาว,L,เสื้อผ้า,299.00,250.00,399.00,350.00,50,30,80\n"
-	template += ",กางเกงยีนส์,กางเกงยีนส์สไตล์สตรีท,น้ำเงิน,32,กางเกง,599.00,500.00,799.00,650.00,25,15,40\n"
-	template += "AC-0001,กระเป๋า,กระเป๋าหนังแท้,ดำ,One Size,กระเป๋า,1299.00,1100.00,1799.00,1500.00,10,5,15\n"
+	template := "skuId,name,description,color,size,category,purchasePriceVAT,purchasePriceNonVAT,salePriceVAT,salePriceNonVAT,stockVAT,stockNonVAT,actualStock,tags\n"
+	template += "SH-0001,เสื้อเชิ้ต,เสื้อเชิ้ตผ้าฝ้าย,ขาว,L,เสื้อผ้า,299.00,250.00,399.00,350.00,50,30,80,\"summer,sale\"\n"
+	template += ",กางเกงยีนส์,กางเกงยีนส์สไตล์สตรีท,น้ำเงิน,32,กางเกง,599.00,500.00,799.00,650.00,25,15,40,\n"
+	template += "AC-0001,กระเป๋า,กระเป๋าหนังแท้,ดำ,One Size,กระเป๋า,1299.00,1100.00,1799.00,1500.00,10,5,15,leather\n"
 
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=product_template.csv")
@@ -594,38 +840,50 @@ func (h *MigrationHandler) generateProductCode(category, size, color string) str
 // MigratePurchasesFromCSV handles CSV file upload and migration for purchases
 func (h *MigrationHandler) MigratePurchasesFromCSV(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max file size
 	if err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "form_parse_failed"))
 		return
 	}
 
 	// Get the uploaded file
 	file, _, err := r.FormFile("csvFile")
 	if err != nil {
-		http.Error(w, "No CSV file uploaded", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "csv_file_required"))
 		return
 	}
 	defer file.Close()
 
+	dryRun := r.FormValue("dryRun") == "true"
+	tracker, err := newMigrationTracker(r.Context(), h.migrationRepo, strings.TrimSpace(r.FormValue("transactionId")), "purchases", dryRun)
+	if errors.Is(err, errMigrationEntityMismatch) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "migration_transaction_mismatch"))
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_progress_failed"))
+		return
+	}
+
 	// Parse CSV
-	result, err := h.parseAndMigratePurchaseCSV(file)
+	result, err := h.parseAndMigratePurchaseCSV(r.Context(), file, tracker, dryRun)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process CSV: %v", err), http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "csv_process_failed", err.Error()))
 		return
 	}
+	tracker.complete(result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 // parseAndMigratePurchaseCSV parses CSV file and migrates purchase data to database
-func (h *MigrationHandler) parseAndMigratePurchaseCSV(file io.Reader) (*MigrationResult, error) {
+func (h *MigrationHandler) parseAndMigratePurchaseCSV(ctx context.Context, file io.Reader, tracker *migrationTracker, dryRun bool) (*MigrationResult, error) {
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 
@@ -669,6 +927,7 @@ func (h *MigrationHandler) parseAndMigratePurchaseCSV(file io.Reader) (*Migratio
 		FailedRows:  0,
 		Errors:      []string{},
 		ProcessedAt: time.Now(),
+		DryRun:      dryRun,
 	}
 
 	// Group records by purchase (same purchaseCode or purchaseDate + customerCode)
@@ -677,9 +936,14 @@ func (h *MigrationHandler) parseAndMigratePurchaseCSV(file io.Reader) (*Migratio
 	// Process each purchase group
 	for groupKey, groupRecords := range purchaseGroups {
 		rowNum := groupRecords[0].RowNum
+		rowKey := "group:" + groupKey
+		if tracker.isProcessed(rowKey) {
+			result.SkippedRows++
+			continue
+		}
 
 		// Create purchase from CSV group
-		purchase, err := h.createPurchaseFromGroup(groupRecords, headerMap)
+		purchase, err := h.createPurchaseFromGroup(ctx, groupRecords, headerMap)
 		if err != nil {
 			result.FailedRows++
 			result.Errors = append(result.Errors, fmt.Sprintf("Group %s: %v", groupKey, err))
@@ -699,8 +963,24 @@ func (h *MigrationHandler) parseAndMigratePurchaseCSV(file io.Reader) (*Migratio
 			continue
 		}
 
+		if dryRun {
+			// The unique index would reject a code that is already taken, so check it instead of saving
+			exists, err := h.purchaseRepo.PurchaseCodeExists(ctx, purchase.PurchaseCode)
+			if err == nil && exists {
+				err = fmt.Errorf("purchase code '%s' already exists", purchase.PurchaseCode)
+			}
+			if err != nil {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save purchase - %v", rowNum, err))
+				continue
+			}
+			tracker.recordSuccess(rowKey)
+			result.SuccessRows++
+			continue
+		}
+
 		// Save to database
-		err = h.purchaseRepo.Create(context.Background(), purchase)
+		err = h.purchaseRepo.Create(ctx, purchase)
 		if err != nil {
 			result.FailedRows++
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save purchase - %v", rowNum, err))
@@ -708,11 +988,12 @@ func (h *MigrationHandler) parseAndMigratePurchaseCSV(file io.Reader) (*Migratio
 		}
 
 		// Update product prices and stock
-		err = h.updateProductsFromPurchase(purchase)
+		err = h.updateProductsFromPurchase(ctx, purchase)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to update products - %v", rowNum, err))
 		}
 
+		tracker.recordSuccess(rowKey)
 		result.SuccessRows++
 	}
 
@@ -754,7 +1035,7 @@ func (h *MigrationHandler) groupPurchaseRecords(records [][]string, headerMap ma
 }
 
 // createPurchaseFromGroup creates a purchase from a group of CSV records
-func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, headerMap map[string]int) (*models.Purchase, error) {
+func (h *MigrationHandler) createPurchaseFromGroup(ctx context.Context, records []PurchaseRecord, headerMap map[string]int) (*models.Purchase, error) {
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no records in group")
 	}
@@ -770,7 +1051,7 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 
 	// Get customer by code
 	customerCode := h.getFieldValue(firstRecord, headerMap, "customercode")
-	customer, err := h.customerRepo.GetByCustomerCode(customerCode)
+	customer, err := h.customerRepo.GetByCustomerCode(ctx, customerCode)
 	if err != nil {
 		return nil, fmt.Errorf("customer not found: %s", customerCode)
 	}
@@ -790,13 +1071,14 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 
 	// Create purchase items
 	var items []models.PurchaseItem
+	var discountTotal float64
 	for _, record := range records {
 		productCode := h.getFieldValue(record.Record, headerMap, "productcode")
 		quantityStr := h.getFieldValue(record.Record, headerMap, "quantity")
 		unitPriceStr := h.getFieldValue(record.Record, headerMap, "unitprice")
 
 		// Get product by code
-		product, err := h.productRepo.GetByCode(context.Background(), productCode)
+		product, err := h.productRepo.GetByCode(ctx, productCode)
 		if err != nil {
 			return nil, fmt.Errorf("product not found: %s", productCode)
 		}
@@ -811,15 +1093,31 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 			return nil, fmt.Errorf("invalid unit price: %s", unitPriceStr)
 		}
 
-		totalPrice := unitPrice * float64(quantity)
+		// Optional discount columns
+		discountPercentStr := h.getFieldValue(record.Record, headerMap, "discountpercent")
+		discountPercent, err := h.parseFloat(discountPercentStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discount percent: %s", discountPercentStr)
+		}
+
+		discountAmountStr := h.getFieldValue(record.Record, headerMap, "discountamount")
+		discountAmount, err := h.parseFloat(discountAmountStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discount amount: %s", discountAmountStr)
+		}
+
+		totalPrice := models.CalculateLineTotal(float64(quantity), unitPrice, discountPercent, discountAmount)
+		discountTotal += unitPrice*float64(quantity) - totalPrice
 
 		items = append(items, models.PurchaseItem{
-			ProductID:   product.ID.Hex(),
-			ProductName: product.Name,
-			ProductCode: product.Code,
-			Quantity:    quantity,
-			UnitPrice:   unitPrice,
-			TotalPrice:  totalPrice,
+			ProductID:       product.ID.Hex(),
+			ProductName:     product.Name,
+			ProductCode:     product.Code,
+			Quantity:        quantity,
+			UnitPrice:       unitPrice,
+			TotalPrice:      totalPrice,
+			DiscountPercent: discountPercent,
+			DiscountAmount:  discountAmount,
 		})
 	}
 
@@ -831,7 +1129,7 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 
 	var totalVAT float64
 	if isVAT {
-		totalVAT = totalAmount * 0.07 // 7% VAT
+		totalVAT = totalAmount * models.VATRate
 	}
 
 	grandTotal := totalAmount + totalVAT + shippingCost
@@ -839,7 +1137,7 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 	// Generate purchase code if not provided
 	purchaseCode := h.getFieldValue(firstRecord, headerMap, "purchasecode")
 	if purchaseCode == "" {
-		purchaseCode, err = h.generatePurchaseCode(isVAT)
+		purchaseCode, err = services.GeneratePurchaseCode(ctx, h.purchaseRepo, isVAT)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate purchase code: %v", err)
 		}
@@ -861,6 +1159,7 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 		Notes:        notesPtr,
 		Items:        items,
 		IsVAT:        isVAT,
+		VATRate:      models.VATRate,
 		ShippingCost: shippingCost,
 		Payment: models.PaymentInfo{
 			IsPaid: false,
@@ -869,19 +1168,20 @@ func (h *MigrationHandler) createPurchaseFromGroup(records []PurchaseRecord, hea
 			IsUpdated:      false,
 			ActualShipping: shippingCost,
 		},
-		TotalAmount: totalAmount,
-		TotalVAT:    totalVAT,
-		GrandTotal:  grandTotal,
+		TotalAmount:   totalAmount,
+		DiscountTotal: discountTotal,
+		TotalVAT:      totalVAT,
+		GrandTotal:    grandTotal,
 	}
 
 	return purchase, nil
 }
 
 // updateProductsFromPurchase updates product prices and stock based on purchase
-func (h *MigrationHandler) updateProductsFromPurchase(purchase *models.Purchase) error {
+func (h *MigrationHandler) updateProductsFromPurchase(ctx context.Context, purchase *models.Purchase) error {
 	for _, item := range purchase.Items {
 		// Get product
-		product, err := h.productRepo.GetByID(context.Background(), item.ProductID)
+		product, err := h.productRepo.GetByID(ctx, item.ProductID)
 		if err != nil {
 			return fmt.Errorf("failed to get product %s: %v", item.ProductID, err)
 		}
@@ -900,7 +1200,7 @@ func (h *MigrationHandler) updateProductsFromPurchase(purchase *models.Purchase)
 		product.Stock.ActualStock += item.Quantity
 
 		// Save updated product
-		err = h.productRepo.Update(context.Background(), item.ProductID, product)
+		err = h.productRepo.Update(ctx, item.ProductID, product)
 		if err != nil {
 			return fmt.Errorf("failed to update product %s: %v", item.ProductID, err)
 		}
@@ -909,35 +1209,18 @@ func (h *MigrationHandler) updateProductsFromPurchase(purchase *models.Purchase)
 	return nil
 }
 
-// generatePurchaseCode generates a unique purchase code
-func (h *MigrationHandler) generatePurchaseCode(isVAT bool) (string, error) {
-	// This is a simplified version - you might want to use the actual repository method
-	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
-	day := now.Day()
-
-	prefix := "P"
-	if isVAT {
-		prefix = "PV"
-	}
-
-	// Simple format: P-YYYYMMDD-001 or PV-YYYYMMDD-001
-	return fmt.Sprintf("%s-%04d%02d%02d-001", prefix, year, month, day), nil
-}
-
 // GetPurchaseCSVTemplate returns a CSV template for purchase data
 func (h *MigrationHandler) GetPurchaseCSVTemplate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Create CSV template
-	template := "purchaseCode,purchaseDate,customerCode,productCode,quantity,unitPrice,isVAT,shippingCost,notes\n"
-	template += "P-001,2024-01-15,C-0001,เ-l/WH,10,299.00,true,50.00,ซื้อเสื้อเชิ้ต\n"
-	template += ",2024-01-15,C-0001,ก-32/BL,5,599.00,true,,ซื้อกางเกงยีนส์\n"
-	template += "P-002,2024-01-16,C-0002,ก-onesize/BK,2,1299.00,false,100.00,ซื้อกระเป๋า\n"
+	template := "purchaseCode,purchaseDate,customerCode,productCode,quantity,unitPrice,isVAT,shippingCost,notes,discountPercent,discountAmount\n"
+	template += "P-001,2024-01-15,C-0001,เ-l/WH,10,299.00,true,50.00,ซื้อเสื้อเชิ้ต,5,\n"
+	template += ",2024-01-15,C-0001,ก-32/BL,5,599.00,true,,ซื้อกางเกงยีนส์,,\n"
+	template += "P-002,2024-01-16,C-0002,ก-onesize/BK,2,1299.00,false,100.00,ซื้อกระเป๋า,,100.00\n"
 
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=purchase_template.csv")
@@ -947,38 +1230,50 @@ func (h *MigrationHandler) GetPurchaseCSVTemplate(w http.ResponseWriter, r *http
 // MigrateSalesFromCSV handles CSV file upload and migration for sales
 func (h *MigrationHandler) MigrateSalesFromCSV(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max file size
 	if err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "form_parse_failed"))
 		return
 	}
 
 	// Get the uploaded file
 	file, _, err := r.FormFile("csvFile")
 	if err != nil {
-		http.Error(w, "No CSV file uploaded", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "csv_file_required"))
 		return
 	}
 	defer file.Close()
 
+	dryRun := r.FormValue("dryRun") == "true"
+	tracker, err := newMigrationTracker(r.Context(), h.migrationRepo, strings.TrimSpace(r.FormValue("transactionId")), "sales", dryRun)
+	if errors.Is(err, errMigrationEntityMismatch) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "migration_transaction_mismatch"))
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "migration_progress_failed"))
+		return
+	}
+
 	// Parse CSV
-	result, err := h.parseAndMigrateSaleCSV(file)
+	result, err := h.parseAndMigrateSaleCSV(r.Context(), file, tracker, dryRun)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to process CSV: %v", err), http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "csv_process_failed", err.Error()))
 		return
 	}
+	tracker.complete(result)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
 // parseAndMigrateSaleCSV parses CSV file and migrates sale data to database
-func (h *MigrationHandler) parseAndMigrateSaleCSV(file io.Reader) (*MigrationResult, error) {
+func (h *MigrationHandler) parseAndMigrateSaleCSV(ctx context.Context, file io.Reader, tracker *migrationTracker, dryRun bool) (*MigrationResult, error) {
 	reader := csv.NewReader(file)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 
@@ -1022,6 +1317,7 @@ func (h *MigrationHandler) parseAndMigrateSaleCSV(file io.Reader) (*MigrationRes
 		FailedRows:  0,
 		Errors:      []string{},
 		ProcessedAt: time.Now(),
+		DryRun:      dryRun,
 	}
 
 	// Group records by sale (same saleCode or saleDate + customerCode)
@@ -1030,9 +1326,14 @@ func (h *MigrationHandler) parseAndMigrateSaleCSV(file io.Reader) (*MigrationRes
 	// Process each sale group
 	for groupKey, groupRecords := range saleGroups {
 		rowNum := groupRecords[0].RowNum
+		rowKey := "group:" + groupKey
+		if tracker.isProcessed(rowKey) {
+			result.SkippedRows++
+			continue
+		}
 
 		// Create sale from CSV group
-		sale, err := h.createSaleFromGroup(groupRecords, headerMap)
+		sale, err := h.createSaleFromGroup(ctx, groupRecords, headerMap)
 		if err != nil {
 			result.FailedRows++
 			result.Errors = append(result.Errors, fmt.Sprintf("Group %s: %v", groupKey, err))
@@ -1052,8 +1353,24 @@ func (h *MigrationHandler) parseAndMigrateSaleCSV(file io.Reader) (*MigrationRes
 			continue
 		}
 
+		if dryRun {
+			// The unique index would reject a code that is already taken, so check it instead of saving
+			exists, err := h.saleRepo.SaleCodeExists(ctx, sale.SaleCode)
+			if err == nil && exists {
+				err = fmt.Errorf("sale code '%s' already exists", sale.SaleCode)
+			}
+			if err != nil {
+				result.FailedRows++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save sale - %v", rowNum, err))
+				continue
+			}
+			tracker.recordSuccess(rowKey)
+			result.SuccessRows++
+			continue
+		}
+
 		// Save to database
-		err = h.saleRepo.Create(sale)
+		err = h.saleRepo.Create(ctx, sale)
 		if err != nil {
 			result.FailedRows++
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to save sale - %v", rowNum, err))
@@ -1061,11 +1378,12 @@ func (h *MigrationHandler) parseAndMigrateSaleCSV(file io.Reader) (*MigrationRes
 		}
 
 		// Update product prices and stock
-		err = h.updateProductsFromSale(sale)
+		err = h.updateProductsFromSale(ctx, sale)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: Failed to update products - %v", rowNum, err))
 		}
 
+		tracker.recordSuccess(rowKey)
 		result.SuccessRows++
 	}
 
@@ -1107,7 +1425,7 @@ func (h *MigrationHandler) groupSaleRecords(records [][]string, headerMap map[st
 }
 
 // createSaleFromGroup creates a sale from a group of CSV records
-func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap map[string]int) (*models.Sale, error) {
+func (h *MigrationHandler) createSaleFromGroup(ctx context.Context, records []SaleRecord, headerMap map[string]int) (*models.Sale, error) {
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no records in group")
 	}
@@ -1123,7 +1441,7 @@ func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap m
 
 	// Get customer by code
 	customerCode := h.getFieldValue(firstRecord, headerMap, "customercode")
-	customer, err := h.customerRepo.GetByCustomerCode(customerCode)
+	customer, err := h.customerRepo.GetByCustomerCode(ctx, customerCode)
 	if err != nil {
 		return nil, fmt.Errorf("customer not found: %s", customerCode)
 	}
@@ -1143,19 +1461,20 @@ func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap m
 
 	// Create sale items
 	var items []models.SaleItem
+	var discountTotal float64
 	for _, record := range records {
 		productCode := h.getFieldValue(record.Record, headerMap, "productcode")
 		quantityStr := h.getFieldValue(record.Record, headerMap, "quantity")
 		unitPriceStr := h.getFieldValue(record.Record, headerMap, "unitprice")
 
 		// Get product by code
-		product, err := h.productRepo.GetByCode(context.Background(), productCode)
+		product, err := h.productRepo.GetByCode(ctx, productCode)
 		if err != nil {
 			return nil, fmt.Errorf("product not found: %s", productCode)
 		}
 
-		quantity, err := h.parseInt(quantityStr)
-		if err != nil {
+		quantity, err := h.parseFloat(quantityStr)
+		if err != nil || !models.IsWholeQuantity(quantity) {
 			return nil, fmt.Errorf("invalid quantity: %s", quantityStr)
 		}
 
@@ -1164,22 +1483,38 @@ func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap m
 			return nil, fmt.Errorf("invalid unit price: %s", unitPriceStr)
 		}
 
-		totalPrice := unitPrice * float64(quantity)
+		// Optional discount columns
+		discountPercentStr := h.getFieldValue(record.Record, headerMap, "discountpercent")
+		discountPercent, err := h.parseFloat(discountPercentStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discount percent: %s", discountPercentStr)
+		}
+
+		discountAmountStr := h.getFieldValue(record.Record, headerMap, "discountamount")
+		discountAmount, err := h.parseFloat(discountAmountStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discount amount: %s", discountAmountStr)
+		}
+
+		totalPrice := models.CalculateLineTotal(quantity, unitPrice, discountPercent, discountAmount)
+		discountTotal += unitPrice*quantity - totalPrice
 
 		items = append(items, models.SaleItem{
-			ProductID:   product.ID.Hex(),
-			ProductName: product.Name,
-			ProductCode: product.Code,
-			Quantity:    quantity,
-			UnitPrice:   unitPrice,
-			TotalPrice:  totalPrice,
+			ProductID:       product.ID.Hex(),
+			ProductName:     product.Name,
+			ProductCode:     product.Code,
+			Quantity:        quantity,
+			UnitPrice:       unitPrice,
+			TotalPrice:      totalPrice,
+			DiscountPercent: discountPercent,
+			DiscountAmount:  discountAmount,
 		})
 	}
 
 	// Generate sale code if not provided
 	saleCode := h.getFieldValue(firstRecord, headerMap, "salecode")
 	if saleCode == "" {
-		saleCode, err = h.generateSaleCode(isVAT)
+		saleCode, err = services.GenerateSaleCode(ctx, h.saleRepo, isVAT)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate sale code: %v", err)
 		}
@@ -1200,6 +1535,7 @@ func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap m
 		Phone:        &customer.Phone,
 		Items:        items,
 		IsVAT:        isVAT,
+		VATRate:      models.VATRate,
 		ShippingCost: shippingCost,
 		Payment: models.PaymentInfo{
 			IsPaid: false,
@@ -1208,17 +1544,18 @@ func (h *MigrationHandler) createSaleFromGroup(records []SaleRecord, headerMap m
 			IsUpdated:      false,
 			ActualShipping: shippingCost,
 		},
-		Notes: notesPtr,
+		Notes:         notesPtr,
+		DiscountTotal: discountTotal,
 	}
 
 	return sale, nil
 }
 
 // updateProductsFromSale updates product prices and stock based on sale
-func (h *MigrationHandler) updateProductsFromSale(sale *models.Sale) error {
+func (h *MigrationHandler) updateProductsFromSale(ctx context.Context, sale *models.Sale) error {
 	for _, item := range sale.Items {
 		// Get product
-		product, err := h.productRepo.GetByID(context.Background(), item.ProductID)
+		product, err := h.productRepo.GetByID(ctx, item.ProductID)
 		if err != nil {
 			return fmt.Errorf("failed to get product %s: %v", item.ProductID, err)
 		}
@@ -1227,20 +1564,21 @@ func (h *MigrationHandler) updateProductsFromSale(sale *models.Sale) error {
 		product.UpdatePrice(item.UnitPrice, sale.IsVAT, false) // false = isSale
 
 		// Update stock - reduce remaining stock
+		quantity := item.StockQuantity()
 		if sale.IsVAT {
-			product.Stock.VAT.Sold += item.Quantity
-			product.Stock.VAT.Remaining -= item.Quantity
+			product.Stock.VAT.Sold += quantity
+			product.Stock.VAT.Remaining -= quantity
 		} else {
-			product.Stock.NonVAT.Sold += item.Quantity
-			product.Stock.NonVAT.Remaining -= item.Quantity
+			product.Stock.NonVAT.Sold += quantity
+			product.Stock.NonVAT.Remaining -= quantity
 		}
-		product.Stock.ActualStock -= item.Quantity
+		product.Stock.ActualStock -= quantity
 
 		// Allow negative stock values to indicate abnormal stock status
 		// Negative values will be visible in UI to show stock issues
 
 		// Save updated product
-		err = h.productRepo.Update(context.Background(), item.ProductID, product)
+		err = h.productRepo.Update(ctx, item.ProductID, product)
 		if err != nil {
 			return fmt.Errorf("failed to update product %s: %v", item.ProductID, err)
 		}
@@ -1249,35 +1587,18 @@ func (h *MigrationHandler) updateProductsFromSale(sale *models.Sale) error {
 	return nil
 }
 
-// generateSaleCode generates a unique sale code
-func (h *MigrationHandler) generateSaleCode(isVAT bool) (string, error) {
-	// This is a simplified version - you might want to use the actual repository method
-	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
-	day := now.Day()
-
-	prefix := "S"
-	if isVAT {
-		prefix = "SV"
-	}
-
-	// Simple format: S-YYYYMMDD-001 or SV-YYYYMMDD-001
-	return fmt.Sprintf("%s-%04d%02d%02d-001", prefix, year, month, day), nil
-}
-
 // GetSaleCSVTemplate returns a CSV template for sale data
 func (h *MigrationHandler) GetSaleCSVTemplate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		RespondWithError(w, localisedError(r.Context(), http.StatusMethodNotAllowed, "method_not_allowed"))
 		return
 	}
 
 	// Create CSV template
-	template := "saleCode,saleDate,customerCode,productCode,quantity,unitPrice,isVAT,shippingCost,notes\n"
-	template += "S-001,2024-01-20,C-0001,เ-l/WH,5,399.00,true,30.00,ขายเสื้อเชิ้ต\n"
-	template += ",2024-01-20,C-0001,ก-32/BL,2,799.00,true,,ขายกางเกงยีนส์\n"
-	template += "S-002,2024-01-21,C-0002,ก-onesize/BK,1,1799.00,false,50.00,ขายกระเป๋า\n"
+	template := "saleCode,saleDate,customerCode,productCode,quantity,unitPrice,isVAT,shippingCost,notes,discountPercent,discountAmount\n"
+	template += "S-001,2024-01-20,C-0001,เ-l/WH,5,399.00,true,30.00,ขายเสื้อเชิ้ต,10,\n"
+	template += ",2024-01-20,C-0001,ก-32/BL,2,799.00,true,,ขายกางเกงยีนส์,,\n"
+	template += "S-002,2024-01-21,C-0002,ก-onesize/BK,1,1799.00,false,50.00,ขายกระเป๋า,,200.00\n"
 
 	w.Header().Set("Content-Type", "text/csv")
 	w.Header().Set("Content-Disposition", "attachment; filename=sale_template.csv")
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// unreachableProductRepo returns a product repository whose database cannot be reached, so every read and
+// write fails quickly
+func unreachableProductRepo(t *testing.T) *repository.ProductRepository {
+	clientOpts := options.Client().ApplyURI("mongodb://127.0.0.1:1").SetServerSelectionTimeout(20 * time.Millisecond)
+	client, err := mongo.Connect(context.Background(), clientOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	repo, err := repository.NewProductRepository(client.Database("goodpack_test").Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestMigrateProductRowsRejectsSKUsRepeatedWithinABatch(t *testing.T) {
+	h := &MigrationHandler{productRepo: unreachableProductRepo(t)}
+	rows := []ProductCSVRow{
+		{SKUID: "BOX-0001", Name: "Box A", Category: "Box"},
+		{SKUID: "BOX-0001", Name: "Box B", Category: "Box"},
+		{SKUID: "BOX-0002", Name: "Box C", Category: "Box"},
+	}
+
+	result := h.migrateProductRows(context.Background(), rows, 10, nil, false)
+
+	if result.FailedRows != 3 {
+		t.Fatalf("FailedRows = %d, want 3 (the database is unreachable): %v", result.FailedRows, result.Errors)
+	}
+	want := "Item 2: SKU ID 'BOX-0001' already exists"
+	if result.Errors[1] != want {
+		t.Errorf("Errors[1] = %q, want %q", result.Errors[1], want)
+	}
+	for _, i := range []int{0, 2} {
+		if !strings.Contains(result.Errors[i], "Failed to save product") {
+			t.Errorf("Errors[%d] = %q, want a save failure", i, result.Errors[i])
+		}
+	}
+}
+
+// migrationTest is a migration handler on a test database
+type migrationTest struct {
+	t *testing.T
+	h *MigrationHandler
+}
+
+func newMigrationTest(t *testing.T) *migrationTest {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &migrationTest{t: t, h: NewMigrationHandler(
+		repository.NewCustomerRepository(db.Collection("customers")),
+		productRepo,
+		repository.NewPurchaseRepository(db.Collection("purchases")),
+		repository.NewSaleRepository(db.Collection("sales")),
+		repository.NewMigrationRepository(db.Collection("migrations")),
+	)}
+}
+
+// upload posts a CSV to a migration endpoint with the given form fields and returns the status and result
+func (mt *migrationTest) upload(handler http.HandlerFunc, csvContent string, fields map[string]string) (int, MigrationResult) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	part, err := form.CreateFormFile("csvFile", "import.csv")
+	if err != nil {
+		mt.t.Fatal(err)
+	}
+	part.Write([]byte(csvContent))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/migration/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	var result MigrationResult
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			mt.t.Fatalf("decode %s: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, result
+}
+
+func TestMigrateCustomersResumesWithTransactionID(t *testing.T) {
+	ctx := context.Background()
+
+	mt := newMigrationTest(t)
+	fields := map[string]string{"transactionId": "customers-2024-01"}
+	header := "companyName,contactName,email\n"
+
+	// The second customer's email is invalid, so only the other two are imported
+	first := header + "Alpha Co,Ann,ann@example.com\nBeta Co,Ben,not-an-email\nGamma Co,Gus,gus@example.com\n"
+	status, result := mt.upload(mt.h.MigrateCustomersFromCSV, first, fields)
+	if status != http.StatusOK || result.SuccessRows != 2 || result.FailedRows != 1 || result.TransactionID != "customers-2024-01" {
+		t.Fatalf("first run: got %d with %+v, want 2 imported and 1 failed", status, result)
+	}
+
+	fixed := header + "Alpha Co,Ann,ann@example.com\nBeta Co,Ben,ben@example.com\nGamma Co,Gus,gus@example.com\n"
+	status, result = mt.upload(mt.h.MigrateCustomersFromCSV, fixed, fields)
+	if status != http.StatusOK || result.SuccessRows != 1 || result.SkippedRows != 2 || result.FailedRows != 0 {
+		t.Fatalf("re-run: got %d with %+v, want 1 imported and 2 skipped", status, result)
+	}
+
+	customers, err := mt.h.customerRepo.GetAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, customer := range customers {
+		names = append(names, customer.CompanyName)
+	}
+	if strings.Join(names, ",") != "Alpha Co,Gamma Co,Beta Co" {
+		t.Errorf("customers = %v, want each imported once", names)
+	}
+
+	migration, err := mt.h.migrationRepo.GetByTransactionID(context.Background(), "customers-2024-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migration.Status != models.MigrationStatusCompleted || len(migration.ProcessedRows) != 3 {
+		t.Errorf("migration = %s with rows %v, want completed with 3 rows", migration.Status, migration.ProcessedRows)
+	}
+}
+
+func TestMigrateCustomersWithoutTransactionIDImportsEveryRow(t *testing.T) {
+	ctx := context.Background()
+
+	mt := newMigrationTest(t)
+	csvContent := "companyName,contactName\nAlpha Co,Ann\n"
+
+	for run := 1; run <= 2; run++ {
+		if status, result := mt.upload(mt.h.MigrateCustomersFromCSV, csvContent, nil); status != http.StatusOK || result.SuccessRows != 1 {
+			t.Fatalf("run %d: got %d with %+v, want the row imported", run, status, result)
+		}
+	}
+	customers, err := mt.h.customerRepo.GetAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 2 {
+		t.Errorf("%d customers, want 2", len(customers))
+	}
+}
+
+func TestMigrationTransactionIDCannotBeReusedForAnotherEntity(t *testing.T) {
+	mt := newMigrationTest(t)
+	fields := map[string]string{"transactionId": "import-1"}
+
+	if status, _ := mt.upload(mt.h.MigrateCustomersFromCSV, "companyName,contactName\nAlpha Co,Ann\n", fields); status != http.StatusOK {
+		t.Fatalf("customer import status = %d, want %d", status, http.StatusOK)
+	}
+	if status, _ := mt.upload(mt.h.MigrateProductsFromCSV, "name,category\nKraft Box,Box\n", fields); status != http.StatusConflict {
+		t.Errorf("product import status = %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestGetMigrationProgress(t *testing.T) {
+	mt := newMigrationTest(t)
+	mt.upload(mt.h.MigrateCustomersFromCSV, "companyName,contactName\nAlpha Co,Ann\n", map[string]string{"transactionId": "import-1"})
+
+	tests := []struct {
+		transactionID string
+		want          int
+	}{
+		{"import-1", http.StatusOK},
+		{"import-2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/migration/status/"+tt.transactionID, nil), map[string]string{"transactionId": tt.transactionID})
+		rec := httptest.NewRecorder()
+		mt.h.GetMigrationProgress(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.transactionID, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestMigrationGeneratesNextCodeOnEachRun(t *testing.T) {
+	mt := newMigrationTest(t)
+	if status, result := mt.upload(mt.h.MigrateCustomersFromCSV, "customerCode,companyName,contactName\nC0001,Alpha Co,Ann\n", nil); status != http.StatusOK || result.SuccessRows != 1 {
+		t.Fatalf("customer import: got %d with %+v", status, result)
+	}
+	if status, result := mt.upload(mt.h.MigrateProductsFromCSV, "skuId,name,category,size,color\nBOX-0001,Kraft Box,Box,A4,Brown\n", nil); status != http.StatusOK || result.SuccessRows != 1 {
+		t.Fatalf("product import: got %d with %+v", status, result)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		csv     string
+		codes   func() []string
+		prefix  string
+	}{
+		{
+			name:    "sales",
+			handler: mt.h.MigrateSalesFromCSV,
+			csv:     "saleDate,customerCode,productCode,quantity,unitPrice,isVAT\n2024-01-15,C0001,BO-A4/BR,2,50,false\n",
+			codes: func() []string {
+				sales, err := mt.h.saleRepo.GetAll(context.Background(), "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var codes []string
+				for _, sale := range sales {
+					codes = append(codes, sale.SaleCode)
+				}
+				return codes
+			},
+			prefix: "NV-",
+		},
+		{
+			name:    "purchases",
+			handler: mt.h.MigratePurchasesFromCSV,
+			csv:     "purchaseDate,customerCode,productCode,quantity,unitPrice,isVAT\n2024-01-15,C0001,BO-A4/BR,5,30,true\n",
+			codes: func() []string {
+				purchases, err := mt.h.purchaseRepo.GetAll(context.Background(), "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var codes []string
+				for _, purchase := range purchases {
+					codes = append(codes, purchase.PurchaseCode)
+				}
+				return codes
+			},
+			prefix: "PUR-VAT-",
+		},
+	}
+	for _, tt := range tests {
+		// The same file imported twice on the same day must not reuse the first run's code
+		for run := 1; run <= 2; run++ {
+			if status, result := mt.upload(tt.handler, tt.csv, nil); status != http.StatusOK || result.SuccessRows != 1 {
+				t.Fatalf("%s run %d: got %d with %+v", tt.name, run, status, result)
+			}
+		}
+
+		codes := tt.codes()
+		if len(codes) != 2 {
+			t.Fatalf("%s: codes = %v, want 2", tt.name, codes)
+		}
+		for i, code := range codes {
+			if !strings.HasPrefix(code, tt.prefix) || !strings.HasSuffix(code, fmt.Sprintf("-%04d", i+1)) {
+				t.Errorf("%s run %d: code = %q, want %s<period>-%04d", tt.name, i+1, code, tt.prefix, i+1)
+			}
+		}
+	}
+}
+
+func TestMigrationDryRunSavesNothing(t *testing.T) {
+	mt := newMigrationTest(t)
+	ctx := context.Background()
+	count := func(items interface{}, err error) int {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reflect.ValueOf(items).Len()
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		csv     string // one valid row and one bad row
+		saved   func() int
+	}{
+		{
+			name:    "customers",
+			handler: mt.h.MigrateCustomersFromCSV,
+			csv:     "customerCode,companyName,contactName,email\nC0001,Alpha Co,Ann,ann@example.com\nC0002,Beta Co,Ben,not-an-email\n",
+			saved:   func() int { return count(mt.h.customerRepo.GetAll(ctx, nil)) },
+		},
+		{
+			name:    "products",
+			handler: mt.h.MigrateProductsFromCSV,
+			csv:     "skuId,name,category,size,color\nBOX-0001,Kraft Box,Box,A4,Brown\nBOX-0002,,Box,A5,Brown\n",
+			saved:   func() int { return count(mt.h.productRepo.GetAll(ctx, nil)) },
+		},
+	}
+	for _, tt := range tests {
+		status, result := mt.upload(tt.handler, tt.csv, map[string]string{"dryRun": "true", "transactionId": "dry-" + tt.name})
+		if status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.name, status, http.StatusOK)
+		}
+		if !result.DryRun || result.SuccessRows != 1 || result.FailedRows != 1 || len(result.Errors) != 1 {
+			t.Errorf("%s: result = %+v, want a dry run with 1 valid row and 1 error", tt.name, result)
+		}
+		if saved := tt.saved(); saved != 0 {
+			t.Errorf("%s: %d saved by a dry run, want none", tt.name, saved)
+		}
+		if _, err := mt.h.migrationRepo.GetByTransactionID(ctx, "dry-"+tt.name); err == nil {
+			t.Errorf("%s: dry run recorded its transaction ID", tt.name)
+		}
+	}
+
+	// Purchases and sales need a customer and product to refer to
+	if status, result := mt.upload(mt.h.MigrateCustomersFromCSV, "customerCode,companyName,contactName\nC0001,Alpha Co,Ann\n", nil); status != http.StatusOK || result.SuccessRows != 1 {
+		t.Fatalf("customer import: got %d with %+v", status, result)
+	}
+	if status, result := mt.upload(mt.h.MigrateProductsFromCSV, "skuId,name,category,size,color\nBOX-0001,Kraft Box,Box,A4,Brown\n", nil); status != http.StatusOK || result.SuccessRows != 1 {
+		t.Fatalf("product import: got %d with %+v", status, result)
+	}
+
+	documents := []struct {
+		name    string
+		handler http.HandlerFunc
+		csv     string
+		saved   func() int
+	}{
+		{
+			name:    "purchases",
+			handler: mt.h.MigratePurchasesFromCSV,
+			csv:     "purchaseDate,customerCode,productCode,quantity,unitPrice,isVAT\n2024-01-15,C0001,BO-A4/BR,5,30,true\n2024-01-16,C9999,BO-A4/BR,5,30,true\n",
+			saved:   func() int { return count(mt.h.purchaseRepo.GetAll(ctx, "", nil)) },
+		},
+		{
+			name:    "sales",
+			handler: mt.h.MigrateSalesFromCSV,
+			csv:     "saleDate,customerCode,productCode,quantity,unitPrice,isVAT\n2024-01-15,C0001,BO-A4/BR,2,50,false\n2024-01-16,C9999,BO-A4/BR,2,50,false\n",
+			saved:   func() int { return count(mt.h.saleRepo.GetAll(ctx, "", nil)) },
+		},
+	}
+	for _, tt := range documents {
+		status, result := mt.upload(tt.handler, tt.csv, map[string]string{"dryRun": "true"})
+		if status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.name, status, http.StatusOK)
+		}
+		if !result.DryRun || result.SuccessRows != 1 || result.FailedRows != 1 || len(result.Errors) != 1 {
+			t.Errorf("%s: result = %+v, want a dry run with 1 valid row and 1 error", tt.name, result)
+		}
+		if saved := tt.saved(); saved != 0 {
+			t.Errorf("%s: %d saved by a dry run, want none", tt.name, saved)
+		}
+	}
+
+	product, err := mt.h.productRepo.GetBySKUID(ctx, "BOX-0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock.ActualStock != 0 {
+		t.Errorf("actual stock = %d after dry runs, want it unchanged", product.Stock.ActualStock)
+	}
+}
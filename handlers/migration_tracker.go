@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// errMigrationEntityMismatch is returned when a transaction ID is reused for a different kind of import
+var errMigrationEntityMismatch = errors.New("transaction ID belongs to another migration")
+
+// migrationTracker skips rows already imported under the same transaction ID and records new ones.
+// A nil tracker (no transactionId sent) tracks nothing, so every row is imported.
+type migrationTracker struct {
+	repo          *repository.MigrationRepository
+	transactionID string
+	processed     map[string]bool
+	dryRun        bool // read earlier progress but record nothing
+}
+
+// newMigrationTracker loads or starts the migration for transactionID; it returns nil when transactionID is empty.
+// A dry run only loads the migration, so rows imported earlier are still reported as skipped.
+func newMigrationTracker(ctx context.Context, repo *repository.MigrationRepository, transactionID, entity string, dryRun bool) (*migrationTracker, error) {
+	if transactionID == "" {
+		return nil, nil
+	}
+
+	var migration *models.Migration
+	var err error
+	if dryRun {
+		migration, err = repo.GetByTransactionID(ctx, transactionID)
+		if errors.Is(err, apierrors.ErrNotFound) {
+			migration, err = &models.Migration{TransactionID: transactionID, Entity: entity}, nil
+		}
+	} else {
+		migration, err = repo.Start(ctx, transactionID, entity)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migration %s: %w", transactionID, err)
+	}
+	if migration.Entity != entity {
+		return nil, fmt.Errorf("%w: %s was used to import %s", errMigrationEntityMismatch, transactionID, migration.Entity)
+	}
+
+	processed := make(map[string]bool, len(migration.ProcessedRows))
+	for _, rowKey := range migration.ProcessedRows {
+		processed[rowKey] = true
+	}
+
+	return &migrationTracker{
+		repo:          repo,
+		transactionID: transactionID,
+		processed:     processed,
+		dryRun:        dryRun,
+	}, nil
+}
+
+// isProcessed reports whether the row was imported by an earlier run
+func (t *migrationTracker) isProcessed(rowKey string) bool {
+	return t != nil && t.processed[rowKey]
+}
+
+// recordSuccess remembers that the row has been imported
+func (t *migrationTracker) recordSuccess(rowKey string) {
+	if t == nil {
+		return
+	}
+	t.processed[rowKey] = true
+	if t.dryRun {
+		return
+	}
+	if err := t.repo.RecordSuccess(context.Background(), t.transactionID, rowKey); err != nil {
+		fmt.Printf("Warning: Failed to record migration progress for %s: %v\n", rowKey, err)
+	}
+}
+
+// complete stores the run summary on the migration record
+func (t *migrationTracker) complete(result *MigrationResult) {
+	if t == nil {
+		return
+	}
+	result.TransactionID = t.transactionID
+	if t.dryRun {
+		return
+	}
+	summary := models.MigrationSummary{
+		TotalRows:   result.TotalRows,
+		SuccessRows: result.SuccessRows,
+		SkippedRows: result.SkippedRows,
+		FailedRows:  result.FailedRows,
+	}
+	if err := t.repo.MarkComplete(context.Background(), t.transactionID, summary); err != nil {
+		fmt.Printf("Warning: Failed to update migration %s: %v\n", t.transactionID, err)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goodpack-server/models"
+)
+
+// paymentErrorCodes maps payment validation errors to their error codes
+var paymentErrorCodes = map[error]string{
+	models.ErrPaymentAmount:   "payment_amount_invalid",
+	models.ErrPaymentMethod:   "payment_method_required",
+	models.ErrPaymentDate:     "invalid_paid_at",
+	models.ErrPaymentExceeded: "payment_exceeds_balance",
+}
+
+// decodePaymentRecord reads a payment from the request body, writing a 400 response if it is invalid
+func decodePaymentRecord(w http.ResponseWriter, r *http.Request) (*models.PaymentRecord, bool) {
+	var req models.PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return nil, false
+	}
+
+	record, err := req.ToPaymentRecord()
+	if err != nil {
+		writePaymentError(w, r, err)
+		return nil, false
+	}
+	return record, true
+}
+
+// writePaymentError writes a payment validation error; over-payment is 422, everything else 400
+func writePaymentError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadRequest
+	if err == models.ErrPaymentExceeded {
+		status = http.StatusUnprocessableEntity
+	}
+	RespondWithError(w, localisedError(r.Context(), status, paymentErrorCodes[err]))
+}
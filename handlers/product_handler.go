@@ -1,46 +1,85 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
 
+	"goodpack-server/apierrors"
 	"goodpack-server/config"
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/services"
+	"goodpack-server/storage"
 )
 
 type ProductHandler struct {
-	repo         *repository.ProductRepository
-	configLoader *config.ConfigLoader
+	repo            *repository.ProductRepository
+	purchaseRepo    *repository.PurchaseRepository
+	configLoader    *config.ConfigLoader
+	fileStorage     storage.FileStorage
+	pdfService      *services.PDFService
+	velocityService *services.VelocityService
 }
 
-func NewProductHandler(repo *repository.ProductRepository) *ProductHandler {
-	configLoader := config.NewConfigLoader()
-	if err := configLoader.LoadConfig(); err != nil {
-		// If config loading fails, continue with empty config
-		// Log error but don't fail the handler creation
-	}
-
+func NewProductHandler(repo *repository.ProductRepository, purchaseRepo *repository.PurchaseRepository, fileStorage storage.FileStorage, velocityService *services.VelocityService) *ProductHandler {
 	return &ProductHandler{
-		repo:         repo,
-		configLoader: configLoader,
+		repo:            repo,
+		purchaseRepo:    purchaseRepo,
+		configLoader:    config.DefaultLoader(),
+		fileStorage:     fileStorage,
+		pdfService:      services.NewPDFService(),
+		velocityService: velocityService,
 	}
 }
 
+// GetProducts returns all products; repeated tag query parameters filter by tag
+// (any of the tags, or all of them with matchAll=true), uom filters by unit of measure and velocityClass by
+// velocity class
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	products, err := h.repo.GetAll(r.Context())
+	sort, ok := parseSort(w, r, productSortFields)
+	if !ok {
+		return
+	}
+
+	var products []*models.Product
+	var err error
+	tags := models.NormalizeTags(r.URL.Query()["tag"])
+	uom := r.URL.Query().Get("uom")
+	velocityClass := r.URL.Query().Get("velocityClass")
+	if velocityClass != "" && !slices.Contains(models.VelocityClasses, velocityClass) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_velocity_class"))
+		return
+	}
+	switch {
+	case velocityClass != "":
+		products, err = h.repo.GetByVelocityClass(r.Context(), velocityClass, sort)
+	case uom != "":
+		products, err = h.repo.GetByUOM(r.Context(), uom, sort)
+	case len(tags) == 1:
+		products, err = h.repo.GetByTag(r.Context(), tags[0], sort)
+	case len(tags) > 1:
+		products, err = h.repo.GetByTags(r.Context(), tags, r.URL.Query().Get("matchAll") == "true", sort)
+	default:
+		products, err = h.repo.GetAll(r.Context(), sort)
+	}
 	if err != nil {
-		http.Error(w, "Failed to get products", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
 		return
 	}
 
@@ -53,13 +92,13 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	// Try to get by ObjectID first, then by SKU ID
-	product, err := h.repo.GetByID(r.Context(), id)
+	// Try to get by ObjectID first, then by SKU ID; either counts as a view of the product
+	product, err := h.repo.ViewByID(r.Context(), id)
 	if err != nil {
 		// If not found by ObjectID, try SKU ID
-		product, err = h.repo.GetBySKUID(r.Context(), id)
+		product, err = h.repo.ViewBySKUID(r.Context(), id)
 		if err != nil {
-			http.Error(w, "Product not found", http.StatusNotFound)
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 			return
 		}
 	}
@@ -72,13 +111,20 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	var productReq models.ProductRequest
 	if err := json.NewDecoder(r.Body).Decode(&productReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &productReq) {
+		return
+	}
+	if len(productReq.Images) > models.MaxProductImages {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "too_many_images", models.MaxProductImages))
 		return
 	}
 
 	product := productReq.ToProduct()
 	if err := h.repo.Create(r.Context(), product); err != nil {
-		http.Error(w, "Failed to create product", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_create_failed"))
 		return
 	}
 
@@ -98,33 +144,132 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		// Try to find by SKUID if ObjectID fails
 		existingProduct, err = h.repo.GetBySKUID(r.Context(), id)
 		if err != nil {
-			http.Error(w, "Product not found", http.StatusNotFound)
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 			return
 		}
 	}
 
 	var productReq models.ProductRequest
 	if err := json.NewDecoder(r.Body).Decode(&productReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &productReq) {
+		return
+	}
+	if len(productReq.Images) > models.MaxProductImages {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "too_many_images", models.MaxProductImages))
+		return
+	}
+	if !checkVersion(w, r, productReq.Version, existingProduct.Version) {
 		return
 	}
 
 	// Update existing product
 	existingProduct.UpdateFromRequest(&productReq)
 	if err := h.repo.Update(r.Context(), existingProduct.ID.Hex(), existingProduct); err != nil {
-		http.Error(w, "Failed to update product", http.StatusInternalServerError)
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.repo.GetByID(r.Context(), existingProduct.ID.Hex()); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_update_failed"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(existingProduct)
 }
 
+// PatchProduct updates only the fields present in the request body
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var patchReq models.ProductPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if patchReq.Images != nil && len(*patchReq.Images) > models.MaxProductImages {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "too_many_images", models.MaxProductImages))
+		return
+	}
+
+	fields := patchReq.ToUpdateFields()
+	if len(fields) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "no_fields_to_update"))
+		return
+	}
+
+	if err := h.repo.Patch(r.Context(), id, fields); err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_update_failed"))
+		return
+	}
+
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(product)
+}
+
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	if err := h.repo.Delete(r.Context(), id); err != nil {
-		http.Error(w, "Failed to delete product", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreProduct restores a soft-deleted product
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.repo.Restore(r.Context(), id); err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "deleted_product_not_found"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_restore_failed"))
+		return
+	}
+
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(product)
+}
+
+// HardDeleteProduct permanently removes a product (admin only)
+func (h *ProductHandler) HardDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := h.repo.HardDelete(r.Context(), id); err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_delete_failed"))
 		return
 	}
 
@@ -139,19 +284,19 @@ func (h *ProductHandler) UpdateStock(w http.ResponseWriter, r *http.Request) {
 
 	var stockReq models.StockUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&stockReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
 		return
 	}
 
 	if err := h.repo.UpdateStock(r.Context(), id, stockReq.Stock); err != nil {
-		http.Error(w, "Failed to update stock", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_update_failed"))
 		return
 	}
 
 	// Get updated product
 	product, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Product not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 		return
 	}
 
@@ -163,13 +308,26 @@ func (h *ProductHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
 
 	categories, err := h.repo.GetCategories(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get categories", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "categories_fetch_failed"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(categories)
 }
 
+// GetTags returns every distinct product tag
+func (h *ProductHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tags, err := h.repo.GetTags(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "tags_fetch_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(tags)
+}
+
 func (h *ProductHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -178,64 +336,413 @@ func (h *ProductHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
 
 	var priceReq models.PriceUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&priceReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
 		return
 	}
 
 	if err := h.repo.UpdatePrice(r.Context(), id, priceReq.Price); err != nil {
-		http.Error(w, "Failed to update price", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "price_update_failed"))
 		return
 	}
 
 	// Get updated product
 	product, err := h.repo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Product not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(product)
 }
 
+// RegenerateQR sets the product's QR data from its current SKU ID, e.g. after the SKU was corrected
+func (h *ProductHandler) RegenerateQR(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	var updated int64
+	if qrData := models.ProductQRData(product.SKUID); product.QRData != qrData {
+		if err := h.repo.Patch(r.Context(), id, bson.M{"qrData": qrData, "updatedAt": time.Now()}); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "qr_regenerate_failed"))
+			return
+		}
+		updated = 1
+	}
+
+	json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+// RegenerateAllQR sets the QR data of every product from its current SKU ID
+func (h *ProductHandler) RegenerateAllQR(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	updated, err := h.repo.RegenerateAllQRData(r.Context())
+	if err != nil {
+		fmt.Printf("Error regenerating QR data after %d products: %v\n", updated, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "qr_regenerate_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+// ReclassifyVelocity reclassifies every product by sales velocity now rather than waiting for the nightly job
+func (h *ProductHandler) ReclassifyVelocity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	result, err := h.velocityService.Reclassify(r.Context(), time.Now())
+	if err != nil {
+		fmt.Printf("Error reclassifying products by velocity: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "velocity_reclassify_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetByCategory returns the products of a category sorted by sortBy (default name) in sortOrder
+// (asc or desc), like GetProducts; minStock and maxStock filter by actual stock
 func (h *ProductHandler) GetByCategory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	category := vars["category"]
+	query := r.URL.Query()
+
+	sort, ok := parseSortBy(w, r, productSortFields, "name")
+	if !ok {
+		return
+	}
+
+	minStock, err := parseOptionalInt(query.Get("minStock"))
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_stock_range"))
+		return
+	}
+	maxStock, err := parseOptionalInt(query.Get("maxStock"))
+	if err != nil || (minStock != nil && maxStock != nil && *minStock > *maxStock) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_stock_range"))
+		return
+	}
+
+	products, err := h.repo.GetByCategory(r.Context(), category, minStock, maxStock, sort)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_by_category_failed"))
+		return
+	}
+	if products == nil {
+		products = []*models.Product{}
+	}
+
+	json.NewEncoder(w).Encode(products)
+}
+
+// parseOptionalInt parses an optional integer query value; an empty value returns nil
+func parseOptionalInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// popularProductsMaxLimit is the most products GetPopularProducts returns
+const popularProductsMaxLimit = 100
 
-	products, err := h.repo.GetByCategory(r.Context(), category)
+// GetPopularProducts returns the products with the highest popularity score, 10 unless limit (at most 100)
+// is given
+func (h *ProductHandler) GetPopularProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := queryLimit(r, 10, popularProductsMaxLimit)
+	products, err := h.repo.GetPopular(r.Context(), limit)
 	if err != nil {
-		http.Error(w, "Failed to get products by category", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(products)
 }
 
+// queryLimit reads ?limit=, defaulting to defaultLimit when it is missing or not a positive number and capped at
+// maxLimit
+func queryLimit(r *http.Request, defaultLimit, maxLimit int) int {
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			return min(parsedLimit, maxLimit)
+		}
+	}
+	return defaultLimit
+}
+
+// relatedProductsMaxLimit is the most products GetRelatedProducts and GetAlsoBoughtProducts return per list
+const relatedProductsMaxLimit = 50
+
+// GetRelatedProducts suggests other products of the product's category and, secondly, products of other
+// categories with its color or size, each closest in VAT sale price first and 5 unless limit (at most 50) is given
+func (h *ProductHandler) GetRelatedProducts(w http.ResponseWriter, r *http.Request) {
+	product, err := h.findProduct(r, mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+	limit := queryLimit(r, 5, relatedProductsMaxLimit)
+
+	sameCategory, err := h.repo.GetRelated(r.Context(), product, limit)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "related_products_failed"))
+		return
+	}
+	sameColorOrSize, err := h.repo.GetSameColorOrSize(r.Context(), product, limit)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "related_products_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RelatedProducts{SameCategory: sameCategory, SameColorOrSize: sameColorOrSize})
+}
+
+// GetLowStockProducts lists the products at or below their own reorder level, or at or below ?threshold= for all
+// products when it is given
 func (h *ProductHandler) GetLowStockProducts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Get threshold from query parameter (default: 10)
-	threshold := 10
+	threshold := 0
 	if thresholdStr := r.URL.Query().Get("threshold"); thresholdStr != "" {
-		// Parse threshold parameter if provided
-		// For now, we'll use default value
+		parsed, err := strconv.Atoi(thresholdStr)
+		if err != nil || parsed < 1 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_threshold"))
+			return
+		}
+		threshold = parsed
 	}
 
 	products, err := h.repo.GetLowStockProducts(r.Context(), threshold)
 	if err != nil {
-		http.Error(w, "Failed to get low stock products", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "low_stock_fetch_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(products)
+}
+
+// SearchProducts searches products by name/description, SKU fragment, category, color and size
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	searchQuery := models.ProductSearchQuery{
+		Name:     strings.TrimSpace(query.Get("q")),
+		Category: query.Get("category"),
+		Color:    query.Get("color"),
+		Size:     query.Get("size"),
+		SKUID:    strings.TrimSpace(query.Get("sku")),
+	}
+
+	var products []*models.Product
+	var err error
+	// A free-text query on its own is ranked by the text index; without the index, or when it finds nothing
+	// (Thai is not split into words), the name and description are matched by regex instead
+	freeText := searchQuery.Name != "" && searchQuery.Category == "" && searchQuery.Color == "" &&
+		searchQuery.Size == "" && searchQuery.SKUID == ""
+	if freeText {
+		products, err = h.repo.TextSearch(r.Context(), searchQuery.Name)
+		if errors.Is(err, repository.ErrNoTextIndex) {
+			err = nil
+		}
+	}
+	if err == nil && len(products) == 0 {
+		products, err = h.repo.Search(r.Context(), searchQuery)
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_search_failed"))
 		return
 	}
+	if products == nil {
+		products = []*models.Product{}
+	}
 
 	json.NewEncoder(w).Encode(products)
 }
 
-// GetConfigCategories returns all categories from config
+// GetReorderSuggestions returns low stock products with the suggested order quantity and latest purchase info
+func (h *ProductHandler) GetReorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	products, err := h.repo.GetLowStockProducts(r.Context(), 0)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "low_stock_fetch_failed"))
+		return
+	}
+
+	suggestions := make([]models.ReorderSuggestion, 0, len(products))
+	for _, product := range products {
+		productID := product.ID.Hex()
+		suggestion := models.ReorderSuggestion{
+			ProductID:         productID,
+			SKUID:             product.SKUID,
+			Code:              product.Code,
+			Name:              product.Name,
+			ActualStock:       product.GetTotalStock(),
+			ReorderLevel:      product.GetReorderLevel(),
+			ReorderQty:        product.ReorderQty,
+			SuggestedOrderQty: product.GetSuggestedOrderQty(),
+			LatestPrice:       product.GetDisplayPrice(),
+		}
+
+		purchase, err := h.purchaseRepo.GetLatestByProductID(r.Context(), productID)
+		if err != nil && !errors.Is(err, apierrors.ErrNotFound) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "latest_purchase_fetch_failed"))
+			return
+		}
+		if purchase != nil {
+			purchaseDate := purchase.PurchaseDate
+			suggestion.LastPurchaseDate = &purchaseDate
+			suggestion.SupplierID = purchase.SupplierID
+			suggestion.SupplierName = purchase.SupplierName
+			for _, item := range purchase.Items {
+				if item.ProductID == productID {
+					suggestion.LatestPrice = item.UnitPrice
+					break
+				}
+			}
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// GetPricingSuggestion suggests a sale price for a target margin over the product's average purchase cost
+// (?targetMarginPercent=30&includeVAT=true)
+func (h *ProductHandler) GetPricingSuggestion(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	targetMargin, err := strconv.ParseFloat(query.Get("targetMarginPercent"), 64)
+	if err != nil || targetMargin < 0 || targetMargin >= 100 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_target_margin"))
+		return
+	}
+	includeVAT := false
+	if value := query.Get("includeVAT"); value != "" {
+		if includeVAT, err = strconv.ParseBool(value); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_include_vat"))
+			return
+		}
+	}
+
+	product, err := h.findProduct(r, mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewPricingSuggestion(product, targetMargin, includeVAT))
+}
+
+// GetPricingSuggestions suggests sale prices for several products at the same target margin
+func (h *ProductHandler) GetPricingSuggestions(w http.ResponseWriter, r *http.Request) {
+	var req models.PricingSuggestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &req) {
+		return
+	}
+
+	suggestions := make([]*models.PricingSuggestion, 0, len(req.ProductIDs))
+	for _, id := range req.ProductIDs {
+		product, err := h.findProduct(r, id)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", id))
+			return
+		}
+		suggestions = append(suggestions, models.NewPricingSuggestion(product, req.TargetMarginPercent, req.IncludeVAT))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// Default label grid of GetLabelSheet: 4 × 8 labels per A4 page
+const (
+	defaultLabelColumns = 4
+	defaultLabelRows    = 8
+)
+
+// GetLabelSheet renders printable shelf labels with QR codes as a PDF, for every product or those of ?category=,
+// in ?columns= × ?rows= labels per A4 page
+func (h *ProductHandler) GetLabelSheet(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	columns, ok := parseLabelCount(query.Get("columns"), defaultLabelColumns, services.LabelSheetMaxColumns)
+	if !ok {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_label_columns"))
+		return
+	}
+	rows, ok := parseLabelCount(query.Get("rows"), defaultLabelRows, services.LabelSheetMaxRows)
+	if !ok {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_label_rows"))
+		return
+	}
+
+	var products []*models.Product
+	var err error
+	if category := query.Get("category"); category != "" {
+		products, err = h.repo.GetByCategory(r.Context(), category, nil, nil, nil)
+	} else {
+		products, err = h.repo.GetAll(r.Context(), nil)
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
+		return
+	}
+	if len(products) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "no_products_found"))
+		return
+	}
+
+	pdf, err := h.pdfService.GenerateLabelSheetPDF(products, columns, rows)
+	if err != nil {
+		fmt.Printf("Error generating label sheet PDF: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "pdf_generate_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=labels-%s.pdf", time.Now().Format("20060102")))
+	w.Write(pdf)
+}
+
+// parseLabelCount parses an optional label column or row count from 1 to max; an empty value returns defaultValue
+func parseLabelCount(value string, defaultValue, max int) (int, bool) {
+	n, err := parseOptionalInt(value)
+	if err != nil || (n != nil && (*n < 1 || *n > max)) {
+		return 0, false
+	}
+	if n == nil {
+		return defaultValue, true
+	}
+	return *n, true
+}
+
+// GetConfigCategories returns the active categories
 func (h *ProductHandler) GetConfigCategories(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	categories := h.configLoader.GetCategories()
+	categories := h.configLoader.GetActiveCategories()
 	json.NewEncoder(w).Encode(categories)
 }
 
@@ -255,7 +762,25 @@ func (h *ProductHandler) GetConfigAccounts(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(accounts)
 }
 
-// UploadProductImage handles product image upload
+// ReloadConfig re-reads the categories, colors and accounts config files (they are also reloaded
+// automatically when they change on disk)
+func (h *ProductHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.configLoader.ReloadConfig(); err != nil {
+		fmt.Printf("Error reloading config: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "config_reload_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{
+		"categories": len(h.configLoader.GetCategories()),
+		"colors":     len(h.configLoader.GetColors()),
+		"accounts":   len(h.configLoader.GetAccounts()),
+	})
+}
+
+// UploadProductImage adds an uploaded image to the product's gallery.
+// Optional form fields: order, altText and isPrimary ("true" makes it the primary image).
 func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	productId := vars["id"]
@@ -263,21 +788,21 @@ func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Reque
 	// Parse multipart form with 10MB max memory
 	err := r.ParseMultipartForm(10 << 20) // 10MB
 	if err != nil {
-		http.Error(w, "Failed to parse multipart form", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "multipart_parse_failed"))
 		return
 	}
 
 	// Get the file from form data
 	file, handler, err := r.FormFile("image")
 	if err != nil {
-		http.Error(w, "No image file provided", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "image_file_required"))
 		return
 	}
 	defer file.Close()
 
 	// Check file size (max 5MB)
 	if handler.Size > 5*1024*1024 {
-		http.Error(w, "File size too large. Maximum size is 5MB", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "file_too_large"))
 		return
 	}
 
@@ -285,7 +810,7 @@ func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Reque
 	fileBytes := make([]byte, 12)
 	_, err = file.Read(fileBytes)
 	if err != nil {
-		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "file_read_failed"))
 		return
 	}
 
@@ -293,59 +818,31 @@ func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Reque
 	file.Seek(0, 0)
 
 	// Check file signature
-	isValidType := false
+	contentType := ""
 	if len(fileBytes) >= 3 {
 		// JPEG signature: FF D8 FF
 		if fileBytes[0] == 0xFF && fileBytes[1] == 0xD8 && fileBytes[2] == 0xFF {
-			isValidType = true
+			contentType = "image/jpeg"
 		}
 		// PNG signature: 89 50 4E 47
 		if fileBytes[0] == 0x89 && fileBytes[1] == 0x50 && fileBytes[2] == 0x4E && fileBytes[3] == 0x47 {
-			isValidType = true
+			contentType = "image/png"
 		}
 		// GIF signature: 47 49 46 38
 		if fileBytes[0] == 0x47 && fileBytes[1] == 0x49 && fileBytes[2] == 0x46 && fileBytes[3] == 0x38 {
-			isValidType = true
+			contentType = "image/gif"
 		}
 		// WebP signature: 52 49 46 46 (RIFF)
 		if len(fileBytes) >= 12 && fileBytes[0] == 0x52 && fileBytes[1] == 0x49 && fileBytes[2] == 0x46 && fileBytes[3] == 0x46 {
 			// Check for WEBP in bytes 8-11
 			if fileBytes[8] == 0x57 && fileBytes[9] == 0x45 && fileBytes[10] == 0x42 && fileBytes[11] == 0x50 {
-				isValidType = true
+				contentType = "image/webp"
 			}
 		}
 	}
 
-	if !isValidType {
-		http.Error(w, "Invalid file type. Only JPEG, PNG, GIF, and WebP are allowed", http.StatusBadRequest)
-		return
-	}
-
-	// Create uploads directory if it doesn't exist
-	uploadDir := "uploads/products"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		http.Error(w, "Failed to create upload directory", http.StatusInternalServerError)
-		return
-	}
-
-	// Generate unique filename
-	ext := filepath.Ext(handler.Filename)
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%s_%d%s", productId, timestamp, ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Create the file
-	dst, err := os.Create(filePath)
-	if err != nil {
-		http.Error(w, "Failed to create file", http.StatusInternalServerError)
-		return
-	}
-	defer dst.Close()
-
-	// Copy the uploaded file to the destination
-	_, err = io.Copy(dst, file)
-	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+	if contentType == "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_image_type"))
 		return
 	}
 
@@ -355,35 +852,60 @@ func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Reque
 		// Try to find by SKUID if ObjectID fails
 		product, err = h.repo.GetBySKUID(r.Context(), productId)
 		if err != nil {
-			// Clean up uploaded file
-			os.Remove(filePath)
-			http.Error(w, "Product not found", http.StatusNotFound)
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 			return
 		}
 	}
+	if len(product.Images) >= models.MaxProductImages {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "too_many_images", models.MaxProductImages))
+		return
+	}
 
-	// Delete old image file if exists
-	if product.ImageURL != nil && *product.ImageURL != "" {
-		oldImagePath := *product.ImageURL
-		// Remove /uploads/ prefix if present
-		if strings.HasPrefix(oldImagePath, "/uploads/") {
-			oldImagePath = strings.TrimPrefix(oldImagePath, "/uploads/")
+	order := 0
+	if orderValue := r.FormValue("order"); orderValue != "" {
+		order, err = strconv.Atoi(orderValue)
+		if err != nil || order < 1 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_order"))
+			return
 		}
+	}
 
-		oldFilePath := filepath.Join("uploads", oldImagePath)
-		if err := os.Remove(oldFilePath); err != nil {
-			// Log warning but don't fail the upload
-			fmt.Printf("Warning: Failed to delete old image file %s: %v\n", oldFilePath, err)
-		}
+	// The image is read once for the original and again for its thumbnails
+	data, err := io.ReadAll(file)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "file_read_failed"))
+		return
+	}
+
+	// Generate unique filename
+	ext := filepath.Ext(handler.Filename)
+	timestamp := time.Now().Unix()
+	filename := fmt.Sprintf("products/%s_%d%s", productId, timestamp, ext)
+
+	imageURL, err := h.fileStorage.Upload(r.Context(), filename, bytes.NewReader(data), contentType)
+	if err != nil {
+		fmt.Printf("Error uploading image: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "file_save_failed"))
+		return
 	}
 
-	// Update product with new image URL
-	imageURL := fmt.Sprintf("/uploads/products/%s", filename)
-	product.ImageURL = &imageURL
+	// Append the new image to the gallery
+	image := models.ProductImage{
+		URL:       imageURL,
+		Order:     order,
+		IsPrimary: r.FormValue("isPrimary") == "true",
+		AltText:   r.FormValue("altText"),
+	}
+	h.uploadThumbnails(r.Context(), &image, data, fmt.Sprintf("products/%s_%d", productId, timestamp))
+	if err := product.AddImage(image); err != nil {
+		h.deleteImageFiles(r.Context(), image)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "too_many_images", models.MaxProductImages))
+		return
+	}
 	if err := h.repo.Update(r.Context(), product.ID.Hex(), product); err != nil {
-		// Clean up uploaded file
-		os.Remove(filePath)
-		http.Error(w, "Failed to update product", http.StatusInternalServerError)
+		// Clean up uploaded files
+		h.deleteImageFiles(r.Context(), image)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_update_failed"))
 		return
 	}
 
@@ -392,6 +914,7 @@ func (h *ProductHandler) UploadProductImage(w http.ResponseWriter, r *http.Reque
 		"success":  true,
 		"message":  "Image uploaded successfully",
 		"imageUrl": imageURL,
+		"images":   product.Images,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -405,15 +928,28 @@ func (h *ProductHandler) ServeProductImage(w http.ResponseWriter, r *http.Reques
 
 	// Security check - prevent directory traversal
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") {
-		http.Error(w, "Invalid filename", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_filename"))
 		return
 	}
 
+	// ?size= serves the thumbnail of that size, or the original for images uploaded before thumbnails were made
+	if size := r.URL.Query().Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || !slices.Contains(services.ProductThumbnailSizes, n) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_image_size"))
+			return
+		}
+		thumbnail := thumbnailFilename(strings.TrimSuffix(filename, filepath.Ext(filename)), n)
+		if _, err := os.Stat(filepath.Join("uploads/products", thumbnail)); err == nil {
+			filename = thumbnail
+		}
+	}
+
 	filePath := filepath.Join("uploads/products", filename)
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		http.Error(w, "Image not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "image_not_found"))
 		return
 	}
 
@@ -436,58 +972,153 @@ func (h *ProductHandler) ServeProductImage(w http.ResponseWriter, r *http.Reques
 	http.ServeFile(w, r, filePath)
 }
 
-// DeleteProductImage deletes a product image
+// uploadThumbnails stores a JPEG thumbnail of the image data for each of services.ProductThumbnailSizes next to
+// the original, named after base, and records their URLs on image. An image that cannot be decoded keeps only
+// its original.
+func (h *ProductHandler) uploadThumbnails(ctx context.Context, image *models.ProductImage, data []byte, base string) {
+	thumbnails, err := services.GenerateThumbnails(data, services.ProductThumbnailSizes)
+	if err != nil {
+		fmt.Printf("Warning: Failed to generate thumbnails for %s: %v\n", image.URL, err)
+		return
+	}
+	for size, thumbnail := range thumbnails {
+		url, err := h.fileStorage.Upload(ctx, thumbnailFilename(base, size), bytes.NewReader(thumbnail), "image/jpeg")
+		if err != nil {
+			fmt.Printf("Warning: Failed to save %dpx thumbnail for %s: %v\n", size, image.URL, err)
+			continue
+		}
+		image.SetThumbnailURL(size, url)
+	}
+}
+
+// thumbnailFilename is the name of the size pixel thumbnail of the image stored as base plus extension
+func thumbnailFilename(base string, size int) string {
+	return fmt.Sprintf("%s_thumb%d.jpg", base, size)
+}
+
+// deleteImageFiles removes the stored original and thumbnails of an image, logging failures
+func (h *ProductHandler) deleteImageFiles(ctx context.Context, image models.ProductImage) {
+	for _, url := range image.FileURLs() {
+		if err := h.fileStorage.Delete(ctx, url); err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Warning: Failed to delete image file %s: %v\n", url, err)
+		}
+	}
+}
+
+// DeleteProductImage deletes a product image, chosen by the imageIndex path variable or the url query parameter.
+// Without either, the primary image is deleted. Deleting the primary image promotes the next one.
 func (h *ProductHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
 	productId := vars["id"]
 
-	// Get existing product
-	product, err := h.repo.GetByID(r.Context(), productId)
+	product, err := h.findProduct(r, productId)
 	if err != nil {
-		// Try to find by SKUID if ObjectID fails
-		product, err = h.repo.GetBySKUID(r.Context(), productId)
-		if err != nil {
-			http.Error(w, "Product not found", http.StatusNotFound)
-			return
-		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
 	}
 
 	// Check if product has an image
-	if product.ImageURL == nil || *product.ImageURL == "" {
-		http.Error(w, "Product has no image to delete", http.StatusBadRequest)
+	if len(product.Images) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_has_no_image"))
 		return
 	}
 
-	// Delete the physical file
-	imagePath := *product.ImageURL
-	if imagePath != "" {
-		// Remove /uploads/ prefix if present
-		if strings.HasPrefix(imagePath, "/uploads/") {
-			imagePath = strings.TrimPrefix(imagePath, "/uploads/")
-		}
-
-		filePath := filepath.Join("uploads", imagePath)
-		if err := os.Remove(filePath); err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Warning: Failed to delete image file %s: %v\n", filePath, err)
-		}
+	index, err := h.imageIndex(product, vars["imageIndex"], r.URL.Query().Get("url"))
+	if err != nil {
+		RespondWithError(w, imageNotFound(r.Context(), err))
+		return
 	}
 
-	// Update product to remove image URL
-	product.ImageURL = nil
+	removed, err := product.RemoveImage(index)
+	if err != nil {
+		RespondWithError(w, imageNotFound(r.Context(), err))
+		return
+	}
 	if err := h.repo.Update(r.Context(), product.ID.Hex(), product); err != nil {
-		http.Error(w, "Failed to update product", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_update_failed"))
 		return
 	}
 
+	// Delete the stored files
+	h.deleteImageFiles(r.Context(), removed)
+
 	// Return success response
 	response := map[string]interface{}{
 		"success":  true,
 		"message":  "Image deleted successfully",
-		"imageUrl": nil,
+		"imageUrl": product.PrimaryImageURL(),
+		"images":   product.Images,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// SetPrimaryProductImage makes the image at imageIndex the product's primary image
+func (h *ProductHandler) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	product, err := h.findProduct(r, vars["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	index, err := h.imageIndex(product, vars["imageIndex"], "")
+	if err != nil {
+		RespondWithError(w, imageNotFound(r.Context(), err))
+		return
+	}
+	if err := product.SetPrimaryImage(index); err != nil {
+		RespondWithError(w, imageNotFound(r.Context(), err))
+		return
+	}
+
+	if err := h.repo.Update(r.Context(), product.ID.Hex(), product); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_update_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(product)
+}
+
+// findProduct gets a product by ObjectID, falling back to SKU ID
+func (h *ProductHandler) findProduct(r *http.Request, id string) (*models.Product, error) {
+	product, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		return h.repo.GetBySKUID(r.Context(), id)
+	}
+	return product, nil
+}
+
+// imageIndex resolves the gallery index from an index path variable or an image URL; with neither it returns the primary image
+func (h *ProductHandler) imageIndex(product *models.Product, indexValue, url string) (int, error) {
+	switch {
+	case indexValue != "":
+		index, err := strconv.Atoi(indexValue)
+		if err != nil || index < 0 || index >= len(product.Images) {
+			return -1, models.ErrImageIndexInvalid
+		}
+		return index, nil
+	case url != "":
+		return product.ImageIndex(url)
+	default:
+		for i, image := range product.Images {
+			if image.IsPrimary {
+				return i, nil
+			}
+		}
+		return 0, nil
+	}
+}
+
+// imageNotFound is the 404 response for an image index or URL that does not match one of the product's images
+func imageNotFound(ctx context.Context, err error) *apierrors.AppError {
+	if errors.Is(err, models.ErrImageIndexInvalid) {
+		return localisedError(ctx, http.StatusNotFound, "invalid_image_index")
+	}
+	return localisedError(ctx, http.StatusNotFound, "image_not_found")
+}
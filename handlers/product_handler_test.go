@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+func TestGetByCategory(t *testing.T) {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ProductHandler{repo: productRepo}
+
+	stock := map[string]int{"Carton": 50, "Adhesive Box": 5, "Mailer Box": 20, "Box Lid": 0}
+	for name, actualStock := range stock {
+		product := &models.Product{ID: primitive.NewObjectID(), Name: name, Category: "Box"}
+		product.Stock.ActualStock = actualStock
+		if _, err := db.Collection("products").InsertOne(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := &models.Product{ID: primitive.NewObjectID(), Name: "Bubble Wrap", Category: "Wrap"}
+	if _, err := db.Collection("products").InsertOne(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Adhesive Box", "Box Lid", "Carton", "Mailer Box"}},
+		{"sortBy=name&sortOrder=asc", []string{"Adhesive Box", "Box Lid", "Carton", "Mailer Box"}},
+		{"sortBy=name&sortOrder=desc", []string{"Mailer Box", "Carton", "Box Lid", "Adhesive Box"}},
+		{"sortBy=stock.actualStock&sortOrder=desc", []string{"Carton", "Mailer Box", "Adhesive Box", "Box Lid"}},
+		{"minStock=5&maxStock=20", []string{"Adhesive Box", "Mailer Box"}},
+		{"minStock=10", []string{"Carton", "Mailer Box"}},
+		{"maxStock=0", []string{"Box Lid"}},
+		{"minStock=100", []string{}},
+	}
+	for _, tt := range tests {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/products/category/Box?"+tt.query, nil), map[string]string{"category": "Box"})
+		rec := httptest.NewRecorder()
+		h.GetByCategory(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+			continue
+		}
+
+		var products []models.Product
+		if err := json.NewDecoder(rec.Body).Decode(&products); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, product := range products {
+			names = append(names, product.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%q: products = %q, want %q", tt.query, names, tt.want)
+		}
+	}
+}
+
+func TestGetByCategoryRejectsInvalidQueries(t *testing.T) {
+	h := &ProductHandler{}
+	for _, query := range []string{"sortBy=cost", "sortOrder=up", "minStock=abc", "minStock=20&maxStock=5"} {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/products/category/Box?"+query, nil), map[string]string{"category": "Box"})
+		rec := httptest.NewRecorder()
+		h.GetByCategory(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetLowStockProductsRejectsInvalidThresholds(t *testing.T) {
+	h := &ProductHandler{}
+	for _, threshold := range []string{"abc", "0", "-5"} {
+		rec := httptest.NewRecorder()
+		h.GetLowStockProducts(rec, httptest.NewRequest(http.MethodGet, "/api/products/low-stock?threshold="+threshold, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("threshold=%s: status = %d, want %d", threshold, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetLabelSheetRejectsInvalidGrids(t *testing.T) {
+	h := &ProductHandler{}
+	for _, query := range []string{"columns=0", "columns=7", "rows=16", "rows=abc", "columns=4&rows=-1"} {
+		rec := httptest.NewRecorder()
+		h.GetLabelSheet(rec, httptest.NewRequest(http.MethodGet, "/api/products/label-sheet?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// regenerateQR calls handler and returns how many products it reports updated
+func regenerateQR(t *testing.T, handler http.HandlerFunc, req *http.Request) int64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var result map[string]int64
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result["updated"]
+}
+
+func TestRegenerateQRAfterSKUChange(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ProductHandler{repo: productRepo}
+
+	product := &models.Product{SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	if err := productRepo.Create(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	id := product.ID.Hex()
+	if product.QRData != "BOX-0001" {
+		t.Fatalf("QRData = %q on creation, want BOX-0001", product.QRData)
+	}
+
+	// The SKU is corrected, leaving the QR data stale
+	if err := productRepo.Patch(ctx, id, bson.M{"skuId": "BOX-0042"}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := func() *http.Request {
+		return mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/products/"+id+"/regenerate-qr", nil), map[string]string{"id": id})
+	}
+	if updated := regenerateQR(t, h.RegenerateQR, req()); updated != 1 {
+		t.Errorf("updated = %d, want 1", updated)
+	}
+	stored, err := productRepo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.QRData != "BOX-0042" {
+		t.Errorf("QRData = %q after regenerating, want the new SKU BOX-0042", stored.QRData)
+	}
+	if updated := regenerateQR(t, h.RegenerateQR, req()); updated != 0 {
+		t.Errorf("updated = %d on a second call, want 0", updated)
+	}
+}
+
+func TestRegenerateAllQR(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ProductHandler{repo: productRepo}
+
+	defer func(format string) { models.QRDataFormat = format }(models.QRDataFormat)
+	models.QRDataFormat = "https://goodpack.app/product/{skuId}"
+
+	// More stale products than one bulk write takes, and one already up to date
+	var products []interface{}
+	for i := 1; i <= 250; i++ {
+		products = append(products, &models.Product{ID: primitive.NewObjectID(), SKUID: fmt.Sprintf("BOX-%04d", i), QRData: "stale"})
+	}
+	current := &models.Product{ID: primitive.NewObjectID(), SKUID: "WRP-0001", QRData: "https://goodpack.app/product/WRP-0001"}
+	products = append(products, current)
+	if _, err := db.Collection("products").InsertMany(ctx, products); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated := regenerateQR(t, h.RegenerateAllQR, httptest.NewRequest(http.MethodPost, "/api/products/regenerate-all-qr", nil)); updated != 250 {
+		t.Errorf("updated = %d, want 250", updated)
+	}
+	for _, sku := range []string{"BOX-0001", "BOX-0250", "WRP-0001"} {
+		var stored models.Product
+		if err := db.Collection("products").FindOne(ctx, bson.M{"skuId": sku}).Decode(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if want := "https://goodpack.app/product/" + sku; stored.QRData != want {
+			t.Errorf("%s: QRData = %q, want %q", sku, stored.QRData, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// mockStorage is an in-memory storage.FileStorage that records uploads and deletes
+type mockStorage struct {
+	files        map[string][]byte // URL -> content
+	contentTypes map[string]string // URL -> content type
+	deleted      []string
+	uploadErr    error
+}
+
+func newMockStorage() *mockStorage {
+	return &mockStorage{files: make(map[string][]byte), contentTypes: make(map[string]string)}
+}
+
+func (s *mockStorage) Upload(ctx context.Context, filename string, content io.Reader, contentType string) (string, error) {
+	if s.uploadErr != nil {
+		return "", s.uploadErr
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	url := "https://files.example.com/" + filename
+	s.files[url] = data
+	s.contentTypes[url] = contentType
+	return url, nil
+}
+
+func (s *mockStorage) Delete(ctx context.Context, url string) error {
+	if _, ok := s.files[url]; !ok {
+		return errors.New("no such file: " + url)
+	}
+	delete(s.files, url)
+	s.deleted = append(s.deleted, url)
+	return nil
+}
+
+// pngImage returns a small PNG
+func pngImage(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadImageRequest is a multipart image upload for the product
+func uploadImageRequest(t *testing.T, productID, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/products/"+productID+"/images", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return mux.SetURLVars(req, map[string]string{"id": productID})
+}
+
+// imageProduct stores a product without images and returns a handler using storage for its files
+func imageProduct(t *testing.T, storage *mockStorage) (*ProductHandler, *models.Product) {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	if _, err := db.Collection("products").InsertOne(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+	return &ProductHandler{repo: productRepo, fileStorage: storage}, product
+}
+
+func TestUploadProductImageRejectsNonImages(t *testing.T) {
+	storage := newMockStorage()
+	h := &ProductHandler{fileStorage: storage}
+
+	rec := httptest.NewRecorder()
+	h.UploadProductImage(rec, uploadImageRequest(t, primitive.NewObjectID().Hex(), "notes.txt", []byte("not an image at all")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("stored %d files, want none", len(storage.files))
+	}
+}
+
+func TestUploadAndDeleteProductImageUseFileStorage(t *testing.T) {
+	storage := newMockStorage()
+	h, product := imageProduct(t, storage)
+	content := pngImage(t)
+
+	rec := httptest.NewRecorder()
+	h.UploadProductImage(rec, uploadImageRequest(t, product.ID.Hex(), "box.png", content))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	stored, err := h.repo.GetByID(context.Background(), product.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Images) != 1 {
+		t.Fatalf("product has %d images, want 1", len(stored.Images))
+	}
+	uploaded := stored.Images[0]
+	prefix := "https://files.example.com/products/" + product.ID.Hex() + "_"
+	if !strings.HasPrefix(uploaded.URL, prefix) || !strings.HasSuffix(uploaded.URL, ".png") {
+		t.Errorf("image URL = %q, want %s<timestamp>.png", uploaded.URL, prefix)
+	}
+	if !bytes.Equal(storage.files[uploaded.URL], content) || storage.contentTypes[uploaded.URL] != "image/png" {
+		t.Errorf("stored original = %d bytes of %s, want the uploaded PNG", len(storage.files[uploaded.URL]), storage.contentTypes[uploaded.URL])
+	}
+	base := strings.TrimSuffix(uploaded.URL, ".png")
+	for size, url := range map[int]string{150: uploaded.Thumb150URL, 400: uploaded.Thumb400URL} {
+		if want := fmt.Sprintf("%s_thumb%d.jpg", base, size); url != want {
+			t.Errorf("%dpx thumbnail URL = %q, want %q", size, url, want)
+		}
+		if storage.contentTypes[url] != "image/jpeg" {
+			t.Errorf("thumbnail %q not stored as a JPEG", url)
+		}
+		config, err := jpeg.DecodeConfig(bytes.NewReader(storage.files[url]))
+		if err != nil {
+			t.Errorf("%dpx thumbnail: %v", size, err)
+			continue
+		}
+		if config.Width != size || config.Height != size {
+			t.Errorf("%dpx thumbnail is %dx%d", size, config.Width, config.Height)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/products/"+product.ID.Hex()+"/images/0", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": product.ID.Hex(), "imageIndex": "0"})
+	rec = httptest.NewRecorder()
+	h.DeleteProductImage(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	want := uploaded.FileURLs()
+	sort.Strings(want)
+	sort.Strings(storage.deleted)
+	if strings.Join(storage.deleted, " ") != strings.Join(want, " ") {
+		t.Errorf("deleted %v, want %v", storage.deleted, want)
+	}
+	if len(storage.files) != 0 {
+		t.Errorf("%d files left in storage, want none", len(storage.files))
+	}
+}
+
+func TestUploadProductImageFailsWhenStorageFails(t *testing.T) {
+	storage := newMockStorage()
+	storage.uploadErr = errors.New("bucket unavailable")
+	h, product := imageProduct(t, storage)
+
+	rec := httptest.NewRecorder()
+	h.UploadProductImage(rec, uploadImageRequest(t, product.ID.Hex(), "box.png", pngImage(t)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	stored, err := h.repo.GetByID(context.Background(), product.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Images) != 0 {
+		t.Errorf("product has %d images, want none", len(stored.Images))
+	}
+}
+
+func TestServeProductImageRejectsUnknownSizes(t *testing.T) {
+	h := &ProductHandler{}
+	for _, size := range []string{"200", "large", "-150"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/images/products/box.png?size="+size, nil)
+		req = mux.SetURLVars(req, map[string]string{"filename": "box.png"})
+		rec := httptest.NewRecorder()
+		h.ServeProductImage(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("size=%s: status = %d, want %d", size, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
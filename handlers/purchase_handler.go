@@ -1,17 +1,20 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"goodpack-server/apierrors"
+	"goodpack-server/middleware"
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/services"
 )
 
 type PurchaseHandler struct {
@@ -19,20 +22,98 @@ type PurchaseHandler struct {
 	customerRepo        *repository.CustomerRepository
 	productRepo         *repository.ProductRepository
 	stockAdjustmentRepo *repository.StockAdjustmentRepository
+	supplierRepo        *repository.SupplierRepository
+	serialNumberRepo    *repository.SerialNumberRepository
+	lotRepo             *repository.LotRepository
+	exchangeRateRepo    *repository.ExchangeRateRepository
+	bankAccountService  *services.BankAccountService
+	pdfService          *services.PDFService
+	webhookService      *services.WebhookService
+	adminToken          string // purchases of requests without it wait for approval; "" approves every purchase
 }
 
-func NewPurchaseHandler(purchaseRepo *repository.PurchaseRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) *PurchaseHandler {
+func NewPurchaseHandler(purchaseRepo *repository.PurchaseRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository, supplierRepo *repository.SupplierRepository, serialNumberRepo *repository.SerialNumberRepository, lotRepo *repository.LotRepository, exchangeRateRepo *repository.ExchangeRateRepository, webhookService *services.WebhookService, adminToken string) *PurchaseHandler {
 	return &PurchaseHandler{
 		purchaseRepo:        purchaseRepo,
 		customerRepo:        customerRepo,
 		productRepo:         productRepo,
 		stockAdjustmentRepo: stockAdjustmentRepo,
+		supplierRepo:        supplierRepo,
+		serialNumberRepo:    serialNumberRepo,
+		lotRepo:             lotRepo,
+		exchangeRateRepo:    exchangeRateRepo,
+		bankAccountService:  services.NewBankAccountService(),
+		pdfService:          services.NewPDFService(),
+		webhookService:      webhookService,
+		adminToken:          adminToken,
 	}
 }
 
+// purchaseApprovalStatuses are the approval statuses GetPurchases can filter by
+var purchaseApprovalStatuses = []string{
+	models.PurchaseApprovalPending,
+	models.PurchaseApprovalApproved,
+	models.PurchaseApprovalRejected,
+}
+
+// applyApproval approves a purchase created or confirmed by an admin, or by anyone when no admin token is
+// configured; anyone else's purchase waits for an admin's approval before it adds stock. It reports whether the
+// purchase is approved.
+func (h *PurchaseHandler) applyApproval(r *http.Request, purchase *models.Purchase) bool {
+	purchase.RequestApproval()
+	if h.adminToken != "" && !middleware.IsAdmin(r, h.adminToken) {
+		return false
+	}
+	purchase.Approve(changedBy(r))
+	return true
+}
+
+// applySupplier validates the optional supplier of a purchase and copies its name
+func (h *PurchaseHandler) applySupplier(ctx context.Context, purchase *models.Purchase) error {
+	if purchase.SupplierID == nil || *purchase.SupplierID == "" {
+		purchase.SupplierID = nil
+		purchase.SupplierName = nil
+		return nil
+	}
+
+	supplier, err := h.supplierRepo.GetByID(ctx, *purchase.SupplierID)
+	if err != nil {
+		return err
+	}
+
+	supplierName := supplier.GetDisplayName()
+	purchase.SupplierName = &supplierName
+	return nil
+}
+
 // enrichPurchaseWithCustomerData enriches a purchase with customer data
-func (h *PurchaseHandler) enrichPurchaseWithCustomerData(purchase *models.Purchase) {
-	customer, err := h.customerRepo.GetByID(purchase.CustomerID)
+// resolveExchangeRate fills in the exchange rate of a purchase in a foreign currency sent without one, from the rate
+// stored for its purchase date or the latest before it. It reports whether the purchase has a rate.
+func (h *PurchaseHandler) resolveExchangeRate(w http.ResponseWriter, r *http.Request, purchaseRequest *models.PurchaseRequest) bool {
+	currency := purchaseRequest.Currency
+	if currency == "" || currency == models.BaseCurrency || purchaseRequest.ExchangeRate > 0 {
+		return true
+	}
+
+	date := purchaseRequest.PurchaseDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	rate, err := h.exchangeRateRepo.GetRate(r.Context(), currency, date)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "exchange_rate_missing", currency, date.Format("2006-01-02")))
+		return false
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "exchange_rate_fetch_failed"))
+		return false
+	}
+	purchaseRequest.ExchangeRate = rate.Rate
+	return true
+}
+
+func (h *PurchaseHandler) enrichPurchaseWithCustomerData(ctx context.Context, purchase *models.Purchase) {
+	customer, err := h.customerRepo.GetByID(ctx, purchase.CustomerID)
 	if err == nil {
 		// Update purchase with customer data
 		purchase.CustomerName = customer.CompanyName
@@ -47,44 +128,28 @@ func (h *PurchaseHandler) enrichPurchaseWithCustomerData(purchase *models.Purcha
 	}
 }
 
-// generatePurchaseID generates a unique purchase ID based on VAT status
-func (h *PurchaseHandler) generatePurchaseID(ctx context.Context, isVAT bool) (string, error) {
-	now := time.Now()
-	// Convert to Buddhist Era (BE)
-	beYear := now.Year() + 543
-	dateStr := fmt.Sprintf("%02d%02d", beYear%100, int(now.Month())) // YYMM format
+func (h *PurchaseHandler) GetPurchases(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	var prefix string
-	if isVAT {
-		prefix = fmt.Sprintf("PUR-VAT-%s", dateStr)
-	} else {
-		prefix = fmt.Sprintf("PUR-NV-%s", dateStr)
+	sort, ok := parseSort(w, r, purchaseSortFields)
+	if !ok {
+		return
 	}
-
-	// Get the next sequence number for this prefix
-	nextSeq, err := h.purchaseRepo.GetNextSequenceNumber(ctx, prefix)
-	if err != nil {
-		return "", err
+	approvalStatus := r.URL.Query().Get("approvalStatus")
+	if approvalStatus != "" && !slices.Contains(purchaseApprovalStatuses, approvalStatus) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_approval_status"))
+		return
 	}
 
-	// Format sequence number with leading zeros (4 digits)
-	seqStr := fmt.Sprintf("%04d", nextSeq)
-
-	return prefix + "-" + seqStr, nil
-}
-
-func (h *PurchaseHandler) GetPurchases(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-
-	purchases, err := h.purchaseRepo.GetAll(ctx)
+	purchases, err := h.purchaseRepo.GetAll(ctx, approvalStatus, sort)
 	if err != nil {
-		http.Error(w, "Failed to fetch purchases", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchases_fetch_failed"))
 		return
 	}
 
 	// Enrich purchases with customer data
 	for i := range purchases {
-		h.enrichPurchaseWithCustomerData(purchases[i])
+		h.enrichPurchaseWithCustomerData(ctx, purchases[i])
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -92,56 +157,86 @@ func (h *PurchaseHandler) GetPurchases(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PurchaseHandler) GetPurchase(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid purchase ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	purchase, err := h.purchaseRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Purchase not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
 		return
 	}
 
 	// Enrich purchase with customer data
-	h.enrichPurchaseWithCustomerData(purchase)
+	h.enrichPurchaseWithCustomerData(ctx, purchase)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(purchase)
 }
 
-func (h *PurchaseHandler) CreatePurchase(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+// GetPurchasePDF generates a purchase document PDF
+func (h *PurchaseHandler) GetPurchasePDF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/pdf)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
 
-	// Read request body for debugging
-	body, err := ioutil.ReadAll(r.Body)
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
 		return
 	}
 
-	// Log request body
-	fmt.Printf("Request body: %s\n", string(body))
+	h.enrichPurchaseWithCustomerData(ctx, purchase)
+	if purchase.Payment.OurAccount != nil && *purchase.Payment.OurAccount != "" {
+		bankAccount, err := h.bankAccountService.LoadBankAccountFromConfig(*purchase.Payment.OurAccount)
+		if err == nil && bankAccount != nil {
+			purchase.Payment.OurAccountInfo = bankAccount
+		}
+	}
 
-	// Create new reader from body
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	pdf, err := h.pdfService.GeneratePurchasePDF(purchase)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "pdf_generate_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.pdf", purchase.PurchaseCode))
+	w.Write(pdf)
+}
+
+func (h *PurchaseHandler) CreatePurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
 	var purchaseRequest models.PurchaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&purchaseRequest); err != nil {
 		fmt.Printf("JSON decode error: %v\n", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &purchaseRequest) {
+		return
+	}
+	if !h.resolveExchangeRate(w, r, &purchaseRequest) {
 		return
 	}
 
 	// Get customer name
-	customer, err := h.customerRepo.GetByID(purchaseRequest.CustomerID)
+	customer, err := h.customerRepo.GetByID(ctx, purchaseRequest.CustomerID)
 	if err != nil {
-		http.Error(w, "Customer not found", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "customer_not_found"))
 		return
 	}
 
@@ -152,24 +247,34 @@ func (h *PurchaseHandler) CreatePurchase(w http.ResponseWriter, r *http.Request)
 	}
 	purchase.ContactName = &customer.ContactName
 
+	if err := h.applySupplier(ctx, purchase); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "supplier_not_found"))
+		return
+	}
+	if !h.checkSerialNumbers(w, r, purchase.Items, "") || !h.checkLots(w, r, purchase.Items) {
+		return
+	}
+
 	// Generate unique purchase code
-	purchaseCode, err := h.generatePurchaseID(ctx, purchase.IsVAT)
+	purchaseCode, err := services.GeneratePurchaseCode(ctx, h.purchaseRepo, purchase.IsVAT)
 	if err != nil {
-		http.Error(w, "Failed to generate purchase code", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_code_generate_failed"))
 		return
 	}
 	purchase.PurchaseCode = purchaseCode
+	h.applyUOM(ctx, purchase)
+	approved := h.applyApproval(r, purchase)
 
 	// Create purchase
 	if err := h.purchaseRepo.Create(ctx, purchase); err != nil {
-		http.Error(w, "Failed to create purchase", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_create_failed"))
 		return
 	}
 
-	// Update product prices and stock
-	if err := h.updateProductData(ctx, purchase); err != nil {
-		// Log error but don't fail the purchase creation
-		// TODO: Add proper logging
+	// Update product prices; a purchase awaiting approval only does when it is approved. Stock is added as
+	// goods are received.
+	if approved {
+		h.addToStock(ctx, purchase)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -178,12 +283,12 @@ func (h *PurchaseHandler) CreatePurchase(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *PurchaseHandler) UpdatePurchase(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid purchase ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
@@ -191,20 +296,33 @@ func (h *PurchaseHandler) UpdatePurchase(w http.ResponseWriter, r *http.Request)
 	// Get existing purchase
 	existingPurchase, err := h.purchaseRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Purchase not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
 		return
 	}
 
 	var purchaseRequest models.PurchaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&purchaseRequest); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &purchaseRequest) {
+		return
+	}
+	if existingPurchase.CurrentStatus() == models.OrderStatusCancelled {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_cancelled"))
+		return
+	}
+	if !checkVersion(w, r, purchaseRequest.Version, existingPurchase.Version) {
+		return
+	}
+	if !h.resolveExchangeRate(w, r, &purchaseRequest) {
 		return
 	}
 
 	// Get customer name
-	customer, err := h.customerRepo.GetByID(purchaseRequest.CustomerID)
+	customer, err := h.customerRepo.GetByID(ctx, purchaseRequest.CustomerID)
 	if err != nil {
-		http.Error(w, "Customer not found", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "customer_not_found"))
 		return
 	}
 
@@ -215,15 +333,36 @@ func (h *PurchaseHandler) UpdatePurchase(w http.ResponseWriter, r *http.Request)
 		existingPurchase.CustomerName = customer.ContactName
 	}
 
+	if err := h.applySupplier(ctx, existingPurchase); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "supplier_not_found"))
+		return
+	}
+	// A draft's serial numbers and lots are checked when it is confirmed
+	if !existingPurchase.IsDraft && (!h.checkSerialNumbers(w, r, existingPurchase.Items, id) || !h.checkLots(w, r, existingPurchase.Items)) {
+		return
+	}
+	h.applyUOM(ctx, existingPurchase)
+
 	if err := h.purchaseRepo.Update(ctx, id, existingPurchase); err != nil {
-		http.Error(w, "Failed to update purchase", http.StatusInternalServerError)
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_update_failed"))
 		return
 	}
 
-	// Update product prices and stock
-	if err := h.updateProductData(ctx, existingPurchase); err != nil {
-		// Log error but don't fail the purchase update
-		// TODO: Add proper logging
+	// Update product prices; a draft only does when it is confirmed and a purchase awaiting approval when it is
+	// approved
+	if existingPurchase.AddsStock() {
+		if err := h.updateProductData(ctx, existingPurchase); err != nil {
+			// Log error but don't fail the purchase update
+			// TODO: Add proper logging
+		}
+		h.receiveSerialNumbers(ctx, existingPurchase)
+		h.receiveLots(ctx, existingPurchase)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -231,56 +370,492 @@ func (h *PurchaseHandler) UpdatePurchase(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *PurchaseHandler) DeletePurchase(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid purchase ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	if err := h.purchaseRepo.Delete(ctx, id); err != nil {
-		http.Error(w, "Failed to delete purchase", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_delete_failed"))
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *PurchaseHandler) updateProductData(ctx context.Context, purchase *models.Purchase) error {
-	// Update product prices and stock for each item
+// DuplicatePurchase creates a draft copy of a purchase for a repeat order (POST /api/purchases/{id}/duplicate).
+// The copy gets a new purchase code, today's date and no payment; stock is only added when it is confirmed.
+func (h *PurchaseHandler) DuplicatePurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/duplicate)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	original, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+
+	purchaseCode, err := services.GeneratePurchaseCode(ctx, h.purchaseRepo, original.IsVAT)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_code_generate_failed"))
+		return
+	}
+
+	purchase := original.Duplicate(purchaseCode)
+	if err := h.purchaseRepo.Create(ctx, purchase); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_duplicate_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// ConfirmPurchase confirms a draft purchase (POST /api/purchases/{id}/confirm), updating product prices as
+// creating a purchase does; its goods can then be received into stock
+func (h *PurchaseHandler) ConfirmPurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/confirm)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+	if !purchase.IsDraft {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_not_draft"))
+		return
+	}
+	if !h.checkSerialNumbers(w, r, purchase.Items, id) || !h.checkLots(w, r, purchase.Items) {
+		return
+	}
+
+	if err := purchase.TransitionStatus(models.OrderStatusConfirmed, changedBy(r), nil); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_not_draft"))
+		return
+	}
+	purchase.IsDraft = false
+	approved := h.applyApproval(r, purchase)
+	if err := h.purchaseRepo.Update(ctx, id, purchase); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_confirm_failed"))
+		return
+	}
+
+	if approved {
+		h.addToStock(ctx, purchase)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// ApprovePurchase approves a purchase awaiting approval (POST /api/purchases/{id}/approve, admin only), updating
+// product prices as creating a purchase does; its goods can then be received into stock
+func (h *PurchaseHandler) ApprovePurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/approve)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+	if err := purchase.Approve(changedBy(r)); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_not_pending"))
+		return
+	}
+	if !h.saveApproval(w, r, id, purchase) {
+		return
+	}
+
+	h.addToStock(ctx, purchase)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// RejectPurchase rejects a purchase awaiting approval with a reason (POST /api/purchases/{id}/reject, admin
+// only); its items never reach stock
+func (h *PurchaseHandler) RejectPurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/reject)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	var rejectRequest models.PurchaseRejectRequest
+	if err := json.NewDecoder(r.Body).Decode(&rejectRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &rejectRequest) {
+		return
+	}
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+	if err := purchase.Reject(rejectRequest.Reason, changedBy(r)); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_not_pending"))
+		return
+	}
+	if !h.saveApproval(w, r, id, purchase) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// saveApproval stores an approved or rejected purchase, responding with the error if that fails
+func (h *PurchaseHandler) saveApproval(w http.ResponseWriter, r *http.Request, id string, purchase *models.Purchase) bool {
+	ctx := r.Context()
+	if err := h.purchaseRepo.Update(ctx, id, purchase); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return false
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_approval_failed"))
+		return false
+	}
+	return true
+}
+
+// UpdatePurchaseStatus moves a purchase along its status workflow (PUT /api/purchases/{id}/status): draft →
+// confirmed → processing → shipped → completed, or cancelled before it ships. Confirming a draft works like
+// ConfirmPurchase; cancelling takes back out of stock what the purchase added and its serial numbers and lots.
+func (h *PurchaseHandler) UpdatePurchaseStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/status)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	var statusReq models.StatusUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&statusReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &statusReq) {
+		return
+	}
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+
+	confirmDraft := statusReq.Status == models.OrderStatusConfirmed && purchase.IsDraft
+	// Taken before the transition, as a cancelled purchase no longer adds stock
+	var stocked map[string]int
+	if statusReq.Status == models.OrderStatusCancelled {
+		stocked = purchase.StockedQuantities()
+	}
+	removeStock := statusReq.Status == models.OrderStatusCancelled && purchase.AddsStock()
+
+	if confirmDraft && (!h.checkSerialNumbers(w, r, purchase.Items, id) || !h.checkLots(w, r, purchase.Items)) {
+		return
+	}
+	if err := purchase.TransitionStatus(statusReq.Status, changedBy(r), statusReq.Notes); err != nil {
+		var transitionErr *models.StatusTransitionError
+		if errors.As(err, &transitionErr) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "invalid_purchase_status_transition", transitionErr.From, transitionErr.To))
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_status_update_failed"))
+		return
+	}
+	approved := false
+	if confirmDraft {
+		purchase.IsDraft = false
+		approved = h.applyApproval(r, purchase)
+	}
+
+	// Take the stock back out and save the purchase in one transaction, so neither is left without the other
+	version := purchase.Version
+	err = h.purchaseRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		purchase.Version = version // a retried transaction saves the purchase again from the version it was read at
+		if removeStock {
+			if err := h.removeStock(txCtx, purchase, stocked); err != nil {
+				return err
+			}
+		}
+		return h.purchaseRepo.Update(txCtx, id, purchase)
+	})
+	if err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		fmt.Printf("Error changing status of purchase %s: %v\n", id, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_status_update_failed"))
+		return
+	}
+
+	if approved {
+		h.addToStock(ctx, purchase)
+	}
+	if removeStock {
+		if err := h.serialNumberRepo.RemovePurchase(ctx, id); err != nil {
+			fmt.Printf("Warning: Failed to remove serial numbers of cancelled purchase %s: %v\n", purchase.PurchaseCode, err)
+		}
+		if err := h.lotRepo.EmptyPurchase(ctx, id); err != nil {
+			fmt.Printf("Warning: Failed to empty lots of cancelled purchase %s: %v\n", purchase.PurchaseCode, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// removeStock takes the quantities a cancelled purchase added out of stock and records the stock history.
+// Products deleted since the purchase are skipped.
+func (h *PurchaseHandler) removeStock(ctx context.Context, purchase *models.Purchase, stocked map[string]int) error {
+	stockType := services.StockTypeForVAT(purchase.IsVAT)
+	purchaseID := purchase.ID.Hex()
+	purchaseCode := purchase.PurchaseCode
+	notes := fmt.Sprintf("ยกเลิกรายการซื้อ %s", purchaseCode)
+
+	seen := make(map[string]bool)
 	for _, item := range purchase.Items {
-		product, err := h.productRepo.GetByID(ctx, item.ProductID)
+		quantity := stocked[item.ProductID]
+		if seen[item.ProductID] || quantity == 0 {
+			continue
+		}
+		seen[item.ProductID] = true
+
+		product, err := h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			services.ApplyStockAdjustment(product, models.AdjustmentTypeReduce, stockType, quantity)
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			continue
+		}
 		if err != nil {
-			continue // Skip if product not found
+			return fmt.Errorf("failed to remove stock of product %s for purchase %s: %w", item.ProductID, purchaseCode, err)
+		}
+		services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+
+		if err := services.RecordStockChange(
+			ctx,
+			h.stockAdjustmentRepo,
+			product,
+			models.SourceTypePurchase,
+			&purchaseID,
+			&purchaseCode,
+			models.AdjustmentTypeReduce,
+			stockType,
+			quantity,
+			&notes,
+		); err != nil {
+			return fmt.Errorf("failed to record stock change of product %s for purchase %s: %w", item.ProductID, purchaseCode, err)
+		}
+	}
+	return nil
+}
+
+// addToStock updates the product prices, serial numbers and lots for a confirmed, approved purchase, and the stock
+// of one saved before receipts added stock, and announces it
+func (h *PurchaseHandler) addToStock(ctx context.Context, purchase *models.Purchase) {
+	if err := h.updateProductData(ctx, purchase); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Warning: Failed to update products for purchase %s: %v\n", purchase.PurchaseCode, err)
+	}
+	h.receiveSerialNumbers(ctx, purchase)
+	h.receiveLots(ctx, purchase)
+
+	dispatchWebhook(h.webhookService, models.WebhookEventPurchaseCreated, purchase)
+}
+
+// applyUOM fills in the display quantity and unit of each item from its product
+func (h *PurchaseHandler) applyUOM(ctx context.Context, purchase *models.Purchase) {
+	for i := range purchase.Items {
+		product, err := h.productRepo.GetByID(ctx, purchase.Items[i].ProductID)
+		if err != nil {
+			continue
 		}
+		purchase.Items[i].ApplyUOM(product)
+	}
+}
 
-		// Update purchase price using new UpdatePrice method
-		product.UpdatePrice(item.UnitPrice, purchase.IsVAT, true) // true = isPurchase
+// checkSerialNumbers checks that each item of a serialised product lists one serial number per unit and that
+// none of them was already received by another purchase (purchaseID is the purchase being updated, "" for a
+// new one), writing the error response when they do not
+func (h *PurchaseHandler) checkSerialNumbers(w http.ResponseWriter, r *http.Request, items []models.PurchaseItem, purchaseID string) bool {
+	for _, item := range items {
+		product, err := h.productRepo.GetByID(r.Context(), item.ProductID)
+		if err != nil {
+			continue // Unknown products are skipped like in updateProductData
+		}
+		if !product.IsSerialised {
+			if len(item.SerialNumbers) > 0 {
+				RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_serialised", item.ProductID))
+				return false
+			}
+			continue
+		}
+		if reason := models.CheckSerialNumbers(item.SerialNumbers, item.Quantity); reason != "" {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_serial_numbers", item.ProductID, reason))
+			return false
+		}
 
-		// Determine stock type based on VAT status
-		var stockType models.StockType
-		if purchase.IsVAT {
-			stockType = models.StockTypeVAT
-		} else {
-			stockType = models.StockTypeNonVAT
+		existing, err := h.serialNumberRepo.GetBySerials(r.Context(), item.ProductID, item.SerialNumbers)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "serial_numbers_fetch_failed"))
+			return false
 		}
+		for _, serial := range item.SerialNumbers {
+			unit, ok := existing[serial]
+			if ok && (unit.PurchaseID == nil || *unit.PurchaseID != purchaseID) {
+				RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "serial_number_already_received", serial, item.ProductID))
+				return false
+			}
+		}
+	}
+	return true
+}
 
-		// Apply stock adjustment using centralized stock management logic
-		ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.Quantity)
+// receiveSerialNumbers records the units of the purchase's serialised items as available
+func (h *PurchaseHandler) receiveSerialNumbers(ctx context.Context, purchase *models.Purchase) {
+	purchaseID := purchase.ID.Hex()
+	for _, item := range purchase.Items {
+		if len(item.SerialNumbers) == 0 {
+			continue
+		}
+		if err := h.serialNumberRepo.Receive(ctx, item.ProductID, item.SerialNumbers, purchaseID, purchase.PurchaseDate); err != nil {
+			fmt.Printf("Warning: Failed to record serial numbers of product %s for purchase %s: %v\n", item.ProductID, purchase.PurchaseCode, err)
+		}
+	}
+}
 
-		// Save updated product
-		if err := h.productRepo.Update(ctx, item.ProductID, product); err != nil {
+// checkLots checks that each item of a lot-tracked product has a lot number and expiry date and that items of
+// other products have neither, writing the error response when they do not
+func (h *PurchaseHandler) checkLots(w http.ResponseWriter, r *http.Request, items []models.PurchaseItem) bool {
+	for i := range items {
+		item := &items[i]
+		product, err := h.productRepo.GetByID(r.Context(), item.ProductID)
+		if err != nil {
+			continue // Unknown products are skipped like in updateProductData
+		}
+		if !product.LotTracking {
+			if item.LotNumber != "" || item.ExpiryDate != nil {
+				RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_lot_tracked", item.ProductID))
+				return false
+			}
 			continue
 		}
+		if reason := item.CheckLot(); reason != "" {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_lot", item.ProductID, reason))
+			return false
+		}
+	}
+	return true
+}
+
+// receiveLots records the lots of the purchase's lot-tracked items with their quantities
+func (h *PurchaseHandler) receiveLots(ctx context.Context, purchase *models.Purchase) {
+	purchaseID := purchase.ID.Hex()
+	for _, item := range purchase.Items {
+		if item.LotNumber == "" {
+			continue
+		}
+		lot := &models.Lot{
+			ProductID:  item.ProductID,
+			LotNumber:  item.LotNumber,
+			ExpiryDate: item.ExpiryDate,
+			Quantity:   item.Quantity,
+			PurchaseID: purchaseID,
+			ReceivedAt: purchase.PurchaseDate,
+		}
+		if err := h.lotRepo.Receive(ctx, lot); err != nil {
+			fmt.Printf("Warning: Failed to record lot %s of product %s for purchase %s: %v\n", item.LotNumber, item.ProductID, purchase.PurchaseCode, err)
+		}
+	}
+}
+
+func (h *PurchaseHandler) updateProductData(ctx context.Context, purchase *models.Purchase) error {
+	// Update product prices and stock for each item
+	for i := range purchase.Items {
+		item := &purchase.Items[i]
+		stockType := services.StockTypeForVAT(purchase.IsVAT)
+		product, err := h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			// Compare with the average purchase price before this purchase moves it
+			item.ApplyPriceVariance(product.StandardCost(purchase.IsVAT))
+
+			// Update purchase price using new UpdatePrice method
+			product.UpdatePrice(item.UnitPrice, purchase.IsVAT, true) // true = isPurchase
+
+			// Save the updated price; the stock is added by receiveStock as goods are received, except for
+			// purchases saved before that, which added the ordered quantities here
+			if !purchase.StockOnReceipt {
+				services.ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.Quantity)
+			}
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			continue // Skip if product not found
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update product %s: %w", item.ProductID, err)
+		}
+		if purchase.StockOnReceipt {
+			continue
+		}
+		services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
 
 		// Record stock change in history
 		purchaseID := purchase.ID.Hex()
 		purchaseCode := purchase.PurchaseCode
 		notes := fmt.Sprintf("ซื้อจากรายการ %s", purchaseCode)
-		if err := RecordStockChange(
+		if err := services.RecordStockChange(
 			ctx,
 			h.stockAdjustmentRepo,
 			product,
@@ -297,5 +872,188 @@ func (h *PurchaseHandler) updateProductData(ctx context.Context, purchase *model
 		}
 	}
 
-	return nil
+	return h.purchaseRepo.SetItemPriceVariances(ctx, purchase.ID.Hex(), purchase.Items)
+}
+
+// receiptErrorCodes are the error codes of the errors of Purchase.AddReceipt
+var receiptErrorCodes = map[error]string{
+	models.ErrReceiptEmpty:       "receipt_empty",
+	models.ErrReceiptQuantity:    "receipt_quantity_invalid",
+	models.ErrReceiptProduct:     "receipt_product_not_in_purchase",
+	models.ErrReceiptOverOrdered: "receipt_over_ordered",
+}
+
+// ReceivePurchase records goods received into the warehouse against a confirmed, approved purchase and adds the
+// received quantities to stock. Purchases saved before receipts added stock (without stockOnReceipt) added the
+// ordered quantities when they were confirmed, so their receipts are only recorded.
+func (h *PurchaseHandler) ReceivePurchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/receive)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+
+	var req models.WarehouseReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+
+	receipt := models.WarehouseReceipt{
+		ReceivedBy: req.ReceivedBy,
+		Items:      req.Items,
+	}
+	if req.ReceivedAt != nil {
+		receipt.ReceivedAt = *req.ReceivedAt
+	}
+
+	if purchase.IsDraft {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_is_draft"))
+		return
+	}
+	if purchase.CurrentStatus() == models.OrderStatusCancelled {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_cancelled"))
+		return
+	}
+	if !purchase.IsApproved() {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_not_approved"))
+		return
+	}
+
+	added, err := purchase.AddReceipt(receipt)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, receiptErrorCodes[err]))
+		return
+	}
+
+	if err := h.purchaseRepo.Update(ctx, id, purchase); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "receipt_record_failed"))
+		return
+	}
+
+	if purchase.StockOnReceipt {
+		h.receiveStock(ctx, purchase, added)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchase)
+}
+
+// receiveStock adds the quantities of a warehouse receipt to product stock and records the stock history
+func (h *PurchaseHandler) receiveStock(ctx context.Context, purchase *models.Purchase, receipt *models.WarehouseReceipt) {
+	stockType := services.StockTypeForVAT(purchase.IsVAT)
+	purchaseID := purchase.ID.Hex()
+	purchaseCode := purchase.PurchaseCode
+
+	for _, item := range receipt.Items {
+		product, err := h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			services.ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.ReceivedQty)
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			continue // Skip if product not found
+		}
+		if err != nil {
+			fmt.Printf("Warning: Failed to update stock for received product %s: %v\n", item.ProductID, err)
+			continue
+		}
+		services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+
+		notes := fmt.Sprintf("รับสินค้าจากรายการ %s (%d/%d)", purchaseCode, purchase.ReceivedQuantity(item.ProductID), item.OrderedQty)
+		if err := services.RecordStockChange(
+			ctx,
+			h.stockAdjustmentRepo,
+			product,
+			models.SourceTypePurchase,
+			&purchaseID,
+			&purchaseCode,
+			models.AdjustmentTypeAdd,
+			stockType,
+			item.ReceivedQty,
+			&notes,
+		); err != nil {
+			fmt.Printf("Warning: Failed to record stock change history: %v\n", err)
+		}
+	}
+}
+
+// RecordPayment records a payment made for a purchase, marking it paid once the grand total is covered
+func (h *PurchaseHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/payment)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+
+	record, ok := decodePaymentRecord(w, r)
+	if !ok {
+		return
+	}
+	if err := purchase.AddPayment(*record); err != nil {
+		writePaymentError(w, r, err)
+		return
+	}
+
+	if err := h.purchaseRepo.Update(ctx, id, purchase); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.purchaseRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "payment_record_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.NewPaymentSummary(purchase.Payments, purchase.GrandTotal, purchase.Payment.IsPaid))
+}
+
+// GetPayments lists the payments recorded for a purchase
+func (h *PurchaseHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/purchases/{id}/payments)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_purchase_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	purchase, err := h.purchaseRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "purchase_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewPaymentSummary(purchase.Payments, purchase.GrandTotal, purchase.Payment.IsPaid))
 }
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// receivingTest is a purchase handler on a test database with one product and one purchase of 100 of it
+type receivingTest struct {
+	t        *testing.T
+	h        *PurchaseHandler
+	product  *models.Product
+	purchase *models.Purchase
+}
+
+func newReceivingTest(t *testing.T, db *mongo.Database, purchase *models.Purchase) *receivingTest {
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &PurchaseHandler{
+		purchaseRepo:        repository.NewPurchaseRepository(db.Collection("purchases")),
+		customerRepo:        repository.NewCustomerRepository(db.Collection("customers")),
+		productRepo:         productRepo,
+		stockAdjustmentRepo: repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")),
+		serialNumberRepo:    repository.NewSerialNumberRepository(db.Collection("serial_numbers")),
+		lotRepo:             repository.NewLotRepository(db.Collection("lots")),
+	}
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	purchase.IsVAT = true
+	purchase.Items = []models.PurchaseItem{{ProductID: product.ID.Hex(), ProductName: product.Name, Quantity: 100, UnitPrice: 10}}
+	if err := h.purchaseRepo.Create(ctx, purchase); err != nil {
+		t.Fatal(err)
+	}
+	return &receivingTest{t: t, h: h, product: product, purchase: purchase}
+}
+
+// receive sends a receipt of quantity and returns the response status
+func (rt *receivingTest) receive(quantity int) int {
+	body := fmt.Sprintf(`{"items": [{"productId": %q, "receivedQty": %d}]}`, rt.product.ID.Hex(), quantity)
+	req := httptest.NewRequest(http.MethodPut, "/api/purchases/"+rt.purchase.ID.Hex()+"/receive", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	rt.h.ReceivePurchase(rec, req)
+	return rec.Code
+}
+
+// setStatus sends a status change and returns the response status
+func (rt *receivingTest) setStatus(status string) int {
+	req := httptest.NewRequest(http.MethodPut, "/api/purchases/"+rt.purchase.ID.Hex()+"/status", strings.NewReader(`{"status": "`+status+`"}`))
+	rec := httptest.NewRecorder()
+	rt.h.UpdatePurchaseStatus(rec, req)
+	return rec.Code
+}
+
+// stock returns the product's VAT stock remaining and its actual stock
+func (rt *receivingTest) stock() (int, int) {
+	product, err := rt.h.productRepo.GetByID(context.Background(), rt.product.ID.Hex())
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+	return product.Stock.VAT.Remaining, product.Stock.ActualStock
+}
+
+func TestReceivePurchaseFullReceipt(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{StockOnReceipt: true})
+
+	if code := rt.receive(100); code != http.StatusOK {
+		t.Fatalf("receive = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 100 || actual != 100 {
+		t.Errorf("stock = %d remaining, %d actual; want 100 each", remaining, actual)
+	}
+	purchase, _ := rt.h.purchaseRepo.GetByID(context.Background(), rt.purchase.ID.Hex())
+	if !purchase.Warehouse.IsUpdated || len(purchase.Warehouse.Receipts) != 1 {
+		t.Errorf("warehouse = %+v, want one receipt and updated", purchase.Warehouse)
+	}
+}
+
+func TestReceivePurchaseTwoPartialReceipts(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{StockOnReceipt: true})
+
+	if code := rt.receive(60); code != http.StatusOK {
+		t.Fatalf("first receipt = %d, want 200", code)
+	}
+	if remaining, _ := rt.stock(); remaining != 60 {
+		t.Errorf("after the first receipt: %d remaining, want 60", remaining)
+	}
+	if code := rt.receive(40); code != http.StatusOK {
+		t.Fatalf("second receipt = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 100 || actual != 100 {
+		t.Errorf("after both receipts: %d remaining, %d actual; want 100 each, not the ordered quantity twice", remaining, actual)
+	}
+}
+
+func TestReceivePurchaseRejectsOverReceipt(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{StockOnReceipt: true})
+
+	if code := rt.receive(90); code != http.StatusOK {
+		t.Fatalf("first receipt = %d, want 200", code)
+	}
+	if code := rt.receive(11); code != http.StatusBadRequest {
+		t.Errorf("receiving 11 with 10 outstanding = %d, want 400", code)
+	}
+	if remaining, _ := rt.stock(); remaining != 90 {
+		t.Errorf("%d remaining, want 90: the rejected receipt must not add stock", remaining)
+	}
+}
+
+func TestReceivePurchaseRecordsOnlyWhenStockWasAddedOnConfirmation(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{})
+
+	if code := rt.receive(100); code != http.StatusOK {
+		t.Fatalf("receive = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 0 || actual != 0 {
+		t.Errorf("stock = %d remaining, %d actual; want unchanged, the purchase added it when confirmed", remaining, actual)
+	}
+}
+
+func TestCreatePurchaseLeavesStockToReceipts(t *testing.T) {
+	db := testDatabase(t)
+	rt := newReceivingTest(t, db, &models.Purchase{})
+	customer := &models.Customer{ID: primitive.NewObjectID(), CustomerCode: "C0001", CompanyName: "Siam Paper"}
+	if _, err := db.Collection("customers").InsertOne(context.Background(), customer); err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"customerId": %q, "isVAT": true, "items": [{"productId": %q, "quantity": 100, "unitPrice": 10}]}`, customer.ID.Hex(), rt.product.ID.Hex())
+	rec := httptest.NewRecorder()
+	rt.h.CreatePurchase(rec, httptest.NewRequest(http.MethodPost, "/api/purchases", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+	var created models.Purchase
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if !created.StockOnReceipt {
+		t.Error("stockOnReceipt = false, want every new purchase to add stock through its receipts")
+	}
+	if remaining, actual := rt.stock(); remaining != 0 || actual != 0 {
+		t.Errorf("after creating: %d remaining, %d actual; want nothing until goods are received", remaining, actual)
+	}
+
+	rt.purchase = &created
+	if code := rt.receive(40); code != http.StatusOK {
+		t.Fatalf("receive = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 40 || actual != 40 {
+		t.Errorf("after receiving 40: %d remaining, %d actual; want 40 each", remaining, actual)
+	}
+}
+
+func TestReceivePurchaseRejectsDrafts(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{StockOnReceipt: true, IsDraft: true, Status: models.OrderStatusDraft})
+
+	if code := rt.receive(10); code != http.StatusConflict {
+		t.Errorf("receiving against a draft = %d, want 409", code)
+	}
+	if remaining, _ := rt.stock(); remaining != 0 {
+		t.Errorf("%d remaining, want 0", remaining)
+	}
+}
+
+func TestUpdatePurchaseStatusCancelRemovesReceivedStock(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{StockOnReceipt: true})
+
+	if code := rt.receive(60); code != http.StatusOK {
+		t.Fatalf("receive = %d, want 200", code)
+	}
+	if code := rt.setStatus(models.OrderStatusCancelled); code != http.StatusOK {
+		t.Fatalf("cancel = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 0 || actual != 0 {
+		t.Errorf("after cancelling: %d remaining, %d actual; want the 60 received taken back out", remaining, actual)
+	}
+
+	if code := rt.receive(40); code != http.StatusConflict {
+		t.Errorf("receiving against a cancelled purchase = %d, want 409", code)
+	}
+	if code := rt.setStatus(models.OrderStatusConfirmed); code != http.StatusConflict {
+		t.Errorf("reopening a cancelled purchase = %d, want 409", code)
+	}
+	if remaining, _ := rt.stock(); remaining != 0 {
+		t.Errorf("%d remaining, want 0", remaining)
+	}
+}
+
+func TestUpdatePurchaseStatusCancelRemovesStockAddedOnConfirmation(t *testing.T) {
+	rt := newReceivingTest(t, testDatabase(t), &models.Purchase{})
+	if _, err := rt.h.productRepo.Modify(context.Background(), rt.product.ID.Hex(), func(product *models.Product) error {
+		product.Stock.VAT.Remaining = 100
+		product.Stock.ActualStock = 100
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := rt.setStatus(models.OrderStatusCancelled); code != http.StatusOK {
+		t.Fatalf("cancel = %d, want 200", code)
+	}
+	if remaining, actual := rt.stock(); remaining != 0 || actual != 0 {
+		t.Errorf("after cancelling: %d remaining, %d actual; want the ordered 100 taken back out", remaining, actual)
+	}
+}
+
+func TestUpdatePurchaseStatusRejectsInvalidStatus(t *testing.T) {
+	h := &PurchaseHandler{}
+	req := httptest.NewRequest(http.MethodPut, "/api/purchases/64b7f0c2a1b2c3d4e5f60718/status", strings.NewReader(`{"status": "lost"}`))
+	rec := httptest.NewRecorder()
+	h.UpdatePurchaseStatus(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status lost = %d, want 400", rec.Code)
+	}
+}
+
+// captureStdout returns what fn writes to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestCreatePurchaseDoesNotLogTheBody(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewPurchaseHandler(
+		repository.NewPurchaseRepository(db.Collection("purchases")),
+		repository.NewCustomerRepository(db.Collection("customers")),
+		productRepo,
+		repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")),
+		repository.NewSupplierRepository(db.Collection("suppliers")),
+		repository.NewSerialNumberRepository(db.Collection("serial_numbers")),
+		repository.NewLotRepository(db.Collection("lots")),
+		repository.NewExchangeRateRepository(db.Collection("exchange_rates")),
+		nil, "",
+	)
+
+	customer := &models.Customer{ID: primitive.NewObjectID(), CustomerCode: "C0001", CompanyName: "Supplier Co"}
+	if _, err := db.Collection("customers").InsertOne(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	const secret = "bank transfer ref 0042-CONFIDENTIAL"
+	body := fmt.Sprintf(`{"customerId": %q, "isVAT": true, "notes": %q, "items": [{"productId": %q, "productName": "Kraft Box", "quantity": 10, "unitPrice": 12.5}]}`,
+		customer.ID.Hex(), secret, product.ID.Hex())
+
+	rec := httptest.NewRecorder()
+	output := captureStdout(t, func() {
+		h.CreatePurchase(rec, httptest.NewRequest(http.MethodPost, "/api/purchases", strings.NewReader(body)))
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if strings.Contains(output, secret) {
+		t.Errorf("request body written to stdout: %s", output)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"goodpack-server/repository"
+)
+
+type QRHandler struct {
+	productRepo *repository.ProductRepository
+}
+
+func NewQRHandler(productRepo *repository.ProductRepository) *QRHandler {
+	return &QRHandler{
+		productRepo: productRepo,
+	}
+}
+
+// GetQRCodeBatch streams a ZIP of product QR code PNGs ({SKUID}.png) for stock-taking labels.
+// Products are selected by category and/or an inclusive SKU ID range (skuStart, skuEnd).
+func (h *QRHandler) GetQRCodeBatch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	category := query.Get("category")
+	skuStart := query.Get("skuStart")
+	skuEnd := query.Get("skuEnd")
+	if category == "" && skuStart == "" && skuEnd == "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "qr_batch_filter_required"))
+		return
+	}
+
+	products, err := h.productRepo.GetBySKURange(r.Context(), category, skuStart, skuEnd)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
+		return
+	}
+	if len(products) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "no_products_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=qr-batch-%s.zip", time.Now().Format("20060102150405")))
+
+	archive := zip.NewWriter(w)
+	for _, product := range products {
+		data := product.QRData
+		if data == "" {
+			data = product.SKUID
+		}
+
+		png, err := qrcode.Encode(data, qrcode.Medium, 256)
+		if err != nil {
+			fmt.Printf("Warning: Failed to generate QR code for %s: %v\n", product.SKUID, err)
+			continue
+		}
+
+		file, err := archive.Create(product.SKUID + ".png")
+		if err != nil {
+			fmt.Printf("Warning: Failed to add QR code for %s to archive: %v\n", product.SKUID, err)
+			return
+		}
+		if _, err := file.Write(png); err != nil {
+			fmt.Printf("Warning: Failed to write QR code for %s: %v\n", product.SKUID, err)
+			return
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		fmt.Printf("Warning: Failed to finish QR code archive: %v\n", err)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+func TestGetQRCodeBatch(t *testing.T) {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewQRHandler(productRepo)
+
+	var products []interface{}
+	for i := 1; i <= 7; i++ {
+		products = append(products, &models.Product{ID: primitive.NewObjectID(), SKUID: fmt.Sprintf("SH-%04d", i), Name: "Shirt", Category: "clothing"})
+	}
+	products = append(products, &models.Product{ID: primitive.NewObjectID(), SKUID: "SH-0004B", Name: "Shirt box", Category: "box"})
+	if _, err := db.Collection("products").InsertMany(context.Background(), products); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetQRCodeBatch(rec, httptest.NewRequest(http.MethodGet, "/api/products/qr-batch?category=clothing&skuStart=SH-0002&skuEnd=SH-0006", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment; filename=qr-batch-") {
+		t.Errorf("Content-Disposition = %q, want a qr-batch attachment", disposition)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("unzip: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+
+		content, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := png.Decode(content); err != nil {
+			t.Errorf("%s is not a PNG: %v", file.Name, err)
+		}
+		content.Close()
+	}
+	sort.Strings(names)
+	want := []string{"SH-0002.png", "SH-0003.png", "SH-0004.png", "SH-0005.png", "SH-0006.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("archive files = %v, want %v", names, want)
+	}
+}
+
+func TestGetQRCodeBatchRequiresAFilter(t *testing.T) {
+	h := NewQRHandler(nil)
+
+	rec := httptest.NewRecorder()
+	h.GetQRCodeBatch(rec, httptest.NewRequest(http.MethodGet, "/api/products/qr-batch", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -1,42 +1,54 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"goodpack-server/apierrors"
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/services"
 )
 
 type QuotationHandler struct {
 	quotationRepo *repository.QuotationRepository
 	customerRepo  *repository.CustomerRepository
 	productRepo   *repository.ProductRepository
+	saleService   *services.SaleService
 }
 
-func NewQuotationHandler(quotationRepo *repository.QuotationRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository) *QuotationHandler {
+func NewQuotationHandler(quotationRepo *repository.QuotationRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, saleService *services.SaleService) *QuotationHandler {
 	return &QuotationHandler{
 		quotationRepo: quotationRepo,
 		customerRepo:  customerRepo,
 		productRepo:   productRepo,
+		saleService:   saleService,
 	}
 }
 
 func (h *QuotationHandler) GetAllQuotations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	w.Header().Set("Content-Type", "application/json")
 
-	quotations, err := h.quotationRepo.GetAll()
+	sort, ok := parseSort(w, r, quotationSortFields)
+	if !ok {
+		return
+	}
+
+	quotations, err := h.quotationRepo.GetAll(ctx, sort)
 	if err != nil {
-		http.Error(w, "Failed to get quotations", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotations_fetch_failed"))
 		return
 	}
 
 	// Populate customer names
 	for _, quotation := range quotations {
-		if customer, err := h.customerRepo.GetByID(quotation.CustomerID); err == nil {
+		if customer, err := h.customerRepo.GetByID(ctx, quotation.CustomerID); err == nil {
 			quotation.CustomerName = customer.CompanyName
 			if customer.ContactName != "" {
 				quotation.ContactName = &customer.ContactName
@@ -60,24 +72,26 @@ func (h *QuotationHandler) GetAllQuotations(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *QuotationHandler) GetQuotation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	w.Header().Set("Content-Type", "application/json")
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid quotation ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
-	quotation, err := h.quotationRepo.GetByID(id)
+	quotation, err := h.quotationRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Quotation not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
 		return
 	}
 
 	// Populate customer information
-	if customer, err := h.customerRepo.GetByID(quotation.CustomerID); err == nil {
+	if customer, err := h.customerRepo.GetByID(ctx, quotation.CustomerID); err == nil {
 		quotation.CustomerName = customer.CompanyName
 		if customer.ContactName != "" {
 			quotation.ContactName = &customer.ContactName
@@ -100,23 +114,26 @@ func (h *QuotationHandler) GetQuotation(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *QuotationHandler) CreateQuotation(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	var quotationReq models.QuotationRequest
 	if err := json.NewDecoder(r.Body).Decode(&quotationReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &quotationReq) {
 		return
 	}
 
 	// Generate quotation code
 	lastCode, err := h.quotationRepo.GetLastQuotationCode(ctx)
 	if err != nil {
-		http.Error(w, "Failed to get last quotation code", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "last_quotation_code_failed"))
 		return
 	}
 	quotationCode, err := models.GenerateQuotationCode(lastCode)
 	if err != nil {
-		http.Error(w, "Failed to generate quotation code", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_code_generate_failed"))
 		return
 	}
 
@@ -125,22 +142,22 @@ func (h *QuotationHandler) CreateQuotation(w http.ResponseWriter, r *http.Reques
 	quotation.QuotationCode = quotationCode
 
 	// Validate customer exists
-	if _, err := h.customerRepo.GetByID(quotation.CustomerID); err != nil {
-		http.Error(w, "Customer not found", http.StatusBadRequest)
+	if _, err := h.customerRepo.GetByID(ctx, quotation.CustomerID); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "customer_not_found"))
 		return
 	}
 
 	// Validate products exist (but don't update stock or prices)
 	for _, item := range quotation.Items {
 		if _, err := h.productRepo.GetByID(ctx, item.ProductID); err != nil {
-			http.Error(w, fmt.Sprintf("Product not found: %s", item.ProductID), http.StatusBadRequest)
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", item.ProductID))
 			return
 		}
 	}
 
 	// Save quotation
-	if err := h.quotationRepo.Create(quotation); err != nil {
-		http.Error(w, "Failed to create quotation", http.StatusInternalServerError)
+	if err := h.quotationRepo.Create(ctx, quotation); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_create_failed"))
 		return
 	}
 
@@ -150,26 +167,29 @@ func (h *QuotationHandler) CreateQuotation(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *QuotationHandler) UpdateQuotation(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid quotation ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	var quotationReq models.QuotationRequest
 	if err := json.NewDecoder(r.Body).Decode(&quotationReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &quotationReq) {
 		return
 	}
 
 	// Get existing quotation
-	existingQuotation, err := h.quotationRepo.GetByID(id)
+	existingQuotation, err := h.quotationRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Quotation not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
 		return
 	}
 
@@ -177,22 +197,28 @@ func (h *QuotationHandler) UpdateQuotation(w http.ResponseWriter, r *http.Reques
 	existingQuotation.UpdateFromRequest(&quotationReq)
 
 	// Validate customer exists
-	if _, err := h.customerRepo.GetByID(existingQuotation.CustomerID); err != nil {
-		http.Error(w, "Customer not found", http.StatusBadRequest)
+	if _, err := h.customerRepo.GetByID(ctx, existingQuotation.CustomerID); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "customer_not_found"))
 		return
 	}
 
 	// Validate products exist (but don't update stock or prices)
 	for _, item := range existingQuotation.Items {
 		if _, err := h.productRepo.GetByID(ctx, item.ProductID); err != nil {
-			http.Error(w, fmt.Sprintf("Product not found: %s", item.ProductID), http.StatusBadRequest)
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", item.ProductID))
 			return
 		}
 	}
 
 	// Save updated quotation
-	if err := h.quotationRepo.Update(id, existingQuotation); err != nil {
-		http.Error(w, "Failed to update quotation", http.StatusInternalServerError)
+	if err := h.quotationRepo.Update(ctx, id, existingQuotation, changedBy(r)); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.quotationRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_update_failed"))
 		return
 	}
 
@@ -201,16 +227,18 @@ func (h *QuotationHandler) UpdateQuotation(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *QuotationHandler) DeleteQuotation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid quotation ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
-	if err := h.quotationRepo.Delete(id); err != nil {
-		http.Error(w, "Failed to delete quotation", http.StatusInternalServerError)
+	if err := h.quotationRepo.Delete(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_delete_failed"))
 		return
 	}
 
@@ -218,18 +246,20 @@ func (h *QuotationHandler) DeleteQuotation(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *QuotationHandler) CopyToSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 5 {
-		http.Error(w, "Invalid quotation ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	// Get quotation
-	quotation, err := h.quotationRepo.GetByID(id)
+	quotation, err := h.quotationRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Quotation not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
 		return
 	}
 
@@ -239,3 +269,139 @@ func (h *QuotationHandler) CopyToSale(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(saleRequest)
 }
+
+// AcceptQuotation marks a quotation as accepted and creates the sale from it
+func (h *QuotationHandler) AcceptQuotation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/quotations/{id}/accept)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	quotation, err := h.quotationRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
+		return
+	}
+
+	if quotation.SaleCode != nil && *quotation.SaleCode != "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "quotation_already_converted"))
+		return
+	}
+	if quotation.Status == models.QuotationStatusRejected || quotation.Status == models.QuotationStatusExpired {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "quotation_not_acceptable", quotation.Status))
+		return
+	}
+
+	sale, err := h.saleService.AcceptQuotation(ctx, quotation, changedBy(r))
+	if err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			// Another request converted, rejected, expired or edited the quotation since it was read
+			if current, err := h.quotationRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		if respondSaleItemsError(w, r, err) {
+			return
+		}
+		var creditExceeded *services.CreditLimitExceededError
+		if errors.As(err, &creditExceeded) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "credit_limit_exceeded", creditExceeded.OutstandingBalance, creditExceeded.SaleTotal, creditExceeded.CreditLimit))
+			return
+		}
+		fmt.Printf("Error creating sale from quotation %s: %v\n", quotation.QuotationCode, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_create_failed"))
+		return
+	}
+
+	response := map[string]interface{}{
+		"quotation": quotation,
+		"sale":      sale,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetQuotationVersions lists the version history of a quotation, oldest first
+func (h *QuotationHandler) GetQuotationVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/quotations/{id}/versions)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	quotation, err := h.quotationRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
+		return
+	}
+
+	versions := quotation.Versions
+	if versions == nil {
+		versions = []models.QuotationVersion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// GetQuotationVersion returns a quotation as it was at a version
+func (h *QuotationHandler) GetQuotationVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID and version from URL path (/api/quotations/{id}/versions/{versionNumber})
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 6 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_quotation_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-3]
+	versionNumber, err := strconv.Atoi(pathParts[len(pathParts)-1])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_version_number"))
+		return
+	}
+
+	quotation, err := h.quotationRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "quotation_not_found"))
+		return
+	}
+
+	for _, version := range quotation.Versions {
+		if version.VersionNumber != versionNumber {
+			continue
+		}
+
+		snapshot, err := version.Quotation()
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_version_read_failed"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+		return
+	}
+
+	RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "version_not_found"))
+}
+
+// changedBy returns the X-User-ID header, or nil when it is not sent
+func changedBy(r *http.Request) *string {
+	if userID := r.Header.Get("X-User-ID"); userID != "" {
+		return &userID
+	}
+	return nil
+}
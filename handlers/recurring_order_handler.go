@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type RecurringOrderHandler struct {
+	recurringOrderRepo *repository.RecurringOrderRepository
+	customerRepo       *repository.CustomerRepository
+	productRepo        *repository.ProductRepository
+}
+
+func NewRecurringOrderHandler(recurringOrderRepo *repository.RecurringOrderRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository) *RecurringOrderHandler {
+	return &RecurringOrderHandler{
+		recurringOrderRepo: recurringOrderRepo,
+		customerRepo:       customerRepo,
+		productRepo:        productRepo,
+	}
+}
+
+// GetRecurringOrders lists the recurring orders, soonest next run first, optionally only those of ?customerId=
+func (h *RecurringOrderHandler) GetRecurringOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.recurringOrderRepo.GetAll(r.Context(), r.URL.Query().Get("customerId"))
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "recurring_orders_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *RecurringOrderHandler) GetRecurringOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	order, err := h.recurringOrderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "recurring_order_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *RecurringOrderHandler) CreateRecurringOrder(w http.ResponseWriter, r *http.Request) {
+	var orderRequest models.RecurringOrderRequest
+	if !h.decodeRecurringOrderRequest(w, r, &orderRequest) {
+		return
+	}
+
+	order := orderRequest.ToRecurringOrder()
+	if err := h.recurringOrderRepo.Create(r.Context(), order); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "recurring_order_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *RecurringOrderHandler) UpdateRecurringOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var orderRequest models.RecurringOrderRequest
+	if !h.decodeRecurringOrderRequest(w, r, &orderRequest) {
+		return
+	}
+
+	order, err := h.recurringOrderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "recurring_order_not_found"))
+		return
+	}
+
+	order.UpdateFromRequest(&orderRequest)
+	if err := h.recurringOrderRepo.Update(r.Context(), id, order); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "recurring_order_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *RecurringOrderHandler) DeleteRecurringOrder(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.recurringOrderRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "recurring_order_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeRecurringOrderRequest decodes and validates a recurring order body, writing the error response when
+// it is invalid. The customer and every product must exist.
+func (h *RecurringOrderHandler) decodeRecurringOrderRequest(w http.ResponseWriter, r *http.Request, orderRequest *models.RecurringOrderRequest) bool {
+	ctx := r.Context()
+
+	if err := json.NewDecoder(r.Body).Decode(orderRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return false
+	}
+	if !validateRequest(w, r, orderRequest) {
+		return false
+	}
+	if orderRequest.NextRunAt.IsZero() {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "next_run_at_required"))
+		return false
+	}
+
+	if _, err := h.customerRepo.GetByID(ctx, orderRequest.CustomerID); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "customer_not_found"))
+		return false
+	}
+	for _, item := range orderRequest.Items {
+		if _, err := h.productRepo.GetByID(r.Context(), item.ProductID); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found"))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,690 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/xuri/excelize/v2"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+// abcCacheTTL is how long an ABC analysis is reused before it is recomputed
+const abcCacheTTL = time.Hour
+
+// maxForecastPeriods is the furthest ahead a sales forecast reaches
+const maxForecastPeriods = 24
+
+type ReportHandler struct {
+	productRepo         *repository.ProductRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+	reportRepo          *repository.ReportRepository
+	quotationRepo       *repository.QuotationRepository
+	valuationService    *services.ValuationService
+	forecastService     *services.ForecastService
+	pdfService          *services.PDFService
+
+	abcMu    sync.Mutex
+	abcCache map[string]*models.ABCAnalysis // period -> analysis
+}
+
+func NewReportHandler(productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository, reportRepo *repository.ReportRepository, quotationRepo *repository.QuotationRepository, valuationService *services.ValuationService, forecastService *services.ForecastService) *ReportHandler {
+	return &ReportHandler{
+		productRepo:         productRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+		reportRepo:          reportRepo,
+		quotationRepo:       quotationRepo,
+		valuationService:    valuationService,
+		forecastService:     forecastService,
+		pdfService:          services.NewPDFService(),
+		abcCache:            make(map[string]*models.ABCAnalysis),
+	}
+}
+
+var analysisPeriodPattern = regexp.MustCompile(`^(\d+)(days?|months?|years?)$`)
+
+// parseAnalysisPeriod turns a period such as "12months", "90days" or "1year" into its start date
+func parseAnalysisPeriod(period string, now time.Time) (time.Time, bool) {
+	match := analysisPeriodPattern.FindStringSubmatch(period)
+	if match == nil {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+
+	switch strings.TrimSuffix(match[2], "s") {
+	case "day":
+		return now.AddDate(0, 0, -n), true
+	case "month":
+		return now.AddDate(0, -n, 0), true
+	default:
+		return now.AddDate(-n, 0, 0), true
+	}
+}
+
+// GetABCAnalysis classifies products into A (top 80% of sales revenue), B (next 15%) and C (last 5%).
+// Results are cached per period for an hour.
+func (h *ReportHandler) GetABCAnalysis(w http.ResponseWriter, r *http.Request) {
+	period := strings.ToLower(r.URL.Query().Get("period"))
+	if period == "" {
+		period = "12months"
+	}
+
+	now := time.Now()
+	startDate, ok := parseAnalysisPeriod(period, now)
+	if !ok {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_period"))
+		return
+	}
+
+	h.abcMu.Lock()
+	analysis, cached := h.abcCache[period]
+	h.abcMu.Unlock()
+
+	if !cached || now.Sub(analysis.GeneratedAt) > abcCacheTTL {
+		revenues, err := h.stockAdjustmentRepo.GetSalesRevenueByProduct(r.Context(), startDate)
+		if err != nil {
+			fmt.Printf("Error computing ABC analysis: %v\n", err)
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "abc_analysis_failed"))
+			return
+		}
+
+		rows, summary, totalRevenue := models.ClassifyABC(revenues)
+		analysis = &models.ABCAnalysis{
+			Period:       period,
+			StartDate:    startDate,
+			GeneratedAt:  now,
+			TotalRevenue: totalRevenue,
+			Products:     rows,
+			Summary:      summary,
+		}
+
+		h.abcMu.Lock()
+		h.abcCache[period] = analysis
+		h.abcMu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// GetProductCostAnalysis returns a product's weighted average purchase cost, overall and by month,
+// next to the moving averages in its price info
+func (h *ReportHandler) GetProductCostAnalysis(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	product, err := h.productRepo.GetByID(r.Context(), id)
+	if err != nil {
+		product, err = h.productRepo.GetBySKUID(r.Context(), id)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+	}
+
+	records, err := h.stockAdjustmentRepo.GetPurchaseCostHistory(r.Context(), product.ID.Hex())
+	if err != nil {
+		fmt.Printf("Error computing cost analysis: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "cost_analysis_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewCostAnalysis(product, records))
+}
+
+// GetAlsoBoughtProducts lists the products most often sold on the same sales as the product, 5 unless limit
+// (at most 50) is given
+func (h *ReportHandler) GetAlsoBoughtProducts(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	product, err := h.productRepo.GetByID(r.Context(), id)
+	if err != nil {
+		product, err = h.productRepo.GetBySKUID(r.Context(), id)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+	}
+
+	products, err := h.reportRepo.GetAlsoBought(r.Context(), product.ID.Hex(), queryLimit(r, 5, relatedProductsMaxLimit))
+	if err != nil {
+		fmt.Printf("Error finding products bought together: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "also_bought_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+// GetDashboard returns the financial summary for startDate..endDate (default: the current month to date)
+func (h *ReportHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if startDate.IsZero() {
+		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	if endDate.IsZero() {
+		endDate = now
+	}
+
+	dashboard, err := h.reportRepo.GetDashboard(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing dashboard: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "dashboard_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dashboard)
+}
+
+// GetProductProfitability compares each product's sales revenue with its average purchase cost for the
+// period (defaults to the current month), most profitable first
+func (h *ReportHandler) GetProductProfitability(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if startDate.IsZero() {
+		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	if endDate.IsZero() {
+		endDate = now
+	}
+
+	report, err := h.reportRepo.GetProductProfitability(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing product profitability: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "profitability_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetQuotationFunnel counts the quotations dated startDate..endDate (default: all quotations) by status,
+// with the acceptance rate, days to acceptance, average value and the revenue of the sales made from them
+func (h *ReportHandler) GetQuotationFunnel(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.quotationRepo.GetFunnelStats(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing quotation funnel: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_funnel_failed"))
+		return
+	}
+
+	funnel := models.QuotationFunnel{QuotationFunnelStats: *stats}
+	if !startDate.IsZero() {
+		funnel.StartDate = &startDate
+	}
+	if !endDate.IsZero() {
+		funnel.EndDate = &endDate
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(funnel)
+}
+
+// GetQuotationFunnelByCustomer is GetQuotationFunnel per customer, customers with the most quotations first
+func (h *ReportHandler) GetQuotationFunnelByCustomer(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	funnels, err := h.quotationRepo.GetFunnelStatsByCustomer(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing quotation funnel by customer: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "quotation_funnel_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(funnels)
+}
+
+// GetRevenueTrend returns sales revenue, purchase cost and gross profit per day, week or month.
+// Without dates it covers the last 12 months (monthly), 12 weeks (weekly) or 30 days (daily).
+func (h *ReportHandler) GetRevenueTrend(w http.ResponseWriter, r *http.Request) {
+	granularity := strings.ToLower(r.URL.Query().Get("granularity"))
+	if granularity == "" {
+		granularity = models.TrendMonthly
+	}
+	if !models.IsTrendGranularity(granularity) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_granularity"))
+		return
+	}
+
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	if endDate.IsZero() {
+		endDate = time.Now()
+	}
+	if startDate.IsZero() {
+		switch granularity {
+		case models.TrendDaily:
+			startDate = endDate.AddDate(0, 0, -29)
+		case models.TrendWeekly:
+			startDate = endDate.AddDate(0, 0, -7*11)
+		default:
+			startDate = time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, endDate.Location()).AddDate(0, -11, 0)
+		}
+	}
+	if startDate.After(endDate) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_date_range"))
+		return
+	}
+
+	trend, err := h.reportRepo.GetRevenueTrend(r.Context(), granularity, startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing revenue trend: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "revenue_trend_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
+
+// GetPeriodComparison compares the sales of period1Start..period1End with those of period2Start..period2End
+// (all required, YYYY-MM-DD): revenue, units sold, order count, average order value and the top 5 products of
+// each, with the change from period 2 to period 1
+func (h *ReportHandler) GetPeriodComparison(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var dates [4]time.Time
+	for i, name := range []string{"period1Start", "period1End", "period2Start", "period2End"} {
+		parsed, err := time.Parse("2006-01-02", query.Get(name))
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_comparison_period"))
+			return
+		}
+		if i%2 == 1 {
+			// Set to end of day
+			parsed = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		}
+		dates[i] = parsed
+	}
+	if dates[0].After(dates[1]) || dates[2].After(dates[3]) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_comparison_range"))
+		return
+	}
+
+	comparison, err := h.reportRepo.GetPeriodComparison(r.Context(), dates[0], dates[1], dates[2], dates[3])
+	if err != nil {
+		fmt.Printf("Error comparing sales periods: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "period_comparison_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// GetCustomerAging buckets the receivables of unpaid sales per customer by days since the sale date:
+// 0-30, 31-60, 61-90 and over 90. customerId limits the report to one customer.
+func (h *ReportHandler) GetCustomerAging(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportRepo.GetCustomerAging(r.Context(), time.Now(), r.URL.Query().Get("customerId"))
+	if err != nil {
+		fmt.Printf("Error computing customer aging: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "customer_aging_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetDeliveryPerformance reports how the deliveries of the sales dated startDate..endDate (default: the current
+// month) went: counts by delivery status, the on-time rate and the average days late of late deliveries
+func (h *ReportHandler) GetDeliveryPerformance(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if startDate.IsZero() {
+		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	if endDate.IsZero() {
+		endDate = now
+	}
+	if startDate.After(endDate) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_date_range"))
+		return
+	}
+
+	performance, err := h.reportRepo.GetDeliveryPerformance(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing delivery performance: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "delivery_performance_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(performance)
+}
+
+// GetPurchaseVariance reports how the prices paid on the purchases dated startDate..endDate (default: the
+// current month) compared with the products' average purchase prices, per product and per purchase
+func (h *ReportHandler) GetPurchaseVariance(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if startDate.IsZero() {
+		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	}
+	if endDate.IsZero() {
+		endDate = now
+	}
+	if startDate.After(endDate) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_date_range"))
+		return
+	}
+
+	report, err := h.reportRepo.GetPurchaseVariance(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing purchase variance: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "purchase_variance_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetReturnAnalysis reports the sale returns of startDate..endDate (default: the last 12 months) per
+// product, highest return rate first, per reason and per month
+func (h *ReportHandler) GetReturnAnalysis(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+	if endDate.IsZero() {
+		endDate = time.Now()
+	}
+	if startDate.IsZero() {
+		startDate = time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, endDate.Location()).AddDate(0, -11, 0)
+	}
+	if startDate.After(endDate) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_date_range"))
+		return
+	}
+
+	analysis, err := h.reportRepo.GetReturnAnalysis(r.Context(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error computing return analysis: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "return_analysis_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
+
+// GetSalesForecast forecasts a product's sales quantity and revenue for the next periods with a 3- or
+// 6-period moving average of its past sales
+func (h *ReportHandler) GetSalesForecast(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	productID := query.Get("productId")
+	if productID == "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_id_required"))
+		return
+	}
+
+	granularity := strings.ToLower(query.Get("granularity"))
+	if granularity == "" {
+		granularity = models.TrendMonthly
+	}
+	if !models.IsTrendGranularity(granularity) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_granularity"))
+		return
+	}
+
+	periods := 3
+	if value := query.Get("periods"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxForecastPeriods {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_forecast_periods"))
+			return
+		}
+		periods = parsed
+	}
+
+	window := models.ForecastWindowShort
+	if value := query.Get("window"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || (parsed != models.ForecastWindowShort && parsed != models.ForecastWindowLong) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_forecast_window"))
+			return
+		}
+		window = parsed
+	}
+
+	if _, err := h.productRepo.GetByID(r.Context(), productID); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	forecast, err := h.forecastService.SalesForecast(r.Context(), productID, granularity, window, periods, time.Now())
+	if err != nil {
+		fmt.Printf("Error computing sales forecast: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sales_forecast_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
+
+// GetInventoryValuation values the current inventory using FIFO (default) or average cost
+func (h *ReportHandler) GetInventoryValuation(w http.ResponseWriter, r *http.Request) {
+	method := strings.ToLower(r.URL.Query().Get("method"))
+	if method == "" {
+		method = models.ValuationMethodFIFO
+	}
+	if method == "average-cost" {
+		method = models.ValuationMethodAverage
+	}
+	if method != models.ValuationMethodFIFO && method != models.ValuationMethodAverage {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_valuation_method"))
+		return
+	}
+
+	report, err := h.valuationService.InventoryValuation(r.Context(), method)
+	if err != nil {
+		fmt.Printf("Error computing inventory valuation: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "inventory_valuation_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportInventoryXLSX exports the current inventory snapshot as an Excel file
+func (h *ReportHandler) ExportInventoryXLSX(w http.ResponseWriter, r *http.Request) {
+	products, err := h.productRepo.GetAll(r.Context(), nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
+		return
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Inventory"
+	f.SetSheetName("Sheet1", sheet)
+
+	headers := []interface{}{
+		"SKU ID", "Product Code", "Name", "Category", "Color", "Size",
+		"VAT Purchased", "VAT Sold", "VAT Remaining",
+		"Non-VAT Purchased", "Non-VAT Sold", "Non-VAT Remaining",
+		"Actual Stock", "Latest Purchase Price", "Latest Sale Price", "Total Value",
+	}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "spreadsheet_build_failed"))
+		return
+	}
+
+	if headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err == nil {
+		f.SetRowStyle(sheet, 1, 1, headerStyle)
+	}
+
+	for i, product := range products {
+		purchasePrice := product.GetDisplayPrice()
+		salePrice := product.Price.SaleVAT.Latest
+		if salePrice == 0 {
+			salePrice = product.Price.SaleNonVAT.Latest
+		}
+
+		row := []interface{}{
+			product.SKUID,
+			product.Code,
+			product.Name,
+			product.Category,
+			product.Color,
+			product.Size,
+			product.Stock.VAT.Purchased,
+			product.Stock.VAT.Sold,
+			product.Stock.VAT.Remaining,
+			product.Stock.NonVAT.Purchased,
+			product.Stock.NonVAT.Sold,
+			product.Stock.NonVAT.Remaining,
+			product.Stock.ActualStock,
+			purchasePrice,
+			salePrice,
+			float64(product.Stock.ActualStock) * purchasePrice,
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "spreadsheet_build_failed"))
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("inventory-%s.xlsx", time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if err := f.Write(w); err != nil {
+		fmt.Printf("Warning: Failed to write inventory spreadsheet: %v\n", err)
+	}
+}
+
+// catalogProducts returns the products for a catalog export, limited to the category query parameter if set
+func (h *ReportHandler) catalogProducts(r *http.Request) ([]*models.Product, error) {
+	if category := r.URL.Query().Get("category"); category != "" {
+		return h.productRepo.GetByCategory(r.Context(), category, nil, nil, nil)
+	}
+	return h.productRepo.GetAll(r.Context(), nil)
+}
+
+// ExportCatalogPDF exports a printable product catalog grouped by category
+func (h *ReportHandler) ExportCatalogPDF(w http.ResponseWriter, r *http.Request) {
+	products, err := h.catalogProducts(r)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
+		return
+	}
+
+	pdf, err := h.pdfService.GenerateCatalogPDF(products)
+	if err != nil {
+		fmt.Printf("Error generating catalog PDF: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "pdf_generate_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=catalog-%s.pdf", time.Now().Format("20060102")))
+	w.Write(pdf)
+}
+
+// ExportCatalogXLSX exports the product catalog as an Excel file, grouped by category
+func (h *ReportHandler) ExportCatalogXLSX(w http.ResponseWriter, r *http.Request) {
+	products, err := h.catalogProducts(r)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_get_failed"))
+		return
+	}
+	services.SortCatalogProducts(products)
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Catalog"
+	f.SetSheetName("Sheet1", sheet)
+
+	headers := []interface{}{
+		"Category", "SKU ID", "Name", "Description", "Color", "Size", "UOM",
+		"Sale Price (VAT)", "Sale Price (Non-VAT)", "Stock", "Image URL",
+	}
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "spreadsheet_build_failed"))
+		return
+	}
+
+	if headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err == nil {
+		f.SetRowStyle(sheet, 1, 1, headerStyle)
+	}
+
+	for i, product := range products {
+		imageURL := ""
+		if url := product.PrimaryImageURL(); url != nil {
+			imageURL = *url
+		}
+
+		row := []interface{}{
+			product.Category,
+			product.SKUID,
+			product.Name,
+			product.Description,
+			product.Color,
+			product.Size,
+			product.UOM,
+			product.Price.SaleVAT.Latest,
+			product.Price.SaleNonVAT.Latest,
+			product.GetTotalStock(),
+			imageURL,
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "spreadsheet_build_failed"))
+			return
+		}
+	}
+
+	filename := fmt.Sprintf("catalog-%s.xlsx", time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if err := f.Write(w); err != nil {
+		fmt.Printf("Warning: Failed to write catalog spreadsheet: %v\n", err)
+	}
+}
@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+func TestExportInventoryXLSX(t *testing.T) {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ReportHandler{productRepo: productRepo}
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Code: "KB-01", Name: "Kraft Box", Category: "Box", Color: "Brown", Size: "A4"}
+	product.Stock.VAT = models.StockInfo{Purchased: 10, Sold: 4, Remaining: 6}
+	product.Stock.NonVAT = models.StockInfo{Purchased: 5, Sold: 1, Remaining: 4}
+	product.Stock.ActualStock = 10
+	product.Price.PurchaseVAT.Latest = 12.5
+	product.Price.SaleVAT.Latest = 20
+	if _, err := db.Collection("products").InsertOne(context.Background(), product); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ExportInventoryXLSX(rec, httptest.NewRequest(http.MethodGet, "/api/reports/inventory/xlsx", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	f, err := excelize.OpenReader(rec.Body)
+	if err != nil {
+		t.Fatalf("decode spreadsheet: %v", err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("Inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want header and 1 product", len(rows))
+	}
+
+	wantHeader := []string{
+		"SKU ID", "Product Code", "Name", "Category", "Color", "Size",
+		"VAT Purchased", "VAT Sold", "VAT Remaining",
+		"Non-VAT Purchased", "Non-VAT Sold", "Non-VAT Remaining",
+		"Actual Stock", "Latest Purchase Price", "Latest Sale Price", "Total Value",
+	}
+	if !reflect.DeepEqual(rows[0], wantHeader) {
+		t.Errorf("header = %q, want %q", rows[0], wantHeader)
+	}
+	wantRow := []string{"BOX-0001", "KB-01", "Kraft Box", "Box", "Brown", "A4", "10", "4", "6", "5", "1", "4", "10", "12.5", "20", "125"}
+	if !reflect.DeepEqual(rows[1], wantRow) {
+		t.Errorf("product row = %q, want %q", rows[1], wantRow)
+	}
+}
+
+// reportRepoHandler returns a report handler whose aggregations run on db
+func reportRepoHandler(db *mongo.Database) *ReportHandler {
+	return &ReportHandler{reportRepo: repository.NewReportRepository(
+		db.Collection("sales"), db.Collection("purchases"), db.Collection("customers"),
+		db.Collection("products"), db.Collection("sale_returns"),
+	)}
+}
+
+func TestGetDashboard(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := reportRepoHandler(db)
+
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	item := func(productID string, quantity, totalPrice float64) bson.M {
+		return bson.M{"productId": productID, "productName": "Product " + productID, "quantity": quantity, "totalPrice": totalPrice}
+	}
+	insert := func(collection string, docs ...interface{}) {
+		if _, err := db.Collection(collection).InsertMany(ctx, docs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	insert("customers",
+		bson.M{"customerCode": "C1", "createdAt": day(time.June, 5)},
+		bson.M{"customerCode": "C2", "createdAt": day(time.June, 20)},
+		bson.M{"customerCode": "C3", "createdAt": day(time.May, 1)}, // before the period
+	)
+	insert("sales",
+		bson.M{"saleDate": day(time.June, 10), "isVAT": true, "vatRate": 0.07, "payment": bson.M{"isPaid": true},
+			"items": bson.A{item("A", 10, 1000), item("B", 2, 400)}},
+		bson.M{"saleDate": day(time.June, 15), "shippingCost": 100, "payments": bson.A{bson.M{"amount": 300}},
+			"items": bson.A{item("B", 5, 1000), item("C", 1, 50)}},
+		bson.M{"saleDate": day(time.June, 25), "items": bson.A{item("D", 20, 200)}},
+		// Before the period: left out of the totals but still owed
+		bson.M{"saleDate": day(time.May, 20), "items": bson.A{item("A", 100, 5000)}},
+		bson.M{"saleDate": day(time.June, 12), "isDeleted": true, "items": bson.A{item("A", 50, 999)}},
+	)
+	insert("purchases",
+		bson.M{"purchaseDate": day(time.June, 2), "totalAmount": 1000},
+		bson.M{"purchaseDate": day(time.June, 18), "totalAmount": 590},
+		bson.M{"purchaseDate": day(time.July, 1), "totalAmount": 9999}, // after the period
+	)
+
+	rec := httptest.NewRecorder()
+	h.GetDashboard(rec, httptest.NewRequest(http.MethodGet, "/api/reports/dashboard?startDate=2025-06-01&endDate=2025-06-30", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got models.Dashboard
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"salesRevenue", got.SalesRevenue, 2650},
+		{"purchaseCost", got.PurchaseCost, 1590},
+		{"grossProfit", got.GrossProfit, 1060},
+		{"grossMarginPct", got.GrossMarginPct, 40},
+		{"newCustomers", float64(got.NewCustomers), 2},
+		{"salesCount", float64(got.SalesCount), 3},
+		{"purchaseCount", float64(got.PurchaseCount), 2},
+		{"outstandingReceivables", got.OutstandingReceivables, 6050}, // 1050 + 100 - 300, 200 and 5000
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	wantByQuantity := []models.TopProduct{
+		{ProductID: "D", ProductName: "Product D", Quantity: 20, Revenue: 200},
+		{ProductID: "A", ProductName: "Product A", Quantity: 10, Revenue: 1000},
+		{ProductID: "B", ProductName: "Product B", Quantity: 7, Revenue: 1400},
+	}
+	if !reflect.DeepEqual(got.TopProductsByQuantity, wantByQuantity) {
+		t.Errorf("topProductsByQuantity = %+v, want %+v", got.TopProductsByQuantity, wantByQuantity)
+	}
+	wantByRevenue := []models.TopProduct{wantByQuantity[2], wantByQuantity[1], wantByQuantity[0]}
+	if !reflect.DeepEqual(got.TopProductsByRevenue, wantByRevenue) {
+		t.Errorf("topProductsByRevenue = %+v, want %+v", got.TopProductsByRevenue, wantByRevenue)
+	}
+}
+
+func TestGetDashboardRejectsInvalidDates(t *testing.T) {
+	h := &ReportHandler{}
+	for _, query := range []string{"startDate=01-06-2025", "endDate=2025-13-01"} {
+		rec := httptest.NewRecorder()
+		h.GetDashboard(rec, httptest.NewRequest(http.MethodGet, "/api/reports/dashboard?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetProductCostAnalysis(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &ReportHandler{
+		productRepo:         productRepo,
+		stockAdjustmentRepo: repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")),
+	}
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box"}
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	productID := product.ID.Hex()
+
+	// purchase inserts a purchase of quantity units for totalPrice and the stock adjustments it made
+	purchase := func(date time.Time, quantity int, totalPrice float64, stockType models.StockType, deleted bool, adjustments ...int) {
+		id := primitive.NewObjectID()
+		doc := bson.M{
+			"_id": id, "purchaseCode": "PUR-" + id.Hex()[18:], "purchaseDate": date, "isDeleted": deleted,
+			"items": bson.A{bson.M{"productId": productID, "quantity": quantity, "totalPrice": totalPrice}},
+		}
+		if _, err := db.Collection("purchases").InsertOne(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+		sourceID := id.Hex()
+		for _, change := range adjustments {
+			adjustment := models.StockAdjustment{
+				ProductID: productID, AdjustmentType: models.AdjustmentTypeAdd, StockType: stockType, Quantity: change,
+				SourceType: models.SourceTypePurchase, SourceID: &sourceID,
+			}
+			if change < 0 {
+				adjustment.AdjustmentType, adjustment.Quantity = models.AdjustmentTypeReduce, -change
+			}
+			if _, err := db.Collection("stock_adjustments").InsertOne(ctx, adjustment); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	purchase(date(time.January, 5), 10, 1000, models.StockTypeVAT, false, 10)
+	purchase(date(time.January, 20), 30, 2400, models.StockTypeNonVAT, false, 40, -10) // edited down from 40
+	purchase(date(time.February, 10), 40, 3800, models.StockTypeVAT, false, 40)
+	purchase(date(time.February, 15), 50, 100, models.StockTypeVAT, true, 50)
+	saleID := primitive.NewObjectID().Hex()
+	if _, err := db.Collection("stock_adjustments").InsertOne(ctx, models.StockAdjustment{
+		ProductID: productID, AdjustmentType: models.AdjustmentTypeReduce, StockType: models.StockTypeVAT, Quantity: 5,
+		SourceType: models.SourceTypeSale, SourceID: &saleID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/products/"+productID+"/cost-analysis", nil), map[string]string{"id": productID})
+	rec := httptest.NewRecorder()
+	h.GetProductCostAnalysis(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got models.CostAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	// (10×100 + 30×80 + 40×95) / 80; the deleted purchase and the sale are left out
+	if got.TotalQuantity != 80 || got.WeightedAverageCost != 90 {
+		t.Errorf("total quantity %d, weighted average %v, want 80 and 90", got.TotalQuantity, got.WeightedAverageCost)
+	}
+	if got.WeightedAverageVAT != 96 || got.WeightedAverageNonVAT != 80 {
+		t.Errorf("VAT %v, Non-VAT %v, want 96 and 80", got.WeightedAverageVAT, got.WeightedAverageNonVAT)
+	}
+	if len(got.Purchases) != 3 || len(got.ByMonth) != 2 {
+		t.Errorf("got %d purchases over %d months, want 3 over 2", len(got.Purchases), len(got.ByMonth))
+	}
+}
+
+// catalogHandler returns a report handler over a database holding two boxes and a wrap
+func catalogHandler(t *testing.T) *ReportHandler {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, product := range []*models.Product{
+		{ID: primitive.NewObjectID(), SKUID: "BOX-0002", Name: "Mailer Box", Category: "Box"},
+		{ID: primitive.NewObjectID(), SKUID: "WRP-0001", Name: "Bubble Wrap", Category: "Wrap"},
+		{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"},
+	} {
+		if _, err := db.Collection("products").InsertOne(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &ReportHandler{productRepo: productRepo, pdfService: services.NewPDFService()}
+}
+
+func TestExportCatalogPDF(t *testing.T) {
+	h := catalogHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.ExportCatalogPDF(rec, httptest.NewRequest(http.MethodGet, "/api/reports/catalog.pdf?category=Box", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if rec.Body.Len() == 0 || !bytes.HasPrefix(rec.Body.Bytes(), []byte("%PDF")) {
+		t.Errorf("body starts %q, want %%PDF", rec.Body.Bytes()[:min(rec.Body.Len(), 16)])
+	}
+}
+
+func TestExportCatalogXLSX(t *testing.T) {
+	h := catalogHandler(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"BOX-0001", "BOX-0002", "WRP-0001"}},
+		{"?category=Box", []string{"BOX-0001", "BOX-0002"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ExportCatalogXLSX(rec, httptest.NewRequest(http.MethodGet, "/api/reports/catalog.xlsx"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want %d", tt.query, rec.Code, http.StatusOK)
+		}
+
+		f, err := excelize.OpenReader(rec.Body)
+		if err != nil {
+			t.Fatalf("decode spreadsheet: %v", err)
+		}
+		rows, err := f.GetRows("Catalog")
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var skuIDs []string
+		for _, row := range rows[1:] {
+			skuIDs = append(skuIDs, row[1])
+		}
+		if !reflect.DeepEqual(skuIDs, tt.want) {
+			t.Errorf("%q: SKU IDs = %q, want %q", tt.query, skuIDs, tt.want)
+		}
+	}
+}
+
+func TestGetRevenueTrend(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := reportRepoHandler(db)
+
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	sale := func(date time.Time, totals ...float64) bson.M {
+		items := bson.A{}
+		for _, total := range totals {
+			items = append(items, bson.M{"productId": "p1", "quantity": 1, "totalPrice": total})
+		}
+		return bson.M{"saleDate": date, "items": items}
+	}
+	if _, err := db.Collection("sales").InsertMany(ctx, []interface{}{
+		sale(day(time.January, 5), 1000, 500),
+		sale(day(time.January, 31), 250),
+		sale(day(time.March, 1), 900),
+		sale(day(time.April, 1), 7777), // after the range
+		bson.M{"saleDate": day(time.March, 2), "isDeleted": true, "items": bson.A{bson.M{"totalPrice": 5000}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection("purchases").InsertMany(ctx, []interface{}{
+		bson.M{"purchaseDate": day(time.January, 10), "totalAmount": 1000},
+		bson.M{"purchaseDate": day(time.March, 20), "totalAmount": 600},
+		bson.M{"purchaseDate": day(time.March, 25), "totalAmount": 600},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetRevenueTrend(rec, httptest.NewRequest(http.MethodGet, "/api/reports/revenue-trend?granularity=monthly&startDate=2024-01-01&endDate=2024-03-31", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var got []models.RevenueTrendPoint
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.RevenueTrendPoint{
+		{Period: "2024-01", SalesRevenue: 1750, PurchaseCost: 1000, GrossProfit: 750, SalesCount: 2, PurchaseCount: 1},
+		{Period: "2024-02"},
+		{Period: "2024-03", SalesRevenue: 900, PurchaseCost: 1200, GrossProfit: -300, SalesCount: 1, PurchaseCount: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trend = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetRevenueTrendRejectsInvalidQueries(t *testing.T) {
+	h := &ReportHandler{}
+	for _, query := range []string{"granularity=yearly", "startDate=2024-03-01&endDate=2024-01-31", "startDate=March"} {
+		rec := httptest.NewRecorder()
+		h.GetRevenueTrend(rec, httptest.NewRequest(http.MethodGet, "/api/reports/revenue-trend?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetProductProfitability(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := reportRepoHandler(db)
+
+	box := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box"}
+	bottle := &models.Product{ID: primitive.NewObjectID(), SKUID: "BT-0001", Name: "Bottle"}
+	for _, product := range []*models.Product{box, bottle} {
+		if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	boxID, bottleID, capID := box.ID.Hex(), bottle.ID.Hex(), primitive.NewObjectID().Hex() // the cap has been deleted
+
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	line := func(productID, name string, quantity, totalPrice float64) bson.M {
+		return bson.M{"productId": productID, "productName": name, "quantity": quantity, "totalPrice": totalPrice}
+	}
+	if _, err := db.Collection("sales").InsertMany(ctx, []interface{}{
+		bson.M{"saleDate": day(time.March, 3), "items": bson.A{line(boxID, "Box", 6, 600), line(bottleID, "Bottle", 4, 200)}},
+		bson.M{"saleDate": day(time.March, 20), "items": bson.A{line(boxID, "Box", 4, 400), line(capID, "Cap", 5, 250)}},
+		bson.M{"saleDate": day(time.April, 2), "items": bson.A{line(boxID, "Box", 100, 100)}}, // after the period
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Purchases up to the end of the period count, even those made before it
+	if _, err := db.Collection("purchases").InsertMany(ctx, []interface{}{
+		bson.M{"purchaseDate": day(time.February, 1), "items": bson.A{line(boxID, "Box", 20, 1200), line(capID, "Cap", 10, 700)}},
+		bson.M{"purchaseDate": day(time.April, 1), "items": bson.A{line(boxID, "Box", 100, 100)}},
+		bson.M{"purchaseDate": day(time.March, 1), "isDeleted": true, "items": bson.A{line(bottleID, "Bottle", 10, 10)}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetProductProfitability(rec, httptest.NewRequest(http.MethodGet, "/api/reports/product-profitability?startDate=2025-03-01&endDate=2025-03-31", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var report models.ProfitabilityReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.ProductProfitability{
+		{ProductID: boxID, ProductName: "Kraft Box", SKUID: "BOX-0001", UnitsSold: 10, SalesRevenue: 1000, PurchasedQuantity: 20,
+			AverageSalePrice: 100, AveragePurchaseCost: 60, GrossMarginPerUnit: 40, GrossProfit: 400, MarginPct: 40},
+		{ProductID: bottleID, ProductName: "Bottle", SKUID: "BT-0001", UnitsSold: 4, SalesRevenue: 200,
+			AverageSalePrice: 50, GrossMarginPerUnit: 50, GrossProfit: 200, MarginPct: 100},
+		{ProductID: capID, ProductName: "Cap", UnitsSold: 5, SalesRevenue: 250, PurchasedQuantity: 10,
+			AverageSalePrice: 50, AveragePurchaseCost: 70, GrossMarginPerUnit: -20, GrossProfit: -100, MarginPct: -40},
+	}
+	if !reflect.DeepEqual(report.Products, want) {
+		t.Errorf("products = %+v, want %+v", report.Products, want)
+	}
+}
+
+func TestGetReturnAnalysis(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := reportRepoHandler(db)
+
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	line := func(productID string, quantity, totalPrice float64) bson.M {
+		return bson.M{"productId": productID, "productName": "Product " + productID, "quantity": quantity, "totalPrice": totalPrice}
+	}
+	saleIDs := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	if _, err := db.Collection("sales").InsertMany(ctx, []interface{}{
+		bson.M{"_id": saleIDs[0], "saleDate": day(time.January, 5), "items": bson.A{line("box", 10, 1000), line("wrap", 20, 400)}},
+		bson.M{"_id": saleIDs[1], "saleDate": day(time.January, 20), "items": bson.A{line("box", 10, 1000)}},
+		bson.M{"_id": saleIDs[2], "saleDate": day(time.February, 3), "items": bson.A{line("cup", 50, 500)}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	returned := func(sale int, date time.Time, items ...models.ReturnItem) *models.SaleReturn {
+		return &models.SaleReturn{ID: primitive.NewObjectID(), OriginalSaleID: saleIDs[sale].Hex(), ReturnDate: date, Items: items}
+	}
+	item := func(productID string, quantity int, totalPrice float64, reason string) models.ReturnItem {
+		return models.ReturnItem{ProductID: productID, ProductName: "Product " + productID, Quantity: quantity, TotalPrice: totalPrice, Reason: reason}
+	}
+	for _, saleReturn := range []*models.SaleReturn{
+		returned(0, day(time.January, 10), item("box", 2, 200, "damaged"), item("wrap", 6, 120, "wrong size")),
+		returned(0, day(time.February, 1), item("box", 1, 100, " damaged ")),
+		returned(1, day(time.February, 10), item("box", 2, 200, "damaged")),
+		returned(2, day(time.February, 12), item("cup", 5, 50, "wrong size")),
+		returned(2, day(time.March, 5), item("cup", 40, 400, "damaged")), // after the range
+	} {
+		if _, err := db.Collection("sale_returns").InsertOne(ctx, saleReturn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetReturnAnalysis(rec, httptest.NewRequest(http.MethodGet, "/api/reports/return-analysis?startDate=2024-01-01&endDate=2024-02-29", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var analysis models.ReturnAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&analysis); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sale 1 is valued once although two returns were made against it
+	if analysis.Returns != 4 || analysis.ReturnValue != 670 || analysis.OriginalSaleValue != 2900 {
+		t.Errorf("totals = %+v, want 4 returns worth 670 of sales worth 2900", analysis.ReturnValueTotals)
+	}
+
+	wantProducts := []struct {
+		productID string
+		returned  int
+		sold      float64
+		rate      float64
+	}{
+		{"wrap", 6, 20, 30},
+		{"box", 5, 20, 25},
+		{"cup", 5, 50, 10},
+	}
+	if len(analysis.Products) != len(wantProducts) {
+		t.Fatalf("products = %+v, want %d", analysis.Products, len(wantProducts))
+	}
+	for i, want := range wantProducts {
+		got := analysis.Products[i]
+		if got.ProductID != want.productID || got.ReturnedQuantity != want.returned || got.QuantitySold != want.sold ||
+			got.ReturnRatePct == nil || *got.ReturnRatePct != want.rate {
+			t.Errorf("product %d = %+v, want %s with %d of %v returned (%v%%)", i, got, want.productID, want.returned, want.sold, want.rate)
+		}
+	}
+
+	wantReasons := []models.ReturnReasonCount{
+		{Reason: "damaged", Count: 3, Quantity: 5, SharePct: 60},
+		{Reason: "wrong size", Count: 2, Quantity: 11, SharePct: 40},
+	}
+	if !reflect.DeepEqual(analysis.Reasons, wantReasons) {
+		t.Errorf("reasons = %+v, want %+v", analysis.Reasons, wantReasons)
+	}
+
+	wantTrend := []models.ReturnTrendPoint{
+		{Period: "2024-01", Returns: 1, Quantity: 8, Value: 320},
+		{Period: "2024-02", Returns: 3, Quantity: 8, Value: 350},
+	}
+	if !reflect.DeepEqual(analysis.MonthlyTrend, wantTrend) {
+		t.Errorf("monthly trend = %+v, want %+v", analysis.MonthlyTrend, wantTrend)
+	}
+}
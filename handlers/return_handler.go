@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+type ReturnHandler struct {
+	returnRepo          *repository.SaleReturnRepository
+	saleRepo            *repository.SaleRepository
+	productRepo         *repository.ProductRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+	serialNumberRepo    *repository.SerialNumberRepository
+}
+
+func NewReturnHandler(returnRepo *repository.SaleReturnRepository, saleRepo *repository.SaleRepository, productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository, serialNumberRepo *repository.SerialNumberRepository) *ReturnHandler {
+	return &ReturnHandler{
+		returnRepo:          returnRepo,
+		saleRepo:            saleRepo,
+		productRepo:         productRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+		serialNumberRepo:    serialNumberRepo,
+	}
+}
+
+func (h *ReturnHandler) GetReturns(w http.ResponseWriter, r *http.Request) {
+	returns, err := h.returnRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "returns_fetch_failed"))
+		return
+	}
+	if returns == nil {
+		returns = []*models.SaleReturn{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	saleReturn, err := h.returnRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "return_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saleReturn)
+}
+
+// GetSaleReturns lists all returns made against a sale
+func (h *ReturnHandler) GetSaleReturns(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	returns, err := h.returnRepo.GetBySaleID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "returns_fetch_failed"))
+		return
+	}
+	if returns == nil {
+		returns = []*models.SaleReturn{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+// CreateSaleReturn records items returned from a sale and puts them back into stock
+func (h *ReturnHandler) CreateSaleReturn(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	var returnReq models.SaleReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&returnReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if len(returnReq.Items) == 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "items_required"))
+		return
+	}
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+	// A draft has not cut any stock and a cancelled sale has put it back already
+	if !sale.HoldsStock() {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "sale_not_returnable"))
+		return
+	}
+
+	// Quantities already returned against this sale
+	previousReturns, err := h.returnRepo.GetBySaleID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "previous_returns_fetch_failed"))
+		return
+	}
+
+	if invalid := validateReturnQuantities(ctx, sale, previousReturns, returnReq.Items); invalid != nil {
+		RespondWithError(w, invalid)
+		return
+	}
+	invalid, err := h.validateReturnSerialNumbers(ctx, sale, returnReq.Items)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "serial_numbers_fetch_failed"))
+		return
+	}
+	if invalid != nil {
+		RespondWithError(w, invalid)
+		return
+	}
+
+	saleReturn := returnReq.ToSaleReturn(sale)
+
+	// Save the return and put its items back into stock in one transaction, so a return is never saved
+	// without its stock or the other way round
+	err = h.saleRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := h.returnRepo.Create(txCtx, saleReturn); err != nil {
+			return err
+		}
+		return h.restoreStock(txCtx, sale, saleReturn)
+	})
+	var appErr *apierrors.AppError
+	if errors.As(err, &appErr) {
+		RespondWithError(w, appErr)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error creating return for sale %s: %v\n", id, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "return_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(saleReturn)
+}
+
+// restoreStock puts the items of a return back into stock using stock management logic, recording the stock
+// history and marking returned serial numbers
+func (h *ReturnHandler) restoreStock(ctx context.Context, sale *models.Sale, saleReturn *models.SaleReturn) error {
+	saleID := sale.ID.Hex()
+	stockType := services.StockTypeForVAT(sale.IsVAT)
+	sourceID := saleReturn.ID.Hex()
+	sourceCode := saleReturn.ReturnCode
+	for _, item := range saleReturn.Items {
+		product, err := h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			services.ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.Quantity)
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			return localisedError(ctx, http.StatusBadRequest, "product_not_found_id", item.ProductID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore stock for product %s: %w", item.ProductID, err)
+		}
+		services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+
+		var notes *string
+		if item.Reason != "" {
+			reason := item.Reason
+			notes = &reason
+		}
+		if err := services.RecordStockChange(ctx, h.stockAdjustmentRepo, product, models.SourceTypeReturn, &sourceID, &sourceCode, models.AdjustmentTypeAdd, stockType, item.Quantity, notes); err != nil {
+			fmt.Printf("Warning: Failed to record stock history: %v\n", err)
+		}
+
+		if len(item.SerialNumbers) > 0 {
+			if err := h.serialNumberRepo.MarkReturned(ctx, item.ProductID, item.SerialNumbers, saleID); err != nil {
+				return fmt.Errorf("failed to mark serial numbers of product %s returned: %w", item.ProductID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateReturnQuantities checks that every returned product was on the sale and that the
+// total returned (including earlier returns) does not exceed the quantity sold.
+// It returns the 400 error to respond with, or nil when the request is valid.
+func validateReturnQuantities(ctx context.Context, sale *models.Sale, previousReturns []*models.SaleReturn, items []models.ReturnItemRequest) *apierrors.AppError {
+	sold := make(map[string]int)
+	for _, item := range sale.Items {
+		sold[item.ProductID] += item.StockQuantity()
+	}
+
+	returned := models.ReturnedQuantities(previousReturns)
+
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return localisedError(ctx, http.StatusBadRequest, "invalid_return_quantity", item.ProductID)
+		}
+		soldQty, ok := sold[item.ProductID]
+		if !ok {
+			return localisedError(ctx, http.StatusBadRequest, "product_not_in_sale", item.ProductID)
+		}
+		returned[item.ProductID] += item.Quantity
+		if returned[item.ProductID] > soldQty {
+			return localisedError(ctx, http.StatusBadRequest, "return_quantity_exceeds_sold", item.ProductID, soldQty, returned[item.ProductID])
+		}
+	}
+
+	return nil
+}
+
+// validateReturnSerialNumbers checks that each returned serialised product lists one serial number per
+// unit, each sold by this sale and not yet returned. It returns the 400 error to respond with, or nil when the
+// serial numbers are valid.
+func (h *ReturnHandler) validateReturnSerialNumbers(ctx context.Context, sale *models.Sale, items []models.ReturnItemRequest) (*apierrors.AppError, error) {
+	saleID := sale.ID.Hex()
+	for _, item := range items {
+		product, err := h.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil || !product.IsSerialised {
+			if len(item.SerialNumbers) > 0 {
+				return localisedError(ctx, http.StatusBadRequest, "product_not_serialised", item.ProductID), nil
+			}
+			continue
+		}
+		if reason := models.CheckSerialNumbers(item.SerialNumbers, item.Quantity); reason != "" {
+			return localisedError(ctx, http.StatusBadRequest, "invalid_serial_numbers", item.ProductID, reason), nil
+		}
+
+		units, err := h.serialNumberRepo.GetBySerials(ctx, item.ProductID, item.SerialNumbers)
+		if err != nil {
+			return nil, err
+		}
+		for _, serial := range item.SerialNumbers {
+			unit, ok := units[serial]
+			if !ok || unit.Status != models.SerialNumberStatusSold || unit.SaleID == nil || *unit.SaleID != saleID {
+				return localisedError(ctx, http.StatusBadRequest, "serial_number_not_returnable", serial, item.ProductID), nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+func TestValidateReturnQuantities(t *testing.T) {
+	sale := &models.Sale{Items: []models.SaleItem{
+		{ProductID: "p1", Quantity: 3},
+		{ProductID: "p1", Quantity: 2},
+		{ProductID: "p2", Quantity: 1},
+	}}
+	previous := []*models.SaleReturn{{Items: []models.ReturnItem{{ProductID: "p1", Quantity: 4}}}}
+
+	tests := []struct {
+		name  string
+		items []models.ReturnItemRequest
+		want  string // part of the error message, "" when valid
+	}{
+		{"within what is left", []models.ReturnItemRequest{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 1}}, ""},
+		{"more than is left", []models.ReturnItemRequest{{ProductID: "p1", Quantity: 2}}, "exceeds sold quantity"},
+		{"split over lines", []models.ReturnItemRequest{{ProductID: "p2", Quantity: 1}, {ProductID: "p2", Quantity: 1}}, "exceeds sold quantity"},
+		{"not on the sale", []models.ReturnItemRequest{{ProductID: "p3", Quantity: 1}}, "not part of this sale"},
+		{"zero quantity", []models.ReturnItemRequest{{ProductID: "p1", Quantity: 0}}, "Invalid return quantity"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := validateReturnQuantities(context.Background(), sale, previous, tt.items); err != nil {
+			got = err.Message
+		}
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// returnTest is a return handler on a test database with a product of which a sale cut 5 units
+type returnTest struct {
+	t           *testing.T
+	h           *ReturnHandler
+	saleService *services.SaleService
+	product     *models.Product
+	sale        *models.Sale
+}
+
+func newReturnTest(t *testing.T, sale *models.Sale) *returnTest {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saleRepo := repository.NewSaleRepository(db.Collection("sales"))
+	returnRepo := repository.NewSaleReturnRepository(db.Collection("sale_returns"))
+	stockAdjustmentRepo := repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments"))
+	serialNumberRepo := repository.NewSerialNumberRepository(db.Collection("serial_numbers"))
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	product.Stock.VAT = models.StockInfo{Purchased: 10, Sold: 5, Remaining: 5}
+	product.Stock.ActualStock = 5
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	sale.SaleCode = "SV-6901-0001"
+	sale.IsVAT = true
+	sale.Items = []models.SaleItem{{ProductID: product.ID.Hex(), ProductName: product.Name, Quantity: 5, UnitPrice: 20, TotalPrice: 100}}
+	if err := saleRepo.Create(ctx, sale); err != nil {
+		t.Fatal(err)
+	}
+
+	return &returnTest{
+		t:           t,
+		h:           NewReturnHandler(returnRepo, saleRepo, productRepo, stockAdjustmentRepo, serialNumberRepo),
+		saleService: services.NewSaleService(saleRepo, productRepo, nil, nil, stockAdjustmentRepo, nil, serialNumberRepo, nil, returnRepo),
+		product:     product,
+		sale:        sale,
+	}
+}
+
+// createReturn returns quantity units of the product and returns the response status
+func (rt *returnTest) createReturn(quantity int) int {
+	saleID := rt.sale.ID.Hex()
+	body := fmt.Sprintf(`{"items": [{"productId": %q, "quantity": %d, "reason": "damaged"}]}`, rt.product.ID.Hex(), quantity)
+	req := httptest.NewRequest(http.MethodPost, "/api/sales/"+saleID+"/returns", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{"id": saleID})
+	rec := httptest.NewRecorder()
+	rt.h.CreateSaleReturn(rec, req)
+	return rec.Code
+}
+
+func (rt *returnTest) remaining() int {
+	product, err := rt.h.productRepo.GetByID(context.Background(), rt.product.ID.Hex())
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+	return product.Stock.VAT.Remaining
+}
+
+func TestCreateSaleReturnRejectsSalesNotHoldingStock(t *testing.T) {
+	for name, sale := range map[string]*models.Sale{
+		"draft":     {IsDraft: true, Status: models.OrderStatusDraft},
+		"cancelled": {Status: models.OrderStatusCancelled},
+	} {
+		rt := newReturnTest(t, sale)
+		if code := rt.createReturn(1); code != http.StatusConflict {
+			t.Errorf("%s sale: return = %d, want 409", name, code)
+		}
+		if remaining := rt.remaining(); remaining != 5 {
+			t.Errorf("%s sale: %d remaining, want 5", name, remaining)
+		}
+	}
+}
+
+func TestRestoreStockSkipsReturnedQuantities(t *testing.T) {
+	rt := newReturnTest(t, &models.Sale{Status: models.OrderStatusConfirmed})
+
+	if code := rt.createReturn(2); code != http.StatusCreated {
+		t.Fatalf("return = %d, want 201", code)
+	}
+	if remaining := rt.remaining(); remaining != 7 {
+		t.Fatalf("after the return: %d remaining, want 7", remaining)
+	}
+
+	// As cancelling or deleting the sale does
+	if err := rt.saleService.RestoreStock(context.Background(), rt.sale); err != nil {
+		t.Fatal(err)
+	}
+	if remaining := rt.remaining(); remaining != 10 {
+		t.Errorf("after restoring the sale's stock: %d remaining, want 10, not the 2 returned units again", remaining)
+	}
+}
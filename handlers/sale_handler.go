@@ -3,14 +3,19 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
-	"time"
 
+	"github.com/skip2/go-qrcode"
+
+	"goodpack-server/apierrors"
 	"goodpack-server/models"
 	"goodpack-server/repository"
 	"goodpack-server/services"
+	"goodpack-server/utils"
 )
 
 type SaleHandler struct {
@@ -20,9 +25,12 @@ type SaleHandler struct {
 	quotationRepo       *repository.QuotationRepository
 	stockAdjustmentRepo *repository.StockAdjustmentRepository
 	bankAccountService  *services.BankAccountService
+	pdfService          *services.PDFService
+	saleService         *services.SaleService
+	webhookService      *services.WebhookService
 }
 
-func NewSaleHandler(saleRepo *repository.SaleRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, quotationRepo *repository.QuotationRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) *SaleHandler {
+func NewSaleHandler(saleRepo *repository.SaleRepository, customerRepo *repository.CustomerRepository, productRepo *repository.ProductRepository, quotationRepo *repository.QuotationRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository, saleService *services.SaleService, webhookService *services.WebhookService) *SaleHandler {
 	return &SaleHandler{
 		saleRepo:            saleRepo,
 		customerRepo:        customerRepo,
@@ -30,12 +38,15 @@ func NewSaleHandler(saleRepo *repository.SaleRepository, customerRepo *repositor
 		quotationRepo:       quotationRepo,
 		stockAdjustmentRepo: stockAdjustmentRepo,
 		bankAccountService:  services.NewBankAccountService(),
+		pdfService:          services.NewPDFService(),
+		saleService:         saleService,
+		webhookService:      webhookService,
 	}
 }
 
 // enrichSaleWithCustomerData enriches a sale with customer data
-func (h *SaleHandler) enrichSaleWithCustomerData(sale *models.Sale) {
-	customer, err := h.customerRepo.GetByID(sale.CustomerID)
+func (h *SaleHandler) enrichSaleWithCustomerData(ctx context.Context, sale *models.Sale) {
+	customer, err := h.customerRepo.GetByID(ctx, sale.CustomerID)
 	if err == nil {
 		// Update sale with customer data
 		sale.CustomerName = customer.CompanyName
@@ -60,41 +71,30 @@ func (h *SaleHandler) enrichSaleWithBankAccountData(sale *models.Sale) {
 	}
 }
 
-// generateSaleID generates a unique sale ID based on VAT status
-func (h *SaleHandler) generateSaleID(ctx context.Context, isVAT bool) (string, error) {
-	now := time.Now()
-	// Convert to Buddhist Era (BE)
-	beYear := now.Year() + 543
-	dateStr := fmt.Sprintf("%02d%02d", beYear%100, int(now.Month())) // YYMM format
+// GetSales lists the sales, optionally only those of ?deliveryStatus=
+func (h *SaleHandler) GetSales(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	var prefix string
-	if isVAT {
-		prefix = fmt.Sprintf("INV-%s", dateStr)
-	} else {
-		prefix = fmt.Sprintf("NV-%s", dateStr)
+	deliveryStatus := r.URL.Query().Get("deliveryStatus")
+	if deliveryStatus != "" && !slices.Contains(models.DeliveryStatuses, deliveryStatus) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_delivery_status"))
+		return
 	}
 
-	nextSeq, err := h.saleRepo.GetNextSequenceNumber(ctx, prefix)
-	if err != nil {
-		return "", err
+	sort, ok := parseSort(w, r, saleSortFields)
+	if !ok {
+		return
 	}
 
-	seqStr := fmt.Sprintf("%04d", nextSeq)
-	return prefix + "-" + seqStr, nil
-}
-
-func (h *SaleHandler) GetSales(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-
-	sales, err := h.saleRepo.GetAll(ctx)
+	sales, err := h.saleRepo.GetAll(ctx, deliveryStatus, sort)
 	if err != nil {
-		http.Error(w, "Failed to fetch sales", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sales_fetch_failed"))
 		return
 	}
 
 	// Enrich sales with customer data
 	for i := range sales {
-		h.enrichSaleWithCustomerData(&sales[i])
+		h.enrichSaleWithCustomerData(ctx, &sales[i])
 		h.enrichSaleWithBankAccountData(&sales[i])
 	}
 
@@ -103,250 +103,632 @@ func (h *SaleHandler) GetSales(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SaleHandler) GetSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid sale ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
-	sale, err := h.saleRepo.GetByID(id)
+	sale, err := h.saleRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Sale not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
 		return
 	}
 
 	// Enrich sale with customer data
-	h.enrichSaleWithCustomerData(sale)
+	h.enrichSaleWithCustomerData(ctx, sale)
 	h.enrichSaleWithBankAccountData(sale)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(sale)
 }
 
-func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+// GetSalePDF generates an invoice PDF for a sale
+func (h *SaleHandler) GetSalePDF(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	var saleReq models.SaleRequest
-	if err := json.NewDecoder(r.Body).Decode(&saleReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	// Extract ID from URL path (/api/sales/{id}/pdf)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
 		return
 	}
+	id := pathParts[len(pathParts)-2]
 
-	// Generate sale ID
-	saleCode, err := h.generateSaleID(ctx, saleReq.IsVAT)
+	sale, err := h.saleRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Failed to generate sale ID", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
 		return
 	}
 
-	// Create sale
-	sale := saleReq.ToSale()
-	sale.SaleCode = saleCode
+	h.enrichSaleWithCustomerData(ctx, sale)
+	h.enrichSaleWithBankAccountData(sale)
 
-	// Cut stock for each item
-	for _, item := range sale.Items {
-		product, err := h.productRepo.GetByID(ctx, item.ProductID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Product not found: %s", item.ProductID), http.StatusBadRequest)
-			return
-		}
+	pdf, err := h.pdfService.GenerateSalePDF(sale)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "pdf_generate_failed"))
+		return
+	}
 
-		// Update sale price using new UpdatePrice method
-		product.UpdatePrice(item.UnitPrice, sale.IsVAT, false) // false = isSale
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.pdf", sale.SaleCode))
+	w.Write(pdf)
+}
 
-		// Determine stock type based on VAT status
-		var stockType models.StockType
-		if sale.IsVAT {
-			stockType = models.StockTypeVAT
-		} else {
-			stockType = models.StockTypeNonVAT
-		}
+// GetSalePromptPayQR returns a PromptPay QR for the sale's grand total, paid to the sale's bank account.
+// Responds with a PNG when the client accepts image/png, otherwise with the raw payload as JSON.
+func (h *SaleHandler) GetSalePromptPayQR(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-		// Apply stock adjustment using centralized stock management logic
-		ApplyStockAdjustment(product, models.AdjustmentTypeReduce, stockType, item.Quantity)
+	// Extract ID from URL path (/api/sales/{id}/promptpay-qr)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
 
-		// Update product
-		if err := h.productRepo.Update(ctx, item.ProductID, product); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to update product stock: %s", item.ProductID), http.StatusInternalServerError)
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	accountID := ""
+	if sale.BankAccountID != nil && *sale.BankAccountID != "" {
+		accountID = *sale.BankAccountID
+	} else if sale.Payment.OurAccount != nil {
+		accountID = *sale.Payment.OurAccount
+	}
+	if accountID == "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "sale_has_no_bank_account"))
+		return
+	}
+
+	bankAccount, err := h.bankAccountService.LoadBankAccountFromConfig(accountID)
+	if err != nil || bankAccount == nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "bank_account_not_found"))
+		return
+	}
+	if bankAccount.PromptPayID == "" {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "bank_account_no_promptpay"))
+		return
+	}
+
+	amount := fmt.Sprintf("%.2f", sale.CalculateGrandTotal())
+	payload, err := utils.GeneratePromptPayPayload(bankAccount.PromptPayID, amount)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "promptpay_payload_failed", err.Error()))
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "image/png") {
+		png, err := qrcode.Encode(payload, qrcode.Medium, 512)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "qr_code_generate_failed"))
 			return
 		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s-promptpay.png", sale.SaleCode))
+		w.Write(png)
+		return
+	}
 
-		// Record stock change in history
-		saleID := sale.ID.Hex()
-		saleCode := sale.SaleCode
-		notes := fmt.Sprintf("ขายจากรายการ %s", saleCode)
-		if err := RecordStockChange(
-			ctx,
-			h.stockAdjustmentRepo,
-			product,
-			models.SourceTypeSale,
-			&saleID,
-			&saleCode,
-			models.AdjustmentTypeReduce,
-			stockType,
-			item.Quantity,
-			&notes,
-		); err != nil {
-			// Log error but don't fail the sale
-			fmt.Printf("Warning: Failed to record stock change history: %v\n", err)
-		}
+	response := map[string]interface{}{
+		"saleCode":    sale.SaleCode,
+		"promptPayId": bankAccount.PromptPayID,
+		"amount":      amount,
+		"payload":     payload,
 	}
 
-	// Save sale
-	if err := h.saleRepo.Create(sale); err != nil {
-		http.Error(w, "Failed to create sale", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var saleReq models.SaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&saleReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &saleReq) {
 		return
 	}
 
-	// Update quotation with sale code if quotationCode is provided
-	if sale.QuotationCode != nil && *sale.QuotationCode != "" {
-		if err := h.updateQuotationWithSaleCode(ctx, *sale.QuotationCode, sale.SaleCode); err != nil {
-			// Log error but don't fail the sale creation
-			fmt.Printf("Warning: Failed to update quotation %s with sale code %s: %v\n", *sale.QuotationCode, sale.SaleCode, err)
+	sale, err := h.saleService.CreateSale(ctx, &saleReq)
+	if err != nil {
+		if respondSaleItemsError(w, r, err) {
+			return
+		}
+		var creditExceeded *services.CreditLimitExceededError
+		if errors.As(err, &creditExceeded) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "credit_limit_exceeded", creditExceeded.OutstandingBalance, creditExceeded.SaleTotal, creditExceeded.CreditLimit))
+			return
 		}
+		fmt.Printf("Error creating sale: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_create_failed"))
+		return
 	}
 
+	dispatchWebhook(h.webhookService, models.WebhookEventSaleCreated, sale)
+	h.dispatchLowStock(ctx, sale)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(sale)
 }
 
+// respondSaleItemsError writes the 400 response for a sale whose items or bundles cannot be resolved and
+// reports whether err was one of those
+func respondSaleItemsError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var productNotFound *services.ProductNotFoundError
+	var bundleNotFound *services.BundleNotFoundError
+	var serialNumbers *services.SerialNumberError
+	switch {
+	case errors.As(err, &productNotFound):
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "product_not_found_id", productNotFound.ProductID))
+	case errors.As(err, &bundleNotFound):
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "bundle_not_found_id", bundleNotFound.BundleID))
+	case errors.As(err, &serialNumbers):
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_serial_numbers", serialNumbers.ProductID, serialNumbers.Reason))
+	case errors.Is(err, services.ErrNoSaleItems):
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "sale_items_required"))
+	default:
+		return false
+	}
+	return true
+}
+
+// dispatchLowStock sends stock.low for each product the sale left at or below its reorder level
+func (h *SaleHandler) dispatchLowStock(ctx context.Context, sale *models.Sale) {
+	seen := make(map[string]bool)
+	for _, item := range sale.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+
+		product, err := h.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil || !product.IsLowStock() {
+			continue
+		}
+		dispatchWebhook(h.webhookService, models.WebhookEventStockLow, product)
+	}
+}
+
 func (h *SaleHandler) UpdateSale(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid sale ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	var saleReq models.SaleRequest
 	if err := json.NewDecoder(r.Body).Decode(&saleReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &saleReq) {
+		return
+	}
+	if err := h.saleService.ExpandBundles(ctx, &saleReq); err != nil {
+		if !respondSaleItemsError(w, r, err) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_update_failed"))
+		}
 		return
 	}
 
 	// Get existing sale
-	existingSale, err := h.saleRepo.GetByID(id)
+	existingSale, err := h.saleRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Sale not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
 		return
 	}
-
-	// Restore stock for old items using stock management logic
-	for _, item := range existingSale.Items {
-		product, err := h.productRepo.GetByID(ctx, item.ProductID)
-		if err == nil {
-			var stockType models.StockType
-			if existingSale.IsVAT {
-				stockType = models.StockTypeVAT
-			} else {
-				stockType = models.StockTypeNonVAT
-			}
-			// Restore stock by adding back (reverse the reduce operation)
-			ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.Quantity)
-			h.productRepo.Update(ctx, item.ProductID, product)
+	if !checkVersion(w, r, saleReq.Version, existingSale.Version) {
+		return
+	}
+	if existingSale.CurrentStatus() == models.OrderStatusCancelled {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "sale_cancelled"))
+		return
+	}
+	if err := h.saleService.CheckSerialNumbers(ctx, saleReq.Items, id); err != nil {
+		if !respondSaleItemsError(w, r, err) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_update_failed"))
 		}
+		return
 	}
 
 	// Update sale
+	isDraft := existingSale.IsDraft
+	previousCustomerID := existingSale.CustomerID
+	previous := *existingSale // the sale as stored, whose items give their stock back
 	existingSale.UpdateFromRequest(&saleReq)
 
-	// Cut stock for new items using stock management logic
-	for _, item := range existingSale.Items {
-		product, err := h.productRepo.GetByID(ctx, item.ProductID)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Product not found: %s", item.ProductID), http.StatusBadRequest)
-			return
+	// Swap the stock of the old items for the new ones and save the sale in one transaction, so a version
+	// conflict on the save leaves the stock as it was
+	version := existingSale.Version
+	err = h.saleRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		existingSale.Version = version // a retried transaction saves the sale again from the version it was read at
+		if err := h.swapStock(txCtx, &previous, existingSale); err != nil {
+			return err
 		}
-
-		// Determine stock type based on VAT status
-		var stockType models.StockType
-		if existingSale.IsVAT {
-			stockType = models.StockTypeVAT
-		} else {
-			stockType = models.StockTypeNonVAT
-		}
-
-		// Apply stock adjustment using centralized stock management logic
-		ApplyStockAdjustment(product, models.AdjustmentTypeReduce, stockType, item.Quantity)
-
-		// Update product
-		if err := h.productRepo.Update(ctx, item.ProductID, product); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to update product stock: %s", item.ProductID), http.StatusInternalServerError)
+		return h.saleRepo.Update(txCtx, id, existingSale)
+	})
+	if errors.Is(err, apierrors.ErrConflict) {
+		if current, err := h.saleRepo.GetByID(ctx, id); err == nil {
+			writeVersionConflict(w, r, current.Version)
 			return
 		}
 	}
-
-	// Save updated sale
-	if err := h.saleRepo.Update(id, existingSale); err != nil {
-		http.Error(w, "Failed to update sale", http.StatusInternalServerError)
+	var appErr *apierrors.AppError
+	if errors.As(err, &appErr) {
+		RespondWithError(w, appErr)
+		return
+	}
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_update_failed"))
 		return
 	}
 
+	// Units of the old items go back in stock before the new items take theirs
+	if !isDraft {
+		h.saleService.ReleaseSerialNumbers(ctx, existingSale)
+		h.saleService.SellSerialNumbers(ctx, existingSale)
+	}
+
+	// Payment status or amount may have changed
+	h.saleService.RefreshOutstandingBalance(ctx, existingSale.CustomerID)
+	if previousCustomerID != existingSale.CustomerID {
+		h.saleService.RefreshOutstandingBalance(ctx, previousCustomerID)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(existingSale)
 }
 
+// swapStock puts back the stock the items of previous cut and cuts it for the items of sale, using stock
+// management logic; a draft has not cut any stock, so only the units of its items are filled in
+func (h *SaleHandler) swapStock(ctx context.Context, previous, sale *models.Sale) error {
+	if !previous.IsDraft {
+		stockType := services.StockTypeForVAT(previous.IsVAT)
+		for _, item := range previous.Items {
+			product, err := h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+				// Restore stock by adding back (reverse the reduce operation)
+				services.ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.StockQuantity())
+				return nil
+			})
+			if errors.Is(err, apierrors.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to restore stock of product %s: %w", item.ProductID, err)
+			}
+			services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+		}
+		h.saleService.ReleaseLots(ctx, previous)
+	}
+
+	// Cut stock for new items using stock management logic
+	stockType := services.StockTypeForVAT(sale.IsVAT)
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		product, err := h.productRepo.GetByID(ctx, item.ProductID)
+		if err != nil {
+			return localisedError(ctx, http.StatusBadRequest, "product_not_found_id", item.ProductID)
+		}
+		item.ApplyUOM(product)
+		if sale.IsDraft {
+			item.Lots = nil // Lots are allocated when the draft is confirmed
+			continue
+		}
+
+		product, err = h.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			services.ApplyStockAdjustment(product, models.AdjustmentTypeReduce, stockType, item.StockQuantity())
+			return nil
+		})
+		if err != nil {
+			return localisedError(ctx, http.StatusInternalServerError, "product_stock_update_failed_id", item.ProductID)
+		}
+		services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+		h.saleService.AllocateLots(ctx, product, item, sale.SaleCode)
+	}
+	return nil
+}
+
 func (h *SaleHandler) DeleteSale(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 
 	// Extract ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
-		http.Error(w, "Invalid sale ID", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
 		return
 	}
 	id := pathParts[len(pathParts)-1]
 
 	// Get existing sale to restore stock
-	existingSale, err := h.saleRepo.GetByID(id)
+	existingSale, err := h.saleRepo.GetByID(ctx, id)
 	if err != nil {
-		http.Error(w, "Sale not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
 		return
 	}
 
-	// Restore stock for all items using stock management logic
-	for _, item := range existingSale.Items {
-		product, err := h.productRepo.GetByID(ctx, item.ProductID)
-		if err == nil {
-			var stockType models.StockType
-			if existingSale.IsVAT {
-				stockType = models.StockTypeVAT
-			} else {
-				stockType = models.StockTypeNonVAT
-			}
-			// Restore stock by adding back (reverse the reduce operation)
-			ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, item.Quantity)
-			h.productRepo.Update(ctx, item.ProductID, product)
+	// Restore stock for all items; a draft has not cut any yet and a cancelled sale has put it back already
+	holdsStock := existingSale.HoldsStock()
+	if holdsStock {
+		if err := h.saleService.RestoreStock(ctx, existingSale); err != nil {
+			fmt.Printf("Error restoring stock of sale %s: %v\n", id, err)
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_delete_failed"))
+			return
 		}
 	}
 
 	// Delete sale
-	if err := h.saleRepo.Delete(id); err != nil {
-		http.Error(w, "Failed to delete sale", http.StatusInternalServerError)
+	if err := h.saleRepo.Delete(ctx, id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_delete_failed"))
 		return
 	}
+	if holdsStock {
+		h.saleService.ReleaseSerialNumbers(ctx, existingSale)
+		h.saleService.ReleaseLots(ctx, existingSale)
+	}
+	h.saleService.RefreshOutstandingBalance(ctx, existingSale.CustomerID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// updateQuotationWithSaleCode updates a quotation with the sale code
-func (h *SaleHandler) updateQuotationWithSaleCode(ctx context.Context, quotationCode, saleCode string) error {
-	// Find quotation by code
-	quotation, err := h.quotationRepo.GetByCode(quotationCode)
+// DuplicateSale creates a draft copy of a sale for a repeat order (POST /api/sales/{id}/duplicate). The copy
+// gets a new sale code, today's date and no payment; stock is only cut when it is confirmed.
+func (h *SaleHandler) DuplicateSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/duplicate)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	original, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	sale, err := h.saleService.DuplicateSale(ctx, original)
+	if err != nil {
+		fmt.Printf("Error duplicating sale %s: %v\n", id, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_duplicate_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sale)
+}
+
+// ConfirmSale confirms a draft sale (POST /api/sales/{id}/confirm), cutting stock for its items as creating a
+// sale does
+func (h *SaleHandler) ConfirmSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/confirm)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	if err := h.saleService.ConfirmSale(ctx, sale, changedBy(r)); err != nil {
+		if errors.Is(err, services.ErrSaleNotDraft) {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "sale_not_draft"))
+			return
+		}
+		h.writeSaleStatusError(w, r, id, err, "sale_confirm_failed")
+		return
+	}
+
+	dispatchWebhook(h.webhookService, models.WebhookEventSaleCreated, sale)
+	h.dispatchLowStock(ctx, sale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sale)
+}
+
+// UpdateSaleStatus moves a sale along its status workflow (PUT /api/sales/{id}/status): draft → confirmed →
+// processing → shipped → completed, or cancelled before it ships. Confirming a draft cuts stock and cancelling
+// puts it back.
+func (h *SaleHandler) UpdateSaleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/status)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	var statusReq models.StatusUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&statusReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &statusReq) {
+		return
+	}
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	wasDraft := sale.IsDraft
+	if err := h.saleService.UpdateSaleStatus(ctx, sale, statusReq.Status, changedBy(r), statusReq.Notes); err != nil {
+		h.writeSaleStatusError(w, r, id, err, "sale_status_update_failed")
+		return
+	}
+
+	if wasDraft && !sale.IsDraft {
+		dispatchWebhook(h.webhookService, models.WebhookEventSaleCreated, sale)
+		h.dispatchLowStock(ctx, sale)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sale)
+}
+
+// UpdateSaleDelivery sets a sale's delivery status and its expected and actual delivery dates
+func (h *SaleHandler) UpdateSaleDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/delivery)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	var deliveryReq models.SaleDeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&deliveryReq); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &deliveryReq) {
+		return
+	}
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("quotation not found: %w", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
 	}
 
-	// Update quotation with sale code
-	quotation.SaleCode = &saleCode
-	quotation.UpdatedAt = time.Now()
+	sale.UpdateDelivery(&deliveryReq)
+	if err := h.saleRepo.Update(r.Context(), id, sale); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.saleRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "sale_delivery_update_failed"))
+		return
+	}
 
-	// Save updated quotation
-	return h.quotationRepo.Update(quotation.ID.Hex(), quotation)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sale)
+}
+
+// writeSaleStatusError responds with the error of confirming a sale or changing its status; unexpected errors
+// are logged and answered with failedKey
+func (h *SaleHandler) writeSaleStatusError(w http.ResponseWriter, r *http.Request, id string, err error, failedKey string) {
+	ctx := r.Context()
+
+	if respondSaleItemsError(w, r, err) {
+		return
+	}
+	var transitionErr *models.StatusTransitionError
+	var creditExceeded *services.CreditLimitExceededError
+	switch {
+	case errors.As(err, &transitionErr):
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "invalid_sale_status_transition", transitionErr.From, transitionErr.To))
+	case errors.As(err, &creditExceeded):
+		RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "credit_limit_exceeded", creditExceeded.OutstandingBalance, creditExceeded.SaleTotal, creditExceeded.CreditLimit))
+	case errors.Is(err, apierrors.ErrConflict):
+		if current, err := h.saleRepo.GetByID(ctx, id); err == nil {
+			writeVersionConflict(w, r, current.Version)
+			return
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, failedKey))
+	default:
+		fmt.Printf("Error changing status of sale %s: %v\n", id, err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, failedKey))
+	}
+}
+
+// RecordPayment records a payment received for a sale, marking it paid once the grand total is covered
+func (h *SaleHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/payment)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	record, ok := decodePaymentRecord(w, r)
+	if !ok {
+		return
+	}
+	if err := sale.AddPayment(*record); err != nil {
+		writePaymentError(w, r, err)
+		return
+	}
+
+	if err := h.saleRepo.Update(ctx, id, sale); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			if current, err := h.saleRepo.GetByID(ctx, id); err == nil {
+				writeVersionConflict(w, r, current.Version)
+				return
+			}
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "payment_record_failed"))
+		return
+	}
+	h.saleService.RefreshOutstandingBalance(ctx, sale.CustomerID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.NewPaymentSummary(sale.Payments, sale.CalculateGrandTotal(), sale.Payment.IsPaid))
+}
+
+// GetPayments lists the payments recorded for a sale
+func (h *SaleHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Extract ID from URL path (/api/sales/{id}/payments)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 5 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sale_id"))
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	sale, err := h.saleRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "sale_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewPaymentSummary(sale.Payments, sale.CalculateGrandTotal(), sale.Payment.IsPaid))
 }
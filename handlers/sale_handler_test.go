@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+// creditTest is a sale handler on a test database with a customer who owes 600 on an unpaid sale
+type creditTest struct {
+	t        *testing.T
+	h        *SaleHandler
+	customer *models.Customer
+	product  *models.Product
+	unpaid   *models.Sale
+}
+
+func newCreditTest(t *testing.T, creditLimit float64) *creditTest {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saleRepo := repository.NewSaleRepository(db.Collection("sales"))
+	customerRepo := repository.NewCustomerRepository(db.Collection("customers"))
+	stockAdjustmentRepo := repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments"))
+	saleService := services.NewSaleService(saleRepo, productRepo, customerRepo, nil, stockAdjustmentRepo,
+		repository.NewBundleRepository(db.Collection("bundles")),
+		repository.NewSerialNumberRepository(db.Collection("serial_numbers")),
+		repository.NewLotRepository(db.Collection("lots")),
+		repository.NewSaleReturnRepository(db.Collection("sale_returns")))
+
+	ct := &creditTest{
+		t:        t,
+		h:        NewSaleHandler(saleRepo, customerRepo, productRepo, nil, stockAdjustmentRepo, saleService, nil),
+		customer: &models.Customer{ID: primitive.NewObjectID(), CustomerCode: "C0001", CompanyName: "Company", ContactName: "Contact", CreditLimit: creditLimit},
+		product:  &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"},
+	}
+	ct.product.Stock.NonVAT = models.StockInfo{Purchased: 100, Remaining: 100}
+	ct.product.Stock.ActualStock = 100
+	if _, err := db.Collection("customers").InsertOne(ctx, ct.customer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection("products").InsertOne(ctx, ct.product); err != nil {
+		t.Fatal(err)
+	}
+
+	ct.unpaid = &models.Sale{
+		SaleCode:   "SN-6901-0001",
+		CustomerID: ct.customer.ID.Hex(),
+		Items:      []models.SaleItem{{ProductID: ct.product.ID.Hex(), Quantity: 6, UnitPrice: 100, TotalPrice: 600}},
+	}
+	if err := saleRepo.Create(ctx, ct.unpaid); err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+// createSale sells one non-VAT unit of the product at price and returns the response status
+func (ct *creditTest) createSale(price float64, isPaid bool) int {
+	body := fmt.Sprintf(`{"customerId": %q, "items": [{"productId": %q, "quantity": 1, "unitPrice": %v}], "payment": {"isPaid": %t}}`,
+		ct.customer.ID.Hex(), ct.product.ID.Hex(), price, isPaid)
+	rec := httptest.NewRecorder()
+	ct.h.CreateSale(rec, httptest.NewRequest(http.MethodPost, "/api/sales", strings.NewReader(body)))
+	return rec.Code
+}
+
+func (ct *creditTest) outstandingBalance() float64 {
+	customer, err := ct.h.customerRepo.GetByID(context.Background(), ct.customer.ID.Hex())
+	if err != nil {
+		ct.t.Fatal(err)
+	}
+	return customer.OutstandingBalance
+}
+
+func TestCreateSaleEnforcesCreditLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  float64
+		isPaid bool
+		want   int
+	}{
+		{"below the limit", 300, false, http.StatusCreated},
+		{"at the limit", 400, false, http.StatusCreated},
+		{"over the limit", 400.01, false, http.StatusUnprocessableEntity},
+		{"over the limit but paid", 500, true, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct := newCreditTest(t, 1000)
+			if got := ct.createSale(tt.price, tt.isPaid); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSaleWithoutCreditLimit(t *testing.T) {
+	ct := newCreditTest(t, 0)
+	if got := ct.createSale(5000, false); got != http.StatusCreated {
+		t.Errorf("status = %d, want %d: a credit limit of 0 is no limit", got, http.StatusCreated)
+	}
+}
+
+func TestRecordPaymentReducesOutstandingBalance(t *testing.T) {
+	ct := newCreditTest(t, 1000)
+	if got := ct.createSale(300, false); got != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d", got, http.StatusCreated)
+	}
+	if got := ct.outstandingBalance(); got != 900 {
+		t.Fatalf("outstanding balance = %v, want 900", got)
+	}
+
+	saleID := ct.unpaid.ID.Hex()
+	rec := httptest.NewRecorder()
+	ct.h.RecordPayment(rec, httptest.NewRequest(http.MethodPost, "/api/sales/"+saleID+"/payment", strings.NewReader(`{"method": "cash", "amount": 600}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("payment status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var summary models.PaymentSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if !summary.IsPaid {
+		t.Errorf("payment summary = %+v, want the sale paid", summary)
+	}
+	if got := ct.outstandingBalance(); got != 300 {
+		t.Errorf("outstanding balance = %v, want 300", got)
+	}
+
+	// The paid sale no longer counts towards the limit
+	if got := ct.createSale(700, false); got != http.StatusCreated {
+		t.Errorf("status = %d, want %d", got, http.StatusCreated)
+	}
+}
+
+func TestDuplicateSaleCutsStockOnlyWhenConfirmed(t *testing.T) {
+	ct := newCreditTest(t, 0)
+	ctx := context.Background()
+	productID := ct.product.ID.Hex()
+	before := time.Now()
+
+	// stock returns the product's non-VAT stock and how many stock adjustments it has
+	stock := func() (int, int64) {
+		t.Helper()
+		product, err := ct.h.productRepo.GetByID(ctx, productID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		adjustments, err := ct.h.stockAdjustmentRepo.CountByProductID(ctx, productID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return product.Stock.NonVAT.Remaining, adjustments
+	}
+
+	originalID := ct.unpaid.ID.Hex()
+	rec := httptest.NewRecorder()
+	ct.h.DuplicateSale(rec, httptest.NewRequest(http.MethodPost, "/api/sales/"+originalID+"/duplicate", nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("duplicate status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var duplicate models.Sale
+	if err := json.NewDecoder(rec.Body).Decode(&duplicate); err != nil {
+		t.Fatal(err)
+	}
+
+	if duplicate.ID == ct.unpaid.ID || duplicate.SaleCode == "" || duplicate.SaleCode == ct.unpaid.SaleCode {
+		t.Errorf("duplicate %s (%s), want a sale and code of its own, not %s (%s)", duplicate.ID.Hex(), duplicate.SaleCode, originalID, ct.unpaid.SaleCode)
+	}
+	if duplicate.SaleDate.Before(before) || duplicate.CreatedAt.Before(before) {
+		t.Errorf("duplicate dated %v, created %v, want now", duplicate.SaleDate, duplicate.CreatedAt)
+	}
+	if !duplicate.IsDraft || duplicate.Payment.IsPaid || duplicate.CustomerID != ct.unpaid.CustomerID || len(duplicate.Items) != 1 {
+		t.Errorf("duplicate = %+v, want an unpaid draft of the original's items for the same customer", duplicate)
+	}
+	if remaining, adjustments := stock(); remaining != 100 || adjustments != 0 {
+		t.Errorf("after duplicating: %d remaining with %d adjustments, want 100 and none", remaining, adjustments)
+	}
+
+	duplicateID := duplicate.ID.Hex()
+	rec = httptest.NewRecorder()
+	ct.h.ConfirmSale(rec, httptest.NewRequest(http.MethodPost, "/api/sales/"+duplicateID+"/confirm", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if remaining, adjustments := stock(); remaining != 94 || adjustments != 1 {
+		t.Errorf("after confirming: %d remaining with %d adjustments, want 94 and 1", remaining, adjustments)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type SerialNumberHandler struct {
+	serialNumberRepo *repository.SerialNumberRepository
+	productRepo      *repository.ProductRepository
+}
+
+func NewSerialNumberHandler(serialNumberRepo *repository.SerialNumberRepository, productRepo *repository.ProductRepository) *SerialNumberHandler {
+	return &SerialNumberHandler{
+		serialNumberRepo: serialNumberRepo,
+		productRepo:      productRepo,
+	}
+}
+
+// GetProductSerialNumbers lists the units of a serialised product, optionally only those with ?status=
+func (h *SerialNumberHandler) GetProductSerialNumbers(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !models.ValidSerialNumberStatus(status) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_serial_number_status"))
+		return
+	}
+
+	if _, err := h.productRepo.GetByID(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	serialNumbers, err := h.serialNumberRepo.GetByProduct(r.Context(), id, status)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "serial_numbers_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serialNumbers)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+func TestSerialNumberLifecycle(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saleRepo := repository.NewSaleRepository(db.Collection("sales"))
+	customerRepo := repository.NewCustomerRepository(db.Collection("customers"))
+	stockAdjustmentRepo := repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments"))
+	serialNumberRepo := repository.NewSerialNumberRepository(db.Collection("serial_numbers"))
+	lotRepo := repository.NewLotRepository(db.Collection("lots"))
+	returnRepo := repository.NewSaleReturnRepository(db.Collection("sale_returns"))
+	saleService := services.NewSaleService(saleRepo, productRepo, customerRepo, nil, stockAdjustmentRepo,
+		repository.NewBundleRepository(db.Collection("bundles")), serialNumberRepo, lotRepo, returnRepo)
+
+	purchases := NewPurchaseHandler(
+		repository.NewPurchaseRepository(db.Collection("purchases")), customerRepo, productRepo, stockAdjustmentRepo,
+		repository.NewSupplierRepository(db.Collection("suppliers")), serialNumberRepo, lotRepo,
+		repository.NewExchangeRateRepository(db.Collection("exchange_rates")), nil, "",
+	)
+	sales := NewSaleHandler(saleRepo, customerRepo, productRepo, nil, stockAdjustmentRepo, saleService, nil)
+	returns := NewReturnHandler(returnRepo, saleRepo, productRepo, stockAdjustmentRepo, serialNumberRepo)
+	serialNumbers := NewSerialNumberHandler(serialNumberRepo, productRepo)
+
+	customer := &models.Customer{ID: primitive.NewObjectID(), CustomerCode: "C0001", CompanyName: "Company"}
+	if _, err := db.Collection("customers").InsertOne(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "NB-0001", Name: "Notebook", Category: "Notebook", IsSerialised: true}
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	productID := product.ID.Hex()
+
+	// serials lists the product's serial numbers with status
+	serials := func(status string) []string {
+		t.Helper()
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/products/"+productID+"/serial-numbers?status="+status, nil), map[string]string{"id": productID})
+		rec := httptest.NewRecorder()
+		serialNumbers.GetProductSerialNumbers(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list %s serial numbers: status = %d, want %d", status, rec.Code, http.StatusOK)
+		}
+		var units []*models.SerialNumber
+		if err := json.NewDecoder(rec.Body).Decode(&units); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, unit := range units {
+			names = append(names, unit.SerialNumber)
+		}
+		sort.Strings(names)
+		return names
+	}
+	// sell sells the units with the serial numbers and returns the response
+	sell := func(serials ...string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"customerId": %q, "items": [{"productId": %q, "quantity": %d, "unitPrice": 25000, "serialNumbers": %s}], "payment": {"isPaid": true}}`,
+			customer.ID.Hex(), productID, len(serials), jsonList(t, serials))
+		rec := httptest.NewRecorder()
+		sales.CreateSale(rec, httptest.NewRequest(http.MethodPost, "/api/sales", strings.NewReader(body)))
+		return rec
+	}
+	expect := func(step string, got, want []string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", step, got, want)
+		}
+	}
+
+	// Receive
+	body := fmt.Sprintf(`{"customerId": %q, "items": [{"productId": %q, "productName": "Notebook", "quantity": 3, "unitPrice": 20000, "serialNumbers": ["SN-1", "SN-2", "SN-3"]}]}`,
+		customer.ID.Hex(), productID)
+	rec := httptest.NewRecorder()
+	purchases.CreatePurchase(rec, httptest.NewRequest(http.MethodPost, "/api/purchases", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	expect("after the purchase, available", serials(models.SerialNumberStatusAvailable), []string{"SN-1", "SN-2", "SN-3"})
+
+	// Sell
+	rec = sell("SN-1", "SN-2")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("sale: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var sale models.Sale
+	if err := json.NewDecoder(rec.Body).Decode(&sale); err != nil {
+		t.Fatal(err)
+	}
+	expect("after the sale, available", serials(models.SerialNumberStatusAvailable), []string{"SN-3"})
+	expect("after the sale, sold", serials(models.SerialNumberStatusSold), []string{"SN-1", "SN-2"})
+	if rec := sell("SN-1"); rec.Code < 400 {
+		t.Errorf("selling a sold unit again: status = %d, want an error", rec.Code)
+	}
+	if rec := sell("SN-9"); rec.Code < 400 {
+		t.Errorf("selling a unit never received: status = %d, want an error", rec.Code)
+	}
+
+	// Return
+	saleID := sale.ID.Hex()
+	body = fmt.Sprintf(`{"items": [{"productId": %q, "quantity": 1, "reason": "faulty screen", "serialNumbers": ["SN-1"]}]}`, productID)
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/sales/"+saleID+"/returns", strings.NewReader(body)), map[string]string{"id": saleID})
+	rec = httptest.NewRecorder()
+	returns.CreateSaleReturn(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("return: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	expect("after the return, sold", serials(models.SerialNumberStatusSold), []string{"SN-2"})
+	expect("after the return, returned", serials(models.SerialNumberStatusReturned), []string{"SN-1"})
+
+	// A returned unit is back in stock and can be sold again
+	if rec := sell("SN-1"); rec.Code != http.StatusCreated {
+		t.Errorf("selling a returned unit: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	expect("after selling it again, sold", serials(models.SerialNumberStatusSold), []string{"SN-1", "SN-2"})
+}
+
+// jsonList encodes values as a JSON array
+func jsonList(t *testing.T, values []string) string {
+	t.Helper()
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// sseKeepAliveInterval is how often an idle event stream gets a comment line, so proxies keep it open
+const sseKeepAliveInterval = 30 * time.Second
+
+// sseClientBuffer is how many events a slow client may fall behind before events to it are dropped
+const sseClientBuffer = 16
+
+// SSEHandler streams inventory events to clients as Server-Sent Events
+type SSEHandler struct {
+	mu      sync.Mutex
+	clients map[chan models.InventoryEvent]struct{}
+	done    chan struct{} // closed when Run returns, ending every stream
+}
+
+func NewSSEHandler() *SSEHandler {
+	return &SSEHandler{
+		clients: make(map[chan models.InventoryEvent]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run sends each event from events to every subscribed client until ctx is cancelled or events is closed.
+// Open streams are then ended, so they do not hold up a server shutdown.
+func (h *SSEHandler) Run(ctx context.Context, events <-chan models.InventoryEvent) {
+	defer close(h.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			h.broadcast(event)
+		}
+	}
+}
+
+func (h *SSEHandler) broadcast(event models.InventoryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		select {
+		case client <- event:
+		default:
+			// The client is not keeping up; it misses this event rather than holding up the others
+		}
+	}
+}
+
+func (h *SSEHandler) subscribe() chan models.InventoryEvent {
+	client := make(chan models.InventoryEvent, sseClientBuffer)
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+func (h *SSEHandler) unsubscribe(client chan models.InventoryEvent) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+}
+
+// StreamInventory streams stock changes (GET /api/events/inventory) as `data: {...}` events until the client
+// disconnects
+func (h *SSEHandler) StreamInventory(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "streaming_unsupported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client := h.subscribe()
+	defer h.unsubscribe(client)
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case event := <-client:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
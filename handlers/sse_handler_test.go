@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/database"
+	"goodpack-server/models"
+)
+
+// subscribeInventory opens the inventory event stream of h and returns the events read from it. The channel
+// is closed when the stream ends.
+func subscribeInventory(t *testing.T, h *SSEHandler) <-chan models.InventoryEvent {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(h.StreamInventory))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", contentType)
+	}
+
+	events := make(chan models.InventoryEvent, sseClientBuffer)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var event models.InventoryEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				t.Errorf("event %q: %v", data, err)
+				return
+			}
+			events <- event
+		}
+	}()
+
+	// The handler subscribes after sending the headers; wait for it, so no event is broadcast before
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.mu.Lock()
+		subscribed := len(h.clients) > 0
+		h.mu.Unlock()
+		if subscribed {
+			return events
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not subscribe within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// nextEvent returns the next event from events, failing the test if none arrives within timeout
+func nextEvent(t *testing.T, events <-chan models.InventoryEvent, timeout time.Duration) models.InventoryEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended, want an event")
+		}
+		return event
+	case <-time.After(timeout):
+		t.Fatalf("no event within %s", timeout)
+	}
+	return models.InventoryEvent{}
+}
+
+func TestStreamInventorySendsEachEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := NewSSEHandler()
+	inventoryEvents := make(chan models.InventoryEvent)
+	go h.Run(ctx, inventoryEvents)
+
+	events := subscribeInventory(t, h)
+	sent := models.InventoryEvent{ProductID: "p1", SKUID: "BOX-0001", PreviousStock: 10, NewStock: 7, Timestamp: time.Date(2024, time.January, 5, 12, 0, 0, 0, time.UTC)}
+	inventoryEvents <- sent
+
+	got := nextEvent(t, events, time.Second)
+	if got.ProductID != sent.ProductID || got.SKUID != sent.SKUID || got.PreviousStock != 10 || got.NewStock != 7 || !got.Timestamp.Equal(sent.Timestamp) {
+		t.Errorf("event = %+v, want %+v", got, sent)
+	}
+
+	// Stopping the handler ends the stream
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("got another event after the handler stopped, want the stream ended")
+		}
+	case <-time.After(time.Second):
+		t.Error("stream still open 1s after the handler stopped")
+	}
+}
+
+func TestInventoryStockChangeReachesEventStream(t *testing.T) {
+	db := testDatabase(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	products := db.Collection("products")
+
+	warmUp := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Warm-up Box"}
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0002", Name: "Kraft Box"}
+	product.Stock.ActualStock = 10
+	for _, p := range []*models.Product{warmUp, product} {
+		if _, err := products.InsertOne(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inventoryEvents := make(chan models.InventoryEvent)
+	watchErr := make(chan error, 1)
+	go func() { watchErr <- database.WatchInventory(ctx, db, inventoryEvents) }()
+	h := NewSSEHandler()
+	go h.Run(ctx, inventoryEvents)
+	events := subscribeInventory(t, h)
+
+	setStock := func(p *models.Product, stock int) {
+		t.Helper()
+		if _, err := products.UpdateByID(ctx, p.ID, bson.M{"$set": bson.M{"stock.actualStock": stock}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The change stream opens in the background: change the warm-up product until its event shows it is open
+	ready := false
+	for stock := 1; !ready && stock <= 50; stock++ {
+		setStock(warmUp, stock)
+		select {
+		case err := <-watchErr:
+			t.Skipf("change streams unavailable (MongoDB must run as a replica set): %v", err)
+		case event := <-events:
+			ready = event.ProductID == warmUp.ID.Hex()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	if !ready {
+		t.Fatal("change stream did not start within 10s")
+	}
+
+	setStock(product, 7)
+	for {
+		event := nextEvent(t, events, time.Second)
+		if event.ProductID == warmUp.ID.Hex() {
+			continue // a late warm-up change
+		}
+		if event.ProductID != product.ID.Hex() || event.SKUID != "BOX-0002" || event.PreviousStock != 10 || event.NewStock != 7 {
+			t.Errorf("event = %+v, want BOX-0002 going from 10 to 7", event)
+		}
+		return
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"goodpack-server/models"
 	"goodpack-server/repository"
+	"goodpack-server/services"
 )
 
 type StockAdjustmentHandler struct {
@@ -26,95 +27,9 @@ func NewStockAdjustmentHandler(adjustmentRepo *repository.StockAdjustmentReposit
 	}
 }
 
-// ApplyStockAdjustment applies stock adjustment to a product (core logic)
-// Allows negative stock values to indicate abnormal stock status
-func ApplyStockAdjustment(
-	product *models.Product,
-	adjustmentType models.StockAdjustmentType,
-	stockType models.StockType,
-	quantity int,
-) {
-	if stockType == models.StockTypeActualStock {
-		// For ActualStock, just add or subtract directly
-		// Allow negative values to show abnormal stock status
-		if adjustmentType == models.AdjustmentTypeAdd {
-			product.Stock.ActualStock += quantity
-		} else {
-			product.Stock.ActualStock -= quantity
-			// Allow negative stock to indicate abnormal status
-		}
-	} else {
-		// For VAT or NonVAT
-		var stockInfo *models.StockInfo
-		if stockType == models.StockTypeVAT {
-			stockInfo = &product.Stock.VAT
-		} else {
-			stockInfo = &product.Stock.NonVAT
-		}
-
-		if adjustmentType == models.AdjustmentTypeAdd {
-			// เพิ่ม: บวกใน Purchased และ Remaining
-			stockInfo.Purchased += quantity
-			stockInfo.Remaining += quantity
-			// Also update ActualStock
-			product.Stock.ActualStock += quantity
-		} else {
-			// ลด: บวกใน Sold และลด Remaining
-			stockInfo.Sold += quantity
-			stockInfo.Remaining -= quantity
-			// Allow negative remaining to indicate abnormal status
-			// Also update ActualStock
-			product.Stock.ActualStock -= quantity
-			// Allow negative ActualStock to indicate abnormal status
-		}
-	}
-}
-
-// RecordStockChange records a stock change in history (helper function for other handlers)
-func RecordStockChange(
-	ctx context.Context,
-	adjustmentRepo *repository.StockAdjustmentRepository,
-	product *models.Product,
-	sourceType models.SourceType,
-	sourceID, sourceCode *string,
-	adjustmentType models.StockAdjustmentType,
-	stockType models.StockType,
-	quantity int,
-	notes *string,
-) error {
-	adjustment := &models.StockAdjustment{
-		ProductID:      product.ID.Hex(),
-		ProductName:    product.Name,
-		SKUID:          product.SKUID,
-		AdjustmentType: adjustmentType,
-		StockType:      stockType,
-		Quantity:       quantity,
-		SourceType:     sourceType,
-		SourceID:       sourceID,
-		SourceCode:     sourceCode,
-		Notes:          notes,
-		CreatedAt:      time.Now(),
-	}
-
-	// Store before values
-	adjustment.BeforeVATPurchased = product.Stock.VAT.Purchased
-	adjustment.BeforeVATSold = product.Stock.VAT.Sold
-	adjustment.BeforeVATRemaining = product.Stock.VAT.Remaining
-	adjustment.BeforeNonVATPurchased = product.Stock.NonVAT.Purchased
-	adjustment.BeforeNonVATSold = product.Stock.NonVAT.Sold
-	adjustment.BeforeNonVATRemaining = product.Stock.NonVAT.Remaining
-	adjustment.BeforeActualStock = product.Stock.ActualStock
-
-	// Set after values
-	adjustment.SetAfterValues(product)
-
-	// Save adjustment history
-	return adjustmentRepo.Create(ctx, adjustment)
-}
-
 // AdjustStock handles stock adjustment request
 func (h *StockAdjustmentHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
@@ -125,7 +40,7 @@ func (h *StockAdjustmentHandler) AdjustStock(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		product, err = h.productRepo.GetBySKUID(ctx, productID)
 		if err != nil {
-			http.Error(w, "Product not found", http.StatusNotFound)
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 			return
 		}
 	}
@@ -133,23 +48,12 @@ func (h *StockAdjustmentHandler) AdjustStock(w http.ResponseWriter, r *http.Requ
 	// Parse request
 	var req models.StockAdjustmentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
 		return
 	}
 
 	// Validate request
-	if req.Quantity <= 0 {
-		http.Error(w, "Quantity must be greater than 0", http.StatusBadRequest)
-		return
-	}
-
-	if req.AdjustmentType != models.AdjustmentTypeAdd && req.AdjustmentType != models.AdjustmentTypeReduce {
-		http.Error(w, "Invalid adjustment type. Must be 'add' or 'reduce'", http.StatusBadRequest)
-		return
-	}
-
-	if req.StockType != models.StockTypeVAT && req.StockType != models.StockTypeNonVAT && req.StockType != models.StockTypeActualStock {
-		http.Error(w, "Invalid stock type. Must be 'vat', 'nonvat', or 'actualstock'", http.StatusBadRequest)
+	if !validateRequest(w, r, &req) {
 		return
 	}
 
@@ -157,14 +61,15 @@ func (h *StockAdjustmentHandler) AdjustStock(w http.ResponseWriter, r *http.Requ
 	adjustment := req.ToStockAdjustment(product, models.SourceTypeAdjustment, nil, nil)
 
 	// Apply stock adjustment using centralized logic
-	ApplyStockAdjustment(product, req.AdjustmentType, req.StockType, req.Quantity)
+	services.ApplyStockAdjustment(product, req.AdjustmentType, req.StockType, req.Quantity)
 
 	// Update product
 	product.UpdatedAt = time.Now()
 	if err := h.productRepo.Update(ctx, product.ID.Hex(), product); err != nil {
-		http.Error(w, "Failed to update product stock", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_stock_update_failed"))
 		return
 	}
+	services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
 
 	// Set after values in adjustment record
 	adjustment.SetAfterValues(product)
@@ -179,9 +84,228 @@ func (h *StockAdjustmentHandler) AdjustStock(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(product)
 }
 
+// TransferStock moves stock between the VAT and Non-VAT buckets of a product. Actual stock is unchanged; the
+// transfer is recorded as one stock history entry describing both sides.
+func (h *StockAdjustmentHandler) TransferStock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	product, err := h.findProduct(ctx, productID)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	var req models.StockTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &req) {
+		return
+	}
+
+	available := product.Stock.VAT.Remaining
+	if req.FromStockType == models.StockTypeNonVAT {
+		available = product.Stock.NonVAT.Remaining
+	}
+	if req.Quantity > available {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "stock_transfer_insufficient", req.Quantity, req.FromStockType, available))
+		return
+	}
+
+	// Create adjustment record (before values)
+	adjustment := req.ToStockAdjustment(product)
+
+	services.TransferStock(product, req.FromStockType, req.ToStockType, req.Quantity)
+
+	product.UpdatedAt = time.Now()
+	if err := h.productRepo.Update(ctx, product.ID.Hex(), product); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_stock_update_failed"))
+		return
+	}
+
+	adjustment.SetAfterValues(product)
+	if err := h.adjustmentRepo.Create(ctx, adjustment); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Warning: Failed to save stock adjustment history: %v\n", err)
+	}
+
+	json.NewEncoder(w).Encode(product)
+}
+
+// BulkAdjustStock applies many stock adjustments in one MongoDB transaction: every adjustment is
+// validated first, and if any is invalid or a write fails nothing is changed
+func (h *StockAdjustmentHandler) BulkAdjustStock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.BulkStockAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(ctx, http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if len(req.Adjustments) == 0 {
+		RespondWithError(w, localisedError(ctx, http.StatusBadRequest, "adjustments_required"))
+		return
+	}
+
+	result := &models.BulkAdjustmentResult{Errors: []string{}, UpdatedProducts: []*models.Product{}}
+	for i, item := range req.Adjustments {
+		if err := item.Validate(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("adjustment %d (%s): %v", i+1, item.ProductID, err))
+			continue
+		}
+		if _, err := h.findProduct(ctx, item.ProductID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("adjustment %d (%s): product not found", i+1, item.ProductID))
+		}
+	}
+	if len(result.Errors) > 0 {
+		result.FailedCount = len(result.Errors)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	var updated []*models.Product
+	err := h.adjustmentRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		updated = nil
+		products := make(map[string]*models.Product) // the same product may be adjusted more than once
+
+		for i, item := range req.Adjustments {
+			product, ok := products[item.ProductID]
+			if !ok {
+				var err error
+				if product, err = h.findProduct(txCtx, item.ProductID); err != nil {
+					return fmt.Errorf("adjustment %d (%s): product not found", i+1, item.ProductID)
+				}
+				products[item.ProductID] = product
+				updated = append(updated, product)
+			}
+
+			adjustment := item.ToStockAdjustment(product, models.SourceTypeAdjustment, nil, nil)
+			services.ApplyStockAdjustment(product, item.AdjustmentType, item.StockType, item.Quantity)
+			product.UpdatedAt = time.Now()
+			if err := h.productRepo.Update(txCtx, product.ID.Hex(), product); err != nil {
+				return fmt.Errorf("adjustment %d (%s): %w", i+1, item.ProductID, err)
+			}
+
+			adjustment.SetAfterValues(product)
+			if err := h.adjustmentRepo.Create(txCtx, adjustment); err != nil {
+				return fmt.Errorf("adjustment %d (%s): %w", i+1, item.ProductID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error applying bulk stock adjustment: %v\n", err)
+		result.FailedCount = len(req.Adjustments)
+		result.Errors = append(result.Errors, err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	categories := make(map[string]bool)
+	for _, product := range updated {
+		if !categories[product.Category] {
+			categories[product.Category] = true
+			services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+		}
+	}
+
+	result.SuccessCount = len(req.Adjustments)
+	result.UpdatedProducts = updated
+	json.NewEncoder(w).Encode(result)
+}
+
+// findProduct looks a product up by ObjectID, then by SKU ID
+func (h *StockAdjustmentHandler) findProduct(ctx context.Context, productID string) (*models.Product, error) {
+	product, err := h.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		product, err = h.productRepo.GetBySKUID(ctx, productID)
+	}
+	return product, err
+}
+
+// GetStockDiscrepancies lists products whose actual stock differs from VAT + Non-VAT remaining
+func (h *StockAdjustmentHandler) GetStockDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	discrepancies, err := h.productRepo.GetStockDiscrepancies(ctx)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_discrepancies_fetch_failed"))
+		return
+	}
+	if discrepancies == nil {
+		discrepancies = []*models.StockDiscrepancy{}
+	}
+
+	json.NewEncoder(w).Encode(discrepancies)
+}
+
+// ReconcileStock sets a product's actual stock to VAT + Non-VAT remaining and records the change
+func (h *StockAdjustmentHandler) ReconcileStock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	product, err := h.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+		return
+	}
+
+	computedStock := product.Stock.VAT.Remaining + product.Stock.NonVAT.Remaining
+	delta := computedStock - product.Stock.ActualStock
+	if delta == 0 {
+		// Already in agreement, nothing to record
+		json.NewEncoder(w).Encode(product)
+		return
+	}
+
+	req := models.StockAdjustmentRequest{
+		AdjustmentType: models.AdjustmentTypeAdd,
+		StockType:      models.StockTypeActualStock,
+		Quantity:       delta,
+	}
+	if delta < 0 {
+		req.AdjustmentType = models.AdjustmentTypeReduce
+		req.Quantity = -delta
+	}
+	notes := fmt.Sprintf("Reconciled actual stock %d to VAT + Non-VAT remaining %d", product.Stock.ActualStock, computedStock)
+	req.Notes = &notes
+
+	// Create adjustment record (before values)
+	adjustment := req.ToStockAdjustment(product, models.SourceTypeReconciliation, nil, nil)
+
+	services.ApplyStockAdjustment(product, req.AdjustmentType, req.StockType, req.Quantity)
+
+	product.UpdatedAt = time.Now()
+	if err := h.productRepo.Update(ctx, product.ID.Hex(), product); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_stock_update_failed"))
+		return
+	}
+	services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
+
+	adjustment.SetAfterValues(product)
+	if err := h.adjustmentRepo.Create(ctx, adjustment); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Warning: Failed to save stock adjustment history: %v\n", err)
+	}
+
+	json.NewEncoder(w).Encode(product)
+}
+
 // GetStockHistory gets stock adjustment history for a product
 func (h *StockAdjustmentHandler) GetStockHistory(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
@@ -192,7 +316,7 @@ func (h *StockAdjustmentHandler) GetStockHistory(w http.ResponseWriter, r *http.
 	if err != nil {
 		_, err = h.productRepo.GetBySKUID(ctx, productID)
 		if err != nil {
-			http.Error(w, "Product not found", http.StatusNotFound)
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
 			return
 		}
 	}
@@ -235,16 +359,87 @@ func (h *StockAdjustmentHandler) GetStockHistory(w http.ResponseWriter, r *http.
 	}
 
 	if err != nil {
-		http.Error(w, "Failed to get stock history", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_history_fetch_failed"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(adjustments)
 }
 
+// GetStockTimeline returns a product's stock movements with a running balance
+func (h *StockAdjustmentHandler) GetStockTimeline(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	product, err := h.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		product, err = h.productRepo.GetBySKUID(ctx, productID)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+	}
+
+	// Get date range from query parameters (optional)
+	var startDate, endDate time.Time
+	if startDateStr := r.URL.Query().Get("startDate"); startDateStr != "" {
+		if parsed, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			startDate = parsed
+		}
+	}
+	if endDateStr := r.URL.Query().Get("endDate"); endDateStr != "" {
+		if parsed, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			// Set to end of day
+			endDate = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		}
+	}
+
+	// The running balance needs the full history, so fetch everything and filter afterwards
+	adjustments, err := h.adjustmentRepo.GetByProductID(ctx, product.ID.Hex(), 0)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_history_fetch_failed"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(services.BuildStockTimeline(adjustments, startDate, endDate))
+}
+
+// GetProductMovements lists a product's stock movements, oldest first, with the reference, customer and
+// unit price of the sale or purchase behind each one (startDate, endDate)
+func (h *StockAdjustmentHandler) GetProductMovements(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+
+	product, err := h.productRepo.GetByID(r.Context(), productID)
+	if err != nil {
+		product, err = h.productRepo.GetBySKUID(r.Context(), productID)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "product_not_found"))
+			return
+		}
+	}
+
+	startDate, endDate, ok := parseExportDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	movements, err := h.adjustmentRepo.GetEnrichedMovements(r.Context(), product.ID.Hex(), startDate, endDate)
+	if err != nil {
+		fmt.Printf("Error fetching stock movements: %v\n", err)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_history_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
 // GetAllStockHistory gets all stock adjustments across all products
 func (h *StockAdjustmentHandler) GetAllStockHistory(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
 	// Get limit and skip from query parameters
@@ -265,7 +460,7 @@ func (h *StockAdjustmentHandler) GetAllStockHistory(w http.ResponseWriter, r *ht
 
 	adjustments, err := h.adjustmentRepo.GetAll(ctx, limit, skip)
 	if err != nil {
-		http.Error(w, "Failed to get stock history", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_history_fetch_failed"))
 		return
 	}
 
@@ -274,7 +469,7 @@ func (h *StockAdjustmentHandler) GetAllStockHistory(w http.ResponseWriter, r *ht
 
 // GetStockHistoryBySource gets stock adjustments by source type and source ID
 func (h *StockAdjustmentHandler) GetStockHistoryBySource(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
 	// Get source type and source ID from query parameters
@@ -282,20 +477,20 @@ func (h *StockAdjustmentHandler) GetStockHistoryBySource(w http.ResponseWriter,
 	sourceID := r.URL.Query().Get("sourceId")
 
 	if sourceTypeStr == "" || sourceID == "" {
-		http.Error(w, "sourceType and sourceId are required", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "source_required"))
 		return
 	}
 
 	sourceType := models.SourceType(sourceTypeStr)
 	if sourceType != models.SourceTypePurchase && sourceType != models.SourceTypeSale &&
 		sourceType != models.SourceTypeAdjustment && sourceType != models.SourceTypeMigration {
-		http.Error(w, "Invalid source type", http.StatusBadRequest)
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_source_type"))
 		return
 	}
 
 	adjustments, err := h.adjustmentRepo.GetBySource(ctx, sourceType, sourceID)
 	if err != nil {
-		http.Error(w, "Failed to get stock history", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_history_fetch_failed"))
 		return
 	}
 
@@ -304,7 +499,7 @@ func (h *StockAdjustmentHandler) GetStockHistoryBySource(w http.ResponseWriter,
 
 // DeleteStockAdjustment deletes a stock adjustment and reverses the stock change
 func (h *StockAdjustmentHandler) DeleteStockAdjustment(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
+	ctx := r.Context()
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
@@ -313,40 +508,46 @@ func (h *StockAdjustmentHandler) DeleteStockAdjustment(w http.ResponseWriter, r
 	// Get the adjustment record
 	adjustment, err := h.adjustmentRepo.GetByID(ctx, adjustmentID)
 	if err != nil {
-		http.Error(w, "Stock adjustment not found", http.StatusNotFound)
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "stock_adjustment_not_found"))
 		return
 	}
 
 	// Get the product
 	product, err := h.productRepo.GetByID(ctx, adjustment.ProductID)
 	if err != nil {
-		http.Error(w, "Product not found", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_not_found"))
 		return
 	}
 
-	// Reverse the stock adjustment
-	// If it was "add", we need to "reduce"
-	// If it was "reduce", we need to "add"
-	var reverseType models.StockAdjustmentType
-	if adjustment.AdjustmentType == models.AdjustmentTypeAdd {
-		reverseType = models.AdjustmentTypeReduce
+	if adjustment.SourceType == models.SourceTypeTransfer {
+		// Move the stock back to the bucket it came from
+		services.TransferStock(product, adjustment.ToStockType, adjustment.StockType, adjustment.Quantity)
 	} else {
-		reverseType = models.AdjustmentTypeAdd
-	}
+		// Reverse the stock adjustment
+		// If it was "add", we need to "reduce"
+		// If it was "reduce", we need to "add"
+		var reverseType models.StockAdjustmentType
+		if adjustment.AdjustmentType == models.AdjustmentTypeAdd {
+			reverseType = models.AdjustmentTypeReduce
+		} else {
+			reverseType = models.AdjustmentTypeAdd
+		}
 
-	// Apply reverse adjustment
-	ApplyStockAdjustment(product, reverseType, adjustment.StockType, adjustment.Quantity)
+		// Apply reverse adjustment
+		services.ApplyStockAdjustment(product, reverseType, adjustment.StockType, adjustment.Quantity)
+	}
 
 	// Update product
 	product.UpdatedAt = time.Now()
 	if err := h.productRepo.Update(ctx, product.ID.Hex(), product); err != nil {
-		http.Error(w, "Failed to update product stock", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "product_stock_update_failed"))
 		return
 	}
+	services.RefreshInventoryLevel(ctx, h.productRepo, product.Category)
 
 	// Delete the adjustment record
 	if err := h.adjustmentRepo.Delete(ctx, adjustmentID); err != nil {
-		http.Error(w, "Failed to delete stock adjustment", http.StatusInternalServerError)
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_adjustment_delete_failed"))
 		return
 	}
 
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// bulkTest is a stock adjustment handler on a test database with two products holding 10 VAT units each
+type bulkTest struct {
+	t        *testing.T
+	db       *mongo.Database
+	h        *StockAdjustmentHandler
+	products []*models.Product
+}
+
+func newBulkTest(t *testing.T) *bulkTest {
+	db := testDatabase(t)
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bt := &bulkTest{
+		t:  t,
+		db: db,
+		h:  NewStockAdjustmentHandler(repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")), productRepo),
+	}
+	for i := 1; i <= 2; i++ {
+		product := &models.Product{ID: primitive.NewObjectID(), SKUID: fmt.Sprintf("BOX-%04d", i), Name: "Kraft Box", Category: "Box"}
+		product.Stock.VAT = models.StockInfo{Purchased: 10, Remaining: 10}
+		product.Stock.ActualStock = 10
+		if _, err := db.Collection("products").InsertOne(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+		bt.products = append(bt.products, product)
+	}
+	return bt
+}
+
+// adjust sends a bulk adjustment adding quantities[i] VAT units to product ids[i]
+func (bt *bulkTest) adjust(ids []string, quantities []int) (int, models.BulkAdjustmentResult) {
+	var items []string
+	for i, id := range ids {
+		items = append(items, fmt.Sprintf(`{"productId": %q, "adjustmentType": "add", "stockType": "vat", "quantity": %d}`, id, quantities[i]))
+	}
+	body := `{"adjustments": [` + strings.Join(items, ",") + `]}`
+	rec := httptest.NewRecorder()
+	bt.h.BulkAdjustStock(rec, httptest.NewRequest(http.MethodPost, "/api/stock-adjustments/bulk", strings.NewReader(body)))
+
+	var result models.BulkAdjustmentResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		bt.t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	return rec.Code, result
+}
+
+// remaining returns the VAT stock remaining of each product
+func (bt *bulkTest) remaining() []int {
+	var stock []int
+	for _, product := range bt.products {
+		stored, err := bt.h.productRepo.GetByID(context.Background(), product.ID.Hex())
+		if err != nil {
+			bt.t.Fatal(err)
+		}
+		stock = append(stock, stored.Stock.VAT.Remaining)
+	}
+	return stock
+}
+
+// history returns how many stock history records were saved
+func (bt *bulkTest) history() int64 {
+	count, err := bt.db.Collection("stock_adjustments").CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		bt.t.Fatal(err)
+	}
+	return count
+}
+
+func TestBulkAdjustStockAppliesEveryAdjustment(t *testing.T) {
+	bt := newBulkTest(t)
+	first, second := bt.products[0].ID.Hex(), bt.products[1].SKUID
+
+	status, result := bt.adjust([]string{first, second, first}, []int{5, 3, 2})
+	if status != http.StatusOK || result.SuccessCount != 3 || result.FailedCount != 0 {
+		t.Fatalf("got %d with %+v, want 200 and 3 applied", status, result)
+	}
+	if len(result.UpdatedProducts) != 2 {
+		t.Errorf("%d updated products, want 2", len(result.UpdatedProducts))
+	}
+	if got := bt.remaining(); got[0] != 17 || got[1] != 13 {
+		t.Errorf("remaining = %v, want [17 13]", got)
+	}
+	if got := bt.history(); got != 3 {
+		t.Errorf("%d history records, want 3", got)
+	}
+}
+
+func TestBulkAdjustStockRejectsAllWhenOneProductIsUnknown(t *testing.T) {
+	bt := newBulkTest(t)
+
+	status, result := bt.adjust([]string{bt.products[0].ID.Hex(), primitive.NewObjectID().Hex()}, []int{5, 3})
+	if status != http.StatusBadRequest || result.FailedCount != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "adjustment 2") {
+		t.Fatalf("got %d with %+v, want 400 naming adjustment 2", status, result)
+	}
+	if got := bt.remaining(); got[0] != 10 || got[1] != 10 {
+		t.Errorf("remaining = %v, want nothing applied", got)
+	}
+	if got := bt.history(); got != 0 {
+		t.Errorf("%d history records, want none", got)
+	}
+}
+
+func TestBulkAdjustStockRollsBackWhenAWriteFails(t *testing.T) {
+	bt := newBulkTest(t)
+	var hello bson.M
+	if err := bt.db.Client().Database("admin").RunCommand(context.Background(), bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := hello["setName"]; !ok {
+		t.Skip("MongoDB is not a replica set, so bulk adjustments run without a transaction")
+	}
+
+	// One history record per product is allowed, so the third adjustment fails after two were written
+	_, err := bt.db.Collection("stock_adjustments").Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "productId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := bt.products[0].ID.Hex(), bt.products[1].ID.Hex()
+	status, result := bt.adjust([]string{first, second, first}, []int{5, 3, 2})
+	if status != http.StatusInternalServerError || result.SuccessCount != 0 || result.FailedCount != 3 {
+		t.Fatalf("got %d with %+v, want 500 and nothing applied", status, result)
+	}
+	if got := bt.remaining(); got[0] != 10 || got[1] != 10 {
+		t.Errorf("remaining = %v, want the written adjustments rolled back", got)
+	}
+	if got := bt.history(); got != 0 {
+		t.Errorf("%d history records, want none", got)
+	}
+}
+
+// setActualStock overwrites a product's actual stock so it no longer matches its VAT + Non-VAT remaining
+func (bt *bulkTest) setActualStock(product *models.Product, actualStock int) {
+	_, err := bt.db.Collection("products").UpdateByID(context.Background(), product.ID, bson.M{"$set": bson.M{"stock.actualStock": actualStock}})
+	if err != nil {
+		bt.t.Fatal(err)
+	}
+}
+
+func (bt *bulkTest) discrepancies() []models.StockDiscrepancy {
+	rec := httptest.NewRecorder()
+	bt.h.GetStockDiscrepancies(rec, httptest.NewRequest(http.MethodGet, "/api/products/stock-discrepancies", nil))
+	if rec.Code != http.StatusOK {
+		bt.t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var discrepancies []models.StockDiscrepancy
+	if err := json.Unmarshal(rec.Body.Bytes(), &discrepancies); err != nil {
+		bt.t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	return discrepancies
+}
+
+func (bt *bulkTest) reconcile(product *models.Product) models.Product {
+	req := httptest.NewRequest(http.MethodPost, "/api/products/"+product.ID.Hex()+"/reconcile-stock", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": product.ID.Hex()})
+	rec := httptest.NewRecorder()
+	bt.h.ReconcileStock(rec, req)
+	if rec.Code != http.StatusOK {
+		bt.t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var reconciled models.Product
+	if err := json.Unmarshal(rec.Body.Bytes(), &reconciled); err != nil {
+		bt.t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	return reconciled
+}
+
+func TestGetStockDiscrepancies(t *testing.T) {
+	bt := newBulkTest(t)
+
+	if got := bt.discrepancies(); len(got) != 0 {
+		t.Fatalf("discrepancies = %+v, want none while every product agrees", got)
+	}
+
+	bt.setActualStock(bt.products[1], 7)
+	want := models.StockDiscrepancy{
+		ProductID:     bt.products[1].ID.Hex(),
+		SKUID:         bt.products[1].SKUID,
+		Name:          bt.products[1].Name,
+		ActualStock:   7,
+		ComputedStock: 10,
+		Discrepancy:   -3,
+	}
+	if got := bt.discrepancies(); len(got) != 1 || got[0] != want {
+		t.Errorf("discrepancies = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestReconcileStock(t *testing.T) {
+	tests := []struct {
+		name        string
+		actualStock int
+		wantType    models.StockAdjustmentType
+		wantHistory int64
+	}{
+		{"actual stock above remaining", 13, models.AdjustmentTypeReduce, 1},
+		{"actual stock below remaining", 6, models.AdjustmentTypeAdd, 1},
+		{"no discrepancy", 10, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bt := newBulkTest(t)
+			product := bt.products[0]
+			bt.setActualStock(product, tt.actualStock)
+
+			reconciled := bt.reconcile(product)
+			if reconciled.Stock.ActualStock != 10 || reconciled.Stock.VAT.Remaining != 10 {
+				t.Errorf("stock = %+v, want actual stock 10 with VAT remaining unchanged", reconciled.Stock)
+			}
+			stored, err := bt.h.productRepo.GetByID(context.Background(), product.ID.Hex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Stock.ActualStock != 10 {
+				t.Errorf("stored actual stock = %d, want 10", stored.Stock.ActualStock)
+			}
+
+			if got := bt.history(); got != tt.wantHistory {
+				t.Fatalf("%d history records, want %d", got, tt.wantHistory)
+			}
+			if tt.wantHistory == 0 {
+				return
+			}
+			var adjustment models.StockAdjustment
+			if err := bt.db.Collection("stock_adjustments").FindOne(context.Background(), bson.M{}).Decode(&adjustment); err != nil {
+				t.Fatal(err)
+			}
+			wantQuantity := tt.actualStock - 10
+			if wantQuantity < 0 {
+				wantQuantity = -wantQuantity
+			}
+			if adjustment.SourceType != models.SourceTypeReconciliation || adjustment.AdjustmentType != tt.wantType ||
+				adjustment.StockType != models.StockTypeActualStock || adjustment.Quantity != wantQuantity {
+				t.Errorf("adjustment = %s %s %d of %s, want reconciliation %s %d of %s", adjustment.SourceType, adjustment.AdjustmentType,
+					adjustment.Quantity, adjustment.StockType, tt.wantType, wantQuantity, models.StockTypeActualStock)
+			}
+			if len(bt.discrepancies()) != 0 {
+				t.Errorf("product still listed as a discrepancy")
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+type StockCountHandler struct {
+	stockCountRepo *repository.StockCountRepository
+	productRepo    *repository.ProductRepository
+	adjustmentRepo *repository.StockAdjustmentRepository
+}
+
+func NewStockCountHandler(stockCountRepo *repository.StockCountRepository, productRepo *repository.ProductRepository, adjustmentRepo *repository.StockAdjustmentRepository) *StockCountHandler {
+	return &StockCountHandler{
+		stockCountRepo: stockCountRepo,
+		productRepo:    productRepo,
+		adjustmentRepo: adjustmentRepo,
+	}
+}
+
+func (h *StockCountHandler) GetStockCounts(w http.ResponseWriter, r *http.Request) {
+	stockCounts, err := h.stockCountRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_counts_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCounts)
+}
+
+func (h *StockCountHandler) GetStockCount(w http.ResponseWriter, r *http.Request) {
+	stockCount, err := h.stockCountRepo.GetByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "stock_count_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCount)
+}
+
+// CreateStockCount starts a count, taking each product's current actual stock as its system quantity
+func (h *StockCountHandler) CreateStockCount(w http.ResponseWriter, r *http.Request) {
+	// The body is optional
+	var req models.StockCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &req) {
+		return
+	}
+
+	products, err := h.productRepo.GetAll(r.Context(), nil)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "products_fetch_failed"))
+		return
+	}
+
+	stockCount := models.NewStockCount(products, req.Notes)
+	if err := h.stockCountRepo.Create(r.Context(), stockCount); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_count_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stockCount)
+}
+
+// RecordCountedQuantity records the quantity counted for one product; counting it again replaces the quantity
+func (h *StockCountHandler) RecordCountedQuantity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.StockCountItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &req) {
+		return
+	}
+
+	stockCount, err := h.stockCountRepo.GetByID(r.Context(), vars["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "stock_count_not_found"))
+		return
+	}
+	if stockCount.Status == models.StockCountStatusCompleted {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "stock_count_completed"))
+		return
+	}
+
+	if !stockCount.RecordCount(vars["productId"], *req.CountedQty) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "stock_count_item_not_found"))
+		return
+	}
+	if err := h.stockCountRepo.Update(r.Context(), vars["id"], stockCount); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "stock_count_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCount)
+}
+
+// CompleteStockCount adjusts the actual stock of every counted product by its variance and marks the count
+// completed, all in one MongoDB transaction. Adjusting by the variance rather than setting the counted
+// quantity keeps sales and purchases made while the count was in progress. Uncounted products are unchanged.
+func (h *StockCountHandler) CompleteStockCount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	stockCount, err := h.stockCountRepo.GetByID(ctx, id)
+	if err != nil {
+		RespondWithError(w, localisedError(ctx, http.StatusNotFound, "stock_count_not_found"))
+		return
+	}
+	if stockCount.Status == models.StockCountStatusCompleted {
+		RespondWithError(w, localisedError(ctx, http.StatusConflict, "stock_count_completed"))
+		return
+	}
+
+	var categories []string
+	err = h.adjustmentRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		categories = nil
+		for _, item := range stockCount.VarianceItems() {
+			category, err := h.adjustForVariance(txCtx, stockCount, item)
+			if err != nil {
+				return fmt.Errorf("product %s: %w", item.ProductID, err)
+			}
+			if category != "" {
+				categories = append(categories, category)
+			}
+		}
+
+		stockCount.Complete()
+		return h.stockCountRepo.Update(txCtx, id, stockCount)
+	})
+	if err != nil {
+		fmt.Printf("Error completing stock count %s: %v\n", stockCount.CountID, err)
+		RespondWithError(w, localisedError(ctx, http.StatusInternalServerError, "stock_count_complete_failed"))
+		return
+	}
+
+	refreshed := make(map[string]bool)
+	for _, category := range categories {
+		if !refreshed[category] {
+			refreshed[category] = true
+			services.RefreshInventoryLevel(ctx, h.productRepo, category)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCount)
+}
+
+// adjustForVariance applies a counted item's variance to the product's actual stock and records it in the
+// stock history, returning the product's category. Products deleted since the count started are skipped.
+func (h *StockCountHandler) adjustForVariance(ctx context.Context, stockCount *models.StockCount, item models.StockCountItem) (string, error) {
+	product, err := h.productRepo.GetByID(ctx, item.ProductID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		fmt.Printf("Warning: Product %s was deleted during stock count %s, skipping its variance\n", item.ProductID, stockCount.CountID)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	req := models.StockAdjustmentRequest{
+		AdjustmentType: models.AdjustmentTypeAdd,
+		StockType:      models.StockTypeActualStock,
+		Quantity:       item.Variance,
+	}
+	if item.Variance < 0 {
+		req.AdjustmentType = models.AdjustmentTypeReduce
+		req.Quantity = -item.Variance
+	}
+	notes := fmt.Sprintf("Stock count %s: counted %d, system %d", stockCount.CountID, *item.CountedQty, item.SystemQty)
+	req.Notes = &notes
+
+	sourceID := stockCount.ID.Hex()
+	adjustment := req.ToStockAdjustment(product, models.SourceTypeStockCount, &sourceID, &stockCount.CountID)
+	services.ApplyStockAdjustment(product, req.AdjustmentType, req.StockType, req.Quantity)
+
+	product.UpdatedAt = time.Now()
+	if err := h.productRepo.Update(ctx, product.ID.Hex(), product); err != nil {
+		return "", err
+	}
+
+	adjustment.SetAfterValues(product)
+	if err := h.adjustmentRepo.Create(ctx, adjustment); err != nil {
+		return "", err
+	}
+	return product.Category, nil
+}
+
+// GetVarianceReport lists the counted products whose counted quantity differs from the system stock
+func (h *StockCountHandler) GetVarianceReport(w http.ResponseWriter, r *http.Request) {
+	stockCount, err := h.stockCountRepo.GetByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "stock_count_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stockCount.VarianceReport())
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// stockCountRequest runs handler with the body and path variables and decodes a successful response into result
+func stockCountRequest(t *testing.T, handler http.HandlerFunc, body string, vars map[string]string, result interface{}) int {
+	t.Helper()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/stock-counts", strings.NewReader(body)), vars)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if result != nil && rec.Code < 300 {
+		if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code
+}
+
+func TestStockCountWorkflow(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewStockCountHandler(
+		repository.NewStockCountRepository(db.Collection("stock_counts")),
+		productRepo,
+		repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")),
+	)
+
+	ids := make([]string, 3)
+	for i, stock := range []int{10, 5, 7} {
+		product := &models.Product{ID: primitive.NewObjectID(), SKUID: fmt.Sprintf("BOX-%04d", i+1), Name: "Box", Category: "Box"}
+		product.Stock.ActualStock = stock
+		if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = product.ID.Hex()
+	}
+
+	var count models.StockCount
+	if status := stockCountRequest(t, h.CreateStockCount, "", nil, &count); status != http.StatusCreated {
+		t.Fatalf("start: status = %d, want %d", status, http.StatusCreated)
+	}
+	if count.Status != models.StockCountStatusDraft || len(count.Items) != 3 {
+		t.Fatalf("started count = %s with %d items, want a draft of 3", count.Status, len(count.Items))
+	}
+	countID := count.ID.Hex()
+
+	// The first product is short by 2, the second over by 4 and the third is not counted
+	record := func(productID, body string) int {
+		return stockCountRequest(t, h.RecordCountedQuantity, body, map[string]string{"id": countID, "productId": productID}, nil)
+	}
+	for _, tt := range []struct {
+		productID, body string
+		want            int
+	}{
+		{ids[0], `{"countedQty": 8}`, http.StatusOK},
+		{ids[1], `{"countedQty": 9}`, http.StatusOK},
+		{ids[2], `{"countedQty": -1}`, http.StatusBadRequest},
+		{primitive.NewObjectID().Hex(), `{"countedQty": 1}`, http.StatusNotFound},
+	} {
+		if status := record(tt.productID, tt.body); status != tt.want {
+			t.Errorf("record %s %s: status = %d, want %d", tt.productID, tt.body, status, tt.want)
+		}
+	}
+
+	var report models.StockCountVarianceReport
+	if status := stockCountRequest(t, h.GetVarianceReport, "", map[string]string{"id": countID}, &report); status != http.StatusOK {
+		t.Fatalf("variance report: status = %d, want %d", status, http.StatusOK)
+	}
+	if report.Status != models.StockCountStatusInProgress || report.CountedItems != 2 || report.TotalVariance != 2 || len(report.Items) != 2 {
+		t.Errorf("variance report = %+v, want 2 counted in progress with a total variance of 2", report)
+	}
+
+	if status := stockCountRequest(t, h.CompleteStockCount, "", map[string]string{"id": countID}, &count); status != http.StatusOK {
+		t.Fatalf("complete: status = %d, want %d", status, http.StatusOK)
+	}
+	if count.Status != models.StockCountStatusCompleted || count.CompletedAt == nil {
+		t.Errorf("completed count = %s at %v, want completed", count.Status, count.CompletedAt)
+	}
+
+	for i, want := range []int{8, 9, 7} {
+		product, err := productRepo.GetByID(ctx, ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.Stock.ActualStock != want {
+			t.Errorf("product %d actual stock = %d, want %d", i+1, product.Stock.ActualStock, want)
+		}
+	}
+	adjustments, err := db.Collection("stock_adjustments").CountDocuments(ctx, bson.M{"sourceType": models.SourceTypeStockCount, "sourceId": countID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adjustments != 2 {
+		t.Errorf("%d stock adjustments, want one per variance", adjustments)
+	}
+
+	// A completed count is closed
+	if status := record(ids[2], `{"countedQty": 7}`); status != http.StatusConflict {
+		t.Errorf("record after completion: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status := stockCountRequest(t, h.CompleteStockCount, "", map[string]string{"id": countID}, nil); status != http.StatusConflict {
+		t.Errorf("complete again: status = %d, want %d", status, http.StatusConflict)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+type SupplierHandler struct {
+	supplierRepo *repository.SupplierRepository
+	purchaseRepo *repository.PurchaseRepository
+}
+
+func NewSupplierHandler(supplierRepo *repository.SupplierRepository, purchaseRepo *repository.PurchaseRepository) *SupplierHandler {
+	return &SupplierHandler{
+		supplierRepo: supplierRepo,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+func (h *SupplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := h.supplierRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "suppliers_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suppliers)
+}
+
+func (h *SupplierHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	supplier, err := h.supplierRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "supplier_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var supplierRequest models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&supplierRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+
+	supplier := supplierRequest.ToSupplier()
+	if err := h.supplierRepo.Create(r.Context(), supplier); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(supplier)
+}
+
+func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var supplierRequest models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&supplierRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+
+	supplier, err := h.supplierRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "supplier_not_found"))
+		return
+	}
+
+	supplier.UpdateFromRequest(&supplierRequest)
+	if err := h.supplierRepo.Update(r.Context(), id, supplier); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+func (h *SupplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.supplierRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSupplierPurchases lists all purchases made from a supplier
+func (h *SupplierHandler) GetSupplierPurchases(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if _, err := h.supplierRepo.GetByID(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "supplier_not_found"))
+		return
+	}
+
+	purchases, err := h.purchaseRepo.GetBySupplierID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_purchases_fetch_failed"))
+		return
+	}
+	if purchases == nil {
+		purchases = []*models.Purchase{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchases)
+}
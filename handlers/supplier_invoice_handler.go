@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// supplierInvoiceStatuses are the statuses GetSupplierInvoices can filter by
+var supplierInvoiceStatuses = []string{
+	models.SupplierInvoiceStatusUnmatched,
+	models.SupplierInvoiceStatusPartiallyMatched,
+	models.SupplierInvoiceStatusMatched,
+}
+
+type SupplierInvoiceHandler struct {
+	supplierInvoiceRepo   *repository.SupplierInvoiceRepository
+	supplierRepo          *repository.SupplierRepository
+	purchaseRepo          *repository.PurchaseRepository
+	matchTolerancePercent float64
+}
+
+func NewSupplierInvoiceHandler(supplierInvoiceRepo *repository.SupplierInvoiceRepository, supplierRepo *repository.SupplierRepository, purchaseRepo *repository.PurchaseRepository, matchTolerancePercent float64) *SupplierInvoiceHandler {
+	return &SupplierInvoiceHandler{
+		supplierInvoiceRepo:   supplierInvoiceRepo,
+		supplierRepo:          supplierRepo,
+		purchaseRepo:          purchaseRepo,
+		matchTolerancePercent: matchTolerancePercent,
+	}
+}
+
+// GetSupplierInvoices lists the supplier invoices, newest first, optionally only those of ?status=
+func (h *SupplierInvoiceHandler) GetSupplierInvoices(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(supplierInvoiceStatuses, status) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_supplier_invoice_status"))
+		return
+	}
+
+	invoices, err := h.supplierInvoiceRepo.GetAll(r.Context(), status)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_invoices_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoices)
+}
+
+func (h *SupplierInvoiceHandler) GetSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	invoice, err := h.supplierInvoiceRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "supplier_invoice_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// CreateSupplierInvoice records an invoice received from a supplier, unmatched until purchases are linked to it
+func (h *SupplierInvoiceHandler) CreateSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	var invoiceRequest models.SupplierInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&invoiceRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &invoiceRequest) {
+		return
+	}
+	if invoiceRequest.InvoiceDate.IsZero() {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invoice_date_required"))
+		return
+	}
+
+	supplier, err := h.supplierRepo.GetByID(r.Context(), invoiceRequest.SupplierID)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "supplier_not_found"))
+		return
+	}
+	if _, err := h.supplierInvoiceRepo.GetBySupplierAndNumber(r.Context(), invoiceRequest.SupplierID, invoiceRequest.InvoiceNumber); err == nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "supplier_invoice_exists"))
+		return
+	}
+
+	invoice := invoiceRequest.ToSupplierInvoice()
+	if invoice.TotalAmount <= 0 {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "supplier_invoice_total_required"))
+		return
+	}
+	invoice.SupplierName = supplier.CompanyName
+
+	if err := h.supplierInvoiceRepo.Create(r.Context(), invoice); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_invoice_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// MatchSupplierInvoice links purchases of the invoice's supplier to the invoice, in addition to those already
+// linked. The invoice becomes matched when the linked purchases' grand totals are within the configured tolerance
+// of the invoice total; linking purchases that take them over it is rejected with 422.
+func (h *SupplierInvoiceHandler) MatchSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var matchRequest models.SupplierInvoiceMatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&matchRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &matchRequest) {
+		return
+	}
+
+	invoice, err := h.supplierInvoiceRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "supplier_invoice_not_found"))
+		return
+	}
+
+	var purchases []*models.Purchase
+	var newIDs []string
+	for _, purchaseID := range invoice.MatchedPurchaseIDs {
+		// Purchases deleted since they were matched no longer count
+		if purchase, err := h.purchaseRepo.GetByID(r.Context(), purchaseID); err == nil {
+			purchases = append(purchases, purchase)
+		}
+	}
+	for _, purchaseID := range matchRequest.PurchaseIDs {
+		if slices.Contains(invoice.MatchedPurchaseIDs, purchaseID) || slices.Contains(newIDs, purchaseID) {
+			continue
+		}
+		purchase, err := h.purchaseRepo.GetByID(r.Context(), purchaseID)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "purchase_not_found_id", purchaseID))
+			return
+		}
+		if purchase.SupplierID == nil || *purchase.SupplierID != invoice.SupplierID {
+			RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "purchase_wrong_supplier", purchase.PurchaseCode))
+			return
+		}
+		purchases = append(purchases, purchase)
+		newIDs = append(newIDs, purchaseID)
+	}
+
+	if len(newIDs) > 0 {
+		matched, err := h.supplierInvoiceRepo.GetMatchedToPurchases(r.Context(), newIDs, invoice.ID)
+		if err != nil {
+			RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_invoice_match_failed"))
+			return
+		}
+		if len(matched) > 0 {
+			RespondWithError(w, localisedError(r.Context(), http.StatusConflict, "purchase_already_matched", matched[0].InvoiceNumber))
+			return
+		}
+	}
+
+	if err := invoice.Match(purchases, h.matchTolerancePercent); errors.Is(err, models.ErrInvoiceOverMatched) {
+		var total float64
+		for _, purchase := range purchases {
+			total += purchase.GrandTotal
+		}
+		RespondWithError(w, localisedError(r.Context(), http.StatusUnprocessableEntity, "supplier_invoice_over_matched", total, invoice.TotalAmount, h.matchTolerancePercent))
+		return
+	}
+
+	if err := h.supplierInvoiceRepo.Update(r.Context(), id, invoice); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "supplier_invoice_match_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
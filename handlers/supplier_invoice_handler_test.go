@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+func TestMatchSupplierInvoice(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	h := NewSupplierInvoiceHandler(
+		repository.NewSupplierInvoiceRepository(db.Collection("supplier_invoices")),
+		repository.NewSupplierRepository(db.Collection("suppliers")),
+		repository.NewPurchaseRepository(db.Collection("purchases")),
+		1,
+	)
+
+	supplier := &models.Supplier{ID: primitive.NewObjectID(), CompanyName: "Box Factory"}
+	other := &models.Supplier{ID: primitive.NewObjectID(), CompanyName: "Wrap Co"}
+	for _, s := range []*models.Supplier{supplier, other} {
+		if _, err := db.Collection("suppliers").InsertOne(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// purchase stores a purchase from s with the grand total and returns its ID
+	purchase := func(s *models.Supplier, grandTotal float64) string {
+		supplierID := s.ID.Hex()
+		p := &models.Purchase{ID: primitive.NewObjectID(), PurchaseCode: fmt.Sprintf("PUR-VAT-6701-%04.0f", grandTotal), SupplierID: &supplierID, GrandTotal: grandTotal}
+		if _, err := db.Collection("purchases").InsertOne(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		return p.ID.Hex()
+	}
+	// createInvoice records an invoice of the supplier for total and returns its ID
+	createInvoice := func(number string, total float64) string {
+		body := fmt.Sprintf(`{"invoiceNumber": %q, "supplierId": %q, "invoiceDate": "2024-01-31", "totalAmount": %v}`, number, supplier.ID.Hex(), total)
+		rec := httptest.NewRecorder()
+		h.CreateSupplierInvoice(rec, httptest.NewRequest(http.MethodPost, "/api/supplier-invoices", strings.NewReader(body)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, want %d: %s", number, rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var invoice models.SupplierInvoice
+		if err := json.NewDecoder(rec.Body).Decode(&invoice); err != nil {
+			t.Fatal(err)
+		}
+		return invoice.ID.Hex()
+	}
+	// match links the purchases to the invoice and returns the status code and the invoice's status
+	match := func(invoiceID string, purchaseIDs ...string) (int, string) {
+		ids, _ := json.Marshal(purchaseIDs)
+		req := httptest.NewRequest(http.MethodPost, "/api/supplier-invoices/"+invoiceID+"/match", strings.NewReader(fmt.Sprintf(`{"purchaseIds": %s}`, ids)))
+		rec := httptest.NewRecorder()
+		h.MatchSupplierInvoice(rec, mux.SetURLVars(req, map[string]string{"id": invoiceID}))
+		invoice, err := h.supplierInvoiceRepo.GetByID(ctx, invoiceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, invoice.Status
+	}
+
+	t.Run("exact match", func(t *testing.T) {
+		invoiceID := createInvoice("BF-001", 1000)
+		if code, status := match(invoiceID, purchase(supplier, 600)); code != http.StatusOK || status != models.SupplierInvoiceStatusPartiallyMatched {
+			t.Errorf("first purchase: %d %s, want 200 partially_matched", code, status)
+		}
+		if code, status := match(invoiceID, purchase(supplier, 400)); code != http.StatusOK || status != models.SupplierInvoiceStatusMatched {
+			t.Errorf("second purchase: %d %s, want 200 matched", code, status)
+		}
+	})
+
+	t.Run("within tolerance", func(t *testing.T) {
+		invoiceID := createInvoice("BF-002", 1000)
+		if code, status := match(invoiceID, purchase(supplier, 1009.5)); code != http.StatusOK || status != models.SupplierInvoiceStatusMatched {
+			t.Errorf("got %d %s, want 200 matched within 1%%", code, status)
+		}
+	})
+
+	t.Run("over-match rejected", func(t *testing.T) {
+		invoiceID := createInvoice("BF-003", 1000)
+		if code, status := match(invoiceID, purchase(supplier, 700), purchase(supplier, 320)); code != http.StatusUnprocessableEntity || status != models.SupplierInvoiceStatusUnmatched {
+			t.Errorf("got %d %s, want 422 and the invoice left unmatched", code, status)
+		}
+		if code, _ := match(invoiceID, purchase(other, 100)); code != http.StatusUnprocessableEntity {
+			t.Errorf("purchase from another supplier: %d, want 422", code)
+		}
+	})
+
+	t.Run("purchase matched twice", func(t *testing.T) {
+		purchaseID := purchase(supplier, 500)
+		if code, _ := match(createInvoice("BF-004", 500), purchaseID); code != http.StatusOK {
+			t.Fatalf("first invoice: %d, want 200", code)
+		}
+		if code, _ := match(createInvoice("BF-005", 500), purchaseID); code != http.StatusConflict {
+			t.Errorf("second invoice: %d, want 409", code)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	h.GetSupplierInvoices(rec, httptest.NewRequest(http.MethodGet, "/api/supplier-invoices?status=unmatched", nil))
+	var unmatched []*models.SupplierInvoice
+	if err := json.NewDecoder(rec.Body).Decode(&unmatched); err != nil {
+		t.Fatal(err)
+	}
+	var numbers []string
+	for _, invoice := range unmatched {
+		numbers = append(numbers, invoice.InvoiceNumber)
+	}
+	if strings.Join(numbers, ",") != "BF-005,BF-003" { // newest first
+		t.Errorf("unmatched invoices = %v, want BF-005 and BF-003", numbers)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"goodpack-server/utils"
+	"goodpack-server/validation"
+)
+
+// Fields each list endpoint can be sorted by with sortBy
+var (
+	productSortFields   = []string{"name", "skuId", "category", "stock.actualStock", "price.saleVAT.latest", "popularity", "createdAt", "updatedAt"}
+	customerSortFields  = []string{"customerCode", "companyName", "contactName", "outstandingBalance", "createdAt", "updatedAt"}
+	saleSortFields      = []string{"saleCode", "saleDate", "customerName", "createdAt", "updatedAt"}
+	purchaseSortFields  = []string{"purchaseCode", "purchaseDate", "customerName", "totalAmount", "grandTotal", "createdAt", "updatedAt"}
+	quotationSortFields = []string{"quotationCode", "quotationDate", "customerName", "status", "validUntil", "createdAt", "updatedAt"}
+)
+
+// validateRequest checks req against its validate tags; if it is invalid a 400 with the
+// list of field errors is written and false is returned
+func validateRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	err := validation.Validate(req)
+	if err == nil {
+		return true
+	}
+
+	var fieldErrs validation.Errors
+	if !errors.As(err, &fieldErrs) {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(fieldErrs)
+	return false
+}
+
+// parseSort reads the sortBy and sortOrder query parameters; if they are invalid a 400 is written
+// and false is returned
+func parseSort(w http.ResponseWriter, r *http.Request, allowedFields []string) (bson.D, bool) {
+	sort, err := utils.ParseSortOptions(r, allowedFields)
+	return checkSort(w, r, sort, err)
+}
+
+// parseSortBy is parseSort for a list sorted by defaultField unless sortBy is given; see utils.ParseSortOptionsBy
+func parseSortBy(w http.ResponseWriter, r *http.Request, allowedFields []string, defaultField string) (bson.D, bool) {
+	sort, err := utils.ParseSortOptionsBy(r, allowedFields, defaultField)
+	return checkSort(w, r, sort, err)
+}
+
+// checkSort writes the 400 for a sort that could not be parsed
+func checkSort(w http.ResponseWriter, r *http.Request, sort bson.D, err error) (bson.D, bool) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidSortOrder):
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sort_order"))
+		return nil, false
+	case err != nil:
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_sort_field"))
+		return nil, false
+	}
+	return sort, true
+}
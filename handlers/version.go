@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goodpack-server/apierrors"
+)
+
+// versionConflict is the body of a 409 for an update made against an outdated version
+type versionConflict struct {
+	*apierrors.AppError
+	CurrentVersion int `json:"currentVersion"`
+}
+
+// checkVersion makes sure an update was made against the document's current version: without a version a 400
+// is written, with an outdated one a 409 carrying the current version; either way false is returned
+func checkVersion(w http.ResponseWriter, r *http.Request, requested *int, current int) bool {
+	if requested == nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "version_required"))
+		return false
+	}
+	if *requested != current {
+		writeVersionConflict(w, r, current)
+		return false
+	}
+	return true
+}
+
+// writeVersionConflict writes a 409 with the document's current version, so the client can reload it and retry
+func writeVersionConflict(w http.ResponseWriter, r *http.Request, currentVersion int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(versionConflict{
+		AppError:       localisedError(r.Context(), http.StatusConflict, "version_conflict"),
+		CurrentVersion: currentVersion,
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+// defaultWebhookDeliveryLimit is how many delivery attempts are listed when limit is not given
+const defaultWebhookDeliveryLimit = 50
+
+type WebhookHandler struct {
+	webhookRepo *repository.WebhookRepository
+}
+
+func NewWebhookHandler(webhookRepo *repository.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo: webhookRepo,
+	}
+}
+
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.webhookRepo.GetAll(r.Context())
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "webhooks_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	webhook, err := h.webhookRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "webhook_not_found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookRequest models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &webhookRequest) {
+		return
+	}
+
+	webhook := webhookRequest.ToWebhook()
+	if err := h.webhookRepo.Create(r.Context(), webhook); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "webhook_create_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var webhookRequest models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookRequest); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusBadRequest, "invalid_request_body"))
+		return
+	}
+	if !validateRequest(w, r, &webhookRequest) {
+		return
+	}
+
+	webhook, err := h.webhookRepo.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "webhook_not_found"))
+		return
+	}
+
+	webhook.UpdateFromRequest(&webhookRequest)
+	if err := h.webhookRepo.Update(r.Context(), id, webhook); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "webhook_update_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.webhookRepo.Delete(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "webhook_delete_failed"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists a webhook's delivery attempts, newest first
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	limit := defaultWebhookDeliveryLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	if _, err := h.webhookRepo.GetByID(r.Context(), id); err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusNotFound, "webhook_not_found"))
+		return
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveries(r.Context(), id, limit)
+	if err != nil {
+		RespondWithError(w, localisedError(r.Context(), http.StatusInternalServerError, "webhook_deliveries_fetch_failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// dispatchWebhook sends an event to the subscribed webhooks; a failure is logged rather than failing the request
+func dispatchWebhook(webhookService *services.WebhookService, event string, payload interface{}) {
+	if webhookService == nil {
+		return
+	}
+	if err := webhookService.Dispatch(context.Background(), event, payload); err != nil {
+		fmt.Printf("Warning: Failed to dispatch %s webhooks: %v\n", event, err)
+	}
+}
@@ -0,0 +1,60 @@
+package locales
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// Supported locales
+const (
+	English = "en"
+	Thai    = "th"
+
+	Default = English
+)
+
+//go:embed *.json
+var files embed.FS
+
+// messages maps locale -> error code -> message
+var messages = load()
+
+func load() map[string]map[string]string {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]map[string]string)
+	for _, entry := range entries {
+		data, err := files.ReadFile(entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic("locales: invalid " + entry.Name() + ": " + err.Error())
+		}
+		loaded[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = catalog
+	}
+	return loaded
+}
+
+// IsSupported reports whether there is a message catalog for locale
+func IsSupported(locale string) bool {
+	_, ok := messages[locale]
+	return ok
+}
+
+// Message returns the message for code in locale, falling back to English and then to the code itself
+func Message(locale, code string) string {
+	if message, ok := messages[locale][code]; ok {
+		return message
+	}
+	if message, ok := messages[Default][code]; ok {
+		return message
+	}
+	return code
+}
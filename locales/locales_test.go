@@ -0,0 +1,48 @@
+package locales
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestCatalogsHaveTheSameCodes(t *testing.T) {
+	for locale, catalog := range messages {
+		for code := range catalog {
+			if _, ok := messages[Default][code]; !ok {
+				t.Errorf("%s: %q has no %s message", locale, code, Default)
+			}
+		}
+		for code := range messages[Default] {
+			if _, ok := catalog[code]; !ok {
+				t.Errorf("%s: missing a message for %q", locale, code)
+			}
+		}
+	}
+}
+
+func TestMessageFallback(t *testing.T) {
+	if !IsSupported(Thai) || IsSupported("fr") {
+		t.Fatal("expected th to be supported and fr not")
+	}
+	if got, want := Message("fr", "abc_analysis_failed"), Message(English, "abc_analysis_failed"); got != want {
+		t.Errorf("Message for an unsupported locale = %q, want the English %q", got, want)
+	}
+	if got := Message(Thai, "no_such_code"); got != "no_such_code" {
+		t.Errorf("Message for an unknown code = %q, want the code itself", got)
+	}
+}
+
+// verb matches the fmt verbs of a message with parameters
+var verb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogsUseTheSameParameters(t *testing.T) {
+	for locale, catalog := range messages {
+		for code, message := range catalog {
+			got, want := verb.FindAllString(message, -1), verb.FindAllString(messages[Default][code], -1)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: %q has parameters %v, want %v as in %s", locale, code, got, want, Default)
+			}
+		}
+	}
+}
@@ -1,44 +1,229 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"goodpack-server/config"
 	"goodpack-server/database"
+	"goodpack-server/handlers"
+	"goodpack-server/middleware"
+	"goodpack-server/models"
 	"goodpack-server/repository"
 	"goodpack-server/routes"
+	"goodpack-server/scheduler"
+	"goodpack-server/services"
+	"goodpack-server/storage"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	models.VATRate = cfg.VATRate
+	models.QRDataFormat = cfg.QRDataFormat
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Connect to MongoDB
-	mongoDB, err := database.NewMongoDB(cfg.MongoURI, cfg.Database)
+	mongoDB, err := database.NewMongoDB(cfg.MongoURI, cfg.Database,
+		database.WithConnectTimeout(cfg.MongoConnectTimeout),
+		database.WithMaxRetries(cfg.MongoMaxRetries),
+		database.WithMaxPoolSize(cfg.MongoMaxPoolSize),
+	)
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
 	defer mongoDB.Close()
 
+	// Ensure indexes
+	database.AuditLogTTL = time.Duration(cfg.AuditLogTTLDays) * 24 * time.Hour
+	indexCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := database.EnsureIndexes(indexCtx, mongoDB.Database); err != nil {
+		log.Printf("Warning: Failed to ensure MongoDB indexes: %v", err)
+	}
+	cancel()
+
+	// Categories live in the database once seeded from categories.json, which stays the fallback
+	categoryRepo := repository.NewCategoryRepository(mongoDB.GetCollection("categories"))
+	configLoader := config.DefaultLoader()
+	seedCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	seeded, err := categoryRepo.SeedFromConfig(seedCtx, configLoader.GetCategories())
+	cancel()
+	if err != nil {
+		log.Printf("Warning: Failed to seed categories, using categories.json: %v", err)
+	} else {
+		if seeded > 0 {
+			log.Printf("Seeded %d categories from categories.json", seeded)
+		}
+		configLoader.SetCategorySource(categoryRepo)
+	}
+
 	// Initialize repositories
-	productRepo := repository.NewProductRepository(mongoDB.GetCollection("products"))
+	productRepo, err := repository.NewProductRepository(mongoDB.GetCollection("products"), repository.WithSKUCacheTTL(cfg.SKUCacheTTL))
+	if err != nil {
+		log.Fatalf("Failed to initialize product repository: %v", err)
+	}
 	customerRepo := repository.NewCustomerRepository(mongoDB.GetCollection("customers"))
 	purchaseRepo := repository.NewPurchaseRepository(mongoDB.GetCollection("purchases"))
 	saleRepo := repository.NewSaleRepository(mongoDB.GetCollection("sales"))
 	quotationRepo := repository.NewQuotationRepository(mongoDB.GetCollection("quotations"))
 	stockAdjustmentRepo := repository.NewStockAdjustmentRepository(mongoDB.GetCollection("stock_adjustments"))
+	auditLogRepo := repository.NewAuditLogRepository(mongoDB.GetCollection("audit_logs"))
+	supplierRepo := repository.NewSupplierRepository(mongoDB.GetCollection("suppliers"))
+	saleReturnRepo := repository.NewSaleReturnRepository(mongoDB.GetCollection("sale_returns"))
+	migrationRepo := repository.NewMigrationRepository(mongoDB.GetCollection("migrations"))
+	reportRepo := repository.NewReportRepository(mongoDB.GetCollection("sales"), mongoDB.GetCollection("purchases"), mongoDB.GetCollection("customers"), mongoDB.GetCollection("products"), mongoDB.GetCollection("sale_returns"))
+	webhookRepo := repository.NewWebhookRepository(mongoDB.GetCollection("webhooks"), mongoDB.GetCollection("webhook_deliveries"))
+	stockCountRepo := repository.NewStockCountRepository(mongoDB.GetCollection("stock_counts"))
+	budgetRepo := repository.NewBudgetRepository(mongoDB.GetCollection("budgets"))
+	recurringOrderRepo := repository.NewRecurringOrderRepository(mongoDB.GetCollection("recurring_orders"))
+	bundleRepo := repository.NewBundleRepository(mongoDB.GetCollection("bundles"))
+	serialNumberRepo := repository.NewSerialNumberRepository(mongoDB.GetCollection("serial_numbers"))
+	lotRepo := repository.NewLotRepository(mongoDB.GetCollection("lots"))
+	supplierInvoiceRepo := repository.NewSupplierInvoiceRepository(mongoDB.GetCollection("supplier_invoices"))
+	customerNoteRepo := repository.NewCustomerNoteRepository(mongoDB.GetCollection("customer_notes"))
+	exchangeRateRepo := repository.NewExchangeRateRepository(mongoDB.GetCollection("exchange_rates"))
+
+	// Initialize file storage
+	fileStorage, err := storage.NewFromConfig(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+
+	// Stream stock changes to /api/events/inventory; change streams need MongoDB to run as a replica set
+	sseHandler := handlers.NewSSEHandler()
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	inventoryEvents := make(chan models.InventoryEvent, 64)
+	go sseHandler.Run(eventsCtx, inventoryEvents)
+	go func() {
+		if err := database.WatchInventory(eventsCtx, mongoDB.Database, inventoryEvents); err != nil {
+			log.Printf("Warning: Inventory events disabled: %v", err)
+		}
+	}()
 
 	// Setup routes
-	router := routes.SetupRoutes(productRepo, customerRepo, purchaseRepo, saleRepo, quotationRepo, stockAdjustmentRepo)
+	routesCtx, stopRoutes := context.WithCancel(context.Background())
+	router := routes.SetupRoutes(routesCtx, cfg, routes.Dependencies{
+		ProductRepo:         productRepo,
+		CustomerRepo:        customerRepo,
+		PurchaseRepo:        purchaseRepo,
+		SaleRepo:            saleRepo,
+		QuotationRepo:       quotationRepo,
+		StockAdjustmentRepo: stockAdjustmentRepo,
+		AuditLogRepo:        auditLogRepo,
+		SupplierRepo:        supplierRepo,
+		SaleReturnRepo:      saleReturnRepo,
+		MigrationRepo:       migrationRepo,
+		ReportRepo:          reportRepo,
+		WebhookRepo:         webhookRepo,
+		StockCountRepo:      stockCountRepo,
+		BudgetRepo:          budgetRepo,
+		CategoryRepo:        categoryRepo,
+		RecurringOrderRepo:  recurringOrderRepo,
+		BundleRepo:          bundleRepo,
+		SerialNumberRepo:    serialNumberRepo,
+		LotRepo:             lotRepo,
+		SupplierInvoiceRepo: supplierInvoiceRepo,
+		CustomerNoteRepo:    customerNoteRepo,
+		ExchangeRateRepo:    exchangeRateRepo,
+		SSEHandler:          sseHandler,
+		HealthDB:            mongoDB,
+		FileStorage:         fileStorage,
+	})
+
+	// Start background jobs
+	quotationExpiryJob := scheduler.NewQuotationExpiryJob(quotationRepo, cfg.QuotationExpiryInterval)
+	quotationExpiryJob.Start()
+
+	saleService := services.NewSaleService(saleRepo, productRepo, customerRepo, quotationRepo, stockAdjustmentRepo, bundleRepo, serialNumberRepo, lotRepo, saleReturnRepo)
+	recurringOrderJob := scheduler.NewRecurringOrderJob(recurringOrderRepo, saleService, services.NewWebhookService(webhookRepo), cfg.RecurringOrderInterval)
+	recurringOrderJob.Start()
+
+	velocityJob := scheduler.NewVelocityClassificationJob(services.NewVelocityService(productRepo, stockAdjustmentRepo), cfg.VelocityClassifyInterval)
+	velocityJob.Start()
+
+	popularityJob := scheduler.NewPopularityScoreJob(services.NewPopularityService(productRepo, stockAdjustmentRepo), cfg.PopularityScoreInterval)
+	popularityJob.Start()
+
+	var lowStockNotifier *services.LowStockNotifier
+	if cfg.SMTPHost != "" && cfg.AlertEmail != "" {
+		emailService := services.NewSMTPEmailService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword)
+		lowStockNotifier = services.NewLowStockNotifier(productRepo, emailService, cfg.AlertEmail, cfg.LowStockCheckInterval)
+		lowStockNotifier.Start()
+	} else {
+		log.Println("Low-stock email alerts disabled (set SMTP_HOST and ALERT_EMAIL to enable)")
+	}
+	stopJobs := func() {
+		stopEvents()
+		stopRoutes()
+		quotationExpiryJob.Stop()
+		recurringOrderJob.Stop()
+		velocityJob.Stop()
+		popularityJob.Stop()
+		if lowStockNotifier != nil {
+			lowStockNotifier.Stop()
+		}
+	}
 
 	// Start server
+	server, redirectServer, listen, err := newServer(cfg, router)
+	if err != nil {
+		stopJobs()
+		log.Fatalf("Invalid server configuration: %v", err)
+	}
+
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
 	log.Printf("🚀 Server starting on port :%s", cfg.Port)
-	log.Printf("📱 API Base URL: http://localhost:%s/api", cfg.Port)
-	log.Printf("🔍 Health Check: http://localhost:%s/api/health", cfg.Port)
+	log.Printf("📱 API Base URL: %s://localhost:%s/api", scheme, cfg.Port)
+	log.Printf("🔍 Health Check: %s://localhost:%s/api/health", scheme, cfg.Port)
 	log.Printf("🗄️  Database: MongoDB (%s)", cfg.Database)
 
-	if err := http.ListenAndServe(":"+cfg.Port, router); err != nil {
+	serverErr := make(chan error, 2)
+	go func() {
+		if err := listen(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+	if redirectServer != nil {
+		log.Printf("↪️  Redirecting HTTP on port %s to HTTPS", redirectServer.Addr)
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serverErr <- err
+			}
+		}()
+	}
+
+	// Wait for shutdown signal
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		stopJobs()
 		log.Fatalf("Server failed to start: %v", err)
+	case <-stop:
+	}
+
+	log.Println("Shutting down server...")
+	stopJobs()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer shutdownCancel()
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: Redirect server shutdown did not complete: %v", err)
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Server shutdown did not complete: %v", err)
 	}
 }
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// HTTPRequestsTotal counts HTTP requests by method, route template and status code
+	HTTPRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goodpack_http_requests_total",
+			Help: "Total number of HTTP requests",
+		},
+		[]string{"method", "path", "status"},
+	)
+
+	// HTTPRequestDuration observes HTTP request latency by method and route template
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goodpack_http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "path"},
+	)
+
+	// MongoOperationDuration observes MongoDB operation latency by collection and operation
+	MongoOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goodpack_mongo_operation_duration_seconds",
+			Help:    "MongoDB operation duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"collection", "operation"},
+	)
+
+	// ActiveConnections tracks the number of requests currently being served
+	ActiveConnections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goodpack_http_active_connections",
+			Help: "Number of active HTTP connections",
+		},
+	)
+
+	// InventoryLevel tracks the total actual stock per product category
+	InventoryLevel = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goodpack_inventory_level",
+			Help: "Total actual stock per product category",
+		},
+		[]string{"category"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		MongoOperationDuration,
+		ActiveConnections,
+		InventoryLevel,
+	)
+}
+
+// ObserveMongoOperation records the duration of a MongoDB operation started at start.
+// Intended to be used with defer: defer metrics.ObserveMongoOperation("products", "find", time.Now())
+func ObserveMongoOperation(collection, operation string, start time.Time) {
+	MongoOperationDuration.WithLabelValues(collection, operation).Observe(time.Since(start).Seconds())
+}
+
+// SetInventoryLevel sets the inventory level gauge for a category
+func SetInventoryLevel(category string, level int) {
+	InventoryLevel.WithLabelValues(category).Set(float64(level))
+}
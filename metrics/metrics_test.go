@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveMongoOperation(t *testing.T) {
+	count := func() uint64 {
+		var m dto.Metric
+		if err := MongoOperationDuration.WithLabelValues("products", "GetByID").(prometheus.Histogram).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	before := count()
+	func() {
+		defer ObserveMongoOperation("products", "GetByID", time.Now())
+	}()
+	if got := count() - before; got != 1 {
+		t.Errorf("operations observed = %d, want 1", got)
+	}
+}
+
+func TestSetInventoryLevel(t *testing.T) {
+	SetInventoryLevel("Box", 120)
+	SetInventoryLevel("Box", 80)
+	if got := testutil.ToFloat64(InventoryLevel.WithLabelValues("Box")); got != 80 {
+		t.Errorf("inventory level = %v, want the latest 80", got)
+	}
+}
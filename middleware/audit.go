@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// auditedEntities maps the first API path segment to the audited entity type
+var auditedEntities = map[string]models.AuditEntityType{
+	"products":   models.AuditEntityProduct,
+	"customers":  models.AuditEntityCustomer,
+	"sales":      models.AuditEntitySale,
+	"purchases":  models.AuditEntityPurchase,
+	"quotations": models.AuditEntityQuotation,
+	"suppliers":  models.AuditEntitySupplier,
+}
+
+// bodyRecorder captures the status code and body written by the wrapped handler
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (br *bodyRecorder) WriteHeader(status int) {
+	br.status = status
+	br.ResponseWriter.WriteHeader(status)
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	br.body.Write(b)
+	return br.ResponseWriter.Write(b)
+}
+
+// Audit records create/update/delete requests on audited entities with before/after snapshots
+func Audit(auditRepo *repository.AuditLogRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/"), "/")
+			entityType, ok := auditedEntities[segments[0]]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entityID := mux.Vars(r)["id"]
+			action := auditAction(r.Method, segments)
+
+			// Snapshot the current state before the handler mutates it
+			var before bson.Raw
+			if entityID != "" {
+				if raw, err := auditRepo.Snapshot(r.Context(), entityType, entityID); err == nil {
+					before = raw
+				}
+			}
+
+			recorder := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusBadRequest {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if entityID == "" {
+				entityID = idFromResponse(recorder.body.Bytes())
+			}
+
+			var after bson.Raw
+			if entityID != "" {
+				if raw, err := auditRepo.Snapshot(ctx, entityType, entityID); err == nil {
+					after = raw
+				}
+			}
+			if after == nil && action != models.AuditActionDelete {
+				after = jsonToRaw(recorder.body.Bytes())
+			}
+
+			entry := &models.AuditLog{
+				EntityType: entityType,
+				EntityID:   entityID,
+				Action:     action,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Before:     before,
+				After:      after,
+				IP:         clientIP(r),
+				CreatedAt:  time.Now(),
+			}
+			if userID := r.Header.Get("X-User-ID"); userID != "" {
+				entry.UserID = &userID
+			}
+
+			if err := auditRepo.Create(ctx, entry); err != nil {
+				log.Printf("Warning: Failed to record audit log: %v", err)
+			}
+		})
+	}
+}
+
+// auditAction derives the audited action from the request method: DELETE always deletes, POST to the
+// collection creates and anything else, such as PUT or a POST action on one entity, updates
+func auditAction(method string, segments []string) models.AuditAction {
+	switch {
+	case method == http.MethodDelete:
+		return models.AuditActionDelete
+	case method == http.MethodPost && len(segments) == 1:
+		return models.AuditActionCreate
+	default:
+		return models.AuditActionUpdate
+	}
+}
+
+// idFromResponse extracts the "id" field of a JSON response body, ignoring zero ObjectIDs
+func idFromResponse(body []byte) string {
+	var response struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	if response.ID == primitive.NilObjectID.Hex() {
+		return ""
+	}
+	return response.ID
+}
+
+// jsonToRaw converts a JSON object body into a BSON document, returning nil for anything else
+func jsonToRaw(body []byte) bson.Raw {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(body, false, &doc); err != nil {
+		return nil
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
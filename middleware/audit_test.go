@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// testDatabase returns a database of its own on the MongoDB at MONGODB_TEST_URI, dropped when the test ends.
+// Tests that need MongoDB are skipped when the variable is not set.
+func testDatabase(t *testing.T) *mongo.Database {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database(fmt.Sprintf("goodpack_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+func TestAuditAction(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   models.AuditAction
+	}{
+		{http.MethodPost, "products", models.AuditActionCreate},
+		{http.MethodPut, "products/abc", models.AuditActionUpdate},
+		{http.MethodPatch, "products/abc", models.AuditActionUpdate},
+		{http.MethodPost, "sales/abc/payment", models.AuditActionUpdate},
+		{http.MethodDelete, "products/abc", models.AuditActionDelete},
+		{http.MethodDelete, "products/abc/hard-delete", models.AuditActionDelete},
+	}
+	for _, tt := range tests {
+		if got := auditAction(tt.method, strings.Split(tt.path, "/")); got != tt.want {
+			t.Errorf("%s /api/%s: action %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIDFromResponse(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id":"65a000000000000000000001","name":"Box"}`, "65a000000000000000000001"},
+		{`{"id":"000000000000000000000000"}`, ""},
+		{`{"name":"Box"}`, ""},
+		{`[{"id":"65a000000000000000000001"}]`, ""},
+	}
+	for _, tt := range tests {
+		if got := idFromResponse([]byte(tt.body)); got != tt.want {
+			t.Errorf("idFromResponse(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAuditSkipsReads(t *testing.T) {
+	// A nil repository would panic if a read were audited
+	router := mux.NewRouter()
+	router.Use(Audit(nil))
+	router.HandleFunc("/api/products/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/api/products/abc", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got %d, want 200", method, w.Code)
+		}
+	}
+}
+
+func TestAuditRecordsProductUpdateBeforeAndAfter(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	auditRepo := repository.NewAuditLogRepository(db.Collection("audit_logs"))
+
+	id := primitive.NewObjectID()
+	if _, err := db.Collection("products").InsertOne(ctx, bson.M{"_id": id, "name": "Box"}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(Audit(auditRepo))
+	router.HandleFunc("/api/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		db.Collection("products").UpdateByID(r.Context(), id, bson.M{"$set": bson.M{"name": "Big box"}})
+		w.Write([]byte(`{}`))
+	}).Methods("PUT")
+	router.HandleFunc("/api/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		db.Collection("products").DeleteOne(r.Context(), bson.M{"_id": id})
+	}).Methods("DELETE")
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/api/products/"+id.Hex(), strings.NewReader(`{"name":"Big box"}`)))
+	}
+
+	logs, err := auditRepo.GetAll(ctx, models.AuditLogFilter{EntityID: id.Hex()}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d audit logs, want 2", len(logs))
+	}
+
+	deleted, updated := logs[0], logs[1] // newest first
+	if updated.Action != models.AuditActionUpdate || deleted.Action != models.AuditActionDelete {
+		t.Errorf("actions %q, %q, want update then delete", updated.Action, deleted.Action)
+	}
+	if name := updated.Before.Lookup("name").StringValue(); name != "Box" {
+		t.Errorf("before name = %q, want Box", name)
+	}
+	if name := updated.After.Lookup("name").StringValue(); name != "Big box" {
+		t.Errorf("after name = %q, want Big box", name)
+	}
+	if deleted.After != nil {
+		t.Errorf("after of the delete = %s, want none", deleted.After)
+	}
+}
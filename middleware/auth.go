@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"goodpack-server/apierrors"
+)
+
+// AdminTokenHeader is the request header carrying the admin token
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminOnly restricts a route to requests carrying the configured admin token.
+// When no token is configured, admin routes are disabled entirely.
+func AdminOnly(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if adminToken == "" {
+				apierrors.Write(w, apierrors.New(http.StatusForbidden, "admin_not_configured", "Admin access is not configured"))
+				return
+			}
+
+			if !IsAdmin(r, adminToken) {
+				apierrors.Write(w, apierrors.New(http.StatusUnauthorized, "admin_required", "Admin access required"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsAdmin reports whether the request carries the configured admin token; it never does when no token is
+// configured
+func IsAdmin(r *http.Request, adminToken string) bool {
+	if adminToken == "" {
+		return false
+	}
+	token := r.Header.Get(AdminTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
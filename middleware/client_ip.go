@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the networks of the reverse proxies whose X-Forwarded-For header is believed
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the reverse proxies, as IP addresses or CIDR ranges, whose X-Forwarded-For header
+// identifies the client. Requests from anywhere else are identified by their remote address, so clients cannot
+// pick their own IP for rate limiting and audit logs. It must be called before requests are served.
+func SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the originating client IP. X-Forwarded-For is only honoured on requests from a trusted
+// proxy, and then read from the right: the first address not itself a trusted proxy is the client, since
+// everything to its left was supplied by the client.
+func clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+		remote = hop
+	}
+	return remote
+}
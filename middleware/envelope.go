@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"goodpack-server/apierrors"
+)
+
+// APIVersion is reported in the meta of enveloped responses
+const APIVersion = "1.0.0"
+
+// RequestIDHeader carries the ID generated for each request
+const RequestIDHeader = "X-Request-ID"
+
+// EnvelopeMeta describes the request an enveloped response answers
+type EnvelopeMeta struct {
+	RequestID string `json:"requestId"`
+	Timestamp string `json:"timestamp"`
+	Version   string `json:"version"`
+}
+
+// EnvelopeError is the error of an enveloped error response
+type EnvelopeError struct {
+	Code    string          `json:"code"`              // e.g. not_found, bad_request
+	Message string          `json:"message"`           // ข้อความจาก handler
+	Details json.RawMessage `json:"details,omitempty"` // JSON body of the error, e.g. validation errors
+}
+
+type successEnvelope struct {
+	Data json.RawMessage `json:"data"`
+	Meta EnvelopeMeta    `json:"meta"`
+}
+
+type errorEnvelope struct {
+	Error EnvelopeError `json:"error"`
+	Meta  EnvelopeMeta  `json:"meta"`
+}
+
+// envelopeRecorder buffers a response so it can be wrapped before anything is sent
+type envelopeRecorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	streaming bool // the handler flushed, so the response is passed through unwrapped
+}
+
+func (er *envelopeRecorder) WriteHeader(status int) {
+	if er.status == 0 {
+		er.status = status
+	}
+}
+
+func (er *envelopeRecorder) Write(b []byte) (int, error) {
+	if er.streaming {
+		return er.ResponseWriter.Write(b)
+	}
+	if er.status == 0 {
+		er.status = http.StatusOK
+	}
+	return er.body.Write(b)
+}
+
+// Flush lets streaming handlers such as Server-Sent Events work: from the first flush on, the response is
+// sent as written instead of being wrapped
+func (er *envelopeRecorder) Flush() {
+	if !er.streaming {
+		er.streaming = true
+		if er.status == 0 {
+			er.status = http.StatusOK
+		}
+		er.ResponseWriter.WriteHeader(er.status)
+		er.ResponseWriter.Write(er.body.Bytes())
+		er.body.Reset()
+	}
+	if f, ok := er.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Envelope gives every request an ID (sent as X-Request-ID) and wraps JSON responses in
+// {"data": ..., "meta": {...}} and error responses in {"error": {"code", "message"}, "meta": {...}}.
+// Files, documents and other non-JSON successful responses are sent unchanged.
+func Envelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := uuid.NewString()
+		w.Header().Set(RequestIDHeader, requestID)
+
+		recorder := &envelopeRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.streaming {
+			return
+		}
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		meta := EnvelopeMeta{
+			RequestID: requestID,
+			Timestamp: time.Now().Format(time.RFC3339),
+			Version:   APIVersion,
+		}
+
+		var envelope interface{}
+		switch {
+		case status >= http.StatusBadRequest:
+			envelope = errorEnvelope{Error: envelopeError(status, w.Header().Get("Content-Type"), recorder.body.Bytes()), Meta: meta}
+		case isJSON(w.Header().Get("Content-Type")) && json.Valid(recorder.body.Bytes()):
+			envelope = successEnvelope{Data: json.RawMessage(bytes.TrimSpace(recorder.body.Bytes())), Meta: meta}
+		default:
+			w.WriteHeader(status)
+			w.Write(recorder.body.Bytes())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(envelope)
+	})
+}
+
+// envelopeError builds the error of an error response from its status and body
+func envelopeError(status int, contentType string, body []byte) EnvelopeError {
+	envelopeErr := EnvelopeError{
+		Code:    apierrors.CodeForStatus(status),
+		Message: strings.TrimSpace(string(body)),
+	}
+	if isJSON(contentType) && json.Valid(body) {
+		envelopeErr.Message = http.StatusText(status)
+		envelopeErr.Details = json.RawMessage(bytes.TrimSpace(body))
+		liftAppError(&envelopeErr, body)
+	}
+	if envelopeErr.Message == "" {
+		envelopeErr.Message = http.StatusText(status)
+	}
+	return envelopeErr
+}
+
+// liftAppError moves the code and message of an apierrors.AppError body into the envelope error,
+// leaving any other fields of the body (e.g. currentVersion) as its details
+func liftAppError(envelopeErr *EnvelopeError, body []byte) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return
+	}
+	var code, message string
+	if json.Unmarshal(fields["code"], &code) != nil || json.Unmarshal(fields["message"], &message) != nil || code == "" {
+		return
+	}
+	envelopeErr.Code = code
+	envelopeErr.Message = message
+	envelopeErr.Details = nil
+
+	delete(fields, "code")
+	delete(fields, "message")
+	if len(fields) > 0 {
+		envelopeErr.Details, _ = json.Marshal(fields)
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type envelopeResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Error *EnvelopeError  `json:"error"`
+	Meta  EnvelopeMeta    `json:"meta"`
+}
+
+func serveEnvelope(t *testing.T, status int, contentType, body string) (*httptest.ResponseRecorder, envelopeResponse) {
+	t.Helper()
+	handler := Envelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+
+	var response envelopeResponse
+	if isJSON(w.Header().Get("Content-Type")) {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid envelope %q: %v", w.Body.String(), err)
+		}
+	}
+	return w, response
+}
+
+func TestEnvelopeWrapsJSON(t *testing.T) {
+	w, response := serveEnvelope(t, http.StatusCreated, "application/json", `{"id":"abc"}`+"\n")
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", w.Code)
+	}
+	if string(response.Data) != `{"id":"abc"}` {
+		t.Errorf("data = %s, want the handler's body", response.Data)
+	}
+	if response.Meta.RequestID == "" || response.Meta.RequestID != w.Header().Get(RequestIDHeader) {
+		t.Errorf("meta request ID %q does not match the %s header %q", response.Meta.RequestID, RequestIDHeader, w.Header().Get(RequestIDHeader))
+	}
+	if response.Meta.Version != APIVersion || response.Meta.Timestamp == "" {
+		t.Errorf("meta = %+v, want the version and a timestamp", response.Meta)
+	}
+}
+
+func TestEnvelopeWrapsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    string
+		wantMessage string
+		wantDetails string
+	}{
+		{"plain text", "text/plain", "Product not found\n", "not_found", "Product not found", ""},
+		{"app error", "application/json", `{"code":"version_conflict","message":"Stale version","currentVersion":3}`, "version_conflict", "Stale version", `{"currentVersion":3}`},
+		{"other JSON", "application/json", `{"name":"required"}`, "not_found", "Not Found", `{"name":"required"}`},
+		{"empty", "text/plain", "", "not_found", "Not Found", ""},
+	}
+	for _, tt := range tests {
+		w, response := serveEnvelope(t, http.StatusNotFound, tt.contentType, tt.body)
+		if w.Code != http.StatusNotFound || response.Error == nil {
+			t.Errorf("%s: status %d, error %v; want 404 with an error", tt.name, w.Code, response.Error)
+			continue
+		}
+		if response.Error.Code != tt.wantCode || response.Error.Message != tt.wantMessage || string(response.Error.Details) != tt.wantDetails {
+			t.Errorf("%s: error = %s / %s / %s, want %s / %s / %s", tt.name,
+				response.Error.Code, response.Error.Message, response.Error.Details, tt.wantCode, tt.wantMessage, tt.wantDetails)
+		}
+	}
+}
+
+func TestEnvelopePassesFilesThrough(t *testing.T) {
+	w, _ := serveEnvelope(t, http.StatusOK, "application/pdf", "%PDF-1.4")
+	if w.Body.String() != "%PDF-1.4" || w.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("PDF response = %q (%s), want it unchanged", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("no %s header on a file response", RequestIDHeader)
+	}
+}
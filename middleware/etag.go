@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etagRecorder buffers a response so its ETag can be computed before anything is sent
+type etagRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (er *etagRecorder) WriteHeader(status int) {
+	if er.status == 0 {
+		er.status = status
+	}
+}
+
+func (er *etagRecorder) Write(b []byte) (int, error) {
+	if er.status == 0 {
+		er.status = http.StatusOK
+	}
+	return er.body.Write(b)
+}
+
+// ETag adds an ETag (MD5 of the body) to successful responses and answers 304 Not Modified when the
+// request's If-None-Match already has it, so polling clients only download lists that changed
+func ETag(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &etagRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status != http.StatusOK {
+			if recorder.status != 0 {
+				w.WriteHeader(recorder.status)
+			}
+			w.Write(recorder.body.Bytes())
+			return
+		}
+
+		sum := md5.Sum(recorder.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(recorder.body.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(recorder.body.Bytes())
+	})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag (weak comparison, as RFC 7232 requires)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func etagHandler(status int, body string) http.Handler {
+	return ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func serveETag(handler http.Handler, method, ifNoneMatch string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/api/products", nil)
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestETagConditionalGet(t *testing.T) {
+	handler := etagHandler(http.StatusOK, `[{"name":"Box"}]`)
+
+	first := serveETag(handler, http.MethodGet, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.String() != `[{"name":"Box"}]` {
+		t.Fatalf("first GET: %d, ETag %q, body %q", first.Code, etag, first.Body.String())
+	}
+
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := serveETag(handler, http.MethodGet, ifNoneMatch)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: got %d with %d bytes, want an empty 304", ifNoneMatch, w.Code, w.Body.Len())
+		}
+	}
+
+	if w := serveETag(etagHandler(http.StatusOK, `[]`), http.MethodGet, etag); w.Code != http.StatusOK {
+		t.Errorf("changed body: got %d, want 200", w.Code)
+	}
+}
+
+func TestETagSkipsErrorsAndWrites(t *testing.T) {
+	w := serveETag(etagHandler(http.StatusNotFound, `{"error":"not found"}`), http.MethodGet, "*")
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" || w.Body.String() != `{"error":"not found"}` {
+		t.Errorf("error response: %d, ETag %q, body %q; want it passed through untagged", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+
+	w = serveETag(etagHandler(http.StatusOK, `{}`), http.MethodPost, "*")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Errorf("POST: %d, ETag %q; want it passed through untagged", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goodpack-server/locales"
+)
+
+type localeKey struct{}
+
+// I18n picks the response language from the Accept-Language header and stores it in the request context
+func I18n(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := parseAcceptLanguage(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, locale)))
+	})
+}
+
+// LocaleFromContext returns the locale chosen by I18n, or the default locale
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok {
+		return locale
+	}
+	return locales.Default
+}
+
+// parseAcceptLanguage returns the supported locale with the highest quality, e.g. "th-TH,th;q=0.9,en;q=0.8" -> "th"
+func parseAcceptLanguage(header string) string {
+	type candidate struct {
+		locale  string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		// "th-TH" -> "th"
+		locale := strings.SplitN(tag, "-", 2)[0]
+		if quality > 0 && locales.IsSupported(locale) {
+			candidates = append(candidates, candidate{locale: locale, quality: quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return locales.Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].locale
+}
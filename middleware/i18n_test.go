@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goodpack-server/locales"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", locales.Default},
+		{"th", locales.Thai},
+		{"th-TH,th;q=0.9,en;q=0.8", locales.Thai},
+		{"en-US,th;q=0.5", locales.English},
+		{"fr-FR,th;q=0.7,en;q=0.9", locales.English}, // unsupported tags are skipped
+		{"en;q=0.2, TH;q=0.8", locales.Thai},
+		{"th;q=0,en;q=0.1", locales.English}, // q=0 means not acceptable
+		{"de,fr", locales.Default},
+	}
+	for _, tt := range tests {
+		if got := parseAcceptLanguage(tt.header); got != tt.want {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestI18nStoresLocale(t *testing.T) {
+	var locale string
+	handler := I18n(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = LocaleFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+	r.Header.Set("Accept-Language", "th-TH")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if locale != locales.Thai {
+		t.Errorf("locale in context = %q, want th", locale)
+	}
+	if got := w.Header().Get("Content-Language"); got != locales.Thai {
+		t.Errorf("Content-Language = %q, want th", got)
+	}
+	if got := LocaleFromContext(context.Background()); got != locales.Default {
+		t.Errorf("locale without I18n = %q, want the default", got)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"goodpack-server/metrics"
+)
+
+// histogramCount is the number of observations of one series of a histogram
+func histogramCount(t *testing.T, histogram *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := histogram.WithLabelValues(labels...).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetricsLabelsByRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Metrics)
+	router.HandleFunc("/api/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods(http.MethodGet)
+
+	requests := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/api/products/{id}", "404")
+	before := testutil.ToFloat64(requests)
+	observed := histogramCount(t, metrics.HTTPRequestDuration, http.MethodGet, "/api/products/{id}")
+
+	for _, id := range []string{"abc", "def"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/products/"+id, nil))
+	}
+
+	if got := testutil.ToFloat64(requests) - before; got != 2 {
+		t.Errorf("requests counted under the route template = %v, want 2", got)
+	}
+	if got := histogramCount(t, metrics.HTTPRequestDuration, http.MethodGet, "/api/products/{id}") - observed; got != 2 {
+		t.Errorf("durations observed under the route template = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/api/products/abc", "404")); got != 0 {
+		t.Errorf("requests counted under the raw path = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(metrics.ActiveConnections); got != 0 {
+		t.Errorf("active connections after the requests = %v, want 0", got)
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"goodpack-server/apierrors"
+)
+
+const (
+	// rateLimiterIdleTTL is how long an IP's limiter is kept after its last request
+	rateLimiterIdleTTL = 10 * time.Minute
+	// rateLimiterCleanupInterval is how often idle limiters are evicted
+	rateLimiterCleanupInterval = time.Minute
+)
+
+// clientLimiter is the token bucket for one client IP
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen atomic.Int64 // unix nanoseconds
+}
+
+// RateLimiter is a per-IP token bucket limiter
+type RateLimiter struct {
+	limit   rate.Limit
+	burst   int
+	clients sync.Map // ip -> *clientLimiter
+}
+
+// NewRateLimiter creates a limiter refilling each IP's bucket at limit with the given burst,
+// and starts a goroutine that evicts limiters for IPs that have gone idle until ctx is done
+func NewRateLimiter(ctx context.Context, limit rate.Limit, burst int) *RateLimiter {
+	rl := &RateLimiter{
+		limit: limit,
+		burst: burst,
+	}
+	go rl.cleanup(ctx, rateLimiterCleanupInterval)
+	return rl
+}
+
+// Limit rejects requests with 429 Too Many Requests once the client's bucket is empty
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reservation := rl.getLimiter(clientIP(r)).Reserve()
+		if !reservation.OK() {
+			apierrors.Write(w, apierrors.New(http.StatusTooManyRequests, "too_many_requests", "Too many requests"))
+			return
+		}
+
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			apierrors.Write(w, apierrors.New(http.StatusTooManyRequests, "too_many_requests", "Too many requests"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
+	now := time.Now().UnixNano()
+
+	if value, ok := rl.clients.Load(ip); ok {
+		client := value.(*clientLimiter)
+		client.lastSeen.Store(now)
+		return client.limiter
+	}
+
+	client := &clientLimiter{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+	client.lastSeen.Store(now)
+	value, _ := rl.clients.LoadOrStore(ip, client)
+	return value.(*clientLimiter).limiter
+}
+
+// cleanup removes limiters that have not been used recently every interval until ctx is done
+func (rl *RateLimiter) cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rl.evictIdle(time.Now().Add(-rateLimiterIdleTTL))
+		}
+	}
+}
+
+// evictIdle removes the limiters of clients last seen before cutoff
+func (rl *RateLimiter) evictIdle(cutoff time.Time) {
+	rl.clients.Range(func(key, value interface{}) bool {
+		if value.(*clientLimiter).lastSeen.Load() < cutoff.UnixNano() {
+			rl.clients.Delete(key)
+		}
+		return true
+	})
+}
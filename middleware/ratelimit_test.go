@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func rateLimitedHandler(t *testing.T, limit rate.Limit, burst int) http.Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewRateLimiter(ctx, limit, burst).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serve(handler http.Handler, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimiterBurstAndRecovery(t *testing.T) {
+	handler := rateLimitedHandler(t, rate.Every(50*time.Millisecond), 3)
+
+	for i := 0; i < 3; i++ {
+		if w := serve(handler, "192.0.2.1:1234", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d of the burst: got %d, want 200", i+1, w.Code)
+		}
+	}
+	w := serve(handler, "192.0.2.1:1234", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request after the burst: got %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+
+	// Another client has a bucket of its own
+	if w := serve(handler, "192.0.2.2:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("other client: got %d, want 200", w.Code)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if w := serve(handler, "192.0.2.1:1234", ""); w.Code != http.StatusOK {
+		t.Errorf("request after the bucket refilled: got %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimiterIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	if err := SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	handler := rateLimitedHandler(t, rate.Every(time.Hour), 1)
+
+	serve(handler, "192.0.2.1:1234", "198.51.100.1")
+	if w := serve(handler, "192.0.2.1:1234", "198.51.100.2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For: got %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimiterKeysOnClientBehindTrustedProxy(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+	handler := rateLimitedHandler(t, rate.Every(time.Hour), 1)
+
+	if w := serve(handler, "10.0.0.5:1234", "198.51.100.1"); w.Code != http.StatusOK {
+		t.Fatalf("first client: got %d, want 200", w.Code)
+	}
+	if w := serve(handler, "10.0.0.5:1234", "198.51.100.2"); w.Code != http.StatusOK {
+		t.Errorf("second client through the same proxy: got %d, want 200", w.Code)
+	}
+	// An address the client prepends itself does not give it a new bucket
+	if w := serve(handler, "10.0.0.5:1234", "203.0.113.9, 198.51.100.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("client prepending an address: got %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimiterEvictsIdleClients(t *testing.T) {
+	rl := &RateLimiter{limit: rate.Every(time.Hour), burst: 1}
+	rl.getLimiter("192.0.2.1")
+	rl.getLimiter("192.0.2.2").Allow()
+
+	rl.evictIdle(time.Now().Add(-time.Minute))
+	if _, ok := rl.clients.Load("192.0.2.1"); !ok {
+		t.Error("client seen within the idle time was evicted")
+	}
+
+	rl.evictIdle(time.Now().Add(time.Minute))
+	if _, ok := rl.clients.Load("192.0.2.2"); ok {
+		t.Error("idle client was kept")
+	}
+	// An evicted client starts again with a full bucket
+	if !rl.getLimiter("192.0.2.2").Allow() {
+		t.Error("evicted client was still limited")
+	}
+}
+
+func TestRateLimiterCleanupStopsWithContext(t *testing.T) {
+	rl := &RateLimiter{limit: rate.Every(time.Hour), burst: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rl.cleanup(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup kept running after its context was cancelled")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.1", "172.16.0.0/12"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{"direct", "192.0.2.1:1234", "", "192.0.2.1"},
+		{"untrusted sender", "192.0.2.1:1234", "198.51.100.1", "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "10.0.0.1:1234", "198.51.100.1, 172.16.0.3", "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.1:1234", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	defer SetTrustedProxies(nil)
+	for _, proxy := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if err := SetTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("SetTrustedProxies(%q) succeeded, want an error", proxy)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+
+	"goodpack-server/apierrors"
+)
+
+// MaxUploadBytes bounds multipart upload bodies (the 10 MB form limit plus multipart overhead)
+const MaxUploadBytes = 12 << 20
+
+// uploadPath matches the endpoints that take multipart file uploads instead of JSON
+var uploadPath = regexp.MustCompile(`^/api/(products/[^/]+/image|migration/[^/]+/csv)$`)
+
+// importPath matches the endpoints that take a JSON import as large as a CSV upload
+var importPath = regexp.MustCompile(`^/api/migration/products/json$`)
+
+// isFileUpload reports whether the request is a POST to a file upload endpoint
+func isFileUpload(r *http.Request) bool {
+	return r.Method == http.MethodPost && uploadPath.MatchString(r.URL.Path)
+}
+
+// isJSONImport reports whether the request is a POST to a JSON import endpoint
+func isJSONImport(r *http.Request) bool {
+	return r.Method == http.MethodPost && importPath.MatchString(r.URL.Path)
+}
+
+// MaxBodySizeMiddleware rejects request bodies larger than maxBytes with 413.
+// File uploads and JSON imports are allowed up to MaxUploadBytes.
+func MaxBodySizeMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := maxBytes
+			if (isFileUpload(r) || isJSONImport(r)) && limit < MaxUploadBytes {
+				limit = MaxUploadBytes
+			}
+
+			// Bodies with a declared length can be rejected up front; chunked bodies fail when read past the limit
+			if r.ContentLength > limit {
+				apierrors.Write(w, apierrors.New(http.StatusRequestEntityTooLarge, "request_too_large", "Request body too large"))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ContentTypeMiddleware returns 415 for POST, PUT and PATCH requests with a body that is not application/json.
+// File upload endpoints are exempt.
+func ContentTypeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Action endpoints such as /restore or /accept are called without a body
+		if r.ContentLength == 0 || isFileUpload(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			apierrors.Write(w, apierrors.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
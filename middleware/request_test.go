@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// readingHandler reads the whole body and answers 200, or 413 when the body is over the limit
+var readingHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMaxBodySizeMiddleware(t *testing.T) {
+	handler := MaxBodySizeMiddleware(16)(readingHandler)
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{"within the limit", "/api/products", 16, false, http.StatusOK},
+		{"declared length over the limit", "/api/products", 17, false, http.StatusRequestEntityTooLarge},
+		{"chunked body over the limit", "/api/products", 17, true, http.StatusRequestEntityTooLarge},
+		{"upload over the JSON limit", "/api/products/abc/image", 1024, false, http.StatusOK},
+		{"upload over the upload limit", "/api/products/abc/image", MaxUploadBytes + 1, false, http.StatusRequestEntityTooLarge},
+		{"JSON import over the JSON limit", "/api/migration/products/json", 1024, false, http.StatusOK},
+		{"JSON import over the upload limit", "/api/migration/products/json", MaxUploadBytes + 1, false, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+		if tt.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestContentTypeMiddleware(t *testing.T) {
+	handler := ContentTypeMiddleware(readingHandler)
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		contentType string
+		want        int
+	}{
+		{"JSON", http.MethodPost, "/api/products", "{}", "application/json", http.StatusOK},
+		{"JSON with charset", http.MethodPut, "/api/products/abc", "{}", "application/json; charset=utf-8", http.StatusOK},
+		{"form body", http.MethodPost, "/api/products", "a=b", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"missing content type", http.MethodPatch, "/api/products/abc", "{}", "", http.StatusUnsupportedMediaType},
+		{"action without a body", http.MethodPost, "/api/products/abc/restore", "", "", http.StatusOK},
+		{"file upload", http.MethodPost, "/api/migration/products/csv", "data", "multipart/form-data; boundary=x", http.StatusOK},
+		{"GET", http.MethodGet, "/api/products", "", "text/plain", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
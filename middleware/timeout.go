@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"goodpack-server/apierrors"
+)
+
+// timeoutWriter buffers a response so a timeout can still replace it with a 503
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.status == 0 && !tw.timedOut {
+		tw.status = status
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(b)
+}
+
+// timeoutHandler answers 503 when its handler has not finished within timeout
+type timeoutHandler struct {
+	handler http.Handler
+	timeout time.Duration // 0 = no limit
+}
+
+func (th *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if th.timeout <= 0 {
+		th.handler.ServeHTTP(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), th.timeout)
+	defer cancel()
+
+	tw := &timeoutWriter{header: w.Header().Clone()}
+	done := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+				return
+			}
+			close(done)
+		}()
+		th.handler.ServeHTTP(tw, r.WithContext(ctx))
+	}()
+
+	select {
+	case p := <-panicked:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		for key, values := range tw.header {
+			w.Header()[key] = values
+		}
+		if tw.status != 0 {
+			w.WriteHeader(tw.status)
+		}
+		w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		// The handler keeps running until it notices the cancelled context; anything it writes is dropped
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+		apierrors.Write(w, apierrors.New(http.StatusServiceUnavailable, "request_timeout", "request timeout"))
+	}
+}
+
+// Timeout limits a route to d (0 = no limit), replacing TimeoutMiddleware's limit for it: register slow
+// routes such as reports as Timeout(longer)(handler) and streams as Timeout(0)(handler)
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &timeoutHandler{handler: next, timeout: d}
+	}
+}
+
+// TimeoutMiddleware answers 503 {"code": "request_timeout"} when a request takes longer than d (0 = no limit)
+// and cancels its context, so MongoDB queries run with it stop. Routes registered with Timeout keep their own
+// limit instead.
+func TimeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := Timeout(d)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if _, ok := route.GetHandler().(*timeoutHandler); ok {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sleepingHandler answers after d, or stops when its request context is cancelled
+func sleepingHandler(d time.Duration, cancelled chan<- error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(d):
+			w.Header().Set("X-Handler", "done")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("ok"))
+		case <-r.Context().Done():
+			if cancelled != nil {
+				cancelled <- r.Context().Err()
+			}
+		}
+	})
+}
+
+func TestTimeoutPassesFastResponsesThrough(t *testing.T) {
+	w := httptest.NewRecorder()
+	Timeout(time.Second)(sleepingHandler(0, nil)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+
+	if w.Code != http.StatusCreated || w.Body.String() != "ok" || w.Header().Get("X-Handler") != "done" {
+		t.Errorf("got %d %q (X-Handler %q), want the handler's 201 ok", w.Code, w.Body.String(), w.Header().Get("X-Handler"))
+	}
+}
+
+func TestTimeoutAnswers503AndCancelsTheHandler(t *testing.T) {
+	cancelled := make(chan error, 1)
+	w := httptest.NewRecorder()
+	Timeout(20*time.Millisecond)(sleepingHandler(time.Second, cancelled)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reports/abc", nil))
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusServiceUnavailable || body.Code != "request_timeout" {
+		t.Errorf("got %d %q, want 503 request_timeout", w.Code, w.Body.String())
+	}
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("handler context error = %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("handler context was not cancelled")
+	}
+}
+
+func TestTimeoutMiddlewareKeepsRouteLimits(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(TimeoutMiddleware(20 * time.Millisecond))
+	router.Handle("/slow", sleepingHandler(50*time.Millisecond, nil))
+	router.Handle("/report", Timeout(time.Second)(sleepingHandler(50*time.Millisecond, nil)))
+	router.Handle("/stream", Timeout(0)(sleepingHandler(50*time.Millisecond, nil)))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/slow", http.StatusServiceUnavailable},
+		{"/report", http.StatusCreated},
+		{"/stream", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
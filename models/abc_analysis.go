@@ -0,0 +1,82 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ABC classes, by share of sales revenue
+const (
+	ABCClassA = "A" // สินค้าที่ทำรายได้ 80% แรก
+	ABCClassB = "B" // 15% ถัดมา
+	ABCClassC = "C" // 5% สุดท้าย
+)
+
+// ProductRevenue is the quantity sold and revenue of one product over a period
+type ProductRevenue struct {
+	ProductID   string  `bson:"_id" json:"productId"`
+	ProductName string  `bson:"productName" json:"productName"`
+	SKUID       string  `bson:"skuId" json:"skuId"`
+	Quantity    int     `bson:"quantity" json:"quantity"`
+	Revenue     float64 `bson:"revenue" json:"revenue"`
+}
+
+// ABCAnalysisRow is one product's revenue share and class
+type ABCAnalysisRow struct {
+	ProductRevenue    `bson:",inline"`
+	RevenuePercent    float64 `json:"revenuePercent"`    // สัดส่วนรายได้ของสินค้านี้ (%)
+	CumulativePercent float64 `json:"cumulativePercent"` // สัดส่วนรายได้สะสม (%)
+	Class             string  `json:"class"`
+}
+
+// ABCAnalysis is the ABC classification of products over a period
+type ABCAnalysis struct {
+	Period       string           `json:"period"`
+	StartDate    time.Time        `json:"startDate"`
+	GeneratedAt  time.Time        `json:"generatedAt"`
+	TotalRevenue float64          `json:"totalRevenue"`
+	Products     []ABCAnalysisRow `json:"products"`
+	Summary      map[string]int   `json:"summary"` // จำนวนสินค้าในแต่ละกลุ่ม
+}
+
+// ClassifyABC sorts products by revenue and assigns each a class. A product is in A while the revenue
+// before it is under 80%, and in B while it is under 95%, so the product that crosses a boundary stays
+// in the higher class.
+func ClassifyABC(revenues []ProductRevenue) ([]ABCAnalysisRow, map[string]int, float64) {
+	sorted := make([]ProductRevenue, len(revenues))
+	copy(sorted, revenues)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Revenue > sorted[j].Revenue
+	})
+
+	var totalRevenue float64
+	for _, revenue := range sorted {
+		totalRevenue += revenue.Revenue
+	}
+
+	rows := make([]ABCAnalysisRow, 0, len(sorted))
+	summary := map[string]int{ABCClassA: 0, ABCClassB: 0, ABCClassC: 0}
+	var cumulative float64
+	for _, revenue := range sorted {
+		row := ABCAnalysisRow{ProductRevenue: revenue}
+		if totalRevenue > 0 {
+			row.RevenuePercent = revenue.Revenue / totalRevenue * 100
+		}
+
+		switch {
+		case totalRevenue > 0 && cumulative < 80:
+			row.Class = ABCClassA
+		case totalRevenue > 0 && cumulative < 95:
+			row.Class = ABCClassB
+		default:
+			row.Class = ABCClassC
+		}
+
+		cumulative += row.RevenuePercent
+		row.CumulativePercent = cumulative
+		summary[row.Class]++
+		rows = append(rows, row)
+	}
+
+	return rows, summary, totalRevenue
+}
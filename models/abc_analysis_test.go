@@ -0,0 +1,63 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassifyABC(t *testing.T) {
+	revenues := []ProductRevenue{
+		{ProductID: "p4", Revenue: 4},
+		{ProductID: "p1", Revenue: 70},
+		{ProductID: "p5", Revenue: 3},
+		{ProductID: "p3", Revenue: 8},
+		{ProductID: "p2", Revenue: 15},
+	}
+	rows, summary, total := ClassifyABC(revenues)
+
+	if total != 100 {
+		t.Errorf("total revenue = %v, want 100", total)
+	}
+	want := []struct {
+		id         string
+		class      string
+		cumulative float64
+	}{
+		{"p1", ABCClassA, 70},
+		{"p2", ABCClassA, 85}, // crosses 80% but starts below it
+		{"p3", ABCClassB, 93},
+		{"p4", ABCClassB, 97}, // crosses 95% but starts below it
+		{"p5", ABCClassC, 100},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.ProductID != w.id || row.Class != w.class || math.Abs(row.CumulativePercent-w.cumulative) > 1e-9 {
+			t.Errorf("row %d = %s class %s cumulative %v, want %s class %s cumulative %v",
+				i, row.ProductID, row.Class, row.CumulativePercent, w.id, w.class, w.cumulative)
+		}
+	}
+	if summary[ABCClassA] != 2 || summary[ABCClassB] != 2 || summary[ABCClassC] != 1 {
+		t.Errorf("summary = %v, want A:2 B:2 C:1", summary)
+	}
+	if revenues[0].ProductID != "p4" {
+		t.Error("ClassifyABC reordered its input")
+	}
+}
+
+func TestClassifyABCWithoutRevenue(t *testing.T) {
+	rows, summary, total := ClassifyABC([]ProductRevenue{{ProductID: "p1"}, {ProductID: "p2"}})
+	if total != 0 {
+		t.Errorf("total revenue = %v, want 0", total)
+	}
+	for _, row := range rows {
+		if row.Class != ABCClassC || row.RevenuePercent != 0 {
+			t.Errorf("%s: class %s, %v%%, want C at 0%%", row.ProductID, row.Class, row.RevenuePercent)
+		}
+	}
+	if summary[ABCClassC] != 2 {
+		t.Errorf("summary = %v, want C:2", summary)
+	}
+}
@@ -6,6 +6,7 @@ type Account struct {
 	AccountNumber string `json:"accountNumber" bson:"accountNumber"`
 	BankName      string `json:"bankName" bson:"bankName"`
 	AccountType   string `json:"accountType" bson:"accountType"`
+	PromptPayID   string `json:"promptPayId,omitempty" bson:"promptPayId,omitempty"`
 	IsActive      bool   `json:"isActive" bson:"isActive"`
 }
 
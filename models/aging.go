@@ -0,0 +1,65 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Aging buckets, by days since the sale date
+const (
+	AgingCurrent = "current" // 0-30 วัน
+	Aging31To60  = "31-60"
+	Aging61To90  = "61-90"
+	AgingOver90  = "90+"
+)
+
+// AgingBuckets splits what is still owed on unpaid sales by how long ago they were made. Amounts include
+// VAT and shipping, less recorded payments.
+type AgingBuckets struct {
+	Current      float64 `bson:"current" json:"current"`
+	Days31To60   float64 `bson:"days31To60" json:"days31To60"`
+	Days61To90   float64 `bson:"days61To90" json:"days61To90"`
+	Over90       float64 `bson:"over90" json:"over90"`
+	Total        float64 `bson:"total" json:"total"`
+	InvoiceCount int64   `bson:"invoiceCount" json:"invoiceCount"`
+}
+
+// Add adds another customer's buckets to these
+func (b *AgingBuckets) Add(other AgingBuckets) {
+	b.Current += other.Current
+	b.Days31To60 += other.Days31To60
+	b.Days61To90 += other.Days61To90
+	b.Over90 += other.Over90
+	b.Total += other.Total
+	b.InvoiceCount += other.InvoiceCount
+}
+
+// CustomerAging is the aging of one customer's receivables
+type CustomerAging struct {
+	CustomerID   string `bson:"_id" json:"customerId"`
+	CustomerName string `bson:"customerName" json:"customerName"`
+	AgingBuckets `bson:",inline"`
+}
+
+// AgingReport is the aging of receivables per customer as of a date, with the totals of all customers
+type AgingReport struct {
+	AsOf      time.Time       `json:"asOf"`
+	Customers []CustomerAging `json:"customers"` // ยอดค้างมากที่สุดก่อน
+	Summary   AgingBuckets    `json:"summary"`
+}
+
+// NewAgingReport sorts the customers by what they owe, most first, and totals their buckets
+func NewAgingReport(asOf time.Time, customers []CustomerAging) *AgingReport {
+	if customers == nil {
+		customers = []CustomerAging{}
+	}
+	sort.SliceStable(customers, func(i, j int) bool {
+		return customers[i].Total > customers[j].Total
+	})
+
+	report := &AgingReport{AsOf: asOf, Customers: customers}
+	for _, customer := range customers {
+		report.Summary.Add(customer.AgingBuckets)
+	}
+	return report
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAgingReport(t *testing.T) {
+	asOf := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	customers := []CustomerAging{
+		{CustomerID: "c1", AgingBuckets: AgingBuckets{Current: 100, Total: 100, InvoiceCount: 1}},
+		{CustomerID: "c2", AgingBuckets: AgingBuckets{Days31To60: 200, Over90: 300, Total: 500, InvoiceCount: 2}},
+		{CustomerID: "c3", AgingBuckets: AgingBuckets{Days61To90: 250, Total: 250, InvoiceCount: 1}},
+	}
+	report := NewAgingReport(asOf, customers)
+
+	order := []string{"c2", "c3", "c1"}
+	for i, id := range order {
+		if report.Customers[i].CustomerID != id {
+			t.Errorf("customer %d = %s, want %s (most owed first)", i, report.Customers[i].CustomerID, id)
+		}
+	}
+	want := AgingBuckets{Current: 100, Days31To60: 200, Days61To90: 250, Over90: 300, Total: 850, InvoiceCount: 4}
+	if report.Summary != want {
+		t.Errorf("summary = %+v, want %+v", report.Summary, want)
+	}
+
+	if empty := NewAgingReport(asOf, nil); empty.Customers == nil || empty.Summary != (AgingBuckets{}) {
+		t.Errorf("report without customers = %+v, want an empty list and zero totals", empty)
+	}
+}
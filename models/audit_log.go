@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntityType represents the kind of entity an audit entry refers to
+type AuditEntityType string
+
+const (
+	AuditEntityProduct   AuditEntityType = "product"
+	AuditEntityCustomer  AuditEntityType = "customer"
+	AuditEntitySale      AuditEntityType = "sale"
+	AuditEntityPurchase  AuditEntityType = "purchase"
+	AuditEntityQuotation AuditEntityType = "quotation"
+	AuditEntitySupplier  AuditEntityType = "supplier"
+)
+
+// AuditAction represents the mutation recorded by an audit entry
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "create"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+)
+
+// AuditLog records a create/update/delete operation with before/after snapshots
+type AuditLog struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EntityType AuditEntityType    `bson:"entityType" json:"entityType"`
+	EntityID   string             `bson:"entityId" json:"entityId"`
+	Action     AuditAction        `bson:"action" json:"action"`
+	Method     string             `bson:"method" json:"method"` // HTTP method ที่ใช้
+	Path       string             `bson:"path" json:"path"`     // URL path ที่เรียก
+	Before     bson.Raw           `bson:"before,omitempty" json:"-"`
+	After      bson.Raw           `bson:"after,omitempty" json:"-"`
+	UserID     *string            `bson:"userId,omitempty" json:"userId,omitempty"`
+	IP         string             `bson:"ip" json:"ip"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// AuditLogFilter holds the optional filters for listing audit logs
+type AuditLogFilter struct {
+	EntityType string
+	EntityID   string
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+// MarshalJSON renders the before/after snapshots as relaxed extended JSON instead of raw bytes
+func (a AuditLog) MarshalJSON() ([]byte, error) {
+	type auditLogAlias AuditLog
+
+	before, err := rawToJSON(a.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := rawToJSON(a.After)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		auditLogAlias
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}{
+		auditLogAlias: auditLogAlias(a),
+		Before:        before,
+		After:         after,
+	})
+}
+
+// rawToJSON converts a BSON document to JSON, returning null for empty documents
+func rawToJSON(raw bson.Raw) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return bson.MarshalExtJSON(raw, false, false)
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Budget variance groupings
+const (
+	BudgetGroupByCategory = "category"
+	BudgetGroupBySupplier = "supplier"
+)
+
+// BudgetPeriodFormat is the layout of a budget period (YYYY-MM)
+const BudgetPeriodFormat = "2006-01"
+
+// Budget is the planned purchase spending for a month, for one category, one supplier, or overall when neither is set
+type Budget struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Period       string             `bson:"period" json:"period"`                             // YYYY-MM
+	Category     *string            `bson:"category,omitempty" json:"category,omitempty"`     // หมวดหมู่สินค้า
+	SupplierID   *string            `bson:"supplierId,omitempty" json:"supplierId,omitempty"` // ผู้จำหน่าย
+	BudgetAmount float64            `bson:"budgetAmount" json:"budgetAmount"`                 // ไม่รวม VAT และค่าขนส่ง
+	Notes        *string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted    bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt    *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type BudgetRequest struct {
+	Period       string  `json:"period" validate:"required"`
+	Category     *string `json:"category,omitempty" validate:"omitempty,excluded_with=SupplierID"`
+	SupplierID   *string `json:"supplierId,omitempty"`
+	BudgetAmount float64 `json:"budgetAmount" validate:"min=0"`
+	Notes        *string `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+func (br *BudgetRequest) ToBudget() *Budget {
+	now := time.Now()
+	return &Budget{
+		Period:       br.Period,
+		Category:     br.Category,
+		SupplierID:   br.SupplierID,
+		BudgetAmount: br.BudgetAmount,
+		Notes:        br.Notes,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+func (b *Budget) UpdateFromRequest(br *BudgetRequest) {
+	b.Period = br.Period
+	b.Category = br.Category
+	b.SupplierID = br.SupplierID
+	b.BudgetAmount = br.BudgetAmount
+	b.Notes = br.Notes
+	b.UpdatedAt = time.Now()
+}
+
+// PurchaseSpend is the purchase cost of one category or supplier in a period
+type PurchaseSpend struct {
+	Key    string  `bson:"_id" json:"key"` // หมวดหมู่ หรือรหัสผู้จำหน่าย ("" = ไม่ระบุ)
+	Name   string  `bson:"name" json:"name"`
+	Amount float64 `bson:"amount" json:"amount"`
+}
+
+// BudgetVarianceLine compares the budget of a category or supplier with what was spent.
+// Variance is budget minus actual, so a negative variance is overspending.
+type BudgetVarianceLine struct {
+	Key             string   `json:"key"`
+	Name            string   `json:"name"`
+	BudgetAmount    float64  `json:"budgetAmount"`
+	ActualAmount    float64  `json:"actualAmount"`
+	Variance        float64  `json:"variance"`
+	VariancePercent *float64 `json:"variancePercent"` // variance / budgetAmount × 100, null without a budget
+}
+
+// BudgetVarianceReport compares a month's purchase budgets with the actual purchase cost
+type BudgetVarianceReport struct {
+	Period  string               `json:"period"`
+	GroupBy string               `json:"groupBy"`
+	Lines   []BudgetVarianceLine `json:"lines"`
+	Total   BudgetVarianceLine   `json:"total"`
+}
+
+func newBudgetVarianceLine(key, name string, budgetAmount, actualAmount float64) BudgetVarianceLine {
+	line := BudgetVarianceLine{
+		Key:          key,
+		Name:         name,
+		BudgetAmount: roundMoney(budgetAmount),
+		ActualAmount: roundMoney(actualAmount),
+		Variance:     roundMoney(budgetAmount - actualAmount),
+	}
+	if budgetAmount != 0 {
+		percent := roundMoney((budgetAmount - actualAmount) / budgetAmount * 100)
+		line.VariancePercent = &percent
+	}
+	return line
+}
+
+// NewBudgetVarianceReport lines up the period's budgets for groupBy (category or supplier budgets) with the
+// actual spending, one line per category or supplier with a budget or spending, largest spending first.
+// supplierNames names the suppliers whose purchases carry no supplier name.
+// The total compares the overall budget, or the sum of the line budgets when there is none, with all spending.
+func NewBudgetVarianceReport(period, groupBy string, budgets []*Budget, spending []PurchaseSpend, supplierNames map[string]string) *BudgetVarianceReport {
+	budgetByKey := make(map[string]float64)
+	var overallBudget, lineBudgets float64
+	hasOverall := false
+	for _, budget := range budgets {
+		switch {
+		case budget.Category == nil && budget.SupplierID == nil:
+			overallBudget += budget.BudgetAmount
+			hasOverall = true
+		case groupBy == BudgetGroupByCategory && budget.Category != nil:
+			budgetByKey[*budget.Category] += budget.BudgetAmount
+			lineBudgets += budget.BudgetAmount
+		case groupBy == BudgetGroupBySupplier && budget.SupplierID != nil:
+			budgetByKey[*budget.SupplierID] += budget.BudgetAmount
+			lineBudgets += budget.BudgetAmount
+		}
+	}
+
+	lines := []BudgetVarianceLine{}
+	var totalSpent float64
+	for _, spend := range spending {
+		name := spend.Name
+		if name == "" && groupBy == BudgetGroupBySupplier {
+			name = supplierNames[spend.Key]
+		}
+		lines = append(lines, newBudgetVarianceLine(spend.Key, name, budgetByKey[spend.Key], spend.Amount))
+		delete(budgetByKey, spend.Key)
+		totalSpent += spend.Amount
+	}
+	for key, amount := range budgetByKey {
+		name := key
+		if groupBy == BudgetGroupBySupplier {
+			name = supplierNames[key]
+		}
+		lines = append(lines, newBudgetVarianceLine(key, name, amount, 0))
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].ActualAmount != lines[j].ActualAmount {
+			return lines[i].ActualAmount > lines[j].ActualAmount
+		}
+		return lines[i].Key < lines[j].Key
+	})
+
+	totalBudget := lineBudgets
+	if hasOverall {
+		totalBudget = overallBudget
+	}
+	return &BudgetVarianceReport{
+		Period:  period,
+		GroupBy: groupBy,
+		Lines:   lines,
+		Total:   newBudgetVarianceLine("", "", totalBudget, totalSpent),
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func variancePct(value float64) *float64 {
+	return &value
+}
+
+func TestNewBudgetVarianceReportByCategory(t *testing.T) {
+	category := func(name string) *string { return &name }
+	supplier := "s1"
+	budgets := []*Budget{
+		{BudgetAmount: 10000}, // overall
+		{Category: category("Box"), BudgetAmount: 5000},
+		{Category: category("Wrap"), BudgetAmount: 2000},
+		{Category: category("Cap"), BudgetAmount: 1000},
+		{SupplierID: &supplier, BudgetAmount: 3000}, // a supplier budget, left out when grouping by category
+	}
+	spending := []PurchaseSpend{
+		{Key: "Box", Name: "Box", Amount: 6000},
+		{Key: "Wrap", Name: "Wrap", Amount: 1500},
+		{Key: "", Name: "", Amount: 500},
+	}
+	report := NewBudgetVarianceReport("2024-01", BudgetGroupByCategory, budgets, spending, nil)
+
+	// Largest spending first; variance is budget minus actual
+	want := []BudgetVarianceLine{
+		{Key: "Box", Name: "Box", BudgetAmount: 5000, ActualAmount: 6000, Variance: -1000, VariancePercent: variancePct(-20)},
+		{Key: "Wrap", Name: "Wrap", BudgetAmount: 2000, ActualAmount: 1500, Variance: 500, VariancePercent: variancePct(25)},
+		{Key: "", Name: "", ActualAmount: 500, Variance: -500},
+		{Key: "Cap", Name: "Cap", BudgetAmount: 1000, Variance: 1000, VariancePercent: variancePct(100)},
+	}
+	if !reflect.DeepEqual(report.Lines, want) {
+		t.Errorf("lines = %+v, want %+v", report.Lines, want)
+	}
+	wantTotal := BudgetVarianceLine{BudgetAmount: 10000, ActualAmount: 8000, Variance: 2000, VariancePercent: variancePct(20)}
+	if !reflect.DeepEqual(report.Total, wantTotal) {
+		t.Errorf("total = %+v, want the overall budget %+v", report.Total, wantTotal)
+	}
+}
+
+func TestNewBudgetVarianceReportBySupplier(t *testing.T) {
+	s1, s2 := "s1", "s2"
+	budgets := []*Budget{
+		{SupplierID: &s1, BudgetAmount: 3000},
+		{SupplierID: &s2, BudgetAmount: 1000},
+	}
+	spending := []PurchaseSpend{
+		{Key: "s1", Name: "Acme Packaging", Amount: 3300},
+		{Key: "", Amount: 200},
+	}
+	report := NewBudgetVarianceReport("2024-01", BudgetGroupBySupplier, budgets, spending, map[string]string{"s2": "Beta Supplies"})
+
+	want := []BudgetVarianceLine{
+		{Key: "s1", Name: "Acme Packaging", BudgetAmount: 3000, ActualAmount: 3300, Variance: -300, VariancePercent: variancePct(-10)},
+		{Key: "", ActualAmount: 200, Variance: -200},
+		{Key: "s2", Name: "Beta Supplies", BudgetAmount: 1000, Variance: 1000, VariancePercent: variancePct(100)},
+	}
+	if !reflect.DeepEqual(report.Lines, want) {
+		t.Errorf("lines = %+v, want %+v", report.Lines, want)
+	}
+	// Without an overall budget the total is the sum of the supplier budgets
+	wantTotal := BudgetVarianceLine{BudgetAmount: 4000, ActualAmount: 3500, Variance: 500, VariancePercent: variancePct(12.5)}
+	if !reflect.DeepEqual(report.Total, wantTotal) {
+		t.Errorf("total = %+v, want %+v", report.Total, wantTotal)
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProductBundle is a set of products sold together at one price, e.g. shirt + belt + socks
+type ProductBundle struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description *string            `bson:"description,omitempty" json:"description,omitempty"`
+	BundlePrice float64            `bson:"bundlePrice" json:"bundlePrice"` // ราคาขายทั้งชุด (ไม่รวม VAT)
+	Components  []BundleComponent  `bson:"components" json:"components"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted   bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt   *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// BundleComponent is one product of a bundle and how many base units of it one bundle holds
+type BundleComponent struct {
+	ProductID string `bson:"productId" json:"productId" validate:"required"`
+	Quantity  int    `bson:"quantity" json:"quantity" validate:"min=1"`
+}
+
+type ProductBundleRequest struct {
+	Name        string            `json:"name" validate:"required,max=200"`
+	Description *string           `json:"description,omitempty" validate:"omitempty,max=1000"`
+	BundlePrice float64           `json:"bundlePrice" validate:"min=0"`
+	Components  []BundleComponent `json:"components" validate:"required,min=1,dive"`
+}
+
+// BundleItem adds a bundle to a sale; it is expanded into a sale item per component
+type BundleItem struct {
+	BundleID string `json:"bundleId" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"` // จำนวนชุด
+}
+
+// BundleAvailability reports whether there is enough stock of every component to sell a quantity of a bundle
+type BundleAvailability struct {
+	BundleID     string                        `json:"bundleId"`
+	Quantity     int                           `json:"quantity"`     // จำนวนชุดที่ตรวจสอบ
+	Available    bool                          `json:"available"`    // ทุกสินค้ามีสต็อกพอ
+	MaxAvailable int                           `json:"maxAvailable"` // จำนวนชุดสูงสุดที่ขายได้จากสต็อกปัจจุบัน
+	Components   []BundleComponentAvailability `json:"components"`
+}
+
+type BundleComponentAvailability struct {
+	ProductID   string `json:"productId"`
+	ProductName string `json:"productName"`
+	Required    int    `json:"required"` // component quantity x bundle quantity
+	InStock     int    `json:"inStock"`
+	Sufficient  bool   `json:"sufficient"`
+}
+
+func (br *ProductBundleRequest) ToProductBundle() *ProductBundle {
+	now := time.Now()
+	bundle := &ProductBundle{
+		CreatedAt: now,
+	}
+	bundle.UpdateFromRequest(br)
+	return bundle
+}
+
+func (b *ProductBundle) UpdateFromRequest(br *ProductBundleRequest) {
+	b.Name = br.Name
+	b.Description = br.Description
+	b.BundlePrice = br.BundlePrice
+	b.Components = br.Components
+	b.UpdatedAt = time.Now()
+}
+
+// Expand turns quantity bundles into one sale item per component. The bundle price is shared between the
+// components in proportion to their list value (list price x quantity), or to their quantity when none of
+// them has a list price. Shares are rounded to satang with the last component taking the remainder, so the
+// lines always add up to the bundle price.
+func (b *ProductBundle) Expand(quantity int, listPrices map[string]float64) []SaleItem {
+	weights := make([]float64, len(b.Components))
+	totalWeight := 0.0
+	for i, component := range b.Components {
+		weights[i] = listPrices[component.ProductID] * float64(component.Quantity)
+		totalWeight += weights[i]
+	}
+	if totalWeight <= 0 {
+		totalWeight = 0
+		for i, component := range b.Components {
+			weights[i] = float64(component.Quantity)
+			totalWeight += weights[i]
+		}
+	}
+
+	bundleTotal := roundMoney(b.BundlePrice * float64(quantity))
+	bundleID := b.ID.Hex()
+	items := make([]SaleItem, 0, len(b.Components))
+	remaining := bundleTotal
+	for i, component := range b.Components {
+		share := remaining
+		if i < len(b.Components)-1 {
+			share = roundMoney(bundleTotal * weights[i] / totalWeight)
+			remaining -= share
+		}
+
+		itemQuantity := float64(component.Quantity * quantity)
+		items = append(items, SaleItem{
+			ProductID:  component.ProductID,
+			Quantity:   itemQuantity,
+			UnitPrice:  share / itemQuantity,
+			TotalPrice: share,
+			BundleID:   bundleID,
+		})
+	}
+	return items
+}
+
+// CheckAvailability compares the stock of each component with what quantity bundles need; stock maps product
+// ID to its actual stock and names maps it to the product name.
+func (b *ProductBundle) CheckAvailability(quantity int, stock map[string]int, names map[string]string) *BundleAvailability {
+	availability := &BundleAvailability{
+		BundleID:   b.ID.Hex(),
+		Quantity:   quantity,
+		Available:  true,
+		Components: make([]BundleComponentAvailability, 0, len(b.Components)),
+	}
+
+	// Repeated components share the same stock
+	required := make(map[string]int)
+	for _, component := range b.Components {
+		required[component.ProductID] += component.Quantity
+	}
+
+	maxAvailable := -1
+	for productID, perBundle := range required {
+		inStock := stock[productID]
+		if fits := max(inStock, 0) / perBundle; maxAvailable < 0 || fits < maxAvailable {
+			maxAvailable = fits
+		}
+	}
+	availability.MaxAvailable = max(maxAvailable, 0)
+
+	for _, component := range b.Components {
+		total := required[component.ProductID] * quantity
+		line := BundleComponentAvailability{
+			ProductID:   component.ProductID,
+			ProductName: names[component.ProductID],
+			Required:    component.Quantity * quantity,
+			InStock:     stock[component.ProductID],
+			Sufficient:  stock[component.ProductID] >= total,
+		}
+		if !line.Sufficient {
+			availability.Available = false
+		}
+		availability.Components = append(availability.Components, line)
+	}
+	return availability
+}
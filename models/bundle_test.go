@@ -0,0 +1,85 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBundleExpandSharesPriceByListValue(t *testing.T) {
+	bundle := ProductBundle{BundlePrice: 100, Components: []BundleComponent{
+		{ProductID: "a", Quantity: 1},
+		{ProductID: "b", Quantity: 2},
+		{ProductID: "c", Quantity: 1},
+	}}
+	items := bundle.Expand(3, map[string]float64{"a": 50, "b": 10, "c": 30})
+
+	want := []struct {
+		productID string
+		quantity  float64
+		total     float64
+	}{
+		{"a", 3, 150},
+		{"b", 6, 60},
+		{"c", 3, 90},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.ProductID != w.productID || item.Quantity != w.quantity || math.Abs(item.TotalPrice-w.total) > 1e-9 {
+			t.Errorf("item %d = %s x%v for %v, want %s x%v for %v", i, item.ProductID, item.Quantity, item.TotalPrice, w.productID, w.quantity, w.total)
+		}
+		if math.Abs(item.UnitPrice*item.Quantity-item.TotalPrice) > 1e-9 {
+			t.Errorf("item %d: unit price %v x %v != total %v", i, item.UnitPrice, item.Quantity, item.TotalPrice)
+		}
+	}
+}
+
+func TestBundleExpandAddsUpToBundlePrice(t *testing.T) {
+	// Without list prices the price is shared by quantity, and the last line takes the rounding remainder
+	bundle := ProductBundle{BundlePrice: 100, Components: []BundleComponent{
+		{ProductID: "a", Quantity: 1},
+		{ProductID: "b", Quantity: 1},
+		{ProductID: "c", Quantity: 1},
+	}}
+	items := bundle.Expand(1, nil)
+
+	var total float64
+	for _, item := range items {
+		total += item.TotalPrice
+	}
+	if math.Abs(total-100) > 1e-9 {
+		t.Errorf("lines add up to %v, want 100", total)
+	}
+	if items[0].TotalPrice != 33.33 || math.Abs(items[2].TotalPrice-33.34) > 1e-9 {
+		t.Errorf("lines = %v, %v, %v, want 33.33, 33.33, 33.34", items[0].TotalPrice, items[1].TotalPrice, items[2].TotalPrice)
+	}
+}
+
+func TestBundleCheckAvailability(t *testing.T) {
+	bundle := ProductBundle{Components: []BundleComponent{
+		{ProductID: "a", Quantity: 1},
+		{ProductID: "b", Quantity: 2},
+		{ProductID: "a", Quantity: 1}, // shares a's stock with the first line
+	}}
+	stock := map[string]int{"a": 5, "b": 3}
+
+	availability := bundle.CheckAvailability(2, stock, map[string]string{"a": "Box"})
+	if availability.Available {
+		t.Error("available, but two bundles need 4 of b with 3 in stock")
+	}
+	if availability.MaxAvailable != 1 {
+		t.Errorf("max available = %d, want 1", availability.MaxAvailable)
+	}
+	if line := availability.Components[0]; !line.Sufficient || line.Required != 2 || line.ProductName != "Box" {
+		t.Errorf("a: %+v, want 2 required and sufficient", line)
+	}
+	if line := availability.Components[1]; line.Sufficient || line.Required != 4 {
+		t.Errorf("b: %+v, want 4 required and insufficient", line)
+	}
+
+	if availability := bundle.CheckAvailability(1, stock, nil); !availability.Available {
+		t.Errorf("one bundle: %+v, want available", availability)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Category is a product category; its abbreviation prefixes the SKU IDs and product codes of its products
+type Category struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`                 // ชื่อหมวดหมู่ (ภาษาไทย)
+	Abbreviation string             `bson:"abbreviation" json:"abbreviation"` // ตัวย่อสำหรับ SKU เช่น BT, CP-SCR
+	English      string             `bson:"english" json:"english"`           // ชื่อภาษาอังกฤษ
+	IsActive     bool               `bson:"isActive" json:"isActive"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted    bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt    *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type CategoryRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Abbreviation string `json:"abbreviation" validate:"required,max=20"`
+	English      string `json:"english" validate:"max=100"`
+	IsActive     *bool  `json:"isActive,omitempty"` // default true
+}
+
+// Normalize trims the names and upper-cases the abbreviation
+func (cr *CategoryRequest) Normalize() {
+	cr.Name = strings.TrimSpace(cr.Name)
+	cr.Abbreviation = strings.ToUpper(strings.TrimSpace(cr.Abbreviation))
+	cr.English = strings.TrimSpace(cr.English)
+}
+
+func (cr *CategoryRequest) ToCategory() *Category {
+	now := time.Now()
+	category := &Category{
+		Name:         cr.Name,
+		Abbreviation: cr.Abbreviation,
+		English:      cr.English,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if cr.IsActive != nil {
+		category.IsActive = *cr.IsActive
+	}
+	return category
+}
+
+func (c *Category) UpdateFromRequest(cr *CategoryRequest) {
+	c.Name = cr.Name
+	c.Abbreviation = cr.Abbreviation
+	c.English = cr.English
+	if cr.IsActive != nil {
+		c.IsActive = *cr.IsActive
+	}
+	c.UpdatedAt = time.Now()
+}
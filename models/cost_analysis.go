@@ -0,0 +1,103 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// PurchaseCostRecord is the quantity of a product bought on one purchase and its unit cost after discounts
+type PurchaseCostRecord struct {
+	PurchaseID   string    `bson:"purchaseId" json:"purchaseId"`
+	PurchaseCode string    `bson:"purchaseCode" json:"purchaseCode"`
+	PurchaseDate time.Time `bson:"purchaseDate" json:"purchaseDate"`
+	StockType    StockType `bson:"stockType" json:"stockType"`
+	Quantity     int       `bson:"quantity" json:"quantity"` // จำนวนสุทธิที่รับเข้าจากใบซื้อนี้
+	UnitCost     float64   `bson:"unitCost" json:"unitCost"` // ราคาต่อหน่วยหลังหักส่วนลด (ไม่รวม VAT)
+}
+
+// MonthlyCost is the weighted average purchase cost of a product in one month
+type MonthlyCost struct {
+	Month       string  `json:"month"` // YYYY-MM
+	Quantity    int     `json:"quantity"`
+	TotalCost   float64 `json:"totalCost"`
+	AverageCost float64 `json:"averageCost"`
+}
+
+// CostAnalysis compares a product's true weighted average purchase cost (sum of cost × quantity / sum of
+// quantity) with the moving averages kept in its price info
+type CostAnalysis struct {
+	ProductID             string               `json:"productId"`
+	ProductName           string               `json:"productName"`
+	SKUID                 string               `json:"skuId"`
+	TotalQuantity         int                  `json:"totalQuantity"`
+	TotalCost             float64              `json:"totalCost"`
+	WeightedAverageCost   float64              `json:"weightedAverageCost"`
+	WeightedAverageVAT    float64              `json:"weightedAverageVAT"`    // เฉพาะการซื้อแบบ VAT
+	WeightedAverageNonVAT float64              `json:"weightedAverageNonVAT"` // เฉพาะการซื้อแบบ Non-VAT
+	PriceAverageVAT       float64              `json:"priceAverageVAT"`       // price.purchaseVAT.average
+	PriceAverageNonVAT    float64              `json:"priceAverageNonVAT"`    // price.purchaseNonVAT.average
+	ByMonth               []MonthlyCost        `json:"byMonth"`
+	Purchases             []PurchaseCostRecord `json:"purchases"`
+}
+
+// NewCostAnalysis computes the weighted average costs of a product from its purchase records
+func NewCostAnalysis(product *Product, records []PurchaseCostRecord) *CostAnalysis {
+	analysis := &CostAnalysis{
+		ProductID:          product.ID.Hex(),
+		ProductName:        product.Name,
+		SKUID:              product.SKUID,
+		PriceAverageVAT:    product.Price.PurchaseVAT.Average,
+		PriceAverageNonVAT: product.Price.PurchaseNonVAT.Average,
+		ByMonth:            []MonthlyCost{},
+		Purchases:          records,
+	}
+	if analysis.Purchases == nil {
+		analysis.Purchases = []PurchaseCostRecord{}
+	}
+
+	var vatQuantity, nonVATQuantity int
+	var vatCost, nonVATCost float64
+	months := make(map[string]*MonthlyCost)
+	for _, record := range records {
+		cost := record.UnitCost * float64(record.Quantity)
+		analysis.TotalQuantity += record.Quantity
+		analysis.TotalCost += cost
+
+		if record.StockType == StockTypeVAT {
+			vatQuantity += record.Quantity
+			vatCost += cost
+		} else {
+			nonVATQuantity += record.Quantity
+			nonVATCost += cost
+		}
+
+		key := record.PurchaseDate.Format("2006-01")
+		month, ok := months[key]
+		if !ok {
+			month = &MonthlyCost{Month: key}
+			months[key] = month
+		}
+		month.Quantity += record.Quantity
+		month.TotalCost += cost
+	}
+
+	analysis.WeightedAverageCost = averageCost(analysis.TotalCost, analysis.TotalQuantity)
+	analysis.WeightedAverageVAT = averageCost(vatCost, vatQuantity)
+	analysis.WeightedAverageNonVAT = averageCost(nonVATCost, nonVATQuantity)
+
+	for _, month := range months {
+		month.AverageCost = averageCost(month.TotalCost, month.Quantity)
+		analysis.ByMonth = append(analysis.ByMonth, *month)
+	}
+	sort.Slice(analysis.ByMonth, func(i, j int) bool {
+		return analysis.ByMonth[i].Month < analysis.ByMonth[j].Month
+	})
+	return analysis
+}
+
+func averageCost(totalCost float64, quantity int) float64 {
+	if quantity <= 0 {
+		return 0
+	}
+	return totalCost / float64(quantity)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewCostAnalysis(t *testing.T) {
+	product := &Product{Name: "Kraft Box", SKUID: "BOX-0001"}
+	product.Price.PurchaseVAT.Average = 93
+	product.Price.PurchaseNonVAT.Average = 81
+
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	records := []PurchaseCostRecord{
+		{PurchaseID: "po1", PurchaseDate: date(time.January, 5), StockType: StockTypeVAT, Quantity: 10, UnitCost: 100},
+		{PurchaseID: "po2", PurchaseDate: date(time.January, 20), StockType: StockTypeNonVAT, Quantity: 30, UnitCost: 80},
+		{PurchaseID: "po3", PurchaseDate: date(time.February, 10), StockType: StockTypeVAT, Quantity: 40, UnitCost: 95},
+	}
+	analysis := NewCostAnalysis(product, records)
+
+	// (10×100 + 30×80 + 40×95) / 80
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"totalQuantity", float64(analysis.TotalQuantity), 80},
+		{"totalCost", analysis.TotalCost, 7200},
+		{"weightedAverageCost", analysis.WeightedAverageCost, 90},
+		{"weightedAverageVAT", analysis.WeightedAverageVAT, 96},
+		{"weightedAverageNonVAT", analysis.WeightedAverageNonVAT, 80},
+		{"priceAverageVAT", analysis.PriceAverageVAT, 93},
+		{"priceAverageNonVAT", analysis.PriceAverageNonVAT, 81},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	wantMonths := []MonthlyCost{
+		{Month: "2025-01", Quantity: 40, TotalCost: 3400, AverageCost: 85},
+		{Month: "2025-02", Quantity: 40, TotalCost: 3800, AverageCost: 95},
+	}
+	if !reflect.DeepEqual(analysis.ByMonth, wantMonths) {
+		t.Errorf("byMonth = %+v, want %+v", analysis.ByMonth, wantMonths)
+	}
+}
+
+func TestNewCostAnalysisWithoutPurchases(t *testing.T) {
+	analysis := NewCostAnalysis(&Product{}, nil)
+	if analysis.WeightedAverageCost != 0 || analysis.Purchases == nil || analysis.ByMonth == nil {
+		t.Errorf("analysis = %+v, want zero costs and empty lists", analysis)
+	}
+}
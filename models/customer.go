@@ -1,31 +1,42 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Customer struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	CustomerCode  string             `bson:"customerCode" json:"customerCode"`
-	CompanyName   string             `bson:"companyName" json:"companyName"`
-	ContactName   string             `bson:"contactName" json:"contactName"`
-	TaxID         string             `bson:"taxId" json:"taxId"`
-	Phone         string             `bson:"phone" json:"phone"`
-	Address       string             `bson:"address" json:"address"`
-	ContactMethod string             `bson:"contactMethod" json:"contactMethod"`
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CustomerCode       string             `bson:"customerCode" json:"customerCode"`
+	CompanyName        string             `bson:"companyName" json:"companyName"`
+	ContactName        string             `bson:"contactName" json:"contactName"`
+	TaxID              string             `bson:"taxId" json:"taxId"`
+	Phone              string             `bson:"phone" json:"phone"`
+	Address            string             `bson:"address" json:"address"`
+	ContactMethod      string             `bson:"contactMethod" json:"contactMethod"`
+	Email              string             `bson:"email,omitempty" json:"email"`                           // เก็บเป็นตัวพิมพ์เล็ก, ไม่ซ้ำกับลูกค้าอื่น
+	CreditLimit        float64            `bson:"creditLimit" json:"creditLimit"`                         // วงเงินเครดิต (0 = ไม่จำกัด)
+	OutstandingBalance float64            `bson:"outstandingBalance" json:"outstandingBalance"`           // ยอดขายที่ยังไม่ชำระ
+	LastContactAt      *time.Time         `bson:"lastContactAt,omitempty" json:"lastContactAt,omitempty"` // บันทึกการติดต่อล่าสุด
+	CreatedAt          time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt          time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version            int                `bson:"version" json:"version"`
+	IsDeleted          bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt          *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
 type CustomerRequest struct {
-	CompanyName   string `json:"companyName" bson:"companyName"`
-	ContactName   string `json:"contactName" bson:"contactName"`
-	TaxID         string `json:"taxId" bson:"taxId"`
-	Phone         string `json:"phone" bson:"phone"`
-	Address       string `json:"address" bson:"address"`
-	ContactMethod string `json:"contactMethod" bson:"contactMethod"`
+	CompanyName   string `json:"companyName" bson:"companyName" validate:"required_without=ContactName,max=200"`
+	ContactName   string `json:"contactName" bson:"contactName" validate:"max=200"`
+	TaxID         string `json:"taxId" bson:"taxId" validate:"max=20"`
+	Phone         string `json:"phone" bson:"phone" validate:"max=50"`
+	Address       string `json:"address" bson:"address" validate:"max=500"`
+	ContactMethod string `json:"contactMethod" bson:"contactMethod" validate:"max=100"`
+	Email         string `json:"email" bson:"email" validate:"max=254"`
+	Version       *int   `json:"version,omitempty" bson:"-"` // required when updating
 }
 
 func (cr *CustomerRequest) ToCustomer() *Customer {
@@ -37,6 +48,7 @@ func (cr *CustomerRequest) ToCustomer() *Customer {
 		Phone:         cr.Phone,
 		Address:       cr.Address,
 		ContactMethod: cr.ContactMethod,
+		Email:         NormalizeEmail(cr.Email),
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
@@ -49,5 +61,80 @@ func (c *Customer) UpdateFromRequest(cr *CustomerRequest) {
 	c.Phone = cr.Phone
 	c.Address = cr.Address
 	c.ContactMethod = cr.ContactMethod
+	c.Email = NormalizeEmail(cr.Email)
 	c.UpdatedAt = time.Now()
 }
+
+// CustomerPatchRequest represents a partial customer update; only non-nil fields are changed
+type CustomerPatchRequest struct {
+	CompanyName   *string `json:"companyName,omitempty"`
+	ContactName   *string `json:"contactName,omitempty"`
+	TaxID         *string `json:"taxId,omitempty"`
+	Phone         *string `json:"phone,omitempty"`
+	Address       *string `json:"address,omitempty"`
+	ContactMethod *string `json:"contactMethod,omitempty"`
+	Email         *string `json:"email,omitempty"`
+}
+
+// ToUpdateFields returns the $set fields for the non-nil values of the patch, or an empty map if nothing is set.
+// An empty email is returned as nil, which CustomerRepository.Patch unsets.
+func (cr *CustomerPatchRequest) ToUpdateFields() bson.M {
+	fields := bson.M{}
+	if cr.CompanyName != nil {
+		fields["companyName"] = *cr.CompanyName
+	}
+	if cr.ContactName != nil {
+		fields["contactName"] = *cr.ContactName
+	}
+	if cr.TaxID != nil {
+		fields["taxId"] = *cr.TaxID
+	}
+	if cr.Phone != nil {
+		fields["phone"] = *cr.Phone
+	}
+	if cr.Address != nil {
+		fields["address"] = *cr.Address
+	}
+	if cr.ContactMethod != nil {
+		fields["contactMethod"] = *cr.ContactMethod
+	}
+	if cr.Email != nil {
+		if email := NormalizeEmail(*cr.Email); email != "" {
+			fields["email"] = email
+		} else {
+			fields["email"] = nil
+		}
+	}
+
+	if len(fields) > 0 {
+		fields["updatedAt"] = time.Now()
+	}
+	return fields
+}
+
+// NormalizeEmail trims and lowercases an email address, so lookups and the unique index ignore case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreditLimitRequest represents the request body for setting a customer's credit limit
+type CreditLimitRequest struct {
+	CreditLimit float64 `json:"creditLimit"`
+}
+
+// TransactionTotals is the aggregated count, amount and latest date of a customer's purchases or sales
+type TransactionTotals struct {
+	Count       int64      `bson:"count" json:"count"`
+	TotalAmount float64    `bson:"totalAmount" json:"totalAmount"`
+	LastDate    *time.Time `bson:"lastDate,omitempty" json:"lastDate,omitempty"`
+}
+
+// CustomerSummary summarizes all transactions of a customer
+type CustomerSummary struct {
+	CustomerID          string     `json:"customerId"`
+	TotalPurchases      int64      `json:"totalPurchases"`      // จำนวนรายการซื้อ
+	TotalSales          int64      `json:"totalSales"`          // จำนวนรายการขาย
+	TotalPurchaseAmount float64    `json:"totalPurchaseAmount"` // ยอดซื้อรวม
+	TotalSaleAmount     float64    `json:"totalSaleAmount"`     // ยอดขายรวม
+	LastTransactionDate *time.Time `json:"lastTransactionDate,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "fmt"
+
+// FormatCustomerCode formats the nth customer code: C-0001, C-0002, etc.
+func FormatCustomerCode(n int) string {
+	return fmt.Sprintf("C-%04d", n)
+}
+
+// CustomerCodeChange is one customer whose code was changed by renumbering
+type CustomerCodeChange struct {
+	CustomerID  string `json:"customerId"`
+	CompanyName string `json:"companyName"`
+	OldCode     string `json:"oldCode"`
+	NewCode     string `json:"newCode"`
+}
+
+// CustomerRenumberResult is the outcome of renumbering every customer's code
+type CustomerRenumberResult struct {
+	Total   int                  `json:"total"`   // จำนวนลูกค้าทั้งหมด
+	Changed int                  `json:"changed"` // จำนวนลูกค้าที่เปลี่ยนรหัส
+	Changes []CustomerCodeChange `json:"changes"`
+}
+
+// RenumberCustomers assigns sequential codes from C-0001 to customers in the order given and returns the customers
+// whose code changes. Deleted customers keep a code, as codes are unique across deleted customers too, so they are
+// numbered after the others to keep the codes of active customers gapless.
+func RenumberCustomers(customers []*Customer) []CustomerCodeChange {
+	ordered := make([]*Customer, 0, len(customers))
+	for _, customer := range customers {
+		if !customer.IsDeleted {
+			ordered = append(ordered, customer)
+		}
+	}
+	for _, customer := range customers {
+		if customer.IsDeleted {
+			ordered = append(ordered, customer)
+		}
+	}
+
+	changes := []CustomerCodeChange{}
+	for i, customer := range ordered {
+		code := FormatCustomerCode(i + 1)
+		if customer.CustomerCode != code {
+			changes = append(changes, CustomerCodeChange{
+				CustomerID:  customer.ID.Hex(),
+				CompanyName: customer.CompanyName,
+				OldCode:     customer.CustomerCode,
+				NewCode:     code,
+			})
+		}
+	}
+	return changes
+}
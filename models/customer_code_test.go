@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestRenumberCustomers(t *testing.T) {
+	customers := []*Customer{
+		{CompanyName: "A", CustomerCode: "C-0001"},
+		{CompanyName: "Gone", CustomerCode: "C-0002", IsDeleted: true},
+		{CompanyName: "B", CustomerCode: "C-0003"},
+		{CompanyName: "C", CustomerCode: "C-0007"},
+	}
+	changes := RenumberCustomers(customers)
+
+	// Active customers close the gaps; the deleted one moves after them
+	want := []CustomerCodeChange{
+		{CompanyName: "B", OldCode: "C-0003", NewCode: "C-0002"},
+		{CompanyName: "C", OldCode: "C-0007", NewCode: "C-0003"},
+		{CompanyName: "Gone", OldCode: "C-0002", NewCode: "C-0004"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i, w := range want {
+		got := changes[i]
+		if got.CompanyName != w.CompanyName || got.OldCode != w.OldCode || got.NewCode != w.NewCode {
+			t.Errorf("change %d = %s %s -> %s, want %s %s -> %s", i, got.CompanyName, got.OldCode, got.NewCode, w.CompanyName, w.OldCode, w.NewCode)
+		}
+	}
+	if customers[2].CustomerCode != "C-0003" {
+		t.Error("RenumberCustomers changed a customer's code itself")
+	}
+
+	if changes := RenumberCustomers([]*Customer{{CustomerCode: "C-0001"}}); len(changes) != 0 || changes == nil {
+		t.Errorf("gapless codes: changes = %v, want none", changes)
+	}
+	if got := FormatCustomerCode(12345); got != "C-12345" {
+		t.Errorf("FormatCustomerCode(12345) = %s, want C-12345", got)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Customer note types
+const (
+	CustomerNoteTypeCall  = "call"
+	CustomerNoteTypeEmail = "email"
+	CustomerNoteTypeVisit = "visit"
+	CustomerNoteTypeOther = "other"
+)
+
+// CustomerNote is a note on an interaction with a customer, kept as the customer's activity log
+type CustomerNote struct {
+	NoteID     primitive.ObjectID `bson:"_id,omitempty" json:"noteId"`
+	CustomerID string             `bson:"customerId" json:"customerId"`
+	NoteText   string             `bson:"noteText" json:"noteText"`
+	NoteType   string             `bson:"noteType" json:"noteType"`                       // call, email, visit, other
+	CreatedBy  *string            `bson:"createdBy,omitempty" json:"createdBy,omitempty"` // ผู้บันทึก (X-User-ID)
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type CustomerNoteRequest struct {
+	NoteText string `json:"noteText" validate:"required,max=2000"`
+	NoteType string `json:"noteType" validate:"omitempty,oneof=call email visit other"` // "" = other
+}
+
+func (nr *CustomerNoteRequest) ToCustomerNote(customerID string, createdBy *string) *CustomerNote {
+	noteType := nr.NoteType
+	if noteType == "" {
+		noteType = CustomerNoteTypeOther
+	}
+	return &CustomerNote{
+		CustomerID: customerID,
+		NoteText:   nr.NoteText,
+		NoteType:   noteType,
+		CreatedBy:  createdBy,
+		CreatedAt:  time.Now(),
+	}
+}
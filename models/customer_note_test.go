@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCustomerNoteRequestToCustomerNote(t *testing.T) {
+	createdBy := "user-1"
+	tests := []struct {
+		noteType string
+		want     string
+	}{
+		{"call", CustomerNoteTypeCall},
+		{"visit", CustomerNoteTypeVisit},
+		{"", CustomerNoteTypeOther},
+	}
+	for _, tt := range tests {
+		before := time.Now()
+		request := &CustomerNoteRequest{NoteText: "Asked for a quote on 500 boxes", NoteType: tt.noteType}
+		note := request.ToCustomerNote("customer-1", &createdBy)
+		if note.NoteType != tt.want {
+			t.Errorf("NoteType %q: got %q, want %q", tt.noteType, note.NoteType, tt.want)
+		}
+		if note.CustomerID != "customer-1" || note.NoteText != request.NoteText || note.CreatedBy != &createdBy {
+			t.Errorf("note = %+v, want the request's customer, text and creator", note)
+		}
+		if note.CreatedAt.Before(before) || note.CreatedAt.After(time.Now()) {
+			t.Errorf("CreatedAt = %v, want now", note.CreatedAt)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// TopProduct is a best-selling product in a period
+type TopProduct struct {
+	ProductID   string  `bson:"_id" json:"productId"`
+	ProductName string  `bson:"productName" json:"productName"`
+	Quantity    float64 `bson:"quantity" json:"quantity"`
+	Revenue     float64 `bson:"revenue" json:"revenue"`
+}
+
+// Dashboard is the financial summary of a period. Revenue and cost exclude VAT and shipping.
+type Dashboard struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+
+	SalesRevenue   float64 `json:"salesRevenue"`   // ยอดขายหลังหักส่วนลด ไม่รวม VAT
+	PurchaseCost   float64 `json:"purchaseCost"`   // ยอดซื้อหลังหักส่วนลด ไม่รวม VAT
+	GrossProfit    float64 `json:"grossProfit"`    // salesRevenue - purchaseCost
+	GrossMarginPct float64 `json:"grossMarginPct"` // grossProfit / salesRevenue × 100
+
+	NewCustomers  int64 `json:"newCustomers"`
+	SalesCount    int64 `json:"salesCount"`
+	PurchaseCount int64 `json:"purchaseCount"`
+
+	OutstandingReceivables float64 `json:"outstandingReceivables"` // ยอดค้างรับทั้งหมด ณ ปัจจุบัน (ไม่จำกัดช่วงเวลา)
+
+	TopProductsByQuantity []TopProduct `json:"topProductsByQuantity"`
+	TopProductsByRevenue  []TopProduct `json:"topProductsByRevenue"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Delivery statuses of sales
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusInTransit = "in_transit"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// DeliveryStatuses are the delivery statuses in the order goods go through them
+var DeliveryStatuses = []string{DeliveryStatusPending, DeliveryStatusInTransit, DeliveryStatusDelivered, DeliveryStatusFailed}
+
+// SaleDeliveryRequest is the body of PUT /api/sales/{id}/delivery; the dates it leaves out are cleared
+type SaleDeliveryRequest struct {
+	DeliveryStatus       string      `json:"deliveryStatus" validate:"required,oneof=pending in_transit delivered failed"`
+	ExpectedDeliveryDate *CustomTime `json:"expectedDeliveryDate,omitempty"`
+	ActualDeliveryDate   *CustomTime `json:"actualDeliveryDate,omitempty"` // default now when delivered
+}
+
+// CurrentDeliveryStatus is the sale's delivery status; sales saved before deliveries were tracked are pending
+func (s *Sale) CurrentDeliveryStatus() string {
+	if s.DeliveryStatus == "" {
+		return DeliveryStatusPending
+	}
+	return s.DeliveryStatus
+}
+
+// UpdateDelivery sets the sale's delivery status and dates from the request
+func (s *Sale) UpdateDelivery(dr *SaleDeliveryRequest) {
+	s.DeliveryStatus = dr.DeliveryStatus
+	s.ExpectedDeliveryDate = nil
+	if dr.ExpectedDeliveryDate != nil {
+		s.ExpectedDeliveryDate = &dr.ExpectedDeliveryDate.Time
+	}
+	s.ActualDeliveryDate = nil
+	if dr.ActualDeliveryDate != nil {
+		s.ActualDeliveryDate = &dr.ActualDeliveryDate.Time
+	} else if dr.DeliveryStatus == DeliveryStatusDelivered {
+		now := time.Now()
+		s.ActualDeliveryDate = &now
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// SaleDelivery is the delivery of one sale, as read for the delivery performance report
+type SaleDelivery struct {
+	DeliveryStatus       string     `bson:"deliveryStatus"`
+	ExpectedDeliveryDate *time.Time `bson:"expectedDeliveryDate"`
+	ActualDeliveryDate   *time.Time `bson:"actualDeliveryDate"`
+}
+
+// DeliveryPerformance is how the deliveries of the sales dated StartDate..EndDate went. Only sales delivered with
+// both an expected and an actual delivery date are on time or late.
+type DeliveryPerformance struct {
+	StartDate       time.Time      `json:"startDate"`
+	EndDate         time.Time      `json:"endDate"`
+	TotalSales      int            `json:"totalSales"`
+	ByStatus        map[string]int `json:"byStatus"`        // จำนวนรายการขายตามสถานะการจัดส่ง
+	OnTimeCount     int            `json:"onTimeCount"`     // ส่งถึงภายในวันที่กำหนด
+	LateCount       int            `json:"lateCount"`       // ส่งถึงหลังวันที่กำหนด
+	OnTimeRate      float64        `json:"onTimeRate"`      // % ของรายการที่ส่งตรงเวลา
+	AverageDaysLate float64        `json:"averageDaysLate"` // เฉลี่ยเฉพาะรายการที่ส่งช้า
+}
+
+// NewDeliveryPerformance counts the deliveries by status and works out the on-time rate and the average days late.
+// A delivery is on time when it arrived on or before the expected day; days late are counted in calendar days.
+func NewDeliveryPerformance(from, to time.Time, deliveries []SaleDelivery) *DeliveryPerformance {
+	performance := &DeliveryPerformance{
+		StartDate:  from,
+		EndDate:    to,
+		TotalSales: len(deliveries),
+		ByStatus:   make(map[string]int, len(DeliveryStatuses)),
+	}
+	for _, status := range DeliveryStatuses {
+		performance.ByStatus[status] = 0
+	}
+
+	var totalDaysLate int
+	for _, delivery := range deliveries {
+		status := delivery.DeliveryStatus
+		if status == "" {
+			status = DeliveryStatusPending
+		}
+		performance.ByStatus[status]++
+
+		if status != DeliveryStatusDelivered || delivery.ExpectedDeliveryDate == nil || delivery.ActualDeliveryDate == nil {
+			continue
+		}
+		if daysLate := DaysLate(*delivery.ExpectedDeliveryDate, *delivery.ActualDeliveryDate); daysLate > 0 {
+			performance.LateCount++
+			totalDaysLate += daysLate
+		} else {
+			performance.OnTimeCount++
+		}
+	}
+
+	if measured := performance.OnTimeCount + performance.LateCount; measured > 0 {
+		performance.OnTimeRate = math.Round(float64(performance.OnTimeCount)/float64(measured)*10000) / 100
+	}
+	if performance.LateCount > 0 {
+		performance.AverageDaysLate = math.Round(float64(totalDaysLate)/float64(performance.LateCount)*100) / 100
+	}
+	return performance
+}
+
+// DaysLate is how many calendar days after the expected day a delivery arrived, 0 or less when it was on time
+func DaysLate(expected, actual time.Time) int {
+	expectedDay := time.Date(expected.Year(), expected.Month(), expected.Day(), 0, 0, 0, 0, time.UTC)
+	actualDay := time.Date(actual.Year(), actual.Month(), actual.Day(), 0, 0, 0, 0, time.UTC)
+	return int(actualDay.Sub(expectedDay).Hours() / 24)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func deliveryTime(day, hour int) *time.Time {
+	t := time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestDaysLate(t *testing.T) {
+	tests := []struct {
+		expected, actual *time.Time
+		want             int
+	}{
+		{deliveryTime(10, 9), deliveryTime(10, 23), 0}, // later the same day is on time
+		{deliveryTime(10, 23), deliveryTime(11, 1), 1}, // counted in calendar days, not 24 hours
+		{deliveryTime(10, 0), deliveryTime(8, 0), -2},
+		{deliveryTime(10, 0), deliveryTime(13, 0), 3},
+	}
+	for _, tt := range tests {
+		if got := DaysLate(*tt.expected, *tt.actual); got != tt.want {
+			t.Errorf("DaysLate(%v, %v) = %d, want %d", tt.expected, tt.actual, got, tt.want)
+		}
+	}
+}
+
+func TestNewDeliveryPerformance(t *testing.T) {
+	deliveries := []SaleDelivery{
+		{DeliveryStatus: DeliveryStatusDelivered, ExpectedDeliveryDate: deliveryTime(10, 9), ActualDeliveryDate: deliveryTime(10, 18)},
+		{DeliveryStatus: DeliveryStatusDelivered, ExpectedDeliveryDate: deliveryTime(10, 9), ActualDeliveryDate: deliveryTime(9, 9)},
+		{DeliveryStatus: DeliveryStatusDelivered, ExpectedDeliveryDate: deliveryTime(10, 9), ActualDeliveryDate: deliveryTime(12, 9)},
+		{DeliveryStatus: DeliveryStatusDelivered, ExpectedDeliveryDate: deliveryTime(10, 9), ActualDeliveryDate: deliveryTime(11, 9)},
+		{DeliveryStatus: DeliveryStatusDelivered, ActualDeliveryDate: deliveryTime(11, 9)}, // no expected date: not measured
+		{DeliveryStatus: DeliveryStatusPending, ExpectedDeliveryDate: deliveryTime(1, 9)},
+		{}, // saved before deliveries were tracked
+		{DeliveryStatus: DeliveryStatusFailed},
+	}
+	performance := NewDeliveryPerformance(time.Time{}, time.Time{}, deliveries)
+
+	if performance.TotalSales != 8 {
+		t.Errorf("total sales = %d, want 8", performance.TotalSales)
+	}
+	wantByStatus := map[string]int{DeliveryStatusPending: 2, DeliveryStatusInTransit: 0, DeliveryStatusDelivered: 5, DeliveryStatusFailed: 1}
+	for status, count := range wantByStatus {
+		if got, ok := performance.ByStatus[status]; !ok || got != count {
+			t.Errorf("%s: %d, want %d", status, got, count)
+		}
+	}
+	if performance.OnTimeCount != 2 || performance.LateCount != 2 || performance.OnTimeRate != 50 || performance.AverageDaysLate != 1.5 {
+		t.Errorf("performance = %+v, want 2 on time, 2 late, 50%% on time, 1.5 days late on average", performance)
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+// CalculateLineTotal returns the line total after the percentage discount and then the fixed discount amount.
+// The result never goes below zero.
+func CalculateLineTotal(quantity, unitPrice, discountPercent, discountAmount float64) float64 {
+	total := unitPrice*quantity*(1-discountPercent/100) - discountAmount
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// calculateLineDiscount returns the discount given on a line (gross price minus line total)
+func calculateLineDiscount(quantity, unitPrice, totalPrice float64) float64 {
+	return unitPrice*quantity - totalPrice
+}
@@ -0,0 +1,54 @@
+package models
+
+import "testing"
+
+func TestCalculateLineTotal(t *testing.T) {
+	tests := []struct {
+		name                                           string
+		quantity, unitPrice, discountPercent, discount float64
+		want                                           float64
+	}{
+		{"no discount", 3, 100, 0, 0, 300},
+		{"percentage", 3, 100, 10, 0, 270},
+		{"amount", 3, 100, 0, 25, 275},
+		{"percentage then amount", 3, 100, 10, 20, 250},
+		{"discount above the price", 1, 50, 0, 80, 0},
+	}
+	for _, tt := range tests {
+		if got := CalculateLineTotal(tt.quantity, tt.unitPrice, tt.discountPercent, tt.discount); got != tt.want {
+			t.Errorf("%s: CalculateLineTotal = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSaleRequestToSaleTotalsLineDiscounts(t *testing.T) {
+	req := SaleRequest{Items: []SaleItem{
+		{ProductID: "p1", Quantity: 2, UnitPrice: 100, DiscountPercent: 10},
+		{ProductID: "p2", Quantity: 1, UnitPrice: 50, DiscountAmount: 5},
+	}}
+
+	sale := req.ToSale()
+
+	if sale.Items[0].TotalPrice != 180 || sale.Items[1].TotalPrice != 45 {
+		t.Errorf("line totals = %v and %v, want 180 and 45", sale.Items[0].TotalPrice, sale.Items[1].TotalPrice)
+	}
+	if sale.DiscountTotal != 25 {
+		t.Errorf("DiscountTotal = %v, want 25", sale.DiscountTotal)
+	}
+}
+
+func TestPurchaseRequestToPurchaseTotalsLineDiscounts(t *testing.T) {
+	req := PurchaseRequest{Items: []PurchaseItem{
+		{ProductID: "p1", Quantity: 4, UnitPrice: 25, DiscountPercent: 20},
+		{ProductID: "p2", Quantity: 10, UnitPrice: 3, DiscountAmount: 2},
+	}}
+
+	purchase := req.ToPurchase()
+
+	if purchase.TotalAmount != 108 {
+		t.Errorf("TotalAmount = %v, want 108", purchase.TotalAmount)
+	}
+	if purchase.DiscountTotal != 22 {
+		t.Errorf("DiscountTotal = %v, want 22", purchase.DiscountTotal)
+	}
+}
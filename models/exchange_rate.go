@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BaseCurrency is the currency every amount is kept in; purchases in other currencies are converted to it
+const BaseCurrency = "THB"
+
+// ExchangeRate is how many baht one unit of a currency was worth on a day
+type ExchangeRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Currency  string             `bson:"currency" json:"currency"` // ISO 4217 เช่น USD
+	Date      time.Time          `bson:"date" json:"date"`         // วันที่ของอัตรา (เที่ยงคืน UTC)
+	Rate      float64            `bson:"rate" json:"rate"`         // บาทต่อ 1 หน่วยสกุลเงิน
+	CreatedBy *string            `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ExchangeRateRequest is the body of POST /api/exchange-rates
+type ExchangeRateRequest struct {
+	Currency string     `json:"currency" validate:"required,iso4217"`
+	Date     CustomTime `json:"date"`
+	Rate     float64    `json:"rate" validate:"gt=0"`
+}
+
+func (er *ExchangeRateRequest) ToExchangeRate(createdBy *string) *ExchangeRate {
+	now := time.Now()
+	return &ExchangeRate{
+		Currency:  er.Currency,
+		Date:      RateDate(er.Date.Time),
+		Rate:      er.Rate,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// RateDate is the day of t in t's own time zone, as midnight UTC: the date exchange rates are stored under
+func RateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// purchaseCurrency is the currency and exchange rate of a purchase; an empty currency is baht, at a rate of 1
+func purchaseCurrency(currency string, rate float64) (string, float64) {
+	if currency == "" || currency == BaseCurrency {
+		return BaseCurrency, 1
+	}
+	return currency, rate
+}
+
+// applyCurrency sets the purchase's currency and rate on the items and, for a foreign currency, converts each
+// item's unitPriceForeign to a baht unitPrice, so the totals worked out from the items are in baht
+func applyCurrency(items []PurchaseItem, currency string, rate float64) {
+	for i := range items {
+		item := &items[i]
+		item.Currency = currency
+		item.ExchangeRate = rate
+		if currency == BaseCurrency {
+			item.UnitPriceForeign = 0
+		} else {
+			item.UnitPrice = roundMoney(item.UnitPriceForeign * rate)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestForeignCurrencyPurchaseIsInBaht(t *testing.T) {
+	req := PurchaseRequest{
+		Currency:     "USD",
+		ExchangeRate: 35.123,
+		Items: []PurchaseItem{
+			{ProductID: "p1", Quantity: 2, UnitPriceForeign: 10.5},
+			{ProductID: "p2", Quantity: 1, UnitPriceForeign: 4},
+		},
+	}
+	purchase := req.ToPurchase()
+
+	if purchase.Currency != "USD" || purchase.ExchangeRate != 35.123 {
+		t.Errorf("purchase currency = %s at %v, want USD at 35.123", purchase.Currency, purchase.ExchangeRate)
+	}
+	// 10.5 USD x 35.123 = 368.7915, rounded to satang
+	if item := purchase.Items[0]; item.UnitPrice != 368.79 || item.Currency != "USD" || item.ExchangeRate != 35.123 {
+		t.Errorf("item = %+v, want 368.79 baht per unit in USD at 35.123", item)
+	}
+	if math.Abs(purchase.GrandTotal-(737.58+140.49)) > 1e-9 {
+		t.Errorf("grand total = %v, want 878.07 baht", purchase.GrandTotal)
+	}
+}
+
+func TestBahtPurchaseIgnoresForeignPrices(t *testing.T) {
+	for _, currency := range []string{"", BaseCurrency} {
+		req := PurchaseRequest{
+			Currency:     currency,
+			ExchangeRate: 35,
+			Items:        []PurchaseItem{{ProductID: "p1", Quantity: 1, UnitPrice: 100, UnitPriceForeign: 3}},
+		}
+		purchase := req.ToPurchase()
+		if purchase.Currency != BaseCurrency || purchase.ExchangeRate != 1 {
+			t.Errorf("currency %q: purchase in %s at %v, want THB at 1", currency, purchase.Currency, purchase.ExchangeRate)
+		}
+		if item := purchase.Items[0]; item.UnitPrice != 100 || item.UnitPriceForeign != 0 {
+			t.Errorf("currency %q: item = %+v, want 100 baht and no foreign price", currency, item)
+		}
+	}
+}
+
+func TestRateDate(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	got := RateDate(time.Date(2024, 3, 15, 1, 30, 0, 0, bangkok))
+	if want := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("RateDate = %v, want %v (the local day, not the UTC one)", got, want)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// InventoryEvent reports a change of a product's actual stock, streamed to clients as it happens
+type InventoryEvent struct {
+	ProductID     string    `json:"productId"`
+	SKUID         string    `json:"skuId"`
+	PreviousStock int       `json:"previousStock"`
+	NewStock      int       `json:"newStock"`
+	Timestamp     time.Time `json:"timestamp"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Inventory valuation methods
+const (
+	ValuationMethodFIFO    = "fifo"
+	ValuationMethodAverage = "average"
+)
+
+// InventoryValuationItem is the stock value of one product
+type InventoryValuationItem struct {
+	ProductID   string  `json:"productId"`
+	SKUID       string  `json:"skuId"`
+	Name        string  `json:"name"`
+	Category    string  `json:"category"`
+	Quantity    int     `json:"quantity"`    // จำนวนคงเหลือที่ใช้คำนวณมูลค่า
+	UnitCost    float64 `json:"unitCost"`    // ต้นทุนเฉลี่ยต่อหน่วยของสต็อกคงเหลือ
+	TotalValue  float64 `json:"totalValue"`  // มูลค่าสินค้าคงเหลือ
+	ActualStock int     `json:"actualStock"` // สินค้าคงเหลือจริงในระบบ
+}
+
+// InventoryValuationReport is the stock value of all products using one costing method
+type InventoryValuationReport struct {
+	Method     string                   `json:"method"`
+	Items      []InventoryValuationItem `json:"items"`
+	TotalValue float64                  `json:"totalValue"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Lot is a batch of a lot-tracked product received on one purchase; Quantity is what is left of it
+type Lot struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID        string             `bson:"productId" json:"productId"`
+	LotNumber        string             `bson:"lotNumber" json:"lotNumber"`
+	ExpiryDate       *time.Time         `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	Quantity         int                `bson:"quantity" json:"quantity"`                 // คงเหลือ
+	ReceivedQuantity int                `bson:"receivedQuantity" json:"receivedQuantity"` // จำนวนที่รับเข้า
+	PurchaseID       string             `bson:"purchaseId" json:"purchaseId"`
+	ReceivedAt       time.Time          `bson:"receivedAt" json:"receivedAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// LotAllocation is the quantity a sale item took from one lot
+type LotAllocation struct {
+	LotID      string     `bson:"lotId" json:"lotId"`
+	LotNumber  string     `bson:"lotNumber" json:"lotNumber"`
+	ExpiryDate *time.Time `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	Quantity   int        `bson:"quantity" json:"quantity"`
+}
+
+// ExpiringLot is a lot with stock left that expires within the report window, or has already expired
+type ExpiringLot struct {
+	Lot
+	ProductName  string `json:"productName"`
+	SKUID        string `json:"skuId"`
+	DaysToExpiry int    `json:"daysToExpiry"` // ติดลบเมื่อหมดอายุแล้ว
+	Expired      bool   `json:"expired"`
+}
+
+// CheckLot checks that an item of a lot-tracked product names its lot and expiry date. It returns the problem,
+// or "" when it does.
+func (i *PurchaseItem) CheckLot() string {
+	if strings.TrimSpace(i.LotNumber) == "" {
+		return "lotNumber is required"
+	}
+	if i.ExpiryDate == nil || i.ExpiryDate.IsZero() {
+		return "expiryDate is required"
+	}
+	return ""
+}
+
+// SortFEFO orders lots First Expired First Out: earliest expiry first, lots without an expiry date last, and
+// lots expiring together by when they were received
+func SortFEFO(lots []*Lot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := lots[i].ExpiryDate, lots[j].ExpiryDate
+		switch {
+		case a == nil && b == nil:
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return lots[i].ReceivedAt.Before(lots[j].ReceivedAt)
+	})
+}
+
+// AllocateFEFO takes quantity from lots, earliest expiry first. It returns the allocations and the quantity the
+// lots could not cover.
+func AllocateFEFO(lots []*Lot, quantity int) ([]LotAllocation, int) {
+	ordered := make([]*Lot, 0, len(lots))
+	for _, lot := range lots {
+		if lot.Quantity > 0 {
+			ordered = append(ordered, lot)
+		}
+	}
+	SortFEFO(ordered)
+
+	var allocations []LotAllocation
+	remaining := quantity
+	for _, lot := range ordered {
+		if remaining <= 0 {
+			break
+		}
+		take := min(lot.Quantity, remaining)
+		allocations = append(allocations, LotAllocation{
+			LotID:      lot.ID.Hex(),
+			LotNumber:  lot.LotNumber,
+			ExpiryDate: lot.ExpiryDate,
+			Quantity:   take,
+		})
+		remaining -= take
+	}
+	return allocations, max(remaining, 0)
+}
+
+// NewExpiringLot reports a lot's days to expiry as of now, counted in whole days
+func NewExpiringLot(lot *Lot, product *Product, now time.Time) ExpiringLot {
+	expiring := ExpiringLot{Lot: *lot}
+	if product != nil {
+		expiring.ProductName = product.Name
+		expiring.SKUID = product.SKUID
+	}
+	if lot.ExpiryDate != nil {
+		expiring.DaysToExpiry = int(math.Floor(lot.ExpiryDate.Sub(now).Hours() / 24))
+		expiring.Expired = lot.ExpiryDate.Before(now)
+	}
+	return expiring
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func lotDate(day int) *time.Time {
+	date := time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+func TestAllocateFEFO(t *testing.T) {
+	lots := []*Lot{
+		{LotNumber: "no-expiry", Quantity: 100, ReceivedAt: *lotDate(1)},
+		{LotNumber: "late", Quantity: 5, ExpiryDate: lotDate(20), ReceivedAt: *lotDate(2)},
+		{LotNumber: "early-second", Quantity: 3, ExpiryDate: lotDate(10), ReceivedAt: *lotDate(4)},
+		{LotNumber: "empty", Quantity: 0, ExpiryDate: lotDate(5), ReceivedAt: *lotDate(1)},
+		{LotNumber: "early-first", Quantity: 2, ExpiryDate: lotDate(10), ReceivedAt: *lotDate(3)},
+	}
+
+	allocations, short := AllocateFEFO(lots, 8)
+	want := []struct {
+		lot      string
+		quantity int
+	}{
+		{"early-first", 2}, // same expiry: received first goes first
+		{"early-second", 3},
+		{"late", 3},
+	}
+	if short != 0 || len(allocations) != len(want) {
+		t.Fatalf("allocations = %+v, short %d; want %v", allocations, short, want)
+	}
+	for i, w := range want {
+		if allocations[i].LotNumber != w.lot || allocations[i].Quantity != w.quantity {
+			t.Errorf("allocation %d = %s x%d, want %s x%d", i, allocations[i].LotNumber, allocations[i].Quantity, w.lot, w.quantity)
+		}
+	}
+	if lots[0].LotNumber != "no-expiry" {
+		t.Error("AllocateFEFO reordered its input")
+	}
+
+	// Lots without an expiry date go last
+	allocations, _ = AllocateFEFO(lots, 12)
+	if last := allocations[len(allocations)-1]; last.LotNumber != "no-expiry" || last.Quantity != 2 {
+		t.Errorf("last allocation = %+v, want 2 from no-expiry", last)
+	}
+
+	if _, short := AllocateFEFO(lots[1:], 20); short != 10 {
+		t.Errorf("short = %d, want 10 not covered by lots", short)
+	}
+}
+
+func TestNewExpiringLot(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	product := &Product{Name: "Glue", SKUID: "GL-0001"}
+
+	soon := NewExpiringLot(&Lot{ExpiryDate: lotDate(13)}, product, now)
+	if soon.DaysToExpiry != 2 || soon.Expired || soon.ProductName != "Glue" {
+		t.Errorf("lot expiring on the 13th = %+v, want 2 whole days left", soon)
+	}
+	expired := NewExpiringLot(&Lot{ExpiryDate: lotDate(9)}, nil, now)
+	if expired.DaysToExpiry != -2 || !expired.Expired {
+		t.Errorf("lot expired on the 9th = %+v, want expired, -2 days", expired)
+	}
+}
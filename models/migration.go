@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Migration statuses
+const (
+	MigrationStatusInProgress = "in_progress"
+	MigrationStatusCompleted  = "completed"
+)
+
+// Migration tracks the progress of a CSV import so it can be re-run without duplicating rows
+type Migration struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TransactionID string             `bson:"transactionId" json:"transactionId"`                 // รหัสที่ผู้ใช้ส่งมากับการ import
+	Entity        string             `bson:"entity" json:"entity"`                               // customers, products, purchases, sales
+	ProcessedRows []string           `bson:"processedRows" json:"processedRows"`                 // แถว/กลุ่มที่ import สำเร็จแล้ว
+	Status        string             `bson:"status" json:"status"`                               // in_progress, completed
+	LastResult    *MigrationSummary  `bson:"lastResult,omitempty" json:"lastResult,omitempty"`   // ผลการรันครั้งล่าสุด
+	CompletedAt   *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"` // วันที่ import ครบทุกแถว
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// MigrationSummary is the row counts of one migration run
+type MigrationSummary struct {
+	TotalRows   int `bson:"totalRows" json:"totalRows"`
+	SuccessRows int `bson:"successRows" json:"successRows"`
+	SkippedRows int `bson:"skippedRows" json:"skippedRows"`
+	FailedRows  int `bson:"failedRows" json:"failedRows"`
+}
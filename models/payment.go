@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// paymentTolerance absorbs rounding when comparing payments with a grand total
+const paymentTolerance = 0.005
+
+var (
+	ErrPaymentAmount   = errors.New("payment amount must be greater than zero")
+	ErrPaymentMethod   = errors.New("payment method is required")
+	ErrPaymentDate     = errors.New("invalid paidAt, use YYYY-MM-DD")
+	ErrPaymentExceeded = errors.New("payment exceeds the outstanding balance")
+)
+
+// PaymentRecord is one payment received for a sale or made for a purchase
+type PaymentRecord struct {
+	Method     string    `bson:"method" json:"method"` // เช่น cash, bank_transfer
+	Amount     float64   `bson:"amount" json:"amount"`
+	OurAccount *string   `bson:"ourAccount,omitempty" json:"ourAccount,omitempty"`
+	PaidAt     time.Time `bson:"paidAt" json:"paidAt"`
+	Reference  *string   `bson:"reference,omitempty" json:"reference,omitempty"` // เลขที่อ้างอิงการโอน
+	RecordedAt time.Time `bson:"recordedAt" json:"recordedAt"`
+}
+
+type PaymentRequest struct {
+	Method     string  `json:"method"`
+	Amount     float64 `json:"amount"`
+	OurAccount *string `json:"ourAccount,omitempty"`
+	PaidAt     string  `json:"paidAt,omitempty"` // YYYY-MM-DD or RFC 3339, defaults to now
+	Reference  *string `json:"reference,omitempty"`
+}
+
+// ToPaymentRecord validates the request and converts it to a payment record
+func (pr *PaymentRequest) ToPaymentRecord() (*PaymentRecord, error) {
+	if pr.Amount <= 0 {
+		return nil, ErrPaymentAmount
+	}
+	if pr.Method == "" {
+		return nil, ErrPaymentMethod
+	}
+
+	now := time.Now()
+	paidAt := now
+	if pr.PaidAt != "" {
+		var err error
+		if paidAt, err = time.Parse("2006-01-02", pr.PaidAt); err != nil {
+			if paidAt, err = time.Parse(time.RFC3339, pr.PaidAt); err != nil {
+				return nil, ErrPaymentDate
+			}
+		}
+	}
+
+	return &PaymentRecord{
+		Method:     pr.Method,
+		Amount:     pr.Amount,
+		OurAccount: pr.OurAccount,
+		PaidAt:     paidAt,
+		Reference:  pr.Reference,
+		RecordedAt: now,
+	}, nil
+}
+
+// PaymentSummary is the payments of a sale or purchase against its grand total
+type PaymentSummary struct {
+	Payments   []PaymentRecord `json:"payments"`
+	GrandTotal float64         `json:"grandTotal"`
+	TotalPaid  float64         `json:"totalPaid"`
+	Balance    float64         `json:"balance"` // ยอดคงค้าง
+	IsPaid     bool            `json:"isPaid"`
+}
+
+// TotalPaid sums the amounts of the payments
+func TotalPaid(payments []PaymentRecord) float64 {
+	var total float64
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+// NewPaymentSummary summarises payments against a grand total
+func NewPaymentSummary(payments []PaymentRecord, grandTotal float64, isPaid bool) *PaymentSummary {
+	if payments == nil {
+		payments = []PaymentRecord{}
+	}
+	totalPaid := TotalPaid(payments)
+	return &PaymentSummary{
+		Payments:   payments,
+		GrandTotal: grandTotal,
+		TotalPaid:  totalPaid,
+		Balance:    grandTotal - totalPaid,
+		IsPaid:     isPaid,
+	}
+}
+
+// addPayment appends a payment unless it would take the total paid over the grand total,
+// marking the document as paid once the grand total is covered
+func addPayment(info *PaymentInfo, payments *[]PaymentRecord, record PaymentRecord, grandTotal float64) error {
+	if TotalPaid(*payments)+record.Amount > grandTotal+paymentTolerance {
+		return ErrPaymentExceeded
+	}
+
+	*payments = append(*payments, record)
+	info.IsPaid = TotalPaid(*payments) >= grandTotal-paymentTolerance
+
+	method := record.Method
+	paidAt := record.PaidAt
+	info.PaymentMethod = &method
+	info.PaymentDate = &paidAt
+	if record.OurAccount != nil {
+		info.OurAccount = record.OurAccount
+	}
+	return nil
+}
+
+// AddPayment records a payment received for the sale
+func (s *Sale) AddPayment(record PaymentRecord) error {
+	if err := addPayment(&s.Payment, &s.Payments, record, s.CalculateGrandTotal()); err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// AddPayment records a payment made for the purchase
+func (p *Purchase) AddPayment(record PaymentRecord) error {
+	if err := addPayment(&p.Payment, &p.Payments, record, p.GrandTotal); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now()
+	return nil
+}
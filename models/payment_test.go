@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPaymentRequestToPaymentRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     PaymentRequest
+		wantErr error
+		paidAt  time.Time
+	}{
+		{"date", PaymentRequest{Method: "cash", Amount: 100, PaidAt: "2024-03-15"}, nil, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"RFC 3339", PaymentRequest{Method: "cash", Amount: 100, PaidAt: "2024-03-15T10:30:00Z"}, nil, time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"zero amount", PaymentRequest{Method: "cash"}, ErrPaymentAmount, time.Time{}},
+		{"no method", PaymentRequest{Amount: 100}, ErrPaymentMethod, time.Time{}},
+		{"bad date", PaymentRequest{Method: "cash", Amount: 100, PaidAt: "15/03/2024"}, ErrPaymentDate, time.Time{}},
+	}
+	for _, tt := range tests {
+		record, err := tt.req.ToPaymentRecord()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !record.PaidAt.Equal(tt.paidAt) {
+			t.Errorf("%s: paidAt = %v, want %v", tt.name, record.PaidAt, tt.paidAt)
+		}
+	}
+}
+
+func TestPurchaseAddPayment(t *testing.T) {
+	purchase := Purchase{GrandTotal: 1000}
+
+	if err := purchase.AddPayment(PaymentRecord{Method: "cash", Amount: 400}); err != nil {
+		t.Fatal(err)
+	}
+	if purchase.Payment.IsPaid {
+		t.Error("marked paid after a partial payment")
+	}
+
+	if err := purchase.AddPayment(PaymentRecord{Method: "bank_transfer", Amount: 600.01}); !errors.Is(err, ErrPaymentExceeded) {
+		t.Errorf("overpayment: error = %v, want ErrPaymentExceeded", err)
+	}
+	if len(purchase.Payments) != 1 {
+		t.Errorf("rejected payment was recorded: %d payments", len(purchase.Payments))
+	}
+
+	// Rounding within the tolerance settles the balance
+	if err := purchase.AddPayment(PaymentRecord{Method: "bank_transfer", Amount: 599.999}); err != nil {
+		t.Fatal(err)
+	}
+	if !purchase.Payment.IsPaid {
+		t.Error("not marked paid once the grand total is covered")
+	}
+	if method := purchase.Payment.PaymentMethod; method == nil || *method != "bank_transfer" {
+		t.Errorf("payment method = %v, want the latest bank_transfer", method)
+	}
+
+	summary := NewPaymentSummary(purchase.Payments, purchase.GrandTotal, purchase.Payment.IsPaid)
+	if summary.TotalPaid != 999.999 || !summary.IsPaid {
+		t.Errorf("summary = %+v, want 999.999 paid and settled", summary)
+	}
+	if summary := NewPaymentSummary(nil, 50, false); summary.Payments == nil || summary.Balance != 50 {
+		t.Errorf("summary without payments = %+v, want an empty list and a balance of 50", summary)
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// PeriodPerformance is the sales performance of one period. Revenue excludes VAT and shipping.
+type PeriodPerformance struct {
+	StartDate         time.Time    `json:"startDate"`
+	EndDate           time.Time    `json:"endDate"`
+	Revenue           float64      `json:"revenue"`           // ยอดขายหลังหักส่วนลด ไม่รวม VAT
+	UnitsSold         float64      `json:"unitsSold"`         // จำนวนหน่วยที่ขาย (หน่วยย่อย)
+	OrderCount        int64        `json:"orderCount"`        // จำนวนรายการขาย
+	AverageOrderValue float64      `json:"averageOrderValue"` // revenue / orderCount
+	TopProducts       []TopProduct `json:"topProducts"`       // สินค้าขายดีตามยอดขาย
+}
+
+// MetricChange is how much a metric moved from period 2 to period 1
+type MetricChange struct {
+	Absolute float64  `json:"absolute"` // period1 - period2
+	Percent  *float64 `json:"percent"`  // absolute / period2 × 100; null when period 2 is zero
+}
+
+// PeriodChanges are the changes of each metric of a PeriodComparison
+type PeriodChanges struct {
+	Revenue           MetricChange `json:"revenue"`
+	UnitsSold         MetricChange `json:"unitsSold"`
+	OrderCount        MetricChange `json:"orderCount"`
+	AverageOrderValue MetricChange `json:"averageOrderValue"`
+}
+
+// PeriodComparison sets the sales of period 1 beside those of period 2, e.g. this month against the same month
+// last year
+type PeriodComparison struct {
+	Period1 PeriodPerformance `json:"period1"`
+	Period2 PeriodPerformance `json:"period2"`
+	Changes PeriodChanges     `json:"changes"`
+}
+
+// NewPeriodComparison compares period1 with period2, working out the average order values and the changes
+func NewPeriodComparison(period1, period2 PeriodPerformance) *PeriodComparison {
+	period1.AverageOrderValue = averageOrderValue(period1.Revenue, period1.OrderCount)
+	period2.AverageOrderValue = averageOrderValue(period2.Revenue, period2.OrderCount)
+
+	return &PeriodComparison{
+		Period1: period1,
+		Period2: period2,
+		Changes: PeriodChanges{
+			Revenue:           newMetricChange(period1.Revenue, period2.Revenue),
+			UnitsSold:         newMetricChange(period1.UnitsSold, period2.UnitsSold),
+			OrderCount:        newMetricChange(float64(period1.OrderCount), float64(period2.OrderCount)),
+			AverageOrderValue: newMetricChange(period1.AverageOrderValue, period2.AverageOrderValue),
+		},
+	}
+}
+
+func averageOrderValue(revenue float64, orderCount int64) float64 {
+	if orderCount == 0 {
+		return 0
+	}
+	return roundMoney(revenue / float64(orderCount))
+}
+
+func newMetricChange(value, baseline float64) MetricChange {
+	change := MetricChange{Absolute: roundMoney(value - baseline)}
+	if baseline != 0 {
+		percent := roundMoney((value - baseline) / baseline * 100)
+		change.Percent = &percent
+	}
+	return change
+}
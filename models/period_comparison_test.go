@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestNewPeriodComparison(t *testing.T) {
+	comparison := NewPeriodComparison(
+		PeriodPerformance{Revenue: 1500, UnitsSold: 30, OrderCount: 3},
+		PeriodPerformance{Revenue: 1000, OrderCount: 4},
+	)
+
+	if comparison.Period1.AverageOrderValue != 500 || comparison.Period2.AverageOrderValue != 250 {
+		t.Errorf("average order values = %v and %v, want 500 and 250", comparison.Period1.AverageOrderValue, comparison.Period2.AverageOrderValue)
+	}
+
+	tests := []struct {
+		name     string
+		change   MetricChange
+		absolute float64
+		percent  float64
+	}{
+		{"revenue", comparison.Changes.Revenue, 500, 50},
+		{"order count", comparison.Changes.OrderCount, -1, -25},
+		{"average order value", comparison.Changes.AverageOrderValue, 250, 100},
+	}
+	for _, tt := range tests {
+		if tt.change.Absolute != tt.absolute || tt.change.Percent == nil || *tt.change.Percent != tt.percent {
+			t.Errorf("%s: change = %v (%v%%), want %v (%v%%)", tt.name, tt.change.Absolute, tt.change.Percent, tt.absolute, tt.percent)
+		}
+	}
+
+	// Growth from nothing has no percentage
+	if units := comparison.Changes.UnitsSold; units.Absolute != 30 || units.Percent != nil {
+		t.Errorf("units sold: change = %v (%v%%), want 30 with no percentage", units.Absolute, units.Percent)
+	}
+}
+
+func TestNewPeriodComparisonWithoutOrders(t *testing.T) {
+	comparison := NewPeriodComparison(PeriodPerformance{}, PeriodPerformance{})
+	if comparison.Period1.AverageOrderValue != 0 || comparison.Changes.Revenue.Percent != nil {
+		t.Errorf("empty periods: %+v, want zero averages and no percentages", comparison)
+	}
+}
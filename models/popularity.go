@@ -0,0 +1,53 @@
+package models
+
+import "math"
+
+// Weights of the normalised view and sale counts in a product's popularity score
+const (
+	PopularityViewWeight  = 0.3
+	PopularitySalesWeight = 0.7
+)
+
+// PopularityWindowDays is how many days of sales count towards a product's sales count
+const PopularityWindowDays = 90
+
+// ProductPopularity is the sales count and popularity score computed for one product
+type ProductPopularity struct {
+	SalesCount      int     `json:"salesCount"`
+	PopularityScore float64 `json:"popularityScore"`
+}
+
+// PopularityRescoreResult is the outcome of a popularity scoring run
+type PopularityRescoreResult struct {
+	Scored  int   `json:"scored"`  // จำนวนสินค้าที่คำนวณคะแนน
+	Changed int64 `json:"changed"` // จำนวนสินค้าที่คะแนนเปลี่ยน
+}
+
+// ScorePopularity scores every product in viewCounts (keyed by product ID) from 0 to 1, to 4 decimal places:
+// 0.3 × its views / the most views + 0.7 × its sales / the most sales. Products missing from saleCounts sold
+// nothing; when no product has views (or sales), that part of every score is 0.
+func ScorePopularity(viewCounts, saleCounts map[string]int) map[string]ProductPopularity {
+	maxViews, maxSales := 0, 0
+	for productID, views := range viewCounts {
+		maxViews = max(maxViews, views)
+		maxSales = max(maxSales, saleCounts[productID])
+	}
+
+	popularity := make(map[string]ProductPopularity, len(viewCounts))
+	for productID, views := range viewCounts {
+		sales := saleCounts[productID]
+		popularity[productID] = ProductPopularity{
+			SalesCount:      sales,
+			PopularityScore: math.Round((PopularityViewWeight*normalise(views, maxViews)+PopularitySalesWeight*normalise(sales, maxSales))*10000) / 10000,
+		}
+	}
+	return popularity
+}
+
+// normalise scales value to 0-1 by the largest value
+func normalise(value, largest int) float64 {
+	if largest <= 0 || value <= 0 {
+		return 0
+	}
+	return float64(value) / float64(largest)
+}
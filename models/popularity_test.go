@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestScorePopularity(t *testing.T) {
+	viewCounts := map[string]int{"a": 100, "b": 50, "c": 0, "d": 30}
+	saleCounts := map[string]int{"a": 10, "c": 5, "d": 3, "other": 100}
+	popularity := ScorePopularity(viewCounts, saleCounts)
+
+	want := map[string]ProductPopularity{
+		"a": {SalesCount: 10, PopularityScore: 1},
+		"b": {SalesCount: 0, PopularityScore: 0.15},
+		"c": {SalesCount: 5, PopularityScore: 0.35},
+		"d": {SalesCount: 3, PopularityScore: 0.3},
+	}
+	if len(popularity) != len(want) {
+		t.Errorf("scored %d products, want only the %d in viewCounts", len(popularity), len(want))
+	}
+	for productID, w := range want {
+		if popularity[productID] != w {
+			t.Errorf("%s: %+v, want %+v", productID, popularity[productID], w)
+		}
+	}
+}
+
+func TestScorePopularityWithoutActivity(t *testing.T) {
+	popularity := ScorePopularity(map[string]int{"a": 0, "b": 0}, nil)
+	for productID, p := range popularity {
+		if p.PopularityScore != 0 {
+			t.Errorf("%s without views or sales: score %v, want 0", productID, p.PopularityScore)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// StandardCost is the product's average purchase price for VAT or non-VAT purchases, the standard a new purchase
+// price is compared against. It is 0 when the product has never been bought that way.
+func (p *Product) StandardCost(isVAT bool) float64 {
+	if isVAT {
+		return p.Price.PurchaseVAT.Average
+	}
+	return p.Price.PurchaseNonVAT.Average
+}
+
+// ApplyPriceVariance records how much more (positive, unfavorable) or less (negative, favorable) the item cost
+// than the standard cost. An item without a standard cost has no variance.
+func (item *PurchaseItem) ApplyPriceVariance(standardCost float64) {
+	if standardCost <= 0 {
+		item.PriceVariance = 0
+		return
+	}
+	item.PriceVariance = roundMoney((item.UnitPrice - standardCost) * float64(item.Quantity))
+}
+
+// PurchaseItemVariance is the price variance of one purchase item, as read for the variance report
+type PurchaseItemVariance struct {
+	PurchaseID    string    `bson:"purchaseId"`
+	PurchaseCode  string    `bson:"purchaseCode"`
+	PurchaseDate  time.Time `bson:"purchaseDate"`
+	SupplierName  string    `bson:"supplierName"`
+	ProductID     string    `bson:"productId"`
+	ProductCode   string    `bson:"productCode"`
+	ProductName   string    `bson:"productName"`
+	Quantity      int       `bson:"quantity"`
+	PriceVariance float64   `bson:"priceVariance"`
+}
+
+// PriceVarianceTotals splits a total price variance into its favorable (below standard cost, negative) and
+// unfavorable (above standard cost, positive) parts
+type PriceVarianceTotals struct {
+	TotalVariance       float64 `json:"totalVariance"`
+	FavorableVariance   float64 `json:"favorableVariance"`   // ซื้อได้ถูกกว่าราคาเฉลี่ย (ติดลบ)
+	UnfavorableVariance float64 `json:"unfavorableVariance"` // ซื้อแพงกว่าราคาเฉลี่ย (บวก)
+}
+
+func (t *PriceVarianceTotals) add(variance float64) {
+	t.TotalVariance += variance
+	if variance < 0 {
+		t.FavorableVariance += variance
+	} else {
+		t.UnfavorableVariance += variance
+	}
+}
+
+func (t *PriceVarianceTotals) round() {
+	t.TotalVariance = roundMoney(t.TotalVariance)
+	t.FavorableVariance = roundMoney(t.FavorableVariance)
+	t.UnfavorableVariance = roundMoney(t.UnfavorableVariance)
+}
+
+// ProductPriceVariance is the price variance of one product over the purchases of a period
+type ProductPriceVariance struct {
+	ProductID   string `json:"productId"`
+	ProductCode string `json:"productCode"`
+	ProductName string `json:"productName"`
+	Quantity    int    `json:"quantity"`
+	PriceVarianceTotals
+}
+
+// PurchasePriceVariance is the price variance of one purchase
+type PurchasePriceVariance struct {
+	PurchaseID   string    `json:"purchaseId"`
+	PurchaseCode string    `json:"purchaseCode"`
+	PurchaseDate time.Time `json:"purchaseDate"`
+	SupplierName string    `json:"supplierName"`
+	PriceVarianceTotals
+}
+
+// PurchaseVarianceReport is how the purchase prices of StartDate..EndDate compared with the products' standard
+// costs, in total, per product and per purchase
+type PurchaseVarianceReport struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	PriceVarianceTotals
+	ByProduct  []*ProductPriceVariance  `json:"byProduct"`
+	ByPurchase []*PurchasePriceVariance `json:"byPurchase"`
+}
+
+// NewPurchaseVarianceReport totals the item variances per product and per purchase, largest variance either way
+// first
+func NewPurchaseVarianceReport(from, to time.Time, items []PurchaseItemVariance) *PurchaseVarianceReport {
+	report := &PurchaseVarianceReport{
+		StartDate:  from,
+		EndDate:    to,
+		ByProduct:  []*ProductPriceVariance{},
+		ByPurchase: []*PurchasePriceVariance{},
+	}
+
+	products := make(map[string]*ProductPriceVariance)
+	purchases := make(map[string]*PurchasePriceVariance)
+	for _, item := range items {
+		report.add(item.PriceVariance)
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			product = &ProductPriceVariance{ProductID: item.ProductID, ProductCode: item.ProductCode, ProductName: item.ProductName}
+			products[item.ProductID] = product
+			report.ByProduct = append(report.ByProduct, product)
+		}
+		product.Quantity += item.Quantity
+		product.add(item.PriceVariance)
+
+		purchase, ok := purchases[item.PurchaseID]
+		if !ok {
+			purchase = &PurchasePriceVariance{
+				PurchaseID:   item.PurchaseID,
+				PurchaseCode: item.PurchaseCode,
+				PurchaseDate: item.PurchaseDate,
+				SupplierName: item.SupplierName,
+			}
+			purchases[item.PurchaseID] = purchase
+			report.ByPurchase = append(report.ByPurchase, purchase)
+		}
+		purchase.add(item.PriceVariance)
+	}
+
+	report.round()
+	for _, product := range report.ByProduct {
+		product.round()
+	}
+	for _, purchase := range report.ByPurchase {
+		purchase.round()
+	}
+	sort.SliceStable(report.ByProduct, func(i, j int) bool {
+		return math.Abs(report.ByProduct[i].TotalVariance) > math.Abs(report.ByProduct[j].TotalVariance)
+	})
+	sort.SliceStable(report.ByPurchase, func(i, j int) bool {
+		return math.Abs(report.ByPurchase[i].TotalVariance) > math.Abs(report.ByPurchase[j].TotalVariance)
+	})
+	return report
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPurchaseVarianceReport(t *testing.T) {
+	items := []PurchaseItemVariance{
+		{PurchaseID: "po1", ProductID: "p1", Quantity: 10, PriceVariance: 50},
+		{PurchaseID: "po1", ProductID: "p2", Quantity: 5, PriceVariance: -80},
+		{PurchaseID: "po2", ProductID: "p1", Quantity: 4, PriceVariance: -20},
+		{PurchaseID: "po2", ProductID: "p3", Quantity: 2, PriceVariance: 10},
+	}
+	report := NewPurchaseVarianceReport(time.Time{}, time.Time{}, items)
+
+	if want := (PriceVarianceTotals{TotalVariance: -40, FavorableVariance: -100, UnfavorableVariance: 60}); report.PriceVarianceTotals != want {
+		t.Errorf("totals = %+v, want %+v", report.PriceVarianceTotals, want)
+	}
+
+	// Largest variance either way first
+	wantProducts := []struct {
+		id       string
+		quantity int
+		totals   PriceVarianceTotals
+	}{
+		{"p2", 5, PriceVarianceTotals{TotalVariance: -80, FavorableVariance: -80}},
+		{"p1", 14, PriceVarianceTotals{TotalVariance: 30, FavorableVariance: -20, UnfavorableVariance: 50}},
+		{"p3", 2, PriceVarianceTotals{TotalVariance: 10, UnfavorableVariance: 10}},
+	}
+	if len(report.ByProduct) != len(wantProducts) {
+		t.Fatalf("got %d products, want %d", len(report.ByProduct), len(wantProducts))
+	}
+	for i, w := range wantProducts {
+		got := report.ByProduct[i]
+		if got.ProductID != w.id || got.Quantity != w.quantity || got.PriceVarianceTotals != w.totals {
+			t.Errorf("product %d = %s x%d %+v, want %s x%d %+v", i, got.ProductID, got.Quantity, got.PriceVarianceTotals, w.id, w.quantity, w.totals)
+		}
+	}
+
+	if len(report.ByPurchase) != 2 || report.ByPurchase[0].PurchaseID != "po1" || report.ByPurchase[0].TotalVariance != -30 || report.ByPurchase[1].TotalVariance != -10 {
+		t.Errorf("purchases = %+v, %+v; want po1 at -30 then po2 at -10", report.ByPurchase[0], report.ByPurchase[1])
+	}
+
+	if empty := NewPurchaseVarianceReport(time.Time{}, time.Time{}, nil); empty.ByProduct == nil || empty.ByPurchase == nil {
+		t.Error("report without purchases: want empty lists, not null")
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+// PricingSuggestion is the sale price that gives a product a target margin over its average purchase cost.
+// Prices are per base unit; SuggestedPriceNonVAT, CurrentSalePrice and BreakEvenPrice are before VAT like the
+// product's price info, SuggestedPriceVAT includes VAT at the current rate.
+type PricingSuggestion struct {
+	ProductID            string  `json:"productId"`
+	SKUID                string  `json:"skuId"`
+	Name                 string  `json:"name"`
+	TargetMarginPercent  float64 `json:"targetMarginPercent"`
+	IncludeVAT           bool    `json:"includeVAT"`           // ใช้ต้นทุนและราคาขายแบบ VAT
+	PurchaseCost         float64 `json:"purchaseCost"`         // ราคาซื้อเฉลี่ย
+	SuggestedPriceVAT    float64 `json:"suggestedPriceVAT"`    // ราคาขายแนะนำรวม VAT
+	SuggestedPriceNonVAT float64 `json:"suggestedPriceNonVAT"` // ราคาขายแนะนำก่อน VAT
+	CurrentSalePrice     float64 `json:"currentSalePrice"`     // ราคาขายล่าสุด
+	EffectiveMargin      float64 `json:"effectiveMargin"`      // กำไรขั้นต้น (%) ที่ราคาขายล่าสุด
+	BreakEvenPrice       float64 `json:"breakEvenPrice"`       // ราคาขายที่ไม่มีกำไร
+}
+
+// PricingSuggestionRequest asks for pricing suggestions for several products at once
+type PricingSuggestionRequest struct {
+	ProductIDs          []string `json:"productIds" validate:"required,min=1,max=100,dive,required"`
+	TargetMarginPercent float64  `json:"targetMarginPercent" validate:"min=0,lt=100"`
+	IncludeVAT          bool     `json:"includeVAT"`
+}
+
+// SalePriceForMargin is the price at which marginPercent of the price is profit over cost:
+// cost / (1 - marginPercent/100). A margin of 100% or more has no such price and returns 0.
+func SalePriceForMargin(cost, marginPercent float64) float64 {
+	if marginPercent >= 100 {
+		return 0
+	}
+	return cost / (1 - marginPercent/100)
+}
+
+// MarginPercent is the share of price that is profit over cost, in percent; 0 when there is no price
+func MarginPercent(cost, price float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	return (price - cost) / price * 100
+}
+
+// NewPricingSuggestion suggests a sale price for the target margin from the product's average purchase cost,
+// VAT or non-VAT depending on includeVAT, and compares it with the latest sale price of the same type
+func NewPricingSuggestion(product *Product, targetMarginPercent float64, includeVAT bool) *PricingSuggestion {
+	cost := product.Price.PurchaseNonVAT.Average
+	currentSalePrice := product.Price.SaleNonVAT.Latest
+	if includeVAT {
+		cost = product.Price.PurchaseVAT.Average
+		currentSalePrice = product.Price.SaleVAT.Latest
+	}
+
+	salePrice := SalePriceForMargin(cost, targetMarginPercent)
+	return &PricingSuggestion{
+		ProductID:            product.ID.Hex(),
+		SKUID:                product.SKUID,
+		Name:                 product.Name,
+		TargetMarginPercent:  targetMarginPercent,
+		IncludeVAT:           includeVAT,
+		PurchaseCost:         roundMoney(cost),
+		SuggestedPriceVAT:    roundMoney(salePrice * (1 + VATRate)),
+		SuggestedPriceNonVAT: roundMoney(salePrice),
+		CurrentSalePrice:     currentSalePrice,
+		EffectiveMargin:      roundMoney(MarginPercent(cost, currentSalePrice)),
+		BreakEvenPrice:       roundMoney(cost),
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSalePriceForMargin(t *testing.T) {
+	tests := []struct {
+		cost, margin, want float64
+	}{
+		{75, 25, 100},
+		{60, 40, 100},
+		{80, 0, 80},
+		{80, 100, 0}, // no price gives a 100% margin
+		{80, 120, 0},
+	}
+	for _, tt := range tests {
+		got := SalePriceForMargin(tt.cost, tt.margin)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("SalePriceForMargin(%v, %v) = %v, want %v", tt.cost, tt.margin, got, tt.want)
+		}
+		if tt.want > 0 && math.Abs(MarginPercent(tt.cost, got)-tt.margin) > 1e-9 {
+			t.Errorf("MarginPercent(%v, %v) = %v, want %v back", tt.cost, got, MarginPercent(tt.cost, got), tt.margin)
+		}
+	}
+	if got := MarginPercent(50, 0); got != 0 {
+		t.Errorf("MarginPercent without a price = %v, want 0", got)
+	}
+}
+
+func TestNewPricingSuggestion(t *testing.T) {
+	product := &Product{Name: "Kraft Box", Price: Price{
+		PurchaseVAT:    PriceInfo{Average: 75},
+		PurchaseNonVAT: PriceInfo{Average: 60},
+		SaleVAT:        PriceInfo{Latest: 120},
+		SaleNonVAT:     PriceInfo{Latest: 90},
+	}}
+
+	vat := NewPricingSuggestion(product, 25, true)
+	if vat.PurchaseCost != 75 || vat.SuggestedPriceNonVAT != 100 || vat.SuggestedPriceVAT != 107 {
+		t.Errorf("VAT suggestion = %+v, want cost 75, 100 before VAT and 107 with it", vat)
+	}
+	if vat.CurrentSalePrice != 120 || vat.EffectiveMargin != 37.5 || vat.BreakEvenPrice != 75 {
+		t.Errorf("VAT suggestion = %+v, want current 120 at a 37.5%% margin, break-even 75", vat)
+	}
+
+	nonVAT := NewPricingSuggestion(product, 40, false)
+	if nonVAT.PurchaseCost != 60 || nonVAT.SuggestedPriceNonVAT != 100 || nonVAT.CurrentSalePrice != 90 {
+		t.Errorf("non-VAT suggestion = %+v, want cost 60, suggested 100, current 90", nonVAT)
+	}
+}
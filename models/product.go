@@ -2,8 +2,10 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -60,32 +62,154 @@ type Stock struct {
 
 // Product represents a product in the inventory
 type Product struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	SKUID       string             `bson:"skuId" json:"skuId"`             // XY-0000 หรือ XYZ-0000
-	Code        string             `bson:"code" json:"code"`               // XY-aaaa/AB
-	Name        string             `bson:"name" json:"name"`               // ชื่อสินค้า
-	Description string             `bson:"description" json:"description"` // รายละเอียด
-	Color       string             `bson:"color" json:"color"`             // สี
-	Size        string             `bson:"size" json:"size"`               // ขนาด
-	Category    string             `bson:"category" json:"category"`       // ประเภทสินค้า (สำหรับสร้าง SKU_ID)
-	QRData      string             `bson:"qrData" json:"qrData"`           // ข้อมูล QR
-	ImageURL    *string            `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
-	Price       Price              `bson:"price" json:"price"` // ข้อมูลราคา
-	Stock       Stock              `bson:"stock" json:"stock"` // ข้อมูลสต็อก
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SKUID            string             `bson:"skuId" json:"skuId"`                       // XY-0000 หรือ XYZ-0000
+	Code             string             `bson:"code" json:"code"`                         // XY-aaaa/AB
+	Name             string             `bson:"name" json:"name"`                         // ชื่อสินค้า
+	Description      string             `bson:"description" json:"description"`           // รายละเอียด
+	Color            string             `bson:"color" json:"color"`                       // สี
+	Size             string             `bson:"size" json:"size"`                         // ขนาด
+	Category         string             `bson:"category" json:"category"`                 // ประเภทสินค้า (สำหรับสร้าง SKU_ID)
+	QRData           string             `bson:"qrData" json:"qrData"`                     // ข้อมูล QR
+	Images           []ProductImage     `bson:"images" json:"images"`                     // รูปภาพสินค้า (สูงสุด 10 รูป)
+	Tags             []string           `bson:"tags" json:"tags"`                         // แท็กสำหรับจัดกลุ่ม/กรองสินค้า
+	UOM              string             `bson:"uom" json:"uom"`                           // หน่วยขาย เช่น pcs, box, kg
+	ConversionFactor float64            `bson:"conversionFactor" json:"conversionFactor"` // จำนวนหน่วยย่อยต่อ 1 หน่วยขาย (0 = 1)
+	Price            Price              `bson:"price" json:"price"`                       // ข้อมูลราคา
+	Stock            Stock              `bson:"stock" json:"stock"`                       // ข้อมูลสต็อก
+	ReorderLevel     int                `bson:"reorderLevel" json:"reorderLevel"`         // จุดสั่งซื้อใหม่ (0 = ใช้ค่าเริ่มต้น)
+	ReorderQty       int                `bson:"reorderQty" json:"reorderQty"`             // จำนวนสต็อกเป้าหมายเมื่อสั่งซื้อใหม่
+	IsSerialised     bool               `bson:"isSerialised" json:"isSerialised"`         // ติดตามสินค้าทีละเครื่องด้วยหมายเลขซีเรียล
+	LotTracking      bool               `bson:"lotTracking" json:"lotTracking"`           // ติดตามล็อตและวันหมดอายุ
+	VelocityClass    string             `bson:"velocityClass" json:"velocityClass"`       // A, B, C ตามจำนวนที่ขายได้ใน 90 วัน หรือ unclassified
+	ViewCount        int                `bson:"viewCount" json:"viewCount"`               // จำนวนครั้งที่เปิดดูสินค้า
+	SalesCount       int                `bson:"salesCount" json:"salesCount"`             // จำนวนรายการขายใน 90 วัน
+	PopularityScore  float64            `bson:"popularityScore" json:"popularityScore"`   // คะแนนความนิยม 0-1
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version          int                `bson:"version" json:"version"`
+	IsDeleted        bool               `bson:"isDeleted" json:"isDeleted"`                     // ถูกลบแล้ว (soft delete)
+	DeletedAt        *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"` // วันที่ลบ
 }
 
 // ProductRequest represents the request body for creating/updating a product
 type ProductRequest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Color       string  `json:"color"`
-	Size        string  `json:"size"`
-	Category    string  `json:"category"`
-	ImageURL    *string `json:"imageUrl,omitempty"`
-	Price       Price   `json:"price"`
-	Stock       Stock   `json:"stock"`
+	Name             string         `json:"name" validate:"required,min=1,max=200"`
+	Description      string         `json:"description" validate:"max=2000"`
+	Color            string         `json:"color" validate:"max=100"`
+	Size             string         `json:"size" validate:"max=100"`
+	Category         string         `json:"category" validate:"max=100"`
+	ImageURL         *string        `json:"imageUrl,omitempty"` // รูปเดียวแบบเดิม ใช้เมื่อไม่ได้ส่ง images
+	Images           []ProductImage `json:"images,omitempty"`
+	Tags             []string       `json:"tags"`
+	UOM              string         `json:"uom" validate:"max=50"`
+	ConversionFactor float64        `json:"conversionFactor" validate:"min=0"`
+	Price            Price          `json:"price"`
+	Stock            Stock          `json:"stock"`
+	ReorderLevel     int            `json:"reorderLevel" validate:"min=0"`
+	ReorderQty       int            `json:"reorderQty" validate:"min=0"`
+	IsSerialised     bool           `json:"isSerialised"`
+	LotTracking      bool           `json:"lotTracking"`
+	Version          *int           `json:"version,omitempty"` // เวอร์ชันที่อ่านมา ต้องส่งเมื่อแก้ไข
+}
+
+// ProductPatchRequest represents a partial product update; only non-nil fields are changed
+type ProductPatchRequest struct {
+	Name             *string         `json:"name,omitempty"`
+	Description      *string         `json:"description,omitempty"`
+	Color            *string         `json:"color,omitempty"`
+	Size             *string         `json:"size,omitempty"`
+	Category         *string         `json:"category,omitempty"`
+	ImageURL         *string         `json:"imageUrl,omitempty"` // "" = ลบรูปภาพทั้งหมด, อื่นๆ = แทนที่ด้วยรูปเดียว
+	Images           *[]ProductImage `json:"images,omitempty"`
+	Tags             *[]string       `json:"tags,omitempty"`
+	UOM              *string         `json:"uom,omitempty"`
+	ConversionFactor *float64        `json:"conversionFactor,omitempty"`
+	Price            *Price          `json:"price,omitempty"`
+	Stock            *Stock          `json:"stock,omitempty"`
+	ReorderLevel     *int            `json:"reorderLevel,omitempty"`
+	ReorderQty       *int            `json:"reorderQty,omitempty"`
+	IsSerialised     *bool           `json:"isSerialised,omitempty"`
+	LotTracking      *bool           `json:"lotTracking,omitempty"`
+}
+
+// ToUpdateFields returns the $set fields for the non-nil values of the patch, or an empty map if nothing is set
+func (pr *ProductPatchRequest) ToUpdateFields() bson.M {
+	fields := bson.M{}
+	if pr.Name != nil {
+		fields["name"] = *pr.Name
+	}
+	if pr.Description != nil {
+		fields["description"] = *pr.Description
+	}
+	if pr.Color != nil {
+		fields["color"] = *pr.Color
+	}
+	if pr.Size != nil {
+		fields["size"] = *pr.Size
+	}
+	if pr.Category != nil {
+		fields["category"] = *pr.Category
+	}
+	if pr.ImageURL != nil {
+		fields["images"] = imagesFromURL(pr.ImageURL)
+	}
+	if pr.Images != nil {
+		product := Product{Images: *pr.Images}
+		product.normalizeImages()
+		fields["images"] = product.Images
+	}
+	if pr.Tags != nil {
+		fields["tags"] = NormalizeTags(*pr.Tags)
+	}
+	if pr.UOM != nil {
+		fields["uom"] = *pr.UOM
+	}
+	if pr.ConversionFactor != nil {
+		fields["conversionFactor"] = *pr.ConversionFactor
+	}
+	if pr.Price != nil {
+		fields["price"] = *pr.Price
+	}
+	if pr.Stock != nil {
+		fields["stock"] = *pr.Stock
+	}
+	if pr.ReorderLevel != nil {
+		fields["reorderLevel"] = *pr.ReorderLevel
+	}
+	if pr.ReorderQty != nil {
+		fields["reorderQty"] = *pr.ReorderQty
+	}
+	if pr.IsSerialised != nil {
+		fields["isSerialised"] = *pr.IsSerialised
+	}
+	if pr.LotTracking != nil {
+		fields["lotTracking"] = *pr.LotTracking
+	}
+
+	if len(fields) > 0 {
+		fields["updatedAt"] = time.Now()
+	}
+	return fields
+}
+
+// ProductSearchQuery holds the optional filters for searching products; all set fields must match
+type ProductSearchQuery struct {
+	Name     string // ค้นหาบางส่วนในชื่อหรือรายละเอียด (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+	Category string
+	Color    string
+	Size     string
+	SKUID    string // ค้นหาบางส่วนของ SKU ID หรือรหัสสินค้า
+}
+
+// StockDiscrepancy is a product whose actual stock differs from VAT + Non-VAT remaining
+type StockDiscrepancy struct {
+	ProductID     string `bson:"_id" json:"productId"`
+	SKUID         string `bson:"skuId" json:"skuId"`
+	Name          string `bson:"name" json:"name"`
+	ActualStock   int    `bson:"actualStock" json:"actualStock"`     // สินค้าคงเหลือจริง
+	ComputedStock int    `bson:"computedStock" json:"computedStock"` // VAT + Non-VAT คงเหลือ
+	Discrepancy   int    `bson:"discrepancy" json:"discrepancy"`     // actualStock - computedStock
 }
 
 // StockUpdateRequest represents the request body for updating stock
@@ -102,16 +226,24 @@ type PriceUpdateRequest struct {
 func (pr *ProductRequest) ToProduct() *Product {
 	now := time.Now()
 	return &Product{
-		Name:        pr.Name,
-		Description: pr.Description,
-		Color:       pr.Color,
-		Size:        pr.Size,
-		Category:    pr.Category,
-		ImageURL:    pr.ImageURL,
-		Price:       pr.Price,
-		Stock:       pr.Stock,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		Name:             pr.Name,
+		Description:      pr.Description,
+		Color:            pr.Color,
+		Size:             pr.Size,
+		Category:         pr.Category,
+		Images:           pr.gallery(),
+		Tags:             NormalizeTags(pr.Tags),
+		UOM:              pr.UOM,
+		ConversionFactor: pr.ConversionFactor,
+		Price:            pr.Price,
+		Stock:            pr.Stock,
+		ReorderLevel:     pr.ReorderLevel,
+		ReorderQty:       pr.ReorderQty,
+		IsSerialised:     pr.IsSerialised,
+		LotTracking:      pr.LotTracking,
+		VelocityClass:    VelocityClassUnclassified,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 }
 
@@ -122,13 +254,45 @@ func (p *Product) UpdateFromRequest(pr *ProductRequest) {
 	p.Color = pr.Color
 	p.Size = pr.Size
 	p.Category = pr.Category
-	// Update ImageURL (allow null to delete image)
-	p.ImageURL = pr.ImageURL
+	// Replace the gallery (allow null to delete all images)
+	p.Images = pr.gallery()
+	p.Tags = NormalizeTags(pr.Tags)
+	p.UOM = pr.UOM
+	p.ConversionFactor = pr.ConversionFactor
 	p.Price = pr.Price
 	p.Stock = pr.Stock
+	p.ReorderLevel = pr.ReorderLevel
+	p.ReorderQty = pr.ReorderQty
+	p.IsSerialised = pr.IsSerialised
+	p.LotTracking = pr.LotTracking
 	p.UpdatedAt = time.Now()
 }
 
+// gallery returns the request's images, falling back to the legacy imageUrl
+func (pr *ProductRequest) gallery() []ProductImage {
+	if len(pr.Images) == 0 {
+		return imagesFromURL(pr.ImageURL)
+	}
+	product := Product{Images: pr.Images}
+	product.normalizeImages()
+	return product.Images
+}
+
+// NormalizeTags trims and lowercases tags, dropping empty and duplicate ones
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
 // GetTotalStock returns the actual stock (ActualStock represents the real total)
 func (p *Product) GetTotalStock() int {
 	return p.Stock.ActualStock
@@ -221,10 +385,45 @@ func (p *Product) UpdatePrice(newPrice float64, isVAT bool, isPurchase bool) {
 	}
 }
 
-// IsLowStock checks if the product is low on stock
+// DefaultReorderLevel is used for products that have no reorder level set
+const DefaultReorderLevel = 10
+
+// GetReorderLevel returns the product's reorder level, falling back to DefaultReorderLevel
+func (p *Product) GetReorderLevel() int {
+	if p.ReorderLevel > 0 {
+		return p.ReorderLevel
+	}
+	return DefaultReorderLevel
+}
+
+// IsLowStock checks if the product is at or below its reorder level
 func (p *Product) IsLowStock() bool {
-	totalStock := p.GetTotalStock()
-	return totalStock <= 10
+	return p.GetTotalStock() <= p.GetReorderLevel()
+}
+
+// GetSuggestedOrderQty returns how many units to order to bring stock back up to ReorderQty
+func (p *Product) GetSuggestedOrderQty() int {
+	suggested := p.ReorderQty - p.GetTotalStock()
+	if suggested < 0 {
+		return 0
+	}
+	return suggested
+}
+
+// ReorderSuggestion represents a product that should be reordered
+type ReorderSuggestion struct {
+	ProductID         string     `json:"productId"`
+	SKUID             string     `json:"skuId"`
+	Code              string     `json:"code"`
+	Name              string     `json:"name"`
+	ActualStock       int        `json:"actualStock"`       // สินค้าคงเหลือจริง
+	ReorderLevel      int        `json:"reorderLevel"`      // จุดสั่งซื้อใหม่
+	ReorderQty        int        `json:"reorderQty"`        // จำนวนสต็อกเป้าหมาย
+	SuggestedOrderQty int        `json:"suggestedOrderQty"` // จำนวนที่แนะนำให้สั่งซื้อ
+	LatestPrice       float64    `json:"latestPrice"`       // ราคาซื้อล่าสุดจากผู้ขาย
+	SupplierID        *string    `json:"supplierId,omitempty"`
+	SupplierName      *string    `json:"supplierName,omitempty"`
+	LastPurchaseDate  *time.Time `json:"lastPurchaseDate,omitempty"` // วันที่ซื้อล่าสุด
 }
 
 // GetFormattedPrice returns formatted price string
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MaxProductImages is the largest gallery a product can have
+const MaxProductImages = 10
+
+var (
+	ErrTooManyImages     = errors.New("product already has the maximum number of images")
+	ErrImageNotFound     = errors.New("image not found")
+	ErrImageIndexInvalid = errors.New("invalid image index")
+)
+
+// ProductImage is one photo in a product's gallery
+type ProductImage struct {
+	URL         string `bson:"url" json:"url"`                                     // รูปต้นฉบับ
+	Thumb150URL string `bson:"thumb150Url,omitempty" json:"thumb150Url,omitempty"` // รูปย่อ 150×150 (JPEG)
+	Thumb400URL string `bson:"thumb400Url,omitempty" json:"thumb400Url,omitempty"` // รูปย่อ 400×400 (JPEG)
+	Order       int    `bson:"order" json:"order"`                                 // ลำดับการแสดงผล
+	IsPrimary   bool   `bson:"isPrimary" json:"isPrimary"`                         // รูปหลัก (ใช้เป็น imageUrl เดิม)
+	AltText     string `bson:"altText" json:"altText"`                             // คำอธิบายรูป
+}
+
+// SetThumbnailURL records the URL of the thumbnail of size pixels; other sizes are ignored
+func (i *ProductImage) SetThumbnailURL(size int, url string) {
+	switch size {
+	case 150:
+		i.Thumb150URL = url
+	case 400:
+		i.Thumb400URL = url
+	}
+}
+
+// FileURLs returns the URLs of the original and of every thumbnail stored for the image
+func (i *ProductImage) FileURLs() []string {
+	urls := []string{i.URL}
+	for _, url := range []string{i.Thumb150URL, i.Thumb400URL} {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// PrimaryImageURL returns the URL of the primary image, or nil if the product has no images
+func (p *Product) PrimaryImageURL() *string {
+	for _, image := range p.Images {
+		if image.IsPrimary {
+			url := image.URL
+			return &url
+		}
+	}
+	return nil
+}
+
+// AddImage adds an image to the gallery; the first image, or one flagged as primary, becomes the primary image
+func (p *Product) AddImage(image ProductImage) error {
+	if len(p.Images) >= MaxProductImages {
+		return ErrTooManyImages
+	}
+
+	if image.Order <= 0 {
+		image.Order = 1
+		for _, existing := range p.Images {
+			if existing.Order >= image.Order {
+				image.Order = existing.Order + 1
+			}
+		}
+	}
+	if image.IsPrimary {
+		for i := range p.Images {
+			p.Images[i].IsPrimary = false
+		}
+	}
+
+	p.Images = append(p.Images, image)
+	p.normalizeImages()
+	return nil
+}
+
+// RemoveImage removes the image at index; removing the primary image promotes the next one
+func (p *Product) RemoveImage(index int) (ProductImage, error) {
+	if index < 0 || index >= len(p.Images) {
+		return ProductImage{}, ErrImageIndexInvalid
+	}
+
+	removed := p.Images[index]
+	p.Images = append(p.Images[:index], p.Images[index+1:]...)
+	if removed.IsPrimary && len(p.Images) > 0 {
+		p.Images[index%len(p.Images)].IsPrimary = true
+	}
+	p.normalizeImages()
+	return removed, nil
+}
+
+// ImageIndex returns the gallery index of the image with url
+func (p *Product) ImageIndex(url string) (int, error) {
+	for i, image := range p.Images {
+		if image.URL == url {
+			return i, nil
+		}
+	}
+	return -1, ErrImageNotFound
+}
+
+// SetPrimaryImage makes the image at index the primary image
+func (p *Product) SetPrimaryImage(index int) error {
+	if index < 0 || index >= len(p.Images) {
+		return ErrImageIndexInvalid
+	}
+	for i := range p.Images {
+		p.Images[i].IsPrimary = i == index
+	}
+	return nil
+}
+
+// normalizeImages sorts the gallery by order and makes sure exactly one image is primary
+func (p *Product) normalizeImages() {
+	sort.SliceStable(p.Images, func(i, j int) bool {
+		return p.Images[i].Order < p.Images[j].Order
+	})
+
+	primary := -1
+	for i := range p.Images {
+		if p.Images[i].IsPrimary && primary == -1 {
+			primary = i
+			continue
+		}
+		p.Images[i].IsPrimary = false
+	}
+	if primary == -1 && len(p.Images) > 0 {
+		p.Images[0].IsPrimary = true
+	}
+}
+
+// imagesFromURL builds a single-image gallery from a legacy imageUrl value
+func imagesFromURL(url *string) []ProductImage {
+	if url == nil || *url == "" {
+		return []ProductImage{}
+	}
+	return []ProductImage{{URL: *url, Order: 1, IsPrimary: true}}
+}
+
+// MarshalJSON adds the primary image as the legacy imageUrl field
+func (p Product) MarshalJSON() ([]byte, error) {
+	type productAlias Product
+	images := p.Images
+	if images == nil {
+		images = []ProductImage{}
+	}
+	return json.Marshal(struct {
+		productAlias
+		Images   []ProductImage `json:"images"`
+		ImageURL *string        `json:"imageUrl,omitempty"`
+	}{
+		productAlias: productAlias(p),
+		Images:       images,
+		ImageURL:     p.PrimaryImageURL(),
+	})
+}
+
+// UnmarshalBSON reads documents saved before the gallery existed, turning imageUrl into a one-image gallery
+func (p *Product) UnmarshalBSON(data []byte) error {
+	type productAlias Product
+	var doc struct {
+		Product  productAlias `bson:",inline"`
+		ImageURL *string      `bson:"imageUrl,omitempty"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*p = Product(doc.Product)
+	if len(p.Images) == 0 {
+		p.Images = imagesFromURL(doc.ImageURL)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func imageURLs(p *Product) []string {
+	urls := make([]string, len(p.Images))
+	for i, image := range p.Images {
+		urls[i] = image.URL
+	}
+	return urls
+}
+
+func TestProductGallery(t *testing.T) {
+	var p Product
+	for _, url := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if err := p.AddImage(ProductImage{URL: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if primary := p.PrimaryImageURL(); primary == nil || *primary != "a.jpg" {
+		t.Fatalf("primary = %v, want the first image a.jpg", primary)
+	}
+
+	if err := p.AddImage(ProductImage{URL: "d.jpg", IsPrimary: true}); err != nil {
+		t.Fatal(err)
+	}
+	if primary := p.PrimaryImageURL(); *primary != "d.jpg" {
+		t.Errorf("primary = %s after adding a primary image, want d.jpg", *primary)
+	}
+
+	index, err := p.ImageIndex("d.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RemoveImage(index); err != nil {
+		t.Fatal(err)
+	}
+	primaries := 0
+	for _, image := range p.Images {
+		if image.IsPrimary {
+			primaries++
+		}
+	}
+	if len(p.Images) != 3 || primaries != 1 {
+		t.Errorf("after removing the primary image: %d images, %d primary, want 3 with 1 primary", len(p.Images), primaries)
+	}
+
+	if _, err := p.RemoveImage(5); !errors.Is(err, ErrImageIndexInvalid) {
+		t.Errorf("RemoveImage(5) = %v, want ErrImageIndexInvalid", err)
+	}
+	if _, err := p.ImageIndex("missing.jpg"); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("ImageIndex(missing) = %v, want ErrImageNotFound", err)
+	}
+}
+
+func TestProductGalleryLimit(t *testing.T) {
+	var p Product
+	for i := 0; i < MaxProductImages; i++ {
+		if err := p.AddImage(ProductImage{URL: "image.jpg"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.AddImage(ProductImage{URL: "one-too-many.jpg"}); !errors.Is(err, ErrTooManyImages) {
+		t.Errorf("AddImage past the limit = %v, want ErrTooManyImages", err)
+	}
+}
+
+func TestProductGalleryKeepsOrder(t *testing.T) {
+	p := Product{Images: []ProductImage{{URL: "b.jpg", Order: 2}, {URL: "a.jpg", Order: 1}}}
+	p.normalizeImages()
+	if urls := imageURLs(&p); urls[0] != "a.jpg" || urls[1] != "b.jpg" {
+		t.Errorf("images = %v, want sorted by order", urls)
+	}
+	if !p.Images[0].IsPrimary {
+		t.Error("first image not made primary when none was")
+	}
+}
+
+func TestProductLegacyImageURL(t *testing.T) {
+	legacy, err := bson.Marshal(bson.M{"name": "Box", "imageUrl": "old.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Product
+	if err := bson.Unmarshal(legacy, &p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Images) != 1 || p.Images[0].URL != "old.jpg" || !p.Images[0].IsPrimary {
+		t.Fatalf("images = %+v, want a one-image gallery of old.jpg", p.Images)
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		ImageURL string `json:"imageUrl"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ImageURL != "old.jpg" {
+		t.Errorf("JSON imageUrl = %q, want the primary image old.jpg", decoded.ImageURL)
+	}
+}
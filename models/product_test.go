@@ -0,0 +1,84 @@
+package models
+
+import "testing"
+
+func TestProductReorder(t *testing.T) {
+	tests := []struct {
+		name          string
+		product       Product
+		wantLevel     int
+		wantLow       bool
+		wantSuggested int
+	}{
+		{"default level", Product{Stock: Stock{ActualStock: 10}, ReorderQty: 50}, DefaultReorderLevel, true, 40},
+		{"above own level", Product{Stock: Stock{ActualStock: 6}, ReorderLevel: 5, ReorderQty: 20}, 5, false, 14},
+		{"at own level", Product{Stock: Stock{ActualStock: 5}, ReorderLevel: 5, ReorderQty: 20}, 5, true, 15},
+		{"stock above target", Product{Stock: Stock{ActualStock: 30}, ReorderLevel: 5, ReorderQty: 20}, 5, false, 0},
+		{"negative stock", Product{Stock: Stock{ActualStock: -2}, ReorderQty: 10}, DefaultReorderLevel, true, 12},
+	}
+	for _, tt := range tests {
+		if got := tt.product.GetReorderLevel(); got != tt.wantLevel {
+			t.Errorf("%s: GetReorderLevel = %d, want %d", tt.name, got, tt.wantLevel)
+		}
+		if got := tt.product.IsLowStock(); got != tt.wantLow {
+			t.Errorf("%s: IsLowStock = %t, want %t", tt.name, got, tt.wantLow)
+		}
+		if got := tt.product.GetSuggestedOrderQty(); got != tt.wantSuggested {
+			t.Errorf("%s: GetSuggestedOrderQty = %d, want %d", tt.name, got, tt.wantSuggested)
+		}
+	}
+}
+
+func TestProductPatchRequestToUpdateFields(t *testing.T) {
+	if fields := (&ProductPatchRequest{}).ToUpdateFields(); len(fields) != 0 {
+		t.Errorf("empty patch: fields = %v, want none", fields)
+	}
+
+	name := "Kraft Box"
+	reorderLevel := 0
+	tags := []string{" Eco ", "eco", "Gift"}
+	fields := (&ProductPatchRequest{Name: &name, ReorderLevel: &reorderLevel, Tags: &tags}).ToUpdateFields()
+
+	if len(fields) != 4 {
+		t.Errorf("fields = %v, want name, reorderLevel, tags and updatedAt only", fields)
+	}
+	if fields["name"] != "Kraft Box" {
+		t.Errorf("name = %v, want Kraft Box", fields["name"])
+	}
+	// A zero value is set, not skipped
+	if level, ok := fields["reorderLevel"]; !ok || level != 0 {
+		t.Errorf("reorderLevel = %v (set %t), want 0", level, ok)
+	}
+	if got, ok := fields["tags"].([]string); !ok || len(got) != 2 || got[0] != "eco" || got[1] != "gift" {
+		t.Errorf("tags = %v, want the normalized [eco gift]", fields["tags"])
+	}
+	if _, ok := fields["updatedAt"]; !ok {
+		t.Error("updatedAt not set")
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"nil", nil, []string{}},
+		{"trims and lowercases", []string{"  Eco ", "GIFT"}, []string{"eco", "gift"}},
+		{"drops empty", []string{"", "   ", "box"}, []string{"box"}},
+		{"drops duplicates keeping first order", []string{"Box", "eco", "box ", "ECO"}, []string{"box", "eco"}},
+	}
+	for _, tt := range tests {
+		got := NormalizeTags(tt.tags)
+		if got == nil || len(got) != len(tt.want) {
+			t.Errorf("%s: NormalizeTags(%q) = %q, want %q", tt.name, tt.tags, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: NormalizeTags(%q) = %q, want %q", tt.name, tt.tags, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ProductProfitability compares what a product sold for with what it cost to buy. Amounts exclude VAT.
+type ProductProfitability struct {
+	ProductID           string  `bson:"productId" json:"productId"`
+	ProductName         string  `bson:"productName" json:"productName"`
+	SKUID               string  `bson:"skuId" json:"skuId"`
+	UnitsSold           float64 `bson:"unitsSold" json:"unitsSold"`
+	SalesRevenue        float64 `bson:"salesRevenue" json:"salesRevenue"` // ยอดขายหลังหักส่วนลด
+	AverageSalePrice    float64 `bson:"-" json:"averageSalePrice"`
+	PurchasedQuantity   float64 `bson:"purchasedQuantity" json:"purchasedQuantity"` // จำนวนที่ซื้อทั้งหมดจนถึงวันสิ้นสุด
+	PurchaseCost        float64 `bson:"purchaseCost" json:"-"`
+	AveragePurchaseCost float64 `bson:"-" json:"averagePurchaseCost"` // 0 เมื่อไม่มีประวัติการซื้อ
+	GrossMarginPerUnit  float64 `bson:"-" json:"grossMarginPerUnit"`
+	GrossProfit         float64 `bson:"-" json:"grossProfit"` // grossMarginPerUnit × unitsSold
+	MarginPct           float64 `bson:"-" json:"marginPct"`   // grossProfit / salesRevenue × 100
+}
+
+// ProfitabilityReport lists the products sold in a period, most profitable first
+type ProfitabilityReport struct {
+	StartDate time.Time              `json:"startDate"`
+	EndDate   time.Time              `json:"endDate"`
+	Products  []ProductProfitability `json:"products"`
+}
+
+// Calculate fills in the averages and margins from the sale and purchase totals
+func (p *ProductProfitability) Calculate() {
+	if p.UnitsSold > 0 {
+		p.AverageSalePrice = p.SalesRevenue / p.UnitsSold
+	}
+	if p.PurchasedQuantity > 0 {
+		p.AveragePurchaseCost = p.PurchaseCost / p.PurchasedQuantity
+	}
+	p.GrossMarginPerUnit = p.AverageSalePrice - p.AveragePurchaseCost
+	p.GrossProfit = p.SalesRevenue - p.AveragePurchaseCost*p.UnitsSold
+	if p.SalesRevenue != 0 {
+		p.MarginPct = p.GrossProfit / p.SalesRevenue * 100
+	}
+}
+
+// NewProfitabilityReport calculates each product's margins and sorts them by gross profit, highest first
+func NewProfitabilityReport(from, to time.Time, products []ProductProfitability) *ProfitabilityReport {
+	if products == nil {
+		products = []ProductProfitability{}
+	}
+	for i := range products {
+		products[i].Calculate()
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		if products[i].GrossProfit != products[j].GrossProfit {
+			return products[i].GrossProfit > products[j].GrossProfit
+		}
+		return products[i].ProductName < products[j].ProductName
+	})
+
+	return &ProfitabilityReport{
+		StartDate: from,
+		EndDate:   to,
+		Products:  products,
+	}
+}
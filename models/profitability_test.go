@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewProfitabilityReport(t *testing.T) {
+	products := []ProductProfitability{
+		{ProductID: "c", ProductName: "Cap", UnitsSold: 5, SalesRevenue: 250, PurchasedQuantity: 10, PurchaseCost: 700},
+		{ProductID: "b", ProductName: "Bottle", UnitsSold: 4, SalesRevenue: 200}, // never purchased
+		{ProductID: "a", ProductName: "Box", UnitsSold: 10, SalesRevenue: 1000, PurchasedQuantity: 20, PurchaseCost: 1200},
+	}
+	report := NewProfitabilityReport(time.Time{}, time.Time{}, products)
+
+	// Most profitable first
+	want := []struct {
+		id                                 string
+		averageSalePrice, averageCost      float64
+		marginPerUnit, grossProfit, margin float64
+	}{
+		{"a", 100, 60, 40, 400, 40},
+		{"b", 50, 0, 50, 200, 100},
+		{"c", 50, 70, -20, -100, -40},
+	}
+	if len(report.Products) != len(want) {
+		t.Fatalf("got %d products, want %d", len(report.Products), len(want))
+	}
+	for i, w := range want {
+		got := report.Products[i]
+		if got.ProductID != w.id || got.AverageSalePrice != w.averageSalePrice || got.AveragePurchaseCost != w.averageCost ||
+			got.GrossMarginPerUnit != w.marginPerUnit || got.GrossProfit != w.grossProfit || got.MarginPct != w.margin {
+			t.Errorf("product %d = %s sale %v cost %v margin/unit %v profit %v margin %v%%, want %+v", i, got.ProductID,
+				got.AverageSalePrice, got.AveragePurchaseCost, got.GrossMarginPerUnit, got.GrossProfit, got.MarginPct, w)
+		}
+	}
+}
+
+func TestNewProfitabilityReportWithoutSales(t *testing.T) {
+	if report := NewProfitabilityReport(time.Time{}, time.Time{}, nil); report.Products == nil {
+		t.Error("products = nil, want an empty list")
+	}
+}
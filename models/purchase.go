@@ -7,36 +7,71 @@ import (
 )
 
 type Purchase struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	PurchaseCode string             `bson:"purchaseCode" json:"purchaseCode"`
-	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
-	PurchaseDate time.Time          `bson:"purchaseDate" json:"purchaseDate"`
-	CustomerID   string             `bson:"customerId" json:"customerId"`
-	CustomerName string             `bson:"customerName" json:"customerName"`
-	ContactName  *string            `bson:"contactName,omitempty" json:"contactName,omitempty"`
-	CustomerCode *string            `bson:"customerCode,omitempty" json:"customerCode,omitempty"`
-	TaxID        *string            `bson:"taxId,omitempty" json:"taxId,omitempty"`
-	Address      *string            `bson:"address,omitempty" json:"address,omitempty"`
-	Phone        *string            `bson:"phone,omitempty" json:"phone,omitempty"`
-	Notes        *string            `bson:"notes,omitempty" json:"notes,omitempty"`
-	Items        []PurchaseItem     `bson:"items" json:"items"`
-	IsVAT        bool               `bson:"isVAT" json:"isVAT"`
-	ShippingCost float64            `bson:"shippingCost" json:"shippingCost"`
-	Payment      PaymentInfo        `bson:"payment" json:"payment"`
-	Warehouse    WarehouseInfo      `bson:"warehouse" json:"warehouse"`
-	TotalAmount  float64            `bson:"totalAmount" json:"totalAmount"`
-	TotalVAT     float64            `bson:"totalVAT" json:"totalVAT"`
-	GrandTotal   float64            `bson:"grandTotal" json:"grandTotal"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PurchaseCode    string             `bson:"purchaseCode" json:"purchaseCode"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version         int                `bson:"version" json:"version"`
+	PurchaseDate    time.Time          `bson:"purchaseDate" json:"purchaseDate"`
+	CustomerID      string             `bson:"customerId" json:"customerId"`
+	CustomerName    string             `bson:"customerName" json:"customerName"`
+	SupplierID      *string            `bson:"supplierId,omitempty" json:"supplierId,omitempty"`
+	SupplierName    *string            `bson:"supplierName,omitempty" json:"supplierName,omitempty"`
+	ContactName     *string            `bson:"contactName,omitempty" json:"contactName,omitempty"`
+	CustomerCode    *string            `bson:"customerCode,omitempty" json:"customerCode,omitempty"`
+	TaxID           *string            `bson:"taxId,omitempty" json:"taxId,omitempty"`
+	Address         *string            `bson:"address,omitempty" json:"address,omitempty"`
+	Phone           *string            `bson:"phone,omitempty" json:"phone,omitempty"`
+	Notes           *string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	Items           []PurchaseItem     `bson:"items" json:"items"`
+	IsVAT           bool               `bson:"isVAT" json:"isVAT"`
+	VATRate         float64            `bson:"vatRate,omitempty" json:"vatRate"` // อัตรา VAT ณ วันที่สร้าง (ไม่มี = 7%)
+	ShippingCost    float64            `bson:"shippingCost" json:"shippingCost"`
+	Currency        string             `bson:"currency,omitempty" json:"currency"`         // สกุลเงินของผู้จำหน่าย ISO 4217 (ไม่มี = THB)
+	ExchangeRate    float64            `bson:"exchangeRate,omitempty" json:"exchangeRate"` // บาทต่อ 1 หน่วยสกุลเงิน (THB = 1)
+	Payment         PaymentInfo        `bson:"payment" json:"payment"`
+	Payments        []PaymentRecord    `bson:"payments,omitempty" json:"payments,omitempty"` // ประวัติการชำระเงิน
+	Warehouse       WarehouseInfo      `bson:"warehouse" json:"warehouse"`
+	StockOnReceipt  bool               `bson:"stockOnReceipt,omitempty" json:"stockOnReceipt,omitempty"`   // เพิ่มสต็อกตามจำนวนที่รับเข้าคลัง (ไม่มี = รายการเก่าที่เพิ่มสต็อกตามจำนวนที่สั่งซื้อเมื่อยืนยัน)
+	IsDraft         bool               `bson:"isDraft,omitempty" json:"isDraft,omitempty"`                 // สร้างจากการคัดลอก ยังไม่เพิ่มสต็อกจนกว่าจะยืนยัน
+	Status          string             `bson:"status,omitempty" json:"status,omitempty"`                   // สถานะ (ไม่มี = draft หรือ confirmed ตาม isDraft)
+	StatusHistory   []StatusEntry      `bson:"statusHistory,omitempty" json:"statusHistory,omitempty"`     // ประวัติการเปลี่ยนสถานะ
+	ApprovalStatus  string             `bson:"approvalStatus,omitempty" json:"approvalStatus,omitempty"`   // pending, approved, rejected (ไม่มี = approved)
+	ApprovedBy      *string            `bson:"approvedBy,omitempty" json:"approvedBy,omitempty"`           // ผู้อนุมัติหรือปฏิเสธ (X-User-ID)
+	ApprovedAt      *time.Time         `bson:"approvedAt,omitempty" json:"approvedAt,omitempty"`           // วันที่อนุมัติหรือปฏิเสธ
+	RejectionReason *string            `bson:"rejectionReason,omitempty" json:"rejectionReason,omitempty"` // เหตุผลที่ปฏิเสธ
+	TotalAmount     float64            `bson:"totalAmount" json:"totalAmount"`
+	DiscountTotal   float64            `bson:"discountTotal" json:"discountTotal"` // ส่วนลดรวมทุกรายการ
+	TotalVAT        float64            `bson:"totalVAT" json:"totalVAT"`
+	GrandTotal      float64            `bson:"grandTotal" json:"grandTotal"`
+	IsDeleted       bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt       *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
 type PurchaseItem struct {
-	ProductID   string  `bson:"productId" json:"productId"`
+	ProductID   string  `bson:"productId" json:"productId" validate:"required"`
 	ProductName string  `bson:"productName" json:"productName"`
 	ProductCode string  `bson:"productCode" json:"productCode"`
-	Quantity    int     `bson:"quantity" json:"quantity"`
-	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice"`
+	Quantity    int     `bson:"quantity" json:"quantity" validate:"required,min=1"`
+	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice" validate:"min=0"` // บาท; คำนวณจาก unitPriceForeign เมื่อซื้อเป็นเงินต่างประเทศ
 	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`
+
+	Currency         string  `bson:"currency,omitempty" json:"currency,omitempty"`                                  // สกุลเงินของรายการซื้อ
+	ExchangeRate     float64 `bson:"exchangeRate,omitempty" json:"exchangeRate,omitempty"`                          // บาทต่อ 1 หน่วยสกุลเงิน
+	UnitPriceForeign float64 `bson:"unitPriceForeign,omitempty" json:"unitPriceForeign,omitempty" validate:"min=0"` // ราคาต่อหน่วยในสกุลเงินของผู้จำหน่าย
+
+	DiscountPercent float64 `bson:"discountPercent" json:"discountPercent" validate:"min=0,max=100"` // ส่วนลด (%)
+	DiscountAmount  float64 `bson:"discountAmount" json:"discountAmount" validate:"min=0"`           // ส่วนลด (บาท)
+
+	DisplayQuantity float64 `bson:"displayQuantity,omitempty" json:"displayQuantity,omitempty"` // จำนวนในหน่วยซื้อของสินค้า (Quantity / ConversionFactor)
+	DisplayUOM      string  `bson:"displayUom,omitempty" json:"displayUom,omitempty"`           // หน่วยซื้อ เช่น box
+
+	SerialNumbers []string `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"` // หมายเลขซีเรียลของสินค้าที่ติดตามรายเครื่อง (1 ต่อหน่วย)
+
+	LotNumber  string     `bson:"lotNumber,omitempty" json:"lotNumber,omitempty"`   // เลขล็อต (สินค้าที่ติดตามล็อต)
+	ExpiryDate *time.Time `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"` // วันหมดอายุของล็อต
+
+	PriceVariance float64 `bson:"priceVariance" json:"priceVariance"` // (ราคาซื้อ - ราคาซื้อเฉลี่ยก่อนรับเข้า) x จำนวน; ติดลบ = ซื้อได้ถูกกว่า
 }
 
 type PaymentInfo struct {
@@ -54,14 +89,16 @@ type BankAccount struct {
 	AccountNumber string `json:"accountNumber"`
 	BankName      string `json:"bankName"`
 	AccountType   string `json:"accountType"`
+	PromptPayID   string `json:"promptPayId,omitempty"` // เบอร์โทรศัพท์/เลขประจำตัวผู้เสียภาษีสำหรับพร้อมเพย์
 	IsActive      bool   `json:"isActive"`
 }
 
 type WarehouseInfo struct {
-	IsUpdated      bool            `bson:"isUpdated" json:"isUpdated"`
-	Notes          *string         `bson:"notes,omitempty" json:"notes,omitempty"`
-	ActualShipping float64         `bson:"actualShipping" json:"actualShipping"`
-	Items          []WarehouseItem `bson:"items" json:"items"`
+	IsUpdated      bool               `bson:"isUpdated" json:"isUpdated"`
+	Notes          *string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	ActualShipping float64            `bson:"actualShipping" json:"actualShipping"`
+	Items          []WarehouseItem    `bson:"items" json:"items"`
+	Receipts       []WarehouseReceipt `bson:"receipts,omitempty" json:"receipts,omitempty"` // ประวัติการรับสินค้าเข้าคลัง
 }
 
 type WarehouseItem struct {
@@ -74,79 +111,156 @@ type WarehouseItem struct {
 
 type PurchaseRequest struct {
 	PurchaseDate time.Time      `json:"purchaseDate" bson:"purchaseDate"`
-	CustomerID   string         `json:"customerId" bson:"customerId"`
+	CustomerID   string         `json:"customerId" bson:"customerId" validate:"required"`
+	SupplierID   *string        `json:"supplierId,omitempty" bson:"supplierId,omitempty"`
 	Notes        *string        `json:"notes,omitempty" bson:"notes,omitempty"`
-	Items        []PurchaseItem `json:"items" bson:"items"`
+	Items        []PurchaseItem `json:"items" bson:"items" validate:"required,min=1,dive"`
 	IsVAT        bool           `json:"isVAT" bson:"isVAT"`
-	ShippingCost float64        `json:"shippingCost" bson:"shippingCost"`
+	ShippingCost float64        `json:"shippingCost" bson:"shippingCost" validate:"min=0"`
+	Currency     string         `json:"currency,omitempty" bson:"currency,omitempty" validate:"omitempty,iso4217"` // default THB
+	ExchangeRate float64        `json:"exchangeRate,omitempty" bson:"exchangeRate,omitempty" validate:"min=0"`     // 0 = the stored rate for the purchase date
 	Payment      PaymentInfo    `json:"payment" bson:"payment"`
 	Warehouse    WarehouseInfo  `json:"warehouse" bson:"warehouse"`
+	Version      *int           `json:"version,omitempty" bson:"-"` // required when updating
 }
 
 func (pr *PurchaseRequest) ToPurchase() *Purchase {
 	now := time.Now()
 
-	// Calculate totals
-	var totalAmount float64
-	for _, item := range pr.Items {
-		totalAmount += item.TotalPrice
-	}
+	// Calculate totals, in baht
+	currency, exchangeRate := purchaseCurrency(pr.Currency, pr.ExchangeRate)
+	applyCurrency(pr.Items, currency, exchangeRate)
+	totalAmount, discountTotal := calculatePurchaseItems(pr.Items)
 
-	var totalVAT float64
-	if pr.IsVAT {
-		totalVAT = totalAmount * 0.07 // 7% VAT
-	}
+	totalVAT := calculateVAT(totalAmount, pr.IsVAT, VATRate)
 
 	grandTotal := totalAmount + totalVAT
 
 	return &Purchase{
-		PurchaseCode: "", // Will be populated by handler
-		CreatedAt:    now,
-		UpdatedAt:    now,
-		PurchaseDate: pr.PurchaseDate,
-		CustomerID:   pr.CustomerID,
-		CustomerName: "",  // Will be populated from customer data
-		ContactName:  nil, // Will be populated from customer data
-		CustomerCode: nil, // Will be populated from customer data
-		TaxID:        nil, // Will be populated from customer data
-		Address:      nil, // Will be populated from customer data
-		Phone:        nil, // Will be populated from customer data
-		Notes:        pr.Notes,
-		Items:        pr.Items,
-		IsVAT:        pr.IsVAT,
-		ShippingCost: pr.ShippingCost,
-		Payment:      pr.Payment,
-		Warehouse:    pr.Warehouse,
-		TotalAmount:  totalAmount,
-		TotalVAT:     totalVAT,
-		GrandTotal:   grandTotal,
+		PurchaseCode:   "", // Will be populated by handler
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		PurchaseDate:   pr.PurchaseDate,
+		CustomerID:     pr.CustomerID,
+		CustomerName:   "", // Will be populated from customer data
+		SupplierID:     pr.SupplierID,
+		ContactName:    nil, // Will be populated from customer data
+		CustomerCode:   nil, // Will be populated from customer data
+		TaxID:          nil, // Will be populated from customer data
+		Address:        nil, // Will be populated from customer data
+		Phone:          nil, // Will be populated from customer data
+		Notes:          pr.Notes,
+		Items:          pr.Items,
+		IsVAT:          pr.IsVAT,
+		Status:         OrderStatusConfirmed,
+		VATRate:        VATRate,
+		ShippingCost:   pr.ShippingCost,
+		Currency:       currency,
+		ExchangeRate:   exchangeRate,
+		Payment:        pr.Payment,
+		Warehouse:      pr.Warehouse,
+		StockOnReceipt: true, // Goods receipts add the stock
+		TotalAmount:    totalAmount,
+		DiscountTotal:  discountTotal,
+		TotalVAT:       totalVAT,
+		GrandTotal:     grandTotal,
 	}
 }
 
 func (p *Purchase) UpdateFromRequest(pr *PurchaseRequest) {
-	// Calculate totals
-	var totalAmount float64
-	for _, item := range pr.Items {
-		totalAmount += item.TotalPrice
-	}
+	// Calculate totals, in baht
+	currency, exchangeRate := purchaseCurrency(pr.Currency, pr.ExchangeRate)
+	applyCurrency(pr.Items, currency, exchangeRate)
+	totalAmount, discountTotal := calculatePurchaseItems(pr.Items)
 
-	var totalVAT float64
-	if pr.IsVAT {
-		totalVAT = totalAmount * 0.07 // 7% VAT
-	}
+	// Recalculated at the rate the purchase was created with
+	p.VATRate = p.EffectiveVATRate()
+	totalVAT := calculateVAT(totalAmount, pr.IsVAT, p.VATRate)
 
 	grandTotal := totalAmount + totalVAT
 
 	p.PurchaseDate = pr.PurchaseDate
 	p.CustomerID = pr.CustomerID
+	p.SupplierID = pr.SupplierID
 	p.Notes = pr.Notes
 	p.Items = pr.Items
 	p.IsVAT = pr.IsVAT
 	p.ShippingCost = pr.ShippingCost
+	p.Currency = currency
+	p.ExchangeRate = exchangeRate
 	p.Payment = pr.Payment
+	receipts := p.Warehouse.Receipts
 	p.Warehouse = pr.Warehouse
+	p.Warehouse.Receipts = receipts // receipts are only recorded through the receive endpoint
 	p.TotalAmount = totalAmount
+	p.DiscountTotal = discountTotal
 	p.TotalVAT = totalVAT
 	p.GrandTotal = grandTotal
 	p.UpdatedAt = time.Now()
 }
+
+// calculatePurchaseItems applies line discounts to the items and returns the total amount and total discount
+// Duplicate copies a purchase for a repeat order as a draft: same supplier, items, currency and exchange rate, VAT
+// type, shipping and notes, but dated now, unpaid, not yet received and with no serial numbers or lots
+func (p *Purchase) Duplicate(purchaseCode string) *Purchase {
+	now := time.Now()
+	items := make([]PurchaseItem, len(p.Items))
+	for i, item := range p.Items {
+		item.SerialNumbers = nil
+		item.LotNumber = ""
+		item.ExpiryDate = nil
+		item.PriceVariance = 0
+		items[i] = item
+	}
+
+	payment := p.Payment
+	payment.IsPaid = false
+	payment.PaymentDate = nil
+
+	// The copy is taxed at the current rate
+	totalVAT := calculateVAT(p.TotalAmount, p.IsVAT, VATRate)
+
+	return &Purchase{
+		PurchaseCode:   purchaseCode,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		PurchaseDate:   now,
+		CustomerID:     p.CustomerID,
+		CustomerName:   p.CustomerName,
+		SupplierID:     p.SupplierID,
+		SupplierName:   p.SupplierName,
+		ContactName:    p.ContactName,
+		CustomerCode:   p.CustomerCode,
+		TaxID:          p.TaxID,
+		Address:        p.Address,
+		Phone:          p.Phone,
+		Notes:          p.Notes,
+		Items:          items,
+		IsVAT:          p.IsVAT,
+		VATRate:        VATRate,
+		ShippingCost:   p.ShippingCost,
+		Currency:       p.Currency,
+		ExchangeRate:   p.ExchangeRate,
+		Payment:        payment,
+		Warehouse:      WarehouseInfo{Items: []WarehouseItem{}},
+		StockOnReceipt: true,
+		IsDraft:        true,
+		Status:         OrderStatusDraft,
+		TotalAmount:    p.TotalAmount,
+		DiscountTotal:  p.DiscountTotal,
+		TotalVAT:       totalVAT,
+		GrandTotal:     p.TotalAmount + totalVAT,
+	}
+}
+
+func calculatePurchaseItems(items []PurchaseItem) (float64, float64) {
+	var totalAmount, discountTotal float64
+	for i := range items {
+		item := &items[i]
+		item.TotalPrice = CalculateLineTotal(float64(item.Quantity), item.UnitPrice, item.DiscountPercent, item.DiscountAmount)
+		item.PriceVariance = 0 // worked out again when the purchase adds stock
+		totalAmount += item.TotalPrice
+		discountTotal += calculateLineDiscount(float64(item.Quantity), item.UnitPrice, item.TotalPrice)
+	}
+	return totalAmount, discountTotal
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Purchase approval statuses
+const (
+	PurchaseApprovalPending  = "pending"
+	PurchaseApprovalApproved = "approved"
+	PurchaseApprovalRejected = "rejected"
+)
+
+// ErrPurchaseNotPending is returned when approving or rejecting a purchase that is not awaiting approval
+var ErrPurchaseNotPending = errors.New("purchase is not pending approval")
+
+// PurchaseRejectRequest is the body of POST /api/purchases/{id}/reject
+type PurchaseRejectRequest struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+// IsApproved reports whether the purchase has been approved; purchases saved before approvals were recorded are
+func (p *Purchase) IsApproved() bool {
+	return p.ApprovalStatus == "" || p.ApprovalStatus == PurchaseApprovalApproved
+}
+
+// AddsStock reports whether the purchase is in effect: confirmed, approved and not cancelled, so its prices apply
+// and its goods can be received into stock
+func (p *Purchase) AddsStock() bool {
+	return !p.IsDraft && p.IsApproved() && p.CurrentStatus() != OrderStatusCancelled
+}
+
+// RequestApproval leaves the purchase pending until an admin approves it
+func (p *Purchase) RequestApproval() {
+	p.ApprovalStatus = PurchaseApprovalPending
+	p.ApprovedBy = nil
+	p.ApprovedAt = nil
+	p.RejectionReason = nil
+}
+
+// Approve approves a pending purchase as approvedBy
+func (p *Purchase) Approve(approvedBy *string) error {
+	if p.ApprovalStatus != PurchaseApprovalPending {
+		return ErrPurchaseNotPending
+	}
+	now := time.Now()
+	p.ApprovalStatus = PurchaseApprovalApproved
+	p.ApprovedBy = approvedBy
+	p.ApprovedAt = &now
+	p.RejectionReason = nil
+	p.UpdatedAt = now
+	return nil
+}
+
+// Reject rejects a pending purchase for reason; rejectedBy and the time are recorded as the approver's
+func (p *Purchase) Reject(reason string, rejectedBy *string) error {
+	if p.ApprovalStatus != PurchaseApprovalPending {
+		return ErrPurchaseNotPending
+	}
+	now := time.Now()
+	p.ApprovalStatus = PurchaseApprovalRejected
+	p.ApprovedBy = rejectedBy
+	p.ApprovedAt = &now
+	p.RejectionReason = &reason
+	p.UpdatedAt = now
+	return nil
+}
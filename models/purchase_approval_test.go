@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPurchaseApproval(t *testing.T) {
+	if legacy := (&Purchase{}); !legacy.IsApproved() || !legacy.AddsStock() {
+		t.Error("purchase saved before approvals: want approved and in stock")
+	}
+
+	admin := "admin"
+	purchase := &Purchase{}
+	purchase.RequestApproval()
+	if purchase.IsApproved() || purchase.AddsStock() {
+		t.Error("pending purchase: want not approved and not in stock")
+	}
+
+	if err := purchase.Approve(&admin); err != nil {
+		t.Fatal(err)
+	}
+	if !purchase.AddsStock() || *purchase.ApprovedBy != "admin" || purchase.ApprovedAt == nil {
+		t.Errorf("approved purchase: %+v, want in stock and approved by admin", purchase)
+	}
+	if err := purchase.Approve(&admin); !errors.Is(err, ErrPurchaseNotPending) {
+		t.Errorf("approving twice: %v, want ErrPurchaseNotPending", err)
+	}
+	if err := purchase.Reject("too late", &admin); !errors.Is(err, ErrPurchaseNotPending) {
+		t.Errorf("rejecting an approved purchase: %v, want ErrPurchaseNotPending", err)
+	}
+
+	// A rejected purchase can be resubmitted, which clears the rejection
+	rejected := &Purchase{}
+	rejected.RequestApproval()
+	if err := rejected.Reject("over budget", &admin); err != nil {
+		t.Fatal(err)
+	}
+	if rejected.AddsStock() || rejected.RejectionReason == nil || *rejected.RejectionReason != "over budget" {
+		t.Errorf("rejected purchase: %+v, want no stock and the reason kept", rejected)
+	}
+	rejected.RequestApproval()
+	if rejected.RejectionReason != nil || rejected.ApprovedBy != nil || rejected.ApprovalStatus != PurchaseApprovalPending {
+		t.Errorf("resubmitted purchase: %+v, want pending with the rejection cleared", rejected)
+	}
+
+	if (&Purchase{IsDraft: true}).AddsStock() {
+		t.Error("draft purchase: want not in stock")
+	}
+	if (&Purchase{Status: OrderStatusCancelled}).AddsStock() {
+		t.Error("cancelled purchase: want not in stock")
+	}
+}
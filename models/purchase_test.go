@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurchaseDuplicate(t *testing.T) {
+	received := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	supplier := "Box Factory"
+	original := &Purchase{
+		PurchaseCode: "PUR-VAT-6701-0001",
+		PurchaseDate: received,
+		SupplierName: &supplier,
+		Items: []PurchaseItem{
+			{ProductID: "p1", Quantity: 2, UnitPrice: 20000, TotalPrice: 40000, SerialNumbers: []string{"SN-1", "SN-2"}, LotNumber: "LOT-1"},
+		},
+		IsVAT:        true,
+		ShippingCost: 100,
+		TotalAmount:  40000,
+		Payment:      PaymentInfo{IsPaid: true, PaymentDate: &received},
+		Warehouse:    WarehouseInfo{IsUpdated: true},
+		CreatedAt:    received,
+	}
+	before := time.Now()
+
+	duplicate := original.Duplicate("PUR-VAT-6702-0001")
+
+	if duplicate.PurchaseCode != "PUR-VAT-6702-0001" {
+		t.Errorf("PurchaseCode = %s, want PUR-VAT-6702-0001", duplicate.PurchaseCode)
+	}
+	if duplicate.PurchaseDate.Before(before) || duplicate.CreatedAt.Before(before) || duplicate.UpdatedAt.Before(before) {
+		t.Errorf("dates = %v, %v, %v, want now", duplicate.PurchaseDate, duplicate.CreatedAt, duplicate.UpdatedAt)
+	}
+	if duplicate.SupplierName != &supplier || !duplicate.IsVAT || duplicate.ShippingCost != 100 {
+		t.Errorf("duplicate = %+v, want the original's supplier, VAT and shipping", duplicate)
+	}
+	if duplicate.Payment.IsPaid || duplicate.Payment.PaymentDate != nil || duplicate.Warehouse.IsUpdated || !duplicate.IsDraft {
+		t.Errorf("duplicate = %+v, want an unpaid draft not yet received", duplicate)
+	}
+	if !duplicate.StockOnReceipt {
+		t.Error("StockOnReceipt = false for a copy of a purchase saved before receipts added stock, want true")
+	}
+	if item := duplicate.Items[0]; item.SerialNumbers != nil || item.LotNumber != "" || item.Quantity != 2 {
+		t.Errorf("item = %+v, want the quantity without serial numbers or lot", item)
+	}
+	if want := 40000 * (1 + VATRate); duplicate.GrandTotal != want {
+		t.Errorf("GrandTotal = %v, want %v at the current VAT rate", duplicate.GrandTotal, want)
+	}
+}
+
+func TestPurchaseRequestToPurchaseAddsStockOnReceipt(t *testing.T) {
+	request := &PurchaseRequest{Items: []PurchaseItem{{ProductID: "p1", Quantity: 10, UnitPrice: 5}}}
+	if purchase := request.ToPurchase(); !purchase.StockOnReceipt {
+		t.Error("StockOnReceipt = false, want new purchases to add stock through their receipts")
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "strings"
+
+// QRDataFormat is the content of new product QR codes, with {skuId} replaced by the product's SKU ID; it is set
+// from config.QRDataFormat at startup. Empty means the bare SKU ID.
+var QRDataFormat = ""
+
+// ProductQRData is the QR code content of a product with skuID
+func ProductQRData(skuID string) string {
+	if QRDataFormat == "" {
+		return skuID
+	}
+	return strings.ReplaceAll(QRDataFormat, "{skuId}", skuID)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "testing"
+
+func TestProductQRData(t *testing.T) {
+	defer func(format string) { QRDataFormat = format }(QRDataFormat)
+
+	QRDataFormat = ""
+	if got := ProductQRData("BOX-0001"); got != "BOX-0001" {
+		t.Errorf("ProductQRData() = %q, want the bare SKU ID", got)
+	}
+
+	QRDataFormat = "https://goodpack.app/product/{skuId}"
+	if got, want := ProductQRData("CP-SCR-0012"), "https://goodpack.app/product/CP-SCR-0012"; got != want {
+		t.Errorf("ProductQRData() = %q, want %q", got, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -40,14 +41,26 @@ func (ct CustomTime) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ct.Time.Format(time.RFC3339))
 }
 
+// Quotation statuses
+const (
+	QuotationStatusDraft    = "draft"
+	QuotationStatusSent     = "sent"
+	QuotationStatusAccepted = "accepted"
+	QuotationStatusRejected = "rejected"
+	QuotationStatusExpired  = "expired"
+)
+
 // QuotationItem represents an item in a quotation
 type QuotationItem struct {
-	ProductID   string  `bson:"productId" json:"productId"`     // รหัสสินค้า
-	ProductName string  `bson:"productName" json:"productName"` // ชื่อสินค้า
-	ProductCode string  `bson:"productCode" json:"productCode"` // รหัสสินค้า
-	Quantity    int     `bson:"quantity" json:"quantity"`       // จำนวน
-	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice"`     // ราคาต่อหน่วย
-	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`   // ราคารวม
+	ProductID   string  `bson:"productId" json:"productId" validate:"required"`     // รหัสสินค้า
+	ProductName string  `bson:"productName" json:"productName"`                     // ชื่อสินค้า
+	ProductCode string  `bson:"productCode" json:"productCode"`                     // รหัสสินค้า
+	Quantity    int     `bson:"quantity" json:"quantity" validate:"required,min=1"` // จำนวน
+	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice" validate:"min=0"`        // ราคาต่อหน่วย
+	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`                       // ราคารวม
+
+	DiscountPercent float64 `bson:"discountPercent" json:"discountPercent" validate:"min=0,max=100"` // ส่วนลด (%)
+	DiscountAmount  float64 `bson:"discountAmount" json:"discountAmount" validate:"min=0"`           // ส่วนลด (บาท)
 }
 
 // Quotation represents a quotation document
@@ -64,29 +77,50 @@ type Quotation struct {
 	Phone             *string            `bson:"phone,omitempty" json:"phone,omitempty"`                         // เบอร์โทรศัพท์
 	Items             []QuotationItem    `bson:"items" json:"items"`                                             // รายการสินค้า
 	IsVAT             bool               `bson:"isVAT" json:"isVAT"`                                             // มี VAT หรือไม่
+	VATRate           float64            `bson:"vatRate,omitempty" json:"vatRate"`                               // อัตรา VAT ณ วันที่สร้าง (ไม่มี = 7%)
 	ShippingCost      float64            `bson:"shippingCost" json:"shippingCost"`                               // ค่าขนส่ง
 	Notes             *string            `bson:"notes,omitempty" json:"notes,omitempty"`                         // หมายเหตุ
 	ValidUntil        *time.Time         `bson:"validUntil,omitempty" json:"validUntil,omitempty"`               // ราคาใช้ได้ถึง
 	Status            string             `bson:"status" json:"status"`                                           // สถานะ (draft, sent, accepted, rejected, expired)
 	SaleCode          *string            `bson:"saleCode,omitempty" json:"saleCode,omitempty"`                   // รหัสรายการขายที่สร้างจาก quotation นี้
+	AcceptedAt        *time.Time         `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`               // วันที่ตอบรับ
 	BankAccountID     *string            `bson:"bankAccountId,omitempty" json:"bankAccountId,omitempty"`         // รหัสบัญชีธนาคาร
 	BankName          *string            `bson:"bankName,omitempty" json:"bankName,omitempty"`                   // ชื่อธนาคาร
 	BankAccountName   *string            `bson:"bankAccountName,omitempty" json:"bankAccountName,omitempty"`     // ชื่อบัญชี
 	BankAccountNumber *string            `bson:"bankAccountNumber,omitempty" json:"bankAccountNumber,omitempty"` // เลขบัญชี
+	Versions          []QuotationVersion `bson:"versions,omitempty" json:"-"`                                    // ประวัติการแก้ไข (ดูผ่าน /versions)
 	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version           int                `bson:"version" json:"version"`
+}
+
+// QuotationVersion is a snapshot of a quotation taken just before it was updated
+type QuotationVersion struct {
+	VersionNumber int       `bson:"versionNumber" json:"versionNumber"`             // 1 = ก่อนการแก้ไขครั้งแรก
+	Snapshot      bson.Raw  `bson:"snapshot" json:"-"`                              // เอกสารก่อนแก้ไข (ไม่รวม versions)
+	ChangedAt     time.Time `bson:"changedAt" json:"changedAt"`                     // วันที่แก้ไข
+	ChangedBy     *string   `bson:"changedBy,omitempty" json:"changedBy,omitempty"` // ผู้แก้ไข (X-User-ID)
+}
+
+// Quotation decodes the snapshot back into the quotation as it was at this version
+func (v *QuotationVersion) Quotation() (*Quotation, error) {
+	var quotation Quotation
+	if err := bson.Unmarshal(v.Snapshot, &quotation); err != nil {
+		return nil, err
+	}
+	return &quotation, nil
 }
 
 // QuotationRequest represents the request body for creating/updating a quotation
 type QuotationRequest struct {
 	QuotationDate     CustomTime      `json:"quotationDate"`
-	CustomerID        string          `json:"customerId"`
-	Items             []QuotationItem `json:"items"`
+	CustomerID        string          `json:"customerId" validate:"required"`
+	Items             []QuotationItem `json:"items" validate:"required,min=1,dive"`
 	IsVAT             bool            `json:"isVAT"`
-	ShippingCost      float64         `json:"shippingCost"`
+	ShippingCost      float64         `json:"shippingCost" validate:"min=0"`
 	Notes             *string         `json:"notes,omitempty"`
 	ValidUntil        *CustomTime     `json:"validUntil,omitempty"`
-	Status            string          `json:"status"`
+	Status            string          `json:"status" validate:"omitempty,oneof=draft sent accepted rejected expired"`
 	BankAccountID     *string         `json:"bankAccountId,omitempty"`
 	BankName          *string         `json:"bankName,omitempty"`
 	BankAccountName   *string         `json:"bankAccountName,omitempty"`
@@ -96,14 +130,15 @@ type QuotationRequest struct {
 // ToQuotation converts QuotationRequest to Quotation
 func (qr *QuotationRequest) ToQuotation() *Quotation {
 	now := time.Now()
+	calculateQuotationItems(qr.Items)
 	quotation := &Quotation{
 		QuotationDate:     qr.QuotationDate.Time,
 		CustomerID:        qr.CustomerID,
 		Items:             qr.Items,
 		IsVAT:             qr.IsVAT,
+		VATRate:           VATRate,
 		ShippingCost:      qr.ShippingCost,
 		Notes:             qr.Notes,
-		Status:            qr.Status,
 		BankAccountID:     qr.BankAccountID,
 		BankName:          qr.BankName,
 		BankAccountName:   qr.BankAccountName,
@@ -112,6 +147,7 @@ func (qr *QuotationRequest) ToQuotation() *Quotation {
 		UpdatedAt:         now,
 	}
 
+	quotation.SetStatus(qr.Status)
 	if qr.ValidUntil != nil {
 		quotation.ValidUntil = &qr.ValidUntil.Time
 	}
@@ -123,6 +159,7 @@ func (qr *QuotationRequest) ToQuotation() *Quotation {
 func (q *Quotation) UpdateFromRequest(qr *QuotationRequest) {
 	q.QuotationDate = qr.QuotationDate.Time
 	q.CustomerID = qr.CustomerID
+	calculateQuotationItems(qr.Items)
 	q.Items = qr.Items
 	q.IsVAT = qr.IsVAT
 	q.ShippingCost = qr.ShippingCost
@@ -132,7 +169,7 @@ func (q *Quotation) UpdateFromRequest(qr *QuotationRequest) {
 	} else {
 		q.ValidUntil = nil
 	}
-	q.Status = qr.Status
+	q.SetStatus(qr.Status)
 	q.BankAccountID = qr.BankAccountID
 	q.BankName = qr.BankName
 	q.BankAccountName = qr.BankAccountName
@@ -140,6 +177,17 @@ func (q *Quotation) UpdateFromRequest(qr *QuotationRequest) {
 	q.UpdatedAt = time.Now()
 }
 
+// SetStatus changes the quotation's status, recording when it was accepted
+func (q *Quotation) SetStatus(status string) {
+	if status != QuotationStatusAccepted {
+		q.AcceptedAt = nil
+	} else if q.Status != QuotationStatusAccepted || q.AcceptedAt == nil {
+		now := time.Now()
+		q.AcceptedAt = &now
+	}
+	q.Status = status
+}
+
 // GenerateQuotationCode generates a new quotation code in format QU-YYMM-XXXX
 func GenerateQuotationCode(lastCode string) (string, error) {
 	now := time.Now()
@@ -163,17 +211,14 @@ func GenerateQuotationCode(lastCode string) (string, error) {
 	return fmt.Sprintf("%s%04d", prefix, newSeq), nil
 }
 
-// CalculateGrandTotal calculates the grand total including VAT and shipping
+// CalculateGrandTotal calculates the grand total including VAT at the quotation's rate and shipping
 func (q *Quotation) CalculateGrandTotal() float64 {
 	totalBeforeVAT := 0.0
 	for _, item := range q.Items {
 		totalBeforeVAT += item.TotalPrice
 	}
 
-	totalVAT := 0.0
-	if q.IsVAT {
-		totalVAT = totalBeforeVAT * 0.07
-	}
+	totalVAT := calculateVAT(totalBeforeVAT, q.IsVAT, q.EffectiveVATRate())
 
 	return totalBeforeVAT + totalVAT + q.ShippingCost
 }
@@ -187,9 +232,12 @@ func (q *Quotation) ToSaleRequest() *SaleRequest {
 			ProductID:   item.ProductID,
 			ProductName: item.ProductName,
 			ProductCode: item.ProductCode,
-			Quantity:    item.Quantity,
+			Quantity:    float64(item.Quantity),
 			UnitPrice:   item.UnitPrice,
 			TotalPrice:  item.TotalPrice,
+
+			DiscountPercent: item.DiscountPercent,
+			DiscountAmount:  item.DiscountAmount,
 		}
 	}
 
@@ -215,3 +263,11 @@ func (q *Quotation) ToSaleRequest() *SaleRequest {
 		BankAccountNumber: q.BankAccountNumber,
 	}
 }
+
+// calculateQuotationItems applies line discounts to the items' total prices
+func calculateQuotationItems(items []QuotationItem) {
+	for i := range items {
+		item := &items[i]
+		item.TotalPrice = CalculateLineTotal(float64(item.Quantity), item.UnitPrice, item.DiscountPercent, item.DiscountAmount)
+	}
+}
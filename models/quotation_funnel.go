@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// QuotationFunnelStats counts quotations by status. Values are line totals after discounts, excluding VAT
+// and shipping, like the other reports.
+type QuotationFunnelStats struct {
+	Total    int64 `bson:"total" json:"total"`
+	Draft    int64 `bson:"draft" json:"draft"`
+	Sent     int64 `bson:"sent" json:"sent"`
+	Accepted int64 `bson:"accepted" json:"accepted"`
+	Rejected int64 `bson:"rejected" json:"rejected"`
+	Expired  int64 `bson:"expired" json:"expired"`
+
+	AcceptanceRatePct   *float64 `bson:"-" json:"acceptanceRatePct"`                     // accepted / (accepted + rejected) × 100, null ถ้ายังไม่มีใบที่ตอบรับหรือปฏิเสธ
+	AvgDaysToAcceptance *float64 `bson:"avgDaysToAcceptance" json:"avgDaysToAcceptance"` // วันเฉลี่ยจากสร้างจนถึงตอบรับ
+	AvgQuotationValue   float64  `bson:"avgQuotationValue" json:"avgQuotationValue"`
+	ConvertedRevenue    float64  `bson:"convertedRevenue" json:"convertedRevenue"` // ยอดขายของรายการขายที่สร้างจากใบเสนอราคา
+}
+
+// CalculateAcceptanceRate sets AcceptanceRatePct from the accepted and rejected counts
+func (s *QuotationFunnelStats) CalculateAcceptanceRate() {
+	s.AcceptanceRatePct = nil
+	if decided := s.Accepted + s.Rejected; decided > 0 {
+		rate := float64(s.Accepted) / float64(decided) * 100
+		s.AcceptanceRatePct = &rate
+	}
+}
+
+// QuotationFunnel is the quotation funnel of the quotations dated in a period (no dates = all quotations)
+type QuotationFunnel struct {
+	StartDate *time.Time `json:"startDate,omitempty"`
+	EndDate   *time.Time `json:"endDate,omitempty"`
+	QuotationFunnelStats
+}
+
+// CustomerQuotationFunnel is the quotation funnel of one customer
+type CustomerQuotationFunnel struct {
+	CustomerID           string `bson:"_id" json:"customerId"`
+	CustomerName         string `bson:"customerName" json:"customerName"`
+	QuotationFunnelStats `bson:",inline"`
+}
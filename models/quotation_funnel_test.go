@@ -0,0 +1,28 @@
+package models
+
+import "testing"
+
+func TestCalculateAcceptanceRate(t *testing.T) {
+	rate := func(pct float64) *float64 { return &pct }
+	tests := []struct {
+		name               string
+		accepted, rejected int64
+		want               *float64
+	}{
+		{"none decided", 0, 0, nil},
+		{"all accepted", 3, 0, rate(100)},
+		{"all rejected", 0, 2, rate(0)},
+		{"one in four", 1, 3, rate(25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Draft, sent and expired quotations are undecided and do not count
+			stats := &QuotationFunnelStats{Draft: 5, Sent: 4, Expired: 2, Accepted: tt.accepted, Rejected: tt.rejected}
+			stats.CalculateAcceptanceRate()
+			if (stats.AcceptanceRatePct == nil) != (tt.want == nil) ||
+				stats.AcceptanceRatePct != nil && *stats.AcceptanceRatePct != *tt.want {
+				t.Errorf("AcceptanceRatePct = %v, want %v", stats.AcceptanceRatePct, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestQuotationToSaleRequest(t *testing.T) {
+	quotation := &Quotation{
+		QuotationCode: "QU-6901-0007",
+		CustomerID:    "customer-1",
+		IsVAT:         true,
+		ShippingCost:  50,
+		Items: []QuotationItem{
+			{ProductID: "p1", Quantity: 3, UnitPrice: 100, TotalPrice: 300},
+			{ProductID: "p2", Quantity: 2, UnitPrice: 40, TotalPrice: 72, DiscountPercent: 10},
+		},
+	}
+
+	sale := quotation.ToSaleRequest().ToSale()
+
+	if sale.QuotationCode == nil || *sale.QuotationCode != quotation.QuotationCode {
+		t.Errorf("QuotationCode = %v, want %s", sale.QuotationCode, quotation.QuotationCode)
+	}
+	if sale.CustomerID != "customer-1" || !sale.IsVAT || sale.ShippingCost != 50 {
+		t.Errorf("sale = %+v, want the quotation's customer, VAT and shipping", sale)
+	}
+	if sale.Payment.IsPaid {
+		t.Error("sale from a quotation is paid, want unpaid")
+	}
+	if len(sale.Items) != 2 {
+		t.Fatalf("len(Items) = %d, want 2", len(sale.Items))
+	}
+	for i, want := range []int{3, 2} {
+		if got := sale.Items[i].StockQuantity(); got != want {
+			t.Errorf("Items[%d].StockQuantity() = %d, want %d", i, got, want)
+		}
+	}
+	if sale.Items[1].TotalPrice != 72 {
+		t.Errorf("Items[1].TotalPrice = %v, want 72 after the 10%% discount", sale.Items[1].TotalPrice)
+	}
+}
+
+func TestQuotationSetStatusAccepted(t *testing.T) {
+	quotation := &Quotation{Status: QuotationStatusSent}
+
+	quotation.SetStatus(QuotationStatusAccepted)
+	if quotation.AcceptedAt == nil {
+		t.Fatal("AcceptedAt not set on acceptance")
+	}
+	acceptedAt := *quotation.AcceptedAt
+
+	// Accepting again, as a retried transaction does, keeps the first acceptance time
+	quotation.SetStatus(QuotationStatusAccepted)
+	if !quotation.AcceptedAt.Equal(acceptedAt) {
+		t.Errorf("AcceptedAt moved from %v to %v", acceptedAt, *quotation.AcceptedAt)
+	}
+
+	quotation.SetStatus(QuotationStatusRejected)
+	if quotation.AcceptedAt != nil {
+		t.Errorf("AcceptedAt = %v after rejection, want nil", *quotation.AcceptedAt)
+	}
+}
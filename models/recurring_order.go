@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurringOrder is a sale made for a customer every IntervalDays, e.g. a monthly standing order
+type RecurringOrder struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CustomerID   string             `bson:"customerId" json:"customerId"`                   // รหัสลูกค้า
+	Items        []SaleItem         `bson:"items" json:"items"`                             // รายการสินค้าของแต่ละรอบ
+	IsVAT        bool               `bson:"isVAT" json:"isVAT"`                             // มี VAT หรือไม่
+	ShippingCost float64            `bson:"shippingCost" json:"shippingCost"`               // ค่าขนส่ง
+	Notes        *string            `bson:"notes,omitempty" json:"notes,omitempty"`         // หมายเหตุ (ใส่ในรายการขาย)
+	IntervalDays int                `bson:"intervalDays" json:"intervalDays"`               // ทุกกี่วัน
+	NextRunAt    time.Time          `bson:"nextRunAt" json:"nextRunAt"`                     // สร้างรายการขายครั้งถัดไป
+	IsActive     bool               `bson:"isActive" json:"isActive"`                       // false = หยุดชั่วคราว
+	LastRunAt    *time.Time         `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"` // ครั้งล่าสุดที่สร้างรายการขาย
+	LastSaleCode *string            `bson:"lastSaleCode,omitempty" json:"lastSaleCode,omitempty"`
+	LastError    *string            `bson:"lastError,omitempty" json:"lastError,omitempty"` // เหตุผลที่รอบล่าสุดสร้างรายการขายไม่ได้ เช่น เกินวงเงิน
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted    bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt    *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type RecurringOrderRequest struct {
+	CustomerID   string     `json:"customerId" validate:"required"`
+	Items        []SaleItem `json:"items" validate:"required,min=1,dive"`
+	IsVAT        bool       `json:"isVAT"`
+	ShippingCost float64    `json:"shippingCost" validate:"min=0"`
+	Notes        *string    `json:"notes,omitempty" validate:"omitempty,max=1000"`
+	IntervalDays int        `json:"intervalDays" validate:"required,min=1,max=366"`
+	NextRunAt    CustomTime `json:"nextRunAt"`          // วันที่สร้างรายการขายครั้งแรก
+	IsActive     *bool      `json:"isActive,omitempty"` // default true
+}
+
+func (rr *RecurringOrderRequest) ToRecurringOrder() *RecurringOrder {
+	now := time.Now()
+	order := &RecurringOrder{
+		CreatedAt: now,
+		IsActive:  true,
+	}
+	order.UpdateFromRequest(rr)
+	return order
+}
+
+func (o *RecurringOrder) UpdateFromRequest(rr *RecurringOrderRequest) {
+	o.CustomerID = rr.CustomerID
+	o.Items = rr.Items
+	o.IsVAT = rr.IsVAT
+	o.ShippingCost = rr.ShippingCost
+	o.Notes = rr.Notes
+	o.IntervalDays = rr.IntervalDays
+	o.NextRunAt = rr.NextRunAt.Time
+	if rr.IsActive != nil {
+		o.IsActive = *rr.IsActive
+	}
+	o.UpdatedAt = time.Now()
+}
+
+// ToSaleRequest builds the unpaid sale of one run, dated saleDate
+func (o *RecurringOrder) ToSaleRequest(saleDate time.Time) *SaleRequest {
+	items := make([]SaleItem, len(o.Items))
+	copy(items, o.Items)
+
+	return &SaleRequest{
+		SaleDate:     saleDate,
+		CustomerID:   o.CustomerID,
+		Items:        items,
+		IsVAT:        o.IsVAT,
+		ShippingCost: o.ShippingCost,
+		Payment:      PaymentInfo{IsPaid: false},
+		Warehouse: WarehouseInfo{
+			IsUpdated:      false,
+			ActualShipping: o.ShippingCost,
+			Items:          []WarehouseItem{},
+		},
+		Notes: o.Notes,
+	}
+}
+
+// Advance records a successful run that created saleCode and schedules the next one IntervalDays later
+func (o *RecurringOrder) Advance(runAt time.Time, saleCode string) {
+	o.LastRunAt = &runAt
+	o.LastSaleCode = &saleCode
+	o.LastError = nil
+	o.NextRunAt = o.NextRunAt.AddDate(0, 0, o.IntervalDays)
+	o.UpdatedAt = runAt
+}
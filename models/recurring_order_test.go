@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurringOrderAdvance(t *testing.T) {
+	due := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	lastError := "customer over credit limit"
+	order := &RecurringOrder{IntervalDays: 30, NextRunAt: due, LastError: &lastError}
+
+	// A run late in the day still schedules the next one from the due date, not from when it ran
+	runAt := due.Add(15 * time.Hour)
+	order.Advance(runAt, "SV-6701-0001")
+
+	if want := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC); !order.NextRunAt.Equal(want) {
+		t.Errorf("NextRunAt = %v, want %v", order.NextRunAt, want)
+	}
+	if order.LastRunAt == nil || !order.LastRunAt.Equal(runAt) {
+		t.Errorf("LastRunAt = %v, want %v", order.LastRunAt, runAt)
+	}
+	if order.LastSaleCode == nil || *order.LastSaleCode != "SV-6701-0001" {
+		t.Errorf("LastSaleCode = %v, want SV-6701-0001", order.LastSaleCode)
+	}
+	if order.LastError != nil {
+		t.Errorf("LastError = %q, want it cleared after a successful run", *order.LastError)
+	}
+}
+
+func TestRecurringOrderToSaleRequest(t *testing.T) {
+	notes := "monthly standing order"
+	order := &RecurringOrder{
+		CustomerID:   "c1",
+		Items:        []SaleItem{{ProductID: "p1", Quantity: 10, UnitPrice: 20, TotalPrice: 200}},
+		IsVAT:        true,
+		ShippingCost: 50,
+		Notes:        &notes,
+	}
+	saleDate := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+
+	req := order.ToSaleRequest(saleDate)
+	if !req.SaleDate.Equal(saleDate) || req.CustomerID != "c1" || !req.IsVAT || req.ShippingCost != 50 || req.Notes != &notes {
+		t.Errorf("request = %+v, want the order's customer, VAT, shipping and notes dated %v", req, saleDate)
+	}
+	if req.Payment.IsPaid {
+		t.Error("sale is paid, want unpaid")
+	}
+
+	// The sale gets its own items, so filling them in does not change the order
+	req.Items[0].Quantity = 99
+	if order.Items[0].Quantity != 10 {
+		t.Errorf("order quantity = %v after editing the sale, want 10", order.Items[0].Quantity)
+	}
+}
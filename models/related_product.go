@@ -0,0 +1,20 @@
+package models
+
+// RelatedProducts are the suggestions shown next to a product: other products of its category and, as secondary
+// suggestions, products of other categories with the same color or size, each closest VAT sale price first
+type RelatedProducts struct {
+	SameCategory    []*Product `json:"sameCategory"`
+	SameColorOrSize []*Product `json:"sameColorOrSize"`
+}
+
+// AlsoBoughtProduct is a product sold together with another one: SaleCount is the number of sales that included
+// both, Quantity the units of this product on those sales
+type AlsoBoughtProduct struct {
+	ProductID string `bson:"_id" json:"productId"`
+	SKUID     string `bson:"skuId" json:"skuId"`
+	Code      string `bson:"code" json:"code"`
+	Name      string `bson:"name" json:"name"`
+	Category  string `bson:"category" json:"category"`
+	SaleCount int    `bson:"saleCount" json:"saleCount"`
+	Quantity  int    `bson:"quantity" json:"quantity"`
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sale return statuses
+const (
+	ReturnStatusCompleted = "completed" // รับคืนสินค้าและคืนสต็อกแล้ว
+)
+
+// ReturnItem represents a returned line of a sale
+type ReturnItem struct {
+	ProductID   string  `bson:"productId" json:"productId"`     // รหัสสินค้า
+	ProductName string  `bson:"productName" json:"productName"` // ชื่อสินค้า
+	ProductCode string  `bson:"productCode" json:"productCode"` // รหัสสินค้า
+	Quantity    int     `bson:"quantity" json:"quantity"`       // จำนวนที่คืน
+	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice"`     // ราคาต่อหน่วยหลังหักส่วนลด
+	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`   // ราคารวม
+	Reason      string  `bson:"reason" json:"reason"`           // เหตุผลที่คืน
+
+	SerialNumbers []string `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"` // หมายเลขซีเรียลที่คืน
+}
+
+// SaleReturn represents goods returned by a customer against a sale (credit note)
+type SaleReturn struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReturnCode       string             `bson:"returnCode" json:"returnCode"`             // RT-YYMM-XXXX
+	OriginalSaleID   string             `bson:"originalSaleId" json:"originalSaleId"`     // รหัสรายการขายเดิม
+	OriginalSaleCode string             `bson:"originalSaleCode" json:"originalSaleCode"` // เลขที่รายการขายเดิม
+	CustomerID       string             `bson:"customerId" json:"customerId"`             // รหัสลูกค้า
+	ReturnDate       time.Time          `bson:"returnDate" json:"returnDate"`             // วันที่รับคืน
+	Items            []ReturnItem       `bson:"items" json:"items"`                       // รายการสินค้าที่คืน
+	IsVAT            bool               `bson:"isVAT" json:"isVAT"`                       // มี VAT หรือไม่ (ตามรายการขายเดิม)
+	RefundAmount     float64            `bson:"refundAmount" json:"refundAmount"`         // ยอดคืนเงินรวม VAT
+	Status           string             `bson:"status" json:"status"`                     // สถานะ
+	Notes            *string            `bson:"notes,omitempty" json:"notes,omitempty"`   // หมายเหตุ
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ReturnItemRequest is one returned line in a SaleReturnRequest
+type ReturnItemRequest struct {
+	ProductID     string   `json:"productId"`
+	Quantity      int      `json:"quantity"`
+	Reason        string   `json:"reason"`
+	SerialNumbers []string `json:"serialNumbers,omitempty"` // required for serialised products, one per unit
+}
+
+// SaleReturnRequest represents the request body for returning items from a sale
+type SaleReturnRequest struct {
+	ReturnDate *CustomTime         `json:"returnDate,omitempty"`
+	Items      []ReturnItemRequest `json:"items"`
+	Notes      *string             `json:"notes,omitempty"`
+}
+
+// ToSaleReturn builds a return against the given sale, pricing each line at the product's average discounted
+// unit price on the sale: a product sold on several lines (e.g. in a bundle and on its own) is refunded at the
+// total of those lines over their quantity
+func (rr *SaleReturnRequest) ToSaleReturn(sale *Sale) *SaleReturn {
+	now := time.Now()
+
+	saleItems := make(map[string]SaleItem)
+	for _, item := range sale.Items {
+		if line, ok := saleItems[item.ProductID]; ok {
+			line.Quantity += item.Quantity
+			line.TotalPrice += item.TotalPrice
+			saleItems[item.ProductID] = line
+		} else {
+			saleItems[item.ProductID] = item
+		}
+	}
+
+	items := make([]ReturnItem, len(rr.Items))
+	totalBeforeVAT := 0.0
+	for i, req := range rr.Items {
+		saleItem := saleItems[req.ProductID]
+		unitPrice := 0.0
+		if saleItem.Quantity > 0 {
+			unitPrice = saleItem.TotalPrice / saleItem.Quantity
+		}
+		items[i] = ReturnItem{
+			ProductID:   req.ProductID,
+			ProductName: saleItem.ProductName,
+			ProductCode: saleItem.ProductCode,
+			Quantity:    req.Quantity,
+			UnitPrice:   roundMoney(unitPrice),
+			TotalPrice:  roundMoney(unitPrice * float64(req.Quantity)),
+			Reason:      req.Reason,
+
+			SerialNumbers: req.SerialNumbers,
+		}
+		totalBeforeVAT += items[i].TotalPrice
+	}
+
+	refund := totalBeforeVAT + calculateVAT(totalBeforeVAT, sale.IsVAT, sale.EffectiveVATRate())
+
+	saleReturn := &SaleReturn{
+		OriginalSaleID:   sale.ID.Hex(),
+		OriginalSaleCode: sale.SaleCode,
+		CustomerID:       sale.CustomerID,
+		ReturnDate:       now,
+		Items:            items,
+		IsVAT:            sale.IsVAT,
+		RefundAmount:     roundMoney(refund),
+		Status:           ReturnStatusCompleted,
+		Notes:            rr.Notes,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if rr.ReturnDate != nil {
+		saleReturn.ReturnDate = rr.ReturnDate.Time
+	}
+
+	return saleReturn
+}
+
+// ReturnedQuantities totals the quantity of each product returned, in base units
+func ReturnedQuantities(returns []*SaleReturn) map[string]int {
+	returned := make(map[string]int)
+	for _, saleReturn := range returns {
+		for _, item := range saleReturn.Items {
+			returned[item.ProductID] += item.Quantity
+		}
+	}
+	return returned
+}
+
+// UnreturnedQuantities returns the stock quantity each item of the sale still holds once the returned
+// quantities (see ReturnedQuantities) are taken off it, from the first line of a product on
+func (s *Sale) UnreturnedQuantities(returned map[string]int) []int {
+	left := make(map[string]int, len(returned))
+	for productID, quantity := range returned {
+		left[productID] = quantity
+	}
+
+	quantities := make([]int, len(s.Items))
+	for i, item := range s.Items {
+		quantity := item.StockQuantity()
+		taken := min(quantity, left[item.ProductID])
+		left[item.ProductID] -= taken
+		quantities[i] = quantity - taken
+	}
+	return quantities
+}
+
+// GenerateReturnCode generates a new return code in format RT-YYMM-XXXX
+func GenerateReturnCode(lastCode string) (string, error) {
+	now := time.Now()
+	buddhistYear := now.Year() + 543 // Convert to Buddhist year
+	month := int(now.Month())
+
+	prefix := fmt.Sprintf("RT-%02d%02d-", buddhistYear%100, month) // YYMM
+
+	if lastCode == "" {
+		return prefix + "0001", nil
+	}
+
+	var lastYear, lastMonth, lastSeq int
+	_, err := fmt.Sscanf(lastCode, "RT-%02d%02d-%04d", &lastYear, &lastMonth, &lastSeq)
+	if err != nil {
+		return "", fmt.Errorf("invalid last return code format: %w", err)
+	}
+
+	// Restart the sequence every month
+	if lastYear != buddhistYear%100 || lastMonth != month {
+		return prefix + "0001", nil
+	}
+
+	return fmt.Sprintf("%s%04d", prefix, lastSeq+1), nil
+}
+
+// roundMoney rounds an amount to 2 decimal places
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ProductReturnStats is how much of a product was returned in a period against how much was sold
+type ProductReturnStats struct {
+	ProductID        string   `bson:"_id" json:"productId"`
+	ProductName      string   `bson:"productName" json:"productName"`
+	ProductCode      string   `bson:"productCode" json:"productCode"`
+	Returns          int64    `bson:"returns" json:"returns"` // จำนวนรายการคืน
+	ReturnedQuantity int      `bson:"returnedQuantity" json:"returnedQuantity"`
+	ReturnValue      float64  `bson:"returnValue" json:"returnValue"`   // มูลค่าที่คืน ไม่รวม VAT
+	QuantitySold     float64  `bson:"quantitySold" json:"quantitySold"` // จำนวนที่ขายในช่วงเวลาเดียวกัน
+	ReturnRatePct    *float64 `bson:"-" json:"returnRatePct"`           // returnedQuantity / quantitySold × 100, null ถ้าไม่มีการขาย
+}
+
+// ReturnReasonCount is how often a reason was given for returned lines
+type ReturnReasonCount struct {
+	Reason   string  `bson:"_id" json:"reason"`
+	Count    int64   `bson:"count" json:"count"` // จำนวนรายการสินค้าที่คืนด้วยเหตุผลนี้
+	Quantity int     `bson:"quantity" json:"quantity"`
+	SharePct float64 `bson:"-" json:"sharePct"` // count / จำนวนรายการทั้งหมด × 100
+}
+
+// ReturnTrendPoint is the returns of one month
+type ReturnTrendPoint struct {
+	Period   string  `bson:"_id" json:"period"` // 2024-01
+	Returns  int64   `bson:"returns" json:"returns"`
+	Quantity int     `bson:"quantity" json:"quantity"`
+	Value    float64 `bson:"value" json:"value"`
+}
+
+// ReturnValueTotals compares the value returned with the value of the sales it was returned from
+type ReturnValueTotals struct {
+	Returns           int64   `bson:"returns" json:"returns"`
+	ReturnValue       float64 `bson:"returnValue" json:"returnValue"`
+	OriginalSaleValue float64 `bson:"originalSaleValue" json:"originalSaleValue"` // มูลค่ารายการขายเดิม (นับแต่ละรายการขายครั้งเดียว)
+	ReturnValuePct    float64 `bson:"-" json:"returnValuePct"`                    // returnValue / originalSaleValue × 100
+}
+
+// ReturnAnalysis is the sale returns of a period. Values are line totals after discounts, excluding VAT.
+type ReturnAnalysis struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	ReturnValueTotals
+	Products     []ProductReturnStats `json:"products"` // อัตราการคืนสูงสุดก่อน
+	Reasons      []ReturnReasonCount  `json:"reasons"`  // เหตุผลที่พบบ่อยที่สุดก่อน
+	MonthlyTrend []ReturnTrendPoint   `json:"monthlyTrend"`
+}
+
+// NewReturnAnalysis calculates the rates and shares of the aggregated returns, sorts the products by return
+// rate (then returned quantity) and the reasons by frequency, and fills months without returns with zeros
+func NewReturnAnalysis(from, to time.Time, totals ReturnValueTotals, products []ProductReturnStats, reasons []ReturnReasonCount, months []ReturnTrendPoint) *ReturnAnalysis {
+	if totals.OriginalSaleValue > 0 {
+		totals.ReturnValuePct = totals.ReturnValue / totals.OriginalSaleValue * 100
+	}
+
+	if products == nil {
+		products = []ProductReturnStats{}
+	}
+	for i := range products {
+		if products[i].QuantitySold > 0 {
+			rate := float64(products[i].ReturnedQuantity) / products[i].QuantitySold * 100
+			products[i].ReturnRatePct = &rate
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := products[i].ReturnRatePct, products[j].ReturnRatePct
+		if (a == nil) != (b == nil) {
+			return a != nil // products with a rate before those sold outside the period
+		}
+		if a != nil && *a != *b {
+			return *a > *b
+		}
+		return products[i].ReturnedQuantity > products[j].ReturnedQuantity
+	})
+
+	if reasons == nil {
+		reasons = []ReturnReasonCount{}
+	}
+	var lines int64
+	for _, reason := range reasons {
+		lines += reason.Count
+	}
+	for i := range reasons {
+		if lines > 0 {
+			reasons[i].SharePct = float64(reasons[i].Count) / float64(lines) * 100
+		}
+	}
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasons[i].Count > reasons[j].Count
+	})
+
+	byMonth := make(map[string]ReturnTrendPoint, len(months))
+	for _, month := range months {
+		byMonth[month.Period] = month
+	}
+	trend := []ReturnTrendPoint{}
+	for _, period := range TrendPeriods(TrendMonthly, from, to) {
+		point := byMonth[period]
+		point.Period = period
+		trend = append(trend, point)
+	}
+
+	return &ReturnAnalysis{
+		StartDate:         from,
+		EndDate:           to,
+		ReturnValueTotals: totals,
+		Products:          products,
+		Reasons:           reasons,
+		MonthlyTrend:      trend,
+	}
+}
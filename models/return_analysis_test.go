@@ -0,0 +1,78 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewReturnAnalysis(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC)
+	totals := ReturnValueTotals{Returns: 4, ReturnValue: 250, OriginalSaleValue: 1000}
+	products := []ProductReturnStats{
+		{ProductID: "cup", ReturnedQuantity: 5, QuantitySold: 50},
+		{ProductID: "lid", ReturnedQuantity: 3}, // not sold in the period
+		{ProductID: "box", ReturnedQuantity: 5, QuantitySold: 20},
+		{ProductID: "wrap", ReturnedQuantity: 2, QuantitySold: 20},
+		{ProductID: "bag", ReturnedQuantity: 1, QuantitySold: 10},
+	}
+	reasons := []ReturnReasonCount{
+		{Reason: "wrong size", Count: 1, Quantity: 2},
+		{Reason: "damaged", Count: 3, Quantity: 9},
+	}
+	months := []ReturnTrendPoint{
+		{Period: "2024-03", Returns: 1, Quantity: 2, Value: 50},
+		{Period: "2024-01", Returns: 3, Quantity: 9, Value: 200},
+	}
+
+	analysis := NewReturnAnalysis(from, to, totals, products, reasons, months)
+
+	if analysis.ReturnValuePct != 25 {
+		t.Errorf("ReturnValuePct = %v, want 25", analysis.ReturnValuePct)
+	}
+
+	// By rate, equal rates by returned quantity, products without sales last
+	wantOrder := []string{"box", "cup", "wrap", "bag", "lid"}
+	wantRates := []float64{25, 10, 10, 10}
+	for i, product := range analysis.Products {
+		if product.ProductID != wantOrder[i] {
+			t.Errorf("product %d = %s, want %s", i, product.ProductID, wantOrder[i])
+		}
+		if i < len(wantRates) && (product.ReturnRatePct == nil || *product.ReturnRatePct != wantRates[i]) {
+			t.Errorf("%s: ReturnRatePct = %v, want %v", product.ProductID, product.ReturnRatePct, wantRates[i])
+		}
+	}
+	if lid := analysis.Products[4]; lid.ReturnRatePct != nil {
+		t.Errorf("lid: ReturnRatePct = %v, want nil without sales", *lid.ReturnRatePct)
+	}
+
+	wantReasons := []ReturnReasonCount{
+		{Reason: "damaged", Count: 3, Quantity: 9, SharePct: 75},
+		{Reason: "wrong size", Count: 1, Quantity: 2, SharePct: 25},
+	}
+	if !reflect.DeepEqual(analysis.Reasons, wantReasons) {
+		t.Errorf("reasons = %+v, want %+v", analysis.Reasons, wantReasons)
+	}
+
+	wantTrend := []ReturnTrendPoint{
+		{Period: "2024-01", Returns: 3, Quantity: 9, Value: 200},
+		{Period: "2024-02"},
+		{Period: "2024-03", Returns: 1, Quantity: 2, Value: 50},
+	}
+	if !reflect.DeepEqual(analysis.MonthlyTrend, wantTrend) {
+		t.Errorf("monthly trend = %+v, want %+v", analysis.MonthlyTrend, wantTrend)
+	}
+}
+
+func TestNewReturnAnalysisWithoutReturns(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	analysis := NewReturnAnalysis(from, from.AddDate(0, 1, -1), ReturnValueTotals{}, nil, nil, nil)
+
+	if analysis.Products == nil || analysis.Reasons == nil {
+		t.Error("products or reasons are nil, want empty lists")
+	}
+	if len(analysis.MonthlyTrend) != 1 || analysis.MonthlyTrend[0] != (ReturnTrendPoint{Period: "2024-01"}) {
+		t.Errorf("monthly trend = %+v, want one empty January", analysis.MonthlyTrend)
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import "testing"
+
+func TestToSaleReturnPricesAtDiscountedUnitPrice(t *testing.T) {
+	sale := &Sale{
+		IsVAT:   true,
+		VATRate: 0.07,
+		Items: []SaleItem{
+			{ProductID: "p1", ProductName: "Box", Quantity: 4, TotalPrice: 360}, // 100 each less 10%
+		},
+	}
+	req := SaleReturnRequest{Items: []ReturnItemRequest{{ProductID: "p1", Quantity: 2, Reason: "damaged"}}}
+
+	saleReturn := req.ToSaleReturn(sale)
+	item := saleReturn.Items[0]
+	if item.UnitPrice != 90 || item.TotalPrice != 180 || item.ProductName != "Box" {
+		t.Errorf("return item = %+v, want 2 × 90 = 180", item)
+	}
+	if saleReturn.RefundAmount != 192.6 {
+		t.Errorf("RefundAmount = %v, want 180 + 7%% VAT = 192.6", saleReturn.RefundAmount)
+	}
+}
+
+func TestToSaleReturnAveragesProductOverSaleLines(t *testing.T) {
+	sale := &Sale{
+		Items: []SaleItem{
+			{ProductID: "p1", Quantity: 2, TotalPrice: 100}, // bundle component at 50
+			{ProductID: "p2", Quantity: 1, TotalPrice: 30},
+			{ProductID: "p1", Quantity: 3, TotalPrice: 240}, // standalone at 80
+		},
+	}
+	req := SaleReturnRequest{Items: []ReturnItemRequest{{ProductID: "p1", Quantity: 5}}}
+
+	saleReturn := req.ToSaleReturn(sale)
+	if got := saleReturn.Items[0]; got.UnitPrice != 68 || got.TotalPrice != 340 {
+		t.Errorf("returning all 5 units = %v × %v, want 68 × 5 = 340 (the total of both lines)", got.UnitPrice, got.TotalPrice)
+	}
+	if saleReturn.RefundAmount != 340 {
+		t.Errorf("RefundAmount = %v, want 340 on a non-VAT sale", saleReturn.RefundAmount)
+	}
+}
+
+func TestGenerateReturnCode(t *testing.T) {
+	first, err := GenerateReturnCode("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := GenerateReturnCode(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next[:len(next)-4] != first[:len(first)-4] || next[len(next)-4:] != "0002" {
+		t.Errorf("GenerateReturnCode(%q) = %q, want the next number of the month", first, next)
+	}
+	if _, err := GenerateReturnCode("SL-0001"); err == nil {
+		t.Error("GenerateReturnCode accepted a code in another format")
+	}
+}
+
+func TestUnreturnedQuantities(t *testing.T) {
+	sale := &Sale{Items: []SaleItem{
+		{ProductID: "p1", Quantity: 3},
+		{ProductID: "p2", Quantity: 24},
+		{ProductID: "p1", Quantity: 2},
+	}}
+	returns := []*SaleReturn{
+		{Items: []ReturnItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p2", Quantity: 5}}},
+		{Items: []ReturnItem{{ProductID: "p1", Quantity: 2}}},
+	}
+
+	returned := ReturnedQuantities(returns)
+	if returned["p1"] != 4 || returned["p2"] != 5 {
+		t.Fatalf("ReturnedQuantities = %v, want p1: 4, p2: 5", returned)
+	}
+
+	got := sale.UnreturnedQuantities(returned)
+	want := []int{0, 19, 1} // p1's 4 returned come off its first line, then its second
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("UnreturnedQuantities = %v, want %v", got, want)
+			break
+		}
+	}
+	if returned["p1"] != 4 {
+		t.Errorf("UnreturnedQuantities changed the returned quantities: %v", returned)
+	}
+}
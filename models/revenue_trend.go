@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Revenue trend granularities
+const (
+	TrendDaily   = "daily"
+	TrendWeekly  = "weekly"
+	TrendMonthly = "monthly"
+)
+
+// trendDateFormats are the $dateToString formats that label each granularity's periods.
+// Weeks are ISO weeks, e.g. 2024-W03.
+var trendDateFormats = map[string]string{
+	TrendDaily:   "%Y-%m-%d",
+	TrendWeekly:  "%G-W%V",
+	TrendMonthly: "%Y-%m",
+}
+
+// RevenueTrendPoint is the sales and purchase totals of one period. Amounts exclude VAT and shipping.
+type RevenueTrendPoint struct {
+	Period        string  `json:"period"` // 2024-01-15, 2024-W03 หรือ 2024-01
+	SalesRevenue  float64 `json:"salesRevenue"`
+	PurchaseCost  float64 `json:"purchaseCost"`
+	GrossProfit   float64 `json:"grossProfit"` // salesRevenue - purchaseCost
+	SalesCount    int64   `json:"salesCount"`
+	PurchaseCount int64   `json:"purchaseCount"`
+}
+
+// IsTrendGranularity reports whether granularity is daily, weekly or monthly
+func IsTrendGranularity(granularity string) bool {
+	_, ok := trendDateFormats[granularity]
+	return ok
+}
+
+// TrendDateFormat returns the $dateToString format for the granularity's period labels
+func TrendDateFormat(granularity string) string {
+	return trendDateFormats[granularity]
+}
+
+// TrendPeriod returns the label of the period containing t, matching TrendDateFormat (in UTC)
+func TrendPeriod(granularity string, t time.Time) string {
+	t = t.UTC()
+	switch granularity {
+	case TrendDaily:
+		return t.Format("2006-01-02")
+	case TrendWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return t.Format("2006-01")
+}
+
+// TrendPeriodStart returns the start of the period containing t (in UTC)
+func TrendPeriodStart(granularity string, t time.Time) time.Time {
+	t = t.UTC()
+	switch granularity {
+	case TrendDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case TrendWeekly:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7)) // back to Monday
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// AddTrendPeriods moves t forward n periods (back when n is negative)
+func AddTrendPeriods(granularity string, t time.Time, n int) time.Time {
+	switch granularity {
+	case TrendDaily:
+		return t.AddDate(0, 0, n)
+	case TrendWeekly:
+		return t.AddDate(0, 0, 7*n)
+	}
+	return t.AddDate(0, n, 0)
+}
+
+// TrendPeriods lists the labels of every period from..to in order
+func TrendPeriods(granularity string, from, to time.Time) []string {
+	var periods []string
+	for t := TrendPeriodStart(granularity, from); !t.After(to); t = AddTrendPeriods(granularity, t, 1) {
+		periods = append(periods, TrendPeriod(granularity, t))
+	}
+	return periods
+}
+
+// NewRevenueTrend joins the per-period sales and purchase totals into one point per period from..to,
+// with zero totals for periods without transactions
+func NewRevenueTrend(granularity string, from, to time.Time, sales, purchases map[string]TransactionTotals) []RevenueTrendPoint {
+	trend := []RevenueTrendPoint{}
+	for _, period := range TrendPeriods(granularity, from, to) {
+		point := RevenueTrendPoint{
+			Period:        period,
+			SalesRevenue:  sales[period].TotalAmount,
+			PurchaseCost:  purchases[period].TotalAmount,
+			SalesCount:    sales[period].Count,
+			PurchaseCount: purchases[period].Count,
+		}
+		point.GrossProfit = point.SalesRevenue - point.PurchaseCost
+		trend = append(trend, point)
+	}
+	return trend
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRevenueTrendFillsGaps(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.March, 31, 23, 59, 59, 0, time.UTC)
+	sales := map[string]TransactionTotals{
+		"2024-01": {Count: 3, TotalAmount: 1500},
+		"2024-03": {Count: 2, TotalAmount: 900},
+	}
+	purchases := map[string]TransactionTotals{
+		"2024-01": {Count: 1, TotalAmount: 1000},
+		"2024-03": {Count: 1, TotalAmount: 1200},
+	}
+
+	want := []RevenueTrendPoint{
+		{Period: "2024-01", SalesRevenue: 1500, PurchaseCost: 1000, GrossProfit: 500, SalesCount: 3, PurchaseCount: 1},
+		{Period: "2024-02"},
+		{Period: "2024-03", SalesRevenue: 900, PurchaseCost: 1200, GrossProfit: -300, SalesCount: 2, PurchaseCount: 1},
+	}
+	if got := NewRevenueTrend(TrendMonthly, from, to, sales, purchases); !reflect.DeepEqual(got, want) {
+		t.Errorf("trend = %+v, want %+v", got, want)
+	}
+}
+
+func TestTrendPeriods(t *testing.T) {
+	tests := []struct {
+		granularity string
+		from, to    time.Time
+		want        []string
+	}{
+		{TrendDaily, time.Date(2024, 2, 28, 15, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), []string{"2024-02-28", "2024-02-29", "2024-03-01"}},
+		// ISO weeks: 2024-12-30 is in week 1 of 2025
+		{TrendWeekly, time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), []string{"2024-W52", "2025-W01", "2025-W02"}},
+		{TrendMonthly, time.Date(2024, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), []string{"2024-11", "2024-12", "2025-01"}},
+	}
+	for _, tt := range tests {
+		if got := TrendPeriods(tt.granularity, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s periods = %q, want %q", tt.granularity, got, tt.want)
+		}
+	}
+}
@@ -1,52 +1,77 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Sale struct {
-	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	SaleCode          string             `bson:"saleCode" json:"saleCode"`
-	QuotationCode     *string            `bson:"quotationCode,omitempty" json:"quotationCode,omitempty"`
-	SaleDate          time.Time          `bson:"saleDate" json:"saleDate"`
-	CustomerID        string             `bson:"customerId" json:"customerId"`
-	CustomerName      string             `bson:"customerName" json:"customerName"`
-	ContactName       *string            `bson:"contactName,omitempty" json:"contactName,omitempty"`
-	CustomerCode      *string            `bson:"customerCode,omitempty" json:"customerCode,omitempty"`
-	TaxID             *string            `bson:"taxId,omitempty" json:"taxId,omitempty"`
-	Address           *string            `bson:"address,omitempty" json:"address,omitempty"`
-	Phone             *string            `bson:"phone,omitempty" json:"phone,omitempty"`
-	Items             []SaleItem         `bson:"items" json:"items"`
-	IsVAT             bool               `bson:"isVAT" json:"isVAT"`
-	ShippingCost      float64            `bson:"shippingCost" json:"shippingCost"`
-	Payment           PaymentInfo        `bson:"payment" json:"payment"`
-	Warehouse         WarehouseInfo      `bson:"warehouse" json:"warehouse"`
-	Notes             *string            `bson:"notes,omitempty" json:"notes,omitempty"`
-	BankAccountID     *string            `bson:"bankAccountId,omitempty" json:"bankAccountId,omitempty"`
-	BankName          *string            `bson:"bankName,omitempty" json:"bankName,omitempty"`
-	BankAccountName   *string            `bson:"bankAccountName,omitempty" json:"bankAccountName,omitempty"`
-	BankAccountNumber *string            `bson:"bankAccountNumber,omitempty" json:"bankAccountNumber,omitempty"`
-	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID                   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SaleCode             string             `bson:"saleCode" json:"saleCode"`
+	QuotationCode        *string            `bson:"quotationCode,omitempty" json:"quotationCode,omitempty"`
+	SaleDate             time.Time          `bson:"saleDate" json:"saleDate"`
+	CustomerID           string             `bson:"customerId" json:"customerId"`
+	CustomerName         string             `bson:"customerName" json:"customerName"`
+	ContactName          *string            `bson:"contactName,omitempty" json:"contactName,omitempty"`
+	CustomerCode         *string            `bson:"customerCode,omitempty" json:"customerCode,omitempty"`
+	TaxID                *string            `bson:"taxId,omitempty" json:"taxId,omitempty"`
+	Address              *string            `bson:"address,omitempty" json:"address,omitempty"`
+	Phone                *string            `bson:"phone,omitempty" json:"phone,omitempty"`
+	Items                []SaleItem         `bson:"items" json:"items"`
+	IsVAT                bool               `bson:"isVAT" json:"isVAT"`
+	VATRate              float64            `bson:"vatRate,omitempty" json:"vatRate"` // อัตรา VAT ณ วันที่สร้าง (ไม่มี = 7%)
+	ShippingCost         float64            `bson:"shippingCost" json:"shippingCost"`
+	DiscountTotal        float64            `bson:"discountTotal" json:"discountTotal"` // ส่วนลดรวมทุกรายการ
+	Payment              PaymentInfo        `bson:"payment" json:"payment"`
+	Payments             []PaymentRecord    `bson:"payments,omitempty" json:"payments,omitempty"` // ประวัติการรับชำระ
+	Warehouse            WarehouseInfo      `bson:"warehouse" json:"warehouse"`
+	IsDraft              bool               `bson:"isDraft,omitempty" json:"isDraft,omitempty"`                           // สร้างจากการคัดลอก ยังไม่ตัดสต็อกจนกว่าจะยืนยัน
+	Status               string             `bson:"status,omitempty" json:"status,omitempty"`                             // สถานะ (ไม่มี = draft หรือ confirmed ตาม isDraft)
+	StatusHistory        []StatusEntry      `bson:"statusHistory,omitempty" json:"statusHistory,omitempty"`               // ประวัติการเปลี่ยนสถานะ
+	DeliveryStatus       string             `bson:"deliveryStatus,omitempty" json:"deliveryStatus,omitempty"`             // สถานะการจัดส่ง (ไม่มี = pending)
+	ExpectedDeliveryDate *time.Time         `bson:"expectedDeliveryDate,omitempty" json:"expectedDeliveryDate,omitempty"` // วันที่กำหนดส่ง
+	ActualDeliveryDate   *time.Time         `bson:"actualDeliveryDate,omitempty" json:"actualDeliveryDate,omitempty"`     // วันที่ส่งถึงจริง
+	Notes                *string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	BankAccountID        *string            `bson:"bankAccountId,omitempty" json:"bankAccountId,omitempty"`
+	BankName             *string            `bson:"bankName,omitempty" json:"bankName,omitempty"`
+	BankAccountName      *string            `bson:"bankAccountName,omitempty" json:"bankAccountName,omitempty"`
+	BankAccountNumber    *string            `bson:"bankAccountNumber,omitempty" json:"bankAccountNumber,omitempty"`
+	CreatedAt            time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt            time.Time          `bson:"updatedAt" json:"updatedAt"`
+	Version              int                `bson:"version" json:"version"`
+	IsDeleted            bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt            *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
 type SaleItem struct {
-	ProductID   string  `bson:"productId" json:"productId"`
+	ProductID   string  `bson:"productId" json:"productId" validate:"required"`
 	ProductName string  `bson:"productName" json:"productName"`
 	ProductCode string  `bson:"productCode" json:"productCode"`
-	Quantity    int     `bson:"quantity" json:"quantity"`
-	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice"`
+	Quantity    float64 `bson:"quantity" json:"quantity" validate:"gt=0,whole"` // จำนวนในหน่วยย่อย (ใช้ตัดสต็อก) ต้องเป็นจำนวนเต็ม
+	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice" validate:"min=0"`
 	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`
+
+	DiscountPercent float64 `bson:"discountPercent" json:"discountPercent" validate:"min=0,max=100"` // ส่วนลด (%)
+	DiscountAmount  float64 `bson:"discountAmount" json:"discountAmount" validate:"min=0"`           // ส่วนลด (บาท)
+
+	DisplayQuantity float64 `bson:"displayQuantity,omitempty" json:"displayQuantity,omitempty"` // จำนวนในหน่วยขายของสินค้า (Quantity / ConversionFactor)
+	DisplayUOM      string  `bson:"displayUom,omitempty" json:"displayUom,omitempty"`           // หน่วยขาย เช่น box
+
+	BundleID      string   `bson:"bundleId,omitempty" json:"bundleId,omitempty"`           // ชุดสินค้าที่รายการนี้แตกออกมา
+	SerialNumbers []string `bson:"serialNumbers,omitempty" json:"serialNumbers,omitempty"` // หมายเลขซีเรียลที่ขาย (สินค้าที่ติดตามรายเครื่อง)
+
+	Lots []LotAllocation `bson:"lots,omitempty" json:"lots,omitempty"` // ล็อตที่ตัดสต็อก (FEFO) กำหนดโดยระบบ
 }
 
 type SaleRequest struct {
 	SaleDate          time.Time     `json:"saleDate"`
-	CustomerID        string        `json:"customerId"`
-	Items             []SaleItem    `json:"items"`
+	CustomerID        string        `json:"customerId" validate:"required"`
+	Items             []SaleItem    `json:"items" validate:"required_without=Bundles,dive"`
+	Bundles           []BundleItem  `json:"bundles,omitempty" validate:"omitempty,dive"` // expanded into items by the sale service
 	IsVAT             bool          `json:"isVAT"`
-	ShippingCost      float64       `json:"shippingCost"`
+	ShippingCost      float64       `json:"shippingCost" validate:"min=0"`
 	Payment           PaymentInfo   `json:"payment"`
 	Warehouse         WarehouseInfo `json:"warehouse"`
 	Notes             *string       `json:"notes,omitempty"`
@@ -55,18 +80,23 @@ type SaleRequest struct {
 	BankName          *string       `json:"bankName,omitempty"`
 	BankAccountName   *string       `json:"bankAccountName,omitempty"`
 	BankAccountNumber *string       `json:"bankAccountNumber,omitempty"`
+	Version           *int          `json:"version,omitempty"` // required when updating
 }
 
 func (sr *SaleRequest) ToSale() *Sale {
 	now := time.Now()
+	discountTotal := calculateSaleItems(sr.Items)
 	return &Sale{
 		SaleDate:          sr.SaleDate,
 		CustomerID:        sr.CustomerID,
 		Items:             sr.Items,
 		IsVAT:             sr.IsVAT,
+		VATRate:           VATRate,
 		ShippingCost:      sr.ShippingCost,
+		DiscountTotal:     discountTotal,
 		Payment:           sr.Payment,
 		Warehouse:         sr.Warehouse,
+		Status:            OrderStatusConfirmed,
 		Notes:             sr.Notes,
 		QuotationCode:     sr.QuotationCode,
 		BankAccountID:     sr.BankAccountID,
@@ -78,12 +108,24 @@ func (sr *SaleRequest) ToSale() *Sale {
 	}
 }
 
+// StockQuantity is the quantity cut from stock. Stock is counted in whole base units, so Quantity is validated to
+// be whole; fractional amounts are sold through the UOM (e.g. 0.5 box = 6 pcs).
+func (i *SaleItem) StockQuantity() int {
+	return int(i.Quantity)
+}
+
+// IsWholeQuantity reports whether a quantity is a whole number of base units
+func IsWholeQuantity(quantity float64) bool {
+	return quantity == math.Trunc(quantity)
+}
+
 func (s *Sale) UpdateFromRequest(req *SaleRequest) {
 	s.SaleDate = req.SaleDate
 	s.CustomerID = req.CustomerID
 	s.Items = req.Items
 	s.IsVAT = req.IsVAT
 	s.ShippingCost = req.ShippingCost
+	s.DiscountTotal = calculateSaleItems(req.Items)
 	s.Payment = req.Payment
 	s.Warehouse = req.Warehouse
 	s.Notes = req.Notes
@@ -94,3 +136,71 @@ func (s *Sale) UpdateFromRequest(req *SaleRequest) {
 	s.BankAccountNumber = req.BankAccountNumber
 	s.UpdatedAt = time.Now()
 }
+
+// Duplicate copies a sale for a repeat order as a draft: same customer, items, VAT type, shipping and notes, but
+// dated now, unpaid, not yet shipped and with no serial numbers, which are picked when the draft is confirmed.
+// Lots are allocated again on confirmation.
+func (s *Sale) Duplicate(saleCode string) *Sale {
+	now := time.Now()
+	items := make([]SaleItem, len(s.Items))
+	for i, item := range s.Items {
+		item.SerialNumbers = nil
+		item.Lots = nil
+		items[i] = item
+	}
+
+	payment := s.Payment
+	payment.IsPaid = false
+	payment.PaymentDate = nil
+
+	return &Sale{
+		SaleCode:          saleCode,
+		SaleDate:          now,
+		CustomerID:        s.CustomerID,
+		CustomerName:      s.CustomerName,
+		ContactName:       s.ContactName,
+		CustomerCode:      s.CustomerCode,
+		TaxID:             s.TaxID,
+		Address:           s.Address,
+		Phone:             s.Phone,
+		Items:             items,
+		IsVAT:             s.IsVAT,
+		VATRate:           VATRate,
+		ShippingCost:      s.ShippingCost,
+		DiscountTotal:     s.DiscountTotal,
+		Payment:           payment,
+		Warehouse:         WarehouseInfo{Items: []WarehouseItem{}},
+		IsDraft:           true,
+		Status:            OrderStatusDraft,
+		Notes:             s.Notes,
+		BankAccountID:     s.BankAccountID,
+		BankName:          s.BankName,
+		BankAccountName:   s.BankAccountName,
+		BankAccountNumber: s.BankAccountNumber,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
+
+// CalculateGrandTotal calculates the grand total including VAT at the sale's rate and shipping
+func (s *Sale) CalculateGrandTotal() float64 {
+	totalBeforeVAT := 0.0
+	for _, item := range s.Items {
+		totalBeforeVAT += item.TotalPrice
+	}
+
+	totalVAT := calculateVAT(totalBeforeVAT, s.IsVAT, s.EffectiveVATRate())
+
+	return totalBeforeVAT + totalVAT + s.ShippingCost
+}
+
+// calculateSaleItems applies line discounts to the items and returns the total discount
+func calculateSaleItems(items []SaleItem) float64 {
+	var discountTotal float64
+	for i := range items {
+		item := &items[i]
+		item.TotalPrice = CalculateLineTotal(item.Quantity, item.UnitPrice, item.DiscountPercent, item.DiscountAmount)
+		discountTotal += calculateLineDiscount(item.Quantity, item.UnitPrice, item.TotalPrice)
+	}
+	return discountTotal
+}
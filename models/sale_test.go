@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaleDuplicate(t *testing.T) {
+	paidAt := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	notes := "deliver before noon"
+	original := &Sale{
+		SaleCode:     "INV-6701-0001",
+		SaleDate:     paidAt,
+		CustomerID:   "c1",
+		CustomerName: "Cafe Amazon",
+		Items: []SaleItem{
+			{ProductID: "p1", Quantity: 2, UnitPrice: 25000, TotalPrice: 50000, SerialNumbers: []string{"SN-1", "SN-2"}},
+			{ProductID: "p2", Quantity: 10, UnitPrice: 20, TotalPrice: 200},
+		},
+		IsVAT:        true,
+		ShippingCost: 50,
+		Notes:        &notes,
+		Payment:      PaymentInfo{IsPaid: true, PaymentDate: &paidAt},
+		Warehouse:    WarehouseInfo{IsUpdated: true},
+		Status:       OrderStatusCompleted,
+		CreatedAt:    paidAt,
+	}
+	before := time.Now()
+
+	duplicate := original.Duplicate("INV-6702-0001")
+
+	if duplicate.SaleCode != "INV-6702-0001" {
+		t.Errorf("SaleCode = %s, want INV-6702-0001", duplicate.SaleCode)
+	}
+	if duplicate.SaleDate.Before(before) || duplicate.CreatedAt.Before(before) || duplicate.UpdatedAt.Before(before) {
+		t.Errorf("dates = %v, %v, %v, want now", duplicate.SaleDate, duplicate.CreatedAt, duplicate.UpdatedAt)
+	}
+	if duplicate.CustomerID != "c1" || duplicate.CustomerName != "Cafe Amazon" || !duplicate.IsVAT || duplicate.ShippingCost != 50 || duplicate.Notes != &notes {
+		t.Errorf("duplicate = %+v, want the original's customer, VAT, shipping and notes", duplicate)
+	}
+	if duplicate.Payment.IsPaid || duplicate.Payment.PaymentDate != nil || duplicate.Warehouse.IsUpdated {
+		t.Errorf("payment %+v, warehouse %+v, want unpaid and not shipped", duplicate.Payment, duplicate.Warehouse)
+	}
+	if !duplicate.IsDraft || duplicate.Status != OrderStatusDraft {
+		t.Errorf("IsDraft = %t, Status = %s, want a draft", duplicate.IsDraft, duplicate.Status)
+	}
+	if len(duplicate.Items) != 2 || duplicate.Items[0].Quantity != 2 || duplicate.Items[0].SerialNumbers != nil {
+		t.Errorf("items = %+v, want both items without serial numbers", duplicate.Items)
+	}
+	if len(original.Items[0].SerialNumbers) != 2 {
+		t.Errorf("original serial numbers = %v, want them kept", original.Items[0].SerialNumbers)
+	}
+}
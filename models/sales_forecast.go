@@ -0,0 +1,28 @@
+package models
+
+// Sales forecast moving-average windows
+const (
+	ForecastWindowShort = 3
+	ForecastWindowLong  = 6
+)
+
+// Forecast confidence levels, from how much the sales in the moving-average window vary
+const (
+	ForecastConfidenceHigh   = "high"   // coefficient of variation below 0.25
+	ForecastConfidenceMedium = "medium" // below 0.5
+	ForecastConfidenceLow    = "low"
+)
+
+// PeriodSales is the net quantity of a product sold in one period and its revenue
+type PeriodSales struct {
+	Quantity float64 `bson:"quantity" json:"quantity"`
+	Revenue  float64 `bson:"revenue" json:"revenue"`
+}
+
+// SalesForecastPoint is the forecast sales of a product in one future period. Revenue excludes VAT.
+type SalesForecastPoint struct {
+	Period            string  `json:"period"` // 2024-04, 2024-W15 หรือ 2024-04-01
+	ForecastedQty     float64 `json:"forecastedQty"`
+	ForecastedRevenue float64 `json:"forecastedRevenue"` // forecastedQty × ราคาขายเฉลี่ยในช่วงที่ใช้คำนวณ
+	Confidence        string  `json:"confidence"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Serial number statuses
+const (
+	SerialNumberStatusAvailable = "available"
+	SerialNumberStatusSold      = "sold"
+	SerialNumberStatusReturned  = "returned" // กลับเข้าสต็อกจากการคืนสินค้า ขายได้อีกครั้ง
+)
+
+// SerialNumber is one unit of a serialised product, tracked from the purchase that received it to the sale
+// that sold it
+type SerialNumber struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID    string             `bson:"productId" json:"productId"`
+	SerialNumber string             `bson:"serialNumber" json:"serialNumber"`
+	Status       string             `bson:"status" json:"status"`
+	SaleID       *string            `bson:"saleId,omitempty" json:"saleId,omitempty"`         // รายการขายล่าสุดของเครื่องนี้
+	PurchaseID   *string            `bson:"purchaseId,omitempty" json:"purchaseId,omitempty"` // รายการซื้อที่รับเข้า
+	ReceivedAt   time.Time          `bson:"receivedAt" json:"receivedAt"`
+	SoldAt       *time.Time         `bson:"soldAt,omitempty" json:"soldAt,omitempty"`
+	ReturnedAt   *time.Time         `bson:"returnedAt,omitempty" json:"returnedAt,omitempty"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ValidSerialNumberStatus reports whether status is a known serial number status
+func ValidSerialNumberStatus(status string) bool {
+	switch status {
+	case SerialNumberStatusAvailable, SerialNumberStatusSold, SerialNumberStatusReturned:
+		return true
+	}
+	return false
+}
+
+// IsInStock reports whether the unit can be sold: it was received and not sold, or sold and returned
+func (s *SerialNumber) IsInStock() bool {
+	return s.Status == SerialNumberStatusAvailable || s.Status == SerialNumberStatusReturned
+}
+
+// CheckSerialNumbers checks that serials name exactly quantity distinct units of a serialised product.
+// It returns the problem, or "" when they do.
+func CheckSerialNumbers(serials []string, quantity int) string {
+	if len(serials) != quantity {
+		return fmt.Sprintf("expected %d serial numbers, got %d", quantity, len(serials))
+	}
+
+	seen := make(map[string]bool, len(serials))
+	for _, serial := range serials {
+		if strings.TrimSpace(serial) == "" {
+			return "serial numbers must not be blank"
+		}
+		if seen[serial] {
+			return fmt.Sprintf("serial number %s is listed more than once", serial)
+		}
+		seen[serial] = true
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSerialNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		serials  []string
+		quantity int
+		want     string // part of the problem, "" when the serials are valid
+	}{
+		{"one per unit", []string{"SN-1", "SN-2"}, 2, ""},
+		{"too few", []string{"SN-1"}, 2, "expected 2 serial numbers, got 1"},
+		{"too many", []string{"SN-1", "SN-2", "SN-3"}, 2, "expected 2 serial numbers, got 3"},
+		{"blank", []string{"SN-1", " "}, 2, "must not be blank"},
+		{"repeated", []string{"SN-1", "SN-1"}, 2, "SN-1 is listed more than once"},
+	}
+	for _, tt := range tests {
+		got := CheckSerialNumbers(tt.serials, tt.quantity)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSerialNumberIsInStock(t *testing.T) {
+	for status, want := range map[string]bool{
+		SerialNumberStatusAvailable: true,
+		SerialNumberStatusSold:      false,
+		SerialNumberStatusReturned:  true,
+	} {
+		if got := (&SerialNumber{Status: status}).IsInStock(); got != want {
+			t.Errorf("%s unit: IsInStock() = %t, want %t", status, got, want)
+		}
+	}
+}
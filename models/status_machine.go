@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Order statuses of sales and purchases
+const (
+	OrderStatusDraft      = "draft"
+	OrderStatusConfirmed  = "confirmed"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusCompleted  = "completed"
+	OrderStatusCancelled  = "cancelled"
+)
+
+// orderStatusTransitions are the statuses an order can move to from each status. Completed and cancelled orders
+// are final, and nothing goes back to draft once stock has moved.
+var orderStatusTransitions = map[string][]string{
+	OrderStatusDraft:      {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:  {OrderStatusProcessing, OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusCompleted},
+	OrderStatusCompleted:  {},
+	OrderStatusCancelled:  {},
+}
+
+// StatusTransitionError is returned when an order cannot move from its status to the requested one
+type StatusTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// StatusEntry is one status change in an order's status history
+type StatusEntry struct {
+	Status    string    `bson:"status" json:"status"`
+	ChangedAt time.Time `bson:"changedAt" json:"changedAt"`
+	ChangedBy *string   `bson:"changedBy,omitempty" json:"changedBy,omitempty"` // ผู้เปลี่ยนสถานะ (X-User-ID)
+	Notes     *string   `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+// StatusUpdateRequest is the body of PUT /api/sales/{id}/status and PUT /api/purchases/{id}/status
+type StatusUpdateRequest struct {
+	Status string  `json:"status" validate:"required,oneof=draft confirmed processing shipped completed cancelled"`
+	Notes  *string `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+// CanTransitionOrderStatus reports whether an order can move from status from to status to
+func CanTransitionOrderStatus(from, to string) bool {
+	return slices.Contains(orderStatusTransitions[from], to)
+}
+
+// orderStatus is the status of an order; orders saved before statuses were stored are draft or confirmed
+func orderStatus(status string, isDraft bool) string {
+	switch {
+	case status != "":
+		return status
+	case isDraft:
+		return OrderStatusDraft
+	default:
+		return OrderStatusConfirmed
+	}
+}
+
+// transitionOrderStatus moves an order from its current status to status, appending the change to history
+func transitionOrderStatus(current string, status *string, history *[]StatusEntry, to string, changedBy, notes *string) error {
+	if !CanTransitionOrderStatus(current, to) {
+		return &StatusTransitionError{From: current, To: to}
+	}
+	*status = to
+	*history = append(*history, StatusEntry{
+		Status:    to,
+		ChangedAt: time.Now(),
+		ChangedBy: changedBy,
+		Notes:     notes,
+	})
+	return nil
+}
+
+// CurrentStatus is the sale's status
+func (s *Sale) CurrentStatus() string {
+	return orderStatus(s.Status, s.IsDraft)
+}
+
+// HoldsStock reports whether the sale's items are cut from stock: confirmed and not cancelled
+func (s *Sale) HoldsStock() bool {
+	return !s.IsDraft && s.CurrentStatus() != OrderStatusCancelled
+}
+
+// TransitionStatus moves the sale to status and records the change in its status history
+func (s *Sale) TransitionStatus(status string, changedBy, notes *string) error {
+	if err := transitionOrderStatus(s.CurrentStatus(), &s.Status, &s.StatusHistory, status, changedBy, notes); err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// CurrentStatus is the purchase's status
+func (p *Purchase) CurrentStatus() string {
+	return orderStatus(p.Status, p.IsDraft)
+}
+
+// TransitionStatus moves the purchase to status and records the change in its status history
+func (p *Purchase) TransitionStatus(status string, changedBy, notes *string) error {
+	if err := transitionOrderStatus(p.CurrentStatus(), &p.Status, &p.StatusHistory, status, changedBy, notes); err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now()
+	return nil
+}
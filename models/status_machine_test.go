@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransitionOrderStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderStatusDraft, OrderStatusConfirmed, true},
+		{OrderStatusDraft, OrderStatusShipped, false},
+		{OrderStatusConfirmed, OrderStatusShipped, true},
+		{OrderStatusProcessing, OrderStatusShipped, true},
+		{OrderStatusShipped, OrderStatusCompleted, true},
+		{OrderStatusShipped, OrderStatusCancelled, false},
+		{OrderStatusConfirmed, OrderStatusDraft, false},
+		{OrderStatusCompleted, OrderStatusCancelled, false},
+		{OrderStatusCancelled, OrderStatusConfirmed, false},
+		{OrderStatusConfirmed, OrderStatusConfirmed, false},
+		{"unknown", OrderStatusConfirmed, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransitionOrderStatus(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s -> %s: %t, want %t", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSaleTransitionStatus(t *testing.T) {
+	// Sales saved before statuses were stored count as confirmed, or draft when they are drafts
+	if got := (&Sale{}).CurrentStatus(); got != OrderStatusConfirmed {
+		t.Errorf("legacy sale status = %s, want confirmed", got)
+	}
+	if got := (&Sale{IsDraft: true}).CurrentStatus(); got != OrderStatusDraft {
+		t.Errorf("legacy draft status = %s, want draft", got)
+	}
+
+	user := "u1"
+	sale := &Sale{}
+	if err := sale.TransitionStatus(OrderStatusShipped, &user, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sale.Status != OrderStatusShipped || len(sale.StatusHistory) != 1 || *sale.StatusHistory[0].ChangedBy != "u1" {
+		t.Errorf("after shipping: status %s, history %+v", sale.Status, sale.StatusHistory)
+	}
+
+	err := sale.TransitionStatus(OrderStatusCancelled, nil, nil)
+	var transitionErr *StatusTransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != OrderStatusShipped || transitionErr.To != OrderStatusCancelled {
+		t.Errorf("cancelling a shipped sale: %v, want a StatusTransitionError", err)
+	}
+	if sale.Status != OrderStatusShipped || len(sale.StatusHistory) != 1 {
+		t.Errorf("rejected transition changed the sale: status %s, %d history entries", sale.Status, len(sale.StatusHistory))
+	}
+
+	if (&Sale{Status: OrderStatusCancelled}).HoldsStock() || (&Sale{IsDraft: true}).HoldsStock() || !sale.HoldsStock() {
+		t.Error("HoldsStock: want only confirmed, uncancelled sales to hold stock")
+	}
+}
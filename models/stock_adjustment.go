@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -31,6 +33,11 @@ const (
 	SourceTypeSale       SourceType = "sale"       // จากรายการขาย
 	SourceTypeAdjustment SourceType = "adjustment" // จากฟีเจอร์แก้ไขสต็อก
 	SourceTypeMigration  SourceType = "migration"  // จาก migration
+	SourceTypeReturn     SourceType = "return"     // จากการรับคืนสินค้า
+
+	SourceTypeReconciliation SourceType = "reconciliation" // จากการกระทบยอดสต็อก
+	SourceTypeStockCount     SourceType = "stock_count"    // จากการตรวจนับสต็อก
+	SourceTypeTransfer       SourceType = "transfer"       // จากการโอนสต็อกระหว่าง VAT และ Non-VAT
 )
 
 // StockAdjustment represents a stock adjustment record
@@ -45,6 +52,9 @@ type StockAdjustment struct {
 	StockType      StockType           `bson:"stockType" json:"stockType"`           // vat, nonvat, or actualstock
 	Quantity       int                 `bson:"quantity" json:"quantity"`             // จำนวนที่เพิ่ม/ลด
 
+	// Transfers only: StockType is the bucket reduced and ToStockType the bucket added to
+	ToStockType StockType `bson:"toStockType,omitempty" json:"toStockType,omitempty"`
+
 	// Stock values before and after adjustment
 	BeforeVATPurchased    int `bson:"beforeVATPurchased" json:"beforeVATPurchased"`
 	BeforeVATSold         int `bson:"beforeVATSold" json:"beforeVATSold"`
@@ -63,7 +73,7 @@ type StockAdjustment struct {
 	AfterActualStock     int `bson:"afterActualStock" json:"afterActualStock"`
 
 	// Source information
-	SourceType SourceType `bson:"sourceType" json:"sourceType"`                     // purchase, sale, adjustment, migration
+	SourceType SourceType `bson:"sourceType" json:"sourceType"`                     // purchase, sale, adjustment, migration, return, reconciliation, stock_count, transfer
 	SourceID   *string    `bson:"sourceId,omitempty" json:"sourceId,omitempty"`     // ID of purchase/sale if applicable
 	SourceCode *string    `bson:"sourceCode,omitempty" json:"sourceCode,omitempty"` // Code of purchase/sale (e.g., PUR-VAT-6701-0001)
 
@@ -77,10 +87,10 @@ type StockAdjustment struct {
 
 // StockAdjustmentRequest represents the request body for creating a stock adjustment
 type StockAdjustmentRequest struct {
-	AdjustmentType StockAdjustmentType `json:"adjustmentType"`  // "add" or "reduce"
-	StockType      StockType           `json:"stockType"`       // "vat", "nonvat", or "actualstock"
-	Quantity       int                 `json:"quantity"`        // จำนวนที่เพิ่ม/ลด
-	Notes          *string             `json:"notes,omitempty"` // หมายเหตุ
+	AdjustmentType StockAdjustmentType `json:"adjustmentType" validate:"required,oneof=add reduce"`        // "add" or "reduce"
+	StockType      StockType           `json:"stockType" validate:"required,oneof=vat nonvat actualstock"` // "vat", "nonvat", or "actualstock"
+	Quantity       int                 `json:"quantity" validate:"required,min=1"`                         // จำนวนที่เพิ่ม/ลด
+	Notes          *string             `json:"notes,omitempty" validate:"omitempty,max=500"`               // หมายเหตุ
 }
 
 // ToStockAdjustment converts StockAdjustmentRequest to StockAdjustment
@@ -123,3 +133,96 @@ func (sa *StockAdjustment) SetAfterValues(product *Product) {
 	sa.AfterNonVATRemaining = product.Stock.NonVAT.Remaining
 	sa.AfterActualStock = product.Stock.ActualStock
 }
+
+// StockTimelineEntry is one stock movement of a product with the running actual-stock balance
+type StockTimelineEntry struct {
+	Date           time.Time `json:"date"`
+	Action         string    `json:"action"`         // Purchase, Sale, Adjustment, ...
+	Delta          int       `json:"delta"`          // +เพิ่ม / -ลด
+	RunningBalance int       `json:"runningBalance"` // สินค้าคงเหลือหลังรายการนี้
+	StockType      StockType `json:"stockType"`
+	SourceCode     *string   `json:"sourceCode,omitempty"`
+	Notes          *string   `json:"notes,omitempty"`
+	Description    string    `json:"description"` // เช่น "+10 (Purchase PUR-VAT-6701-0001 on 2024-01-15)"
+}
+
+// Stock movement directions
+const (
+	MovementIn  = "in"
+	MovementOut = "out"
+)
+
+// StockMovement is a stock adjustment joined with the sale or purchase it came from
+type StockMovement struct {
+	Date         time.Time  `bson:"date" json:"date"`
+	Type         SourceType `bson:"type" json:"type"` // purchase, sale, adjustment, return, ...
+	Qty          int        `bson:"qty" json:"qty"`
+	Direction    string     `bson:"direction" json:"direction"` // in / out
+	StockType    StockType  `bson:"stockType" json:"stockType"`
+	Reference    *string    `bson:"reference,omitempty" json:"reference,omitempty"`       // เลขที่เอกสารขาย/ซื้อ
+	CustomerName *string    `bson:"customerName,omitempty" json:"customerName,omitempty"` // ลูกค้าหรือผู้ขายในเอกสาร
+	UnitPrice    *float64   `bson:"unitPrice,omitempty" json:"unitPrice,omitempty"`       // ราคาต่อหน่วยหลังส่วนลด ไม่รวม VAT
+	SourceID     *string    `bson:"sourceId,omitempty" json:"sourceId,omitempty"`
+	Notes        *string    `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+// Validate checks the adjustment type, stock type and quantity of a request
+func (req *StockAdjustmentRequest) Validate() error {
+	if req.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	if req.AdjustmentType != AdjustmentTypeAdd && req.AdjustmentType != AdjustmentTypeReduce {
+		return errors.New("invalid adjustment type, must be 'add' or 'reduce'")
+	}
+	if req.StockType != StockTypeVAT && req.StockType != StockTypeNonVAT && req.StockType != StockTypeActualStock {
+		return errors.New("invalid stock type, must be 'vat', 'nonvat' or 'actualstock'")
+	}
+	return nil
+}
+
+// StockTransferRequest moves stock between the VAT and Non-VAT buckets of a product, e.g. when goods bought
+// with VAT are resold through a non-VAT channel; actual stock is unchanged
+type StockTransferRequest struct {
+	FromStockType StockType `json:"fromStockType" validate:"required,oneof=vat nonvat"`
+	ToStockType   StockType `json:"toStockType" validate:"required,oneof=vat nonvat,nefield=FromStockType"`
+	Quantity      int       `json:"quantity" validate:"required,min=1"`
+	Notes         *string   `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+// ToStockAdjustment builds the single history record of a transfer with the product's before values. It is
+// recorded as a reduce of the source bucket, with the destination in ToStockType and both sides in the notes.
+func (req *StockTransferRequest) ToStockAdjustment(product *Product) *StockAdjustment {
+	notes := fmt.Sprintf("โอนสต็อก %d จาก %s ไป %s", req.Quantity, req.FromStockType, req.ToStockType)
+	if req.Notes != nil && *req.Notes != "" {
+		notes += ": " + *req.Notes
+	}
+
+	adjustmentReq := StockAdjustmentRequest{
+		AdjustmentType: AdjustmentTypeReduce,
+		StockType:      req.FromStockType,
+		Quantity:       req.Quantity,
+		Notes:          &notes,
+	}
+	adjustment := adjustmentReq.ToStockAdjustment(product, SourceTypeTransfer, nil, nil)
+	adjustment.ToStockType = req.ToStockType
+	return adjustment
+}
+
+// BulkStockAdjustmentItem is one product's adjustment in a bulk request
+type BulkStockAdjustmentItem struct {
+	ProductID string `json:"productId"` // product ID or SKU ID
+	StockAdjustmentRequest
+}
+
+// BulkStockAdjustmentRequest adjusts several products at once, e.g. after a physical stock count
+type BulkStockAdjustmentRequest struct {
+	Adjustments []BulkStockAdjustmentItem `json:"adjustments"`
+}
+
+// BulkAdjustmentResult reports a bulk adjustment; either every adjustment is applied or none is
+type BulkAdjustmentResult struct {
+	SuccessCount    int        `json:"successCount"`
+	FailedCount     int        `json:"failedCount"`
+	Errors          []string   `json:"errors"`
+	UpdatedProducts []*Product `json:"updatedProducts"`
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Stock count statuses
+const (
+	StockCountStatusDraft      = "draft"       // สร้างแล้ว ยังไม่ได้บันทึกจำนวนที่นับ
+	StockCountStatusInProgress = "in_progress" // กำลังนับ
+	StockCountStatusCompleted  = "completed"   // ปรับสต็อกตามผลต่างแล้ว
+)
+
+// StockCountItem is one product in a physical stock count
+type StockCountItem struct {
+	ProductID   string `bson:"productId" json:"productId"`
+	ProductName string `bson:"productName" json:"productName"`
+	SKUID       string `bson:"skuId" json:"skuId"`
+	SystemQty   int    `bson:"systemQty" json:"systemQty"`                       // actualStock ตอนเริ่มนับ
+	CountedQty  *int   `bson:"countedQty,omitempty" json:"countedQty,omitempty"` // จำนวนที่นับได้ (nil = ยังไม่ได้นับ)
+	Variance    int    `bson:"variance" json:"variance"`                         // countedQty - systemQty
+}
+
+// StockCount is a physical inventory count of every product, reconciled against the system stock when completed
+type StockCount struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CountID     string             `bson:"countId" json:"countId"` // SC-YYMM-XXXX
+	Status      string             `bson:"status" json:"status"`
+	Items       []StockCountItem   `bson:"items" json:"items"`
+	Notes       *string            `bson:"notes,omitempty" json:"notes,omitempty"`
+	StartedAt   time.Time          `bson:"startedAt" json:"startedAt"`
+	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// StockCountRequest is the request body for starting a stock count
+type StockCountRequest struct {
+	Notes *string `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+// StockCountItemRequest records the quantity counted for one product
+type StockCountItemRequest struct {
+	CountedQty *int `json:"countedQty" validate:"required,min=0"`
+}
+
+// StockCountVarianceReport lists the counted products whose counted quantity differs from the system stock
+type StockCountVarianceReport struct {
+	ID            string           `json:"id"`
+	CountID       string           `json:"countId"`
+	Status        string           `json:"status"`
+	TotalItems    int              `json:"totalItems"`
+	CountedItems  int              `json:"countedItems"`
+	TotalVariance int              `json:"totalVariance"` // ผลรวมผลต่าง (บวก = นับได้มากกว่าในระบบ)
+	Items         []StockCountItem `json:"items"`
+}
+
+// NewStockCount starts a draft count with each product's current actual stock as its system quantity
+func NewStockCount(products []*Product, notes *string) *StockCount {
+	now := time.Now()
+	items := make([]StockCountItem, 0, len(products))
+	for _, product := range products {
+		items = append(items, StockCountItem{
+			ProductID:   product.ID.Hex(),
+			ProductName: product.Name,
+			SKUID:       product.SKUID,
+			SystemQty:   product.Stock.ActualStock,
+		})
+	}
+
+	return &StockCount{
+		Status:    StockCountStatusDraft,
+		Items:     items,
+		Notes:     notes,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// RecordCount sets the counted quantity and variance of a product, moving a draft count to in progress.
+// It reports false if the product is not part of the count.
+func (sc *StockCount) RecordCount(productID string, countedQty int) bool {
+	for i := range sc.Items {
+		if sc.Items[i].ProductID != productID {
+			continue
+		}
+		sc.Items[i].CountedQty = &countedQty
+		sc.Items[i].Variance = countedQty - sc.Items[i].SystemQty
+		if sc.Status == StockCountStatusDraft {
+			sc.Status = StockCountStatusInProgress
+		}
+		sc.UpdatedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// VarianceItems returns the counted items with a non-zero variance
+func (sc *StockCount) VarianceItems() []StockCountItem {
+	items := []StockCountItem{}
+	for _, item := range sc.Items {
+		if item.CountedQty != nil && item.Variance != 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// VarianceReport summarises the count and lists its variances
+func (sc *StockCount) VarianceReport() *StockCountVarianceReport {
+	report := &StockCountVarianceReport{
+		ID:         sc.ID.Hex(),
+		CountID:    sc.CountID,
+		Status:     sc.Status,
+		TotalItems: len(sc.Items),
+		Items:      sc.VarianceItems(),
+	}
+	for _, item := range sc.Items {
+		if item.CountedQty != nil {
+			report.CountedItems++
+		}
+	}
+	for _, item := range report.Items {
+		report.TotalVariance += item.Variance
+	}
+	return report
+}
+
+// Complete marks the count as completed
+func (sc *StockCount) Complete() {
+	now := time.Now()
+	sc.Status = StockCountStatusCompleted
+	sc.CompletedAt = &now
+	sc.UpdatedAt = now
+}
+
+// GenerateStockCountID generates the next count ID after lastCountID (SC-YYMM-XXXX, Buddhist year)
+func GenerateStockCountID(lastCountID string) (string, error) {
+	now := time.Now()
+	buddhistYear := now.Year() + 543 // Convert to Buddhist year
+	month := int(now.Month())
+
+	prefix := fmt.Sprintf("SC-%02d%02d-", buddhistYear%100, month) // YYMM
+
+	if lastCountID == "" {
+		return prefix + "0001", nil
+	}
+
+	var lastYear, lastMonth, lastSeq int
+	_, err := fmt.Sscanf(lastCountID, "SC-%02d%02d-%04d", &lastYear, &lastMonth, &lastSeq)
+	if err != nil {
+		return "", fmt.Errorf("invalid last stock count ID format: %w", err)
+	}
+
+	// Restart the sequence every month
+	if lastYear != buddhistYear%100 || lastMonth != month {
+		return prefix + "0001", nil
+	}
+
+	return fmt.Sprintf("%s%04d", prefix, lastSeq+1), nil
+}
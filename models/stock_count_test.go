@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStockCountVarianceReport(t *testing.T) {
+	products := make([]*Product, 3)
+	for i, stock := range []int{10, 5, 7} {
+		products[i] = &Product{ID: primitive.NewObjectID(), Name: fmt.Sprintf("Product %d", i+1)}
+		products[i].Stock.ActualStock = stock
+	}
+	sc := NewStockCount(products, nil)
+	if sc.Status != StockCountStatusDraft || len(sc.Items) != 3 || sc.Items[0].SystemQty != 10 {
+		t.Fatalf("new count = %s with %+v, want a draft of the 3 products' stock", sc.Status, sc.Items)
+	}
+
+	if !sc.RecordCount(products[0].ID.Hex(), 8) || !sc.RecordCount(products[1].ID.Hex(), 9) {
+		t.Fatal("RecordCount did not find a product in the count")
+	}
+	if sc.RecordCount(primitive.NewObjectID().Hex(), 1) {
+		t.Error("RecordCount accepted a product outside the count")
+	}
+	if sc.Status != StockCountStatusInProgress {
+		t.Errorf("status = %s after counting, want %s", sc.Status, StockCountStatusInProgress)
+	}
+	// Counting again replaces the quantity; a product counted at its system quantity has no variance
+	sc.RecordCount(products[2].ID.Hex(), 3)
+	sc.RecordCount(products[2].ID.Hex(), 7)
+
+	report := sc.VarianceReport()
+	if report.TotalItems != 3 || report.CountedItems != 3 || report.TotalVariance != 2 {
+		t.Errorf("report = %d items, %d counted, variance %d, want 3, 3 and 2", report.TotalItems, report.CountedItems, report.TotalVariance)
+	}
+	if len(report.Items) != 2 || report.Items[0].Variance != -2 || report.Items[1].Variance != 4 {
+		t.Errorf("variance items = %+v, want -2 and +4", report.Items)
+	}
+}
+
+func TestGenerateStockCountID(t *testing.T) {
+	now := time.Now()
+	prefix := fmt.Sprintf("SC-%02d%02d-", (now.Year()+543)%100, int(now.Month()))
+
+	tests := []struct {
+		last string
+		want string
+	}{
+		{"", prefix + "0001"},
+		{prefix + "0041", prefix + "0042"},
+		{"SC-0001-0099", prefix + "0001"}, // an earlier month
+	}
+	for _, tt := range tests {
+		got, err := GenerateStockCountID(tt.last)
+		if err != nil || got != tt.want {
+			t.Errorf("GenerateStockCountID(%q) = %q, %v, want %q", tt.last, got, err, tt.want)
+		}
+	}
+	if _, err := GenerateStockCountID("COUNT-1"); err == nil {
+		t.Error("GenerateStockCountID accepted a malformed ID")
+	}
+}
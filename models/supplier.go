@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Supplier struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SupplierCode  string             `bson:"supplierCode" json:"supplierCode"`
+	CompanyName   string             `bson:"companyName" json:"companyName"`
+	ContactName   string             `bson:"contactName" json:"contactName"`
+	TaxID         string             `bson:"taxId" json:"taxId"`
+	Phone         string             `bson:"phone" json:"phone"`
+	Address       string             `bson:"address" json:"address"`
+	ContactMethod string             `bson:"contactMethod" json:"contactMethod"`
+	PaymentTerms  string             `bson:"paymentTerms" json:"paymentTerms"` // เงื่อนไขการชำระเงิน เช่น "30 วัน"
+	LeadTimeDays  int                `bson:"leadTimeDays" json:"leadTimeDays"` // ระยะเวลารอสินค้า (วัน)
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted     bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt     *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type SupplierRequest struct {
+	CompanyName   string `json:"companyName"`
+	ContactName   string `json:"contactName"`
+	TaxID         string `json:"taxId"`
+	Phone         string `json:"phone"`
+	Address       string `json:"address"`
+	ContactMethod string `json:"contactMethod"`
+	PaymentTerms  string `json:"paymentTerms"`
+	LeadTimeDays  int    `json:"leadTimeDays"`
+}
+
+func (sr *SupplierRequest) ToSupplier() *Supplier {
+	now := time.Now()
+	return &Supplier{
+		SupplierCode:  "", // Will be generated by server
+		CompanyName:   sr.CompanyName,
+		ContactName:   sr.ContactName,
+		TaxID:         sr.TaxID,
+		Phone:         sr.Phone,
+		Address:       sr.Address,
+		ContactMethod: sr.ContactMethod,
+		PaymentTerms:  sr.PaymentTerms,
+		LeadTimeDays:  sr.LeadTimeDays,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+func (s *Supplier) UpdateFromRequest(sr *SupplierRequest) {
+	s.CompanyName = sr.CompanyName
+	s.ContactName = sr.ContactName
+	s.TaxID = sr.TaxID
+	s.Phone = sr.Phone
+	s.Address = sr.Address
+	s.ContactMethod = sr.ContactMethod
+	s.PaymentTerms = sr.PaymentTerms
+	s.LeadTimeDays = sr.LeadTimeDays
+	s.UpdatedAt = time.Now()
+}
+
+// GetDisplayName returns the company name, falling back to the contact name
+func (s *Supplier) GetDisplayName() string {
+	if s.CompanyName != "" {
+		return s.CompanyName
+	}
+	return s.ContactName
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Supplier invoice statuses
+const (
+	SupplierInvoiceStatusUnmatched        = "unmatched"
+	SupplierInvoiceStatusPartiallyMatched = "partially_matched"
+	SupplierInvoiceStatusMatched          = "matched"
+)
+
+// ErrInvoiceOverMatched is returned by SupplierInvoice.Match when the matched purchases add up to more than the
+// invoice total plus the tolerance
+var ErrInvoiceOverMatched = errors.New("matched purchases exceed the invoice total")
+
+// SupplierInvoice is an invoice received from a supplier, reconciled against the purchases it bills for
+type SupplierInvoice struct {
+	ID                 primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	InvoiceNumber      string                `bson:"invoiceNumber" json:"invoiceNumber"` // เลขที่ใบแจ้งหนี้ของผู้จำหน่าย
+	SupplierID         string                `bson:"supplierId" json:"supplierId"`
+	SupplierName       string                `bson:"supplierName" json:"supplierName"`
+	InvoiceDate        time.Time             `bson:"invoiceDate" json:"invoiceDate"`
+	DueDate            *time.Time            `bson:"dueDate,omitempty" json:"dueDate,omitempty"` // วันครบกำหนดชำระ
+	LineItems          []SupplierInvoiceLine `bson:"lineItems" json:"lineItems"`
+	TotalAmount        float64               `bson:"totalAmount" json:"totalAmount"`               // ยอดรวมตามใบแจ้งหนี้ (รวม VAT)
+	MatchedPurchaseIDs []string              `bson:"matchedPurchaseIds" json:"matchedPurchaseIds"` // รายการซื้อที่จับคู่แล้ว
+	MatchedAmount      float64               `bson:"matchedAmount" json:"matchedAmount"`           // ยอดรวมของรายการซื้อที่จับคู่
+	Status             string                `bson:"status" json:"status"`                         // unmatched, partially_matched, matched
+	Notes              *string               `bson:"notes,omitempty" json:"notes,omitempty"`
+	CreatedAt          time.Time             `bson:"createdAt" json:"createdAt"`
+	UpdatedAt          time.Time             `bson:"updatedAt" json:"updatedAt"`
+}
+
+// SupplierInvoiceLine is one line as printed on the supplier's invoice
+type SupplierInvoiceLine struct {
+	Description string  `bson:"description" json:"description" validate:"required,max=500"`
+	ProductID   *string `bson:"productId,omitempty" json:"productId,omitempty"`
+	Quantity    float64 `bson:"quantity" json:"quantity" validate:"gt=0"`
+	UnitPrice   float64 `bson:"unitPrice" json:"unitPrice" validate:"min=0"`
+	TotalPrice  float64 `bson:"totalPrice" json:"totalPrice"`
+}
+
+type SupplierInvoiceRequest struct {
+	InvoiceNumber string                `json:"invoiceNumber" validate:"required,max=100"`
+	SupplierID    string                `json:"supplierId" validate:"required"`
+	InvoiceDate   CustomTime            `json:"invoiceDate"`
+	DueDate       *CustomTime           `json:"dueDate,omitempty"`
+	LineItems     []SupplierInvoiceLine `json:"lineItems" validate:"dive"`
+	TotalAmount   float64               `json:"totalAmount" validate:"min=0"` // 0 = sum of the line items
+	Notes         *string               `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+// SupplierInvoiceMatchRequest links purchases to a supplier invoice
+type SupplierInvoiceMatchRequest struct {
+	PurchaseIDs []string `json:"purchaseIds" validate:"required,min=1,max=100,dive,required"`
+}
+
+func (sr *SupplierInvoiceRequest) ToSupplierInvoice() *SupplierInvoice {
+	now := time.Now()
+	lineItems := make([]SupplierInvoiceLine, len(sr.LineItems))
+	var linesTotal float64
+	for i, line := range sr.LineItems {
+		line.TotalPrice = roundMoney(line.Quantity * line.UnitPrice)
+		linesTotal += line.TotalPrice
+		lineItems[i] = line
+	}
+
+	totalAmount := sr.TotalAmount
+	if totalAmount == 0 {
+		totalAmount = roundMoney(linesTotal)
+	}
+
+	invoice := &SupplierInvoice{
+		InvoiceNumber:      sr.InvoiceNumber,
+		SupplierID:         sr.SupplierID,
+		InvoiceDate:        sr.InvoiceDate.Time,
+		LineItems:          lineItems,
+		TotalAmount:        totalAmount,
+		MatchedPurchaseIDs: []string{},
+		Status:             SupplierInvoiceStatusUnmatched,
+		Notes:              sr.Notes,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if sr.DueDate != nil {
+		invoice.DueDate = &sr.DueDate.Time
+	}
+	return invoice
+}
+
+// Match sets the purchases linked to the invoice, those linked before included, and updates the status: matched
+// when their grand totals are within tolerancePercent of the invoice total, partially matched when they are less.
+// If they come to more, the invoice is left unchanged and ErrInvoiceOverMatched is returned.
+func (i *SupplierInvoice) Match(purchases []*Purchase, tolerancePercent float64) error {
+	matchedIDs := make([]string, 0, len(purchases))
+	var matchedAmount float64
+	for _, purchase := range purchases {
+		matchedIDs = append(matchedIDs, purchase.ID.Hex())
+		matchedAmount += purchase.GrandTotal
+	}
+	matchedAmount = roundMoney(matchedAmount)
+
+	tolerance := i.TotalAmount * tolerancePercent / 100
+	if matchedAmount > i.TotalAmount+tolerance {
+		return ErrInvoiceOverMatched
+	}
+
+	i.MatchedPurchaseIDs = matchedIDs
+	i.MatchedAmount = matchedAmount
+	i.Status = invoiceMatchStatus(i.TotalAmount, matchedAmount, tolerancePercent)
+	i.UpdatedAt = time.Now()
+	return nil
+}
+
+// invoiceMatchStatus is the status of an invoice of total with purchases of matchedAmount linked to it
+func invoiceMatchStatus(total, matchedAmount, tolerancePercent float64) string {
+	switch {
+	case matchedAmount == 0:
+		return SupplierInvoiceStatusUnmatched
+	case math.Abs(total-matchedAmount) <= total*tolerancePercent/100:
+		return SupplierInvoiceStatusMatched
+	default:
+		return SupplierInvoiceStatusPartiallyMatched
+	}
+}
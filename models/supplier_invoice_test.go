@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// invoicePurchases returns purchases with the given grand totals
+func invoicePurchases(grandTotals ...float64) []*Purchase {
+	purchases := make([]*Purchase, len(grandTotals))
+	for i, total := range grandTotals {
+		purchases[i] = &Purchase{ID: primitive.NewObjectID(), GrandTotal: total}
+	}
+	return purchases
+}
+
+func TestSupplierInvoiceMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		grandTotals []float64
+		want        string
+	}{
+		{"exact", []float64{600, 400}, SupplierInvoiceStatusMatched},
+		{"under within tolerance", []float64{990}, SupplierInvoiceStatusMatched},
+		{"over within tolerance", []float64{500, 510}, SupplierInvoiceStatusMatched},
+		{"under the tolerance", []float64{989.99}, SupplierInvoiceStatusPartiallyMatched},
+		{"nothing", nil, SupplierInvoiceStatusUnmatched},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &SupplierInvoice{TotalAmount: 1000, Status: SupplierInvoiceStatusUnmatched}
+			purchases := invoicePurchases(tt.grandTotals...)
+			if err := invoice.Match(purchases, 1); err != nil {
+				t.Fatal(err)
+			}
+			if invoice.Status != tt.want {
+				t.Errorf("Status = %s, want %s", invoice.Status, tt.want)
+			}
+			if len(invoice.MatchedPurchaseIDs) != len(purchases) {
+				t.Errorf("MatchedPurchaseIDs = %v, want %d purchases", invoice.MatchedPurchaseIDs, len(purchases))
+			}
+		})
+	}
+}
+
+func TestSupplierInvoiceMatchRejectsOverMatch(t *testing.T) {
+	invoice := &SupplierInvoice{TotalAmount: 1000, Status: SupplierInvoiceStatusPartiallyMatched, MatchedPurchaseIDs: []string{"p1"}, MatchedAmount: 600}
+
+	err := invoice.Match(invoicePurchases(600, 410.01), 1)
+	if !errors.Is(err, ErrInvoiceOverMatched) {
+		t.Fatalf("err = %v, want ErrInvoiceOverMatched", err)
+	}
+	if invoice.Status != SupplierInvoiceStatusPartiallyMatched || len(invoice.MatchedPurchaseIDs) != 1 || invoice.MatchedAmount != 600 {
+		t.Errorf("invoice = %+v, want it unchanged", invoice)
+	}
+
+	// Without a tolerance only an exact total matches
+	if err := invoice.Match(invoicePurchases(1000.01), 0); !errors.Is(err, ErrInvoiceOverMatched) {
+		t.Errorf("err = %v with no tolerance, want ErrInvoiceOverMatched", err)
+	}
+}
+
+func TestSupplierInvoiceRequestTotals(t *testing.T) {
+	req := &SupplierInvoiceRequest{
+		InvoiceNumber: "INV-001",
+		SupplierID:    "s1",
+		LineItems: []SupplierInvoiceLine{
+			{Description: "Kraft Box", Quantity: 3, UnitPrice: 33.335},
+			{Description: "Bubble Wrap", Quantity: 2, UnitPrice: 50},
+		},
+	}
+	invoice := req.ToSupplierInvoice()
+	if invoice.LineItems[0].TotalPrice != 100.01 || invoice.TotalAmount != 200.01 {
+		t.Errorf("line total %v, invoice total %v, want 100.01 and 200.01", invoice.LineItems[0].TotalPrice, invoice.TotalAmount)
+	}
+	if invoice.Status != SupplierInvoiceStatusUnmatched || invoice.MatchedPurchaseIDs == nil {
+		t.Errorf("invoice = %+v, want unmatched with an empty purchase list", invoice)
+	}
+
+	// A total printed on the invoice, e.g. with VAT, is kept
+	req.TotalAmount = 214.01
+	if invoice := req.ToSupplierInvoice(); invoice.TotalAmount != 214.01 {
+		t.Errorf("TotalAmount = %v, want 214.01", invoice.TotalAmount)
+	}
+}
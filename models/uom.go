@@ -0,0 +1,35 @@
+package models
+
+import "math"
+
+// UnitsPerUOM returns how many base units make up one of the product's UOM; an unset factor counts as 1
+func (p *Product) UnitsPerUOM() float64 {
+	if p.ConversionFactor <= 0 {
+		return 1
+	}
+	return p.ConversionFactor
+}
+
+// ToDisplayQuantity converts a quantity in base units into the product's UOM
+func (p *Product) ToDisplayQuantity(quantity float64) float64 {
+	return math.Round(quantity/p.UnitsPerUOM()*1000) / 1000
+}
+
+// ApplyUOM sets the display quantity and unit of a sale item for products sold in packs, boxes, etc.
+// Quantity stays in base units and is what stock is cut by.
+func (i *SaleItem) ApplyUOM(product *Product) {
+	i.DisplayQuantity, i.DisplayUOM = 0, ""
+	if product.UnitsPerUOM() != 1 {
+		i.DisplayQuantity = product.ToDisplayQuantity(i.Quantity)
+		i.DisplayUOM = product.UOM
+	}
+}
+
+// ApplyUOM sets the display quantity and unit of a purchase item for products bought in packs, boxes, etc.
+func (i *PurchaseItem) ApplyUOM(product *Product) {
+	i.DisplayQuantity, i.DisplayUOM = 0, ""
+	if product.UnitsPerUOM() != 1 {
+		i.DisplayQuantity = product.ToDisplayQuantity(float64(i.Quantity))
+		i.DisplayUOM = product.UOM
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestSaleItemApplyUOM(t *testing.T) {
+	box := &Product{UOM: "box", ConversionFactor: 12}
+	item := SaleItem{Quantity: 18}
+	item.ApplyUOM(box)
+	if item.DisplayQuantity != 1.5 || item.DisplayUOM != "box" {
+		t.Errorf("18 pcs in boxes of 12: got %v %s, want 1.5 box", item.DisplayQuantity, item.DisplayUOM)
+	}
+	if item.StockQuantity() != 18 {
+		t.Errorf("StockQuantity = %d, want the 18 base units", item.StockQuantity())
+	}
+
+	piece := &Product{UOM: "pcs", ConversionFactor: 1}
+	item.ApplyUOM(piece)
+	if item.DisplayQuantity != 0 || item.DisplayUOM != "" {
+		t.Errorf("product sold by the piece: got %v %s, want no display quantity", item.DisplayQuantity, item.DisplayUOM)
+	}
+}
+
+func TestPurchaseItemApplyUOM(t *testing.T) {
+	metre := &Product{UOM: "m", ConversionFactor: 100} // stocked in cm
+	item := PurchaseItem{Quantity: 250}
+	item.ApplyUOM(metre)
+	if item.DisplayQuantity != 2.5 || item.DisplayUOM != "m" {
+		t.Errorf("250 cm in metres: got %v %s, want 2.5 m", item.DisplayQuantity, item.DisplayUOM)
+	}
+
+	// An unset conversion factor counts as 1
+	item.ApplyUOM(&Product{UOM: "pcs"})
+	if item.DisplayQuantity != 0 || item.DisplayUOM != "" {
+		t.Errorf("unset conversion factor: got %v %s, want no display quantity", item.DisplayQuantity, item.DisplayUOM)
+	}
+}
+
+func TestToDisplayQuantityRounds(t *testing.T) {
+	product := &Product{ConversionFactor: 3}
+	if got := product.ToDisplayQuantity(1); got != 0.333 {
+		t.Errorf("ToDisplayQuantity(1) = %v, want 0.333", got)
+	}
+}
+
+func TestIsWholeQuantity(t *testing.T) {
+	tests := map[float64]bool{1: true, 250: true, 0.4: false, 2.5: false}
+	for quantity, want := range tests {
+		if got := IsWholeQuantity(quantity); got != want {
+			t.Errorf("IsWholeQuantity(%v) = %t, want %t", quantity, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+// DefaultVATRate is the Thai VAT rate of 7%. Documents saved before the rate was stored on them were taxed at it.
+const DefaultVATRate = 0.07
+
+// VATRate is the rate stored on new sales, purchases and quotations; it is set from config.VATRate at startup.
+// Existing documents keep the rate they were created with.
+var VATRate = DefaultVATRate
+
+// storedVATRate is the rate stored on a document, or DefaultVATRate for documents saved before rates were stored
+func storedVATRate(rate float64) float64 {
+	if rate <= 0 {
+		return DefaultVATRate
+	}
+	return rate
+}
+
+// calculateVAT is the VAT on amount at rate, or 0 for non-VAT documents
+func calculateVAT(amount float64, isVAT bool, rate float64) float64 {
+	if !isVAT {
+		return 0
+	}
+	return amount * rate
+}
+
+// EffectiveVATRate is the VAT rate the sale was created with
+func (s *Sale) EffectiveVATRate() float64 {
+	return storedVATRate(s.VATRate)
+}
+
+// EffectiveVATRate is the VAT rate the purchase was created with
+func (p *Purchase) EffectiveVATRate() float64 {
+	return storedVATRate(p.VATRate)
+}
+
+// EffectiveVATRate is the VAT rate the quotation was created with
+func (q *Quotation) EffectiveVATRate() float64 {
+	return storedVATRate(q.VATRate)
+}
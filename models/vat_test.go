@@ -0,0 +1,32 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSaleKeepsItsVATRate(t *testing.T) {
+	defer func(rate float64) { VATRate = rate }(VATRate)
+
+	VATRate = 0.10
+	sale := (&SaleRequest{IsVAT: true, Items: []SaleItem{{Quantity: 1, UnitPrice: 100}}}).ToSale()
+	if sale.VATRate != 0.10 {
+		t.Fatalf("stored rate = %v, want the configured 0.10", sale.VATRate)
+	}
+
+	// A later rate change does not reprice the sale
+	VATRate = 0.07
+	if got := sale.CalculateGrandTotal(); math.Abs(got-110) > 1e-9 {
+		t.Errorf("grand total = %v, want 110 at the stored 10%%", got)
+	}
+
+	legacy := Sale{IsVAT: true, Items: []SaleItem{{TotalPrice: 100}}}
+	if got := legacy.CalculateGrandTotal(); math.Abs(got-107) > 1e-9 {
+		t.Errorf("grand total without a stored rate = %v, want 107 at the default 7%%", got)
+	}
+
+	nonVAT := Sale{VATRate: 0.10, Items: []SaleItem{{TotalPrice: 100}}, ShippingCost: 20}
+	if got := nonVAT.CalculateGrandTotal(); got != 120 {
+		t.Errorf("non-VAT grand total = %v, want 120", got)
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import "sort"
+
+// Velocity classes, by quantity sold over the last VelocityWindowDays days
+const (
+	VelocityClassA            = "A"            // 20% แรกของสินค้าที่ขายได้มากที่สุด
+	VelocityClassB            = "B"            // 30% ถัดมา
+	VelocityClassC            = "C"            // ที่เหลือ รวมถึงสินค้าที่ขายไม่ได้
+	VelocityClassUnclassified = "unclassified" // ยังไม่ได้จัดกลุ่ม
+)
+
+// VelocityClasses are the classes products can be filtered by
+var VelocityClasses = []string{VelocityClassA, VelocityClassB, VelocityClassC, VelocityClassUnclassified}
+
+// VelocityWindowDays is how many days of sales the velocity classification looks at
+const VelocityWindowDays = 90
+
+// Share of the ranked products in class A, and in classes A and B together
+const (
+	velocityClassAPercent  = 20
+	velocityClassABPercent = 50
+)
+
+// VelocityReclassifyResult is the outcome of a velocity classification run
+type VelocityReclassifyResult struct {
+	Classified int            `json:"classified"` // จำนวนสินค้าที่จัดกลุ่ม
+	Changed    int64          `json:"changed"`    // จำนวนสินค้าที่กลุ่มเปลี่ยน
+	Summary    map[string]int `json:"summary"`    // จำนวนสินค้าในแต่ละกลุ่ม
+}
+
+// ClassifyVelocity ranks the products by the units sold of each (products missing from unitsSold sold none) and
+// assigns them a class: the top 20% of the products are A, the next 30% B and the rest C. Products that sold
+// nothing are always C, and products that sold the same quantity get the same class as the first of them.
+func ClassifyVelocity(productIDs []string, unitsSold map[string]int) map[string]string {
+	ranked := make([]string, len(productIDs))
+	copy(ranked, productIDs)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return unitsSold[ranked[i]] > unitsSold[ranked[j]]
+	})
+
+	n := len(ranked)
+	classes := make(map[string]string, n)
+	for i, productID := range ranked {
+		sold := unitsSold[productID]
+		switch {
+		case sold <= 0:
+			classes[productID] = VelocityClassC
+		case i > 0 && sold == unitsSold[ranked[i-1]]:
+			classes[productID] = classes[ranked[i-1]]
+		case i*100 < velocityClassAPercent*n:
+			classes[productID] = VelocityClassA
+		case i*100 < velocityClassABPercent*n:
+			classes[productID] = VelocityClassB
+		default:
+			classes[productID] = VelocityClassC
+		}
+	}
+	return classes
+}
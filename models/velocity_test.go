@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestClassifyVelocity(t *testing.T) {
+	productIDs := []string{"p0", "p1", "p2", "p3", "p4", "p5", "p6", "p7", "p8", "p9"}
+	unitsSold := map[string]int{
+		"p0": 100, "p1": 90, "p2": 90, // p2 ties with p1 so shares its class
+		"p3": 70, "p4": 60, "p5": 50, "p6": 40, "p7": 30,
+	}
+	classes := ClassifyVelocity(productIDs, unitsSold)
+
+	want := map[string]string{
+		"p0": VelocityClassA, "p1": VelocityClassA, "p2": VelocityClassA,
+		"p3": VelocityClassB, "p4": VelocityClassB,
+		"p5": VelocityClassC, "p6": VelocityClassC, "p7": VelocityClassC,
+		"p8": VelocityClassC, "p9": VelocityClassC, // no sales
+	}
+	for productID, class := range want {
+		if classes[productID] != class {
+			t.Errorf("%s (%d sold): class %s, want %s", productID, unitsSold[productID], classes[productID], class)
+		}
+	}
+}
+
+func TestClassifyVelocityWithoutSales(t *testing.T) {
+	classes := ClassifyVelocity([]string{"p0", "p1"}, nil)
+	for productID, class := range classes {
+		if class != VelocityClassC {
+			t.Errorf("%s without sales: class %s, want C", productID, class)
+		}
+	}
+	if len(classes) != 2 {
+		t.Errorf("classified %d products, want 2", len(classes))
+	}
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrReceiptEmpty       = errors.New("receipt has no items")
+	ErrReceiptQuantity    = errors.New("received quantity must be greater than zero")
+	ErrReceiptProduct     = errors.New("product is not part of this purchase")
+	ErrReceiptOverOrdered = errors.New("received quantity exceeds the quantity still outstanding")
+)
+
+// WarehouseReceipt is one delivery received into the warehouse against a purchase
+type WarehouseReceipt struct {
+	ReceivedAt time.Time              `bson:"receivedAt" json:"receivedAt"`
+	ReceivedBy *string                `bson:"receivedBy,omitempty" json:"receivedBy,omitempty"`
+	Items      []WarehouseReceiptItem `bson:"items" json:"items"`
+}
+
+type WarehouseReceiptItem struct {
+	ProductID   string  `bson:"productId" json:"productId"`
+	ProductName string  `bson:"productName" json:"productName"`
+	OrderedQty  int     `bson:"orderedQty" json:"orderedQty"`   // จำนวนที่สั่งซื้อ
+	ReceivedQty int     `bson:"receivedQty" json:"receivedQty"` // จำนวนที่รับในครั้งนี้
+	Notes       *string `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+type WarehouseReceiptRequest struct {
+	ReceivedAt *time.Time             `json:"receivedAt,omitempty"`
+	ReceivedBy *string                `json:"receivedBy,omitempty"`
+	Items      []WarehouseReceiptItem `json:"items"`
+}
+
+// OrderedQuantity returns the total quantity ordered for a product
+func (p *Purchase) OrderedQuantity(productID string) int {
+	total := 0
+	for _, item := range p.Items {
+		if item.ProductID == productID {
+			total += item.Quantity
+		}
+	}
+	return total
+}
+
+// ReceivedQuantity returns the total quantity of a product received so far
+func (p *Purchase) ReceivedQuantity(productID string) int {
+	total := 0
+	for _, receipt := range p.Warehouse.Receipts {
+		for _, item := range receipt.Items {
+			if item.ProductID == productID {
+				total += item.ReceivedQty
+			}
+		}
+	}
+	return total
+}
+
+// StockedQuantities returns the quantity of each product the purchase has added to stock: what has been
+// received, or the ordered quantities for a purchase saved before receipts added stock. A purchase that is not in
+// effect has added none.
+func (p *Purchase) StockedQuantities() map[string]int {
+	quantities := make(map[string]int)
+	if !p.AddsStock() {
+		return quantities
+	}
+	for _, item := range p.Items {
+		if p.StockOnReceipt {
+			quantities[item.ProductID] = p.ReceivedQuantity(item.ProductID)
+		} else {
+			quantities[item.ProductID] += item.Quantity
+		}
+	}
+	return quantities
+}
+
+// IsFullyReceived reports whether every ordered item has been received in full
+func (p *Purchase) IsFullyReceived() bool {
+	for _, item := range p.Items {
+		if p.ReceivedQuantity(item.ProductID) < p.OrderedQuantity(item.ProductID) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddReceipt validates a receipt against the outstanding quantities, fills in product names and
+// ordered quantities, appends it and marks the warehouse as updated once everything has arrived
+func (p *Purchase) AddReceipt(receipt WarehouseReceipt) (*WarehouseReceipt, error) {
+	if len(receipt.Items) == 0 {
+		return nil, ErrReceiptEmpty
+	}
+
+	receiving := make(map[string]int)
+	for i := range receipt.Items {
+		item := &receipt.Items[i]
+		if item.ReceivedQty <= 0 {
+			return nil, ErrReceiptQuantity
+		}
+		ordered := p.OrderedQuantity(item.ProductID)
+		if ordered == 0 {
+			return nil, ErrReceiptProduct
+		}
+		receiving[item.ProductID] += item.ReceivedQty
+		if p.ReceivedQuantity(item.ProductID)+receiving[item.ProductID] > ordered {
+			return nil, ErrReceiptOverOrdered
+		}
+
+		item.OrderedQty = ordered
+		for _, purchaseItem := range p.Items {
+			if purchaseItem.ProductID == item.ProductID {
+				item.ProductName = purchaseItem.ProductName
+				break
+			}
+		}
+	}
+
+	if receipt.ReceivedAt.IsZero() {
+		receipt.ReceivedAt = time.Now()
+	}
+	p.Warehouse.Receipts = append(p.Warehouse.Receipts, receipt)
+	p.Warehouse.IsUpdated = p.IsFullyReceived()
+	p.UpdatedAt = time.Now()
+	return &p.Warehouse.Receipts[len(p.Warehouse.Receipts)-1], nil
+}
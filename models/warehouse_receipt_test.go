@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func receivingPurchase() *Purchase {
+	return &Purchase{
+		StockOnReceipt: true,
+		Items: []PurchaseItem{
+			{ProductID: "p1", ProductName: "Kraft Box", Quantity: 100},
+			{ProductID: "p2", ProductName: "Tape", Quantity: 20},
+		},
+	}
+}
+
+func TestAddReceiptFullReceipt(t *testing.T) {
+	purchase := receivingPurchase()
+
+	added, err := purchase.AddReceipt(WarehouseReceipt{Items: []WarehouseReceiptItem{
+		{ProductID: "p1", ReceivedQty: 100},
+		{ProductID: "p2", ReceivedQty: 20},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Items[0].ProductName != "Kraft Box" || added.Items[0].OrderedQty != 100 || added.ReceivedAt.IsZero() {
+		t.Errorf("receipt item = %+v, received at %v; want the product name, ordered quantity and a receipt time", added.Items[0], added.ReceivedAt)
+	}
+	if !purchase.IsFullyReceived() || !purchase.Warehouse.IsUpdated {
+		t.Error("want the purchase fully received and the warehouse updated")
+	}
+}
+
+func TestAddReceiptTwoPartialReceipts(t *testing.T) {
+	purchase := receivingPurchase()
+
+	if _, err := purchase.AddReceipt(WarehouseReceipt{Items: []WarehouseReceiptItem{
+		{ProductID: "p1", ReceivedQty: 60},
+		{ProductID: "p2", ReceivedQty: 20},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if purchase.Warehouse.IsUpdated {
+		t.Error("after the first receipt: want the warehouse not yet updated, 40 of p1 are outstanding")
+	}
+
+	added, err := purchase.AddReceipt(WarehouseReceipt{Items: []WarehouseReceiptItem{{ProductID: "p1", ReceivedQty: 40}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Items[0].ReceivedQty != 40 {
+		t.Errorf("second receipt received %d, want only the 40 of this delivery", added.Items[0].ReceivedQty)
+	}
+	if got := purchase.ReceivedQuantity("p1"); got != 100 {
+		t.Errorf("received %d of p1, want 100", got)
+	}
+	if len(purchase.Warehouse.Receipts) != 2 || !purchase.Warehouse.IsUpdated {
+		t.Errorf("%d receipts, warehouse updated %t; want 2 and true", len(purchase.Warehouse.Receipts), purchase.Warehouse.IsUpdated)
+	}
+}
+
+func TestAddReceiptRejectsOverReceipt(t *testing.T) {
+	purchase := receivingPurchase()
+	if _, err := purchase.AddReceipt(WarehouseReceipt{Items: []WarehouseReceiptItem{{ProductID: "p1", ReceivedQty: 90}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		items []WarehouseReceiptItem
+		want  error
+	}{
+		{"more than outstanding", []WarehouseReceiptItem{{ProductID: "p1", ReceivedQty: 11}}, ErrReceiptOverOrdered},
+		{"split over two lines", []WarehouseReceiptItem{{ProductID: "p1", ReceivedQty: 6}, {ProductID: "p1", ReceivedQty: 5}}, ErrReceiptOverOrdered},
+		{"not ordered", []WarehouseReceiptItem{{ProductID: "p3", ReceivedQty: 1}}, ErrReceiptProduct},
+		{"zero quantity", []WarehouseReceiptItem{{ProductID: "p2", ReceivedQty: 0}}, ErrReceiptQuantity},
+		{"no items", nil, ErrReceiptEmpty},
+	}
+	for _, tt := range tests {
+		if _, err := purchase.AddReceipt(WarehouseReceipt{Items: tt.items}); !errors.Is(err, tt.want) {
+			t.Errorf("%s: AddReceipt = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if len(purchase.Warehouse.Receipts) != 1 || purchase.ReceivedQuantity("p1") != 90 {
+		t.Errorf("rejected receipts were recorded: %d receipts, %d of p1 received", len(purchase.Warehouse.Receipts), purchase.ReceivedQuantity("p1"))
+	}
+}
+
+func TestStockedQuantities(t *testing.T) {
+	purchase := receivingPurchase()
+	if _, err := purchase.AddReceipt(WarehouseReceipt{Items: []WarehouseReceiptItem{{ProductID: "p1", ReceivedQty: 60}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := purchase.StockedQuantities(); got["p1"] != 60 || got["p2"] != 0 {
+		t.Errorf("stocked %v, want the 60 received of p1 and none of p2", got)
+	}
+
+	legacy := receivingPurchase()
+	legacy.StockOnReceipt = false
+	if got := legacy.StockedQuantities(); got["p1"] != 100 || got["p2"] != 20 {
+		t.Errorf("legacy purchase stocked %v, want the ordered quantities", got)
+	}
+
+	legacy.IsDraft = true
+	if got := legacy.StockedQuantities(); len(got) != 0 {
+		t.Errorf("draft stocked %v, want nothing", got)
+	}
+}
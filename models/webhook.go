@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook events
+const (
+	WebhookEventSaleCreated     = "sale.created"
+	WebhookEventPurchaseCreated = "purchase.created"
+	WebhookEventStockLow        = "stock.low"
+)
+
+// Webhook is an external URL notified when one of its events happens
+type Webhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL       string             `bson:"url" json:"url"`
+	Events    []string           `bson:"events" json:"events"`
+	Secret    string             `bson:"secret" json:"-"` // ใช้ลงลายเซ็น HMAC-SHA256 ไม่ส่งกลับใน response
+	IsActive  bool               `bson:"isActive" json:"isActive"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	IsDeleted bool               `bson:"isDeleted" json:"isDeleted"`
+	DeletedAt *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type WebhookRequest struct {
+	URL      string   `json:"url" validate:"required,url,max=2000"`
+	Events   []string `json:"events" validate:"required,min=1,dive,oneof=sale.created purchase.created stock.low"`
+	Secret   string   `json:"secret" validate:"max=200"` // ว่างไว้ตอนแก้ไขเพื่อใช้ secret เดิม
+	IsActive *bool    `json:"isActive,omitempty"`        // ค่าเริ่มต้น true
+}
+
+func (wr *WebhookRequest) ToWebhook() *Webhook {
+	now := time.Now()
+	webhook := &Webhook{
+		URL:       wr.URL,
+		Events:    wr.Events,
+		Secret:    wr.Secret,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if wr.IsActive != nil {
+		webhook.IsActive = *wr.IsActive
+	}
+	return webhook
+}
+
+func (wh *Webhook) UpdateFromRequest(wr *WebhookRequest) {
+	wh.URL = wr.URL
+	wh.Events = wr.Events
+	if wr.Secret != "" {
+		wh.Secret = wr.Secret
+	}
+	if wr.IsActive != nil {
+		wh.IsActive = *wr.IsActive
+	}
+	wh.UpdatedAt = time.Now()
+}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID        string      `json:"id"` // เหมือนกันทุกครั้งที่ลองส่งซ้ำ ใช้กันการประมวลผลซ้ำ
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WebhookID  string             `bson:"webhookId" json:"webhookId"`
+	DeliveryID string             `bson:"deliveryId" json:"deliveryId"` // WebhookPayload.ID
+	Event      string             `bson:"event" json:"event"`
+	URL        string             `bson:"url" json:"url"`
+	Attempt    int                `bson:"attempt" json:"attempt"`
+	StatusCode int                `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	Success    bool               `bson:"success" json:"success"`
+	DurationMs int64              `bson:"durationMs" json:"durationMs"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/models"
+)
+
+// aggregateTotals runs a pipeline ending in a single $group stage and decodes it into TransactionTotals.
+// An empty result (no matching documents) yields zero totals.
+func aggregateTotals(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) (*models.TransactionTotals, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals models.TransactionTotals
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&totals); err != nil {
+			return nil, err
+		}
+	}
+
+	return &totals, cursor.Err()
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+// auditCollections maps audited entity types to their MongoDB collections
+var auditCollections = map[models.AuditEntityType]string{
+	models.AuditEntityProduct:   "products",
+	models.AuditEntityCustomer:  "customers",
+	models.AuditEntitySale:      "sales",
+	models.AuditEntityPurchase:  "purchases",
+	models.AuditEntityQuotation: "quotations",
+	models.AuditEntitySupplier:  "suppliers",
+}
+
+type AuditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository(collection *mongo.Collection) *AuditLogRepository {
+	return &AuditLogRepository{
+		collection: collection,
+	}
+}
+
+// Create stores a new audit log entry
+func (r *AuditLogRepository) Create(ctx context.Context, log *models.AuditLog) error {
+	result, err := r.collection.InsertOne(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		log.ID = oid
+	}
+	return nil
+}
+
+// GetAll gets audit logs matching the filter, newest first
+func (r *AuditLogRepository) GetAll(ctx context.Context, filter models.AuditLogFilter, limit, skip int) ([]*models.AuditLog, error) {
+	query := bson.M{}
+	if filter.EntityType != "" {
+		query["entityType"] = filter.EntityType
+	}
+	if filter.EntityID != "" {
+		query["entityId"] = filter.EntityID
+	}
+	if !filter.StartDate.IsZero() || !filter.EndDate.IsZero() {
+		dateRange := bson.M{}
+		if !filter.StartDate.IsZero() {
+			dateRange["$gte"] = filter.StartDate
+		}
+		if !filter.EndDate.IsZero() {
+			dateRange["$lte"] = filter.EndDate
+		}
+		query["createdAt"] = dateRange
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.M{"createdAt": -1})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if skip > 0 {
+		opts.SetSkip(int64(skip))
+	}
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var logs []*models.AuditLog
+	for cursor.Next(ctx) {
+		var log models.AuditLog
+		if err := cursor.Decode(&log); err != nil {
+			continue
+		}
+		logs = append(logs, &log)
+	}
+
+	return logs, cursor.Err()
+}
+
+// Snapshot reads the current state of an audited entity as raw BSON
+func (r *AuditLogRepository) Snapshot(ctx context.Context, entityType models.AuditEntityType, id string) (bson.Raw, error) {
+	collectionName, ok := auditCollections[entityType]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit entity type: %s", entityType)
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.collection.Database().Collection(collectionName).FindOne(ctx, bson.M{"_id": objectID}).Raw()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type BudgetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBudgetRepository(collection *mongo.Collection) *BudgetRepository {
+	return &BudgetRepository{
+		collection: collection,
+	}
+}
+
+func (r *BudgetRepository) Create(ctx context.Context, budget *models.Budget) error {
+	result, err := r.collection.InsertOne(ctx, budget)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		budget.ID = oid
+	}
+	return nil
+}
+
+func (r *BudgetRepository) GetByID(ctx context.Context, id string) (*models.Budget, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var budget models.Budget
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&budget)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &budget, nil
+}
+
+// GetAll gets the budgets, newest period first; a non-empty period (YYYY-MM) limits them to that month
+func (r *BudgetRepository) GetAll(ctx context.Context, period string) ([]*models.Budget, error) {
+	filter := bson.M{}
+	if period != "" {
+		filter["period"] = period
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "period", Value: -1}, {Key: "createdAt", Value: 1}})
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	budgets := []*models.Budget{}
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (r *BudgetRepository) Update(ctx context.Context, id string, budget *models.Budget) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, budget)
+	return err
+}
+
+func (r *BudgetRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type BundleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBundleRepository(collection *mongo.Collection) *BundleRepository {
+	return &BundleRepository{
+		collection: collection,
+	}
+}
+
+func (r *BundleRepository) Create(ctx context.Context, bundle *models.ProductBundle) error {
+	result, err := r.collection.InsertOne(ctx, bundle)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		bundle.ID = oid
+	}
+	return nil
+}
+
+func (r *BundleRepository) GetByID(ctx context.Context, id string) (*models.ProductBundle, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle models.ProductBundle
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&bundle)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &bundle, nil
+}
+
+// GetAll gets the bundles sorted by name
+func (r *BundleRepository) GetAll(ctx context.Context) ([]*models.ProductBundle, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	bundles := []*models.ProductBundle{}
+	if err := cursor.All(ctx, &bundles); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+func (r *BundleRepository) Update(ctx context.Context, id string, bundle *models.ProductBundle) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, bundle)
+	return err
+}
+
+func (r *BundleRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
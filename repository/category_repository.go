@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/config"
+	"goodpack-server/models"
+)
+
+type CategoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCategoryRepository(collection *mongo.Collection) *CategoryRepository {
+	return &CategoryRepository{
+		collection: collection,
+	}
+}
+
+func (r *CategoryRepository) Create(ctx context.Context, category *models.Category) error {
+	result, err := r.collection.InsertOne(ctx, category)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		category.ID = oid
+	}
+	return nil
+}
+
+func (r *CategoryRepository) GetByID(ctx context.Context, id string) (*models.Category, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var category models.Category
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&category)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &category, nil
+}
+
+// GetAll gets the categories in the order they were added
+func (r *CategoryRepository) GetAll(ctx context.Context) ([]*models.Category, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []*models.Category{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) Update(ctx context.Context, id string, category *models.Category) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, category)
+	return err
+}
+
+func (r *CategoryRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
+
+// ConfigCategories returns the categories as config items, so the config loader can read them from here
+func (r *CategoryRepository) ConfigCategories(ctx context.Context) ([]config.CategoryItem, error) {
+	categories, err := r.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]config.CategoryItem, len(categories))
+	for i, category := range categories {
+		items[i] = config.CategoryItem{
+			Name:         category.Name,
+			Abbreviation: category.Abbreviation,
+			English:      category.English,
+			IsActive:     category.IsActive,
+		}
+	}
+	return items, nil
+}
+
+// SeedFromConfig copies the config file categories into the collection when it has never held any, and
+// reports how many were added
+func (r *CategoryRepository) SeedFromConfig(ctx context.Context, items []config.CategoryItem) (int, error) {
+	// Deleted categories count, so a collection emptied on purpose is not filled again
+	count, err := r.collection.CountDocuments(ctx, bson.M{})
+	if err != nil || count > 0 || len(items) == 0 {
+		return 0, err
+	}
+
+	now := time.Now()
+	documents := make([]interface{}, len(items))
+	for i, item := range items {
+		documents[i] = models.Category{
+			Name:         item.Name,
+			Abbreviation: item.Abbreviation,
+			English:      item.English,
+			IsActive:     item.IsActive,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+	}
+	// Ordered, so GetAll keeps the order of categories.json
+	if _, err := r.collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(true)); err != nil {
+		return 0, err
+	}
+	return len(documents), nil
+}
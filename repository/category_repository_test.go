@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/config"
+	"goodpack-server/models"
+	"goodpack-server/utils"
+)
+
+func TestCategoryCRUD(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewCategoryRepository(db.Collection("categories"))
+
+	request := &models.CategoryRequest{Name: " ถาด ", Abbreviation: "tr", English: "tray"}
+	request.Normalize()
+	category := request.ToCategory()
+	if err := repo.Create(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	id := category.ID.Hex()
+
+	stored, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "ถาด" || stored.Abbreviation != "TR" || !stored.IsActive {
+		t.Errorf("stored = %+v, want an active ถาด abbreviated TR", stored)
+	}
+
+	inactive := false
+	stored.UpdateFromRequest(&models.CategoryRequest{Name: "ถาด", Abbreviation: "TRY", English: "tray", IsActive: &inactive})
+	if err := repo.Update(ctx, id, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err = repo.GetByID(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Abbreviation != "TRY" || stored.IsActive {
+		t.Errorf("updated = %+v, want an inactive category abbreviated TRY", stored)
+	}
+
+	if err := repo.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, id); !errors.Is(err, apierrors.ErrNotFound) {
+		t.Errorf("GetByID after delete: err = %v, want not found", err)
+	}
+	if all, err := repo.GetAll(ctx); err != nil || len(all) != 0 {
+		t.Errorf("GetAll after delete = %v, %v, want no categories", all, err)
+	}
+}
+
+func TestSeedFromConfig(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewCategoryRepository(db.Collection("categories"))
+
+	items := []config.CategoryItem{
+		{Name: "ขวด", Abbreviation: "BT", English: "bottle", IsActive: true},
+		{Name: "ฝา", Abbreviation: "CP", English: "cap"},
+	}
+	if seeded, err := repo.SeedFromConfig(ctx, items); err != nil || seeded != 2 {
+		t.Fatalf("SeedFromConfig() = %d, %v, want 2 categories", seeded, err)
+	}
+	got, err := repo.ConfigCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, items) {
+		t.Errorf("categories = %+v, want %+v in file order", got, items)
+	}
+
+	// Seeding again leaves the collection alone
+	if seeded, err := repo.SeedFromConfig(ctx, items); err != nil || seeded != 0 {
+		t.Errorf("second SeedFromConfig() = %d, %v, want nothing added", seeded, err)
+	}
+}
+
+func TestSKUGeneratorPicksUpAddedCategories(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewCategoryRepository(db.Collection("categories"))
+
+	loader := config.NewConfigLoader()
+	loader.SetCategorySource(repo)
+	generator, err := utils.NewSKUGeneratorWithConfig(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &models.CategoryRequest{Name: "ถาด", Abbreviation: "TR", English: "tray"}
+	if err := repo.Create(ctx, request.ToCategory()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ถาด", "Tray"} {
+		if got := generator.GenerateSKUID(name, 0); got != "TR-0001" {
+			t.Errorf("GenerateSKUID(%q, 0) = %s, want TR-0001 from the added category", name, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type CustomerNoteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCustomerNoteRepository(collection *mongo.Collection) *CustomerNoteRepository {
+	return &CustomerNoteRepository{
+		collection: collection,
+	}
+}
+
+func (r *CustomerNoteRepository) Create(ctx context.Context, note *models.CustomerNote) error {
+	result, err := r.collection.InsertOne(ctx, note)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		note.NoteID = oid
+	}
+	return nil
+}
+
+// GetByCustomerID gets a customer's notes, newest first
+func (r *CustomerNoteRepository) GetByCustomerID(ctx context.Context, customerID string, limit, skip int) ([]*models.CustomerNote, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(skip))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"customerId": customerID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	notes := []*models.CustomerNote{}
+	if err := cursor.All(ctx, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// Delete deletes one of a customer's notes
+func (r *CustomerNoteRepository) Delete(ctx context.Context, customerID, noteID string) error {
+	objectID, err := primitive.ObjectIDFromHex(noteID)
+	if err != nil {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID, "customerId": customerID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+	return nil
+}
@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +17,9 @@ import (
 	"goodpack-server/models"
 )
 
+// ErrEmailInUse is returned when a customer is saved with an email another customer, deleted or not, already has
+var ErrEmailInUse = errors.New("email already in use")
+
 type CustomerRepository struct {
 	collection *mongo.Collection
 }
@@ -24,41 +30,36 @@ func NewCustomerRepository(collection *mongo.Collection) *CustomerRepository {
 	}
 }
 
-func (r *CustomerRepository) Create(customer *models.Customer) error {
-	ctx := context.Background()
-
+func (r *CustomerRepository) Create(ctx context.Context, customer *models.Customer) error {
 	// Generate customer code
-	customerCode, err := r.generateCustomerCode()
+	customerCode, err := r.generateCustomerCode(ctx)
 	if err != nil {
 		return err
 	}
 	customer.CustomerCode = customerCode
 
 	_, err = r.collection.InsertOne(ctx, customer)
-	return err
+	return emailInUse(err)
 }
 
-func (r *CustomerRepository) GetByID(id string) (*models.Customer, error) {
-	ctx := context.Background()
-
+func (r *CustomerRepository) GetByID(ctx context.Context, id string) (*models.Customer, error) {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var customer models.Customer
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&customer)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&customer)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &customer, nil
 }
 
-func (r *CustomerRepository) GetAll() ([]*models.Customer, error) {
-	ctx := context.Background()
-
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// GetAll gets every customer in sort order (natural order when sort is nil)
+func (r *CustomerRepository) GetAll(ctx context.Context, sort bson.D) ([]*models.Customer, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), sortOptions(sort))
 	if err != nil {
 		return nil, err
 	}
@@ -76,47 +77,148 @@ func (r *CustomerRepository) GetAll() ([]*models.Customer, error) {
 	return customers, nil
 }
 
-func (r *CustomerRepository) Update(id string, customer *models.Customer) error {
-	ctx := context.Background()
-
+func (r *CustomerRepository) Update(ctx context.Context, id string, customer *models.Customer) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, customer)
-	return err
+	return emailInUse(replaceVersioned(ctx, r.collection, objectID, &customer.Version, customer))
+}
+
+// Patch sets only the given fields on a customer; fields with a nil value are unset
+func (r *CustomerRepository) Patch(ctx context.Context, id string, fields bson.M) error {
+	return r.patch(ctx, id, fields, true)
+}
+
+// SetDerived sets fields the server keeps up to date itself, such as the outstanding balance or the last
+// contact, without moving the version, so a client editing the customer meanwhile gets no conflict
+func (r *CustomerRepository) SetDerived(ctx context.Context, id string, fields bson.M) error {
+	return r.patch(ctx, id, fields, false)
 }
 
-func (r *CustomerRepository) Delete(id string) error {
-	ctx := context.Background()
+func (r *CustomerRepository) patch(ctx context.Context, id string, fields bson.M, bumpVersion bool) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	set, unset := bson.M{}, bson.M{}
+	for field, value := range fields {
+		if value == nil {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{"$set": set}
+	if bumpVersion {
+		update["$inc"] = versionIncrement()
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), update)
+	if err != nil {
+		return emailInUse(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
 
+func (r *CustomerRepository) Delete(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
 	return err
 }
 
-func (r *CustomerRepository) GetByCustomerCode(customerCode string) (*models.Customer, error) {
-	ctx := context.Background()
+// CustomerSearchFields are the fields Search matches the query against
+var CustomerSearchFields = []string{"companyName", "contactName", "phone", "taxId"}
 
-	var customer models.Customer
-	err := r.collection.FindOne(ctx, bson.M{"customerCode": customerCode}).Decode(&customer)
+// Search finds customers with the query anywhere in one of fields (case-insensitive), or whose customer
+// code is exactly the query. Empty fields searches every field in CustomerSearchFields.
+func (r *CustomerRepository) Search(ctx context.Context, query string, fields []string) ([]*models.Customer, error) {
+	if len(fields) == 0 {
+		fields = CustomerSearchFields
+	}
+
+	pattern := containsPattern(query)
+	conditions := bson.A{bson.M{"customerCode": query}}
+	for _, field := range fields {
+		conditions = append(conditions, bson.M{field: pattern})
+	}
+
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"$or": conditions}), options.Find().SetSort(bson.D{{Key: "companyName", Value: 1}, {Key: "_id", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	customers := []*models.Customer{}
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (r *CustomerRepository) GetByCustomerCode(ctx context.Context, customerCode string) (*models.Customer, error) {
+	var customer models.Customer
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"customerCode": customerCode})).Decode(&customer)
+	if err != nil {
+		return nil, notFound(err)
+	}
 
 	return &customer, nil
 }
 
-func (r *CustomerRepository) generateCustomerCode() (string, error) {
-	ctx := context.Background()
+// GetByEmail gets the customer with the email, ignoring case
+func (r *CustomerRepository) GetByEmail(ctx context.Context, email string) (*models.Customer, error) {
+	var customer models.Customer
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"email": models.NormalizeEmail(email)})).Decode(&customer)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &customer, nil
+}
+
+// SearchByEmailDomain gets the customers whose email is at domain (e.g. goodpack.co.th), ignoring case, by company name
+func (r *CustomerRepository) SearchByEmailDomain(ctx context.Context, domain string) ([]*models.Customer, error) {
+	domain = strings.TrimPrefix(models.NormalizeEmail(domain), "@")
+	filter := bson.M{"email": primitive.Regex{Pattern: "@" + regexp.QuoteMeta(domain) + "$", Options: "i"}}
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), options.Find().SetSort(bson.D{{Key: "companyName", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	customers := []*models.Customer{}
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
 
+// emailInUse turns a duplicate key error on the email index into ErrEmailInUse; other errors are returned unchanged
+func emailInUse(err error) error {
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "email") {
+		return fmt.Errorf("%w: %w", ErrEmailInUse, err)
+	}
+	return err
+}
+
+func (r *CustomerRepository) generateCustomerCode(ctx context.Context) (string, error) {
 	// Get the highest customer code
-	opts := options.Find().SetSort(bson.D{{"customerCode", -1}}).SetLimit(1)
+	opts := options.Find().SetSort(bson.D{{Key: "customerCode", Value: -1}}).SetLimit(1)
 	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
 		return "", err
@@ -141,11 +243,51 @@ func (r *CustomerRepository) generateCustomerCode() (string, error) {
 		}
 	}
 
-	// Format as C-0001, C-0002, etc.
-	return fmt.Sprintf("C-%04d", nextNumber), nil
+	return models.FormatCustomerCode(nextNumber), nil
 }
 
 // GenerateCustomerCode is a public method to generate customer code
-func (r *CustomerRepository) GenerateCustomerCode() (string, error) {
-	return r.generateCustomerCode()
+func (r *CustomerRepository) GenerateCustomerCode(ctx context.Context) (string, error) {
+	return r.generateCustomerCode(ctx)
+}
+
+// GetAllForRenumbering gets every customer, deleted ones included, in the order they were created
+func (r *CustomerRepository) GetAllForRenumbering(ctx context.Context) ([]*models.Customer, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	customers := []*models.Customer{}
+	if err := cursor.All(ctx, &customers); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+// SetCustomerCode changes a customer's code, deleted or not
+func (r *CustomerRepository) SetCustomerCode(ctx context.Context, id, customerCode string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{"customerCode": customerCode, "updatedAt": time.Now()},
+		"$inc": versionIncrement(),
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// WithTransaction runs fn in a transaction; see withTransaction
+func (r *CustomerRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTransaction(ctx, r.collection, fn)
 }
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/apierrors"
+)
+
+// writeRetryAttempts is how many times a write is tried while MongoDB fails with a transient error
+const writeRetryAttempts = 3
+
+// notFound wraps mongo.ErrNoDocuments with apierrors.ErrNotFound, so callers can check for either;
+// any other error is returned unchanged
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) && !errors.Is(err, apierrors.ErrNotFound) {
+		return fmt.Errorf("%w: %w", apierrors.ErrNotFound, err)
+	}
+	return err
+}
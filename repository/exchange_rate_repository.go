@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type ExchangeRateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewExchangeRateRepository(collection *mongo.Collection) *ExchangeRateRepository {
+	return &ExchangeRateRepository{
+		collection: collection,
+	}
+}
+
+// Save stores the rate of a currency on a day, replacing the rate already entered for that day if there is one
+func (r *ExchangeRateRepository) Save(ctx context.Context, rate *models.ExchangeRate) error {
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"createdAt": rate.CreatedAt,
+		},
+		"$set": bson.M{
+			"rate":      rate.Rate,
+			"createdBy": rate.CreatedBy,
+			"updatedAt": rate.UpdatedAt,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	filter := bson.M{"currency": rate.Currency, "date": rate.Date}
+	return r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(rate)
+}
+
+// GetRate gets the rate of a currency on date, or the latest one before it when none was entered that day
+func (r *ExchangeRateRepository) GetRate(ctx context.Context, currency string, date time.Time) (*models.ExchangeRate, error) {
+	filter := bson.M{"currency": currency, "date": bson.M{"$lte": models.RateDate(date)}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+
+	var rate models.ExchangeRate
+	if err := r.collection.FindOne(ctx, filter, opts).Decode(&rate); err != nil {
+		return nil, notFound(err)
+	}
+	return &rate, nil
+}
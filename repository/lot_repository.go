@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+// ErrInsufficientLots is returned by AllocateForSale when the product's lots hold less than the quantity sold
+var ErrInsufficientLots = errors.New("not enough stock in lots")
+
+// errLotChanged means a lot was taken from by someone else between reading and allocating it
+var errLotChanged = errors.New("lot quantity changed during allocation")
+
+type LotRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLotRepository(collection *mongo.Collection) *LotRepository {
+	return &LotRepository{
+		collection: collection,
+	}
+}
+
+// Receive records a lot received on a purchase. A lot already recorded for the same product, lot number and
+// purchase is left unchanged, so receiving the same purchase again (e.g. after it is updated) only adds new lots.
+func (r *LotRepository) Receive(ctx context.Context, lot *models.Lot) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"productId": lot.ProductID, "lotNumber": lot.LotNumber, "purchaseId": lot.PurchaseID},
+		bson.M{"$setOnInsert": bson.M{
+			"productId":        lot.ProductID,
+			"lotNumber":        lot.LotNumber,
+			"expiryDate":       lot.ExpiryDate,
+			"quantity":         lot.Quantity,
+			"receivedQuantity": lot.Quantity,
+			"purchaseId":       lot.PurchaseID,
+			"receivedAt":       lot.ReceivedAt,
+			"updatedAt":        now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetByProduct gets the lots of a product in FEFO order; lots that are used up are left out unless includeEmpty
+func (r *LotRepository) GetByProduct(ctx context.Context, productID string, includeEmpty bool) ([]*models.Lot, error) {
+	filter := bson.M{"productId": productID}
+	if !includeEmpty {
+		filter["quantity"] = bson.M{"$gt": 0}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	lots := []*models.Lot{}
+	if err := cursor.All(ctx, &lots); err != nil {
+		return nil, err
+	}
+	models.SortFEFO(lots)
+	return lots, nil
+}
+
+// GetExpiring gets the lots with stock left that expire on or before the given time, earliest expiry first
+func (r *LotRepository) GetExpiring(ctx context.Context, before time.Time) ([]*models.Lot, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "expiryDate", Value: 1}, {Key: "receivedAt", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"quantity":   bson.M{"$gt": 0},
+		"expiryDate": bson.M{"$lte": before},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	lots := []*models.Lot{}
+	if err := cursor.All(ctx, &lots); err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// AllocateForSale takes quantity of a product from its lots First Expired First Out (see models.AllocateFEFO)
+// and returns what was taken from each lot. When the lots hold less than quantity, all of it is taken and the
+// allocations are returned together with ErrInsufficientLots.
+func (r *LotRepository) AllocateForSale(ctx context.Context, productID string, quantity int) ([]models.LotAllocation, error) {
+	for attempt := 0; attempt < writeRetryAttempts; attempt++ {
+		lots, err := r.GetByProduct(ctx, productID, false)
+		if err != nil {
+			return nil, err
+		}
+
+		allocations, shortfall := models.AllocateFEFO(lots, quantity)
+		if err := r.take(ctx, allocations); err != nil {
+			if errors.Is(err, errLotChanged) {
+				continue
+			}
+			return nil, err
+		}
+		if shortfall > 0 {
+			return allocations, fmt.Errorf("%w: %d of %d not covered", ErrInsufficientLots, shortfall, quantity)
+		}
+		return allocations, nil
+	}
+	return nil, errLotChanged
+}
+
+// take reduces each allocated lot by its quantity. If a lot no longer holds enough, what was already taken is
+// put back and errLotChanged is returned.
+func (r *LotRepository) take(ctx context.Context, allocations []models.LotAllocation) error {
+	for i, allocation := range allocations {
+		objectID, err := primitive.ObjectIDFromHex(allocation.LotID)
+		if err != nil {
+			return err
+		}
+
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": objectID, "quantity": bson.M{"$gte": allocation.Quantity}},
+			bson.M{
+				"$inc": bson.M{"quantity": -allocation.Quantity},
+				"$set": bson.M{"updatedAt": time.Now()},
+			},
+		)
+		if err == nil && result.MatchedCount == 0 {
+			err = errLotChanged
+		}
+		if err != nil {
+			if releaseErr := r.Release(ctx, allocations[:i]); releaseErr != nil {
+				return errors.Join(err, releaseErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Release puts the quantities of allocations back into their lots, for when a sale is deleted or its items
+// are replaced
+func (r *LotRepository) Release(ctx context.Context, allocations []models.LotAllocation) error {
+	for _, allocation := range allocations {
+		objectID, err := primitive.ObjectIDFromHex(allocation.LotID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": objectID},
+			bson.M{
+				"$inc": bson.M{"quantity": allocation.Quantity},
+				"$set": bson.M{"updatedAt": time.Now()},
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmptyPurchase sets the remaining quantity of the lots a purchase received to zero, for when the purchase is
+// cancelled; the lots are kept so the allocations of earlier sales still point at them
+func (r *LotRepository) EmptyPurchase(ctx context.Context, purchaseID string) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"purchaseId": purchaseID},
+		bson.M{"$set": bson.M{"quantity": 0, "updatedAt": time.Now()}},
+	)
+	return err
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"goodpack-server/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MigrationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMigrationRepository(collection *mongo.Collection) *MigrationRepository {
+	return &MigrationRepository{
+		collection: collection,
+	}
+}
+
+// GetByTransactionID gets the migration progress for a transaction ID
+func (r *MigrationRepository) GetByTransactionID(ctx context.Context, transactionID string) (*models.Migration, error) {
+	var migration models.Migration
+	err := r.collection.FindOne(ctx, bson.M{"transactionId": transactionID}).Decode(&migration)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &migration, nil
+}
+
+// Start creates the migration record for a transaction ID if it does not exist yet
+func (r *MigrationRepository) Start(ctx context.Context, transactionID, entity string) (*models.Migration, error) {
+	now := time.Now()
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"transactionId": transactionID,
+			"entity":        entity,
+			"processedRows": []string{},
+			"createdAt":     now,
+		},
+		"$set": bson.M{
+			"status":    models.MigrationStatusInProgress,
+			"updatedAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var migration models.Migration
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"transactionId": transactionID}, update, opts).Decode(&migration)
+	if err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// RecordSuccess marks a row (or row group) of the migration as imported
+func (r *MigrationRepository) RecordSuccess(ctx context.Context, transactionID, rowKey string) error {
+	update := bson.M{
+		"$addToSet": bson.M{"processedRows": rowKey},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"transactionId": transactionID}, update)
+	return err
+}
+
+// MarkComplete stores the run summary and, when no rows failed, marks the migration as completed
+func (r *MigrationRepository) MarkComplete(ctx context.Context, transactionID string, summary models.MigrationSummary) error {
+	now := time.Now()
+	set := bson.M{
+		"lastResult": summary,
+		"updatedAt":  now,
+	}
+	if summary.FailedRows == 0 {
+		set["status"] = models.MigrationStatusCompleted
+		set["completedAt"] = now
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"transactionId": transactionID}, bson.M{"$set": set})
+	return err
+}
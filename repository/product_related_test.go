@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+)
+
+func pricedProduct(skuID, category, color, size string, price float64) models.Product {
+	return models.Product{
+		ID:       primitive.NewObjectID(),
+		SKUID:    skuID,
+		Category: category,
+		Color:    color,
+		Size:     size,
+		Price:    models.Price{SaleVAT: models.PriceInfo{Latest: price}},
+	}
+}
+
+func skuIDsOf(products []*models.Product) []string {
+	skuIDs := make([]string, len(products))
+	for i, product := range products {
+		skuIDs[i] = product.SKUID
+	}
+	return skuIDs
+}
+
+func TestRelatedProducts(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	r := &ProductRepository{collection: db.Collection("products")}
+
+	product := pricedProduct("BOX-0001", "Box", "brown", "M", 100)
+	deleted := pricedProduct("BOX-0005", "Box", "brown", "M", 100)
+	deleted.IsDeleted = true
+	products := []interface{}{
+		product,
+		pricedProduct("BOX-0002", "Box", "white", "L", 95),
+		pricedProduct("BOX-0003", "Box", "white", "L", 120),
+		pricedProduct("BOX-0004", "Box", "white", "L", 60),
+		deleted,
+		pricedProduct("TAP-0001", "Tape", "brown", "S", 101),
+		pricedProduct("BAG-0001", "Bag", "white", "M", 150),
+		pricedProduct("PAP-0001", "Paper", "white", "L", 100),
+	}
+	if _, err := r.collection.InsertMany(ctx, products); err != nil {
+		t.Fatal(err)
+	}
+
+	related, err := r.GetRelated(ctx, &product, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := skuIDsOf(related); len(got) != 2 || got[0] != "BOX-0002" || got[1] != "BOX-0003" {
+		t.Errorf("related = %v, want [BOX-0002 BOX-0003]: same category, closest price first, not itself or deleted", got)
+	}
+
+	similar, err := r.GetSameColorOrSize(ctx, &product, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := skuIDsOf(similar); len(got) != 2 || got[0] != "TAP-0001" || got[1] != "BAG-0001" {
+		t.Errorf("same color or size = %v, want [TAP-0001 BAG-0001] from other categories, closest price first", got)
+	}
+
+	plain := pricedProduct("BOX-0009", "Box", "", "", 100)
+	if none, err := r.GetSameColorOrSize(ctx, &plain, 5); err != nil || len(none) != 0 {
+		t.Errorf("product without color or size: %v, %v; want none", skuIDsOf(none), err)
+	}
+}
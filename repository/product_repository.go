@@ -2,8 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,32 +16,79 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"goodpack-server/apierrors"
+	"goodpack-server/metrics"
 	"goodpack-server/models"
 	"goodpack-server/utils"
 )
 
+// ErrNoTextIndex is returned by TextSearch while the products collection has no $text index
+var ErrNoTextIndex = errors.New("products have no text index")
+
+// ErrSKUInUse is returned when a product is saved with a SKU ID another product, deleted or not, already has
+var ErrSKUInUse = errors.New("SKU ID already in use")
+
+// DefaultSKUCacheTTL is how long the highest SKU number of a category is trusted before the SKUs are read again
+const DefaultSKUCacheTTL = time.Minute
+
 type ProductRepository struct {
 	collection   *mongo.Collection
 	skuGenerator *utils.SKUGenerator
+	skuMu        sync.Mutex               // guards skuCache and skuReserved
+	skuCache     map[string]skuCacheEntry // category abbreviation -> highest SKU number
+	skuReserved  map[string]int           // category abbreviation -> highest SKU number handed out, so parallel creates get distinct SKUs
+	skuCacheTTL  time.Duration            // 0 disables the cache
+
+	textIndex atomic.Bool // set once a $text index has been found
+}
+
+// skuCacheEntry is the highest SKU number of a category as of loadedAt
+type skuCacheEntry struct {
+	number   int
+	loadedAt time.Time
 }
 
-func NewProductRepository(collection *mongo.Collection) *ProductRepository {
-	return &ProductRepository{
+// ProductRepositoryOption configures a ProductRepository
+type ProductRepositoryOption func(*ProductRepository)
+
+// WithSKUCacheTTL sets how long the highest SKU number of each category is cached; 0 reads every SKU on each create
+func WithSKUCacheTTL(ttl time.Duration) ProductRepositoryOption {
+	return func(r *ProductRepository) {
+		r.skuCacheTTL = ttl
+	}
+}
+
+// NewProductRepository loads the category config used for SKU IDs up front and fails if it cannot be loaded
+func NewProductRepository(collection *mongo.Collection, opts ...ProductRepositoryOption) (*ProductRepository, error) {
+	skuGenerator, err := utils.NewSKUGenerator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SKU config: %w", err)
+	}
+
+	r := &ProductRepository{
 		collection:   collection,
-		skuGenerator: utils.NewSKUGenerator(),
+		skuGenerator: skuGenerator,
+		skuCache:     make(map[string]skuCacheEntry),
+		skuReserved:  make(map[string]int),
+		skuCacheTTL:  DefaultSKUCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r, nil
 }
 
 func (r *ProductRepository) Create(ctx context.Context, product *models.Product) error {
+	defer metrics.ObserveMongoOperation("products", "Create", time.Now())
+
 	// Generate SKU ID (only if not already set, e.g., from migration)
-	if product.SKUID == "" {
-		existingSKUs, err := r.getAllSKUIDs(ctx)
+	generatedSKU := product.SKUID == ""
+	if generatedSKU {
+		skuID, err := r.reserveSKU(ctx, product.Category)
 		if err != nil {
 			return err
 		}
-
-		nextNumber := r.skuGenerator.GetNextSKUNumber(product.Category, existingSKUs)
-		product.SKUID = r.skuGenerator.GenerateSKUID(product.Category, nextNumber)
+		product.SKUID = skuID
 	}
 
 	// Generate Product Code (only if not already set, e.g., from migration)
@@ -46,82 +98,222 @@ func (r *ProductRepository) Create(ctx context.Context, product *models.Product)
 
 	// Generate QR Data
 	if product.QRData == "" {
-		product.QRData = product.SKUID // Use SKU ID as QR data
+		product.QRData = models.ProductQRData(product.SKUID)
 	}
 
 	// Debug: Log product fields before saving
 	fmt.Printf("DEBUG: Saving product - Name: '%s', Color: '%s' (length: %d), Description: '%s' (length: %d)\n",
 		product.Name, product.Color, len(product.Color), product.Description, len(product.Description))
 
-	result, err := r.collection.InsertOne(ctx, product)
+	// Set the ID up front so that a retried insert cannot store the product twice
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	err := retryInsert(ctx, func() error {
+		_, err := r.collection.InsertOne(ctx, product)
+		return err
+	})
 	if err != nil {
 		fmt.Printf("ERROR: Failed to insert product: %v\n", err)
-		return err
+		if generatedSKU {
+			// The SKU may have been taken by another server; read the SKUs again next time
+			r.skuMu.Lock()
+			delete(r.skuCache, r.skuGenerator.CategoryAbbreviation(product.Category))
+			r.skuMu.Unlock()
+		}
+		return skuInUse(err)
 	}
 
-	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
-		product.ID = oid
-		fmt.Printf("DEBUG: Product saved successfully - ID: %s\n", product.ID.Hex())
-	}
+	r.skuMu.Lock()
+	r.rememberSKU(product.SKUID)
+	r.skuMu.Unlock()
+
+	fmt.Printf("DEBUG: Product saved successfully - ID: %s\n", product.ID.Hex())
 	return nil
 }
 
 func (r *ProductRepository) GetByID(ctx context.Context, id string) (*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByID", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var product models.Product
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&product)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&product)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &product, nil
 }
 
-func (r *ProductRepository) GetAll(ctx context.Context) ([]*models.Product, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// ViewByID gets a product by ID for someone viewing it, counting the view in the same operation
+func (r *ProductRepository) ViewByID(ctx context.Context, id string) (*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "ViewByID", time.Now())
+
+	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	return r.view(ctx, bson.M{"_id": objectID})
+}
 
-	var products []*models.Product
-	for cursor.Next(ctx) {
-		var product models.Product
-		if err := cursor.Decode(&product); err != nil {
-			log.Printf("Error decoding product: %v", err)
-			continue
-		}
-		products = append(products, &product)
+// ViewBySKUID gets a product by SKU ID for someone viewing it, counting the view in the same operation
+func (r *ProductRepository) ViewBySKUID(ctx context.Context, skuID string) (*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "ViewBySKUID", time.Now())
+
+	return r.view(ctx, bson.M{"skuId": skuID})
+}
+
+// view increments the view count of the product matching filter and returns the product as updated. The
+// count is a statistic, so neither the version nor updatedAt changes.
+func (r *ProductRepository) view(ctx context.Context, filter bson.M) (*models.Product, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var product models.Product
+	err := r.collection.FindOneAndUpdate(ctx, notDeleted(filter), bson.M{"$inc": bson.M{"viewCount": 1}}, opts).Decode(&product)
+	if err != nil {
+		return nil, notFound(err)
 	}
+	return &product, nil
+}
 
-	return products, cursor.Err()
+// GetAll gets every product in sort order (natural order when sort is nil)
+func (r *ProductRepository) GetAll(ctx context.Context, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetAll", time.Now())
+
+	return r.findProducts(ctx, notDeleted(bson.M{}), sortOptions(sort))
 }
 
 func (r *ProductRepository) Update(ctx context.Context, id string, product *models.Product) error {
+	defer metrics.ObserveMongoOperation("products", "Update", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, product)
-	return err
+	return retryReplace(ctx, &product.Version,
+		func(writeToken string) error {
+			return replaceVersioned(ctx, r.collection, objectID, &product.Version, writeTokenDocument{product, writeToken})
+		},
+		func() (int, string, error) {
+			return storedWrite(ctx, r.collection, objectID)
+		},
+	)
+}
+
+// Modify reads a product, applies change to it and saves it. When another request saved the product in
+// between, it is read again and change applied to the fresh copy, so stock movements are never lost to a
+// version conflict. It returns the saved product.
+func (r *ProductRepository) Modify(ctx context.Context, id string, change func(*models.Product) error) (*models.Product, error) {
+	for attempt := 0; attempt < writeRetryAttempts; attempt++ {
+		product, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := change(product); err != nil {
+			return nil, err
+		}
+		err = r.Update(ctx, id, product)
+		if errors.Is(err, apierrors.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return product, nil
+	}
+	return nil, errVersionConflict
 }
 
 func (r *ProductRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveMongoOperation("products", "Delete", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
 	return err
 }
 
+// Patch sets only the given fields on a product
+func (r *ProductRepository) Patch(ctx context.Context, id string, fields bson.M) error {
+	defer metrics.ObserveMongoOperation("products", "Patch", time.Now())
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), bson.M{"$set": fields, "$inc": versionIncrement()})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
+// Restore brings a soft-deleted product back
+func (r *ProductRepository) Restore(ctx context.Context, id string) error {
+	defer metrics.ObserveMongoOperation("products", "Restore", time.Now())
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID, "isDeleted": true},
+		bson.M{
+			"$set":   bson.M{"isDeleted": false, "updatedAt": time.Now()},
+			"$unset": bson.M{"deletedAt": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
+// HardDelete permanently removes a product, including soft-deleted ones
+func (r *ProductRepository) HardDelete(ctx context.Context, id string) error {
+	defer metrics.ObserveMongoOperation("products", "HardDelete", time.Now())
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return notFound(mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
 func (r *ProductRepository) UpdateStock(ctx context.Context, id string, stock models.Stock) error {
+	defer metrics.ObserveMongoOperation("products", "UpdateStock", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
@@ -135,12 +327,15 @@ func (r *ProductRepository) UpdateStock(ctx context.Context, id string, stock mo
 				"stock":     stock,
 				"updatedAt": time.Now(),
 			},
+			"$inc": versionIncrement(),
 		},
 	)
 	return err
 }
 
 func (r *ProductRepository) UpdatePrice(ctx context.Context, id string, price models.Price) error {
+	defer metrics.ObserveMongoOperation("products", "UpdatePrice", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
@@ -154,33 +349,96 @@ func (r *ProductRepository) UpdatePrice(ctx context.Context, id string, price mo
 				"price":     price,
 				"updatedAt": time.Now(),
 			},
+			"$inc": versionIncrement(),
 		},
 	)
 	return err
 }
 
 func (r *ProductRepository) GetBySKUID(ctx context.Context, skuID string) (*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetBySKUID", time.Now())
+
 	var product models.Product
-	err := r.collection.FindOne(ctx, bson.M{"skuId": skuID}).Decode(&product)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"skuId": skuID})).Decode(&product)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &product, nil
 }
 
 func (r *ProductRepository) GetByCode(ctx context.Context, code string) (*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByCode", time.Now())
+
 	var product models.Product
-	err := r.collection.FindOne(ctx, bson.M{"code": code}).Decode(&product)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"code": code})).Decode(&product)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &product, nil
 }
 
-func (r *ProductRepository) GetByCategory(ctx context.Context, category string) ([]*models.Product, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"category": category})
+// GetByCategory gets the products of a category in sort order; minStock and maxStock, when set,
+// limit the result to an inclusive range of actual stock
+func (r *ProductRepository) GetByCategory(ctx context.Context, category string, minStock, maxStock *int, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByCategory", time.Now())
+
+	filter := bson.M{"category": category}
+	if minStock != nil || maxStock != nil {
+		stockRange := bson.M{}
+		if minStock != nil {
+			stockRange["$gte"] = *minStock
+		}
+		if maxStock != nil {
+			stockRange["$lte"] = *maxStock
+		}
+		filter["stock.actualStock"] = stockRange
+	}
+
+	return r.findProducts(ctx, notDeleted(filter), sortOptions(sort))
+}
+
+// GetByTag gets the products carrying a tag
+func (r *ProductRepository) GetByTag(ctx context.Context, tag string, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByTag", time.Now())
+
+	return r.findProducts(ctx, notDeleted(bson.M{"tags": tag}), sortOptions(sort))
+}
+
+// GetByTags gets the products carrying all of the tags (matchAll) or any of them
+func (r *ProductRepository) GetByTags(ctx context.Context, tags []string, matchAll bool, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByTags", time.Now())
+
+	operator := "$in"
+	if matchAll {
+		operator = "$all"
+	}
+	return r.findProducts(ctx, notDeleted(bson.M{"tags": bson.M{operator: tags}}), sortOptions(sort))
+}
+
+// GetBySKURange gets products in a category and/or an inclusive SKU ID range, sorted by SKU ID.
+// Empty arguments are not filtered on.
+func (r *ProductRepository) GetBySKURange(ctx context.Context, category, skuStart, skuEnd string) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetBySKURange", time.Now())
+
+	filter := bson.M{}
+	if category != "" {
+		filter["category"] = category
+	}
+	skuRange := bson.M{}
+	if skuStart != "" {
+		skuRange["$gte"] = skuStart
+	}
+	if skuEnd != "" {
+		skuRange["$lte"] = skuEnd
+	}
+	if len(skuRange) > 0 {
+		filter["skuId"] = skuRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "skuId", Value: 1}})
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -199,26 +457,459 @@ func (r *ProductRepository) GetByCategory(ctx context.Context, category string)
 	return products, cursor.Err()
 }
 
+// GetByUOM gets the products sold in a unit of measure
+func (r *ProductRepository) GetByUOM(ctx context.Context, uom string, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByUOM", time.Now())
+
+	return r.findProducts(ctx, notDeleted(bson.M{"uom": uom}), sortOptions(sort))
+}
+
+// GetPopular gets the limit products with the highest popularity score
+func (r *ProductRepository) GetPopular(ctx context.Context, limit int) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetPopular", time.Now())
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "popularityScore", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	return r.findProducts(ctx, notDeleted(bson.M{}), opts)
+}
+
+// GetRelated gets up to limit other products of the product's category, closest VAT sale price first
+func (r *ProductRepository) GetRelated(ctx context.Context, product *models.Product, limit int) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetRelated", time.Now())
+
+	filter := bson.M{"category": product.Category, "_id": bson.M{"$ne": product.ID}}
+	return r.closestPriced(ctx, filter, product.Price.SaleVAT.Latest, limit)
+}
+
+// GetSameColorOrSize gets up to limit products of other categories with the product's color or size, closest
+// VAT sale price first. A product with neither has none.
+func (r *ProductRepository) GetSameColorOrSize(ctx context.Context, product *models.Product, limit int) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetSameColorOrSize", time.Now())
+
+	var matches bson.A
+	if product.Color != "" {
+		matches = append(matches, bson.M{"color": product.Color})
+	}
+	if product.Size != "" {
+		matches = append(matches, bson.M{"size": product.Size})
+	}
+	if len(matches) == 0 {
+		return []*models.Product{}, nil
+	}
+
+	filter := bson.M{"category": bson.M{"$ne": product.Category}, "$or": matches}
+	return r.closestPriced(ctx, filter, product.Price.SaleVAT.Latest, limit)
+}
+
+// closestPriced gets up to limit products matching filter, sorted by how far their VAT sale price is from price
+func (r *ProductRepository) closestPriced(ctx context.Context, filter bson.M, price float64, limit int) ([]*models.Product, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(filter)}},
+		{{Key: "$addFields", Value: bson.M{"priceDistance": bson.M{"$abs": bson.M{"$subtract": bson.A{
+			bson.M{"$ifNull": bson.A{"$price.saleVAT.latest", 0}}, price,
+		}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "priceDistance", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"priceDistance": 0}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	products := []*models.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByVelocityClass gets the products of a velocity class; unclassified includes products saved before
+// classes were stored
+func (r *ProductRepository) GetByVelocityClass(ctx context.Context, class string, sort bson.D) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "GetByVelocityClass", time.Now())
+
+	filter := bson.M{"velocityClass": class}
+	if class == models.VelocityClassUnclassified {
+		filter["velocityClass"] = bson.M{"$nin": bson.A{models.VelocityClassA, models.VelocityClassB, models.VelocityClassC}}
+	}
+	return r.findProducts(ctx, notDeleted(filter), sortOptions(sort))
+}
+
+// GetTags returns every distinct product tag, sorted
+func (r *ProductRepository) GetTags(ctx context.Context) ([]string, error) {
+	defer metrics.ObserveMongoOperation("products", "GetTags", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags"}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tags := []string{}
+	for cursor.Next(ctx) {
+		var result struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			log.Printf("Error decoding tag: %v", err)
+			continue
+		}
+		tags = append(tags, result.ID)
+	}
+
+	return tags, cursor.Err()
+}
+
+// findProducts decodes every product matching filter
+func (r *ProductRepository) findProducts(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.Product, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []*models.Product
+	for cursor.Next(ctx) {
+		var product models.Product
+		if err := cursor.Decode(&product); err != nil {
+			log.Printf("Error decoding product: %v", err)
+			continue
+		}
+		products = append(products, &product)
+	}
+
+	return products, cursor.Err()
+}
+
+// Search finds products matching every filter set in the query
+func (r *ProductRepository) Search(ctx context.Context, query models.ProductSearchQuery) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "Search", time.Now())
+
+	filter := bson.M{}
+	opts := options.Find()
+
+	if query.Name != "" {
+		pattern := containsPattern(query.Name)
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"description": pattern},
+		}
+	}
+	if query.Category != "" {
+		filter["category"] = query.Category
+	}
+	if query.Color != "" {
+		filter["color"] = query.Color
+	}
+	if query.Size != "" {
+		filter["size"] = query.Size
+	}
+	if query.SKUID != "" {
+		pattern := containsPattern(query.SKUID)
+		filter["$and"] = bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"skuId": pattern},
+				bson.M{"code": pattern},
+			}},
+		}
+	}
+
+	return r.findProducts(ctx, notDeleted(filter), opts)
+}
+
+// TextSearch finds products by the words of query in their name or description using the $text index, most
+// relevant first. It returns ErrNoTextIndex while the index does not exist.
+func (r *ProductRepository) TextSearch(ctx context.Context, query string) ([]*models.Product, error) {
+	defer metrics.ObserveMongoOperation("products", "TextSearch", time.Now())
+
+	if !r.hasTextIndex(ctx) {
+		return nil, ErrNoTextIndex
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}})
+	return r.findProducts(ctx, notDeleted(bson.M{"$text": bson.M{"$search": query}}), opts)
+}
+
+// hasTextIndex reports whether a $text index exists on the products collection. EnsureIndexes creates it at
+// startup, but until it has been built the indexes are listed again on every call; once found it is cached.
+func (r *ProductRepository) hasTextIndex(ctx context.Context) bool {
+	if r.textIndex.Load() {
+		return true
+	}
+
+	specs, err := r.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to list product indexes: %v", err)
+		return false
+	}
+	for _, spec := range specs {
+		elements, err := spec.KeysDocument.Elements()
+		if err != nil {
+			continue
+		}
+		for _, element := range elements {
+			if value, ok := element.Value().StringValueOK(); ok && value == "text" {
+				r.textIndex.Store(true)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsPattern builds a case-insensitive regex that matches the literal text anywhere in a field
+func containsPattern(text string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}
+}
+
+// GetLowStockProducts gets the products whose actual stock is at or below threshold, or at or below their own
+// reorder level (see Product.GetReorderLevel) when threshold is 0
 func (r *ProductRepository) GetLowStockProducts(ctx context.Context, threshold int) ([]*models.Product, error) {
-	// This would need to be implemented with aggregation pipeline
-	// For now, we'll get all products and filter in memory
-	allProducts, err := r.GetAll(ctx)
+	defer metrics.ObserveMongoOperation("products", "GetLowStockProducts", time.Now())
+
+	var level interface{} = threshold
+	if threshold <= 0 {
+		level = bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$reorderLevel", 0}}, "$reorderLevel", models.DefaultReorderLevel}}
+	}
+	filter := bson.M{"$expr": bson.M{"$lte": bson.A{"$stock.actualStock", level}}}
+
+	return r.findProducts(ctx, notDeleted(filter))
+}
+
+// lastSKUNumber returns the highest SKU number used in category, reading every SKU only when the cached
+// number is missing or older than the TTL. The caller must hold skuMu.
+func (r *ProductRepository) lastSKUNumber(ctx context.Context, category string) (int, error) {
+	abbrev := r.skuGenerator.CategoryAbbreviation(category)
+	if entry, ok := r.skuCache[abbrev]; ok && time.Since(entry.loadedAt) < r.skuCacheTTL {
+		return entry.number, nil
+	}
+
+	existingSKUs, err := r.getAllSKUIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	number := r.skuGenerator.GetNextSKUNumber(category, existingSKUs)
+	if r.skuCacheTTL > 0 {
+		r.skuCache[abbrev] = skuCacheEntry{number: number, loadedAt: time.Now()}
+	}
+	return number, nil
+}
+
+// reserveSKU generates the next SKU ID of the category and reserves its number, so the insert can run without
+// holding skuMu while parallel creates still get distinct SKUs
+func (r *ProductRepository) reserveSKU(ctx context.Context, category string) (string, error) {
+	r.skuMu.Lock()
+	defer r.skuMu.Unlock()
+
+	lastNumber, err := r.lastSKUNumber(ctx, category)
+	if err != nil {
+		return "", err
+	}
+	abbrev := r.skuGenerator.CategoryAbbreviation(category)
+	if reserved := r.skuReserved[abbrev]; reserved > lastNumber {
+		lastNumber = reserved
+	}
+	r.skuReserved[abbrev] = lastNumber + 1
+	return r.skuGenerator.GenerateSKUID(category, lastNumber), nil
+}
+
+// skuInUse turns a duplicate key error on the SKU index into ErrSKUInUse; other errors are returned unchanged
+func skuInUse(err error) error {
+	if mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "skuId") {
+		return fmt.Errorf("%w: %w", ErrSKUInUse, err)
+	}
+	return err
+}
+
+// rememberSKU raises the cached number for the SKU's category after an insert. The caller must hold skuMu.
+func (r *ProductRepository) rememberSKU(skuID string) {
+	abbrev, number, err := r.skuGenerator.ParseSKUID(skuID)
+	if err != nil {
+		return
+	}
+	if entry, ok := r.skuCache[abbrev]; ok && number > entry.number {
+		entry.number = number
+		r.skuCache[abbrev] = entry
+	}
+}
+
+// qrRegenerateBatchSize is how many products RegenerateAllQRData and setComputedFields update per bulk write
+const qrRegenerateBatchSize = 100
+
+// RegenerateAllQRData sets the QR data of every product whose QR data no longer matches its SKU ID, in bulk writes
+// of qrRegenerateBatchSize products, and returns how many were updated
+func (r *ProductRepository) RegenerateAllQRData(ctx context.Context) (int64, error) {
+	defer metrics.ObserveMongoOperation("products", "RegenerateAllQRData", time.Now())
+
+	opts := options.Find().SetProjection(bson.M{"skuId": 1, "qrData": 1}).SetBatchSize(qrRegenerateBatchSize)
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var updated int64
+	writes := make([]mongo.WriteModel, 0, qrRegenerateBatchSize)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		result, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			updated += result.ModifiedCount
+		}
+		writes = writes[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var product struct {
+			ID     primitive.ObjectID `bson:"_id"`
+			SKUID  string             `bson:"skuId"`
+			QRData string             `bson:"qrData"`
+		}
+		if err := cursor.Decode(&product); err != nil {
+			log.Printf("Error decoding product QR data: %v", err)
+			continue
+		}
+
+		qrData := models.ProductQRData(product.SKUID)
+		if product.QRData == qrData {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": product.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"qrData": qrData, "updatedAt": time.Now()}, "$inc": versionIncrement()}))
+		if len(writes) == qrRegenerateBatchSize {
+			if err := flush(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return updated, err
+	}
+
+	return updated, flush()
+}
+
+// GetIDs gets the IDs of all products that are not deleted
+func (r *ProductRepository) GetIDs(ctx context.Context) ([]string, error) {
+	viewCounts, err := r.GetViewCounts(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var lowStockProducts []*models.Product
-	for _, product := range allProducts {
-		if product.GetTotalStock() <= threshold {
-			lowStockProducts = append(lowStockProducts, product)
+	ids := make([]string, 0, len(viewCounts))
+	for id := range viewCounts {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetViewCounts gets the view count of every product that is not deleted, keyed by product ID
+func (r *ProductRepository) GetViewCounts(ctx context.Context) (map[string]int, error) {
+	defer metrics.ObserveMongoOperation("products", "GetViewCounts", time.Now())
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "viewCount": 1})
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		ViewCount int                `bson:"viewCount"`
+	}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+
+	viewCounts := make(map[string]int, len(products))
+	for _, product := range products {
+		viewCounts[product.ID.Hex()] = product.ViewCount
+	}
+	return viewCounts, nil
+}
+
+// SetVelocityClasses stores the velocity class of each product, keyed by product ID, and returns how many
+// products' classes changed
+func (r *ProductRepository) SetVelocityClasses(ctx context.Context, classes map[string]string) (int64, error) {
+	defer metrics.ObserveMongoOperation("products", "SetVelocityClasses", time.Now())
+
+	fields := make(map[string]bson.M, len(classes))
+	for id, class := range classes {
+		fields[id] = bson.M{"velocityClass": class}
+	}
+	return r.setComputedFields(ctx, fields)
+}
+
+// SetPopularity stores the sales count and popularity score of each product, keyed by product ID, and returns
+// how many products changed
+func (r *ProductRepository) SetPopularity(ctx context.Context, popularity map[string]models.ProductPopularity) (int64, error) {
+	defer metrics.ObserveMongoOperation("products", "SetPopularity", time.Now())
+
+	fields := make(map[string]bson.M, len(popularity))
+	for id, p := range popularity {
+		fields[id] = bson.M{"salesCount": p.SalesCount, "popularityScore": p.PopularityScore}
+	}
+	return r.setComputedFields(ctx, fields)
+}
+
+// setComputedFields sets fields computed by background jobs on each product, keyed by product ID, in bulk
+// writes, and returns how many products changed. Neither the version nor updatedAt changes.
+func (r *ProductRepository) setComputedFields(ctx context.Context, fields map[string]bson.M) (int64, error) {
+	var changed int64
+	writes := make([]mongo.WriteModel, 0, qrRegenerateBatchSize)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
 		}
+		result, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			changed += result.ModifiedCount
+		}
+		writes = writes[:0]
+		return err
 	}
 
-	return lowStockProducts, nil
+	for id, set := range fields {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": objectID}).
+			SetUpdate(bson.M{"$set": set}))
+		if len(writes) == qrRegenerateBatchSize {
+			if err := flush(); err != nil {
+				return changed, err
+			}
+		}
+	}
+
+	return changed, flush()
 }
 
 // getAllSKUIDs gets all existing SKU IDs for number generation
 func (r *ProductRepository) getAllSKUIDs(ctx context.Context) ([]string, error) {
+	defer metrics.ObserveMongoOperation("products", "getAllSKUIDs", time.Now())
+
 	opts := options.Find().SetProjection(bson.M{"skuId": 1})
 	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
@@ -242,10 +933,12 @@ func (r *ProductRepository) getAllSKUIDs(ctx context.Context) ([]string, error)
 }
 
 func (r *ProductRepository) GetCategories(ctx context.Context) ([]string, error) {
+	defer metrics.ObserveMongoOperation("products", "GetCategories", time.Now())
+
 	pipeline := mongo.Pipeline{
-		{{"$match", bson.M{"category": bson.M{"$ne": nil}}}},
-		{{"$group", bson.M{"_id": "$category"}}},
-		{{"$sort", bson.M{"_id": 1}}},
+		{{Key: "$match", Value: notDeleted(bson.M{"category": bson.M{"$ne": nil}})}},
+		{{Key: "$group", Value: bson.M{"_id": "$category"}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
@@ -268,3 +961,67 @@ func (r *ProductRepository) GetCategories(ctx context.Context) ([]string, error)
 
 	return categories, cursor.Err()
 }
+
+// RefreshInventoryLevel recomputes the total actual stock of a category and publishes it to the inventory gauge
+func (r *ProductRepository) RefreshInventoryLevel(ctx context.Context, category string) error {
+	defer metrics.ObserveMongoOperation("products", "RefreshInventoryLevel", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"category": category})}},
+		{{Key: "$group", Value: bson.M{"_id": "$category", "total": bson.M{"$sum": "$stock.actualStock"}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	total := 0
+	if cursor.Next(ctx) {
+		var result struct {
+			Total int `bson:"total"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return err
+		}
+		total = result.Total
+	}
+
+	metrics.SetInventoryLevel(category, total)
+	return cursor.Err()
+}
+
+// GetStockDiscrepancies finds products whose actual stock differs from VAT + Non-VAT remaining
+func (r *ProductRepository) GetStockDiscrepancies(ctx context.Context) ([]*models.StockDiscrepancy, error) {
+	defer metrics.ObserveMongoOperation("products", "GetStockDiscrepancies", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$project", Value: bson.M{
+			"_id":           bson.M{"$toString": "$_id"},
+			"skuId":         1,
+			"name":          1,
+			"actualStock":   "$stock.actualStock",
+			"computedStock": bson.M{"$add": bson.A{"$stock.vat.remaining", "$stock.nonVAT.remaining"}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"discrepancy": bson.M{"$subtract": bson.A{"$actualStock", "$computedStock"}},
+		}}},
+		{{Key: "$match", Value: bson.M{"discrepancy": bson.M{"$ne": 0}}}},
+		{{Key: "$sort", Value: bson.M{"skuId": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var discrepancies []*models.StockDiscrepancy
+	if err := cursor.All(ctx, &discrepancies); err != nil {
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/config"
+	"goodpack-server/models"
+	"goodpack-server/utils"
+)
+
+func cachedSKURepo(t *testing.T, abbrev string, lastNumber int) *ProductRepository {
+	skuGenerator, err := utils.NewSKUGeneratorWithConfig(config.NewConfigLoader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ProductRepository{
+		skuGenerator: skuGenerator,
+		skuCache:     map[string]skuCacheEntry{abbrev: {number: lastNumber, loadedAt: time.Now()}},
+		skuReserved:  make(map[string]int),
+		skuCacheTTL:  time.Minute,
+	}
+}
+
+func TestReserveSKUGivesParallelCreatesDistinctSKUs(t *testing.T) {
+	r := cachedSKURepo(t, "BOX", 5)
+
+	const creates = 20
+	skuIDs := make([]string, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			skuID, err := r.reserveSKU(context.Background(), "Box")
+			if err != nil {
+				t.Error(err)
+			}
+			skuIDs[i] = skuID
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, skuID := range skuIDs {
+		if seen[skuID] {
+			t.Errorf("SKU ID %s handed out twice", skuID)
+		}
+		seen[skuID] = true
+	}
+	if !seen["BOX-0006"] || !seen["BOX-0025"] {
+		t.Errorf("SKU IDs = %v, want BOX-0006 to BOX-0025", skuIDs)
+	}
+}
+
+func TestReserveSKUSkipsNumbersReservedBeforeTheCacheWasRead(t *testing.T) {
+	r := cachedSKURepo(t, "BOX", 5)
+	r.skuReserved["BOX"] = 8
+
+	skuID, err := r.reserveSKU(context.Background(), "Box")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skuID != "BOX-0009" {
+		t.Errorf("reserveSKU = %s, want BOX-0009", skuID)
+	}
+}
+
+func TestSKUInUse(t *testing.T) {
+	if err := skuInUse(errDuplicateSKU); !errors.Is(err, ErrSKUInUse) {
+		t.Errorf("skuInUse(duplicate SKU) = %v, want ErrSKUInUse", err)
+	}
+	if err := skuInUse(errDuplicateID); errors.Is(err, ErrSKUInUse) {
+		t.Errorf("skuInUse(duplicate _id) = %v, want it unchanged", err)
+	}
+	if err := skuInUse(errNetwork); errors.Is(err, ErrSKUInUse) {
+		t.Errorf("skuInUse(network error) = %v, want it unchanged", err)
+	}
+}
+
+// benchmarkCreateProducts times creating 100 products with generated SKU IDs, each round in a fresh collection
+func benchmarkCreateProducts(b *testing.B, skuCacheTTL time.Duration) {
+	db := testDatabase(b)
+	skuGenerator, err := utils.NewSKUGeneratorWithConfig(config.NewConfigLoader())
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := &ProductRepository{
+			collection:   db.Collection(fmt.Sprintf("products_%d", i)),
+			skuGenerator: skuGenerator,
+			skuCache:     make(map[string]skuCacheEntry),
+			skuReserved:  make(map[string]int),
+			skuCacheTTL:  skuCacheTTL,
+		}
+		for n := 0; n < 100; n++ {
+			if err := r.Create(ctx, &models.Product{Name: fmt.Sprintf("Box %d", n), Category: "Box"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCreateProductsWithSKUCache(b *testing.B) {
+	benchmarkCreateProducts(b, DefaultSKUCacheTTL)
+}
+
+func BenchmarkCreateProductsWithoutSKUCache(b *testing.B) {
+	benchmarkCreateProducts(b, 0)
+}
+
+func TestGetLowStockProducts(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	r, err := NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products := []struct {
+		sku          string
+		stock        int
+		reorderLevel int
+		deleted      bool
+	}{
+		{"BOX-0001", 10, 0, false},  // at the default level
+		{"BOX-0002", 11, 0, false},  // above the default level
+		{"BOX-0003", 5, 5, false},   // at its own level
+		{"BOX-0004", 6, 5, false},   // above its own level, below the default
+		{"BOX-0005", 15, 20, false}, // below its own level, above the default
+		{"BOX-0006", 0, 0, true},    // deleted
+	}
+	for _, p := range products {
+		product := &models.Product{
+			ID:           primitive.NewObjectID(),
+			SKUID:        p.sku,
+			Stock:        models.Stock{ActualStock: p.stock},
+			ReorderLevel: p.reorderLevel,
+			IsDeleted:    p.deleted,
+		}
+		if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		threshold int
+		want      []string
+	}{
+		{0, []string{"BOX-0001", "BOX-0003", "BOX-0005"}},
+		{6, []string{"BOX-0003", "BOX-0004"}},
+	}
+	for _, tt := range tests {
+		low, err := r.GetLowStockProducts(ctx, tt.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, product := range low {
+			got = append(got, product.SKUID)
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("GetLowStockProducts(%d) = %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+}
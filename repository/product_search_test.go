@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"goodpack-server/database"
+	"goodpack-server/models"
+)
+
+func TestTextSearchRanksBestMatchFirst(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	r := &ProductRepository{collection: db.Collection("products")}
+
+	if _, err := r.TextSearch(ctx, "kraft"); !errors.Is(err, ErrNoTextIndex) {
+		t.Fatalf("TextSearch without the index = %v, want ErrNoTextIndex", err)
+	}
+
+	products := []interface{}{
+		models.Product{SKUID: "BOX-0001", Name: "Box", Description: "Plain carton"},
+		models.Product{SKUID: "BOX-0002", Name: "Kraft Box", Description: "Kraft box for kraft gifts"},
+		models.Product{SKUID: "PAP-0001", Name: "Kraft Paper", Description: "Brown wrapping"},
+		models.Product{SKUID: "BOX-0003", Name: "Kraft Box", Description: "Kraft box", IsDeleted: true},
+		models.Product{SKUID: "TAP-0001", Name: "Tape", Description: "Clear packing tape"},
+	}
+	if _, err := r.collection.InsertMany(ctx, products); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.EnsureIndexes(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := r.TextSearch(ctx, "kraft box")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skuIDs := make(map[string]bool)
+	for _, product := range found {
+		skuIDs[product.SKUID] = true
+	}
+	if len(found) != 3 || !skuIDs["BOX-0001"] || !skuIDs["BOX-0002"] || !skuIDs["PAP-0001"] {
+		t.Fatalf("found %v, want BOX-0001, BOX-0002 and PAP-0001 without the deleted and unrelated products", skuIDs)
+	}
+	if found[0].SKUID != "BOX-0002" {
+		t.Errorf("first result = %s, want BOX-0002, which matches both words in its name and description", found[0].SKUID)
+	}
+}
+
+func TestContainsPatternMatchesLiteralText(t *testing.T) {
+	tests := []struct {
+		text    string
+		field   string
+		matches bool
+	}{
+		{"kraft", "Brown KRAFT box", true},
+		{"box (large)", "Gift box (large)", true},
+		{"box (large)", "Gift box large", false},
+		{"a.c", "abc", false},
+		{"10+", "Pack of 10+", true},
+	}
+	for _, tt := range tests {
+		pattern := containsPattern(tt.text)
+		if pattern.Options != "i" {
+			t.Errorf("%q: options = %q, want case-insensitive", tt.text, pattern.Options)
+		}
+		re := regexp.MustCompile("(?i)" + pattern.Pattern)
+		if got := re.MatchString(tt.field); got != tt.matches {
+			t.Errorf("%q against %q: match = %t, want %t", tt.text, tt.field, got, tt.matches)
+		}
+	}
+}
@@ -3,12 +3,14 @@ package repository
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"goodpack-server/metrics"
 	"goodpack-server/models"
 )
 
@@ -23,27 +25,50 @@ func NewPurchaseRepository(collection *mongo.Collection) *PurchaseRepository {
 }
 
 func (r *PurchaseRepository) Create(ctx context.Context, purchase *models.Purchase) error {
-	_, err := r.collection.InsertOne(ctx, purchase)
-	return err
+	defer metrics.ObserveMongoOperation("purchases", "Create", time.Now())
+
+	// Set the ID up front so that a retried insert cannot store the purchase twice
+	if purchase.ID.IsZero() {
+		purchase.ID = primitive.NewObjectID()
+	}
+	return retryInsert(ctx, func() error {
+		_, err := r.collection.InsertOne(ctx, purchase)
+		return err
+	})
 }
 
 func (r *PurchaseRepository) GetByID(ctx context.Context, id string) (*models.Purchase, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetByID", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var purchase models.Purchase
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&purchase)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&purchase)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &purchase, nil
 }
 
-func (r *PurchaseRepository) GetAll(ctx context.Context) ([]*models.Purchase, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// GetAll gets every purchase in sort order (natural order when sort is nil)
+func (r *PurchaseRepository) GetAll(ctx context.Context, approvalStatus string, sort bson.D) ([]*models.Purchase, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetAll", time.Now())
+
+	filter := bson.M{}
+	switch approvalStatus {
+	case "":
+	case models.PurchaseApprovalApproved:
+		// Purchases saved before approvals were recorded count as approved
+		filter["approvalStatus"] = bson.M{"$nin": bson.A{models.PurchaseApprovalPending, models.PurchaseApprovalRejected}}
+	default:
+		filter["approvalStatus"] = approvalStatus
+	}
+
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), sortOptions(sort))
 	if err != nil {
 		return nil, err
 	}
@@ -62,27 +87,40 @@ func (r *PurchaseRepository) GetAll(ctx context.Context) ([]*models.Purchase, er
 }
 
 func (r *PurchaseRepository) Update(ctx context.Context, id string, purchase *models.Purchase) error {
+	defer metrics.ObserveMongoOperation("purchases", "Update", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, purchase)
-	return err
+	return replaceVersioned(ctx, r.collection, objectID, &purchase.Version, purchase)
 }
 
 func (r *PurchaseRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveMongoOperation("purchases", "Delete", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
 	return err
 }
 
+// PurchaseCodeExists reports whether any purchase, deleted or not, has the code
+func (r *PurchaseRepository) PurchaseCodeExists(ctx context.Context, purchaseCode string) (bool, error) {
+	defer metrics.ObserveMongoOperation("purchases", "PurchaseCodeExists", time.Now())
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"purchaseCode": purchaseCode}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
 // GetNextSequenceNumber gets the next sequence number for a given prefix
 func (r *PurchaseRepository) GetNextSequenceNumber(ctx context.Context, prefix string) (int, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetNextSequenceNumber", time.Now())
+
 	// Find the highest sequence number for this prefix
 	filter := bson.M{
 		"purchaseCode": bson.M{
@@ -120,3 +158,111 @@ func (r *PurchaseRepository) GetNextSequenceNumber(ctx context.Context, prefix s
 	// If no previous purchase found or parsing failed, start from 1
 	return 1, nil
 }
+
+// GetBySupplierID gets all purchases from a supplier, newest first
+func (r *PurchaseRepository) GetBySupplierID(ctx context.Context, supplierID string) ([]*models.Purchase, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetBySupplierID", time.Now())
+
+	opts := options.Find().SetSort(bson.D{{Key: "purchaseDate", Value: -1}})
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"supplierId": supplierID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var purchases []*models.Purchase
+	for cursor.Next(ctx) {
+		var purchase models.Purchase
+		if err := cursor.Decode(&purchase); err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, &purchase)
+	}
+
+	return purchases, cursor.Err()
+}
+
+// GetLatestByProductID gets the most recent purchase containing the given product
+func (r *PurchaseRepository) GetLatestByProductID(ctx context.Context, productID string) (*models.Purchase, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetLatestByProductID", time.Now())
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "purchaseDate", Value: -1}})
+
+	var purchase models.Purchase
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"items.productId": productID}), opts).Decode(&purchase)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &purchase, nil
+}
+
+// GetByCustomerID gets a page of a customer's purchases, newest first
+func (r *PurchaseRepository) GetByCustomerID(ctx context.Context, customerID string, limit, skip int) ([]*models.Purchase, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetByCustomerID", time.Now())
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "purchaseDate", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(skip))
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"customerId": customerID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var purchases []*models.Purchase
+	for cursor.Next(ctx) {
+		var purchase models.Purchase
+		if err := cursor.Decode(&purchase); err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, &purchase)
+	}
+
+	return purchases, cursor.Err()
+}
+
+// GetCustomerTotals aggregates the count, grand total and latest date of a customer's purchases
+func (r *PurchaseRepository) GetCustomerTotals(ctx context.Context, customerID string) (*models.TransactionTotals, error) {
+	defer metrics.ObserveMongoOperation("purchases", "GetCustomerTotals", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"customerId": customerID})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": "$grandTotal"},
+			"lastDate":    bson.M{"$max": "$purchaseDate"},
+		}}},
+	}
+
+	return aggregateTotals(ctx, r.collection, pipeline)
+}
+
+// SetCustomerCode updates the customer code stored on every one of a customer's purchases, deleted ones included
+func (r *PurchaseRepository) SetCustomerCode(ctx context.Context, customerID, customerCode string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"customerId": customerID}, bson.M{
+		"$set": bson.M{"customerCode": customerCode},
+		"$inc": versionIncrement(),
+	})
+	return err
+}
+
+// SetItemPriceVariances stores the price variance worked out for each item of a purchase when it added stock.
+// The variances are derived from the purchase, so the version the client holds stays current.
+func (r *PurchaseRepository) SetItemPriceVariances(ctx context.Context, id string, items []models.PurchaseItem) error {
+	defer metrics.ObserveMongoOperation("purchases", "SetItemPriceVariances", time.Now())
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	fields := bson.M{}
+	for i, item := range items {
+		fields["items."+strconv.Itoa(i)+".priceVariance"] = item.PriceVariance
+	}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": fields})
+	return err
+}
@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"goodpack-server/apierrors"
 	"goodpack-server/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +14,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrQuotationNotAcceptable is returned by Accept when the quotation already has a sale or was rejected or expired
+var ErrQuotationNotAcceptable = fmt.Errorf("%w: quotation already has a sale or can no longer be accepted", apierrors.ErrConflict)
+
 type QuotationRepository struct {
 	collection *mongo.Collection
 }
@@ -22,16 +27,16 @@ func NewQuotationRepository(collection *mongo.Collection) *QuotationRepository {
 	}
 }
 
-func (r *QuotationRepository) Create(quotation *models.Quotation) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) Create(ctx context.Context, quotation *models.Quotation) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	_, err := r.collection.InsertOne(ctx, quotation)
 	return err
 }
 
-func (r *QuotationRepository) GetByID(id string) (*models.Quotation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) GetByID(ctx context.Context, id string) (*models.Quotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -42,17 +47,18 @@ func (r *QuotationRepository) GetByID(id string) (*models.Quotation, error) {
 	var quotation models.Quotation
 	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&quotation)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &quotation, nil
 }
 
-func (r *QuotationRepository) GetAll() ([]*models.Quotation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetAll gets every quotation in sort order (natural order when sort is nil)
+func (r *QuotationRepository) GetAll(ctx context.Context, sort bson.D) ([]*models.Quotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, bson.M{}, sortOptions(sort))
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +76,10 @@ func (r *QuotationRepository) GetAll() ([]*models.Quotation, error) {
 	return quotations, cursor.Err()
 }
 
-func (r *QuotationRepository) Update(id string, quotation *models.Quotation) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Update replaces a quotation, first appending the stored document to its version history. If the quotation
+// changed since it was read an error wrapping apierrors.ErrConflict is returned.
+func (r *QuotationRepository) Update(ctx context.Context, id string, quotation *models.Quotation, changedBy *string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -79,12 +87,78 @@ func (r *QuotationRepository) Update(id string, quotation *models.Quotation) err
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, quotation)
-	return err
+	return r.replace(ctx, objectID, quotation, changedBy)
+}
+
+// Accept marks a quotation accepted with the code of the sale created from it. A quotation that already has a
+// sale or is rejected or expired fails with ErrQuotationNotAcceptable, and one changed since it was read with
+// an error wrapping apierrors.ErrConflict, so two requests cannot both convert it. Run it in the transaction
+// creating the sale, so the sale is never left unlinked.
+func (r *QuotationRepository) Accept(ctx context.Context, quotation *models.Quotation, saleCode string, changedBy *string) error {
+	if (quotation.SaleCode != nil && *quotation.SaleCode != "") ||
+		quotation.Status == models.QuotationStatusRejected || quotation.Status == models.QuotationStatusExpired {
+		return ErrQuotationNotAcceptable
+	}
+
+	quotation.SetStatus(models.QuotationStatusAccepted)
+	quotation.SaleCode = &saleCode
+	quotation.UpdatedAt = time.Now()
+
+	return r.replace(ctx, quotation.ID, quotation, changedBy)
+}
+
+// replace replaces a quotation still at the version it was read at (see replaceVersioned), first appending the
+// stored document to its version history
+func (r *QuotationRepository) replace(ctx context.Context, objectID primitive.ObjectID, quotation *models.Quotation, changedBy *string) error {
+	current, err := r.collection.FindOne(ctx, bson.M{"_id": objectID}).Raw()
+	if err != nil {
+		return notFound(err)
+	}
+	versions, snapshot, err := splitVersions(current)
+	if err != nil {
+		return err
+	}
+
+	quotation.Versions = append(versions, models.QuotationVersion{
+		VersionNumber: len(versions) + 1,
+		Snapshot:      snapshot,
+		ChangedAt:     time.Now(),
+		ChangedBy:     changedBy,
+	})
+
+	return replaceVersioned(ctx, r.collection, objectID, &quotation.Version, quotation)
+}
+
+// splitVersions separates a stored quotation into its version history and a snapshot of the rest of the document
+func splitVersions(doc bson.Raw) ([]models.QuotationVersion, bson.Raw, error) {
+	var stored struct {
+		Versions []models.QuotationVersion `bson:"versions"`
+	}
+	if err := bson.Unmarshal(doc, &stored); err != nil {
+		return nil, nil, err
+	}
+
+	elements, err := doc.Elements()
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := bson.D{}
+	for _, element := range elements {
+		if element.Key() == "versions" {
+			continue
+		}
+		snapshot = append(snapshot, bson.E{Key: element.Key(), Value: element.Value()})
+	}
+
+	raw, err := bson.Marshal(snapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored.Versions, raw, nil
 }
 
-func (r *QuotationRepository) Delete(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -96,21 +170,21 @@ func (r *QuotationRepository) Delete(id string) error {
 	return err
 }
 
-func (r *QuotationRepository) GetByCode(code string) (*models.Quotation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) GetByCode(ctx context.Context, code string) (*models.Quotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	var quotation models.Quotation
 	err := r.collection.FindOne(ctx, bson.M{"quotationCode": code}).Decode(&quotation)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &quotation, nil
 }
 
 func (r *QuotationRepository) GetLastQuotationCode(ctx context.Context) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	var quotation models.Quotation
@@ -125,8 +199,8 @@ func (r *QuotationRepository) GetLastQuotationCode(ctx context.Context) (string,
 	return quotation.QuotationCode, nil
 }
 
-func (r *QuotationRepository) GetByCustomer(customerID string) ([]*models.Quotation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) GetByCustomer(ctx context.Context, customerID string) ([]*models.Quotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	cursor, err := r.collection.Find(ctx, bson.M{"customerId": customerID})
@@ -147,8 +221,8 @@ func (r *QuotationRepository) GetByCustomer(customerID string) ([]*models.Quotat
 	return quotations, cursor.Err()
 }
 
-func (r *QuotationRepository) GetByStatus(status string) ([]*models.Quotation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *QuotationRepository) GetByStatus(ctx context.Context, status string) ([]*models.Quotation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	cursor, err := r.collection.Find(ctx, bson.M{"status": status})
@@ -168,3 +242,140 @@ func (r *QuotationRepository) GetByStatus(status string) ([]*models.Quotation, e
 
 	return quotations, cursor.Err()
 }
+
+// ExpireOverdueQuotations marks every open quotation past its validUntil date as expired
+func (r *QuotationRepository) ExpireOverdueQuotations(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"validUntil": bson.M{"$lt": now},
+		"status": bson.M{"$nin": []string{
+			models.QuotationStatusExpired,
+			models.QuotationStatusAccepted,
+			models.QuotationStatusRejected,
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    models.QuotationStatusExpired,
+			"updatedAt": now,
+		},
+		"$inc": versionIncrement(),
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetFunnelStats counts the quotations dated from..to (zero = unbounded) by status, with their acceptance
+// rate, average days to acceptance, average value and the revenue of the sales created from them
+func (r *QuotationRepository) GetFunnelStats(ctx context.Context, from, to time.Time) (*models.QuotationFunnelStats, error) {
+	var stats []models.QuotationFunnelStats
+	if err := r.aggregateFunnel(ctx, from, to, nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return &models.QuotationFunnelStats{}, nil
+	}
+	stats[0].CalculateAcceptanceRate()
+	return &stats[0], nil
+}
+
+// GetFunnelStatsByCustomer is GetFunnelStats per customer, customers with the most quotations first
+func (r *QuotationRepository) GetFunnelStatsByCustomer(ctx context.Context, from, to time.Time) ([]models.CustomerQuotationFunnel, error) {
+	funnels := []models.CustomerQuotationFunnel{}
+	err := r.aggregateFunnel(ctx, from, to, "$customerId", bson.M{"customerName": bson.M{"$last": "$customerName"}}, &funnels)
+	if err != nil {
+		return nil, err
+	}
+	for i := range funnels {
+		funnels[i].CalculateAcceptanceRate()
+	}
+	return funnels, nil
+}
+
+// aggregateFunnel groups the quotations dated from..to by groupID into funnel stats, adding the extra
+// accumulators, and decodes the groups into results
+func (r *QuotationRepository) aggregateFunnel(ctx context.Context, from, to time.Time, groupID interface{}, extra bson.M, results interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	match := bson.M{}
+	quotationDate := bson.M{}
+	if !from.IsZero() {
+		quotationDate["$gte"] = from
+	}
+	if !to.IsZero() {
+		quotationDate["$lte"] = to
+	}
+	if len(quotationDate) > 0 {
+		match["quotationDate"] = quotationDate
+	}
+
+	countStatus := func(status string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, 1, 0}}}
+	}
+	group := bson.M{
+		"_id":               groupID,
+		"total":             bson.M{"$sum": 1},
+		"draft":             countStatus(models.QuotationStatusDraft),
+		"sent":              countStatus(models.QuotationStatusSent),
+		"accepted":          countStatus(models.QuotationStatusAccepted),
+		"rejected":          countStatus(models.QuotationStatusRejected),
+		"expired":           countStatus(models.QuotationStatusExpired),
+		"avgQuotationValue": bson.M{"$avg": "$value"},
+		// $avg skips the nulls of quotations that were not accepted
+		"avgDaysToAcceptance": bson.M{"$avg": "$daysToAcceptance"},
+		"convertedRevenue":    bson.M{"$sum": "$convertedRevenue"},
+	}
+	for field, accumulator := range extra {
+		group[field] = accumulator
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		// The sale created from the quotation, if it was not deleted since
+		{{Key: "$lookup", Value: bson.M{
+			"from": "sales",
+			"let":  bson.M{"saleCode": "$saleCode"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: notDeleted(bson.M{"$expr": bson.M{"$eq": bson.A{"$saleCode", "$$saleCode"}}})}},
+				{{Key: "$project", Value: bson.M{"revenue": bson.M{"$sum": "$items.totalPrice"}}}},
+			},
+			"as": "sale",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"value":            bson.M{"$sum": "$items.totalPrice"},
+			"convertedRevenue": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$sale.revenue", 0}}, 0}},
+			// Quotations accepted before acceptedAt was recorded count from their last update
+			"daysToAcceptance": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$status", models.QuotationStatusAccepted}},
+				bson.M{"$divide": bson.A{
+					bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$acceptedAt", "$updatedAt"}}, "$createdAt"}},
+					int64(24 * time.Hour / time.Millisecond),
+				}},
+				nil,
+			}},
+		}}},
+		{{Key: "$group", Value: group}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// SetCustomerCode updates the customer code stored on every one of a customer's quotations, deleted ones included
+func (r *QuotationRepository) SetCustomerCode(ctx context.Context, customerID, customerCode string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"customerId": customerID}, bson.M{"$set": bson.M{"customerCode": customerCode}})
+	return err
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+)
+
+func TestSplitVersionsLeavesHistoryOutOfTheSnapshot(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "quotationCode", Value: "QU-6901-0001"},
+		{Key: "versions", Value: bson.A{bson.M{"versionNumber": 1}}},
+		{Key: "status", Value: "draft"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, snapshot, err := splitVersions(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].VersionNumber != 1 {
+		t.Errorf("versions = %+v, want version 1", versions)
+	}
+	if _, err := snapshot.LookupErr("versions"); err == nil {
+		t.Error("snapshot includes the version history")
+	}
+	if code := snapshot.Lookup("quotationCode").StringValue(); code != "QU-6901-0001" {
+		t.Errorf("snapshot quotationCode = %q, want QU-6901-0001", code)
+	}
+}
+
+func TestQuotationUpdateKeepsVersionHistory(t *testing.T) {
+	ctx := context.Background()
+
+	db := testDatabase(t)
+	repo := NewQuotationRepository(db.Collection("quotations"))
+
+	quotation := &models.Quotation{
+		ID:            primitive.NewObjectID(),
+		QuotationCode: "QU-6901-0001",
+		Status:        models.QuotationStatusDraft,
+		Items:         []models.QuotationItem{{ProductID: "p1", Quantity: 10, UnitPrice: 20}},
+	}
+	if err := repo.Create(ctx, quotation); err != nil {
+		t.Fatal(err)
+	}
+	id := quotation.ID.Hex()
+
+	editor := "sales-1"
+	prices := []float64{18, 17, 15}
+	for _, price := range prices {
+		stored, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored.Items[0].UnitPrice = price
+		if err := repo.Update(ctx, id, stored, &editor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.Items[0].UnitPrice; got != 15 {
+		t.Errorf("current unit price = %v, want 15", got)
+	}
+	if len(stored.Versions) != 3 {
+		t.Fatalf("%d versions, want 3", len(stored.Versions))
+	}
+
+	// Each version is the quotation as it was before that update
+	wantPrices := []float64{20, 18, 17}
+	for i, version := range stored.Versions {
+		if version.VersionNumber != i+1 {
+			t.Errorf("version %d has number %d", i+1, version.VersionNumber)
+		}
+		if version.ChangedBy == nil || *version.ChangedBy != editor {
+			t.Errorf("version %d changed by %v, want %s", i+1, version.ChangedBy, editor)
+		}
+		snapshot, err := version.Quotation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := snapshot.Items[0].UnitPrice; got != wantPrices[i] {
+			t.Errorf("version %d unit price = %v, want %v", i+1, got, wantPrices[i])
+		}
+		if len(snapshot.Versions) != 0 {
+			t.Errorf("version %d snapshot holds %d versions, want none", i+1, len(snapshot.Versions))
+		}
+	}
+}
+
+func TestQuotationAcceptRejectsStaleVersion(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewQuotationRepository(db.Collection("quotations"))
+
+	quotation := &models.Quotation{ID: primitive.NewObjectID(), QuotationCode: "QU-6901-0002", Status: models.QuotationStatusSent}
+	if err := repo.Create(ctx, quotation); err != nil {
+		t.Fatal(err)
+	}
+	id := quotation.ID.Hex()
+	accepting, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editing, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shipping := 100.0
+	editing.ShippingCost = shipping
+	if err := repo.Update(ctx, id, editing, nil); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if err := repo.Accept(ctx, accepting, "INV-6901-0001", nil); !errors.Is(err, apierrors.ErrConflict) {
+		t.Fatalf("accepting the copy read before the edit = %v, want ErrConflict", err)
+	}
+
+	stored, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ShippingCost != shipping || stored.Status != models.QuotationStatusSent || stored.SaleCode != nil {
+		t.Errorf("stored = %+v, want the edit kept and the quotation not accepted", stored)
+	}
+
+	if err := repo.Accept(ctx, stored, "INV-6901-0001", nil); err != nil {
+		t.Fatalf("accepting the current copy: %v", err)
+	}
+	if err := repo.Accept(ctx, stored, "INV-6901-0002", nil); !errors.Is(err, ErrQuotationNotAcceptable) {
+		t.Errorf("accepting twice = %v, want ErrQuotationNotAcceptable", err)
+	}
+}
+
+func TestGetFunnelStats(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewQuotationRepository(db.Collection("quotations"))
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC) }
+	convertedSale, deletedSale := "INV-6701-0001", "INV-6701-0002"
+	quotation := func(customerID, customerName, status string, value float64, created, accepted time.Time, saleCode *string) *models.Quotation {
+		q := &models.Quotation{
+			ID:            primitive.NewObjectID(),
+			QuotationDate: created,
+			CustomerID:    customerID,
+			CustomerName:  customerName,
+			Status:        status,
+			SaleCode:      saleCode,
+			Items:         []models.QuotationItem{{ProductID: "p1", Quantity: 1, UnitPrice: value, TotalPrice: value}},
+			CreatedAt:     created,
+			UpdatedAt:     created,
+		}
+		if !accepted.IsZero() {
+			q.AcceptedAt = &accepted
+		}
+		return q
+	}
+	for _, q := range []*models.Quotation{
+		quotation("c1", "Cafe Amazon", models.QuotationStatusAccepted, 1000, day(1, 2), day(1, 6), &convertedSale),
+		quotation("c1", "Cafe Amazon", models.QuotationStatusDraft, 300, day(1, 10), time.Time{}, nil),
+		quotation("c1", "Cafe Amazon", models.QuotationStatusSent, 200, day(1, 11), time.Time{}, nil),
+		quotation("c2", "Sweet Bakery", models.QuotationStatusAccepted, 2000, day(1, 3), day(1, 5), &deletedSale),
+		quotation("c2", "Sweet Bakery", models.QuotationStatusRejected, 500, day(1, 4), time.Time{}, nil),
+		quotation("c2", "Sweet Bakery", models.QuotationStatusRejected, 800, day(1, 12), time.Time{}, nil),
+		quotation("c2", "Sweet Bakery", models.QuotationStatusExpired, 100, day(1, 20), time.Time{}, nil),
+		quotation("c1", "Cafe Amazon", models.QuotationStatusAccepted, 9999, day(2, 1), day(2, 2), nil), // after the range
+	} {
+		if err := repo.Create(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Collection("sales").InsertMany(ctx, []interface{}{
+		bson.M{"saleCode": convertedSale, "items": bson.A{bson.M{"totalPrice": 950}}},
+		bson.M{"saleCode": deletedSale, "items": bson.A{bson.M{"totalPrice": 2000}}, "isDeleted": true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := day(1, 1), day(1, 31)
+	stats, err := repo.GetFunnelStats(ctx, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 7 || stats.Draft != 1 || stats.Sent != 1 || stats.Accepted != 2 || stats.Rejected != 2 || stats.Expired != 1 {
+		t.Errorf("counts = %+v, want 7 quotations: 1 draft, 1 sent, 2 accepted, 2 rejected, 1 expired", stats)
+	}
+	if stats.AcceptanceRatePct == nil || *stats.AcceptanceRatePct != 50 {
+		t.Errorf("AcceptanceRatePct = %v, want 50", stats.AcceptanceRatePct)
+	}
+	if stats.AvgDaysToAcceptance == nil || math.Abs(*stats.AvgDaysToAcceptance-3) > 1e-9 {
+		t.Errorf("AvgDaysToAcceptance = %v, want 3", stats.AvgDaysToAcceptance)
+	}
+	if math.Abs(stats.AvgQuotationValue-700) > 1e-9 {
+		t.Errorf("AvgQuotationValue = %v, want 700", stats.AvgQuotationValue)
+	}
+	if stats.ConvertedRevenue != 950 {
+		t.Errorf("ConvertedRevenue = %v, want 950 from the sale that was not deleted", stats.ConvertedRevenue)
+	}
+
+	funnels, err := repo.GetFunnelStatsByCustomer(ctx, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		customerID string
+		total      int64
+		rate       float64
+	}{
+		{"c2", 4, 100.0 / 3},
+		{"c1", 3, 100},
+	}
+	if len(funnels) != len(want) {
+		t.Fatalf("got %d customers, want %d: %+v", len(funnels), len(want), funnels)
+	}
+	for i, w := range want {
+		got := funnels[i]
+		if got.CustomerID != w.customerID || got.Total != w.total ||
+			got.AcceptanceRatePct == nil || math.Abs(*got.AcceptanceRatePct-w.rate) > 1e-9 {
+			t.Errorf("customer %d = %+v, want %s with %d quotations and %.2f%% accepted", i, got, w.customerID, w.total, w.rate)
+		}
+	}
+}
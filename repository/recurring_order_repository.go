@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type RecurringOrderRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRecurringOrderRepository(collection *mongo.Collection) *RecurringOrderRepository {
+	return &RecurringOrderRepository{
+		collection: collection,
+	}
+}
+
+func (r *RecurringOrderRepository) Create(ctx context.Context, order *models.RecurringOrder) error {
+	result, err := r.collection.InsertOne(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		order.ID = oid
+	}
+	return nil
+}
+
+func (r *RecurringOrderRepository) GetByID(ctx context.Context, id string) (*models.RecurringOrder, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var order models.RecurringOrder
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&order)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &order, nil
+}
+
+// GetAll gets the recurring orders, soonest next run first; a non-empty customerID limits them to that customer
+func (r *RecurringOrderRepository) GetAll(ctx context.Context, customerID string) ([]*models.RecurringOrder, error) {
+	filter := bson.M{}
+	if customerID != "" {
+		filter["customerId"] = customerID
+	}
+	return r.find(ctx, notDeleted(filter))
+}
+
+// GetDue gets the active recurring orders whose next run is at or before now, oldest first
+func (r *RecurringOrderRepository) GetDue(ctx context.Context, now time.Time) ([]*models.RecurringOrder, error) {
+	return r.find(ctx, notDeleted(bson.M{
+		"isActive":  true,
+		"nextRunAt": bson.M{"$lte": now},
+	}))
+}
+
+func (r *RecurringOrderRepository) Update(ctx context.Context, id string, order *models.RecurringOrder) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, order)
+	return err
+}
+
+func (r *RecurringOrderRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
+
+func (r *RecurringOrderRepository) find(ctx context.Context, filter bson.M) ([]*models.RecurringOrder, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextRunAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []*models.RecurringOrder{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
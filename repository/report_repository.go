@@ -0,0 +1,674 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/errgroup"
+
+	"goodpack-server/metrics"
+	"goodpack-server/models"
+)
+
+// dashboardTopProducts is how many best sellers the dashboard lists
+const dashboardTopProducts = 3
+
+// ReportRepository runs the cross-collection aggregations behind the reports
+type ReportRepository struct {
+	sales       *mongo.Collection
+	purchases   *mongo.Collection
+	customers   *mongo.Collection
+	products    *mongo.Collection
+	saleReturns *mongo.Collection
+}
+
+func NewReportRepository(sales, purchases, customers, products, saleReturns *mongo.Collection) *ReportRepository {
+	return &ReportRepository{
+		sales:       sales,
+		purchases:   purchases,
+		customers:   customers,
+		products:    products,
+		saleReturns: saleReturns,
+	}
+}
+
+// GetDashboard aggregates revenue, cost, counts, receivables and best sellers for sales and purchases dated from..to
+func (r *ReportRepository) GetDashboard(ctx context.Context, from, to time.Time) (*models.Dashboard, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetDashboard", time.Now())
+
+	dashboard := &models.Dashboard{StartDate: from, EndDate: to}
+	inPeriod := func(field string) bson.M {
+		return notDeleted(bson.M{field: bson.M{"$gte": from, "$lte": to}})
+	}
+
+	// Sales revenue (line totals after discounts) and invoice count
+	sales, err := aggregateTotals(ctx, r.sales, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod("saleDate")}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": bson.M{"$sum": "$items.totalPrice"}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	dashboard.SalesRevenue = sales.TotalAmount
+	dashboard.SalesCount = sales.Count
+
+	// Purchase cost and purchase order count
+	purchases, err := aggregateTotals(ctx, r.purchases, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod("purchaseDate")}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": "$totalAmount"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	dashboard.PurchaseCost = purchases.TotalAmount
+	dashboard.PurchaseCount = purchases.Count
+
+	dashboard.GrossProfit = dashboard.SalesRevenue - dashboard.PurchaseCost
+	if dashboard.SalesRevenue != 0 {
+		dashboard.GrossMarginPct = dashboard.GrossProfit / dashboard.SalesRevenue * 100
+	}
+
+	if dashboard.NewCustomers, err = r.customers.CountDocuments(ctx, inPeriod("createdAt")); err != nil {
+		return nil, err
+	}
+
+	// Everything still owed on unpaid sales, whatever their date
+	receivables, err := aggregateTotals(ctx, r.sales, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"payment.isPaid": bson.M{"$ne": true}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": bson.M{"$subtract": bson.A{saleGrandTotal, bson.M{"$sum": "$payments.amount"}}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	dashboard.OutstandingReceivables = receivables.TotalAmount
+
+	if dashboard.TopProductsByQuantity, err = r.topProducts(ctx, inPeriod("saleDate"), "quantity"); err != nil {
+		return nil, err
+	}
+	if dashboard.TopProductsByRevenue, err = r.topProducts(ctx, inPeriod("saleDate"), "revenue"); err != nil {
+		return nil, err
+	}
+
+	return dashboard, nil
+}
+
+// periodComparisonTopProducts is how many best sellers each period of a comparison lists
+const periodComparisonTopProducts = 5
+
+// GetPeriodComparison aggregates the confirmed, not cancelled sales of two periods at the same time and compares
+// period 1 with period 2
+func (r *ReportRepository) GetPeriodComparison(ctx context.Context, period1Start, period1End, period2Start, period2End time.Time) (*models.PeriodComparison, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetPeriodComparison", time.Now())
+
+	var period1, period2 *models.PeriodPerformance
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		period1, err = r.periodSales(ctx, period1Start, period1End)
+		return err
+	})
+	g.Go(func() (err error) {
+		period2, err = r.periodSales(ctx, period2Start, period2End)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return models.NewPeriodComparison(*period1, *period2), nil
+}
+
+// periodSales totals the revenue, units and orders of the sales dated from..to and finds the best sellers by
+// revenue, in one aggregation
+func (r *ReportRepository) periodSales(ctx context.Context, from, to time.Time) (*models.PeriodPerformance, error) {
+	cursor, err := r.sales.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"saleDate": bson.M{"$gte": from, "$lte": to},
+			"isDraft":  bson.M{"$ne": true},
+			"status":   bson.M{"$ne": models.OrderStatusCancelled},
+		})}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":        nil,
+					"orderCount": bson.M{"$sum": 1},
+					"revenue":    bson.M{"$sum": bson.M{"$sum": "$items.totalPrice"}},
+					"unitsSold":  bson.M{"$sum": bson.M{"$sum": "$items.quantity"}},
+				}},
+			},
+			"topProducts": bson.A{
+				bson.M{"$unwind": "$items"},
+				bson.M{"$group": bson.M{
+					"_id":         "$items.productId",
+					"productName": bson.M{"$last": "$items.productName"},
+					"quantity":    bson.M{"$sum": "$items.quantity"},
+					"revenue":     bson.M{"$sum": "$items.totalPrice"},
+				}},
+				bson.M{"$sort": bson.D{{Key: "revenue", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": periodComparisonTopProducts},
+			},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Totals []struct {
+			OrderCount int64   `bson:"orderCount"`
+			Revenue    float64 `bson:"revenue"`
+			UnitsSold  float64 `bson:"unitsSold"`
+		} `bson:"totals"`
+		TopProducts []models.TopProduct `bson:"topProducts"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	period := &models.PeriodPerformance{StartDate: from, EndDate: to, TopProducts: []models.TopProduct{}}
+	if len(results) == 0 {
+		return period, nil
+	}
+	if len(results[0].Totals) > 0 {
+		totals := results[0].Totals[0]
+		period.Revenue = totals.Revenue
+		period.UnitsSold = totals.UnitsSold
+		period.OrderCount = totals.OrderCount
+	}
+	if results[0].TopProducts != nil {
+		period.TopProducts = results[0].TopProducts
+	}
+	return period, nil
+}
+
+// topProducts returns the best-selling products of the matched sales, ranked by quantity or revenue
+func (r *ReportRepository) topProducts(ctx context.Context, match bson.M, rankBy string) ([]models.TopProduct, error) {
+	cursor, err := r.sales.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$items.productId",
+			"productName": bson.M{"$last": "$items.productName"},
+			"quantity":    bson.M{"$sum": "$items.quantity"},
+			"revenue":     bson.M{"$sum": "$items.totalPrice"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: rankBy, Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: dashboardTopProducts}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	products := []models.TopProduct{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetProductProfitability totals the units and revenue of each product sold from..to and joins its
+// average purchase cost, taken from every purchase up to the end of the period since stock sold is often
+// bought earlier. Products sold without any purchase get a zero cost.
+func (r *ReportRepository) GetProductProfitability(ctx context.Context, from, to time.Time) (*models.ProfitabilityReport, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetProductProfitability", time.Now())
+
+	firstOf := func(field string) bson.M {
+		return bson.M{"$arrayElemAt": bson.A{field, 0}}
+	}
+
+	cursor, err := r.sales.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"saleDate": bson.M{"$gte": from, "$lte": to}})}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$items.productId",
+			"productName":  bson.M{"$last": "$items.productName"},
+			"unitsSold":    bson.M{"$sum": "$items.quantity"},
+			"salesRevenue": bson.M{"$sum": "$items.totalPrice"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.purchases.Name(),
+			"let":  bson.M{"productId": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: notDeleted(bson.M{"purchaseDate": bson.M{"$lte": to}})}},
+				{{Key: "$unwind", Value: "$items"}},
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$items.productId", "$$productId"}}}}},
+				{{Key: "$group", Value: bson.M{
+					"_id":      nil,
+					"quantity": bson.M{"$sum": "$items.quantity"},
+					"cost":     bson.M{"$sum": "$items.totalPrice"},
+				}}},
+			},
+			"as": "purchases",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.products.Name(),
+			"let": bson.M{"productId": bson.M{"$convert": bson.M{
+				"input": "$_id", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$productId"}}}}},
+				{{Key: "$project", Value: bson.M{"name": 1, "skuId": 1}}},
+			},
+			"as": "product",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":               0,
+			"productId":         "$_id",
+			"productName":       bson.M{"$ifNull": bson.A{firstOf("$product.name"), "$productName"}},
+			"skuId":             bson.M{"$ifNull": bson.A{firstOf("$product.skuId"), ""}},
+			"unitsSold":         1,
+			"salesRevenue":      1,
+			"purchasedQuantity": bson.M{"$ifNull": bson.A{firstOf("$purchases.quantity"), 0}},
+			"purchaseCost":      bson.M{"$ifNull": bson.A{firstOf("$purchases.cost"), 0}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []models.ProductProfitability
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+
+	return models.NewProfitabilityReport(from, to, products), nil
+}
+
+// GetRevenueTrend totals sales revenue and purchase cost per day, ISO week or month from..to, filling
+// periods without transactions with zeros
+func (r *ReportRepository) GetRevenueTrend(ctx context.Context, granularity string, from, to time.Time) ([]models.RevenueTrendPoint, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetRevenueTrend", time.Now())
+
+	// Same revenue and cost as the dashboard: line totals after discounts, excluding VAT and shipping
+	sales, err := r.totalsByPeriod(ctx, r.sales, "saleDate", granularity, from, to, bson.M{"$sum": "$items.totalPrice"})
+	if err != nil {
+		return nil, err
+	}
+	purchases, err := r.totalsByPeriod(ctx, r.purchases, "purchaseDate", granularity, from, to, "$totalAmount")
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewRevenueTrend(granularity, from, to, sales, purchases), nil
+}
+
+// totalsByPeriod counts the documents dated from..to and sums amount, grouped by the granularity's period label
+func (r *ReportRepository) totalsByPeriod(ctx context.Context, collection *mongo.Collection, dateField, granularity string, from, to time.Time, amount interface{}) (map[string]models.TransactionTotals, error) {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{dateField: bson.M{"$gte": from, "$lte": to}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format": models.TrendDateFormat(granularity),
+				"date":   "$" + dateField,
+			}},
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": amount},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Period                   string `bson:"_id"`
+		models.TransactionTotals `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]models.TransactionTotals, len(rows))
+	for _, row := range rows {
+		totals[row.Period] = row.TransactionTotals
+	}
+	return totals, nil
+}
+
+// GetPurchaseSpendByCategory sums the line totals of purchases dated from..to per product category,
+// largest first. Lines whose product no longer exists fall under an empty category.
+func (r *ReportRepository) GetPurchaseSpendByCategory(ctx context.Context, from, to time.Time) ([]models.PurchaseSpend, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetPurchaseSpendByCategory", time.Now())
+
+	return r.purchaseSpend(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"purchaseDate": bson.M{"$gte": from, "$lte": to}})}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.products.Name(),
+			"let": bson.M{"productId": bson.M{"$convert": bson.M{
+				"input": "$items.productId", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$productId"}}}}},
+				{{Key: "$project", Value: bson.M{"category": 1}}},
+			},
+			"as": "product",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$product.category", 0}}, ""}},
+			"amount": bson.M{"$sum": "$items.totalPrice"},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"name": "$_id"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "amount", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+}
+
+// GetPurchaseSpendBySupplier sums the totals of purchases dated from..to per supplier, largest first.
+// Purchases without a supplier fall under an empty supplier ID.
+func (r *ReportRepository) GetPurchaseSpendBySupplier(ctx context.Context, from, to time.Time) ([]models.PurchaseSpend, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetPurchaseSpendBySupplier", time.Now())
+
+	return r.purchaseSpend(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"purchaseDate": bson.M{"$gte": from, "$lte": to}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$ifNull": bson.A{"$supplierId", ""}},
+			"name":   bson.M{"$last": bson.M{"$ifNull": bson.A{"$supplierName", ""}}},
+			"amount": bson.M{"$sum": "$totalAmount"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "amount", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+}
+
+func (r *ReportRepository) purchaseSpend(ctx context.Context, pipeline mongo.Pipeline) ([]models.PurchaseSpend, error) {
+	cursor, err := r.purchases.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	spending := []models.PurchaseSpend{}
+	if err := cursor.All(ctx, &spending); err != nil {
+		return nil, err
+	}
+	return spending, nil
+}
+
+// GetReturnAnalysis aggregates the sale returns dated from..to per product (against the units sold in the
+// same period), per reason and per month, and compares their value with that of the sales they came from
+func (r *ReportRepository) GetReturnAnalysis(ctx context.Context, from, to time.Time) (*models.ReturnAnalysis, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetReturnAnalysis", time.Now())
+
+	inPeriod := bson.M{"returnDate": bson.M{"$gte": from, "$lte": to}}
+	salesInPeriod := notDeleted(bson.M{"saleDate": bson.M{"$gte": from, "$lte": to}})
+
+	// Each original sale is valued once, however many returns were made against it
+	var totals []models.ReturnValueTotals
+	err := r.aggregate(ctx, r.saleReturns, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$originalSaleId",
+			"returns":     bson.M{"$sum": 1},
+			"returnValue": bson.M{"$sum": bson.M{"$sum": "$items.totalPrice"}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.sales.Name(),
+			"let": bson.M{"saleId": bson.M{"$convert": bson.M{
+				"input": "$_id", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$saleId"}}}}},
+				{{Key: "$project", Value: bson.M{"value": bson.M{"$sum": "$items.totalPrice"}}}},
+			},
+			"as": "sale",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               nil,
+			"returns":           bson.M{"$sum": "$returns"},
+			"returnValue":       bson.M{"$sum": "$returnValue"},
+			"originalSaleValue": bson.M{"$sum": bson.M{"$sum": "$sale.value"}},
+		}}},
+	}, &totals)
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		totals = append(totals, models.ReturnValueTotals{})
+	}
+
+	var products []models.ProductReturnStats
+	err = r.aggregate(ctx, r.saleReturns, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":              "$items.productId",
+			"productName":      bson.M{"$last": "$items.productName"},
+			"productCode":      bson.M{"$last": "$items.productCode"},
+			"returns":          bson.M{"$sum": 1},
+			"returnedQuantity": bson.M{"$sum": "$items.quantity"},
+			"returnValue":      bson.M{"$sum": "$items.totalPrice"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.sales.Name(),
+			"let":  bson.M{"productId": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: salesInPeriod}},
+				{{Key: "$unwind", Value: "$items"}},
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$items.productId", "$$productId"}}}}},
+				{{Key: "$group", Value: bson.M{"_id": nil, "quantity": bson.M{"$sum": "$items.quantity"}}}},
+			},
+			"as": "sold",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"quantitySold": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$sold.quantity", 0}}, 0}},
+		}}},
+	}, &products)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []models.ReturnReasonCount
+	err = r.aggregate(ctx, r.saleReturns, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"$trim": bson.M{"input": bson.M{"$ifNull": bson.A{"$items.reason", ""}}}},
+			"count":    bson.M{"$sum": 1},
+			"quantity": bson.M{"$sum": "$items.quantity"},
+		}}},
+	}, &reasons)
+	if err != nil {
+		return nil, err
+	}
+
+	var months []models.ReturnTrendPoint
+	err = r.aggregate(ctx, r.saleReturns, mongo.Pipeline{
+		{{Key: "$match", Value: inPeriod}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"$dateToString": bson.M{"format": models.TrendDateFormat(models.TrendMonthly), "date": "$returnDate"}},
+			"returns":  bson.M{"$sum": 1},
+			"quantity": bson.M{"$sum": bson.M{"$sum": "$items.quantity"}},
+			"value":    bson.M{"$sum": bson.M{"$sum": "$items.totalPrice"}},
+		}}},
+	}, &months)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewReturnAnalysis(from, to, totals[0], products, reasons, months), nil
+}
+
+// aggregate runs pipeline on collection and decodes every result into results
+func (r *ReportRepository) aggregate(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// GetCustomerAging buckets what is still owed on each unpaid sale by the days from its sale date to asOf
+// and totals the buckets per customer. An empty customerID covers every customer.
+func (r *ReportRepository) GetCustomerAging(ctx context.Context, asOf time.Time, customerID string) (*models.AgingReport, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetCustomerAging", time.Now())
+
+	match := bson.M{"payment.isPaid": bson.M{"$ne": true}}
+	if customerID != "" {
+		match["customerId"] = customerID
+	}
+	inBucket := func(bucket string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$bucket", bucket}}, "$outstanding", 0}}}
+	}
+
+	var customers []models.CustomerAging
+	err := r.aggregate(ctx, r.sales, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(match)}},
+		{{Key: "$set", Value: bson.M{
+			"outstanding": bson.M{"$subtract": bson.A{saleGrandTotal, bson.M{"$sum": "$payments.amount"}}},
+			"daysSinceInvoice": bson.M{"$floor": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{asOf, "$saleDate"}},
+				int64(24 * time.Hour / time.Millisecond),
+			}}},
+		}}},
+		// Sales paid in full by their payments but not yet marked as paid owe nothing
+		{{Key: "$match", Value: bson.M{"outstanding": bson.M{"$gt": 0}}}},
+		{{Key: "$set", Value: bson.M{
+			"bucket": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$lte": bson.A{"$daysSinceInvoice", 30}}, "then": models.AgingCurrent},
+					bson.M{"case": bson.M{"$lte": bson.A{"$daysSinceInvoice", 60}}, "then": models.Aging31To60},
+					bson.M{"case": bson.M{"$lte": bson.A{"$daysSinceInvoice", 90}}, "then": models.Aging61To90},
+				},
+				"default": models.AgingOver90,
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$customerId",
+			"customerName": bson.M{"$last": "$customerName"},
+			"current":      inBucket(models.AgingCurrent),
+			"days31To60":   inBucket(models.Aging31To60),
+			"days61To90":   inBucket(models.Aging61To90),
+			"over90":       inBucket(models.AgingOver90),
+			"total":        bson.M{"$sum": "$outstanding"},
+			"invoiceCount": bson.M{"$sum": 1},
+		}}},
+	}, &customers)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewAgingReport(asOf, customers), nil
+}
+
+// GetDeliveryPerformance reads the deliveries of the confirmed, not cancelled sales dated from..to
+func (r *ReportRepository) GetDeliveryPerformance(ctx context.Context, from, to time.Time) (*models.DeliveryPerformance, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetDeliveryPerformance", time.Now())
+
+	var deliveries []models.SaleDelivery
+	err := r.aggregate(ctx, r.sales, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"saleDate": bson.M{"$gte": from, "$lte": to},
+			"isDraft":  bson.M{"$ne": true},
+			"status":   bson.M{"$ne": models.OrderStatusCancelled},
+		})}},
+		{{Key: "$project", Value: bson.M{"deliveryStatus": 1, "expectedDeliveryDate": 1, "actualDeliveryDate": 1}}},
+	}, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewDeliveryPerformance(from, to, deliveries), nil
+}
+
+// GetPurchaseVariance reads the price variance of every item of the purchases dated from..to that added stock and
+// totals it per product and per purchase
+func (r *ReportRepository) GetPurchaseVariance(ctx context.Context, from, to time.Time) (*models.PurchaseVarianceReport, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetPurchaseVariance", time.Now())
+
+	var items []models.PurchaseItemVariance
+	err := r.aggregate(ctx, r.purchases, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"purchaseDate":   bson.M{"$gte": from, "$lte": to},
+			"isDraft":        bson.M{"$ne": true},
+			"approvalStatus": bson.M{"$nin": bson.A{models.PurchaseApprovalPending, models.PurchaseApprovalRejected}},
+		})}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$project", Value: bson.M{
+			"purchaseId":    bson.M{"$toString": "$_id"},
+			"purchaseCode":  1,
+			"purchaseDate":  1,
+			"supplierName":  bson.M{"$ifNull": bson.A{"$supplierName", ""}},
+			"productId":     "$items.productId",
+			"productCode":   "$items.productCode",
+			"productName":   "$items.productName",
+			"quantity":      "$items.quantity",
+			"priceVariance": bson.M{"$ifNull": bson.A{"$items.priceVariance", 0}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "purchaseDate", Value: 1}, {Key: "_id", Value: 1}}}},
+	}, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewPurchaseVarianceReport(from, to, items), nil
+}
+
+// GetAlsoBought finds up to limit products sold together with productID: those on the same confirmed, not
+// cancelled sales, most shared sales first. Products that have been deleted are left out.
+func (r *ReportRepository) GetAlsoBought(ctx context.Context, productID string, limit int) ([]models.AlsoBoughtProduct, error) {
+	defer metrics.ObserveMongoOperation("reports", "GetAlsoBought", time.Now())
+
+	products := []models.AlsoBoughtProduct{}
+	err := r.aggregate(ctx, r.sales, mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{
+			"items.productId": productID,
+			"isDraft":         bson.M{"$ne": true},
+			"status":          bson.M{"$ne": models.OrderStatusCancelled},
+		})}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$match", Value: bson.M{"items.productId": bson.M{"$ne": productID}}}},
+		// A product on several lines of a sale counts once for that sale
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"sale": "$_id", "productId": "$items.productId"},
+			"quantity": bson.M{"$sum": "$items.quantity"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$_id.productId",
+			"saleCount": bson.M{"$sum": 1},
+			"quantity":  bson.M{"$sum": "$quantity"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.products.Name(),
+			"let": bson.M{"productId": bson.M{"$convert": bson.M{
+				"input": "$_id", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$productId"}}}}},
+				{{Key: "$match", Value: notDeleted(bson.M{})}},
+				{{Key: "$project", Value: bson.M{"skuId": 1, "code": 1, "name": 1, "category": 1}}},
+			},
+			"as": "product",
+		}}},
+		{{Key: "$unwind", Value: "$product"}},
+		{{Key: "$sort", Value: bson.D{{Key: "saleCount", Value: -1}, {Key: "quantity", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{
+			"saleCount": 1,
+			"quantity":  1,
+			"skuId":     "$product.skuId",
+			"code":      "$product.code",
+			"name":      "$product.name",
+			"category":  "$product.category",
+		}}},
+	}, &products)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
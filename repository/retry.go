@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/utils"
+)
+
+// retryWrite runs a write with utils.RetryWithBackoff. Inside a transaction it runs the write once: the
+// driver's WithTransaction retries the whole transaction, and a write retried on its own could run twice in it.
+func retryWrite(ctx context.Context, write func() error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return write()
+	}
+	return utils.RetryWithBackoff(ctx, writeRetryAttempts, write)
+}
+
+// retryInsert runs an insert of a document whose _id is set up front with retryWrite. A duplicate _id on a
+// retried attempt means an earlier attempt stored the document before its reply was lost, so it counts as success.
+func retryInsert(ctx context.Context, insert func() error) error {
+	attempt := 0
+	return retryWrite(ctx, func() error {
+		attempt++
+		err := insert()
+		if attempt > 1 && isDuplicateID(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// retryReplace runs a versioned replace of a document read at *version (see replaceVersioned) with retryWrite.
+// Every attempt stamps the document with a write token of this call. A conflict on a retried attempt while the
+// stored document is at the version the replace moves it to and carries that token means an earlier attempt
+// replaced it before its reply was lost, so it counts as success; another writer that reached the same version
+// in between left its own token, and the conflict stands.
+func retryReplace(ctx context.Context, version *int, replace func(writeToken string) error, storedWrite func() (int, string, error)) error {
+	next := *version + 1
+	token := primitive.NewObjectID().Hex()
+	attempt := 0
+	return retryWrite(ctx, func() error {
+		attempt++
+		err := replace(token)
+		if attempt > 1 && errors.Is(err, apierrors.ErrConflict) {
+			if stored, storedToken, storedErr := storedWrite(); storedErr == nil && stored == next && storedToken == token {
+				*version = next
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// isDuplicateID reports whether err is a duplicate key error on the _id index
+func isDuplicateID(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "index: _id_ ")
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/utils"
+)
+
+var (
+	errNetwork     = mongo.CommandError{Code: 6, Message: "connection reset", Labels: []string{"NetworkError"}}
+	errDuplicateID = mongo.WriteException{WriteErrors: []mongo.WriteError{{
+		Code:    11000,
+		Message: "E11000 duplicate key error collection: goodpack.sales index: _id_ dup key: { _id: ObjectId('65a000000000000000000000') }",
+	}}}
+	errDuplicateSKU = mongo.WriteException{WriteErrors: []mongo.WriteError{{
+		Code:    11000,
+		Message: "E11000 duplicate key error collection: goodpack.products index: skuId_1 dup key: { skuId: \"BG-0001\" }",
+	}}}
+)
+
+func fastRetries(t *testing.T) {
+	previous := utils.RetryBaseDelay
+	utils.RetryBaseDelay = time.Millisecond
+	t.Cleanup(func() { utils.RetryBaseDelay = previous })
+}
+
+// failingInsert returns the errors in turn, then nil
+func failingInsert(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryInsertTreatsDuplicateIDAfterRetryAsSuccess(t *testing.T) {
+	fastRetries(t)
+
+	calls := 0
+	if err := retryInsert(context.Background(), failingInsert(&calls, errNetwork, errDuplicateID)); err != nil {
+		t.Errorf("retryInsert = %v, want nil", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestRetryInsertKeepsDuplicateIDOnFirstAttempt(t *testing.T) {
+	fastRetries(t)
+
+	calls := 0
+	if err := retryInsert(context.Background(), failingInsert(&calls, errDuplicateID)); !isDuplicateID(err) {
+		t.Errorf("retryInsert = %v, want the duplicate _id error", err)
+	}
+}
+
+func TestRetryInsertKeepsOtherDuplicateKeysAfterRetry(t *testing.T) {
+	fastRetries(t)
+
+	calls := 0
+	if err := retryInsert(context.Background(), failingInsert(&calls, errNetwork, errDuplicateSKU)); !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("retryInsert = %v, want the duplicate SKU error", err)
+	}
+}
+
+func TestRetryWriteDoesNotRetryInsideSession(t *testing.T) {
+	fastRetries(t)
+
+	// Connecting is lazy, so the client needs no server to start a session
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	session, err := client.StartSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.EndSession(context.Background())
+
+	ctx := mongo.NewSessionContext(context.Background(), session)
+	calls := 0
+	if err := retryWrite(ctx, failingInsert(&calls, errNetwork)); !mongo.IsNetworkError(err) {
+		t.Errorf("retryWrite = %v, want the network error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+// failingReplace is failingInsert for retryReplace, keeping the write token it is given in token
+func failingReplace(calls *int, token *string, errs ...error) func(string) error {
+	replace := failingInsert(calls, errs...)
+	return func(writeToken string) error {
+		*token = writeToken
+		return replace()
+	}
+}
+
+func TestRetryReplaceTreatsOwnWriteAfterRetryAsSuccess(t *testing.T) {
+	fastRetries(t)
+
+	version, calls, token := 3, 0, ""
+	stored := func() (int, string, error) { return 4, token, nil } // the lost first attempt saved version 4
+	if err := retryReplace(context.Background(), &version, failingReplace(&calls, &token, errNetwork, errVersionConflict), stored); err != nil {
+		t.Errorf("retryReplace = %v, want nil", err)
+	}
+	if version != 4 {
+		t.Errorf("version = %d, want 4", version)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestRetryReplaceKeepsConflictWithAnotherWriteAtTheSameVersion(t *testing.T) {
+	fastRetries(t)
+
+	version, calls, token := 3, 0, ""
+	stored := func() (int, string, error) { return 4, "someone else", nil } // another request saved version 4
+	if err := retryReplace(context.Background(), &version, failingReplace(&calls, &token, errNetwork, errVersionConflict), stored); !errors.Is(err, apierrors.ErrConflict) {
+		t.Errorf("retryReplace = %v, want ErrConflict", err)
+	}
+	if version != 3 {
+		t.Errorf("version = %d, want it left at 3", version)
+	}
+}
+
+func TestRetryReplaceKeepsConflictAtOtherVersion(t *testing.T) {
+	fastRetries(t)
+
+	version, calls, token := 3, 0, ""
+	stored := func() (int, string, error) { return 5, token, nil } // someone else saved over our write
+	if err := retryReplace(context.Background(), &version, failingReplace(&calls, &token, errNetwork, errVersionConflict), stored); !errors.Is(err, apierrors.ErrConflict) {
+		t.Errorf("retryReplace = %v, want ErrConflict", err)
+	}
+	if version != 3 {
+		t.Errorf("version = %d, want it left at 3", version)
+	}
+}
+
+func TestRetryReplaceKeepsConflictOnFirstAttempt(t *testing.T) {
+	fastRetries(t)
+
+	version, calls, token := 3, 0, ""
+	stored := func() (int, string, error) { return 4, token, nil }
+	if err := retryReplace(context.Background(), &version, failingReplace(&calls, &token, errVersionConflict), stored); !errors.Is(err, apierrors.ErrConflict) {
+		t.Errorf("retryReplace = %v, want ErrConflict", err)
+	}
+}
+
+func TestWriteTokenDocumentStampsTheToken(t *testing.T) {
+	product := &models.Product{SKUID: "BOX-0001", Version: 4}
+	data, err := bson.Marshal(writeTokenDocument{product, "token-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored struct {
+		SKUID      string `bson:"skuId"`
+		Version    int    `bson:"version"`
+		WriteToken string `bson:"writeToken"`
+	}
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.SKUID != "BOX-0001" || stored.Version != 4 || stored.WriteToken != "token-1" {
+		t.Errorf("stored = %+v, want the product at version 4 with writeToken token-1", stored)
+	}
+}
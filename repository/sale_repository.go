@@ -4,12 +4,14 @@ import (
 	"context"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"goodpack-server/metrics"
 	"goodpack-server/models"
 )
 
@@ -23,30 +25,52 @@ func NewSaleRepository(collection *mongo.Collection) *SaleRepository {
 	}
 }
 
-func (r *SaleRepository) Create(sale *models.Sale) error {
-	ctx := context.Background()
-	_, err := r.collection.InsertOne(ctx, sale)
-	return err
+func (r *SaleRepository) Create(ctx context.Context, sale *models.Sale) error {
+	defer metrics.ObserveMongoOperation("sales", "Create", time.Now())
+
+	// Set the ID up front so that a retried insert cannot store the sale twice
+	if sale.ID.IsZero() {
+		sale.ID = primitive.NewObjectID()
+	}
+	return retryInsert(ctx, func() error {
+		_, err := r.collection.InsertOne(ctx, sale)
+		return err
+	})
 }
 
-func (r *SaleRepository) GetByID(id string) (*models.Sale, error) {
-	ctx := context.Background()
+func (r *SaleRepository) GetByID(ctx context.Context, id string) (*models.Sale, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetByID", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
 	var sale models.Sale
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&sale)
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&sale)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &sale, nil
 }
 
-func (r *SaleRepository) GetAll(ctx context.Context) ([]models.Sale, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// GetAll gets every sale in sort order (natural order when sort is nil); a non-empty deliveryStatus limits them
+// to that delivery status
+func (r *SaleRepository) GetAll(ctx context.Context, deliveryStatus string, sort bson.D) ([]models.Sale, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetAll", time.Now())
+
+	filter := bson.M{}
+	switch deliveryStatus {
+	case "":
+	case models.DeliveryStatusPending:
+		// Sales saved before deliveries were tracked have no delivery status
+		filter["deliveryStatus"] = bson.M{"$in": bson.A{models.DeliveryStatusPending, nil}}
+	default:
+		filter["deliveryStatus"] = deliveryStatus
+	}
+
+	cursor, err := r.collection.Find(ctx, notDeleted(filter), sortOptions(sort))
 	if err != nil {
 		return nil, err
 	}
@@ -60,29 +84,40 @@ func (r *SaleRepository) GetAll(ctx context.Context) ([]models.Sale, error) {
 	return sales, nil
 }
 
-func (r *SaleRepository) Update(id string, sale *models.Sale) error {
-	ctx := context.Background()
+func (r *SaleRepository) Update(ctx context.Context, id string, sale *models.Sale) error {
+	defer metrics.ObserveMongoOperation("sales", "Update", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, sale)
-	return err
+	return replaceVersioned(ctx, r.collection, objectID, &sale.Version, sale)
 }
 
-func (r *SaleRepository) Delete(id string) error {
-	ctx := context.Background()
+func (r *SaleRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveMongoOperation("sales", "Delete", time.Now())
+
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
 	return err
 }
 
+// SaleCodeExists reports whether any sale, deleted or not, has the code
+func (r *SaleRepository) SaleCodeExists(ctx context.Context, saleCode string) (bool, error) {
+	defer metrics.ObserveMongoOperation("sales", "SaleCodeExists", time.Now())
+
+	count, err := r.collection.CountDocuments(ctx, bson.M{"saleCode": saleCode}, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
 func (r *SaleRepository) GetNextSequenceNumber(ctx context.Context, prefix string) (int, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetNextSequenceNumber", time.Now())
+
 	// Find the highest sequence number for the given prefix
 	filter := bson.M{
 		"saleCode": bson.M{
@@ -124,3 +159,87 @@ func (r *SaleRepository) GetNextSequenceNumber(ctx context.Context, prefix strin
 
 	return seq + 1, nil
 }
+
+// GetByCustomerID gets a page of a customer's sales, newest first
+func (r *SaleRepository) GetByCustomerID(ctx context.Context, customerID string, limit, skip int) ([]*models.Sale, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetByCustomerID", time.Now())
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "saleDate", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(skip))
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{"customerId": customerID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sales []*models.Sale
+	for cursor.Next(ctx) {
+		var sale models.Sale
+		if err := cursor.Decode(&sale); err != nil {
+			return nil, err
+		}
+		sales = append(sales, &sale)
+	}
+
+	return sales, cursor.Err()
+}
+
+// saleGrandTotal computes a sale's grand total in an aggregation.
+// Sales don't store a grand total, so it is items + VAT at the sale's rate (VAT sales only) + shipping.
+// Sales saved before the rate was stored were taxed at models.DefaultVATRate.
+var saleGrandTotal = bson.M{"$add": bson.A{
+	bson.M{"$multiply": bson.A{
+		bson.M{"$sum": "$items.totalPrice"},
+		bson.M{"$cond": bson.A{"$isVAT", bson.M{"$add": bson.A{1, bson.M{"$ifNull": bson.A{"$vatRate", models.DefaultVATRate}}}}, 1}},
+	}},
+	bson.M{"$ifNull": bson.A{"$shippingCost", 0}},
+}}
+
+// GetCustomerTotals aggregates the count, grand total and latest date of a customer's sales
+func (r *SaleRepository) GetCustomerTotals(ctx context.Context, customerID string) (*models.TransactionTotals, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetCustomerTotals", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"customerId": customerID})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": saleGrandTotal},
+			"lastDate":    bson.M{"$max": "$saleDate"},
+		}}},
+	}
+
+	return aggregateTotals(ctx, r.collection, pipeline)
+}
+
+// GetTotalUnpaidByCustomer sums what is still owed on a customer's unpaid sales (grand total less recorded
+// payments); draft sales are not owed until confirmed and cancelled sales are not owed at all
+func (r *SaleRepository) GetTotalUnpaidByCustomer(ctx context.Context, customerID string) (float64, error) {
+	defer metrics.ObserveMongoOperation("sales", "GetTotalUnpaidByCustomer", time.Now())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"customerId": customerID, "payment.isPaid": bson.M{"$ne": true}, "isDraft": bson.M{"$ne": true}, "status": bson.M{"$ne": models.OrderStatusCancelled}})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"count":       bson.M{"$sum": 1},
+			"totalAmount": bson.M{"$sum": bson.M{"$subtract": bson.A{saleGrandTotal, bson.M{"$sum": "$payments.amount"}}}},
+		}}},
+	}
+
+	totals, err := aggregateTotals(ctx, r.collection, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	return totals.TotalAmount, nil
+}
+
+// SetCustomerCode updates the customer code stored on every one of a customer's sales, deleted ones included
+func (r *SaleRepository) SetCustomerCode(ctx context.Context, customerID, customerCode string) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"customerId": customerID}, bson.M{
+		"$set": bson.M{"customerCode": customerCode},
+		"$inc": versionIncrement(),
+	})
+	return err
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type SaleReturnRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSaleReturnRepository(collection *mongo.Collection) *SaleReturnRepository {
+	return &SaleReturnRepository{
+		collection: collection,
+	}
+}
+
+func (r *SaleReturnRepository) Create(ctx context.Context, saleReturn *models.SaleReturn) error {
+	// Generate return code
+	if saleReturn.ReturnCode == "" {
+		lastCode, err := r.GetLastReturnCode(ctx)
+		if err != nil {
+			return err
+		}
+		returnCode, err := models.GenerateReturnCode(lastCode)
+		if err != nil {
+			return err
+		}
+		saleReturn.ReturnCode = returnCode
+	}
+
+	if saleReturn.ID.IsZero() {
+		saleReturn.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, saleReturn)
+	return err
+}
+
+func (r *SaleReturnRepository) GetByID(ctx context.Context, id string) (*models.SaleReturn, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var saleReturn models.SaleReturn
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&saleReturn)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &saleReturn, nil
+}
+
+func (r *SaleReturnRepository) GetAll(ctx context.Context) ([]*models.SaleReturn, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// GetBySaleID gets all returns made against a sale
+func (r *SaleReturnRepository) GetBySaleID(ctx context.Context, saleID string) ([]*models.SaleReturn, error) {
+	return r.find(ctx, bson.M{"originalSaleId": saleID})
+}
+
+func (r *SaleReturnRepository) Update(ctx context.Context, id string, saleReturn *models.SaleReturn) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, saleReturn)
+	return err
+}
+
+func (r *SaleReturnRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+// GetLastReturnCode returns the highest return code issued so far
+func (r *SaleReturnRepository) GetLastReturnCode(ctx context.Context) (string, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "returnCode", Value: -1}})
+
+	var saleReturn models.SaleReturn
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&saleReturn)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return saleReturn.ReturnCode, nil
+}
+
+func (r *SaleReturnRepository) find(ctx context.Context, filter bson.M) ([]*models.SaleReturn, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "returnDate", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var saleReturns []*models.SaleReturn
+	for cursor.Next(ctx) {
+		var saleReturn models.SaleReturn
+		if err := cursor.Decode(&saleReturn); err != nil {
+			return nil, err
+		}
+		saleReturns = append(saleReturns, &saleReturn)
+	}
+
+	return saleReturns, cursor.Err()
+}
@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type SerialNumberRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSerialNumberRepository(collection *mongo.Collection) *SerialNumberRepository {
+	return &SerialNumberRepository{
+		collection: collection,
+	}
+}
+
+// Receive records the units of a purchase as available. Serial numbers already recorded are left unchanged,
+// so receiving the same purchase again (e.g. after it is updated) only adds the new ones.
+func (r *SerialNumberRepository) Receive(ctx context.Context, productID string, serials []string, purchaseID string, receivedAt time.Time) error {
+	if len(serials) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(serials))
+	for _, serial := range serials {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"productId": productID, "serialNumber": serial}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				"productId":    productID,
+				"serialNumber": serial,
+				"status":       models.SerialNumberStatusAvailable,
+				"purchaseId":   purchaseID,
+				"receivedAt":   receivedAt,
+				"updatedAt":    now,
+			}}).
+			SetUpsert(true))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// GetByProduct gets the serial numbers of a product in serial number order; a non-empty status limits them
+// to that status
+func (r *SerialNumberRepository) GetByProduct(ctx context.Context, productID, status string) ([]*models.SerialNumber, error) {
+	filter := bson.M{"productId": productID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "serialNumber", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	serialNumbers := []*models.SerialNumber{}
+	if err := cursor.All(ctx, &serialNumbers); err != nil {
+		return nil, err
+	}
+	return serialNumbers, nil
+}
+
+// GetBySerials gets the recorded units of a product among serials, keyed by serial number
+func (r *SerialNumberRepository) GetBySerials(ctx context.Context, productID string, serials []string) (map[string]*models.SerialNumber, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"productId": productID, "serialNumber": bson.M{"$in": serials}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var serialNumbers []*models.SerialNumber
+	if err := cursor.All(ctx, &serialNumbers); err != nil {
+		return nil, err
+	}
+
+	bySerial := make(map[string]*models.SerialNumber, len(serialNumbers))
+	for _, serialNumber := range serialNumbers {
+		bySerial[serialNumber.SerialNumber] = serialNumber
+	}
+	return bySerial, nil
+}
+
+// MarkSold marks units of a product as sold by a sale; only units in stock are changed
+func (r *SerialNumberRepository) MarkSold(ctx context.Context, productID string, serials []string, saleID string) error {
+	now := time.Now()
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{
+			"productId":    productID,
+			"serialNumber": bson.M{"$in": serials},
+			"status":       bson.M{"$in": []string{models.SerialNumberStatusAvailable, models.SerialNumberStatusReturned}},
+		},
+		bson.M{"$set": bson.M{
+			"status":    models.SerialNumberStatusSold,
+			"saleId":    saleID,
+			"soldAt":    now,
+			"updatedAt": now,
+		}},
+	)
+	return err
+}
+
+// MarkReturned marks units sold by a sale as returned, putting them back in stock
+func (r *SerialNumberRepository) MarkReturned(ctx context.Context, productID string, serials []string, saleID string) error {
+	now := time.Now()
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{
+			"productId":    productID,
+			"serialNumber": bson.M{"$in": serials},
+			"status":       models.SerialNumberStatusSold,
+			"saleId":       saleID,
+		},
+		bson.M{"$set": bson.M{
+			"status":     models.SerialNumberStatusReturned,
+			"returnedAt": now,
+			"updatedAt":  now,
+		}},
+	)
+	return err
+}
+
+// ReleaseSale puts every unit still sold by a sale back to available, for when the sale is deleted or its
+// items are replaced
+func (r *SerialNumberRepository) ReleaseSale(ctx context.Context, saleID string) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"saleId": saleID, "status": models.SerialNumberStatusSold},
+		bson.M{
+			"$set":   bson.M{"status": models.SerialNumberStatusAvailable, "updatedAt": time.Now()},
+			"$unset": bson.M{"saleId": "", "soldAt": ""},
+		},
+	)
+	return err
+}
+
+// RemovePurchase deletes the units a purchase received that are still available, for when the purchase is
+// cancelled; units already sold or returned keep their history
+func (r *SerialNumberRepository) RemovePurchase(ctx context.Context, purchaseID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"purchaseId": purchaseID, "status": models.SerialNumberStatusAvailable})
+	return err
+}
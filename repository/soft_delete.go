@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// notDeleted adds the soft-delete exclusion clause to a filter
+func notDeleted(filter bson.M) bson.M {
+	filter["isDeleted"] = bson.M{"$ne": true}
+	return filter
+}
+
+// softDeleteUpdate returns the update document that marks a record as deleted
+func softDeleteUpdate() bson.M {
+	now := time.Now()
+	return bson.M{
+		"$set": bson.M{
+			"isDeleted": true,
+			"deletedAt": now,
+			"updatedAt": now,
+		},
+	}
+}
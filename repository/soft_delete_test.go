@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+)
+
+func TestNotDeletedExcludesDeletedRecords(t *testing.T) {
+	filter := notDeleted(bson.M{"category": "Box"})
+	if filter["category"] != "Box" {
+		t.Errorf("filter lost its own clause: %v", filter)
+	}
+	if clause, ok := filter["isDeleted"].(bson.M); !ok || clause["$ne"] != true {
+		t.Errorf("isDeleted clause = %v, want {$ne: true}", filter["isDeleted"])
+	}
+}
+
+func TestSoftDeletedProductIsHiddenUntilRestored(t *testing.T) {
+	repo := &ProductRepository{collection: testDatabase(t).Collection("products")}
+	ctx := context.Background()
+
+	kept := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kept"}
+	deleted := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0002", Name: "Deleted"}
+	for _, product := range []*models.Product{kept, deleted} {
+		if _, err := repo.collection.InsertOne(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Delete(ctx, deleted.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	products, err := repo.GetAll(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 || products[0].ID != kept.ID {
+		t.Errorf("GetAll returned %d products, want only the one not deleted", len(products))
+	}
+	if _, err := repo.GetByID(ctx, deleted.ID.Hex()); !errors.Is(err, apierrors.ErrNotFound) {
+		t.Errorf("GetByID of the deleted product = %v, want ErrNotFound", err)
+	}
+
+	// The record is still stored, marked deleted
+	var stored models.Product
+	if err := repo.collection.FindOne(ctx, bson.M{"_id": deleted.ID}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !stored.IsDeleted || stored.DeletedAt == nil {
+		t.Errorf("stored product isDeleted=%v deletedAt=%v, want it marked deleted", stored.IsDeleted, stored.DeletedAt)
+	}
+
+	if err := repo.Restore(ctx, deleted.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repo.GetByID(ctx, deleted.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetByID after Restore = %v", err)
+	}
+	if restored.IsDeleted || restored.DeletedAt != nil {
+		t.Errorf("restored product isDeleted=%v deletedAt=%v, want neither", restored.IsDeleted, restored.DeletedAt)
+	}
+	if err := repo.Restore(ctx, kept.ID.Hex()); !errors.Is(err, apierrors.ErrNotFound) {
+		t.Errorf("Restore of a product that is not deleted = %v, want ErrNotFound", err)
+	}
+}
+
+func TestHardDeleteRemovesSoftDeletedProduct(t *testing.T) {
+	repo := &ProductRepository{collection: testDatabase(t).Collection("products")}
+	ctx := context.Background()
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Box"}
+	if _, err := repo.collection.InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, product.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.HardDelete(ctx, product.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := repo.collection.CountDocuments(ctx, bson.M{}); count != 0 {
+		t.Errorf("%d products stored after HardDelete, want 0", count)
+	}
+}
+
+func TestSoftDeletedCustomerAndPurchaseAreHidden(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	customerRepo := NewCustomerRepository(db.Collection("customers"))
+	purchaseRepo := NewPurchaseRepository(db.Collection("purchases"))
+
+	customer := &models.Customer{CompanyName: "Goodpack"}
+	if err := customerRepo.Create(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+	purchase := &models.Purchase{PurchaseCode: "PUR-VAT-6701-0001"}
+	if err := purchaseRepo.Create(ctx, purchase); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := customerRepo.Delete(ctx, customer.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if err := purchaseRepo.Delete(ctx, purchase.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	if customers, err := customerRepo.GetAll(ctx, nil); err != nil || len(customers) != 0 {
+		t.Errorf("customers GetAll = %d, %v, want none", len(customers), err)
+	}
+	if purchases, err := purchaseRepo.GetAll(ctx, "", nil); err != nil || len(purchases) != 0 {
+		t.Errorf("purchases GetAll = %d, %v, want none", len(purchases), err)
+	}
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sortOptions returns find options applying sort, or no ordering when sort is empty
+func sortOptions(sort bson.D) *options.FindOptions {
+	opts := options.Find()
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+	return opts
+}
@@ -41,7 +41,7 @@ func (r *StockAdjustmentRepository) GetByID(ctx context.Context, id string) (*mo
 	var adjustment models.StockAdjustment
 	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&adjustment)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return &adjustment, nil
@@ -184,8 +184,362 @@ func (r *StockAdjustmentRepository) Delete(ctx context.Context, id string) error
 	}
 
 	if result.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
+		return notFound(mongo.ErrNoDocuments)
 	}
 
 	return nil
 }
+
+// GetSalesRevenueByProduct returns the net quantity sold and revenue per product since a date.
+// Quantities come from sale stock adjustments (stock put back by sale edits counts as negative);
+// the price is the sale line's discounted unit price, looked up from the sale.
+func (r *StockAdjustmentRepository) GetSalesRevenueByProduct(ctx context.Context, since time.Time) ([]models.ProductRevenue, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"sourceType": models.SourceTypeSale,
+			"createdAt":  bson.M{"$gte": since},
+		}}},
+	}
+	pipeline = append(pipeline, saleLinePriceStages()...)
+	pipeline = append(pipeline, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$productId",
+			"productName": bson.M{"$last": "$productName"},
+			"skuId":       bson.M{"$last": "$skuId"},
+			"quantity":    bson.M{"$sum": "$signedQuantity"},
+			"revenue":     bson.M{"$sum": bson.M{"$multiply": bson.A{"$signedQuantity", "$unitPrice"}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"revenue": bson.M{"$gt": 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "revenue", Value: -1}}}},
+	}...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revenues := []models.ProductRevenue{}
+	if err := cursor.All(ctx, &revenues); err != nil {
+		return nil, err
+	}
+	return revenues, nil
+}
+
+// GetUnitsSoldByProduct returns the net quantity of each product sold since a date, keyed by product ID.
+// Stock put back by sale edits counts as negative; products without sale stock adjustments are left out.
+func (r *StockAdjustmentRepository) GetUnitsSoldByProduct(ctx context.Context, since time.Time) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"sourceType": models.SourceTypeSale,
+			"createdAt":  bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$productId",
+			"quantity": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$adjustmentType", models.AdjustmentTypeReduce}},
+				"$quantity",
+				bson.M{"$multiply": bson.A{"$quantity", -1}},
+			}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ProductID string `bson:"_id"`
+		Quantity  int    `bson:"quantity"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	unitsSold := make(map[string]int, len(rows))
+	for _, row := range rows {
+		unitsSold[row.ProductID] = row.Quantity
+	}
+	return unitsSold, nil
+}
+
+// GetSaleCountsByProduct returns the number of sales that took each product out of stock since a date, keyed
+// by product ID
+func (r *StockAdjustmentRepository) GetSaleCountsByProduct(ctx context.Context, since time.Time) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"sourceType":     models.SourceTypeSale,
+			"adjustmentType": models.AdjustmentTypeReduce,
+			"createdAt":      bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$productId",
+			"sales": bson.M{"$addToSet": "$sourceId"},
+		}}},
+		{{Key: "$project", Value: bson.M{"count": bson.M{"$size": "$sales"}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ProductID string `bson:"_id"`
+		Count     int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	saleCounts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		saleCounts[row.ProductID] = row.Count
+	}
+	return saleCounts, nil
+}
+
+// GetSalesByPeriod returns a product's net quantity sold and revenue in each period from..to that had sales,
+// keyed by the period label of the granularity (see models.TrendDateFormat)
+func (r *StockAdjustmentRepository) GetSalesByPeriod(ctx context.Context, productID, granularity string, from, to time.Time) (map[string]models.PeriodSales, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"productId":  productID,
+			"sourceType": models.SourceTypeSale,
+			"createdAt":  bson.M{"$gte": from, "$lte": to},
+		}}},
+	}
+	pipeline = append(pipeline, saleLinePriceStages()...)
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.M{
+		"_id":      bson.M{"$dateToString": bson.M{"format": models.TrendDateFormat(granularity), "date": "$createdAt"}},
+		"quantity": bson.M{"$sum": "$signedQuantity"},
+		"revenue":  bson.M{"$sum": bson.M{"$multiply": bson.A{"$signedQuantity", "$unitPrice"}}},
+	}}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Period             string `bson:"_id"`
+		models.PeriodSales `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	sales := make(map[string]models.PeriodSales, len(rows))
+	for _, row := range rows {
+		sales[row.Period] = row.PeriodSales
+	}
+	return sales, nil
+}
+
+// saleLinePriceStages look up the sale line of each sale stock adjustment and set signedQuantity (stock
+// taken out is positive, stock put back by sale edits negative) and unitPrice (the line's discounted unit price)
+func saleLinePriceStages() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from": "sales",
+			"let": bson.M{
+				"saleId":    bson.M{"$convert": bson.M{"input": "$sourceId", "to": "objectId", "onError": nil, "onNull": nil}},
+				"productId": "$productId",
+			},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$saleId"}}}},
+				bson.M{"$unwind": "$items"},
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$items.productId", "$$productId"}}}},
+				bson.M{"$group": bson.M{
+					"_id":        nil,
+					"totalPrice": bson.M{"$sum": "$items.totalPrice"},
+					"quantity":   bson.M{"$sum": "$items.quantity"},
+				}},
+			},
+			"as": "saleLine",
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$saleLine", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$set", Value: bson.M{
+			"signedQuantity": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$adjustmentType", models.AdjustmentTypeReduce}},
+				"$quantity",
+				bson.M{"$multiply": bson.A{"$quantity", -1}},
+			}},
+			"unitPrice": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$saleLine.quantity", 0}}, 0}},
+				bson.M{"$divide": bson.A{"$saleLine.totalPrice", "$saleLine.quantity"}},
+				0,
+			}},
+		}}},
+	}
+}
+
+// GetPurchaseCostHistory returns the net quantity of a product received on each purchase and the
+// purchase line's discounted unit cost, oldest purchase first. Quantities come from purchase stock
+// adjustments, so stock taken back by purchase edits is netted off; deleted purchases are left out.
+func (r *StockAdjustmentRepository) GetPurchaseCostHistory(ctx context.Context, productID string) ([]models.PurchaseCostRecord, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"productId":  productID,
+			"sourceType": models.SourceTypePurchase,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"sourceId": "$sourceId", "stockType": "$stockType"},
+			"quantity": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$adjustmentType", models.AdjustmentTypeAdd}},
+				"$quantity",
+				bson.M{"$multiply": bson.A{"$quantity", -1}},
+			}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"quantity": bson.M{"$gt": 0}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "purchases",
+			"let": bson.M{
+				"purchaseId": bson.M{"$convert": bson.M{"input": "$_id.sourceId", "to": "objectId", "onError": nil, "onNull": nil}},
+				"productId":  productID,
+			},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"$expr":     bson.M{"$eq": bson.A{"$_id", "$$purchaseId"}},
+					"isDeleted": bson.M{"$ne": true},
+				}},
+				bson.M{"$unwind": "$items"},
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$items.productId", "$$productId"}}}},
+				bson.M{"$group": bson.M{
+					"_id":          "$_id",
+					"purchaseCode": bson.M{"$first": "$purchaseCode"},
+					"purchaseDate": bson.M{"$first": "$purchaseDate"},
+					"totalPrice":   bson.M{"$sum": "$items.totalPrice"},
+					"quantity":     bson.M{"$sum": "$items.quantity"},
+				}},
+			},
+			"as": "purchaseLine",
+		}}},
+		{{Key: "$unwind", Value: "$purchaseLine"}},
+		{{Key: "$match", Value: bson.M{"purchaseLine.quantity": bson.M{"$gt": 0}}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          0,
+			"purchaseId":   "$_id.sourceId",
+			"stockType":    "$_id.stockType",
+			"quantity":     1,
+			"purchaseCode": "$purchaseLine.purchaseCode",
+			"purchaseDate": "$purchaseLine.purchaseDate",
+			"unitCost":     bson.M{"$divide": bson.A{"$purchaseLine.totalPrice", "$purchaseLine.quantity"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "purchaseDate", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.PurchaseCostRecord{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetEnrichedMovements returns a product's stock adjustments created from..to (zero = unbounded), oldest
+// first, each joined with its sale or purchase for the document code, customer and the line's discounted
+// unit price. Adjustments without a source document keep their own source code and have no customer or price.
+func (r *StockAdjustmentRepository) GetEnrichedMovements(ctx context.Context, productID string, from, to time.Time) ([]models.StockMovement, error) {
+	match := bson.M{"productId": productID}
+	createdAt := bson.M{}
+	if !from.IsZero() {
+		createdAt["$gte"] = from
+	}
+	if !to.IsZero() {
+		createdAt["$lte"] = to
+	}
+	if len(createdAt) > 0 {
+		match["createdAt"] = createdAt
+	}
+
+	firstOf := func(field string) bson.M {
+		return bson.M{"$arrayElemAt": bson.A{field, 0}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}}},
+		sourceDocumentLookup("sales", models.SourceTypeSale, "saleCode", "sale"),
+		sourceDocumentLookup("purchases", models.SourceTypePurchase, "purchaseCode", "purchase"),
+		{{Key: "$set", Value: bson.M{
+			"document": bson.M{"$ifNull": bson.A{firstOf("$sale"), firstOf("$purchase")}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"date":      "$createdAt",
+			"type":      "$sourceType",
+			"qty":       "$quantity",
+			"stockType": 1,
+			"direction": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$adjustmentType", models.AdjustmentTypeReduce}},
+				models.MovementOut,
+				models.MovementIn,
+			}},
+			"reference":    bson.M{"$ifNull": bson.A{"$document.code", "$sourceCode"}},
+			"customerName": "$document.customerName",
+			"unitPrice": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$document.quantity", 0}}, 0}},
+				bson.M{"$divide": bson.A{"$document.totalPrice", "$document.quantity"}},
+				"$$REMOVE",
+			}},
+			"sourceId": 1,
+			"notes":    1,
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movements := []models.StockMovement{}
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// sourceDocumentLookup joins adjustments of sourceType with their document in collection as a one-element
+// array holding the document code, customer name and the product's quantity and line total on it
+func sourceDocumentLookup(collection string, sourceType models.SourceType, codeField, as string) bson.D {
+	return bson.D{{Key: "$lookup", Value: bson.M{
+		"from": collection,
+		"let": bson.M{
+			"documentId": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$sourceType", sourceType}},
+				bson.M{"$convert": bson.M{"input": "$sourceId", "to": "objectId", "onError": nil, "onNull": nil}},
+				nil,
+			}},
+			"productId": "$productId",
+		},
+		"pipeline": bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$documentId"}}}},
+			bson.M{"$project": bson.M{
+				"code":         "$" + codeField,
+				"customerName": 1,
+				"items": bson.M{"$filter": bson.M{
+					"input": "$items",
+					"cond":  bson.M{"$eq": bson.A{"$$this.productId", "$$productId"}},
+				}},
+			}},
+			bson.M{"$project": bson.M{
+				"code":         1,
+				"customerName": 1,
+				"quantity":     bson.M{"$sum": "$items.quantity"},
+				"totalPrice":   bson.M{"$sum": "$items.totalPrice"},
+			}},
+		},
+		"as": as,
+	}}}
+}
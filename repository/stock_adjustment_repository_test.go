@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+)
+
+func TestGetEnrichedMovements(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	repo := NewStockAdjustmentRepository(db.Collection("stock_adjustments"))
+
+	productID := primitive.NewObjectID().Hex()
+	purchaseID, saleID := primitive.NewObjectID(), primitive.NewObjectID()
+	if _, err := db.Collection("purchases").InsertOne(ctx, bson.M{
+		"_id": purchaseID, "purchaseCode": "PUR-VAT-6701-0001", "customerName": "Box Factory",
+		"items": bson.A{
+			bson.M{"productId": productID, "quantity": 100, "totalPrice": 1500},
+			bson.M{"productId": "other", "quantity": 7, "totalPrice": 700},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Collection("sales").InsertOne(ctx, bson.M{
+		"_id": saleID, "saleCode": "INV-6701-0001", "customerName": "Cafe Amazon",
+		"items": bson.A{bson.M{"productId": productID, "quantity": 5, "totalPrice": 125}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC) }
+	purchaseHex, purchaseCode := purchaseID.Hex(), "PUR-VAT-6701-0001"
+	saleHex, saleCode := saleID.Hex(), "INV-6701-0001"
+	notes := "damaged in storage"
+	for _, adjustment := range []*models.StockAdjustment{
+		{ProductID: productID, AdjustmentType: models.AdjustmentTypeAdd, StockType: models.StockTypeVAT, Quantity: 100,
+			SourceType: models.SourceTypePurchase, SourceID: &purchaseHex, SourceCode: &purchaseCode, CreatedAt: day(2)},
+		{ProductID: productID, AdjustmentType: models.AdjustmentTypeReduce, StockType: models.StockTypeVAT, Quantity: 5,
+			SourceType: models.SourceTypeSale, SourceID: &saleHex, SourceCode: &saleCode, CreatedAt: day(5)},
+		{ProductID: productID, AdjustmentType: models.AdjustmentTypeReduce, StockType: models.StockTypeActualStock, Quantity: 2,
+			SourceType: models.SourceTypeAdjustment, Notes: &notes, CreatedAt: day(8)},
+		{ProductID: "other", AdjustmentType: models.AdjustmentTypeAdd, StockType: models.StockTypeVAT, Quantity: 7,
+			SourceType: models.SourceTypePurchase, SourceID: &purchaseHex, CreatedAt: day(2)},
+		{ProductID: productID, AdjustmentType: models.AdjustmentTypeAdd, StockType: models.StockTypeVAT, Quantity: 9,
+			SourceType: models.SourceTypeAdjustment, CreatedAt: day(20)}, // after the range
+	} {
+		if err := repo.Create(ctx, adjustment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	movements, err := repo.GetEnrichedMovements(ctx, productID, day(1), day(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movements) != 3 {
+		t.Fatalf("got %d movements, want 3: %+v", len(movements), movements)
+	}
+
+	purchase := movements[0]
+	if purchase.Type != models.SourceTypePurchase || purchase.Direction != models.MovementIn || purchase.Qty != 100 {
+		t.Errorf("purchase movement = %+v, want 100 in", purchase)
+	}
+	if purchase.Reference == nil || *purchase.Reference != "PUR-VAT-6701-0001" ||
+		purchase.CustomerName == nil || *purchase.CustomerName != "Box Factory" ||
+		purchase.UnitPrice == nil || *purchase.UnitPrice != 15 {
+		t.Errorf("purchase movement = %+v, want PUR-VAT-6701-0001 from Box Factory at 15", purchase)
+	}
+
+	sale := movements[1]
+	if sale.Type != models.SourceTypeSale || sale.Direction != models.MovementOut || sale.Qty != 5 {
+		t.Errorf("sale movement = %+v, want 5 out", sale)
+	}
+	if sale.Reference == nil || *sale.Reference != "INV-6701-0001" ||
+		sale.CustomerName == nil || *sale.CustomerName != "Cafe Amazon" ||
+		sale.UnitPrice == nil || *sale.UnitPrice != 25 {
+		t.Errorf("sale movement = %+v, want INV-6701-0001 to Cafe Amazon at 25", sale)
+	}
+
+	// A manual adjustment has no document to join but is still listed
+	adjustment := movements[2]
+	if adjustment.Type != models.SourceTypeAdjustment || adjustment.Direction != models.MovementOut || adjustment.Qty != 2 {
+		t.Errorf("adjustment movement = %+v, want 2 out", adjustment)
+	}
+	if adjustment.Reference != nil || adjustment.CustomerName != nil || adjustment.UnitPrice != nil {
+		t.Errorf("adjustment movement = %+v, want no reference, customer or price", adjustment)
+	}
+	if adjustment.Notes == nil || *adjustment.Notes != notes {
+		t.Errorf("adjustment notes = %v, want %q", adjustment.Notes, notes)
+	}
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type StockCountRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStockCountRepository(collection *mongo.Collection) *StockCountRepository {
+	return &StockCountRepository{
+		collection: collection,
+	}
+}
+
+func (r *StockCountRepository) Create(ctx context.Context, stockCount *models.StockCount) error {
+	// Generate count ID
+	if stockCount.CountID == "" {
+		lastCountID, err := r.GetLastCountID(ctx)
+		if err != nil {
+			return err
+		}
+		countID, err := models.GenerateStockCountID(lastCountID)
+		if err != nil {
+			return err
+		}
+		stockCount.CountID = countID
+	}
+
+	if stockCount.ID.IsZero() {
+		stockCount.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, stockCount)
+	return err
+}
+
+func (r *StockCountRepository) GetByID(ctx context.Context, id string) (*models.StockCount, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var stockCount models.StockCount
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&stockCount)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &stockCount, nil
+}
+
+// GetAll gets every stock count without its items, newest first
+func (r *StockCountRepository) GetAll(ctx context.Context) ([]*models.StockCount, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "startedAt", Value: -1}}).
+		SetProjection(bson.M{"items": 0})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stockCounts := []*models.StockCount{}
+	if err := cursor.All(ctx, &stockCounts); err != nil {
+		return nil, err
+	}
+	return stockCounts, nil
+}
+
+func (r *StockCountRepository) Update(ctx context.Context, id string, stockCount *models.StockCount) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, stockCount)
+	return err
+}
+
+// GetLastCountID returns the highest count ID issued so far
+func (r *StockCountRepository) GetLastCountID(ctx context.Context) (string, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "countId", Value: -1}})
+
+	var stockCount models.StockCount
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&stockCount)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return stockCount.CountID, nil
+}
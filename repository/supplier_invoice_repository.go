@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type SupplierInvoiceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSupplierInvoiceRepository(collection *mongo.Collection) *SupplierInvoiceRepository {
+	return &SupplierInvoiceRepository{
+		collection: collection,
+	}
+}
+
+func (r *SupplierInvoiceRepository) Create(ctx context.Context, invoice *models.SupplierInvoice) error {
+	result, err := r.collection.InsertOne(ctx, invoice)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		invoice.ID = oid
+	}
+	return nil
+}
+
+func (r *SupplierInvoiceRepository) GetByID(ctx context.Context, id string) (*models.SupplierInvoice, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice models.SupplierInvoice
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&invoice); err != nil {
+		return nil, notFound(err)
+	}
+	return &invoice, nil
+}
+
+// GetBySupplierAndNumber gets a supplier's invoice by its invoice number
+func (r *SupplierInvoiceRepository) GetBySupplierAndNumber(ctx context.Context, supplierID, invoiceNumber string) (*models.SupplierInvoice, error) {
+	var invoice models.SupplierInvoice
+	err := r.collection.FindOne(ctx, bson.M{"supplierId": supplierID, "invoiceNumber": invoiceNumber}).Decode(&invoice)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &invoice, nil
+}
+
+// GetAll gets the supplier invoices, newest invoice date first; a non-empty status limits them to that status
+func (r *SupplierInvoiceRepository) GetAll(ctx context.Context, status string) ([]*models.SupplierInvoice, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(ctx, filter)
+}
+
+// GetMatchedToPurchases gets the invoices other than excludeID that any of the purchases is matched to
+func (r *SupplierInvoiceRepository) GetMatchedToPurchases(ctx context.Context, purchaseIDs []string, excludeID primitive.ObjectID) ([]*models.SupplierInvoice, error) {
+	return r.find(ctx, bson.M{
+		"_id":                bson.M{"$ne": excludeID},
+		"matchedPurchaseIds": bson.M{"$in": purchaseIDs},
+	})
+}
+
+func (r *SupplierInvoiceRepository) Update(ctx context.Context, id string, invoice *models.SupplierInvoice) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, invoice)
+	return err
+}
+
+func (r *SupplierInvoiceRepository) find(ctx context.Context, filter bson.M) ([]*models.SupplierInvoice, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "invoiceDate", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invoices := []*models.SupplierInvoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
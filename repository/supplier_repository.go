@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type SupplierRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSupplierRepository(collection *mongo.Collection) *SupplierRepository {
+	return &SupplierRepository{
+		collection: collection,
+	}
+}
+
+func (r *SupplierRepository) Create(ctx context.Context, supplier *models.Supplier) error {
+	// Generate supplier code
+	if supplier.SupplierCode == "" {
+		supplierCode, err := r.GenerateSupplierCode(ctx)
+		if err != nil {
+			return err
+		}
+		supplier.SupplierCode = supplierCode
+	}
+
+	result, err := r.collection.InsertOne(ctx, supplier)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		supplier.ID = oid
+	}
+	return nil
+}
+
+func (r *SupplierRepository) GetByID(ctx context.Context, id string) (*models.Supplier, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var supplier models.Supplier
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&supplier)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &supplier, nil
+}
+
+func (r *SupplierRepository) GetAll(ctx context.Context) ([]*models.Supplier, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var suppliers []*models.Supplier
+	for cursor.Next(ctx) {
+		var supplier models.Supplier
+		if err := cursor.Decode(&supplier); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, &supplier)
+	}
+
+	return suppliers, cursor.Err()
+}
+
+func (r *SupplierRepository) Update(ctx context.Context, id string, supplier *models.Supplier) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, supplier)
+	return err
+}
+
+func (r *SupplierRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
+
+// GenerateSupplierCode generates the next supplier code in format SUP-0001
+func (r *SupplierRepository) GenerateSupplierCode(ctx context.Context) (string, error) {
+	// Get the highest supplier code (including deleted suppliers so codes are never reused)
+	opts := options.FindOne().SetSort(bson.D{{Key: "supplierCode", Value: -1}})
+
+	var lastSupplier models.Supplier
+	err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&lastSupplier)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	// Extract number from last supplier code
+	nextNumber := 1
+	if lastSupplier.SupplierCode != "" {
+		parts := strings.Split(lastSupplier.SupplierCode, "-")
+		if len(parts) == 2 {
+			if num, err := strconv.Atoi(parts[1]); err == nil {
+				nextNumber = num + 1
+			}
+		}
+	}
+
+	return fmt.Sprintf("SUP-%04d", nextNumber), nil
+}
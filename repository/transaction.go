@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transactionSupport caches, per client, whether the server it is connected to supports transactions
+var transactionSupport sync.Map
+
+// withTransaction runs fn in a MongoDB transaction on the collection's client. Repository calls made
+// with the context passed to fn take part in the transaction; any error aborts it.
+// Transactions need MongoDB running as a replica set (or a sharded cluster). On a standalone server fn runs
+// without a transaction, so a failure part way keeps the writes made before it.
+func withTransaction(ctx context.Context, collection *mongo.Collection, fn func(ctx context.Context) error) error {
+	client := collection.Database().Client()
+	supported, err := supportsTransactions(ctx, client)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
+// supportsTransactions asks the server once per client whether it is a replica set member or a mongos
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	if supported, ok := transactionSupport.Load(client); ok {
+		return supported.(bool), nil
+	}
+
+	var hello bson.M
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	supported := helloSupportsTransactions(hello)
+	if !supported {
+		log.Println("Warning: MongoDB is not a replica set, so multi-document writes run without transactions")
+	}
+	transactionSupport.Store(client, supported)
+	return supported, nil
+}
+
+// helloSupportsTransactions reports whether the reply to the hello command comes from a replica set member
+// (it names its set) or from a mongos router of a sharded cluster
+func helloSupportsTransactions(hello bson.M) bool {
+	if _, ok := hello["setName"]; ok {
+		return true
+	}
+	return hello["msg"] == "isdbgrid"
+}
+
+// WithTransaction runs fn in a transaction; see withTransaction
+func (r *StockAdjustmentRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTransaction(ctx, r.collection, fn)
+}
+
+// WithTransaction runs fn in a transaction; see withTransaction
+func (r *SaleRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTransaction(ctx, r.collection, fn)
+}
+
+// WithTransaction runs fn in a transaction; see withTransaction
+func (r *PurchaseRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTransaction(ctx, r.collection, fn)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestHelloSupportsTransactions(t *testing.T) {
+	tests := []struct {
+		name  string
+		hello bson.M
+		want  bool
+	}{
+		{"standalone", bson.M{"isWritablePrimary": true}, false},
+		{"replica set primary", bson.M{"isWritablePrimary": true, "setName": "rs0"}, true},
+		{"mongos", bson.M{"isWritablePrimary": true, "msg": "isdbgrid"}, true},
+	}
+	for _, tt := range tests {
+		if got := helloSupportsTransactions(tt.hello); got != tt.want {
+			t.Errorf("%s: helloSupportsTransactions = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithTransactionWritesOnAnyDeployment(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+	collection := db.Collection("sales")
+	if _, err := collection.InsertOne(ctx, bson.M{"saleCode": "S-0000"}); err != nil {
+		t.Fatal(err) // transactions cannot create collections before MongoDB 4.4
+	}
+
+	err := withTransaction(ctx, collection, func(txCtx context.Context) error {
+		_, err := collection.InsertOne(txCtx, bson.M{"saleCode": "S-0001"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("withTransaction = %v", err)
+	}
+	if n, _ := collection.CountDocuments(ctx, bson.M{"saleCode": "S-0001"}); n != 1 {
+		t.Errorf("saved %d sales, want 1", n)
+	}
+
+	errAbort := errors.New("abort")
+	err = withTransaction(ctx, collection, func(txCtx context.Context) error {
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("withTransaction = %v, want the error of fn", err)
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/apierrors"
+)
+
+// errVersionConflict is returned when a document was changed by someone else after it was read
+var errVersionConflict = fmt.Errorf("%w: document was modified by another request", apierrors.ErrConflict)
+
+// versionFilter matches the document at the given version; documents saved before versioning
+// have no version field and count as version 0
+func versionFilter(objectID primitive.ObjectID, version int) bson.M {
+	if version == 0 {
+		return bson.M{"_id": objectID, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": objectID, "version": version}
+}
+
+// versionIncrement is the update operator that bumps a document's version
+func versionIncrement() bson.M {
+	return bson.M{"version": 1}
+}
+
+// replaceVersioned replaces the document only if it is still at *version, the version it was read at,
+// and moves *version on by one. If the document changed in the meantime an error wrapping
+// apierrors.ErrConflict is returned and *version is left alone; one wrapping apierrors.ErrNotFound if it no longer exists.
+func replaceVersioned(ctx context.Context, collection *mongo.Collection, objectID primitive.ObjectID, version *int, document interface{}) error {
+	current := *version
+	*version = current + 1
+
+	result, err := collection.ReplaceOne(ctx, versionFilter(objectID, current), document)
+	if err != nil {
+		*version = current
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	*version = current
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errVersionConflict
+	}
+	return notFound(mongo.ErrNoDocuments)
+}
+
+// writeTokenDocument is a document stamped with the write token of the call saving it; see retryReplace
+type writeTokenDocument struct {
+	document interface{}
+	token    string
+}
+
+// MarshalBSON encodes the document with its writeToken field set. It runs when the document is written, so it
+// sees the version replaceVersioned moved it to.
+func (d writeTokenDocument) MarshalBSON() ([]byte, error) {
+	data, err := bson.Marshal(d.document)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	stamped := make(bson.D, 0, len(fields)+1)
+	for _, field := range fields {
+		if field.Key != "writeToken" {
+			stamped = append(stamped, field)
+		}
+	}
+	return bson.Marshal(append(stamped, bson.E{Key: "writeToken", Value: d.token}))
+}
+
+// storedWrite reads the version a document is saved at and the write token of the call that saved it
+func storedWrite(ctx context.Context, collection *mongo.Collection, objectID primitive.ObjectID) (int, string, error) {
+	var stored struct {
+		Version    int    `bson:"version"`
+		WriteToken string `bson:"writeToken"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1, "writeToken": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&stored); err != nil {
+		return 0, "", notFound(err)
+	}
+	return stored.Version, stored.WriteToken, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+)
+
+func TestSaleUpdateRejectsStaleVersion(t *testing.T) {
+	repo := NewSaleRepository(testDatabase(t).Collection("sales"))
+	ctx := context.Background()
+
+	sale := &models.Sale{SaleCode: "SA-6701-0001", CustomerID: "c1"}
+	if err := repo.Create(ctx, sale); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.GetByID(ctx, sale.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.GetByID(ctx, sale.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.ShippingCost = 50
+	if err := repo.Update(ctx, first.ID.Hex(), first); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if first.Version != 1 {
+		t.Errorf("Version after update = %d, want 1", first.Version)
+	}
+
+	second.ShippingCost = 80
+	if err := repo.Update(ctx, second.ID.Hex(), second); !errors.Is(err, apierrors.ErrConflict) {
+		t.Fatalf("update from the stale version = %v, want ErrConflict", err)
+	}
+	if second.Version != 0 {
+		t.Errorf("Version after a conflict = %d, want it left at 0", second.Version)
+	}
+
+	stored, err := repo.GetByID(ctx, sale.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ShippingCost != 50 || stored.Version != 1 {
+		t.Errorf("stored sale has shipping %v at version %d, want the first update at version 1", stored.ShippingCost, stored.Version)
+	}
+}
+
+func TestConcurrentVersionedUpdatesKeepOneWinner(t *testing.T) {
+	repo := NewSaleRepository(testDatabase(t).Collection("sales"))
+	ctx := context.Background()
+
+	sale := &models.Sale{SaleCode: "SA-6701-0002", CustomerID: "c1"}
+	if err := repo.Create(ctx, sale); err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 10
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		i := i
+		stale := *sale // every writer read version 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.Update(ctx, stale.ID.Hex(), &stale)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, apierrors.ErrConflict):
+			t.Errorf("Update = %v, want nil or ErrConflict", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d updates from version 0 succeeded, want exactly 1", succeeded)
+	}
+
+	stored, err := repo.GetByID(ctx, sale.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Version != 1 {
+		t.Errorf("stored Version = %d, want 1", stored.Version)
+	}
+}
+
+func TestVersionedUpdateOfMissingSaleIsNotFound(t *testing.T) {
+	repo := NewSaleRepository(testDatabase(t).Collection("sales"))
+
+	sale := &models.Sale{ID: primitive.NewObjectID(), SaleCode: "SA-6701-0003"}
+	if err := repo.Update(context.Background(), sale.ID.Hex(), sale); !errors.Is(err, apierrors.ErrNotFound) {
+		t.Errorf("Update = %v, want ErrNotFound", err)
+	}
+}
+
+func TestProductModifyRetriesOnConflict(t *testing.T) {
+	repo := &ProductRepository{collection: testDatabase(t).Collection("products")}
+	ctx := context.Background()
+
+	product := &models.Product{ID: primitive.NewObjectID(), Stock: models.Stock{ActualStock: 10}}
+	if _, err := repo.collection.InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	saved, err := repo.Modify(ctx, product.ID.Hex(), func(p *models.Product) error {
+		attempts++
+		if attempts == 1 {
+			// Another request takes 3 units after this one read the product
+			other := *p
+			other.Stock.ActualStock -= 3
+			if err := repo.Update(ctx, other.ID.Hex(), &other); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p.Stock.ActualStock += 5
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Modify = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("change applied %d times, want 2", attempts)
+	}
+	if saved.Stock.ActualStock != 12 || saved.Version != 2 {
+		t.Errorf("saved stock %d at version %d, want 12 at version 2", saved.Stock.ActualStock, saved.Version)
+	}
+
+	stored, err := repo.GetByID(ctx, product.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Stock.ActualStock != 12 {
+		t.Errorf("stored stock = %d, want 12 with both changes kept", stored.Stock.ActualStock)
+	}
+}
+
+func TestCustomerSetDerivedKeepsVersion(t *testing.T) {
+	ctx := context.Background()
+
+	repo := NewCustomerRepository(testDatabase(t).Collection("customers"))
+
+	customer := &models.Customer{CompanyName: "Goodpack", ContactName: "Somchai"}
+	if err := repo.Create(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetDerived(ctx, customer.ID.Hex(), bson.M{"outstandingBalance": 1500.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.GetByID(ctx, customer.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.OutstandingBalance != 1500 || stored.Version != customer.Version {
+		t.Errorf("stored balance %v at version %d, want 1500 at version %d", stored.OutstandingBalance, stored.Version, customer.Version)
+	}
+
+	// A client that read the customer before the balance changed can still save it
+	customer.Phone = "0812345678"
+	if err := repo.Update(ctx, customer.ID.Hex(), customer); err != nil {
+		t.Errorf("Update after SetDerived = %v, want nil", err)
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+)
+
+type WebhookRepository struct {
+	collection *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookRepository(collection, deliveries *mongo.Collection) *WebhookRepository {
+	return &WebhookRepository{
+		collection: collection,
+		deliveries: deliveries,
+	}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	result, err := r.collection.InsertOne(ctx, webhook)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		webhook.ID = oid
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id string) (*models.Webhook, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhook models.Webhook
+	err = r.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&webhook)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	return r.find(ctx, notDeleted(bson.M{}))
+}
+
+// GetActiveByEvent gets the active webhooks subscribed to event
+func (r *WebhookRepository) GetActiveByEvent(ctx context.Context, event string) ([]*models.Webhook, error) {
+	return r.find(ctx, notDeleted(bson.M{"isActive": true, "events": event}))
+}
+
+func (r *WebhookRepository) find(ctx context.Context, filter bson.M) ([]*models.Webhook, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []*models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, id string, webhook *models.Webhook) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, webhook)
+	return err
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), softDeleteUpdate())
+	return err
+}
+
+// RecordDelivery stores a delivery attempt
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	result, err := r.deliveries.InsertOne(ctx, delivery)
+	if err != nil {
+		return err
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		delivery.ID = oid
+	}
+	return nil
+}
+
+// GetDeliveries gets a webhook's delivery attempts, newest first
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]*models.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.deliveries.Find(ctx, bson.M{"webhookId": webhookID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain runs the tests from the module root, where the config directory the product handler reads lives
+func TestMain(m *testing.M) {
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(m.Run())
+}
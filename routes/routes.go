@@ -1,64 +1,254 @@
 package routes
 
 import (
-	"encoding/json"
+	"context"
+	_ "embed"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
+	"golang.org/x/time/rate"
 
+	"goodpack-server/config"
 	"goodpack-server/handlers"
+	"goodpack-server/middleware"
 	"goodpack-server/repository"
+	"goodpack-server/services"
+	"goodpack-server/storage"
 )
 
-func SetupRoutes(productRepo *repository.ProductRepository, customerRepo *repository.CustomerRepository, purchaseRepo *repository.PurchaseRepository, saleRepo *repository.SaleRepository, quotationRepo *repository.QuotationRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) http.Handler {
+//go:embed docs/openapi.yaml
+var openAPISpec []byte
+
+//go:embed docs/swagger.html
+var swaggerUI []byte
+
+// Dependencies are the repositories, services and handlers the routes are built from
+type Dependencies struct {
+	ProductRepo         *repository.ProductRepository
+	CustomerRepo        *repository.CustomerRepository
+	PurchaseRepo        *repository.PurchaseRepository
+	SaleRepo            *repository.SaleRepository
+	QuotationRepo       *repository.QuotationRepository
+	StockAdjustmentRepo *repository.StockAdjustmentRepository
+	AuditLogRepo        *repository.AuditLogRepository
+	SupplierRepo        *repository.SupplierRepository
+	SaleReturnRepo      *repository.SaleReturnRepository
+	MigrationRepo       *repository.MigrationRepository
+	ReportRepo          *repository.ReportRepository
+	WebhookRepo         *repository.WebhookRepository
+	StockCountRepo      *repository.StockCountRepository
+	BudgetRepo          *repository.BudgetRepository
+	CategoryRepo        *repository.CategoryRepository
+	RecurringOrderRepo  *repository.RecurringOrderRepository
+	BundleRepo          *repository.BundleRepository
+	SerialNumberRepo    *repository.SerialNumberRepository
+	LotRepo             *repository.LotRepository
+	SupplierInvoiceRepo *repository.SupplierInvoiceRepository
+	CustomerNoteRepo    *repository.CustomerNoteRepository
+	ExchangeRateRepo    *repository.ExchangeRateRepository
+	SSEHandler          *handlers.SSEHandler
+	HealthDB            handlers.HealthDatabase
+	FileStorage         storage.FileStorage
+}
+
+// SetupRoutes builds the API router; background work it starts, such as rate limiter cleanup, stops when ctx is done
+func SetupRoutes(ctx context.Context, cfg *config.Config, deps Dependencies) http.Handler {
 	router := mux.NewRouter()
+	router.Use(middleware.Metrics)
+	router.Use(middleware.I18n)
+	if cfg.ResponseEnvelope {
+		router.Use(middleware.Envelope)
+	}
+	if cfg.MaxBodyBytes > 0 {
+		router.Use(middleware.MaxBodySizeMiddleware(cfg.MaxBodyBytes))
+	}
+	router.Use(middleware.ContentTypeMiddleware)
+	router.Use(middleware.Audit(deps.AuditLogRepo))
+	if cfg.RateLimitRPS > 0 {
+		router.Use(middleware.NewRateLimiter(ctx, rate.Limit(cfg.RateLimitRPS), cfg.RateLimitBurst).Limit)
+	}
+	router.Use(middleware.TimeoutMiddleware(cfg.RequestTimeout))
 
-	// Initialize handlers test2
-	productHandler := handlers.NewProductHandler(productRepo)
-	customerHandler := handlers.NewCustomerHandler(customerRepo)
-	purchaseHandler := handlers.NewPurchaseHandler(purchaseRepo, customerRepo, productRepo, stockAdjustmentRepo)
-	saleHandler := handlers.NewSaleHandler(saleRepo, customerRepo, productRepo, quotationRepo, stockAdjustmentRepo)
-	quotationHandler := handlers.NewQuotationHandler(quotationRepo, customerRepo, productRepo)
-	migrationHandler := handlers.NewMigrationHandler(customerRepo, productRepo, purchaseRepo, saleRepo)
-	stockAdjustmentHandler := handlers.NewStockAdjustmentHandler(stockAdjustmentRepo, productRepo)
+	// Migration imports do large writes, so they get a stricter limit of their own
+	migrationLimit := func(h http.Handler) http.Handler { return h }
+	if cfg.MigrationRateLimitPerMinute > 0 {
+		limit := rate.Every(time.Minute / time.Duration(cfg.MigrationRateLimitPerMinute))
+		migrationLimit = middleware.NewRateLimiter(ctx, limit, cfg.MigrationRateLimitPerMinute).Limit
+	}
+
+	// Initialize services
+	saleService := services.NewSaleService(deps.SaleRepo, deps.ProductRepo, deps.CustomerRepo, deps.QuotationRepo, deps.StockAdjustmentRepo, deps.BundleRepo, deps.SerialNumberRepo, deps.LotRepo, deps.SaleReturnRepo)
+	valuationService := services.NewValuationService(deps.ProductRepo, deps.PurchaseRepo, deps.StockAdjustmentRepo)
+	forecastService := services.NewForecastService(deps.StockAdjustmentRepo)
+	webhookService := services.NewWebhookService(deps.WebhookRepo)
+
+	// Initialize handlers
+	productHandler := handlers.NewProductHandler(deps.ProductRepo, deps.PurchaseRepo, deps.FileStorage, services.NewVelocityService(deps.ProductRepo, deps.StockAdjustmentRepo))
+	customerHandler := handlers.NewCustomerHandler(deps.CustomerRepo, deps.PurchaseRepo, deps.SaleRepo, deps.QuotationRepo)
+	customerNoteHandler := handlers.NewCustomerNoteHandler(deps.CustomerNoteRepo, deps.CustomerRepo)
+	purchaseHandler := handlers.NewPurchaseHandler(deps.PurchaseRepo, deps.CustomerRepo, deps.ProductRepo, deps.StockAdjustmentRepo, deps.SupplierRepo, deps.SerialNumberRepo, deps.LotRepo, deps.ExchangeRateRepo, webhookService, cfg.AdminToken)
+	saleHandler := handlers.NewSaleHandler(deps.SaleRepo, deps.CustomerRepo, deps.ProductRepo, deps.QuotationRepo, deps.StockAdjustmentRepo, saleService, webhookService)
+	qrHandler := handlers.NewQRHandler(deps.ProductRepo)
+	quotationHandler := handlers.NewQuotationHandler(deps.QuotationRepo, deps.CustomerRepo, deps.ProductRepo, saleService)
+	migrationHandler := handlers.NewMigrationHandler(deps.CustomerRepo, deps.ProductRepo, deps.PurchaseRepo, deps.SaleRepo, deps.MigrationRepo)
+	stockAdjustmentHandler := handlers.NewStockAdjustmentHandler(deps.StockAdjustmentRepo, deps.ProductRepo)
+	auditLogHandler := handlers.NewAuditLogHandler(deps.AuditLogRepo)
+	supplierHandler := handlers.NewSupplierHandler(deps.SupplierRepo, deps.PurchaseRepo)
+	reportHandler := handlers.NewReportHandler(deps.ProductRepo, deps.StockAdjustmentRepo, deps.ReportRepo, deps.QuotationRepo, valuationService, forecastService)
+	healthHandler := handlers.NewHealthHandler(deps.HealthDB)
+	exportHandler := handlers.NewExportHandler(deps.CustomerRepo, deps.ProductRepo, deps.PurchaseRepo, deps.SaleRepo)
+	returnHandler := handlers.NewReturnHandler(deps.SaleReturnRepo, deps.SaleRepo, deps.ProductRepo, deps.StockAdjustmentRepo, deps.SerialNumberRepo)
+	webhookHandler := handlers.NewWebhookHandler(deps.WebhookRepo)
+	stockCountHandler := handlers.NewStockCountHandler(deps.StockCountRepo, deps.ProductRepo, deps.StockAdjustmentRepo)
+	budgetHandler := handlers.NewBudgetHandler(deps.BudgetRepo, deps.ReportRepo, deps.SupplierRepo)
+	supplierInvoiceHandler := handlers.NewSupplierInvoiceHandler(deps.SupplierInvoiceRepo, deps.SupplierRepo, deps.PurchaseRepo, cfg.InvoiceMatchTolerancePercent)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(deps.ExchangeRateRepo)
+	categoryHandler := handlers.NewCategoryHandler(deps.CategoryRepo)
+	recurringOrderHandler := handlers.NewRecurringOrderHandler(deps.RecurringOrderRepo, deps.CustomerRepo, deps.ProductRepo)
+	bundleHandler := handlers.NewBundleHandler(deps.BundleRepo, deps.ProductRepo)
+	serialNumberHandler := handlers.NewSerialNumberHandler(deps.SerialNumberRepo, deps.ProductRepo)
+	lotHandler := handlers.NewLotHandler(deps.LotRepo, deps.ProductRepo)
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
+	adminOnly := middleware.AdminOnly(cfg.AdminToken)
+	// Reports, exports, imports and other bulk work get the longer timeout
+	slow := middleware.Timeout(cfg.SlowRequestTimeout)
 
 	// Product routes
-	api.HandleFunc("/products", productHandler.GetProducts).Methods("GET")
+	api.Handle("/products", middleware.ETag(http.HandlerFunc(productHandler.GetProducts))).Methods("GET")
 	api.HandleFunc("/products", productHandler.CreateProduct).Methods("POST")
+	api.HandleFunc("/products/search", productHandler.SearchProducts).Methods("GET")
+	api.HandleFunc("/products/tags", productHandler.GetTags).Methods("GET")
+	api.Handle("/products/qr-batch", slow(http.HandlerFunc(qrHandler.GetQRCodeBatch))).Methods("GET")
+	api.Handle("/products/label-sheet", slow(http.HandlerFunc(productHandler.GetLabelSheet))).Methods("GET")
+	api.Handle("/products/regenerate-all-qr", slow(http.HandlerFunc(productHandler.RegenerateAllQR))).Methods("POST")
+	api.HandleFunc("/products/low-stock", productHandler.GetLowStockProducts).Methods("GET")
+	api.HandleFunc("/products/popular", productHandler.GetPopularProducts).Methods("GET")
+	api.HandleFunc("/products/reorder-suggestions", productHandler.GetReorderSuggestions).Methods("GET")
+	api.HandleFunc("/products/pricing-suggestions", productHandler.GetPricingSuggestions).Methods("POST")
+	api.HandleFunc("/products/stock-discrepancies", stockAdjustmentHandler.GetStockDiscrepancies).Methods("GET")
 	api.HandleFunc("/products/{id}", productHandler.GetProduct).Methods("GET")
 	api.HandleFunc("/products/{id}", productHandler.UpdateProduct).Methods("PUT")
+	api.HandleFunc("/products/{id}", productHandler.PatchProduct).Methods("PATCH")
 	api.HandleFunc("/products/{id}", productHandler.DeleteProduct).Methods("DELETE")
+	api.HandleFunc("/products/{id}/restore", productHandler.RestoreProduct).Methods("POST")
+	api.Handle("/products/{id}/hard-delete", adminOnly(http.HandlerFunc(productHandler.HardDeleteProduct))).Methods("DELETE")
 	api.HandleFunc("/products/{id}/stock", productHandler.UpdateStock).Methods("PATCH")
 	api.HandleFunc("/products/{id}/price", productHandler.UpdatePrice).Methods("PATCH")
+	api.HandleFunc("/products/{id}/regenerate-qr", productHandler.RegenerateQR).Methods("POST")
+	api.HandleFunc("/products/{id}/pricing-suggestion", productHandler.GetPricingSuggestion).Methods("GET")
+	api.HandleFunc("/products/{id}/related", productHandler.GetRelatedProducts).Methods("GET")
+	api.HandleFunc("/products/{id}/also-bought", reportHandler.GetAlsoBoughtProducts).Methods("GET")
 	api.HandleFunc("/products/{id}/image", productHandler.UploadProductImage).Methods("POST")
 	api.HandleFunc("/products/{id}/image", productHandler.DeleteProductImage).Methods("DELETE")
+	api.HandleFunc("/products/{id}/images/{imageIndex}", productHandler.DeleteProductImage).Methods("DELETE")
+	api.HandleFunc("/products/{id}/images/{imageIndex}/primary", productHandler.SetPrimaryProductImage).Methods("PUT")
 	api.HandleFunc("/products/category/{category}", productHandler.GetByCategory).Methods("GET")
-	api.HandleFunc("/products/low-stock", productHandler.GetLowStockProducts).Methods("GET")
 
 	// Stock Adjustment routes
 	api.HandleFunc("/products/{id}/stock/adjust", stockAdjustmentHandler.AdjustStock).Methods("POST")
+	api.HandleFunc("/products/{id}/stock-transfer", stockAdjustmentHandler.TransferStock).Methods("POST")
 	api.HandleFunc("/products/{id}/stock/history", stockAdjustmentHandler.GetStockHistory).Methods("GET")
+	api.HandleFunc("/products/{id}/stock-timeline", stockAdjustmentHandler.GetStockTimeline).Methods("GET")
+	api.HandleFunc("/products/{id}/movements", stockAdjustmentHandler.GetProductMovements).Methods("GET")
+	api.HandleFunc("/products/{id}/reconcile-stock", stockAdjustmentHandler.ReconcileStock).Methods("POST")
+	api.HandleFunc("/products/{id}/cost-analysis", reportHandler.GetProductCostAnalysis).Methods("GET")
+	api.HandleFunc("/products/{id}/serial-numbers", serialNumberHandler.GetProductSerialNumbers).Methods("GET")
+	api.HandleFunc("/products/{id}/lots", lotHandler.GetProductLots).Methods("GET")
 	api.HandleFunc("/stock/history", stockAdjustmentHandler.GetAllStockHistory).Methods("GET")
 	api.HandleFunc("/stock/history/source", stockAdjustmentHandler.GetStockHistoryBySource).Methods("GET")
 	api.HandleFunc("/stock/adjustments/{id}", stockAdjustmentHandler.DeleteStockAdjustment).Methods("DELETE")
+	api.HandleFunc("/stock-adjustments/bulk", stockAdjustmentHandler.BulkAdjustStock).Methods("POST")
+
+	// Stock count routes
+	api.HandleFunc("/stock-counts", stockCountHandler.GetStockCounts).Methods("GET")
+	api.HandleFunc("/stock-counts", stockCountHandler.CreateStockCount).Methods("POST")
+	api.HandleFunc("/stock-counts/{id}", stockCountHandler.GetStockCount).Methods("GET")
+	api.HandleFunc("/stock-counts/{id}/items/{productId}", stockCountHandler.RecordCountedQuantity).Methods("PUT")
+	api.HandleFunc("/stock-counts/{id}/complete", stockCountHandler.CompleteStockCount).Methods("POST")
+	api.HandleFunc("/stock-counts/{id}/variance-report", stockCountHandler.GetVarianceReport).Methods("GET")
+
+	// Budget routes
+	api.HandleFunc("/budgets", budgetHandler.GetBudgets).Methods("GET")
+	api.HandleFunc("/budgets", budgetHandler.CreateBudget).Methods("POST")
+	api.HandleFunc("/budgets/{id}", budgetHandler.GetBudget).Methods("GET")
+	api.HandleFunc("/budgets/{id}", budgetHandler.UpdateBudget).Methods("PUT")
+	api.HandleFunc("/budgets/{id}", budgetHandler.DeleteBudget).Methods("DELETE")
+
+	// Bundle routes
+	api.HandleFunc("/bundles", bundleHandler.GetBundles).Methods("GET")
+	api.HandleFunc("/bundles", bundleHandler.CreateBundle).Methods("POST")
+	api.HandleFunc("/bundles/{id}", bundleHandler.GetBundle).Methods("GET")
+	api.HandleFunc("/bundles/{id}", bundleHandler.UpdateBundle).Methods("PUT")
+	api.HandleFunc("/bundles/{id}", bundleHandler.DeleteBundle).Methods("DELETE")
+	api.HandleFunc("/bundles/{id}/availability", bundleHandler.GetBundleAvailability).Methods("GET")
+
+	// Recurring order routes
+	api.HandleFunc("/recurring-orders", recurringOrderHandler.GetRecurringOrders).Methods("GET")
+	api.HandleFunc("/recurring-orders", recurringOrderHandler.CreateRecurringOrder).Methods("POST")
+	api.HandleFunc("/recurring-orders/{id}", recurringOrderHandler.GetRecurringOrder).Methods("GET")
+	api.HandleFunc("/recurring-orders/{id}", recurringOrderHandler.UpdateRecurringOrder).Methods("PUT")
+	api.HandleFunc("/recurring-orders/{id}", recurringOrderHandler.DeleteRecurringOrder).Methods("DELETE")
 
 	// Categories routes
 	api.HandleFunc("/categories", productHandler.GetCategories).Methods("GET")
 	api.HandleFunc("/config/categories", productHandler.GetConfigCategories).Methods("GET")
 	api.HandleFunc("/config/colors", productHandler.GetConfigColors).Methods("GET")
 	api.HandleFunc("/config/accounts", productHandler.GetConfigAccounts).Methods("GET")
+	api.Handle("/admin/config/reload", adminOnly(http.HandlerFunc(productHandler.ReloadConfig))).Methods("POST")
+	api.Handle("/admin/products/reclassify", slow(adminOnly(http.HandlerFunc(productHandler.ReclassifyVelocity)))).Methods("POST")
+	api.Handle("/admin/customers/renumber", slow(adminOnly(http.HandlerFunc(customerHandler.RenumberCustomers)))).Methods("POST")
+	api.Handle("/admin/categories", adminOnly(http.HandlerFunc(categoryHandler.GetCategories))).Methods("GET")
+	api.Handle("/admin/categories", adminOnly(http.HandlerFunc(categoryHandler.CreateCategory))).Methods("POST")
+	api.Handle("/admin/categories/{id}", adminOnly(http.HandlerFunc(categoryHandler.GetCategory))).Methods("GET")
+	api.Handle("/admin/categories/{id}", adminOnly(http.HandlerFunc(categoryHandler.UpdateCategory))).Methods("PUT")
+	api.Handle("/admin/categories/{id}", adminOnly(http.HandlerFunc(categoryHandler.DeleteCategory))).Methods("DELETE")
 
 	// Customer routes
 	api.HandleFunc("/customers", customerHandler.GetCustomers).Methods("GET")
 	api.HandleFunc("/customers", customerHandler.CreateCustomer).Methods("POST")
+	api.HandleFunc("/customers/search", customerHandler.SearchCustomers).Methods("GET")
 	api.HandleFunc("/customers/{id}", customerHandler.GetCustomer).Methods("GET")
 	api.HandleFunc("/customers/{id}", customerHandler.UpdateCustomer).Methods("PUT")
+	api.HandleFunc("/customers/{id}", customerHandler.PatchCustomer).Methods("PATCH")
 	api.HandleFunc("/customers/{id}", customerHandler.DeleteCustomer).Methods("DELETE")
+	api.HandleFunc("/customers/{id}/purchases", customerHandler.GetCustomerPurchases).Methods("GET")
+	api.HandleFunc("/customers/{id}/sales", customerHandler.GetCustomerSales).Methods("GET")
+	api.HandleFunc("/customers/{id}/summary", customerHandler.GetCustomerSummary).Methods("GET")
+	api.HandleFunc("/customers/{id}/credit-limit", customerHandler.SetCreditLimit).Methods("PUT")
+	api.HandleFunc("/customers/{id}/notes", customerNoteHandler.GetCustomerNotes).Methods("GET")
+	api.HandleFunc("/customers/{id}/notes", customerNoteHandler.CreateCustomerNote).Methods("POST")
+	api.HandleFunc("/customers/{id}/notes/{noteId}", customerNoteHandler.DeleteCustomerNote).Methods("DELETE")
+
+	// Supplier routes
+	api.HandleFunc("/suppliers", supplierHandler.GetSuppliers).Methods("GET")
+	api.HandleFunc("/suppliers", supplierHandler.CreateSupplier).Methods("POST")
+	api.HandleFunc("/suppliers/{id}", supplierHandler.GetSupplier).Methods("GET")
+	api.HandleFunc("/suppliers/{id}", supplierHandler.UpdateSupplier).Methods("PUT")
+	api.HandleFunc("/suppliers/{id}", supplierHandler.DeleteSupplier).Methods("DELETE")
+	api.HandleFunc("/suppliers/{id}/purchases", supplierHandler.GetSupplierPurchases).Methods("GET")
+
+	// Supplier invoice routes
+	api.HandleFunc("/supplier-invoices", supplierInvoiceHandler.GetSupplierInvoices).Methods("GET")
+	api.HandleFunc("/supplier-invoices", supplierInvoiceHandler.CreateSupplierInvoice).Methods("POST")
+	api.HandleFunc("/supplier-invoices/{id}", supplierInvoiceHandler.GetSupplierInvoice).Methods("GET")
+	api.HandleFunc("/supplier-invoices/{id}/match", supplierInvoiceHandler.MatchSupplierInvoice).Methods("POST")
+
+	// Exchange rate routes
+	api.HandleFunc("/exchange-rates", exchangeRateHandler.GetExchangeRate).Methods("GET")
+	api.HandleFunc("/exchange-rates", exchangeRateHandler.CreateExchangeRate).Methods("POST")
+
+	// Webhook routes
+	api.Handle("/webhooks", adminOnly(http.HandlerFunc(webhookHandler.GetWebhooks))).Methods("GET")
+	api.Handle("/webhooks", adminOnly(http.HandlerFunc(webhookHandler.CreateWebhook))).Methods("POST")
+	api.Handle("/webhooks/{id}", adminOnly(http.HandlerFunc(webhookHandler.GetWebhook))).Methods("GET")
+	api.Handle("/webhooks/{id}", adminOnly(http.HandlerFunc(webhookHandler.UpdateWebhook))).Methods("PUT")
+	api.Handle("/webhooks/{id}", adminOnly(http.HandlerFunc(webhookHandler.DeleteWebhook))).Methods("DELETE")
+	api.Handle("/webhooks/{id}/deliveries", adminOnly(http.HandlerFunc(webhookHandler.GetWebhookDeliveries))).Methods("GET")
 
 	// Purchase routes
 	api.HandleFunc("/purchases", purchaseHandler.GetPurchases).Methods("GET")
@@ -66,44 +256,114 @@ func SetupRoutes(productRepo *repository.ProductRepository, customerRepo *reposi
 	api.HandleFunc("/purchases/{id}", purchaseHandler.GetPurchase).Methods("GET")
 	api.HandleFunc("/purchases/{id}", purchaseHandler.UpdatePurchase).Methods("PUT")
 	api.HandleFunc("/purchases/{id}", purchaseHandler.DeletePurchase).Methods("DELETE")
+	api.HandleFunc("/purchases/{id}/pdf", purchaseHandler.GetPurchasePDF).Methods("GET")
+	api.HandleFunc("/purchases/{id}/receive", purchaseHandler.ReceivePurchase).Methods("PUT")
+	api.HandleFunc("/purchases/{id}/status", purchaseHandler.UpdatePurchaseStatus).Methods("PUT")
+	api.HandleFunc("/purchases/{id}/payment", purchaseHandler.RecordPayment).Methods("POST")
+	api.HandleFunc("/purchases/{id}/payments", purchaseHandler.GetPayments).Methods("GET")
+	api.HandleFunc("/purchases/{id}/duplicate", purchaseHandler.DuplicatePurchase).Methods("POST")
+	api.HandleFunc("/purchases/{id}/confirm", purchaseHandler.ConfirmPurchase).Methods("POST")
+	api.Handle("/purchases/{id}/approve", adminOnly(http.HandlerFunc(purchaseHandler.ApprovePurchase))).Methods("POST")
+	api.Handle("/purchases/{id}/reject", adminOnly(http.HandlerFunc(purchaseHandler.RejectPurchase))).Methods("POST")
 
 	// Sale routes
-	api.HandleFunc("/sales", saleHandler.GetSales).Methods("GET")
+	api.Handle("/sales", middleware.ETag(http.HandlerFunc(saleHandler.GetSales))).Methods("GET")
 	api.HandleFunc("/sales", saleHandler.CreateSale).Methods("POST")
 	api.HandleFunc("/sales/{id}", saleHandler.GetSale).Methods("GET")
 	api.HandleFunc("/sales/{id}", saleHandler.UpdateSale).Methods("PUT")
 	api.HandleFunc("/sales/{id}", saleHandler.DeleteSale).Methods("DELETE")
+	api.HandleFunc("/sales/{id}/pdf", saleHandler.GetSalePDF).Methods("GET")
+	api.HandleFunc("/sales/{id}/promptpay-qr", saleHandler.GetSalePromptPayQR).Methods("GET")
+	api.HandleFunc("/sales/{id}/payment", saleHandler.RecordPayment).Methods("POST")
+	api.HandleFunc("/sales/{id}/payments", saleHandler.GetPayments).Methods("GET")
+	api.HandleFunc("/sales/{id}/duplicate", saleHandler.DuplicateSale).Methods("POST")
+	api.HandleFunc("/sales/{id}/confirm", saleHandler.ConfirmSale).Methods("POST")
+	api.HandleFunc("/sales/{id}/status", saleHandler.UpdateSaleStatus).Methods("PUT")
+	api.HandleFunc("/sales/{id}/delivery", saleHandler.UpdateSaleDelivery).Methods("PUT")
+	api.HandleFunc("/sales/{id}/returns", returnHandler.GetSaleReturns).Methods("GET")
+	api.HandleFunc("/sales/{id}/returns", returnHandler.CreateSaleReturn).Methods("POST")
+
+	// Return routes
+	api.HandleFunc("/returns", returnHandler.GetReturns).Methods("GET")
+	api.HandleFunc("/returns/{id}", returnHandler.GetReturn).Methods("GET")
 
 	// Quotation routes
-	api.HandleFunc("/quotations", quotationHandler.GetAllQuotations).Methods("GET")
+	api.Handle("/quotations", middleware.ETag(http.HandlerFunc(quotationHandler.GetAllQuotations))).Methods("GET")
 	api.HandleFunc("/quotations", quotationHandler.CreateQuotation).Methods("POST")
 	api.HandleFunc("/quotations/{id}", quotationHandler.GetQuotation).Methods("GET")
 	api.HandleFunc("/quotations/{id}", quotationHandler.UpdateQuotation).Methods("PUT")
 	api.HandleFunc("/quotations/{id}", quotationHandler.DeleteQuotation).Methods("DELETE")
 	api.HandleFunc("/quotations/{id}/copy-to-sale", quotationHandler.CopyToSale).Methods("GET")
+	api.HandleFunc("/quotations/{id}/accept", quotationHandler.AcceptQuotation).Methods("POST")
+	api.HandleFunc("/quotations/{id}/versions", quotationHandler.GetQuotationVersions).Methods("GET")
+	api.HandleFunc("/quotations/{id}/versions/{versionNumber}", quotationHandler.GetQuotationVersion).Methods("GET")
 
 	// Migration routes
-	api.HandleFunc("/migration/customers/csv", migrationHandler.MigrateCustomersFromCSV).Methods("POST")
+	api.Handle("/migration/customers/csv", slow(migrationLimit(http.HandlerFunc(migrationHandler.MigrateCustomersFromCSV)))).Methods("POST")
 	api.HandleFunc("/migration/customers/template", migrationHandler.GetCustomerCSVTemplate).Methods("GET")
-	api.HandleFunc("/migration/products/csv", migrationHandler.MigrateProductsFromCSV).Methods("POST")
+	api.Handle("/migration/products/csv", slow(migrationLimit(http.HandlerFunc(migrationHandler.MigrateProductsFromCSV)))).Methods("POST")
+	api.Handle("/migration/products/json", slow(migrationLimit(http.HandlerFunc(migrationHandler.MigrateProductsFromJSON)))).Methods("POST")
 	api.HandleFunc("/migration/products/template", migrationHandler.GetProductCSVTemplate).Methods("GET")
-	api.HandleFunc("/migration/purchases/csv", migrationHandler.MigratePurchasesFromCSV).Methods("POST")
+	api.Handle("/migration/purchases/csv", slow(migrationLimit(http.HandlerFunc(migrationHandler.MigratePurchasesFromCSV)))).Methods("POST")
 	api.HandleFunc("/migration/purchases/template", migrationHandler.GetPurchaseCSVTemplate).Methods("GET")
-	api.HandleFunc("/migration/sales/csv", migrationHandler.MigrateSalesFromCSV).Methods("POST")
+	api.Handle("/migration/sales/csv", slow(migrationLimit(http.HandlerFunc(migrationHandler.MigrateSalesFromCSV)))).Methods("POST")
 	api.HandleFunc("/migration/sales/template", migrationHandler.GetSaleCSVTemplate).Methods("GET")
 	api.HandleFunc("/migration/status", migrationHandler.GetMigrationStatus).Methods("GET")
+	api.HandleFunc("/migration/status/{transactionId}", migrationHandler.GetMigrationProgress).Methods("GET")
+
+	// Export routes (same column layout as the migration templates)
+	api.Handle("/export/customers/csv", slow(http.HandlerFunc(exportHandler.ExportCustomersCSV))).Methods("GET")
+	api.Handle("/export/products/csv", slow(http.HandlerFunc(exportHandler.ExportProductsCSV))).Methods("GET")
+	api.Handle("/export/purchases/csv", slow(http.HandlerFunc(exportHandler.ExportPurchasesCSV))).Methods("GET")
+	api.Handle("/export/sales/csv", slow(http.HandlerFunc(exportHandler.ExportSalesCSV))).Methods("GET")
+
+	// Report routes
+	api.Handle("/reports/inventory/xlsx", slow(http.HandlerFunc(reportHandler.ExportInventoryXLSX))).Methods("GET")
+	api.Handle("/reports/catalog.pdf", slow(http.HandlerFunc(reportHandler.ExportCatalogPDF))).Methods("GET")
+	api.Handle("/reports/catalog.xlsx", slow(http.HandlerFunc(reportHandler.ExportCatalogXLSX))).Methods("GET")
+	api.Handle("/reports/inventory-valuation", slow(http.HandlerFunc(reportHandler.GetInventoryValuation))).Methods("GET")
+	api.Handle("/reports/abc-analysis", slow(http.HandlerFunc(reportHandler.GetABCAnalysis))).Methods("GET")
+	api.Handle("/reports/expiring-lots", slow(http.HandlerFunc(lotHandler.GetExpiringLots))).Methods("GET")
+	api.Handle("/reports/dashboard", slow(http.HandlerFunc(reportHandler.GetDashboard))).Methods("GET")
+	api.Handle("/reports/revenue-trend", slow(http.HandlerFunc(reportHandler.GetRevenueTrend))).Methods("GET")
+	api.Handle("/reports/period-comparison", slow(http.HandlerFunc(reportHandler.GetPeriodComparison))).Methods("GET")
+	api.Handle("/reports/product-profitability", slow(http.HandlerFunc(reportHandler.GetProductProfitability))).Methods("GET")
+	api.Handle("/reports/sales-forecast", slow(http.HandlerFunc(reportHandler.GetSalesForecast))).Methods("GET")
+	api.Handle("/reports/customer-aging", slow(http.HandlerFunc(reportHandler.GetCustomerAging))).Methods("GET")
+	api.Handle("/reports/return-analysis", slow(http.HandlerFunc(reportHandler.GetReturnAnalysis))).Methods("GET")
+	api.Handle("/reports/delivery-performance", slow(http.HandlerFunc(reportHandler.GetDeliveryPerformance))).Methods("GET")
+	api.Handle("/reports/purchase-variance", slow(http.HandlerFunc(reportHandler.GetPurchaseVariance))).Methods("GET")
+	api.Handle("/reports/quotation-funnel", slow(http.HandlerFunc(reportHandler.GetQuotationFunnel))).Methods("GET")
+	api.Handle("/reports/quotation-by-customer", slow(http.HandlerFunc(reportHandler.GetQuotationFunnelByCustomer))).Methods("GET")
+	api.Handle("/reports/budget-variance", slow(http.HandlerFunc(budgetHandler.GetBudgetVariance))).Methods("GET")
 
-	// Static file serving for uploaded images
+	// Audit log routes
+	api.Handle("/audit-logs", adminOnly(http.HandlerFunc(auditLogHandler.GetAuditLogs))).Methods("GET")
+
+	// Static file serving for uploaded images (local storage backend)
+	api.HandleFunc("/images/products/{filename}", productHandler.ServeProductImage).Methods("GET")
 	router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads/"))))
 
-	// Health check
-	api.HandleFunc("/health", healthCheck).Methods("GET")
+	// Event routes
+	api.Handle("/events/inventory", middleware.Timeout(0)(http.HandlerFunc(deps.SSEHandler.StreamInventory))).Methods("GET")
+
+	// Health checks
+	api.HandleFunc("/health", healthHandler.HealthCheck).Methods("GET")
+	api.HandleFunc("/health/ready", healthHandler.Readiness).Methods("GET")
+	api.HandleFunc("/health/live", healthHandler.Liveness).Methods("GET")
+
+	// Prometheus metrics
+	api.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// API documentation
+	api.HandleFunc("/openapi.yaml", serveOpenAPISpec).Methods("GET")
+	api.HandleFunc("/docs", serveSwaggerUI).Methods("GET")
 
 	// CORS configuration
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
 		AllowCredentials: true,
 	})
 
@@ -111,13 +371,14 @@ func SetupRoutes(productRepo *repository.ProductRepository, customerRepo *reposi
 	return handler
 }
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	response := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   "1.0.0",
-		"database":  "mongodb",
-	}
-	json.NewEncoder(w).Encode(response)
+// serveOpenAPISpec serves the OpenAPI description of every route in SetupRoutes
+func serveOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPISpec)
+}
+
+// serveSwaggerUI serves a Swagger UI page that loads /api/openapi.yaml
+func serveSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(swaggerUI)
 }
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"goodpack-server/config"
+)
+
+// testRouter returns the API router with cfg and no database; only routes that need none can be served
+func testRouter(t *testing.T, cfg *config.Config) http.Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return SetupRoutes(ctx, cfg, Dependencies{})
+}
+
+func TestOpenAPISpecDocumentsTheMainRoutes(t *testing.T) {
+	router := testRouter(t, &config.Config{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.yaml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var spec struct {
+		OpenAPI string                            `yaml:"openapi"`
+		Paths   map[string]map[string]interface{} `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.0") {
+		t.Errorf("openapi = %q, want 3.0.x", spec.OpenAPI)
+	}
+
+	required := map[string][]string{
+		"/api/products":                {"get", "post"},
+		"/api/products/{id}":           {"get", "put", "delete"},
+		"/api/customers":               {"get", "post"},
+		"/api/customers/{id}/summary":  {"get"},
+		"/api/purchases":               {"get", "post"},
+		"/api/sales":                   {"get", "post"},
+		"/api/sales/{id}":              {"get", "put", "delete"},
+		"/api/quotations":              {"get", "post"},
+		"/api/stock/history":           {"get"},
+		"/api/reports/dashboard":       {"get"},
+		"/api/migration/customers/csv": {"post"},
+		"/api/health":                  {"get"},
+	}
+	for path, methods := range required {
+		operations, ok := spec.Paths[path]
+		if !ok {
+			t.Errorf("spec is missing %s", path)
+			continue
+		}
+		for _, method := range methods {
+			if _, ok := operations[method]; !ok {
+				t.Errorf("spec is missing %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestSwaggerUILoadsTheSpec(t *testing.T) {
+	router := testRouter(t, &config.Config{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("got %d %s, want an HTML page", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "/api/openapi.yaml") {
+		t.Error("Swagger UI page does not load /api/openapi.yaml")
+	}
+}
+
+func TestCORSPreflightChecksAllowedOrigins(t *testing.T) {
+	router := testRouter(t, &config.Config{
+		CORSAllowedOrigins: []string{"https://app.goodpack.co.th"},
+		CORSAllowedHeaders: []string{"Content-Type", "Authorization"},
+	})
+
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.goodpack.co.th", "https://app.goodpack.co.th"},
+		{"https://evil.example.com", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodOptions, "/api/products", nil)
+		req.Header.Set("Origin", tt.origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want %q", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSAllowsAnyOriginByDefault(t *testing.T) {
+	router := testRouter(t, &config.Config{
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedHeaders: []string{"*"},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/products", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got == "" {
+		t.Error("Access-Control-Allow-Origin is empty, want the request origin allowed")
+	}
+}
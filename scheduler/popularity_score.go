@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"goodpack-server/services"
+)
+
+// DefaultPopularityScoreInterval is how often product popularity scores are recomputed
+const DefaultPopularityScoreInterval = 24 * time.Hour
+
+// PopularityScoreJob periodically recomputes the popularity score of every product
+type PopularityScoreJob struct {
+	popularityService *services.PopularityService
+	interval          time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPopularityScoreJob(popularityService *services.PopularityService, interval time.Duration) *PopularityScoreJob {
+	if interval <= 0 {
+		interval = DefaultPopularityScoreInterval
+	}
+	return &PopularityScoreJob{
+		popularityService: popularityService,
+		interval:          interval,
+	}
+}
+
+// Start runs the job once immediately and then on every tick until Stop is called
+func (j *PopularityScoreJob) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+
+	log.Printf("⏰ Popularity score job started (every %s)", j.interval)
+}
+
+// Stop cancels the job and waits for a running pass to finish
+func (j *PopularityScoreJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce rescores every product and returns how many products' scores changed
+func (j *PopularityScoreJob) RunOnce(ctx context.Context) int64 {
+	result, err := j.popularityService.Rescore(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to score product popularity: %v", err)
+		}
+		return 0
+	}
+	if result.Changed > 0 {
+		log.Printf("Rescored the popularity of %d product(s)", result.Changed)
+	}
+	return result.Changed
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"goodpack-server/repository"
+)
+
+// DefaultQuotationExpiryInterval is how often overdue quotations are checked
+const DefaultQuotationExpiryInterval = time.Hour
+
+// QuotationExpiryJob periodically expires quotations past their validUntil date
+type QuotationExpiryJob struct {
+	quotationRepo *repository.QuotationRepository
+	interval      time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewQuotationExpiryJob(quotationRepo *repository.QuotationRepository, interval time.Duration) *QuotationExpiryJob {
+	if interval <= 0 {
+		interval = DefaultQuotationExpiryInterval
+	}
+	return &QuotationExpiryJob{
+		quotationRepo: quotationRepo,
+		interval:      interval,
+	}
+}
+
+// Start runs the job once immediately and then on every tick until Stop is called
+func (j *QuotationExpiryJob) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+
+	log.Printf("⏰ Quotation expiry job started (every %s)", j.interval)
+}
+
+// Stop cancels the job and waits for a running pass to finish
+func (j *QuotationExpiryJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce expires overdue quotations and returns how many were changed
+func (j *QuotationExpiryJob) RunOnce(ctx context.Context) int64 {
+	count, err := j.quotationRepo.ExpireOverdueQuotations(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to expire overdue quotations: %v", err)
+		}
+		return 0
+	}
+	if count > 0 {
+		log.Printf("Expired %d overdue quotation(s)", count)
+	}
+	return count
+}
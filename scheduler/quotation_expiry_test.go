@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// testDatabase returns a database of its own on the MongoDB at MONGODB_TEST_URI, dropped when the test ends.
+// Tests that need MongoDB are skipped when the variable is not set.
+func testDatabase(t *testing.T) *mongo.Database {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db := client.Database(fmt.Sprintf("goodpack_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+func TestQuotationExpiryRunOnce(t *testing.T) {
+	ctx := context.Background()
+
+	db := testDatabase(t)
+	quotationRepo := repository.NewQuotationRepository(db.Collection("quotations"))
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+	tests := []struct {
+		status     string
+		validUntil *time.Time
+		want       string
+	}{
+		{models.QuotationStatusDraft, &yesterday, models.QuotationStatusExpired},
+		{models.QuotationStatusSent, &yesterday, models.QuotationStatusExpired},
+		{models.QuotationStatusSent, &tomorrow, models.QuotationStatusSent},
+		{models.QuotationStatusSent, nil, models.QuotationStatusSent},
+		{models.QuotationStatusAccepted, &yesterday, models.QuotationStatusAccepted},
+		{models.QuotationStatusRejected, &yesterday, models.QuotationStatusRejected},
+	}
+	ids := make([]primitive.ObjectID, len(tests))
+	for i, tt := range tests {
+		ids[i] = primitive.NewObjectID()
+		quotation := &models.Quotation{
+			ID:            ids[i],
+			QuotationCode: fmt.Sprintf("QU-2401-%04d", i+1),
+			Status:        tt.status,
+			ValidUntil:    tt.validUntil,
+		}
+		if _, err := db.Collection("quotations").InsertOne(context.Background(), quotation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := NewQuotationExpiryJob(quotationRepo, 0)
+	if got := job.RunOnce(context.Background()); got != 2 {
+		t.Errorf("RunOnce() = %d, want 2", got)
+	}
+	for i, tt := range tests {
+		quotation, err := quotationRepo.GetByID(ctx, ids[i].Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quotation.Status != tt.want {
+			t.Errorf("%s quotation valid until %v: status = %q, want %q", tt.status, tt.validUntil, quotation.Status, tt.want)
+		}
+	}
+
+	if got := job.RunOnce(context.Background()); got != 0 {
+		t.Errorf("second RunOnce() = %d, want 0", got)
+	}
+}
+
+func TestNewQuotationExpiryJobDefaultsInterval(t *testing.T) {
+	if job := NewQuotationExpiryJob(nil, 0); job.interval != DefaultQuotationExpiryInterval {
+		t.Errorf("interval = %s, want %s", job.interval, DefaultQuotationExpiryInterval)
+	}
+	if job := NewQuotationExpiryJob(nil, time.Minute); job.interval != time.Minute {
+		t.Errorf("interval = %s, want %s", job.interval, time.Minute)
+	}
+}
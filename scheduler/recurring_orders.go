@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+// DefaultRecurringOrderInterval is how often due recurring orders are turned into sales
+const DefaultRecurringOrderInterval = 24 * time.Hour
+
+// RecurringOrderJob periodically creates the sales of recurring orders that are due
+type RecurringOrderJob struct {
+	recurringOrderRepo *repository.RecurringOrderRepository
+	saleService        *services.SaleService
+	webhookService     *services.WebhookService // optional; sale.created is sent for each sale when set
+	interval           time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRecurringOrderJob(recurringOrderRepo *repository.RecurringOrderRepository, saleService *services.SaleService, webhookService *services.WebhookService, interval time.Duration) *RecurringOrderJob {
+	if interval <= 0 {
+		interval = DefaultRecurringOrderInterval
+	}
+	return &RecurringOrderJob{
+		recurringOrderRepo: recurringOrderRepo,
+		saleService:        saleService,
+		webhookService:     webhookService,
+		interval:           interval,
+	}
+}
+
+// Start runs the job once immediately and then on every tick until Stop is called
+func (j *RecurringOrderJob) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+
+	log.Printf("⏰ Recurring order job started (every %s)", j.interval)
+}
+
+// Stop cancels the job and waits for a running pass to finish
+func (j *RecurringOrderJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce creates a sale for every active recurring order that is due and returns how many were created.
+// Each created sale moves the order's next run on by its interval. An order whose sale cannot be made, e.g.
+// because it would take the customer over their credit limit, keeps its next run and is retried on the
+// following pass, with the reason kept in lastError.
+func (j *RecurringOrderJob) RunOnce(ctx context.Context) int {
+	now := time.Now()
+	orders, err := j.recurringOrderRepo.GetDue(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to load due recurring orders: %v", err)
+		}
+		return 0
+	}
+
+	created := 0
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			break
+		}
+		if j.run(ctx, order, now) {
+			created++
+		}
+	}
+	if created > 0 {
+		log.Printf("Created %d sale(s) from recurring orders", created)
+	}
+	return created
+}
+
+// run creates the sale of one due recurring order and records the outcome on the order
+func (j *RecurringOrderJob) run(ctx context.Context, order *models.RecurringOrder, now time.Time) bool {
+	id := order.ID.Hex()
+
+	sale, err := j.saleService.CreateSale(ctx, order.ToSaleRequest(now))
+	if err != nil {
+		var creditExceeded *services.CreditLimitExceededError
+		if errors.As(err, &creditExceeded) {
+			log.Printf("Recurring order %s paused: customer %s is over their credit limit", id, order.CustomerID)
+		} else {
+			log.Printf("Warning: Failed to create sale from recurring order %s: %v", id, err)
+		}
+
+		reason := err.Error()
+		order.LastError = &reason
+		order.UpdatedAt = now
+		if err := j.recurringOrderRepo.Update(ctx, id, order); err != nil {
+			log.Printf("Warning: Failed to update recurring order %s: %v", id, err)
+		}
+		return false
+	}
+
+	order.Advance(now, sale.SaleCode)
+	if err := j.recurringOrderRepo.Update(ctx, id, order); err != nil {
+		// The order stays due, so the next pass would sell it again
+		log.Printf("Warning: Failed to schedule the next run of recurring order %s after sale %s: %v", id, sale.SaleCode, err)
+	}
+
+	if j.webhookService != nil {
+		if err := j.webhookService.Dispatch(ctx, models.WebhookEventSaleCreated, sale); err != nil {
+			log.Printf("Warning: Failed to dispatch %s webhooks: %v", models.WebhookEventSaleCreated, err)
+		}
+	}
+	return true
+}
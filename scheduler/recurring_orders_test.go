@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+	"goodpack-server/services"
+)
+
+func TestRecurringOrderRunOnce(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	productRepo, err := repository.NewProductRepository(db.Collection("products"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customerRepo := repository.NewCustomerRepository(db.Collection("customers"))
+	recurringOrderRepo := repository.NewRecurringOrderRepository(db.Collection("recurring_orders"))
+	saleService := services.NewSaleService(
+		repository.NewSaleRepository(db.Collection("sales")), productRepo, customerRepo,
+		repository.NewQuotationRepository(db.Collection("quotations")),
+		repository.NewStockAdjustmentRepository(db.Collection("stock_adjustments")),
+		repository.NewBundleRepository(db.Collection("bundles")),
+		repository.NewSerialNumberRepository(db.Collection("serial_numbers")),
+		repository.NewLotRepository(db.Collection("lots")),
+		repository.NewSaleReturnRepository(db.Collection("sale_returns")),
+	)
+
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: "BOX-0001", Name: "Kraft Box", Category: "Box"}
+	product.Stock.VAT = models.StockInfo{Purchased: 100, Remaining: 100}
+	product.Stock.ActualStock = 100
+	if _, err := db.Collection("products").InsertOne(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	regular := &models.Customer{ID: primitive.NewObjectID(), CompanyName: "Cafe Amazon"}
+	overLimit := &models.Customer{ID: primitive.NewObjectID(), CompanyName: "Sweet Bakery", CreditLimit: 100}
+	for _, customer := range []*models.Customer{regular, overLimit} {
+		if err := customerRepo.Create(ctx, customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(time.Millisecond)
+	tomorrow := time.Now().Add(24 * time.Hour).Truncate(time.Millisecond)
+	order := func(customer *models.Customer, nextRunAt time.Time, active bool) *models.RecurringOrder {
+		o := &models.RecurringOrder{
+			CustomerID:   customer.ID.Hex(),
+			Items:        []models.SaleItem{{ProductID: product.ID.Hex(), ProductName: product.Name, Quantity: 10, UnitPrice: 20, TotalPrice: 200}},
+			IsVAT:        true,
+			IntervalDays: 30,
+			NextRunAt:    nextRunAt,
+			IsActive:     active,
+		}
+		if err := recurringOrderRepo.Create(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	due := order(regular, yesterday, true)
+	notDue := order(regular, tomorrow, true)
+	paused := order(regular, yesterday, false)
+	overCredit := order(overLimit, yesterday, true)
+
+	job := NewRecurringOrderJob(recurringOrderRepo, saleService, nil, 0)
+	if got := job.RunOnce(ctx); got != 1 {
+		t.Fatalf("RunOnce() = %d, want 1", got)
+	}
+
+	stored, err := recurringOrderRepo.GetByID(ctx, due.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := yesterday.AddDate(0, 0, 30); !stored.NextRunAt.Equal(want) {
+		t.Errorf("NextRunAt = %v, want %v", stored.NextRunAt, want)
+	}
+	if stored.LastSaleCode == nil || stored.LastRunAt == nil || stored.LastError != nil {
+		t.Fatalf("due order = %+v, want its sale recorded", stored)
+	}
+	var sale models.Sale
+	if err := db.Collection("sales").FindOne(ctx, bson.M{"saleCode": *stored.LastSaleCode}).Decode(&sale); err != nil {
+		t.Fatalf("sale %s: %v", *stored.LastSaleCode, err)
+	}
+	if sale.CustomerID != regular.ID.Hex() || len(sale.Items) != 1 || sale.Items[0].Quantity != 10 || sale.Payment.IsPaid {
+		t.Errorf("sale = %+v, want an unpaid sale of 10 boxes to %s", sale, regular.CompanyName)
+	}
+	if product, err := productRepo.GetByID(ctx, product.ID.Hex()); err != nil || product.Stock.VAT.Remaining != 90 {
+		t.Errorf("VAT stock = %+v, %v, want 90 remaining", product.Stock.VAT, err)
+	}
+
+	for name, o := range map[string]*models.RecurringOrder{"not due": notDue, "inactive": paused, "over credit limit": overCredit} {
+		stored, err := recurringOrderRepo.GetByID(ctx, o.ID.Hex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.NextRunAt.Equal(o.NextRunAt) || stored.LastSaleCode != nil {
+			t.Errorf("%s order = %+v, want it left for a later run", name, stored)
+		}
+	}
+	if stored, _ := recurringOrderRepo.GetByID(ctx, overCredit.ID.Hex()); stored.LastError == nil {
+		t.Error("over credit limit order has no lastError, want the reason it was skipped")
+	}
+
+	if count, err := db.Collection("sales").CountDocuments(ctx, bson.M{}); err != nil || count != 1 {
+		t.Errorf("sales = %d, %v, want 1", count, err)
+	}
+	if got := job.RunOnce(ctx); got != 0 {
+		t.Errorf("second RunOnce() = %d, want 0 until the next due date", got)
+	}
+}
+
+func TestNewRecurringOrderJobDefaultsInterval(t *testing.T) {
+	if job := NewRecurringOrderJob(nil, nil, nil, 0); job.interval != DefaultRecurringOrderInterval {
+		t.Errorf("interval = %s, want %s", job.interval, DefaultRecurringOrderInterval)
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"goodpack-server/services"
+)
+
+// DefaultVelocityClassificationInterval is how often products are reclassified by sales velocity
+const DefaultVelocityClassificationInterval = 24 * time.Hour
+
+// VelocityClassificationJob periodically reclassifies products by how fast they sell
+type VelocityClassificationJob struct {
+	velocityService *services.VelocityService
+	interval        time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewVelocityClassificationJob(velocityService *services.VelocityService, interval time.Duration) *VelocityClassificationJob {
+	if interval <= 0 {
+		interval = DefaultVelocityClassificationInterval
+	}
+	return &VelocityClassificationJob{
+		velocityService: velocityService,
+		interval:        interval,
+	}
+}
+
+// Start runs the job once immediately and then on every tick until Stop is called
+func (j *VelocityClassificationJob) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce(ctx)
+			}
+		}
+	}()
+
+	log.Printf("⏰ Velocity classification job started (every %s)", j.interval)
+}
+
+// Stop cancels the job and waits for a running pass to finish
+func (j *VelocityClassificationJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// RunOnce reclassifies every product and returns how many products' classes changed
+func (j *VelocityClassificationJob) RunOnce(ctx context.Context) int64 {
+	result, err := j.velocityService.Reclassify(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to reclassify products by velocity: %v", err)
+		}
+		return 0
+	}
+	if result.Changed > 0 {
+		log.Printf("Reclassified %d product(s) by velocity", result.Changed)
+	}
+	return result.Changed
+}
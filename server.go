@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"goodpack-server/config"
+)
+
+// newServer builds the API server and, in TLS mode, the plain HTTP server redirecting to it (nil when there is
+// none). The returned function starts the API server, over HTTPS (HTTP/2) with certificate files or Let's
+// Encrypt when configured and over plain HTTP otherwise.
+func newServer(cfg *config.Config, handler http.Handler) (*http.Server, *http.Server, func() error, error) {
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: handler,
+	}
+	if !cfg.TLSEnabled() {
+		return server, nil, server.ListenAndServe, nil
+	}
+
+	server.TLSConfig = &tls.Config{
+		MinVersion:               tls.VersionTLS12,
+		PreferServerCipherSuites: true,
+	}
+	redirect := httpsRedirect(cfg.Port)
+	listen := func() error { return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile) }
+
+	if cfg.LetsEncrypt {
+		if len(cfg.LetsEncryptDomains) == 0 {
+			return nil, nil, nil, fmt.Errorf("LETS_ENCRYPT needs the host names in LETS_ENCRYPT_DOMAINS")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.LetsEncryptDomains...),
+			Cache:      autocert.DirCache(cfg.LetsEncryptCacheDir),
+		}
+		server.TLSConfig = manager.TLSConfig()
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+		server.TLSConfig.PreferServerCipherSuites = true
+		// The redirect server also answers Let's Encrypt's HTTP challenges
+		redirect = manager.HTTPHandler(redirect)
+		listen = func() error { return server.ListenAndServeTLS("", "") }
+	}
+
+	var redirectServer *http.Server
+	if cfg.HTTPRedirectPort != "0" {
+		redirectServer = &http.Server{
+			Addr:    ":" + cfg.HTTPRedirectPort,
+			Handler: redirect,
+		}
+	}
+	return server, redirectServer, listen, nil
+}
+
+// httpsRedirect permanently redirects plain HTTP requests to the same host and path on the HTTPS port
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goodpack-server/config"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		httpsPort string
+		target    string
+		want      string
+	}{
+		{"443", "http://goodpack.co.th/api/products?page=2", "https://goodpack.co.th/api/products?page=2"},
+		{"443", "http://goodpack.co.th:80/api/sales", "https://goodpack.co.th/api/sales"},
+		{"8443", "http://goodpack.co.th:8080/api/sales", "https://goodpack.co.th:8443/api/sales"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		httpsRedirect(tt.httpsPort).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tt.want {
+			t.Errorf("%s to port %s: %d to %q, want 301 to %q", tt.target, tt.httpsPort, w.Code, w.Header().Get("Location"), tt.want)
+		}
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	handler := http.NotFoundHandler()
+
+	server, redirect, _, err := newServer(&config.Config{Port: "8080", HTTPRedirectPort: "80"}, handler)
+	if err != nil || server.Addr != ":8080" || server.TLSConfig != nil || redirect != nil {
+		t.Errorf("plain HTTP: server %+v, redirect %v, error %v; want HTTP on :8080 without a redirect", server, redirect, err)
+	}
+
+	tlsConfig := &config.Config{Port: "8443", HTTPRedirectPort: "8080", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}
+	server, redirect, _, err = newServer(tlsConfig, handler)
+	if err != nil || server.TLSConfig == nil || server.TLSConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("TLS: server %+v, error %v; want TLS 1.2 or later", server, err)
+	}
+	if redirect == nil || redirect.Addr != ":8080" {
+		t.Errorf("TLS: redirect server %+v, want one on :8080", redirect)
+	}
+
+	tlsConfig.HTTPRedirectPort = "0"
+	if _, redirect, _, _ := newServer(tlsConfig, handler); redirect != nil {
+		t.Errorf("HTTP_REDIRECT_PORT=0: redirect server %+v, want none", redirect)
+	}
+
+	if _, _, _, err := newServer(&config.Config{Port: "443", LetsEncrypt: true}, handler); err == nil {
+		t.Error("Let's Encrypt without domains: want an error")
+	}
+	server, _, _, err = newServer(&config.Config{Port: "443", HTTPRedirectPort: "80", LetsEncrypt: true, LetsEncryptDomains: []string{"goodpack.co.th"}, LetsEncryptCacheDir: t.TempDir()}, handler)
+	if err != nil || server.TLSConfig == nil || server.TLSConfig.GetCertificate == nil {
+		t.Errorf("Let's Encrypt: server %+v, error %v; want certificates from autocert", server, err)
+	}
+}
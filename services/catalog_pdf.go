@@ -0,0 +1,168 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"goodpack-server/models"
+	"goodpack-server/storage"
+)
+
+const (
+	catalogImageSize     = 35 // mm
+	catalogSectionHeight = 42 // mm, image plus spacing
+	catalogNoCategory    = "ไม่ระบุหมวดหมู่"
+)
+
+// catalogImageTypes maps image file extensions to the types fpdf can embed
+var catalogImageTypes = map[string]string{
+	".jpg":  "JPG",
+	".jpeg": "JPG",
+	".png":  "PNG",
+	".gif":  "GIF",
+}
+
+// SortCatalogProducts orders products by category, then name, as they appear in the catalog
+func SortCatalogProducts(products []*models.Product) {
+	sort.SliceStable(products, func(i, j int) bool {
+		if products[i].Category != products[j].Category {
+			return products[i].Category < products[j].Category
+		}
+		return products[i].Name < products[j].Name
+	})
+}
+
+// GenerateCatalogPDF renders a product catalog grouped by category, one section per product with its
+// primary image, SKU, description, sale prices and current stock. Each category starts on a new page.
+func (s *PDFService) GenerateCatalogPDF(products []*models.Product) ([]byte, error) {
+	SortCatalogProducts(products)
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+
+	family := s.registerFonts(pdf)
+	company := s.loadCompanyInfo()
+	_, pageHeight := pdf.GetPageSize()
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(family, "", 10)
+		pdf.CellFormat(0, 8, fmt.Sprintf("%s - แคตตาล็อกสินค้า %s - หน้า %d", company.Name, FormatThaiDate(time.Now()), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	if len(products) == 0 {
+		pdf.AddPage()
+		pdf.SetFont(family, "", 14)
+		pdf.CellFormat(0, 10, "ไม่มีสินค้า", "", 1, "C", false, 0, "")
+	}
+
+	category := ""
+	for i, product := range products {
+		productCategory := product.Category
+		if productCategory == "" {
+			productCategory = catalogNoCategory
+		}
+		if i == 0 || productCategory != category {
+			category = productCategory
+			pdf.AddPage()
+			pdf.SetFont(family, "B", 18)
+			pdf.CellFormat(0, 10, category, "B", 1, "L", false, 0, "")
+			pdf.Ln(4)
+		} else if pdf.GetY()+catalogSectionHeight > pageHeight-20 {
+			pdf.AddPage()
+		}
+
+		s.renderCatalogProduct(pdf, family, product)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCatalogProduct draws one product section at the current position
+func (s *PDFService) renderCatalogProduct(pdf *fpdf.Fpdf, family string, product *models.Product) {
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	top := pdf.GetY()
+	textX := left + catalogImageSize + 5
+	textWidth := pageWidth - right - textX
+
+	pdf.Rect(left, top, catalogImageSize, catalogImageSize, "D")
+	if name, ok := s.registerCatalogImage(pdf, product); ok {
+		pdf.ImageOptions(name, left+1, top+1, catalogImageSize-2, catalogImageSize-2, false, fpdf.ImageOptions{}, 0, "")
+	}
+
+	pdf.SetXY(textX, top)
+	pdf.SetFont(family, "B", 14)
+	pdf.CellFormat(textWidth, 7, product.Name, "", 1, "L", false, 0, "")
+
+	pdf.SetFont(family, "", 12)
+	pdf.SetX(textX)
+	pdf.CellFormat(textWidth, 6, "รหัสสินค้า: "+product.SKUID, "", 1, "L", false, 0, "")
+	if product.Description != "" {
+		pdf.SetX(textX)
+		pdf.MultiCell(textWidth, 5, truncateRunes(product.Description, 200), "", "L", false)
+	}
+	pdf.SetX(textX)
+	pdf.CellFormat(textWidth, 6, fmt.Sprintf("ราคาขาย (VAT): %s บาท    ราคาขาย (ไม่มี VAT): %s บาท",
+		formatAmount(product.Price.SaleVAT.Latest), formatAmount(product.Price.SaleNonVAT.Latest)), "", 1, "L", false, 0, "")
+	pdf.SetX(textX)
+	pdf.CellFormat(textWidth, 6, fmt.Sprintf("สินค้าคงเหลือ: %d", product.GetTotalStock()), "", 1, "L", false, 0, "")
+
+	bottom := top + catalogSectionHeight
+	if pdf.GetY() > bottom {
+		bottom = pdf.GetY() + 4
+	}
+	pdf.SetY(bottom)
+}
+
+// registerCatalogImage loads the product's primary image from the local uploads directory.
+// Images stored elsewhere (e.g. S3) or in formats fpdf cannot embed are left out.
+func (s *PDFService) registerCatalogImage(pdf *fpdf.Fpdf, product *models.Product) (string, bool) {
+	url := product.PrimaryImageURL()
+	if url == nil || !strings.HasPrefix(*url, storage.DefaultLocalURLPrefix) {
+		return "", false
+	}
+
+	relative := filepath.Clean("/" + strings.TrimPrefix(*url, storage.DefaultLocalURLPrefix))
+	imageType, ok := catalogImageTypes[strings.ToLower(filepath.Ext(relative))]
+	if !ok {
+		return "", false
+	}
+
+	data, err := os.ReadFile(filepath.Join(storage.DefaultLocalDir, relative))
+	if err != nil {
+		log.Printf("Warning: Failed to read catalog image %s: %v", *url, err)
+		return "", false
+	}
+
+	name := "product-" + product.ID.Hex()
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+	if err := pdf.Error(); err != nil {
+		log.Printf("Warning: Failed to embed catalog image %s: %v", *url, err)
+		pdf.ClearError()
+		return "", false
+	}
+	return name, true
+}
+
+// truncateRunes shortens text to at most n characters, adding an ellipsis when cut
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
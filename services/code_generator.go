@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goodpack-server/repository"
+)
+
+// GenerateSaleCode generates the next sale code based on VAT status (INV-YYMM-XXXX or NV-YYMM-XXXX)
+func GenerateSaleCode(ctx context.Context, saleRepo *repository.SaleRepository, isVAT bool) (string, error) {
+	prefix := "NV-" + currentCodePeriod()
+	if isVAT {
+		prefix = "INV-" + currentCodePeriod()
+	}
+
+	nextSeq, err := saleRepo.GetNextSequenceNumber(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%04d", prefix, nextSeq), nil
+}
+
+// GeneratePurchaseCode generates the next purchase code based on VAT status (PUR-VAT-YYMM-XXXX or PUR-NV-YYMM-XXXX)
+func GeneratePurchaseCode(ctx context.Context, purchaseRepo *repository.PurchaseRepository, isVAT bool) (string, error) {
+	prefix := "PUR-NV-" + currentCodePeriod()
+	if isVAT {
+		prefix = "PUR-VAT-" + currentCodePeriod()
+	}
+
+	nextSeq, err := purchaseRepo.GetNextSequenceNumber(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%04d", prefix, nextSeq), nil
+}
+
+// currentCodePeriod returns the current month as YYMM in the Buddhist Era
+func currentCodePeriod() string {
+	now := time.Now()
+	beYear := now.Year() + 543
+	return fmt.Sprintf("%02d%02d", beYear%100, int(now.Month()))
+}
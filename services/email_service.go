@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EmailService sends HTML email
+type EmailService interface {
+	Send(to, subject, bodyHTML string) error
+}
+
+// SMTPEmailService sends email through an SMTP server, switching to TLS when the server offers STARTTLS.
+// Mail is sent from the SMTP user.
+type SMTPEmailService struct {
+	host     string
+	port     int
+	user     string
+	password string
+}
+
+func NewSMTPEmailService(host string, port int, user, password string) *SMTPEmailService {
+	return &SMTPEmailService{
+		host:     host,
+		port:     port,
+		user:     user,
+		password: password,
+	}
+}
+
+func (s *SMTPEmailService) Send(to, subject, bodyHTML string) error {
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	if err := smtp.SendMail(addr, auth, s.user, []string{to}, buildHTMLMessage(s.user, to, subject, bodyHTML)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", to, err)
+	}
+	return nil
+}
+
+// buildHTMLMessage builds a UTF-8 HTML message; the subject is MIME-encoded and the body base64-encoded
+// so Thai text survives any mail server
+func buildHTMLMessage(from, to, subject, bodyHTML string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(bodyHTML))
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	return msg.Bytes()
+}
+
+// SentEmail is an email recorded by MockEmailService
+type SentEmail struct {
+	To       string
+	Subject  string
+	BodyHTML string
+}
+
+// MockEmailService records emails instead of sending them, for tests and local development.
+// Setting Err makes Send fail with it.
+type MockEmailService struct {
+	mu   sync.Mutex
+	sent []SentEmail
+	Err  error
+}
+
+func (m *MockEmailService) Send(to, subject, bodyHTML string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.sent = append(m.sent, SentEmail{To: to, Subject: subject, BodyHTML: bodyHTML})
+	return nil
+}
+
+// Sent returns the emails sent so far
+func (m *MockEmailService) Sent() []SentEmail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]SentEmail(nil), m.sent...)
+}
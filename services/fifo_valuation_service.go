@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// CostLayer is a batch of stock received at one unit cost
+type CostLayer struct {
+	Quantity int
+	UnitCost float64
+}
+
+// ComputeFIFOLayers replays stock adjustments in chronological order and returns the cost layers
+// still on hand. opening is the stock on hand before the first adjustment (see OpeningStock) and is the
+// oldest layer. Additions push a layer priced by unitCost; reductions consume the oldest layers first.
+// Reductions beyond the stock on hand are carried as a shortfall that later additions fill first; transfers
+// between the VAT and Non-VAT buckets are skipped.
+func ComputeFIFOLayers(opening CostLayer, adjustments []*models.StockAdjustment, unitCost func(*models.StockAdjustment) float64) []CostLayer {
+	sorted := make([]*models.StockAdjustment, len(adjustments))
+	copy(sorted, adjustments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	var layers []CostLayer
+	if opening.Quantity > 0 {
+		layers = append(layers, opening)
+	}
+	shortfall := 0
+
+	for _, adjustment := range sorted {
+		if adjustment.Quantity <= 0 || adjustment.ToStockType != "" {
+			// Transfers between the VAT and Non-VAT buckets leave the stock on hand unchanged
+			continue
+		}
+
+		if adjustment.AdjustmentType == models.AdjustmentTypeAdd {
+			quantity := adjustment.Quantity
+			if shortfall > 0 {
+				filled := min(shortfall, quantity)
+				shortfall -= filled
+				quantity -= filled
+			}
+			if quantity > 0 {
+				layers = append(layers, CostLayer{Quantity: quantity, UnitCost: unitCost(adjustment)})
+			}
+			continue
+		}
+
+		remaining := adjustment.Quantity
+		for remaining > 0 && len(layers) > 0 {
+			consumed := min(remaining, layers[0].Quantity)
+			layers[0].Quantity -= consumed
+			remaining -= consumed
+			if layers[0].Quantity == 0 {
+				layers = layers[1:]
+			}
+		}
+		shortfall += remaining
+	}
+
+	return layers
+}
+
+// OpeningStock returns the stock a product had before the first of its adjustments: its actual stock less
+// what the adjustments added on balance. Stock from before the history began, or imported by migration, has
+// no adjustment of its own. It is 0 when the history accounts for all of the actual stock.
+func OpeningStock(actualStock int, adjustments []*models.StockAdjustment) int {
+	opening := actualStock
+	for _, adjustment := range adjustments {
+		if adjustment.Quantity <= 0 || adjustment.ToStockType != "" {
+			// As in ComputeFIFOLayers, transfers do not count
+			continue
+		}
+		if adjustment.AdjustmentType == models.AdjustmentTypeAdd {
+			opening -= adjustment.Quantity
+		} else {
+			opening += adjustment.Quantity
+		}
+	}
+	return max(opening, 0)
+}
+
+// ValuationService values the inventory using FIFO or average cost
+type ValuationService struct {
+	productRepo         *repository.ProductRepository
+	purchaseRepo        *repository.PurchaseRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+}
+
+func NewValuationService(productRepo *repository.ProductRepository, purchaseRepo *repository.PurchaseRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) *ValuationService {
+	return &ValuationService{
+		productRepo:         productRepo,
+		purchaseRepo:        purchaseRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+	}
+}
+
+// InventoryValuation values every product with the given method (fifo or average)
+func (s *ValuationService) InventoryValuation(ctx context.Context, method string) (*models.InventoryValuationReport, error) {
+	products, err := s.productRepo.GetAll(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	var adjustmentsByProduct map[string][]*models.StockAdjustment
+	purchaseCosts := make(map[string]map[string]float64) // purchaseID -> productID -> unit cost
+	if method == models.ValuationMethodFIFO {
+		adjustments, err := s.stockAdjustmentRepo.GetAll(ctx, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock history: %w", err)
+		}
+		adjustmentsByProduct = make(map[string][]*models.StockAdjustment)
+		for _, adjustment := range adjustments {
+			adjustmentsByProduct[adjustment.ProductID] = append(adjustmentsByProduct[adjustment.ProductID], adjustment)
+		}
+	}
+
+	report := &models.InventoryValuationReport{
+		Method: method,
+		Items:  make([]models.InventoryValuationItem, 0, len(products)),
+	}
+
+	for _, product := range products {
+		item := models.InventoryValuationItem{
+			ProductID:   product.ID.Hex(),
+			SKUID:       product.SKUID,
+			Name:        product.Name,
+			Category:    product.Category,
+			ActualStock: product.Stock.ActualStock,
+		}
+
+		averageCost := averagePurchaseCost(product)
+		if method == models.ValuationMethodFIFO {
+			history := adjustmentsByProduct[item.ProductID]
+			// Stock without history of its own is the oldest, valued at average cost like other stock not bought through a purchase
+			opening := CostLayer{Quantity: OpeningStock(product.Stock.ActualStock, history), UnitCost: averageCost}
+			layers := ComputeFIFOLayers(opening, history, func(adjustment *models.StockAdjustment) float64 {
+				if adjustment.SourceType == models.SourceTypePurchase && adjustment.SourceID != nil {
+					if cost, ok := s.purchaseUnitCost(ctx, purchaseCosts, *adjustment.SourceID, item.ProductID); ok {
+						return cost
+					}
+				}
+				// Stock not bought through a purchase (adjustments, returns, migration) is valued at average cost
+				return averageCost
+			})
+			for _, layer := range layers {
+				item.Quantity += layer.Quantity
+				item.TotalValue += float64(layer.Quantity) * layer.UnitCost
+			}
+		} else {
+			item.Quantity = product.Stock.ActualStock
+			item.TotalValue = float64(item.Quantity) * averageCost
+		}
+
+		if item.Quantity != 0 {
+			item.UnitCost = roundValuation(item.TotalValue / float64(item.Quantity))
+		}
+		item.TotalValue = roundValuation(item.TotalValue)
+		report.TotalValue += item.TotalValue
+		report.Items = append(report.Items, item)
+	}
+
+	report.TotalValue = roundValuation(report.TotalValue)
+	return report, nil
+}
+
+// purchaseUnitCost returns the discounted unit price a product was bought at on a purchase, caching each purchase
+func (s *ValuationService) purchaseUnitCost(ctx context.Context, cache map[string]map[string]float64, purchaseID, productID string) (float64, bool) {
+	costs, ok := cache[purchaseID]
+	if !ok {
+		costs = make(map[string]float64)
+		if purchase, err := s.purchaseRepo.GetByID(ctx, purchaseID); err == nil {
+			for _, purchaseItem := range purchase.Items {
+				if _, seen := costs[purchaseItem.ProductID]; !seen && purchaseItem.Quantity > 0 {
+					costs[purchaseItem.ProductID] = purchaseItem.TotalPrice / float64(purchaseItem.Quantity)
+				}
+			}
+		}
+		cache[purchaseID] = costs
+	}
+
+	cost, ok := costs[productID]
+	return cost, ok
+}
+
+// averagePurchaseCost returns the average VAT purchase price, falling back to the Non-VAT average
+func averagePurchaseCost(product *models.Product) float64 {
+	if product.Price.PurchaseVAT.Average > 0 {
+		return product.Price.PurchaseVAT.Average
+	}
+	return product.Price.PurchaseNonVAT.Average
+}
+
+// roundValuation rounds an amount to 2 decimal places
+func roundValuation(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
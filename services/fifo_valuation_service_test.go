@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// fifoHistory builds a product's stock history, day by day from the start of 2024, with the unit cost of each addition
+type fifoHistory struct {
+	adjustments []*models.StockAdjustment
+	costs       map[*models.StockAdjustment]float64
+}
+
+func (h *fifoHistory) record(day int, adjustmentType models.StockAdjustmentType, quantity int) *models.StockAdjustment {
+	adjustment := &models.StockAdjustment{
+		AdjustmentType: adjustmentType,
+		Quantity:       quantity,
+		CreatedAt:      time.Date(2024, 1, 1+day, 0, 0, 0, 0, time.UTC),
+	}
+	h.adjustments = append(h.adjustments, adjustment)
+	return adjustment
+}
+
+func (h *fifoHistory) add(day, quantity int, unitCost float64) {
+	if h.costs == nil {
+		h.costs = make(map[*models.StockAdjustment]float64)
+	}
+	h.costs[h.record(day, models.AdjustmentTypeAdd, quantity)] = unitCost
+}
+
+func (h *fifoHistory) reduce(day, quantity int) {
+	h.record(day, models.AdjustmentTypeReduce, quantity)
+}
+
+func (h *fifoHistory) transfer(day, quantity int) {
+	h.record(day, models.AdjustmentTypeReduce, quantity).ToStockType = models.StockTypeNonVAT
+}
+
+func (h *fifoHistory) unitCost(adjustment *models.StockAdjustment) float64 {
+	return h.costs[adjustment]
+}
+
+func TestComputeFIFOLayersSellsOldestBatchesFirst(t *testing.T) {
+	var h fifoHistory
+	h.reduce(5, 12) // recorded out of order on purpose
+	h.add(1, 10, 100)
+	h.add(3, 10, 120)
+	h.add(7, 5, 130)
+	h.reduce(9, 3)
+
+	layers := ComputeFIFOLayers(CostLayer{}, h.adjustments, h.unitCost)
+
+	want := []CostLayer{{Quantity: 5, UnitCost: 120}, {Quantity: 5, UnitCost: 130}}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("layers = %+v, want %+v", layers, want)
+	}
+}
+
+func TestComputeFIFOLayersFillsShortfallFromLaterBatch(t *testing.T) {
+	var h fifoHistory
+	h.add(1, 2, 100)
+	h.reduce(2, 5)
+	h.add(3, 10, 110)
+
+	layers := ComputeFIFOLayers(CostLayer{}, h.adjustments, h.unitCost)
+
+	want := []CostLayer{{Quantity: 7, UnitCost: 110}}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("layers = %+v, want %+v", layers, want)
+	}
+}
+
+func TestComputeFIFOLayersSellsOpeningStockFirst(t *testing.T) {
+	var h fifoHistory
+	h.add(1, 10, 100)
+	h.reduce(2, 6)
+
+	layers := ComputeFIFOLayers(CostLayer{Quantity: 8, UnitCost: 90}, h.adjustments, h.unitCost)
+
+	want := []CostLayer{{Quantity: 2, UnitCost: 90}, {Quantity: 10, UnitCost: 100}}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("layers = %+v, want %+v", layers, want)
+	}
+}
+
+func TestComputeFIFOLayersSkipsTransfers(t *testing.T) {
+	var h fifoHistory
+	h.add(1, 10, 100)
+	h.transfer(2, 4)
+
+	layers := ComputeFIFOLayers(CostLayer{}, h.adjustments, h.unitCost)
+
+	want := []CostLayer{{Quantity: 10, UnitCost: 100}}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("layers = %+v, want %+v", layers, want)
+	}
+}
+
+func TestOpeningStock(t *testing.T) {
+	var h fifoHistory
+	h.add(1, 10, 100)
+	h.reduce(2, 4)
+	h.transfer(3, 3)
+
+	tests := []struct {
+		name        string
+		actualStock int
+		history     []*models.StockAdjustment
+		want        int
+	}{
+		{"imported without history", 25, nil, 25},
+		{"stock from before the history", 20, h.adjustments, 14},
+		{"history accounts for all stock", 6, h.adjustments, 0},
+		{"history accounts for more than the stock", 2, h.adjustments, 0},
+	}
+	for _, tt := range tests {
+		if got := OpeningStock(tt.actualStock, tt.history); got != tt.want {
+			t.Errorf("%s: OpeningStock = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
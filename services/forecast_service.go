@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// ForecastService forecasts product sales from their history
+type ForecastService struct {
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+}
+
+func NewForecastService(stockAdjustmentRepo *repository.StockAdjustmentRepository) *ForecastService {
+	return &ForecastService{
+		stockAdjustmentRepo: stockAdjustmentRepo,
+	}
+}
+
+// ForecastMovingAverage forecasts the next periods values of a series with a moving average over
+// len(history) values. Each forecast is appended to the series, so later periods average earlier forecasts.
+func ForecastMovingAverage(history []float64, periods int) []float64 {
+	if len(history) == 0 || periods <= 0 {
+		return []float64{}
+	}
+
+	window := make([]float64, len(history))
+	copy(window, history)
+	forecast := make([]float64, 0, periods)
+	for i := 0; i < periods; i++ {
+		var sum float64
+		for _, value := range window {
+			sum += value
+		}
+		next := sum / float64(len(window))
+		forecast = append(forecast, next)
+		window = append(window[1:], next)
+	}
+	return forecast
+}
+
+// ForecastConfidence rates a moving-average forecast by the coefficient of variation (standard deviation
+// over mean) of the history it was made from: steady sales give high confidence, erratic or no sales low
+func ForecastConfidence(history []float64) string {
+	if len(history) == 0 {
+		return models.ForecastConfidenceLow
+	}
+
+	var sum float64
+	for _, value := range history {
+		sum += value
+	}
+	mean := sum / float64(len(history))
+	if mean <= 0 {
+		return models.ForecastConfidenceLow
+	}
+
+	var variance float64
+	for _, value := range history {
+		variance += (value - mean) * (value - mean)
+	}
+	variance /= float64(len(history))
+
+	switch cv := math.Sqrt(variance) / mean; {
+	case cv < 0.25:
+		return models.ForecastConfidenceHigh
+	case cv < 0.5:
+		return models.ForecastConfidenceMedium
+	}
+	return models.ForecastConfidenceLow
+}
+
+// SalesForecast forecasts a product's sales for the periods starting with the one containing now, from the
+// window completed periods before it. Revenue is the forecast quantity at the average price over the window.
+func (s *ForecastService) SalesForecast(ctx context.Context, productID, granularity string, window, periods int, now time.Time) ([]models.SalesForecastPoint, error) {
+	current := models.TrendPeriodStart(granularity, now)
+	from := models.AddTrendPeriods(granularity, current, -window)
+
+	sales, err := s.stockAdjustmentRepo.GetSalesByPeriod(ctx, productID, granularity, from, current.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales history: %w", err)
+	}
+
+	history := make([]float64, 0, window)
+	var quantity, revenue float64
+	for _, period := range models.TrendPeriods(granularity, from, current.Add(-time.Nanosecond)) {
+		history = append(history, sales[period].Quantity)
+		quantity += sales[period].Quantity
+		revenue += sales[period].Revenue
+	}
+	var averagePrice float64
+	if quantity > 0 {
+		averagePrice = revenue / quantity
+	}
+
+	confidence := ForecastConfidence(history)
+	forecast := make([]models.SalesForecastPoint, 0, periods)
+	for i, qty := range ForecastMovingAverage(history, periods) {
+		forecast = append(forecast, models.SalesForecastPoint{
+			Period:            models.TrendPeriod(granularity, models.AddTrendPeriods(granularity, current, i)),
+			ForecastedQty:     math.Round(qty*100) / 100,
+			ForecastedRevenue: math.Round(qty*averagePrice*100) / 100,
+			Confidence:        confidence,
+		})
+	}
+	return forecast, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"goodpack-server/models"
+)
+
+func TestForecastMovingAverage(t *testing.T) {
+	history := []float64{3, 6, 9}
+	forecast := ForecastMovingAverage(history, 3)
+
+	// Each period averages the last three values, including earlier forecasts
+	want := []float64{6, 7, 22.0 / 3}
+	if len(forecast) != len(want) {
+		t.Fatalf("forecast = %v, want %v", forecast, want)
+	}
+	for i := range want {
+		if math.Abs(forecast[i]-want[i]) > 1e-9 {
+			t.Errorf("period %d = %v, want %v", i+1, forecast[i], want[i])
+		}
+	}
+	if history[0] != 3 || history[2] != 9 {
+		t.Errorf("history changed to %v", history)
+	}
+
+	if got := ForecastMovingAverage(nil, 3); len(got) != 0 || got == nil {
+		t.Errorf("forecast without history = %v, want empty", got)
+	}
+	if got := ForecastMovingAverage(history, 0); len(got) != 0 {
+		t.Errorf("forecast of no periods = %v, want empty", got)
+	}
+}
+
+func TestForecastConfidence(t *testing.T) {
+	tests := []struct {
+		history []float64
+		want    string
+	}{
+		{[]float64{10, 10, 10}, models.ForecastConfidenceHigh},
+		{[]float64{10, 14, 6}, models.ForecastConfidenceMedium}, // coefficient of variation ~0.33
+		{[]float64{0, 20}, models.ForecastConfidenceLow},
+		{[]float64{0, 0, 0}, models.ForecastConfidenceLow},
+		{nil, models.ForecastConfidenceLow},
+	}
+	for _, tt := range tests {
+		if got := ForecastConfidence(tt.history); got != tt.want {
+			t.Errorf("ForecastConfidence(%v) = %s, want %s", tt.history, got, tt.want)
+		}
+	}
+}
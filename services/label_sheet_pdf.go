@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+
+	"goodpack-server/models"
+)
+
+const (
+	labelSheetMargin  = 8   // mm around the label grid
+	labelPadding      = 2   // mm inside each label
+	labelMaxLineSize  = 5   // mm, text line height on large labels
+	labelFontPerLine  = 2.4 // font size in points per mm of line height
+	labelQRMaxPortion = 0.4 // share of the label width the QR code may take
+)
+
+// Largest label grid on an A4 page; smaller labels would not fit a readable QR code
+const (
+	LabelSheetMaxColumns = 6
+	LabelSheetMaxRows    = 15
+)
+
+// GenerateLabelSheetPDF renders shelf labels on A4 pages, columns × rows labels per page. Each label has a QR code
+// of the SKU ID, the SKU ID, the product name (truncated to fit), color, size and current stock.
+func (s *PDFService) GenerateLabelSheetPDF(products []*models.Product, columns, rows int) ([]byte, error) {
+	if columns < 1 || columns > LabelSheetMaxColumns || rows < 1 || rows > LabelSheetMaxRows {
+		return nil, fmt.Errorf("label sheet must have 1-%d columns and 1-%d rows", LabelSheetMaxColumns, LabelSheetMaxRows)
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(labelSheetMargin, labelSheetMargin, labelSheetMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetDrawColor(200, 200, 200)
+
+	family := s.registerFonts(pdf)
+	pageWidth, pageHeight := pdf.GetPageSize()
+	labelWidth := (pageWidth - 2*labelSheetMargin) / float64(columns)
+	labelHeight := (pageHeight - 2*labelSheetMargin) / float64(rows)
+
+	perPage := columns * rows
+	for i, product := range products {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		cell := i % perPage
+		x := labelSheetMargin + float64(cell%columns)*labelWidth
+		y := labelSheetMargin + float64(cell/columns)*labelHeight
+		s.renderLabel(pdf, family, product, x, y, labelWidth, labelHeight)
+	}
+	if len(products) == 0 {
+		pdf.AddPage()
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderLabel draws one label with its top left corner at x, y: the QR code on the left, the text on the right
+func (s *PDFService) renderLabel(pdf *fpdf.Fpdf, family string, product *models.Product, x, y, width, height float64) {
+	pdf.Rect(x, y, width, height, "D")
+
+	qrSize := math.Min(height-2*labelPadding, width*labelQRMaxPortion)
+	if png, err := qrcode.Encode(product.SKUID, qrcode.Medium, 256); err == nil {
+		name := "label-qr-" + product.ID.Hex()
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(png))
+		pdf.ImageOptions(name, x+labelPadding, y+labelPadding, qrSize, qrSize, false, options, 0, "")
+	} else {
+		log.Printf("Warning: Failed to generate label QR code for %s: %v", product.SKUID, err)
+	}
+
+	lines := []string{
+		product.SKUID,
+		product.Name,
+		strings.Join(nonEmpty(product.Color, product.Size), " / "),
+		fmt.Sprintf("คงเหลือ: %d", product.GetTotalStock()),
+	}
+	lineHeight := math.Min(labelMaxLineSize, (height-2*labelPadding)/float64(len(lines)))
+	textX := x + 2*labelPadding + qrSize
+	textWidth := x + width - labelPadding - textX
+
+	for i, line := range lines {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		pdf.SetFont(family, style, lineHeight*labelFontPerLine)
+		pdf.SetXY(textX, y+labelPadding+float64(i)*lineHeight)
+		pdf.CellFormat(textWidth, lineHeight, fitText(pdf, line, textWidth), "", 0, "L", false, 0, "")
+	}
+}
+
+// fitText shortens text with an ellipsis until it fits width in the current font
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for n := len(runes) - 1; n > 0; n-- {
+		if truncated := string(runes[:n]) + "…"; pdf.GetStringWidth(truncated) <= width {
+			return truncated
+		}
+	}
+	return ""
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
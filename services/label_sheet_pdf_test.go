@@ -0,0 +1,53 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"goodpack-server/models"
+)
+
+// pdfPage matches the page objects of a PDF, but not the page tree
+var pdfPage = regexp.MustCompile(`/Type /Page\b`)
+
+func TestGenerateLabelSheetPDF(t *testing.T) {
+	var products []*models.Product
+	for i := 1; i <= 10; i++ {
+		product := &models.Product{SKUID: fmt.Sprintf("BOX-%04d", i), Name: fmt.Sprintf("Kraft Box %d", i), Color: "Brown", Size: "20x20x10"}
+		product.Stock.ActualStock = i * 10
+		products = append(products, product)
+	}
+
+	pdf, err := NewPDFService().GenerateLabelSheetPDF(products, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, pdf, "BOX-0001")
+
+	// A4 portrait in points
+	if !bytes.Contains(pdf, []byte("/MediaBox [0 0 595.28 841.89]")) {
+		t.Error("PDF pages are not A4")
+	}
+	// 8 labels a page
+	if pages := len(pdfPage.FindAll(pdf, -1)); pages != 2 {
+		t.Errorf("pages = %d, want 2 for 10 labels of 4 × 2", pages)
+	}
+	text := pdfText(t, pdf)
+	for _, product := range products {
+		if !strings.Contains(text, product.SKUID) {
+			t.Errorf("label sheet does not contain %s", product.SKUID)
+		}
+	}
+}
+
+func TestGenerateLabelSheetPDFRejectsGridsThatDoNotFit(t *testing.T) {
+	products := []*models.Product{{SKUID: "BOX-0001", Name: "Kraft Box"}}
+	for _, grid := range [][2]int{{0, 8}, {4, 0}, {LabelSheetMaxColumns + 1, 8}, {4, LabelSheetMaxRows + 1}} {
+		if _, err := NewPDFService().GenerateLabelSheetPDF(products, grid[0], grid[1]); err == nil {
+			t.Errorf("%d × %d labels: err = nil, want an error", grid[0], grid[1])
+		}
+	}
+}
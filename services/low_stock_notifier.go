@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"sync"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// DefaultLowStockCheckInterval is how often low-stock products are checked
+const DefaultLowStockCheckInterval = time.Hour
+
+// LowStockSource lists the products at or below their reorder level; *repository.ProductRepository implements it
+type LowStockSource interface {
+	GetLowStockProducts(ctx context.Context, threshold int) ([]*models.Product, error)
+}
+
+var lowStockEmailTemplate = template.Must(template.New("lowStock").Parse(`<p>{{len .}} product(s) are at or below their reorder level:</p>
+<table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse">
+<tr><th>SKU</th><th>Product</th><th>Category</th><th>Stock</th><th>Reorder level</th><th>Suggested order</th></tr>
+{{range .}}<tr><td>{{.SKUID}}</td><td>{{.Name}}</td><td>{{.Category}}</td><td align="right">{{.GetTotalStock}}</td><td align="right">{{.GetReorderLevel}}</td><td align="right">{{.GetSuggestedOrderQty}}</td></tr>
+{{end}}</table>
+`))
+
+// LowStockNotifier periodically emails the products at or below their reorder level. An email is only sent
+// when a product has become low on stock since the last one, and then lists every low-stock product.
+type LowStockNotifier struct {
+	source       LowStockSource
+	emailService EmailService
+	alertEmail   string
+	interval     time.Duration
+
+	notified map[string]bool // IDs of the products low on stock at the last email
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewLowStockNotifier(source LowStockSource, emailService EmailService, alertEmail string, interval time.Duration) *LowStockNotifier {
+	if interval <= 0 {
+		interval = DefaultLowStockCheckInterval
+	}
+	return &LowStockNotifier{
+		source:       source,
+		emailService: emailService,
+		alertEmail:   alertEmail,
+		interval:     interval,
+		notified:     make(map[string]bool),
+	}
+}
+
+// Start checks once immediately and then on every tick until Stop is called
+func (n *LowStockNotifier) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		n.RunOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n.RunOnce(ctx)
+			}
+		}
+	}()
+
+	log.Printf("⏰ Low-stock notifier started (every %s, alerts to %s)", n.interval, n.alertEmail)
+}
+
+// Stop cancels the notifier and waits for a running check to finish
+func (n *LowStockNotifier) Stop() {
+	if n.cancel == nil {
+		return
+	}
+	n.cancel()
+	n.wg.Wait()
+}
+
+// RunOnce checks the low-stock products and emails them if any is new since the last email. It reports
+// whether an email was sent. Products that recover are forgotten, so they are reported again if they run low
+// again; if the email fails the next check tries again.
+func (n *LowStockNotifier) RunOnce(ctx context.Context) bool {
+	products, err := n.source.GetLowStockProducts(ctx, 0)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to check low-stock products: %v", err)
+		}
+		return false
+	}
+
+	current := make(map[string]bool, len(products))
+	newlyLow := 0
+	for _, product := range products {
+		id := product.ID.Hex()
+		current[id] = true
+		if !n.notified[id] {
+			newlyLow++
+		}
+	}
+
+	if newlyLow == 0 {
+		n.notified = current
+		return false
+	}
+
+	body, err := RenderLowStockEmail(products)
+	if err != nil {
+		log.Printf("Warning: Failed to render low-stock email: %v", err)
+		return false
+	}
+	subject := fmt.Sprintf("Low stock alert: %d product(s) at or below reorder level", len(products))
+	if err := n.emailService.Send(n.alertEmail, subject, body); err != nil {
+		log.Printf("Warning: Failed to send low-stock email: %v", err)
+		return false
+	}
+
+	n.notified = current
+	log.Printf("Sent low-stock alert for %d product(s), %d new", len(products), newlyLow)
+	return true
+}
+
+// RenderLowStockEmail renders the HTML table of low-stock products sent by the notifier
+func RenderLowStockEmail(products []*models.Product) (string, error) {
+	var body bytes.Buffer
+	if err := lowStockEmailTemplate.Execute(&body, products); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+)
+
+// stubLowStockSource returns a fixed list of low-stock products
+type stubLowStockSource struct {
+	products []*models.Product
+}
+
+func (s *stubLowStockSource) GetLowStockProducts(ctx context.Context, threshold int) ([]*models.Product, error) {
+	return s.products, nil
+}
+
+func lowStockProduct(sku, name string, stock, reorderLevel int) *models.Product {
+	product := &models.Product{ID: primitive.NewObjectID(), SKUID: sku, Name: name, Category: "Box", ReorderLevel: reorderLevel}
+	product.Stock.ActualStock = stock
+	return product
+}
+
+func TestLowStockNotifierEmailsEveryLowStockProduct(t *testing.T) {
+	source := &stubLowStockSource{products: []*models.Product{
+		lowStockProduct("BOX-0001", "Kraft Box", 3, 10),
+		lowStockProduct("BOX-0002", "Pizza Box", 0, 20),
+	}}
+	email := &MockEmailService{}
+	notifier := NewLowStockNotifier(source, email, "stock@goodpack.test", 0)
+
+	if !notifier.RunOnce(context.Background()) {
+		t.Fatal("RunOnce() = false, want an email for two low-stock products")
+	}
+	sent := email.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(sent))
+	}
+	if sent[0].To != "stock@goodpack.test" {
+		t.Errorf("To = %q, want the alert email", sent[0].To)
+	}
+	if !strings.Contains(sent[0].Subject, "2 product(s)") {
+		t.Errorf("Subject = %q, want it to count 2 products", sent[0].Subject)
+	}
+	for _, want := range []string{"BOX-0001", "Kraft Box", "BOX-0002", "Pizza Box"} {
+		if !strings.Contains(sent[0].BodyHTML, want) {
+			t.Errorf("body does not list %q:\n%s", want, sent[0].BodyHTML)
+		}
+	}
+
+	// Nothing new since the last email
+	if notifier.RunOnce(context.Background()) {
+		t.Error("second RunOnce() = true, want no email when no product became low on stock")
+	}
+
+	// A third product running low sends the full list again
+	source.products = append(source.products, lowStockProduct("WRP-0001", "Bubble Wrap", 1, 5))
+	if !notifier.RunOnce(context.Background()) {
+		t.Fatal("RunOnce() = false, want an email for the newly low product")
+	}
+	if body := email.Sent()[1].BodyHTML; !strings.Contains(body, "Kraft Box") || !strings.Contains(body, "Bubble Wrap") {
+		t.Errorf("body = %s, want every low-stock product listed", body)
+	}
+}
+
+func TestLowStockNotifierRetriesAfterAFailedEmail(t *testing.T) {
+	source := &stubLowStockSource{products: []*models.Product{lowStockProduct("BOX-0001", "Kraft Box", 3, 10)}}
+	email := &MockEmailService{Err: errors.New("smtp unavailable")}
+	notifier := NewLowStockNotifier(source, email, "stock@goodpack.test", 0)
+
+	if notifier.RunOnce(context.Background()) {
+		t.Fatal("RunOnce() = true, want false when the email fails")
+	}
+	email.Err = nil
+	if !notifier.RunOnce(context.Background()) || len(email.Sent()) != 1 {
+		t.Errorf("after the failure: sent %d emails, want the alert retried", len(email.Sent()))
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+
+	"goodpack-server/models"
+)
+
+const (
+	pdfFontFamily  = "THSarabun"
+	pdfFontRegular = "THSarabunNew.ttf"
+	pdfFontBold    = "THSarabunNew-Bold.ttf"
+)
+
+var thaiShortMonths = []string{
+	"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.",
+	"ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค.",
+}
+
+// CompanyInfo represents the company header printed on documents
+type CompanyInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	TaxID   string `json:"taxId"`
+	Phone   string `json:"phone"`
+}
+
+// invoiceLine is a single row of the items table
+type invoiceLine struct {
+	Name      string
+	Code      string
+	Quantity  float64
+	UnitPrice float64
+	VAT       float64
+	Total     float64
+}
+
+// invoiceDocument holds everything needed to render a sale or purchase document
+type invoiceDocument struct {
+	Title         string
+	Code          string
+	Date          time.Time
+	PartyLabel    string
+	PartyName     string
+	PartyTaxID    string
+	PartyAddress  string
+	PartyPhone    string
+	Lines         []invoiceLine
+	Subtotal      float64
+	DiscountTotal float64
+	VATRate       float64
+	VAT           float64
+	ShippingCost  float64
+	GrandTotal    float64
+	BankName      string
+	AccountName   string
+	AccountNumber string
+	Notes         string
+}
+
+type PDFService struct {
+	fontDir string
+}
+
+func NewPDFService() *PDFService {
+	return &PDFService{
+		fontDir: "fonts",
+	}
+}
+
+// GenerateSalePDF renders a sale as a Thai invoice PDF
+func (s *PDFService) GenerateSalePDF(sale *models.Sale) ([]byte, error) {
+	doc := invoiceDocument{
+		Title:         "ใบแจ้งหนี้ / Invoice",
+		Code:          sale.SaleCode,
+		Date:          sale.SaleDate,
+		PartyLabel:    "ลูกค้า",
+		PartyName:     sale.CustomerName,
+		PartyTaxID:    derefString(sale.TaxID),
+		PartyAddress:  derefString(sale.Address),
+		PartyPhone:    derefString(sale.Phone),
+		DiscountTotal: sale.DiscountTotal,
+		VATRate:       sale.EffectiveVATRate(),
+		ShippingCost:  sale.ShippingCost,
+		BankName:      derefString(sale.BankName),
+		AccountName:   derefString(sale.BankAccountName),
+		AccountNumber: derefString(sale.BankAccountNumber),
+		Notes:         derefString(sale.Notes),
+	}
+	if doc.AccountNumber == "" && sale.Payment.OurAccountInfo != nil {
+		doc.BankName = sale.Payment.OurAccountInfo.BankName
+		doc.AccountName = sale.Payment.OurAccountInfo.Name
+		doc.AccountNumber = sale.Payment.OurAccountInfo.AccountNumber
+	}
+
+	for _, item := range sale.Items {
+		doc.Lines = append(doc.Lines, newInvoiceLine(item.ProductName, item.ProductCode, item.Quantity, item.UnitPrice, item.TotalPrice, sale.IsVAT, doc.VATRate))
+		doc.Subtotal += item.TotalPrice
+	}
+	if sale.IsVAT {
+		doc.VAT = doc.Subtotal * doc.VATRate
+	}
+	doc.GrandTotal = doc.Subtotal + doc.VAT + doc.ShippingCost
+
+	return s.render(doc)
+}
+
+// GeneratePurchasePDF renders a purchase as a Thai purchase document PDF
+func (s *PDFService) GeneratePurchasePDF(purchase *models.Purchase) ([]byte, error) {
+	doc := invoiceDocument{
+		Title:         "ใบสั่งซื้อ / Purchase",
+		Code:          purchase.PurchaseCode,
+		Date:          purchase.PurchaseDate,
+		PartyLabel:    "ผู้ขาย",
+		PartyName:     purchase.CustomerName,
+		PartyTaxID:    derefString(purchase.TaxID),
+		PartyAddress:  derefString(purchase.Address),
+		PartyPhone:    derefString(purchase.Phone),
+		Subtotal:      purchase.TotalAmount,
+		DiscountTotal: purchase.DiscountTotal,
+		VATRate:       purchase.EffectiveVATRate(),
+		VAT:           purchase.TotalVAT,
+		ShippingCost:  purchase.ShippingCost,
+		Notes:         derefString(purchase.Notes),
+	}
+	if purchase.SupplierName != nil {
+		doc.PartyName = *purchase.SupplierName
+	}
+	if purchase.Payment.OurAccountInfo != nil {
+		doc.BankName = purchase.Payment.OurAccountInfo.BankName
+		doc.AccountName = purchase.Payment.OurAccountInfo.Name
+		doc.AccountNumber = purchase.Payment.OurAccountInfo.AccountNumber
+	}
+
+	for _, item := range purchase.Items {
+		doc.Lines = append(doc.Lines, newInvoiceLine(item.ProductName, item.ProductCode, float64(item.Quantity), item.UnitPrice, item.TotalPrice, purchase.IsVAT, doc.VATRate))
+	}
+	doc.GrandTotal = doc.Subtotal + doc.VAT + doc.ShippingCost
+
+	return s.render(doc)
+}
+
+// render lays out the document on an A4 page
+func (s *PDFService) render(doc invoiceDocument) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+
+	family := s.registerFonts(pdf)
+	company := s.loadCompanyInfo()
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(family, "", 10)
+		pdf.CellFormat(0, 8, fmt.Sprintf("%s - หน้า %d", company.Name, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Company header
+	pdf.SetFont(family, "B", 18)
+	pdf.CellFormat(110, 8, company.Name, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, doc.Title, "", 1, "R", false, 0, "")
+	pdf.SetFont(family, "", 12)
+	if company.Address != "" {
+		pdf.CellFormat(110, 6, company.Address, "", 1, "L", false, 0, "")
+	}
+	if company.TaxID != "" {
+		pdf.CellFormat(110, 6, "เลขประจำตัวผู้เสียภาษี "+company.TaxID, "", 1, "L", false, 0, "")
+	}
+	if company.Phone != "" {
+		pdf.CellFormat(110, 6, "โทร "+company.Phone, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Document and party details
+	pdf.SetFont(family, "B", 12)
+	pdf.CellFormat(110, 6, doc.PartyLabel+": "+doc.PartyName, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "เลขที่: "+doc.Code, "", 1, "R", false, 0, "")
+	pdf.SetFont(family, "", 12)
+	pdf.CellFormat(110, 6, "เลขประจำตัวผู้เสียภาษี: "+doc.PartyTaxID, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "วันที่: "+FormatThaiDate(doc.Date), "", 1, "R", false, 0, "")
+	if doc.PartyAddress != "" {
+		pdf.MultiCell(110, 6, "ที่อยู่: "+doc.PartyAddress, "", "L", false)
+	}
+	if doc.PartyPhone != "" {
+		pdf.CellFormat(110, 6, "โทร: "+doc.PartyPhone, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Items table
+	widths := []float64{10, 60, 30, 18, 25, 17, 20}
+	headers := []string{"#", "สินค้า", "รหัส", "จำนวน", "ราคา/หน่วย", "VAT", "รวม"}
+	pdf.SetFont(family, "B", 12)
+	pdf.SetFillColor(230, 230, 230)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(family, "", 12)
+	for i, line := range doc.Lines {
+		pdf.CellFormat(widths[0], 7, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(widths[1], 7, line.Name, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 7, line.Code, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 7, strconv.FormatFloat(line.Quantity, 'f', -1, 64), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 7, formatAmount(line.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 7, formatAmount(line.VAT), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[6], 7, formatAmount(line.Total), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Grand total box
+	tableTop := pdf.GetY()
+	totals := [][2]string{
+		{"รวมเป็นเงิน", formatAmount(doc.Subtotal)},
+		{"ส่วนลด", formatAmount(doc.DiscountTotal)},
+		{"ภาษีมูลค่าเพิ่ม " + formatPercent(doc.VATRate), formatAmount(doc.VAT)},
+		{"ค่าขนส่ง", formatAmount(doc.ShippingCost)},
+	}
+	for _, total := range totals {
+		pdf.SetX(125)
+		pdf.CellFormat(40, 7, total[0], "1", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, total[1], "1", 1, "R", false, 0, "")
+	}
+	pdf.SetX(125)
+	pdf.SetFont(family, "B", 13)
+	pdf.CellFormat(40, 8, "ยอดรวมทั้งสิ้น", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(0, 8, formatAmount(doc.GrandTotal), "1", 1, "R", true, 0, "")
+
+	// Payment info with bank account QR
+	if doc.AccountNumber != "" {
+		pdf.SetY(tableTop)
+		pdf.SetFont(family, "B", 12)
+		pdf.CellFormat(100, 6, "ข้อมูลการชำระเงิน", "", 1, "L", false, 0, "")
+		pdf.SetFont(family, "", 12)
+		pdf.CellFormat(100, 6, doc.BankName, "", 1, "L", false, 0, "")
+		pdf.CellFormat(100, 6, "ชื่อบัญชี: "+doc.AccountName, "", 1, "L", false, 0, "")
+		pdf.CellFormat(100, 6, "เลขที่บัญชี: "+doc.AccountNumber, "", 1, "L", false, 0, "")
+
+		if png, err := qrcode.Encode(doc.AccountNumber, qrcode.Medium, 256); err == nil {
+			options := fpdf.ImageOptions{ImageType: "PNG"}
+			pdf.RegisterImageOptionsReader("bank-qr", options, bytes.NewReader(png))
+			pdf.ImageOptions("bank-qr", 15, pdf.GetY()+2, 30, 30, false, options, 0, "")
+		}
+	}
+
+	if doc.Notes != "" {
+		pdf.SetY(pdf.GetY() + 40)
+		pdf.SetFont(family, "", 12)
+		pdf.MultiCell(0, 6, "หมายเหตุ: "+doc.Notes, "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// registerFonts registers the Thai font if available and returns the font family to use
+func (s *PDFService) registerFonts(pdf *fpdf.Fpdf) string {
+	regular := filepath.Join(s.fontDir, pdfFontRegular)
+	if _, err := os.Stat(regular); err != nil {
+		log.Printf("Warning: Thai font %s not found, Thai text will not render correctly", regular)
+		return "Helvetica"
+	}
+
+	pdf.AddUTF8Font(pdfFontFamily, "", regular)
+	bold := filepath.Join(s.fontDir, pdfFontBold)
+	if _, err := os.Stat(bold); err == nil {
+		pdf.AddUTF8Font(pdfFontFamily, "B", bold)
+	} else {
+		pdf.AddUTF8Font(pdfFontFamily, "B", regular)
+	}
+	return pdfFontFamily
+}
+
+// loadCompanyInfo loads the company header from config/company.json
+func (s *PDFService) loadCompanyInfo() CompanyInfo {
+	company := CompanyInfo{Name: "GoodPack"}
+
+	data, err := os.ReadFile(filepath.Join("config", "company.json"))
+	if err != nil {
+		return company
+	}
+	if err := json.Unmarshal(data, &company); err != nil {
+		log.Printf("Warning: Failed to parse company config: %v", err)
+	}
+	return company
+}
+
+// newInvoiceLine builds a table row, calculating the line VAT at vatRate when applicable
+func newInvoiceLine(name, code string, quantity float64, unitPrice, total float64, isVAT bool, vatRate float64) invoiceLine {
+	line := invoiceLine{
+		Name:      name,
+		Code:      code,
+		Quantity:  quantity,
+		UnitPrice: unitPrice,
+		Total:     total,
+	}
+	if isVAT {
+		line.VAT = total * vatRate
+	}
+	return line
+}
+
+// FormatThaiDate formats a date in the Buddhist era, e.g. "5 ม.ค. 2568"
+func FormatThaiDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), thaiShortMonths[t.Month()-1], t.Year()+543)
+}
+
+// formatAmount formats a money amount with thousands separators and two decimals
+func formatAmount(amount float64) string {
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+
+	str := fmt.Sprintf("%.2f", amount)
+	intPart, decPart := str[:len(str)-3], str[len(str)-3:]
+
+	var result []byte
+	for i, digit := range []byte(intPart) {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			result = append(result, ',')
+		}
+		result = append(result, digit)
+	}
+
+	if negative {
+		return "-" + string(result) + decPart
+	}
+	return string(result) + decPart
+}
+
+// formatPercent formats a rate as a percentage without trailing zeros, e.g. 0.07 as "7%"
+func formatPercent(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*10000)/100, 'f', -1, 64) + "%"
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
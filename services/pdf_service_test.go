@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// pdfStream matches the content streams of a PDF
+var pdfStream = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+
+// pdfText returns the decompressed content streams of a PDF, where the text drawn on its pages is
+func pdfText(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var text bytes.Buffer
+	for _, match := range pdfStream.FindAllSubmatch(pdf, -1) {
+		reader, err := zlib.NewReader(bytes.NewReader(match[1]))
+		if err != nil {
+			text.Write(match[1]) // not compressed
+			continue
+		}
+		io.Copy(&text, reader)
+		reader.Close()
+	}
+	return text.String()
+}
+
+// checkPDF fails unless pdf is a complete PDF document that draws want
+func checkPDF(t *testing.T, pdf []byte, want string) {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf[len(pdf)-32:], []byte("%%EOF")) {
+		t.Fatalf("output is not a complete PDF: starts %q", pdf[:min(len(pdf), 16)])
+	}
+	if text := pdfText(t, pdf); !bytes.Contains([]byte(text), []byte(want)) {
+		t.Errorf("PDF does not contain %q", want)
+	}
+}
+
+func TestGenerateSalePDF(t *testing.T) {
+	sale := &models.Sale{
+		SaleCode:     "SA-6701-0042",
+		SaleDate:     time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+		CustomerName: "Goodpack Co.",
+		IsVAT:        true,
+		Items: []models.SaleItem{
+			{ProductName: "Kraft Box", ProductCode: "BOX-S-BRN", Quantity: 3, UnitPrice: 100, TotalPrice: 300},
+		},
+	}
+
+	pdf, err := NewPDFService().GenerateSalePDF(sale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, pdf, "SA-6701-0042")
+}
+
+func TestGeneratePurchasePDF(t *testing.T) {
+	purchase := &models.Purchase{
+		PurchaseCode: "PUR-VAT-6701-0001",
+		PurchaseDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Items:        []models.PurchaseItem{{ProductName: "Kraft Box", Quantity: 10, UnitPrice: 50, TotalPrice: 500}},
+		TotalAmount:  500,
+	}
+
+	pdf, err := NewPDFService().GeneratePurchasePDF(purchase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, pdf, "PUR-VAT-6701-0001")
+}
+
+func TestFormatThaiDate(t *testing.T) {
+	if got := FormatThaiDate(time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)); got != "5 ม.ค. 2568" {
+		t.Errorf("FormatThaiDate = %q, want the Buddhist era date 5 ม.ค. 2568", got)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := map[float64]string{
+		0:          "0.00",
+		999.5:      "999.50",
+		1234567.89: "1,234,567.89",
+		-1500:      "-1,500.00",
+	}
+	for amount, want := range tests {
+		if got := formatAmount(amount); got != want {
+			t.Errorf("formatAmount(%v) = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestGenerateCatalogPDF(t *testing.T) {
+	products := []*models.Product{
+		{Name: "Kraft Box", SKUID: "BOX-0001", Category: "Box", Description: "Brown kraft shipping box"},
+		{Name: "Bubble Wrap", SKUID: "WRP-0001", Category: "Wrap"},
+		{Name: "Mailer Box", SKUID: "BOX-0002", Category: "Box"},
+	}
+	products[0].Price.SaleVAT.Latest = 25
+	products[0].Stock.ActualStock = 120
+
+	pdf, err := NewPDFService().GenerateCatalogPDF(products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, pdf, "BOX-0002")
+	if text := pdfText(t, pdf); !bytes.Contains([]byte(text), []byte("WRP-0001")) {
+		t.Error("catalog PDF does not contain WRP-0001")
+	}
+}
+
+func TestGenerateCatalogPDFWithoutProducts(t *testing.T) {
+	pdf, err := NewPDFService().GenerateCatalogPDF(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF")) {
+		t.Errorf("output starts %q, want %%PDF", pdf[:min(len(pdf), 16)])
+	}
+}
+
+func TestSortCatalogProducts(t *testing.T) {
+	products := []*models.Product{
+		{Name: "Mailer Box", Category: "Box"},
+		{Name: "Bubble Wrap", Category: "Wrap"},
+		{Name: "Kraft Box", Category: "Box"},
+		{Name: "Label", Category: ""},
+	}
+	SortCatalogProducts(products)
+
+	want := []string{"Label", "Kraft Box", "Mailer Box", "Bubble Wrap"}
+	for i, product := range products {
+		if product.Name != want[i] {
+			t.Errorf("product %d = %s, want %s", i, product.Name, want[i])
+		}
+	}
+}
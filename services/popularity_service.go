@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// PopularityService scores products by how often they are viewed and sold
+type PopularityService struct {
+	productRepo         *repository.ProductRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+}
+
+func NewPopularityService(productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) *PopularityService {
+	return &PopularityService{
+		productRepo:         productRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+	}
+}
+
+// Rescore counts the sales of every product in the PopularityWindowDays days before now and stores its sales
+// count and popularity score (see models.ScorePopularity)
+func (s *PopularityService) Rescore(ctx context.Context, now time.Time) (*models.PopularityRescoreResult, error) {
+	viewCounts, err := s.productRepo.GetViewCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	saleCounts, err := s.stockAdjustmentRepo.GetSaleCountsByProduct(ctx, now.AddDate(0, 0, -models.PopularityWindowDays))
+	if err != nil {
+		return nil, err
+	}
+
+	popularity := models.ScorePopularity(viewCounts, saleCounts)
+	changed, err := s.productRepo.SetPopularity(ctx, popularity)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PopularityRescoreResult{
+		Scored:  len(popularity),
+		Changed: changed,
+	}, nil
+}
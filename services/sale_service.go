@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/apierrors"
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// ProductNotFoundError is returned when a sale item references a product that does not exist
+type ProductNotFoundError struct {
+	ProductID string
+}
+
+func (e *ProductNotFoundError) Error() string {
+	return fmt.Sprintf("product not found: %s", e.ProductID)
+}
+
+// BundleNotFoundError is returned when a sale adds a bundle that does not exist
+type BundleNotFoundError struct {
+	BundleID string
+}
+
+func (e *BundleNotFoundError) Error() string {
+	return fmt.Sprintf("bundle not found: %s", e.BundleID)
+}
+
+// SerialNumberError is returned when the serial numbers of a serialised product are missing or not in stock
+type SerialNumberError struct {
+	ProductID string
+	Reason    string
+}
+
+func (e *SerialNumberError) Error() string {
+	return fmt.Sprintf("invalid serial numbers for product %s: %s", e.ProductID, e.Reason)
+}
+
+// ErrNoSaleItems is returned when a sale has neither items nor bundles
+var ErrNoSaleItems = errors.New("sale has no items")
+
+// ErrSaleNotDraft is returned when confirming a sale that is not a draft
+var ErrSaleNotDraft = errors.New("sale is not a draft")
+
+// CreditLimitExceededError is returned when a sale would take a customer over their credit limit
+type CreditLimitExceededError struct {
+	CustomerID         string
+	CreditLimit        float64
+	OutstandingBalance float64
+	SaleTotal          float64
+}
+
+func (e *CreditLimitExceededError) Error() string {
+	return fmt.Sprintf("credit limit exceeded for customer %s: outstanding %.2f + sale %.2f > limit %.2f",
+		e.CustomerID, e.OutstandingBalance, e.SaleTotal, e.CreditLimit)
+}
+
+// SaleService holds the sale creation logic shared by the sale and quotation handlers
+type SaleService struct {
+	saleRepo            *repository.SaleRepository
+	productRepo         *repository.ProductRepository
+	customerRepo        *repository.CustomerRepository
+	quotationRepo       *repository.QuotationRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+	bundleRepo          *repository.BundleRepository
+	serialNumberRepo    *repository.SerialNumberRepository
+	lotRepo             *repository.LotRepository
+	returnRepo          *repository.SaleReturnRepository
+}
+
+func NewSaleService(saleRepo *repository.SaleRepository, productRepo *repository.ProductRepository, customerRepo *repository.CustomerRepository, quotationRepo *repository.QuotationRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository, bundleRepo *repository.BundleRepository, serialNumberRepo *repository.SerialNumberRepository, lotRepo *repository.LotRepository, returnRepo *repository.SaleReturnRepository) *SaleService {
+	return &SaleService{
+		saleRepo:            saleRepo,
+		productRepo:         productRepo,
+		customerRepo:        customerRepo,
+		quotationRepo:       quotationRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+		bundleRepo:          bundleRepo,
+		serialNumberRepo:    serialNumberRepo,
+		lotRepo:             lotRepo,
+		returnRepo:          returnRepo,
+	}
+}
+
+// CreateSale creates a sale, cuts stock for each item and links the originating quotation
+func (s *SaleService) CreateSale(ctx context.Context, saleReq *models.SaleRequest) (*models.Sale, error) {
+	sale, err := s.createSale(ctx, saleReq, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update quotation with sale code if quotationCode is provided
+	if sale.QuotationCode != nil && *sale.QuotationCode != "" {
+		if err := s.linkQuotation(ctx, *sale.QuotationCode, sale.SaleCode); err != nil {
+			// Log error but don't fail the sale creation
+			fmt.Printf("Warning: Failed to update quotation %s with sale code %s: %v\n", *sale.QuotationCode, sale.SaleCode, err)
+		}
+	}
+
+	return sale, nil
+}
+
+// AcceptQuotation creates the sale of a quotation and marks the quotation accepted with the sale's code in the
+// same transaction, so a quotation is converted once and its sale is never left unlinked. A quotation that
+// already has a sale, or was rejected or expired, fails with repository.ErrQuotationNotAcceptable, and one
+// changed since it was read with an error wrapping apierrors.ErrConflict.
+func (s *SaleService) AcceptQuotation(ctx context.Context, quotation *models.Quotation, changedBy *string) (*models.Sale, error) {
+	version := quotation.Version
+	return s.createSale(ctx, quotation.ToSaleRequest(), func(txCtx context.Context, sale *models.Sale) error {
+		quotation.Version = version // a retried transaction saves the quotation again from the version it was read at
+		return s.quotationRepo.Accept(txCtx, quotation, sale.SaleCode, changedBy)
+	})
+}
+
+// createSale creates a sale and cuts stock for each item. The stock cuts and the sale are saved in one
+// transaction, so a failure part way leaves no stock cut without its sale; claim, when not nil, runs first
+// in the same transaction and its error aborts it.
+func (s *SaleService) createSale(ctx context.Context, saleReq *models.SaleRequest, claim func(ctx context.Context, sale *models.Sale) error) (*models.Sale, error) {
+	if err := s.ExpandBundles(ctx, saleReq); err != nil {
+		return nil, err
+	}
+
+	saleCode, err := GenerateSaleCode(ctx, s.saleRepo, saleReq.IsVAT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sale code: %w", err)
+	}
+
+	sale := saleReq.ToSale()
+	sale.ID = primitive.NewObjectID()
+	sale.SaleCode = saleCode
+
+	if err := s.prepareItems(ctx, sale, ""); err != nil {
+		return nil, err
+	}
+
+	err = s.saleRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		if claim != nil {
+			if err := claim(txCtx, sale); err != nil {
+				return err
+			}
+		}
+		if err := s.cutStock(txCtx, sale); err != nil {
+			return err
+		}
+		if err := s.saleRepo.Create(txCtx, sale); err != nil {
+			return fmt.Errorf("failed to create sale: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.SellSerialNumbers(ctx, sale)
+
+	s.RefreshOutstandingBalance(ctx, sale.CustomerID)
+	return sale, nil
+}
+
+// DuplicateSale copies a sale into a new draft sale with its own code, dated now and unpaid, for a customer
+// who reorders the same items. No stock is cut until the draft is confirmed.
+func (s *SaleService) DuplicateSale(ctx context.Context, original *models.Sale) (*models.Sale, error) {
+	saleCode, err := GenerateSaleCode(ctx, s.saleRepo, original.IsVAT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sale code: %w", err)
+	}
+
+	sale := original.Duplicate(saleCode)
+	if err := s.saleRepo.Create(ctx, sale); err != nil {
+		return nil, fmt.Errorf("failed to create sale: %w", err)
+	}
+	return sale, nil
+}
+
+// ConfirmSale turns a draft sale into a normal one: it runs the checks of CreateSale, cuts stock for each item
+// and marks the serial numbers sold
+func (s *SaleService) ConfirmSale(ctx context.Context, sale *models.Sale, changedBy *string) error {
+	if !sale.IsDraft {
+		return ErrSaleNotDraft
+	}
+	return s.UpdateSaleStatus(ctx, sale, models.OrderStatusConfirmed, changedBy, nil)
+}
+
+// UpdateSaleStatus moves a sale to status and records the change in its status history. Confirming a draft
+// cuts stock as ConfirmSale does; cancelling a sale that has cut stock puts it back and releases its serial
+// numbers and lots.
+func (s *SaleService) UpdateSaleStatus(ctx context.Context, sale *models.Sale, status string, changedBy, notes *string) error {
+	confirmDraft := status == models.OrderStatusConfirmed && sale.IsDraft
+	returnStock := status == models.OrderStatusCancelled && sale.HoldsStock()
+	if err := sale.TransitionStatus(status, changedBy, notes); err != nil {
+		return err
+	}
+
+	if confirmDraft {
+		if err := s.prepareItems(ctx, sale, sale.ID.Hex()); err != nil {
+			return err
+		}
+	}
+
+	// Cut or restore the stock and save the sale in one transaction, so neither is left without the other
+	version := sale.Version
+	err := s.saleRepo.WithTransaction(ctx, func(txCtx context.Context) error {
+		sale.Version = version // a retried transaction saves the sale again from the version it was read at
+		if confirmDraft {
+			if err := s.cutStock(txCtx, sale); err != nil {
+				return err
+			}
+			sale.IsDraft = false
+		}
+		if returnStock {
+			if err := s.RestoreStock(txCtx, sale); err != nil {
+				return err
+			}
+		}
+
+		if err := s.saleRepo.Update(txCtx, sale.ID.Hex(), sale); err != nil {
+			return fmt.Errorf("failed to update sale status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if confirmDraft {
+		s.SellSerialNumbers(ctx, sale)
+	}
+	if returnStock {
+		s.ReleaseSerialNumbers(ctx, sale)
+		s.ReleaseLots(ctx, sale)
+	}
+	s.RefreshOutstandingBalance(ctx, sale.CustomerID)
+	return nil
+}
+
+// RestoreStock puts back the stock a sale's items still hold: what was cut for them less what has been returned.
+// Products deleted since the sale are skipped.
+func (s *SaleService) RestoreStock(ctx context.Context, sale *models.Sale) error {
+	returns, err := s.returnRepo.GetBySaleID(ctx, sale.ID.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to get returns of sale %s: %w", sale.SaleCode, err)
+	}
+	quantities := sale.UnreturnedQuantities(models.ReturnedQuantities(returns))
+
+	stockType := StockTypeForVAT(sale.IsVAT)
+	for i, item := range sale.Items {
+		if quantities[i] == 0 {
+			continue
+		}
+		product, err := s.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			// Restore stock by adding back (reverse the reduce operation)
+			ApplyStockAdjustment(product, models.AdjustmentTypeAdd, stockType, quantities[i])
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore stock of product %s for sale %s: %w", item.ProductID, sale.SaleCode, err)
+		}
+		RefreshInventoryLevel(ctx, s.productRepo, product.Category)
+	}
+	return nil
+}
+
+// prepareItems checks that every product of a sale exists, with its serial numbers when serialised (saleID is
+// the sale being confirmed, "" for a new one), fills in the display units and, for an unpaid sale, checks the
+// customer's credit limit
+func (s *SaleService) prepareItems(ctx context.Context, sale *models.Sale, saleID string) error {
+	// Check all products exist before touching any stock
+	for i := range sale.Items {
+		product, err := s.productRepo.GetByID(ctx, sale.Items[i].ProductID)
+		if err != nil {
+			return &ProductNotFoundError{ProductID: sale.Items[i].ProductID}
+		}
+		sale.Items[i].ApplyUOM(product)
+		if err := s.checkSerialNumbers(ctx, product, &sale.Items[i], saleID); err != nil {
+			return err
+		}
+	}
+
+	if !sale.Payment.IsPaid {
+		if err := s.checkCreditLimit(ctx, sale.CustomerID, sale.CalculateGrandTotal()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cutStock reduces stock for each item of a sale, takes it from the lots of lot-tracked products, records the
+// stock history and updates the products' latest sale price
+func (s *SaleService) cutStock(ctx context.Context, sale *models.Sale) error {
+	// Cut stock for each item
+	stockType := StockTypeForVAT(sale.IsVAT)
+	saleID := sale.ID.Hex()
+	notes := fmt.Sprintf("ขายจากรายการ %s", sale.SaleCode)
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		// Re-read the product so repeated items see the previous stock cut
+		product, err := s.productRepo.Modify(ctx, item.ProductID, func(product *models.Product) error {
+			// Update sale price; bundle components sell at a share of the bundle price, not their own price
+			if item.BundleID == "" {
+				product.UpdatePrice(item.UnitPrice, sale.IsVAT, false) // false = isSale
+			}
+
+			ApplyStockAdjustment(product, models.AdjustmentTypeReduce, stockType, item.StockQuantity())
+			return nil
+		})
+		if errors.Is(err, apierrors.ErrNotFound) {
+			return &ProductNotFoundError{ProductID: item.ProductID}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update product stock %s: %w", item.ProductID, err)
+		}
+		RefreshInventoryLevel(ctx, s.productRepo, product.Category)
+		s.AllocateLots(ctx, product, item, sale.SaleCode)
+
+		if err := RecordStockChange(
+			ctx,
+			s.stockAdjustmentRepo,
+			product,
+			models.SourceTypeSale,
+			&saleID,
+			&sale.SaleCode,
+			models.AdjustmentTypeReduce,
+			stockType,
+			item.StockQuantity(),
+			&notes,
+		); err != nil {
+			// Log error but don't fail the sale
+			fmt.Printf("Warning: Failed to record stock change history: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// ExpandBundles replaces the bundles of a sale request with a sale item per component, priced at the
+// component's share of the bundle price (see models.ProductBundle.Expand). Shares follow the latest sale price
+// of each component for the sale's VAT type. Stock is then cut per component like any other item.
+func (s *SaleService) ExpandBundles(ctx context.Context, saleReq *models.SaleRequest) error {
+	for _, bundleItem := range saleReq.Bundles {
+		bundle, err := s.bundleRepo.GetByID(ctx, bundleItem.BundleID)
+		if err != nil {
+			return &BundleNotFoundError{BundleID: bundleItem.BundleID}
+		}
+
+		listPrices := make(map[string]float64, len(bundle.Components))
+		for _, component := range bundle.Components {
+			product, err := s.productRepo.GetByID(ctx, component.ProductID)
+			if err != nil {
+				return &ProductNotFoundError{ProductID: component.ProductID}
+			}
+			if saleReq.IsVAT {
+				listPrices[component.ProductID] = product.Price.SaleVAT.Latest
+			} else {
+				listPrices[component.ProductID] = product.Price.SaleNonVAT.Latest
+			}
+		}
+
+		saleReq.Items = append(saleReq.Items, bundle.Expand(bundleItem.Quantity, listPrices)...)
+	}
+	saleReq.Bundles = nil
+
+	if len(saleReq.Items) == 0 {
+		return ErrNoSaleItems
+	}
+	return nil
+}
+
+// CheckSerialNumbers checks the serial numbers of every serialised product on the items: there must be one
+// per unit, each in stock or already sold by the sale being updated (saleID, "" for a new sale).
+// Items of other products must not carry serial numbers.
+func (s *SaleService) CheckSerialNumbers(ctx context.Context, items []models.SaleItem, saleID string) error {
+	for i := range items {
+		product, err := s.productRepo.GetByID(ctx, items[i].ProductID)
+		if err != nil {
+			return &ProductNotFoundError{ProductID: items[i].ProductID}
+		}
+		if err := s.checkSerialNumbers(ctx, product, &items[i], saleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SaleService) checkSerialNumbers(ctx context.Context, product *models.Product, item *models.SaleItem, saleID string) error {
+	if !product.IsSerialised {
+		if len(item.SerialNumbers) > 0 {
+			return &SerialNumberError{ProductID: item.ProductID, Reason: "the product is not serialised"}
+		}
+		return nil
+	}
+	if reason := models.CheckSerialNumbers(item.SerialNumbers, item.StockQuantity()); reason != "" {
+		return &SerialNumberError{ProductID: item.ProductID, Reason: reason}
+	}
+
+	units, err := s.serialNumberRepo.GetBySerials(ctx, item.ProductID, item.SerialNumbers)
+	if err != nil {
+		return fmt.Errorf("failed to load serial numbers: %w", err)
+	}
+	for _, serial := range item.SerialNumbers {
+		unit, ok := units[serial]
+		if !ok {
+			return &SerialNumberError{ProductID: item.ProductID, Reason: fmt.Sprintf("serial number %s was never received", serial)}
+		}
+		soldBySale := saleID != "" && unit.Status == models.SerialNumberStatusSold && unit.SaleID != nil && *unit.SaleID == saleID
+		if !unit.IsInStock() && !soldBySale {
+			return &SerialNumberError{ProductID: item.ProductID, Reason: fmt.Sprintf("serial number %s is %s", serial, unit.Status)}
+		}
+	}
+	return nil
+}
+
+// SellSerialNumbers marks the serial numbers on a sale's items as sold by it
+func (s *SaleService) SellSerialNumbers(ctx context.Context, sale *models.Sale) {
+	saleID := sale.ID.Hex()
+	for _, item := range sale.Items {
+		if len(item.SerialNumbers) == 0 {
+			continue
+		}
+		if err := s.serialNumberRepo.MarkSold(ctx, item.ProductID, item.SerialNumbers, saleID); err != nil {
+			fmt.Printf("Warning: Failed to mark serial numbers of product %s sold by %s: %v\n", item.ProductID, sale.SaleCode, err)
+		}
+	}
+}
+
+// ReleaseSerialNumbers puts the units sold by a sale back in stock
+func (s *SaleService) ReleaseSerialNumbers(ctx context.Context, sale *models.Sale) {
+	if err := s.serialNumberRepo.ReleaseSale(ctx, sale.ID.Hex()); err != nil {
+		fmt.Printf("Warning: Failed to release serial numbers of sale %s: %v\n", sale.SaleCode, err)
+	}
+}
+
+// AllocateLots takes the quantity of a sale item of a lot-tracked product from its lots, earliest expiry first,
+// and records the lots on the item. Lots not covering the quantity do not stop the sale; the rest is logged.
+func (s *SaleService) AllocateLots(ctx context.Context, product *models.Product, item *models.SaleItem, saleCode string) {
+	item.Lots = nil
+	if !product.LotTracking {
+		return
+	}
+
+	allocations, err := s.lotRepo.AllocateForSale(ctx, item.ProductID, item.StockQuantity())
+	if err != nil {
+		fmt.Printf("Warning: Failed to allocate lots of product %s for sale %s: %v\n", item.ProductID, saleCode, err)
+	}
+	item.Lots = allocations
+}
+
+// ReleaseLots puts the quantities a sale took from lots back into them
+func (s *SaleService) ReleaseLots(ctx context.Context, sale *models.Sale) {
+	for _, item := range sale.Items {
+		if err := s.lotRepo.Release(ctx, item.Lots); err != nil {
+			fmt.Printf("Warning: Failed to release lots of product %s for sale %s: %v\n", item.ProductID, sale.SaleCode, err)
+		}
+	}
+}
+
+// checkCreditLimit rejects an unpaid sale that would take the customer's unpaid total over their credit limit.
+// A credit limit of 0 means no limit.
+func (s *SaleService) checkCreditLimit(ctx context.Context, customerID string, saleTotal float64) error {
+	customer, err := s.customerRepo.GetByID(ctx, customerID)
+	if err != nil || customer.CreditLimit <= 0 {
+		return nil
+	}
+
+	outstanding, err := s.saleRepo.GetTotalUnpaidByCustomer(ctx, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to get outstanding balance: %w", err)
+	}
+	if outstanding+saleTotal > customer.CreditLimit {
+		return &CreditLimitExceededError{
+			CustomerID:         customerID,
+			CreditLimit:        customer.CreditLimit,
+			OutstandingBalance: outstanding,
+			SaleTotal:          saleTotal,
+		}
+	}
+	return nil
+}
+
+// RefreshOutstandingBalance recomputes a customer's unpaid sales total and stores it on the customer
+func (s *SaleService) RefreshOutstandingBalance(ctx context.Context, customerID string) {
+	if customerID == "" {
+		return
+	}
+
+	outstanding, err := s.saleRepo.GetTotalUnpaidByCustomer(ctx, customerID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to compute outstanding balance for customer %s: %v\n", customerID, err)
+		return
+	}
+	if err := s.customerRepo.SetDerived(ctx, customerID, bson.M{"outstandingBalance": outstanding}); err != nil {
+		fmt.Printf("Warning: Failed to update outstanding balance for customer %s: %v\n", customerID, err)
+	}
+}
+
+// linkQuotation updates a quotation with the sale code created from it
+func (s *SaleService) linkQuotation(ctx context.Context, quotationCode, saleCode string) error {
+	quotation, err := s.quotationRepo.GetByCode(ctx, quotationCode)
+	if err != nil {
+		return fmt.Errorf("quotation not found: %w", err)
+	}
+
+	quotation.SaleCode = &saleCode
+	quotation.UpdatedAt = time.Now()
+
+	return s.quotationRepo.Update(ctx, quotation.ID.Hex(), quotation, nil)
+}
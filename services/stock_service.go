@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// ApplyStockAdjustment applies stock adjustment to a product (core logic)
+// Allows negative stock values to indicate abnormal stock status
+func ApplyStockAdjustment(
+	product *models.Product,
+	adjustmentType models.StockAdjustmentType,
+	stockType models.StockType,
+	quantity int,
+) {
+	if stockType == models.StockTypeActualStock {
+		// For ActualStock, just add or subtract directly
+		// Allow negative values to show abnormal stock status
+		if adjustmentType == models.AdjustmentTypeAdd {
+			product.Stock.ActualStock += quantity
+		} else {
+			product.Stock.ActualStock -= quantity
+			// Allow negative stock to indicate abnormal status
+		}
+	} else {
+		// For VAT or NonVAT
+		var stockInfo *models.StockInfo
+		if stockType == models.StockTypeVAT {
+			stockInfo = &product.Stock.VAT
+		} else {
+			stockInfo = &product.Stock.NonVAT
+		}
+
+		if adjustmentType == models.AdjustmentTypeAdd {
+			// เพิ่ม: บวกใน Purchased และ Remaining
+			stockInfo.Purchased += quantity
+			stockInfo.Remaining += quantity
+			// Also update ActualStock
+			product.Stock.ActualStock += quantity
+		} else {
+			// ลด: บวกใน Sold และลด Remaining
+			stockInfo.Sold += quantity
+			stockInfo.Remaining -= quantity
+			// Allow negative remaining to indicate abnormal status
+			// Also update ActualStock
+			product.Stock.ActualStock -= quantity
+			// Allow negative ActualStock to indicate abnormal status
+		}
+	}
+}
+
+// TransferStock moves quantity from one of the VAT / Non-VAT buckets to the other by reducing the first and
+// adding to the second, so actual stock ends where it started
+func TransferStock(product *models.Product, from, to models.StockType, quantity int) {
+	ApplyStockAdjustment(product, models.AdjustmentTypeReduce, from, quantity)
+	ApplyStockAdjustment(product, models.AdjustmentTypeAdd, to, quantity)
+}
+
+// RecordStockChange records a stock change in history
+func RecordStockChange(
+	ctx context.Context,
+	adjustmentRepo *repository.StockAdjustmentRepository,
+	product *models.Product,
+	sourceType models.SourceType,
+	sourceID, sourceCode *string,
+	adjustmentType models.StockAdjustmentType,
+	stockType models.StockType,
+	quantity int,
+	notes *string,
+) error {
+	adjustment := &models.StockAdjustment{
+		ProductID:      product.ID.Hex(),
+		ProductName:    product.Name,
+		SKUID:          product.SKUID,
+		AdjustmentType: adjustmentType,
+		StockType:      stockType,
+		Quantity:       quantity,
+		SourceType:     sourceType,
+		SourceID:       sourceID,
+		SourceCode:     sourceCode,
+		Notes:          notes,
+		CreatedAt:      time.Now(),
+	}
+
+	// Store before values
+	adjustment.BeforeVATPurchased = product.Stock.VAT.Purchased
+	adjustment.BeforeVATSold = product.Stock.VAT.Sold
+	adjustment.BeforeVATRemaining = product.Stock.VAT.Remaining
+	adjustment.BeforeNonVATPurchased = product.Stock.NonVAT.Purchased
+	adjustment.BeforeNonVATSold = product.Stock.NonVAT.Sold
+	adjustment.BeforeNonVATRemaining = product.Stock.NonVAT.Remaining
+	adjustment.BeforeActualStock = product.Stock.ActualStock
+
+	// Set after values
+	adjustment.SetAfterValues(product)
+
+	// Save adjustment history
+	return adjustmentRepo.Create(ctx, adjustment)
+}
+
+// RefreshInventoryLevel updates the inventory level metric for a category after a stock change
+func RefreshInventoryLevel(ctx context.Context, productRepo *repository.ProductRepository, category string) {
+	if err := productRepo.RefreshInventoryLevel(ctx, category); err != nil {
+		fmt.Printf("Warning: Failed to refresh inventory level metric: %v\n", err)
+	}
+}
+
+// StockTypeForVAT returns the stock bucket used by VAT and non-VAT documents
+func StockTypeForVAT(isVAT bool) models.StockType {
+	if isVAT {
+		return models.StockTypeVAT
+	}
+	return models.StockTypeNonVAT
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+
+	"goodpack-server/models"
+)
+
+func TestApplyStockAdjustment(t *testing.T) {
+	product := &models.Product{}
+	product.Stock.VAT = models.StockInfo{Purchased: 10, Remaining: 10}
+	product.Stock.ActualStock = 10
+
+	ApplyStockAdjustment(product, models.AdjustmentTypeReduce, models.StockTypeVAT, 4)
+	if product.Stock.VAT.Sold != 4 || product.Stock.VAT.Remaining != 6 || product.Stock.ActualStock != 6 {
+		t.Fatalf("after selling 4: stock = %+v, want 4 sold and 6 remaining", product.Stock)
+	}
+
+	// A return puts the units back
+	ApplyStockAdjustment(product, models.AdjustmentTypeAdd, models.StockTypeVAT, 3)
+	if product.Stock.VAT.Remaining != 9 || product.Stock.ActualStock != 9 {
+		t.Errorf("after returning 3: stock = %+v, want 9 remaining", product.Stock)
+	}
+	if product.Stock.NonVAT != (models.StockInfo{}) {
+		t.Errorf("non-VAT stock changed: %+v", product.Stock.NonVAT)
+	}
+
+	ApplyStockAdjustment(product, models.AdjustmentTypeReduce, models.StockTypeActualStock, 12)
+	if product.Stock.ActualStock != -3 {
+		t.Errorf("ActualStock = %d, want -3: negative stock is kept to show the discrepancy", product.Stock.ActualStock)
+	}
+}
+
+func TestSaleFromQuotationCutsStockOfItsVATType(t *testing.T) {
+	quotation := &models.Quotation{
+		QuotationCode: "QU-6901-0007",
+		IsVAT:         false,
+		Items:         []models.QuotationItem{{ProductID: "p1", Quantity: 5, UnitPrice: 20}},
+	}
+	sale := quotation.ToSaleRequest().ToSale()
+
+	product := &models.Product{}
+	product.Stock.NonVAT = models.StockInfo{Purchased: 8, Remaining: 8}
+	product.Stock.ActualStock = 8
+
+	// As cutStock does for each item
+	for _, item := range sale.Items {
+		ApplyStockAdjustment(product, models.AdjustmentTypeReduce, StockTypeForVAT(sale.IsVAT), item.StockQuantity())
+	}
+
+	if product.Stock.NonVAT.Sold != 5 || product.Stock.NonVAT.Remaining != 3 || product.Stock.ActualStock != 3 {
+		t.Errorf("stock = %+v, want 5 non-VAT units sold and 3 remaining", product.Stock)
+	}
+	if product.Stock.VAT != (models.StockInfo{}) {
+		t.Errorf("VAT stock changed: %+v", product.Stock.VAT)
+	}
+}
+
+func TestTransferStockKeepsActualStock(t *testing.T) {
+	product := &models.Product{}
+	product.Stock.VAT = models.StockInfo{Purchased: 20, Remaining: 20}
+	product.Stock.NonVAT = models.StockInfo{Purchased: 5, Remaining: 5}
+	product.Stock.ActualStock = 25
+
+	req := models.StockTransferRequest{FromStockType: models.StockTypeVAT, ToStockType: models.StockTypeNonVAT, Quantity: 10}
+	adjustment := req.ToStockAdjustment(product)
+	TransferStock(product, req.FromStockType, req.ToStockType, req.Quantity)
+	adjustment.SetAfterValues(product)
+
+	if product.Stock.ActualStock != 25 {
+		t.Errorf("ActualStock = %d, want it unchanged at 25", product.Stock.ActualStock)
+	}
+	if product.Stock.VAT.Remaining != 10 || product.Stock.NonVAT.Remaining != 15 {
+		t.Errorf("remaining VAT %d, non-VAT %d, want 10 and 15", product.Stock.VAT.Remaining, product.Stock.NonVAT.Remaining)
+	}
+
+	// The single history record carries both sides of the transfer
+	if adjustment.SourceType != models.SourceTypeTransfer || adjustment.StockType != models.StockTypeVAT || adjustment.ToStockType != models.StockTypeNonVAT {
+		t.Errorf("adjustment %s from %s to %s, want a transfer from vat to nonvat", adjustment.SourceType, adjustment.StockType, adjustment.ToStockType)
+	}
+	if adjustment.BeforeVATRemaining != 20 || adjustment.AfterVATRemaining != 10 {
+		t.Errorf("VAT remaining recorded %d -> %d, want 20 -> 10", adjustment.BeforeVATRemaining, adjustment.AfterVATRemaining)
+	}
+	if adjustment.BeforeNonVATRemaining != 5 || adjustment.AfterNonVATRemaining != 15 {
+		t.Errorf("non-VAT remaining recorded %d -> %d, want 5 -> 15", adjustment.BeforeNonVATRemaining, adjustment.AfterNonVATRemaining)
+	}
+	if adjustment.BeforeActualStock != adjustment.AfterActualStock {
+		t.Errorf("actual stock recorded %d -> %d, want no change", adjustment.BeforeActualStock, adjustment.AfterActualStock)
+	}
+}
+
+func TestTransferStockBackRestoresRemaining(t *testing.T) {
+	product := &models.Product{}
+	product.Stock.NonVAT = models.StockInfo{Purchased: 8, Remaining: 8}
+	product.Stock.ActualStock = 8
+
+	TransferStock(product, models.StockTypeNonVAT, models.StockTypeVAT, 3)
+	TransferStock(product, models.StockTypeVAT, models.StockTypeNonVAT, 3)
+
+	if product.Stock.ActualStock != 8 || product.Stock.NonVAT.Remaining != 8 || product.Stock.VAT.Remaining != 0 {
+		t.Errorf("stock = %+v, want 8 non-VAT remaining after transferring there and back", product.Stock)
+	}
+}
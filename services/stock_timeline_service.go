@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// stockSourceLabels are the display names of stock change sources
+var stockSourceLabels = map[models.SourceType]string{
+	models.SourceTypePurchase:       "Purchase",
+	models.SourceTypeSale:           "Sale",
+	models.SourceTypeAdjustment:     "Adjustment",
+	models.SourceTypeMigration:      "Migration",
+	models.SourceTypeReturn:         "Return",
+	models.SourceTypeReconciliation: "Reconciliation",
+	models.SourceTypeStockCount:     "Stock count",
+	models.SourceTypeTransfer:       "Transfer",
+}
+
+// BuildStockTimeline replays a product's stock adjustments in chronological order and returns
+// the movements with a running actual-stock balance. The balance is always computed from the
+// earliest record; startDate/endDate (zero = unbounded) only limit which entries are returned.
+func BuildStockTimeline(adjustments []*models.StockAdjustment, startDate, endDate time.Time) []models.StockTimelineEntry {
+	sorted := make([]*models.StockAdjustment, len(adjustments))
+	copy(sorted, adjustments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	timeline := []models.StockTimelineEntry{}
+	if len(sorted) == 0 {
+		return timeline
+	}
+
+	// Opening balance: the after value of the first record is reliable for every source,
+	// so back out its own change instead of trusting its before value
+	balance := sorted[0].AfterActualStock - stockDelta(sorted[0])
+
+	for _, adjustment := range sorted {
+		delta := stockDelta(adjustment)
+		balance += delta
+
+		if !startDate.IsZero() && adjustment.CreatedAt.Before(startDate) {
+			continue
+		}
+		if !endDate.IsZero() && adjustment.CreatedAt.After(endDate) {
+			continue
+		}
+
+		action := stockSourceLabels[adjustment.SourceType]
+		if action == "" {
+			action = string(adjustment.SourceType)
+		}
+
+		timeline = append(timeline, models.StockTimelineEntry{
+			Date:           adjustment.CreatedAt,
+			Action:         action,
+			Delta:          delta,
+			RunningBalance: balance,
+			StockType:      adjustment.StockType,
+			SourceCode:     adjustment.SourceCode,
+			Notes:          adjustment.Notes,
+			Description:    describeStockMovement(action, delta, adjustment),
+		})
+	}
+
+	return timeline
+}
+
+// stockDelta returns the signed change an adjustment made to actual stock
+func stockDelta(adjustment *models.StockAdjustment) int {
+	if adjustment.SourceType == models.SourceTypeTransfer {
+		return 0 // moves stock between buckets only
+	}
+	if adjustment.AdjustmentType == models.AdjustmentTypeReduce {
+		return -adjustment.Quantity
+	}
+	return adjustment.Quantity
+}
+
+// describeStockMovement formats a movement as e.g. "+10 (Purchase PUR-VAT-6701-0001 on 2024-01-15)"
+func describeStockMovement(action string, delta int, adjustment *models.StockAdjustment) string {
+	source := action
+	if adjustment.SourceCode != nil && *adjustment.SourceCode != "" {
+		source += " " + *adjustment.SourceCode
+	}
+	return fmt.Sprintf("%+d (%s on %s)", delta, source, adjustment.CreatedAt.Format("2006-01-02"))
+}
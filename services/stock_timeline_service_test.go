@@ -0,0 +1,134 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"goodpack-server/models"
+)
+
+// timelineDay returns noon on the given day of January 2024
+func timelineDay(day int) time.Time {
+	return time.Date(2024, time.January, day, 12, 0, 0, 0, time.UTC)
+}
+
+func movement(day int, source models.SourceType, adjustmentType models.StockAdjustmentType, quantity, afterActualStock int) *models.StockAdjustment {
+	return &models.StockAdjustment{
+		SourceType:       source,
+		AdjustmentType:   adjustmentType,
+		Quantity:         quantity,
+		AfterActualStock: afterActualStock,
+		CreatedAt:        timelineDay(day),
+	}
+}
+
+// balances returns the delta and running balance of each timeline entry
+func balances(timeline []models.StockTimelineEntry) [][2]int {
+	got := [][2]int{}
+	for _, entry := range timeline {
+		got = append(got, [2]int{entry.Delta, entry.RunningBalance})
+	}
+	return got
+}
+
+func TestBuildStockTimelineRunningBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		adjustments []*models.StockAdjustment
+		want        [][2]int
+	}{
+		{
+			name: "from an opening balance",
+			adjustments: []*models.StockAdjustment{
+				movement(1, models.SourceTypePurchase, models.AdjustmentTypeAdd, 10, 15),
+				movement(2, models.SourceTypeSale, models.AdjustmentTypeReduce, 3, 12),
+				movement(3, models.SourceTypeReturn, models.AdjustmentTypeAdd, 1, 13),
+			},
+			want: [][2]int{{10, 15}, {-3, 12}, {1, 13}},
+		},
+		{
+			name: "recorded out of order",
+			adjustments: []*models.StockAdjustment{
+				movement(3, models.SourceTypeSale, models.AdjustmentTypeReduce, 4, 6),
+				movement(1, models.SourceTypePurchase, models.AdjustmentTypeAdd, 10, 10),
+			},
+			want: [][2]int{{10, 10}, {-4, 6}},
+		},
+		{
+			name: "going negative",
+			adjustments: []*models.StockAdjustment{
+				movement(1, models.SourceTypeSale, models.AdjustmentTypeReduce, 5, -2),
+				movement(2, models.SourceTypeSale, models.AdjustmentTypeReduce, 4, -6),
+				movement(3, models.SourceTypePurchase, models.AdjustmentTypeAdd, 10, 4),
+			},
+			want: [][2]int{{-5, -2}, {-4, -6}, {10, 4}},
+		},
+		{
+			name: "transfers leave the balance alone",
+			adjustments: []*models.StockAdjustment{
+				movement(1, models.SourceTypePurchase, models.AdjustmentTypeAdd, 8, 8),
+				movement(2, models.SourceTypeTransfer, models.AdjustmentTypeReduce, 5, 8),
+				movement(3, models.SourceTypeSale, models.AdjustmentTypeReduce, 2, 6),
+			},
+			want: [][2]int{{8, 8}, {0, 8}, {-2, 6}},
+		},
+		{
+			name: "opening balance from the first after value",
+			adjustments: []*models.StockAdjustment{
+				// Recorded with a before value of 0 although the product held 20
+				{SourceType: models.SourceTypeMigration, AdjustmentType: models.AdjustmentTypeAdd, Quantity: 5, AfterActualStock: 25, CreatedAt: timelineDay(1)},
+				movement(2, models.SourceTypeSale, models.AdjustmentTypeReduce, 30, -5),
+			},
+			want: [][2]int{{5, 25}, {-30, -5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := balances(BuildStockTimeline(tt.adjustments, time.Time{}, time.Time{}))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("delta and balance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildStockTimelineDateRangeKeepsEarlierBalance(t *testing.T) {
+	adjustments := []*models.StockAdjustment{
+		movement(1, models.SourceTypePurchase, models.AdjustmentTypeAdd, 10, 10),
+		movement(5, models.SourceTypeSale, models.AdjustmentTypeReduce, 12, -2),
+		movement(10, models.SourceTypePurchase, models.AdjustmentTypeAdd, 6, 4),
+		movement(20, models.SourceTypeSale, models.AdjustmentTypeReduce, 1, 3),
+	}
+
+	got := balances(BuildStockTimeline(adjustments, timelineDay(5), timelineDay(10)))
+	want := [][2]int{{-12, -2}, {6, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delta and balance = %v, want %v", got, want)
+	}
+}
+
+func TestBuildStockTimelineEmpty(t *testing.T) {
+	timeline := BuildStockTimeline(nil, time.Time{}, time.Time{})
+	if timeline == nil || len(timeline) != 0 {
+		t.Errorf("timeline = %v, want an empty list", timeline)
+	}
+}
+
+func TestBuildStockTimelineDescription(t *testing.T) {
+	code := "PUR-VAT-6701-0001"
+	purchase := movement(15, models.SourceTypePurchase, models.AdjustmentTypeAdd, 10, 10)
+	purchase.SourceCode = &code
+	count := movement(20, models.SourceTypeStockCount, models.AdjustmentTypeReduce, 3, 7)
+
+	timeline := BuildStockTimeline([]*models.StockAdjustment{purchase, count}, time.Time{}, time.Time{})
+	want := []string{"+10 (Purchase PUR-VAT-6701-0001 on 2024-01-15)", "-3 (Stock count on 2024-01-20)"}
+	for i, entry := range timeline {
+		if entry.Description != want[i] {
+			t.Errorf("entry %d description = %q, want %q", i, entry.Description, want[i])
+		}
+	}
+	if timeline[0].Action != "Purchase" || timeline[0].SourceCode == nil || *timeline[0].SourceCode != code {
+		t.Errorf("entry 0 = %+v, want a Purchase of %s", timeline[0], code)
+	}
+}
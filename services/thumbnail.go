@@ -0,0 +1,58 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	// Decoders for the image types product uploads accept
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ProductThumbnailSizes are the square thumbnails made of every uploaded product image, in pixels
+var ProductThumbnailSizes = []int{150, 400}
+
+// thumbnailJPEGQuality is the JPEG quality thumbnails are saved at
+const thumbnailJPEGQuality = 85
+
+// GenerateThumbnails decodes a JPEG, PNG, GIF or WebP image and returns a JPEG thumbnail of it for each of sizes
+func GenerateThumbnails(data []byte, sizes []int) (map[int][]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnails := make(map[int][]byte, len(sizes))
+	for _, size := range sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Thumbnail(src, size), &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// Thumbnail scales src to fill a size × size square, cropping the middle of the longer side, on a white
+// background so transparent images look the same as JPEG
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	crop := bounds
+	if width, height := bounds.Dx(), bounds.Dy(); width > height {
+		crop.Min.X += (width - height) / 2
+		crop.Max.X = crop.Min.X + height
+	} else {
+		crop.Min.Y += (height - width) / 2
+		crop.Max.Y = crop.Min.Y + width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// halvesPNG is a width × height PNG, red on the left half and blue on the right
+func halvesPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		c := color.RGBA{R: 255, A: 255}
+		if x >= width/2 {
+			c = color.RGBA{B: 255, A: 255}
+		}
+		for y := 0; y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateThumbnails(t *testing.T) {
+	thumbnails, err := GenerateThumbnails(halvesPNG(t, 800, 600), ProductThumbnailSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thumbnails) != len(ProductThumbnailSizes) {
+		t.Fatalf("got %d thumbnails, want %d", len(thumbnails), len(ProductThumbnailSizes))
+	}
+	for _, size := range []int{150, 400} {
+		img, format, err := image.Decode(bytes.NewReader(thumbnails[size]))
+		if err != nil {
+			t.Fatalf("%dpx thumbnail: %v", size, err)
+		}
+		if format != "jpeg" {
+			t.Errorf("%dpx thumbnail format = %s, want jpeg", size, format)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(size, size) {
+			t.Errorf("%dpx thumbnail is %v, want %dx%d", size, got, size, size)
+		}
+
+		// The middle of the wider side is kept, so both halves still show
+		left := color.RGBAModel.Convert(img.At(size/8, size/2)).(color.RGBA)
+		right := color.RGBAModel.Convert(img.At(size-size/8, size/2)).(color.RGBA)
+		if left.R < 200 || left.B > 60 {
+			t.Errorf("%dpx thumbnail left = %v, want red", size, left)
+		}
+		if right.B < 200 || right.R > 60 {
+			t.Errorf("%dpx thumbnail right = %v, want blue", size, right)
+		}
+	}
+}
+
+func TestGenerateThumbnailsFillsTransparencyWithWhite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 40))); err != nil {
+		t.Fatal(err)
+	}
+	thumbnails, err := GenerateThumbnails(buf.Bytes(), []int{150})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumbnails[150]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(img.At(75, 75)).(color.RGBA); c.R < 250 || c.G < 250 || c.B < 250 {
+		t.Errorf("transparent pixel = %v, want white", c)
+	}
+}
+
+func TestGenerateThumbnailsRejectsNonImages(t *testing.T) {
+	if _, err := GenerateThumbnails([]byte("not an image at all"), ProductThumbnailSizes); err == nil {
+		t.Error("GenerateThumbnails() of text succeeded, want an error")
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// VelocityService classifies products by how fast they sell
+type VelocityService struct {
+	productRepo         *repository.ProductRepository
+	stockAdjustmentRepo *repository.StockAdjustmentRepository
+}
+
+func NewVelocityService(productRepo *repository.ProductRepository, stockAdjustmentRepo *repository.StockAdjustmentRepository) *VelocityService {
+	return &VelocityService{
+		productRepo:         productRepo,
+		stockAdjustmentRepo: stockAdjustmentRepo,
+	}
+}
+
+// Reclassify ranks every product by the units sold in the VelocityWindowDays days before now and stores its
+// velocity class (see models.ClassifyVelocity)
+func (s *VelocityService) Reclassify(ctx context.Context, now time.Time) (*models.VelocityReclassifyResult, error) {
+	productIDs, err := s.productRepo.GetIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unitsSold, err := s.stockAdjustmentRepo.GetUnitsSoldByProduct(ctx, now.AddDate(0, 0, -models.VelocityWindowDays))
+	if err != nil {
+		return nil, err
+	}
+
+	classes := models.ClassifyVelocity(productIDs, unitsSold)
+	changed, err := s.productRepo.SetVelocityClasses(ctx, classes)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := map[string]int{models.VelocityClassA: 0, models.VelocityClassB: 0, models.VelocityClassC: 0}
+	for _, class := range classes {
+		summary[class]++
+	}
+	return &models.VelocityReclassifyResult{
+		Classified: len(classes),
+		Changed:    changed,
+		Summary:    summary,
+	}, nil
+}
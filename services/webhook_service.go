@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"goodpack-server/models"
+	"goodpack-server/repository"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Goodpack-Signature" // sha256=<hex HMAC-SHA256 of the body keyed with the webhook secret>
+	WebhookEventHeader     = "X-Goodpack-Event"
+	WebhookDeliveryHeader  = "X-Goodpack-Delivery" // same ID on every retry of a delivery
+)
+
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 3
+	webhookRetryDelay  = 2 * time.Second // doubled after each failed attempt
+)
+
+// WebhookService posts events to the webhooks subscribed to them
+type WebhookService struct {
+	webhookRepo    *repository.WebhookRepository
+	recordDelivery func(ctx context.Context, delivery *models.WebhookDelivery) error
+	client         *http.Client
+	maxAttempts    int
+	retryDelay     time.Duration
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepo:    webhookRepo,
+		recordDelivery: webhookRepo.RecordDelivery,
+		client:         &http.Client{Timeout: webhookTimeout},
+		maxAttempts:    webhookMaxAttempts,
+		retryDelay:     webhookRetryDelay,
+	}
+}
+
+// SignWebhookPayload returns the signature header value for body: "sha256=" and the hex HMAC-SHA256 of
+// body keyed with secret. Receivers recompute it over the raw request body to verify a delivery.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch sends event with payload as its data to every active webhook subscribed to it. Deliveries run
+// in the background; Dispatch only returns an error if the webhooks cannot be loaded or the payload encoded.
+func (s *WebhookService) Dispatch(ctx context.Context, event string, payload interface{}) error {
+	webhooks, err := s.webhookRepo.GetActiveByEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks for %s: %w", event, err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	deliveryID := uuid.NewString()
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:        deliveryID,
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", event, err)
+	}
+
+	for _, webhook := range webhooks {
+		go s.deliver(webhook, deliveryID, event, body)
+	}
+	return nil
+}
+
+// deliver posts body to the webhook, recording every attempt. Timeouts, connection errors, 429 and 5xx
+// responses are retried with exponential backoff; other responses are final.
+func (s *WebhookService) deliver(webhook *models.Webhook, deliveryID, event string, body []byte) {
+	delay := s.retryDelay
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		start := time.Now()
+		statusCode, err := s.post(webhook, deliveryID, event, body)
+
+		delivery := &models.WebhookDelivery{
+			WebhookID:  webhook.ID.Hex(),
+			DeliveryID: deliveryID,
+			Event:      event,
+			URL:        webhook.URL,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Success:    err == nil,
+			DurationMs: time.Since(start).Milliseconds(),
+			CreatedAt:  start,
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if recordErr := s.recordDelivery(context.Background(), delivery); recordErr != nil {
+			fmt.Printf("Warning: Failed to record webhook delivery %s: %v\n", deliveryID, recordErr)
+		}
+
+		if err == nil {
+			return
+		}
+		if !isRetryableDelivery(statusCode) {
+			fmt.Printf("Warning: Webhook %s rejected %s: %v\n", webhook.ID.Hex(), event, err)
+			return
+		}
+		if attempt < s.maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	fmt.Printf("Warning: Webhook %s gave up on %s after %d attempts\n", webhook.ID.Hex(), event, s.maxAttempts)
+}
+
+// post makes one delivery attempt and returns the response status (0 if there was no response)
+func (s *WebhookService) post(webhook *models.Webhook, deliveryID, event string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // drain so the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// isRetryableDelivery reports whether a failed attempt may succeed later
+func isRetryableDelivery(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"goodpack-server/models"
+)
+
+// testWebhookService returns a service that retries quickly and keeps the deliveries it records in memory
+func testWebhookService(timeout time.Duration) (*WebhookService, *[]*models.WebhookDelivery) {
+	var mu sync.Mutex
+	deliveries := []*models.WebhookDelivery{}
+	return &WebhookService{
+		recordDelivery: func(ctx context.Context, delivery *models.WebhookDelivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			deliveries = append(deliveries, delivery)
+			return nil
+		},
+		client:      &http.Client{Timeout: timeout},
+		maxAttempts: 3,
+		retryDelay:  time.Millisecond,
+	}, &deliveries
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"sale.created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("s3cret", body); got != want {
+		t.Errorf("SignWebhookPayload = %q, want %q", got, want)
+	}
+	if SignWebhookPayload("other", body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if SignWebhookPayload("s3cret", []byte(`{"event":"sale.updated"}`)) == want {
+		t.Error("signature does not depend on the body")
+	}
+}
+
+func TestWebhookDeliverySignsTheBody(t *testing.T) {
+	body := []byte(`{"event":"sale.created","data":{"saleCode":"SA-6701-0001"}}`)
+	var gotSignature, gotEvent string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get(WebhookEventHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	s, deliveries := testWebhookService(time.Second)
+	webhook := &models.Webhook{ID: primitive.NewObjectID(), URL: server.URL, Secret: "s3cret"}
+	s.deliver(webhook, "d1", models.WebhookEventSaleCreated, body)
+
+	if gotSignature != SignWebhookPayload("s3cret", gotBody) || string(gotBody) != string(body) {
+		t.Errorf("received signature %q over %s, want the signature of the sent body", gotSignature, gotBody)
+	}
+	if gotEvent != models.WebhookEventSaleCreated {
+		t.Errorf("event header = %q, want %q", gotEvent, models.WebhookEventSaleCreated)
+	}
+	if len(*deliveries) != 1 || !(*deliveries)[0].Success {
+		t.Errorf("recorded %d deliveries, want one successful", len(*deliveries))
+	}
+}
+
+func TestWebhookDeliveryRetriesAfterTimeout(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond) // longer than the client waits
+		}
+	}))
+	defer server.Close()
+
+	s, deliveries := testWebhookService(50 * time.Millisecond)
+	webhook := &models.Webhook{ID: primitive.NewObjectID(), URL: server.URL}
+	s.deliver(webhook, "d1", models.WebhookEventSaleCreated, []byte(`{}`))
+
+	if len(*deliveries) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(*deliveries))
+	}
+	first, second := (*deliveries)[0], (*deliveries)[1]
+	if first.Success || first.StatusCode != 0 || first.Error == "" {
+		t.Errorf("first attempt = %+v, want a failure without a response", first)
+	}
+	if !second.Success || second.Attempt != 2 || second.DeliveryID != "d1" {
+		t.Errorf("second attempt = %+v, want attempt 2 of delivery d1 to succeed", second)
+	}
+}
+
+func TestWebhookDeliveryGivesUpAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, deliveries := testWebhookService(time.Second)
+	s.deliver(&models.Webhook{ID: primitive.NewObjectID(), URL: server.URL}, "d1", models.WebhookEventSaleCreated, []byte(`{}`))
+
+	if len(*deliveries) != 3 {
+		t.Errorf("recorded %d attempts, want 3", len(*deliveries))
+	}
+}
+
+func TestWebhookDeliveryDoesNotRetryClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	s, deliveries := testWebhookService(time.Second)
+	s.deliver(&models.Webhook{ID: primitive.NewObjectID(), URL: server.URL}, "d1", models.WebhookEventSaleCreated, []byte(`{}`))
+
+	if len(*deliveries) != 1 || (*deliveries)[0].StatusCode != http.StatusBadRequest {
+		t.Errorf("recorded %d attempts, want a single 400", len(*deliveries))
+	}
+}
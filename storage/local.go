@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults matching the /uploads/ static file route
+const (
+	DefaultLocalDir       = "uploads"
+	DefaultLocalURLPrefix = "/uploads/"
+)
+
+// LocalStorage keeps files on the server's disk
+type LocalStorage struct {
+	baseDir   string
+	urlPrefix string
+}
+
+func NewLocalStorage(baseDir, urlPrefix string) *LocalStorage {
+	return &LocalStorage{
+		baseDir:   baseDir,
+		urlPrefix: urlPrefix,
+	}
+}
+
+func (s *LocalStorage) Upload(ctx context.Context, filename string, content io.Reader, contentType string) (string, error) {
+	filePath, err := s.resolve(filename)
+	if err != nil {
+		return "", err
+	}
+
+	// Create the target directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	dst, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, content); err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return s.urlPrefix + filepath.ToSlash(filename), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, url string) error {
+	filePath, err := s.resolve(strings.TrimPrefix(url, s.urlPrefix))
+	if err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// resolve maps a relative filename to a path inside baseDir, rejecting directory traversal
+func (s *LocalStorage) resolve(filename string) (string, error) {
+	clean := filepath.Clean("/" + filename)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid filename: %s", filename)
+	}
+	return filepath.Join(s.baseDir, clean), nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageUploadAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, DefaultLocalURLPrefix)
+	ctx := context.Background()
+
+	url, err := s.Upload(ctx, "products/abc_1.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/uploads/products/abc_1.png" {
+		t.Errorf("url = %q, want /uploads/products/abc_1.png", url)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "products", "abc_1.png"))
+	if err != nil || string(content) != "image" {
+		t.Fatalf("stored file = %q, %v; want %q", content, err, "image")
+	}
+
+	if err := s.Delete(ctx, url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "products", "abc_1.png")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+}
+
+func TestLocalStorageStaysInsideBaseDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	s := NewLocalStorage(dir, DefaultLocalURLPrefix)
+
+	url, err := s.Upload(context.Background(), "../../escaped.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.png")); err != nil {
+		t.Errorf("file not stored inside the base directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.png")); !os.IsNotExist(err) {
+		t.Errorf("file stored outside the base directory (url %s)", url)
+	}
+
+	if _, err := s.Upload(context.Background(), "/", strings.NewReader("image"), "image/png"); err == nil {
+		t.Error("Upload of an empty filename succeeded, want an error")
+	}
+}
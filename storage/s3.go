@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Options configures S3Storage
+type S3Options struct {
+	Bucket          string
+	Region          string
+	AccessKeyID     string // empty = use the default AWS credential chain
+	SecretAccessKey string
+	Endpoint        string // optional, for S3-compatible services such as MinIO
+}
+
+// S3Storage keeps files in an S3 (or S3-compatible) bucket
+type S3Storage struct {
+	client  *s3.Client
+	bucket  string
+	baseURL string
+}
+
+func NewS3Storage(ctx context.Context, opts S3Options) (*S3Storage, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("AWS_BUCKET is required for the s3 storage backend")
+	}
+	if opts.Region == "" {
+		return nil, errors.New("AWS_REGION is required for the s3 storage backend")
+	}
+
+	loadOptions := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(opts.Region)}
+	if opts.AccessKeyID != "" {
+		loadOptions = append(loadOptions, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	baseURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", opts.Bucket, opts.Region)
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	if opts.Endpoint != "" {
+		baseURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + opts.Bucket + "/"
+	}
+
+	return &S3Storage{
+		client:  client,
+		bucket:  opts.Bucket,
+		baseURL: baseURL,
+	}, nil
+}
+
+func (s *S3Storage) Upload(ctx context.Context, filename string, content io.Reader, contentType string) (string, error) {
+	key := strings.TrimPrefix(filename, "/")
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        content,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s to S3: %w", key, err)
+	}
+
+	return s.baseURL + key, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, url string) error {
+	if !strings.HasPrefix(url, s.baseURL) {
+		return fmt.Errorf("URL is not in bucket %s: %s", s.bucket, url)
+	}
+	key := strings.TrimPrefix(url, s.baseURL)
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from S3: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// testS3Storage returns S3 storage on a bucket of its own on the MinIO at MINIO_TEST_ENDPOINT, emptied and
+// removed when the test ends. Credentials default to MinIO's minioadmin/minioadmin.
+// Tests that need MinIO are skipped when the variable is not set.
+func testS3Storage(t *testing.T) *S3Storage {
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT not set")
+	}
+	accessKey, secretKey := os.Getenv("MINIO_TEST_ACCESS_KEY"), os.Getenv("MINIO_TEST_SECRET_KEY")
+	if accessKey == "" {
+		accessKey, secretKey = "minioadmin", "minioadmin"
+	}
+
+	ctx := context.Background()
+	s, err := NewS3Storage(ctx, S3Options{
+		Bucket:          fmt.Sprintf("goodpack-test-%d", time.Now().UnixNano()),
+		Region:          "us-east-1",
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		Endpoint:        endpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(s.bucket)}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		objects, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket)})
+		if err == nil {
+			for _, object := range objects.Contents {
+				s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: object.Key})
+			}
+		}
+		s.client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(s.bucket)})
+	})
+	return s
+}
+
+func TestS3StorageUploadAndDelete(t *testing.T) {
+	s := testS3Storage(t)
+	ctx := context.Background()
+
+	url, err := s.Upload(ctx, "/products/abc_1.png", strings.NewReader("image"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := s.baseURL + "products/abc_1.png"; url != want {
+		t.Errorf("url = %q, want %q", url, want)
+	}
+
+	object, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String("products/abc_1.png")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(object.Body)
+	object.Body.Close()
+	if err != nil || string(content) != "image" {
+		t.Errorf("stored object = %q, %v; want %q", content, err, "image")
+	}
+	if object.ContentType == nil || *object.ContentType != "image/png" {
+		t.Errorf("content type = %v, want image/png", aws.ToString(object.ContentType))
+	}
+
+	if err := s.Delete(ctx, url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String("products/abc_1.png")}); err == nil {
+		t.Error("object still exists after Delete")
+	}
+}
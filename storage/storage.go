@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"goodpack-server/config"
+)
+
+// Storage backends selectable with STORAGE_BACKEND
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// FileStorage stores uploaded files and returns the URL they are served from
+type FileStorage interface {
+	// Upload stores content under filename (a relative key such as "products/abc.jpg") and returns its public URL
+	Upload(ctx context.Context, filename string, content io.Reader, contentType string) (url string, err error)
+	// Delete removes the file previously returned by Upload
+	Delete(ctx context.Context, url string) error
+}
+
+// NewFromConfig creates the file storage selected by cfg.StorageBackend
+func NewFromConfig(ctx context.Context, cfg *config.Config) (FileStorage, error) {
+	switch cfg.StorageBackend {
+	case "", BackendLocal:
+		return NewLocalStorage(DefaultLocalDir, DefaultLocalURLPrefix), nil
+	case BackendS3:
+		return NewS3Storage(ctx, S3Options{
+			Bucket:          cfg.AWSBucket,
+			Region:          cfg.AWSRegion,
+			AccessKeyID:     cfg.AWSAccessKeyID,
+			SecretAccessKey: cfg.AWSSecretAccessKey,
+			Endpoint:        cfg.AWSEndpoint,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.StorageBackend)
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"goodpack-server/config"
+)
+
+func TestNewFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantS3  bool
+		wantErr bool
+	}{
+		{name: "default", cfg: config.Config{}},
+		{name: "local", cfg: config.Config{StorageBackend: BackendLocal}},
+		{name: "s3", cfg: config.Config{StorageBackend: BackendS3, AWSBucket: "goodpack", AWSRegion: "ap-southeast-1"}, wantS3: true},
+		{name: "s3 without bucket", cfg: config.Config{StorageBackend: BackendS3, AWSRegion: "ap-southeast-1"}, wantErr: true},
+		{name: "s3 without region", cfg: config.Config{StorageBackend: BackendS3, AWSBucket: "goodpack"}, wantErr: true},
+		{name: "unknown", cfg: config.Config{StorageBackend: "gcs"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewFromConfig(context.Background(), &tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewFromConfig() = %T, want an error", s)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, isS3 := s.(*S3Storage); isS3 != tt.wantS3 {
+				t.Errorf("NewFromConfig() = %T, want S3 storage %t", s, tt.wantS3)
+			}
+		})
+	}
+}
+
+func TestS3StorageURLs(t *testing.T) {
+	tests := []struct {
+		opts S3Options
+		want string
+	}{
+		{S3Options{Bucket: "goodpack", Region: "ap-southeast-1"}, "https://goodpack.s3.ap-southeast-1.amazonaws.com/"},
+		{S3Options{Bucket: "goodpack", Region: "us-east-1", Endpoint: "http://localhost:9000/"}, "http://localhost:9000/goodpack/"},
+	}
+	for _, tt := range tests {
+		s, err := NewS3Storage(context.Background(), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.baseURL != tt.want {
+			t.Errorf("base URL for %+v = %q, want %q", tt.opts, s.baseURL, tt.want)
+		}
+	}
+
+	s, _ := NewS3Storage(context.Background(), S3Options{Bucket: "goodpack", Region: "ap-southeast-1"})
+	if err := s.Delete(context.Background(), "https://other.s3.ap-southeast-1.amazonaws.com/products/a.png"); err == nil {
+		t.Error("Delete of a URL in another bucket succeeded, want an error")
+	}
+}
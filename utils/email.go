@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// maxEmailLength is the longest address SMTP allows
+const maxEmailLength = 254
+
+// emailPattern accepts a local part of the usual unquoted characters, an @ and a domain of at least two labels
+var emailPattern = regexp.MustCompile(`^[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)+$`)
+
+// ValidateEmail checks that email looks like a deliverable address, e.g. sales@goodpack.co.th. It does not
+// check that the domain or mailbox exists.
+func ValidateEmail(email string) error {
+	if len(email) > maxEmailLength {
+		return fmt.Errorf("email address must be at most %d characters", maxEmailLength)
+	}
+	if !emailPattern.MatchString(email) {
+		return errors.New("email address is not valid")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	valid := []string{
+		"sales@goodpack.co.th",
+		"first.last+orders@example.com",
+		"o'brien@mail-server.example.org",
+	}
+	for _, email := range valid {
+		if err := ValidateEmail(email); err != nil {
+			t.Errorf("ValidateEmail(%q) = %v, want nil", email, err)
+		}
+	}
+
+	invalid := []struct {
+		email  string
+		reason string
+	}{
+		{"", "empty"},
+		{"sales", "no @"},
+		{"sales@localhost", "single-label domain"},
+		{"sales@goodpack..co.th", "empty label"},
+		{"sales@-goodpack.com", "label starts with a hyphen"},
+		{"sales @goodpack.com", "space"},
+		{"a@b@goodpack.com", "two @"},
+		{strings.Repeat("a", 250) + "@x.co", "longer than 254 characters"},
+	}
+	for _, tt := range invalid {
+		if err := ValidateEmail(tt.email); err == nil {
+			t.Errorf("ValidateEmail(%q) = nil, want an error (%s)", tt.email, tt.reason)
+		}
+	}
+}
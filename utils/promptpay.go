@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PromptPay EMV QR constants (Bank of Thailand Thai QR Code standard)
+const (
+	promptPayAID          = "A000000677010111"
+	promptPayCurrencyTHB  = "764"
+	promptPayCountryCode  = "TH"
+	promptPayStaticQR     = "11" // reusable, payer enters the amount
+	promptPayDynamicQR    = "12" // one-time, amount is fixed
+	promptPayPhoneLength  = 10
+	promptPayTaxIDLength  = 13
+	promptPayWalletLength = 15
+)
+
+// GeneratePromptPayPayload builds the EMV QR payload for a PromptPay transfer.
+// recipientID is a mobile number (10 digits), tax/citizen ID (13 digits) or e-wallet ID (15 digits);
+// dashes and spaces are ignored. amount may be empty to let the payer enter it.
+func GeneratePromptPayPayload(recipientID, amount string) (string, error) {
+	target, err := promptPayTarget(recipientID)
+	if err != nil {
+		return "", err
+	}
+
+	initMethod := promptPayStaticQR
+	amountField := ""
+	if strings.TrimSpace(amount) != "" {
+		value, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil || value <= 0 {
+			return "", fmt.Errorf("invalid PromptPay amount: %s", amount)
+		}
+		initMethod = promptPayDynamicQR
+		amountField = emvField("54", strconv.FormatFloat(value, 'f', 2, 64))
+	}
+
+	payload := emvField("00", "01") +
+		emvField("01", initMethod) +
+		emvField("29", emvField("00", promptPayAID)+target) +
+		emvField("58", promptPayCountryCode) +
+		emvField("53", promptPayCurrencyTHB) +
+		amountField +
+		"6304"
+
+	return payload + fmt.Sprintf("%04X", CRC16CCITT([]byte(payload))), nil
+}
+
+// promptPayTarget returns the merchant account sub-field for the recipient
+func promptPayTarget(recipientID string) (string, error) {
+	id := strings.NewReplacer("-", "", " ", "").Replace(recipientID)
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid PromptPay ID: %s", recipientID)
+	}
+
+	switch len(id) {
+	case promptPayPhoneLength:
+		// 0812345678 -> 0066812345678
+		return emvField("01", "0066"+strings.TrimPrefix(id, "0")), nil
+	case promptPayTaxIDLength:
+		return emvField("02", id), nil
+	case promptPayWalletLength:
+		return emvField("03", id), nil
+	default:
+		return "", fmt.Errorf("invalid PromptPay ID length: %s", recipientID)
+	}
+}
+
+// emvField encodes a tag-length-value field
+func emvField(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// CRC16CCITT computes the CRC-16/CCITT-FALSE checksum (poly 0x1021, init 0xFFFF) used by EMV QR codes
+func CRC16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
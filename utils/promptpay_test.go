@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCRC16CCITT(t *testing.T) {
+	// The standard check value of CRC-16/CCITT-FALSE
+	if got := CRC16CCITT([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("CRC16CCITT(123456789) = %04X, want 29B1", got)
+	}
+}
+
+func TestGeneratePromptPayPayload(t *testing.T) {
+	tests := []struct {
+		name        string
+		recipientID string
+		amount      string
+		want        string
+	}{
+		{
+			name:        "phone with amount",
+			recipientID: "000-000-0000",
+			amount:      "4.22",
+			want:        "00020101021229370016A000000677010111011300660000000005802TH530376454044.226304E469",
+		},
+		{
+			name:        "phone without amount",
+			recipientID: "0801234567",
+			want:        "00020101021129370016A000000677010111011300668012345675802TH53037646304",
+		},
+		{
+			name:        "tax ID",
+			recipientID: "0105512345678",
+			amount:      "100",
+			want:        "00020101021229370016A000000677010111021301055123456785802TH5303764540610",
+		},
+	}
+	for _, tt := range tests {
+		payload, err := GeneratePromptPayPayload(tt.recipientID, tt.amount)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !strings.HasPrefix(payload, tt.want) {
+			t.Errorf("%s: payload = %s, want it to start with %s", tt.name, payload, tt.want)
+		}
+		body, crc := payload[:len(payload)-4], payload[len(payload)-4:]
+		if want := fmt.Sprintf("%04X", CRC16CCITT([]byte(body))); crc != want {
+			t.Errorf("%s: CRC = %s, want %s", tt.name, crc, want)
+		}
+	}
+}
+
+func TestGeneratePromptPayPayloadRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		recipientID string
+		amount      string
+	}{
+		{"08123", ""},          // wrong length
+		{"08-1234-567x", ""},   // not digits
+		{"0812345678", "abc"},  // amount not a number
+		{"0812345678", "-5"},   // negative amount
+		{"0812345678", "0.00"}, // zero amount
+	}
+	for _, tt := range tests {
+		if _, err := GeneratePromptPayPayload(tt.recipientID, tt.amount); err == nil {
+			t.Errorf("GeneratePromptPayPayload(%q, %q) succeeded, want an error", tt.recipientID, tt.amount)
+		}
+	}
+}
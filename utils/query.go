@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Default list order when sortBy is not given
+const (
+	DefaultSortField = "createdAt"
+	DefaultSortOrder = "desc"
+)
+
+var (
+	ErrInvalidSortField = errors.New("invalid sort field")
+	ErrInvalidSortOrder = errors.New("invalid sort order")
+)
+
+// sortFieldPaths maps JSON field names to their BSON paths where the two differ
+var sortFieldPaths = map[string]string{
+	"id":         "_id",
+	"popularity": "popularityScore",
+}
+
+// ParseSortOptions builds a MongoDB sort from the sortBy and sortOrder (asc or desc) query parameters.
+// sortBy must be one of allowedFields (JSON names, e.g. "price.saleVAT.latest"), so clients cannot sort
+// on arbitrary paths. Without sortBy the result is sorted by createdAt, newest first. Ties are broken
+// by _id so pages are stable.
+func ParseSortOptions(r *http.Request, allowedFields []string) (bson.D, error) {
+	return parseSortOptions(r, allowedFields, DefaultSortField, DefaultSortOrder, "")
+}
+
+// ParseSortOptionsBy is ParseSortOptions for a list in defaultField order: without sortBy it is sorted by
+// defaultField, ascending, and ties on another field are broken by defaultField before _id
+func ParseSortOptionsBy(r *http.Request, allowedFields []string, defaultField string) (bson.D, error) {
+	return parseSortOptions(r, allowedFields, defaultField, "asc", defaultField)
+}
+
+// parseSortOptions builds the sort of ParseSortOptions with the given default field and order; tiebreaker,
+// when set, breaks ties ahead of _id
+func parseSortOptions(r *http.Request, allowedFields []string, defaultField, defaultOrder, tiebreaker string) (bson.D, error) {
+	query := r.URL.Query()
+
+	sortBy := query.Get("sortBy")
+	sortOrder := query.Get("sortOrder")
+	if sortBy == "" {
+		sortBy = defaultField
+		if sortOrder == "" {
+			sortOrder = defaultOrder
+		}
+	}
+
+	allowed := false
+	for _, field := range allowedFields {
+		if field == sortBy {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSortField, sortBy)
+	}
+
+	direction := 1
+	switch sortOrder {
+	case "", "asc":
+	case "desc":
+		direction = -1
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSortOrder, sortOrder)
+	}
+
+	path := sortBy
+	if mapped, ok := sortFieldPaths[sortBy]; ok {
+		path = mapped
+	}
+
+	sort := bson.D{{Key: path, Value: direction}}
+	if tiebreaker != "" && path != tiebreaker {
+		sort = append(sort, bson.E{Key: tiebreaker, Value: 1})
+	}
+	if path != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+	return sort, nil
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseSortOptions(t *testing.T) {
+	allowed := []string{"createdAt", "name", "id", "popularity"}
+	tests := []struct {
+		query   string
+		want    bson.D
+		wantErr error
+	}{
+		{"", bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}, nil},
+		{"?sortOrder=asc", bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}, nil},
+		{"?sortBy=name", bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}, nil},
+		{"?sortBy=name&sortOrder=desc", bson.D{{Key: "name", Value: -1}, {Key: "_id", Value: -1}}, nil},
+		{"?sortBy=id&sortOrder=desc", bson.D{{Key: "_id", Value: -1}}, nil},
+		{"?sortBy=popularity", bson.D{{Key: "popularityScore", Value: 1}, {Key: "_id", Value: 1}}, nil},
+		{"?sortBy=password", nil, ErrInvalidSortField},
+		{"?sortBy=name&sortOrder=up", nil, ErrInvalidSortOrder},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortOptions(httptest.NewRequest("GET", "/api/products"+tt.query, nil), allowed)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: error = %v, want %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: sort = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSortOptionsBy(t *testing.T) {
+	allowed := []string{"createdAt", "name", "id"}
+	tests := []struct {
+		query   string
+		want    bson.D
+		wantErr error
+	}{
+		{"", bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}, nil},
+		{"?sortOrder=desc", bson.D{{Key: "name", Value: -1}, {Key: "_id", Value: -1}}, nil},
+		{"?sortBy=createdAt&sortOrder=desc", bson.D{{Key: "createdAt", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: -1}}, nil},
+		{"?sortBy=id", bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}}, nil},
+		{"?sortBy=cost", nil, ErrInvalidSortField},
+		{"?sortOrder=up", nil, ErrInvalidSortOrder},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortOptionsBy(httptest.NewRequest("GET", "/api/products/category/Box"+tt.query, nil), allowed, "name")
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q: error = %v, want %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: sort = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetryBaseDelay is the wait before the second attempt of RetryWithBackoff; it doubles with every attempt after that
+var RetryBaseDelay = 100 * time.Millisecond
+
+// MongoDB error codes worth retrying: the operation was cut off by a replica set election or a shutdown
+const (
+	mongoInterruptedDueToReplStateChange = 11602
+	mongoShutdownInProgress              = 91
+)
+
+// RetryWithBackoff calls fn up to maxAttempts times while it fails with a transient MongoDB error (a network
+// error, an election or a shutdown), waiting RetryBaseDelay before the second attempt and doubling the wait
+// each time, plus up to half of it again as jitter so that servers retrying together spread out.
+// Any other error, such as a duplicate key or a validation failure, is returned straight away, as is the
+// last error once the attempts run out. Waiting stops early with the context's error when ctx is done.
+func RetryWithBackoff(ctx context.Context, maxAttempts int, fn func() error) error {
+	delay := RetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !IsTransientMongoError(err) {
+			return err
+		}
+
+		wait := delay
+		if half := int64(delay / 2); half > 0 {
+			wait += time.Duration(rand.Int63n(half))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// IsTransientMongoError reports whether err is a MongoDB failure that may succeed when tried again
+func IsTransientMongoError(err error) bool {
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.HasErrorCode(mongoInterruptedDueToReplStateChange) || serverErr.HasErrorCode(mongoShutdownInProgress)
+	}
+	return false
+}
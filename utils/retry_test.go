@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errNetwork = mongo.CommandError{Code: 6, Message: "connection reset", Labels: []string{"NetworkError"}}
+
+func withRetryBaseDelay(t *testing.T, delay time.Duration) {
+	previous := RetryBaseDelay
+	RetryBaseDelay = delay
+	t.Cleanup(func() { RetryBaseDelay = previous })
+}
+
+func TestRetryWithBackoffRetriesTransientErrors(t *testing.T) {
+	withRetryBaseDelay(t, time.Millisecond)
+
+	calls := 0
+	err := RetryWithBackoff(context.Background(), 3, func() error {
+		calls++
+		if calls < 3 {
+			return errNetwork
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RetryWithBackoff = %v, want nil", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	withRetryBaseDelay(t, time.Millisecond)
+
+	calls := 0
+	err := RetryWithBackoff(context.Background(), 3, func() error {
+		calls++
+		return errNetwork
+	})
+	if !mongo.IsNetworkError(err) {
+		t.Errorf("RetryWithBackoff = %v, want the last network error", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryWithBackoffReturnsPermanentErrorsStraightAway(t *testing.T) {
+	withRetryBaseDelay(t, time.Millisecond)
+
+	permanent := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}}}
+	calls := 0
+	err := RetryWithBackoff(context.Background(), 3, func() error {
+		calls++
+		return permanent
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("RetryWithBackoff = %v, want the duplicate key error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryWithBackoffStopsWhenContextIsDone(t *testing.T) {
+	withRetryBaseDelay(t, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := RetryWithBackoff(ctx, 3, func() error { return errNetwork })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RetryWithBackoff = %v, want context.Canceled", err)
+	}
+}
+
+func TestIsTransientMongoError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network error", errNetwork, true},
+		{"election", mongo.CommandError{Code: 11602, Message: "InterruptedDueToReplStateChange"}, true},
+		{"shutdown", mongo.CommandError{Code: 91, Message: "ShutdownInProgress"}, true},
+		{"duplicate key", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+		{"no documents", mongo.ErrNoDocuments, false},
+		{"other error", errors.New("invalid id"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransientMongoError(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransientMongoError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
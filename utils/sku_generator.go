@@ -9,26 +9,52 @@ import (
 	"goodpack-server/config"
 )
 
+var (
+	// categoryAbbreviationPattern matches a SKU prefix: uppercase letters, optionally in hyphenated parts (BT, CP-SCR)
+	categoryAbbreviationPattern = regexp.MustCompile(`^[A-Z]+(?:-[A-Z]+)*$`)
+
+	// skuIDPattern matches a SKU ID: a category abbreviation and a number of at least 4 digits (BT-0001, CP-SCR-0012)
+	skuIDPattern = regexp.MustCompile(`^([A-Z]+(?:-[A-Z]+)*)-(\d{4,})$`)
+)
+
 // SKUGenerator handles SKU ID generation
 type SKUGenerator struct {
 	configLoader *config.ConfigLoader
 }
 
-// NewSKUGenerator creates a new SKU generator
-func NewSKUGenerator() *SKUGenerator {
-	configLoader := config.NewConfigLoader()
-	if err := configLoader.LoadConfig(); err != nil {
-		// If config loading fails, continue with empty config
-		fmt.Printf("Warning: Failed to load config: %v\n", err)
+// NewSKUGenerator creates a SKU generator using the shared config. It fails if the config files cannot be
+// loaded or a category abbreviation could not be read back from a SKU ID, since either would make the
+// generator look for the wrong sequence and hand out SKU IDs that already exist.
+func NewSKUGenerator() (*SKUGenerator, error) {
+	loader, err := config.LoadDefault()
+	if err != nil {
+		return nil, err
 	}
+	return NewSKUGeneratorWithConfig(loader)
+}
 
+// NewSKUGeneratorWithConfig creates a SKU generator using the categories of configLoader
+func NewSKUGeneratorWithConfig(configLoader *config.ConfigLoader) (*SKUGenerator, error) {
+	for _, category := range configLoader.GetCategories() {
+		if err := ValidateCategoryAbbreviation(category.Abbreviation); err != nil {
+			return nil, fmt.Errorf("category %s: %w", category.Name, err)
+		}
+	}
 	return &SKUGenerator{
 		configLoader: configLoader,
+	}, nil
+}
+
+// ValidateCategoryAbbreviation checks that abbrev can prefix a SKU ID that ParseSKUID reads back
+func ValidateCategoryAbbreviation(abbrev string) error {
+	if !categoryAbbreviationPattern.MatchString(abbrev) {
+		return fmt.Errorf("invalid category abbreviation %q: use uppercase letters, optionally separated by hyphens", abbrev)
 	}
+	return nil
 }
 
 // GenerateSKUID generates a SKU ID based on category
-// Format: XY-0000 or XYZ-0000 (depending on category abbreviation length)
+// Format: XY-0000, XYZ-0000 or CP-SCR-0000, following the category abbreviation
 func (sg *SKUGenerator) GenerateSKUID(category string, lastNumber int) string {
 	// Get category abbreviation
 	abbrev := sg.getCategoryAbbreviation(category)
@@ -53,7 +79,13 @@ func (sg *SKUGenerator) GenerateProductCode(category, size, color string) string
 	return fmt.Sprintf("%s-%s/%s", categoryAbbrev, formattedSize, colorAbbrev)
 }
 
-// getCategoryAbbreviation returns abbreviation for category
+// CategoryAbbreviation returns the SKU prefix used for category
+func (sg *SKUGenerator) CategoryAbbreviation(category string) string {
+	return sg.getCategoryAbbreviation(category)
+}
+
+// getCategoryAbbreviation returns abbreviation for category, from the categories collection once the config
+// loader reads from it (see config.ConfigLoader.SetCategorySource), so categories added there apply at once
 func (sg *SKUGenerator) getCategoryAbbreviation(category string) string {
 	return sg.configLoader.GetCategoryAbbreviation(category)
 }
@@ -63,13 +95,9 @@ func (sg *SKUGenerator) getColorAbbreviation(color string) string {
 	return sg.configLoader.GetColorAbbreviation(color)
 }
 
-// ParseSKUID extracts category and number from SKU ID
+// ParseSKUID extracts the category abbreviation and number from a SKU ID
 func (sg *SKUGenerator) ParseSKUID(skuID string) (category string, number int, err error) {
-	// Regex pattern for SKU ID: XY-0000 or XYZ-0000
-	pattern := `^([A-Z]{2,3})-(\d{4})$`
-	regex := regexp.MustCompile(pattern)
-
-	matches := regex.FindStringSubmatch(skuID)
+	matches := skuIDPattern.FindStringSubmatch(skuID)
 	if len(matches) != 3 {
 		return "", 0, fmt.Errorf("invalid SKU ID format: %s", skuID)
 	}
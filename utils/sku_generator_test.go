@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"testing"
+
+	"goodpack-server/config"
+)
+
+func testSKUGenerator(t *testing.T) *SKUGenerator {
+	t.Helper()
+	loader := config.NewConfigLoader()
+	if err := loader.LoadConfigFrom("../config"); err != nil {
+		t.Fatal(err)
+	}
+	generator, err := NewSKUGeneratorWithConfig(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return generator
+}
+
+func TestGenerateSKUIDUsesCategoryAbbreviation(t *testing.T) {
+	generator := testSKUGenerator(t)
+	tests := []struct {
+		category   string
+		lastNumber int
+		want       string
+	}{
+		{"ขวด", 0, "BT-0001"},
+		{"bottle", 41, "BT-0042"},
+		{"ฝาเกลียว", 9, "CP-SCR-0010"},
+		{"Screw Cap", 9999, "CP-SCR-10000"},
+	}
+	for _, tt := range tests {
+		if got := generator.GenerateSKUID(tt.category, tt.lastNumber); got != tt.want {
+			t.Errorf("GenerateSKUID(%q, %d) = %s, want %s", tt.category, tt.lastNumber, got, tt.want)
+		}
+	}
+}
+
+func TestParseSKUID(t *testing.T) {
+	generator := testSKUGenerator(t)
+	tests := []struct {
+		skuID    string
+		category string
+		number   int
+		valid    bool
+	}{
+		{"BT-0001", "BT", 1, true},
+		{"CP-SCR-0012", "CP-SCR", 12, true},
+		{"BT-12345", "BT", 12345, true},
+		{"BT-001", "", 0, false},
+		{"bt-0001", "", 0, false},
+		{"BT0001", "", 0, false},
+	}
+	for _, tt := range tests {
+		category, number, err := generator.ParseSKUID(tt.skuID)
+		if (err == nil) != tt.valid || category != tt.category || number != tt.number {
+			t.Errorf("ParseSKUID(%q) = %q, %d, %v; want %q, %d, valid %t", tt.skuID, category, number, err, tt.category, tt.number, tt.valid)
+		}
+	}
+}
+
+func TestGetNextSKUNumberKeepsCategoriesApart(t *testing.T) {
+	generator := testSKUGenerator(t)
+	existing := []string{"CP-0003", "CP-SCR-0020", "BT-0007", "CP-0011", "legacy"}
+
+	if got := generator.GetNextSKUNumber("ฝา", existing); got != 11 {
+		t.Errorf("cap: last number = %d, want 11", got)
+	}
+	if got := generator.GetNextSKUNumber("ฝาเกลียว", existing); got != 20 {
+		t.Errorf("screw cap: last number = %d, want 20", got)
+	}
+	if got := generator.GetNextSKUNumber("ขวด", nil); got != 0 {
+		t.Errorf("no SKUs: last number = %d, want 0", got)
+	}
+}
+
+func TestValidateCategoryAbbreviation(t *testing.T) {
+	for _, abbrev := range []string{"BT", "CP-SCR", "A"} {
+		if err := ValidateCategoryAbbreviation(abbrev); err != nil {
+			t.Errorf("%q: %v, want valid", abbrev, err)
+		}
+	}
+	for _, abbrev := range []string{"", "bt", "BT-", "BT1", "-BT", "CP--SCR"} {
+		if err := ValidateCategoryAbbreviation(abbrev); err == nil {
+			t.Errorf("%q: valid, want an error", abbrev)
+		}
+	}
+}
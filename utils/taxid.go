@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// thaiTaxIDLength is the number of digits in a Thai tax ID / citizen ID
+const thaiTaxIDLength = 13
+
+// ValidateThaiTaxID checks a 13-digit Thai tax ID (personal or juristic) against the
+// Revenue Department checksum. Dashes and spaces (e.g. 0-1055-12345-67-8) are ignored.
+func ValidateThaiTaxID(id string) error {
+	digits := strings.NewReplacer("-", "", " ", "").Replace(id)
+
+	if len(digits) != thaiTaxIDLength {
+		return fmt.Errorf("tax ID must have %d digits, got %d", thaiTaxIDLength, len(digits))
+	}
+
+	allZero := true
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return errors.New("tax ID must contain only digits")
+		}
+		if c != '0' {
+			allZero = false
+		}
+	}
+	if allZero {
+		return errors.New("tax ID cannot be all zeros")
+	}
+
+	// Weighted sum of the first 12 digits with weights 13..2
+	sum := 0
+	for i := 0; i < thaiTaxIDLength-1; i++ {
+		sum += int(digits[i]-'0') * (thaiTaxIDLength - i)
+	}
+	checkDigit := (11 - sum%11) % 10
+
+	if int(digits[thaiTaxIDLength-1]-'0') != checkDigit {
+		return errors.New("tax ID checksum is invalid")
+	}
+	return nil
+}
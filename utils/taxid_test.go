@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestValidateThaiTaxID(t *testing.T) {
+	valid := []string{
+		"0105512345671",
+		"1101700230708",
+		"0-1055-12345-67-1",
+		"3 1010 01234 56 5",
+	}
+	for _, id := range valid {
+		if err := ValidateThaiTaxID(id); err != nil {
+			t.Errorf("ValidateThaiTaxID(%q) = %v, want nil", id, err)
+		}
+	}
+
+	invalid := []struct {
+		id     string
+		reason string
+	}{
+		{"0105512345672", "wrong check digit"},
+		{"010551234567", "12 digits"},
+		{"01055123456712", "14 digits"},
+		{"01055123456a1", "not a digit"},
+		{"0000000000000", "all zeros"},
+		{"", "empty"},
+	}
+	for _, tt := range invalid {
+		if err := ValidateThaiTaxID(tt.id); err == nil {
+			t.Errorf("ValidateThaiTaxID(%q) succeeded, want an error (%s)", tt.id, tt.reason)
+		}
+	}
+}
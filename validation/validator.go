@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one field that failed a validation rule
+type FieldError struct {
+	Field   string `json:"field"`           // JSON path ของฟิลด์ เช่น items[0].quantity
+	Rule    string `json:"rule"`            // กฎที่ไม่ผ่าน เช่น required, min
+	Param   string `json:"param,omitempty"` // ค่าของกฎ เช่น 1 สำหรับ min=1
+	Message string `json:"message"`
+}
+
+// Errors is returned by Validate when one or more fields are invalid
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+var validate = newValidator()
+
+// newValidator reports fields by their JSON names so errors match the request body
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// whole: a float that is a whole number, e.g. a quantity in base units that stock is counted in
+	v.RegisterValidation("whole", func(fl validator.FieldLevel) bool {
+		value := fl.Field().Float()
+		return value == math.Trunc(value)
+	})
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks v against its validate struct tags and returns Errors listing every invalid field
+func Validate(v interface{}) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fieldErrs := make(Errors, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		field := fieldPath(fieldErr.Namespace())
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   field,
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fmt.Sprintf("%s %s", field, describe(fieldErr)),
+		})
+	}
+	return fieldErrs
+}
+
+// fieldPath drops the struct name from a namespace such as "SaleRequest.items[0].quantity"
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// describe turns a failed rule into a readable message
+func describe(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	kind := fieldErr.Kind()
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		switch kind {
+		case reflect.String:
+			return fmt.Sprintf("must be at least %s characters long", param)
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("must contain at least %s items", param)
+		}
+		return fmt.Sprintf("must be at least %s", param)
+	case "max":
+		switch kind {
+		case reflect.String:
+			return fmt.Sprintf("must be at most %s characters long", param)
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("must contain at most %s items", param)
+		}
+		return fmt.Sprintf("must be at most %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(param, " ", ", "))
+	case "url":
+		return "must be a valid URL"
+	case "whole":
+		return "must be a whole number of base units"
+	case "iso4217":
+		return "must be an ISO 4217 currency code, e.g. USD"
+	case "required_without":
+		return fmt.Sprintf("is required when %s is empty", lowerFirst(param))
+	case "excluded_with":
+		return fmt.Sprintf("must be empty when %s is set", lowerFirst(param))
+	}
+	return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+}
+
+// lowerFirst turns a struct field name used as a rule parameter into its JSON name
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
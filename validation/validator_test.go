@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goodpack-server/models"
+)
+
+func TestValidateRejectsFractionalSaleQuantity(t *testing.T) {
+	req := models.SaleRequest{
+		CustomerID: "c1",
+		Items:      []models.SaleItem{{ProductID: "p1", Quantity: 2}, {ProductID: "p2", Quantity: 2.5}},
+	}
+
+	err := Validate(&req)
+	var fieldErrs Errors
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("Validate = %v, want field errors", err)
+	}
+	if len(fieldErrs) != 1 || fieldErrs[0].Field != "items[1].quantity" || fieldErrs[0].Rule != "whole" {
+		t.Fatalf("got %+v, want one whole error on items[1].quantity", fieldErrs)
+	}
+	if fieldErrs[0].Message != "items[1].quantity must be a whole number of base units" {
+		t.Errorf("message = %q", fieldErrs[0].Message)
+	}
+}
+
+// ruleFailures returns "field:rule" for each field Validate rejects, or nil when v is valid
+func ruleFailures(t *testing.T, v interface{}) []string {
+	err := Validate(v)
+	if err == nil {
+		return nil
+	}
+	var fieldErrs Errors
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("Validate = %v, want field errors", err)
+	}
+	failures := make([]string, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		failures[i] = fieldErr.Field + ":" + fieldErr.Rule
+	}
+	return failures
+}
+
+type validationCase struct {
+	name string
+	req  interface{}
+	want []string // "field:rule" of each failure, nil when valid
+}
+
+func runValidationCases(t *testing.T, tests []validationCase) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := ruleFailures(t, tt.req); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: failures %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateProductRequest(t *testing.T) {
+	runValidationCases(t, []validationCase{
+		{"name of one character", &models.ProductRequest{Name: "A"}, nil},
+		{"name of 200 characters", &models.ProductRequest{Name: strings.Repeat("ก", 200)}, nil},
+		{"name of 201 characters", &models.ProductRequest{Name: strings.Repeat("ก", 201)}, []string{"name:max"}},
+		{"no name", &models.ProductRequest{}, []string{"name:required"}},
+		{"negative conversion factor", &models.ProductRequest{Name: "Box", ConversionFactor: -1}, []string{"conversionFactor:min"}},
+		{"zero reorder level", &models.ProductRequest{Name: "Box", ReorderLevel: 0}, nil},
+		{"negative reorder level", &models.ProductRequest{Name: "Box", ReorderLevel: -1}, []string{"reorderLevel:min"}},
+	})
+}
+
+func TestValidateCustomerRequest(t *testing.T) {
+	runValidationCases(t, []validationCase{
+		{"company name only", &models.CustomerRequest{CompanyName: "Goodpack"}, nil},
+		{"contact name only", &models.CustomerRequest{ContactName: "Somchai"}, nil},
+		{"no name", &models.CustomerRequest{Phone: "0812345678"}, []string{"companyName:required_without"}},
+		{"tax ID of 20 characters", &models.CustomerRequest{CompanyName: "Goodpack", TaxID: strings.Repeat("1", 20)}, nil},
+		{"tax ID of 21 characters", &models.CustomerRequest{CompanyName: "Goodpack", TaxID: strings.Repeat("1", 21)}, []string{"taxId:max"}},
+	})
+}
+
+func TestValidateSaleRequest(t *testing.T) {
+	item := func(quantity, unitPrice, discountPercent float64) []models.SaleItem {
+		return []models.SaleItem{{ProductID: "p1", Quantity: quantity, UnitPrice: unitPrice, DiscountPercent: discountPercent}}
+	}
+	runValidationCases(t, []validationCase{
+		{"one unit at no cost", &models.SaleRequest{CustomerID: "c1", Items: item(1, 0, 0)}, nil},
+		{"zero quantity", &models.SaleRequest{CustomerID: "c1", Items: item(0, 10, 0)}, []string{"items[0].quantity:gt"}},
+		{"negative price", &models.SaleRequest{CustomerID: "c1", Items: item(1, -0.01, 0)}, []string{"items[0].unitPrice:min"}},
+		{"discount of 100%", &models.SaleRequest{CustomerID: "c1", Items: item(1, 10, 100)}, nil},
+		{"discount over 100%", &models.SaleRequest{CustomerID: "c1", Items: item(1, 10, 100.5)}, []string{"items[0].discountPercent:max"}},
+		{"no customer", &models.SaleRequest{Items: item(1, 10, 0)}, []string{"customerId:required"}},
+		{"no items", &models.SaleRequest{CustomerID: "c1"}, []string{"items:required_without"}},
+		{"negative shipping", &models.SaleRequest{CustomerID: "c1", Items: item(1, 10, 0), ShippingCost: -1}, []string{"shippingCost:min"}},
+	})
+}
+
+func TestValidatePurchaseRequest(t *testing.T) {
+	item := func(quantity int, unitPrice float64) []models.PurchaseItem {
+		return []models.PurchaseItem{{ProductID: "p1", Quantity: quantity, UnitPrice: unitPrice}}
+	}
+	runValidationCases(t, []validationCase{
+		{"one unit", &models.PurchaseRequest{CustomerID: "s1", Items: item(1, 10)}, nil},
+		{"zero quantity", &models.PurchaseRequest{CustomerID: "s1", Items: item(0, 10)}, []string{"items[0].quantity:required"}},
+		{"negative quantity", &models.PurchaseRequest{CustomerID: "s1", Items: item(-1, 10)}, []string{"items[0].quantity:min"}},
+		{"negative price", &models.PurchaseRequest{CustomerID: "s1", Items: item(1, -1)}, []string{"items[0].unitPrice:min"}},
+		{"empty items", &models.PurchaseRequest{CustomerID: "s1", Items: []models.PurchaseItem{}}, []string{"items:min"}},
+		{"known currency", &models.PurchaseRequest{CustomerID: "s1", Items: item(1, 10), Currency: "USD"}, nil},
+		{"unknown currency", &models.PurchaseRequest{CustomerID: "s1", Items: item(1, 10), Currency: "XYZ"}, []string{"currency:iso4217"}},
+	})
+}
+
+func TestValidateQuotationRequest(t *testing.T) {
+	items := []models.QuotationItem{{ProductID: "p1", Quantity: 1}}
+	runValidationCases(t, []validationCase{
+		{"draft", &models.QuotationRequest{CustomerID: "c1", Items: items, Status: "draft"}, nil},
+		{"no status", &models.QuotationRequest{CustomerID: "c1", Items: items}, nil},
+		{"unknown status", &models.QuotationRequest{CustomerID: "c1", Items: items, Status: "won"}, []string{"status:oneof"}},
+		{"item without product", &models.QuotationRequest{CustomerID: "c1", Items: []models.QuotationItem{{Quantity: 1}}}, []string{"items[0].productId:required"}},
+		{"no items", &models.QuotationRequest{CustomerID: "c1"}, []string{"items:required"}},
+	})
+}
+
+func TestValidateStockAdjustmentRequest(t *testing.T) {
+	notes := strings.Repeat("x", 501)
+	runValidationCases(t, []validationCase{
+		{"add one VAT unit", &models.StockAdjustmentRequest{AdjustmentType: "add", StockType: "vat", Quantity: 1}, nil},
+		{"reduce actual stock", &models.StockAdjustmentRequest{AdjustmentType: "reduce", StockType: "actualstock", Quantity: 3}, nil},
+		{"zero quantity", &models.StockAdjustmentRequest{AdjustmentType: "add", StockType: "vat"}, []string{"quantity:required"}},
+		{"unknown type", &models.StockAdjustmentRequest{AdjustmentType: "set", StockType: "vat", Quantity: 1}, []string{"adjustmentType:oneof"}},
+		{"unknown stock type", &models.StockAdjustmentRequest{AdjustmentType: "add", StockType: "consignment", Quantity: 1}, []string{"stockType:oneof"}},
+		{"notes over 500 characters", &models.StockAdjustmentRequest{AdjustmentType: "add", StockType: "vat", Quantity: 1, Notes: &notes}, []string{"notes:max"}},
+	})
+}